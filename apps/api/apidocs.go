@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/agentsquads/api/openapi"
+)
+
+const (
+	apiSpecPath = "/api/openapi.json"
+	apiDocsPath = "/api/docs"
+)
+
+var apiInfo = openapi.Info{
+	Title:       "AgentSquads API",
+	Version:     "1.0.0",
+	Description: "Tenant-facing endpoints for channels, swarm tasks, deployments and chat completions.",
+}
+
+// apiDocsEnabled reports whether API_DOCS_ENABLED turns on the OpenAPI
+// document and Swagger UI.
+func apiDocsEnabled() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv("API_DOCS_ENABLED")), "true")
+}
+
+// mountAPIDocs serves the OpenAPI document generated from the routes
+// registered through the openapi package, and a Swagger UI that reads it.
+// Both are public so the UI can load the document without credentials.
+func mountAPIDocs(mux *http.ServeMux, reg *openapi.Registry) {
+	mux.HandleFunc("GET "+apiSpecPath, reg.SpecHandler(apiInfo))
+	mux.HandleFunc("GET "+apiDocsPath, openapi.DocsHandler(apiInfo.Title, apiSpecPath))
+}
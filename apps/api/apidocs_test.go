@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agentsquads/api/coordinator"
+	"github.com/agentsquads/api/llmproxy"
+	"github.com/agentsquads/api/openapi"
+	"github.com/agentsquads/api/routes"
+)
+
+func TestAPIDocsServeValidSpec(t *testing.T) {
+	t.Parallel()
+	mux := http.NewServeMux()
+	routes.NewChannelHandler(nil, nil, nil, nil).Mount(mux)
+	routes.NewDeployHandler(nil).Mount(mux)
+	coordinator.NewHandler(nil).Mount(mux)
+	(&llmproxy.Proxy{}).Mount(mux)
+	mountAPIDocs(mux, openapi.DefaultRegistry)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, apiSpecPath, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	if err := openapi.Validate(w.Body.Bytes()); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+
+	var doc openapi.Document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	for path, method := range map[string]string{
+		"/api/channels/inbound":       "post",
+		"/api/tenants/{id}/swarm/run": "post",
+		"/api/swarm/tasks/{id}":       "get",
+		"/api/deploy/vercel":          "post",
+		"/v1/chat/completions":        "post",
+	} {
+		if doc.Paths[path][method] == nil {
+			t.Errorf("missing %s %s", method, path)
+		}
+	}
+	if _, ok := doc.Paths["/api/admin/webhook-failures"]; ok {
+		t.Error("admin routes should not be documented")
+	}
+	for _, name := range []string{"RunRequest", "SwarmRun", "chatRequest", "vercelDeployRequest", "inboundRequest"} {
+		if doc.Components.Schemas[name] == nil {
+			t.Errorf("missing schema %s", name)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, apiDocsPath, nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "SwaggerUIBundle") {
+		t.Fatalf("docs status = %d", w.Code)
+	}
+	if isProtectedPath(apiSpecPath) || isProtectedPath(apiDocsPath) {
+		t.Fatal("API docs should be public")
+	}
+}
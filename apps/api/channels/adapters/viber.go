@@ -0,0 +1,245 @@
+// Package adapters holds channel integrations built on the channels package:
+// provider API clients, webhook parsing and outbound senders for the fanout.
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/channels"
+)
+
+const (
+	viberAPIBase = "https://chatapi.viber.com/pa"
+
+	// viberSenderNameMax is Viber's limit on sender.name.
+	viberSenderNameMax = 28
+)
+
+// ViberEventTypes are the callbacks requested when registering a webhook.
+var ViberEventTypes = []string{"message", "subscribed", "unsubscribed", "conversation_started"}
+
+// ViberAdapter talks to the Viber REST bot API. It also implements
+// channels.Sender so the fanout can deliver replies to Viber users.
+type ViberAdapter struct {
+	HTTPClient  *http.Client
+	Credentials *channels.CredentialsStore
+	// BaseURL overrides the Viber API root, for tests.
+	BaseURL string
+	log     *slog.Logger
+}
+
+func NewViberAdapter(creds *channels.CredentialsStore) *ViberAdapter {
+	return &ViberAdapter{
+		HTTPClient:  &http.Client{Timeout: 15 * time.Second},
+		Credentials: creds,
+		BaseURL:     viberAPIBase,
+		log:         slog.Default().With("component", "viber"),
+	}
+}
+
+// ViberAccount identifies the public account an auth token belongs to.
+type ViberAccount struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ViberEvent is the subset of a Viber callback the router needs.
+type ViberEvent struct {
+	Event        string
+	MessageToken string
+	SenderID     string
+	SenderName   string
+	MessageType  string
+	Text         string
+}
+
+// VerifyViberSignature checks X-Viber-Content-Signature, the hex HMAC-SHA256
+// of the raw body keyed with the account's auth token.
+func VerifyViberSignature(authToken string, body []byte, signature string) bool {
+	want, err := hex.DecodeString(strings.TrimSpace(signature))
+	if err != nil || len(want) == 0 || authToken == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(authToken))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// ParseViberEvent decodes a webhook callback. Unknown fields are ignored
+// because Viber adds them without versioning the callback.
+func ParseViberEvent(body []byte) (ViberEvent, error) {
+	var payload struct {
+		Event        string      `json:"event"`
+		MessageToken json.Number `json:"message_token"`
+		Sender       struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"sender"`
+		Message struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"message"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return ViberEvent{}, fmt.Errorf("invalid viber payload: %w", err)
+	}
+	if strings.TrimSpace(payload.Event) == "" {
+		return ViberEvent{}, errors.New("invalid viber payload: event is required")
+	}
+	return ViberEvent{
+		Event:        strings.TrimSpace(payload.Event),
+		MessageToken: payload.MessageToken.String(),
+		SenderID:     strings.TrimSpace(payload.Sender.ID),
+		SenderName:   strings.TrimSpace(payload.Sender.Name),
+		MessageType:  strings.TrimSpace(payload.Message.Type),
+		Text:         strings.TrimSpace(payload.Message.Text),
+	}, nil
+}
+
+// AccountInfo validates authToken and returns the account it belongs to.
+func (a *ViberAdapter) AccountInfo(ctx context.Context, authToken string) (ViberAccount, error) {
+	var account ViberAccount
+	if err := a.call(ctx, authToken, "get_account_info", map[string]any{}, &account); err != nil {
+		return ViberAccount{}, err
+	}
+	if account.ID == "" {
+		return ViberAccount{}, errors.New("viber account info returned empty id")
+	}
+	return account, nil
+}
+
+// SetWebhook points the account's callbacks at webhookURL. Viber calls the
+// URL with a "webhook" event before answering, so it must already be served.
+func (a *ViberAdapter) SetWebhook(ctx context.Context, authToken, webhookURL string) error {
+	return a.call(ctx, authToken, "set_webhook", map[string]any{
+		"url":         webhookURL,
+		"event_types": ViberEventTypes,
+		"send_name":   true,
+	}, nil)
+}
+
+// SendText sends a text message to a Viber user.
+func (a *ViberAdapter) SendText(ctx context.Context, authToken, senderName, receiver, text string) error {
+	return a.call(ctx, authToken, "send_message", map[string]any{
+		"receiver": receiver,
+		"type":     "text",
+		"text":     text,
+		"sender":   map[string]string{"name": truncateSenderName(senderName)},
+	}, nil)
+}
+
+// Send implements channels.Sender. Missing credentials or targets are logged
+// and skipped; transport and API failures are returned so the fanout retries.
+func (a *ViberAdapter) Send(ctx context.Context, channel channels.TenantChannel, out channels.OutboundMessage) error {
+	if a.Credentials == nil {
+		a.log.Warn("skip viber delivery: credentials store unavailable", "tenant", channel.TenantID)
+		return nil
+	}
+	cred, err := a.Credentials.GetByTenantChannel(ctx, channel.TenantID, "viber")
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.log.Warn("skip viber delivery: credentials missing", "tenant", channel.TenantID)
+			return nil
+		}
+		a.log.Error("failed loading viber credentials", "tenant", channel.TenantID, "err", err)
+		return fmt.Errorf("load credentials: %w", err)
+	}
+
+	authToken := cred.Config["auth_token"]
+	if authToken == "" {
+		a.log.Warn("skip viber delivery: auth token missing", "tenant", channel.TenantID)
+		return nil
+	}
+	receiver := ""
+	if out.Metadata != nil {
+		receiver = strings.TrimSpace(out.Metadata["channel_user_id"])
+	}
+	if receiver == "" {
+		a.log.Warn("skip viber delivery: target user missing", "tenant", channel.TenantID)
+		return nil
+	}
+
+	if err := a.SendText(ctx, authToken, cred.Config["account_name"], receiver, out.Content); err != nil {
+		a.log.Error("viber delivery failed", "tenant", channel.TenantID, "err", err)
+		return err
+	}
+	return nil
+}
+
+// call posts body to a Viber API method. Viber answers 200 for most failures
+// and reports them in status/status_message.
+func (a *ViberAdapter) call(ctx context.Context, authToken, method string, body any, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encode viber %s: %w", method, err)
+	}
+	base := strings.TrimRight(a.BaseURL, "/")
+	if base == "" {
+		base = viberAPIBase
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/"+method, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("build viber %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Viber-Auth-Token", authToken)
+
+	resp, err := a.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("viber %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("viber %s returned %d", method, resp.StatusCode)
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return fmt.Errorf("invalid response from viber %s", method)
+	}
+	var status struct {
+		Status        int    `json:"status"`
+		StatusMessage string `json:"status_message"`
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return fmt.Errorf("invalid response from viber %s", method)
+	}
+	if status.Status != 0 {
+		detail := strings.TrimSpace(status.StatusMessage)
+		if detail == "" {
+			detail = fmt.Sprintf("status %d", status.Status)
+		}
+		return fmt.Errorf("viber %s failed: %s", method, detail)
+	}
+	if out != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			return fmt.Errorf("decode viber %s: %w", method, err)
+		}
+	}
+	return nil
+}
+
+func truncateSenderName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "AgentSquads"
+	}
+	if runes := []rune(name); len(runes) > viberSenderNameMax {
+		name = string(runes[:viberSenderNameMax])
+	}
+	return name
+}
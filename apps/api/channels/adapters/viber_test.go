@@ -0,0 +1,98 @@
+package adapters
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVerifyViberSignature(t *testing.T) {
+	t.Parallel()
+	body := []byte(`{"event":"message"}`)
+	mac := hmac.New(sha256.New, []byte("tok"))
+	mac.Write(body)
+	sig := hex.EncodeToString(mac.Sum(nil))
+
+	if !VerifyViberSignature("tok", body, sig) {
+		t.Fatalf("valid signature rejected")
+	}
+	for name, tc := range map[string]struct{ token, sig string }{
+		"wrong token": {"other", sig},
+		"empty token": {"", sig},
+		"not hex":     {"tok", "zz"},
+		"empty sig":   {"tok", ""},
+	} {
+		if VerifyViberSignature(tc.token, body, tc.sig) {
+			t.Fatalf("%s: signature accepted", name)
+		}
+	}
+}
+
+func TestParseViberEvent(t *testing.T) {
+	t.Parallel()
+	event, err := ParseViberEvent([]byte(`{
+		"event": "message",
+		"timestamp": 1457764197627,
+		"message_token": 4912661846655238145,
+		"sender": {"id": "01234567890A=", "name": "Jane"},
+		"message": {"type": "text", "text": " hi there "}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseViberEvent: %v", err)
+	}
+	want := ViberEvent{Event: "message", MessageToken: "4912661846655238145", SenderID: "01234567890A=", SenderName: "Jane", MessageType: "text", Text: "hi there"}
+	if event != want {
+		t.Fatalf("event = %+v, want %+v", event, want)
+	}
+
+	for _, body := range []string{`not json`, `{"message":{"text":"x"}}`} {
+		if _, err := ParseViberEvent([]byte(body)); err == nil {
+			t.Fatalf("ParseViberEvent(%s) succeeded", body)
+		}
+	}
+}
+
+func TestViberAdapterCalls(t *testing.T) {
+	t.Parallel()
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Viber-Auth-Token") != "tok" {
+			_, _ = w.Write([]byte(`{"status":2,"status_message":"invalidAuthToken"}`))
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		switch r.URL.Path {
+		case "/get_account_info":
+			_, _ = w.Write([]byte(`{"status":0,"status_message":"ok","id":"pa:1","name":"Squad Bot"}`))
+		default:
+			_, _ = w.Write([]byte(`{"status":0,"status_message":"ok"}`))
+		}
+	}))
+	defer srv.Close()
+
+	a := NewViberAdapter(nil)
+	a.BaseURL = srv.URL
+
+	account, err := a.AccountInfo(context.Background(), "tok")
+	if err != nil || account != (ViberAccount{ID: "pa:1", Name: "Squad Bot"}) {
+		t.Fatalf("AccountInfo = %+v, %v", account, err)
+	}
+	if _, err := a.AccountInfo(context.Background(), "bad"); err == nil || !strings.Contains(err.Error(), "invalidAuthToken") {
+		t.Fatalf("AccountInfo with bad token err = %v", err)
+	}
+
+	longName := strings.Repeat("n", viberSenderNameMax+5)
+	if err := a.SendText(context.Background(), "tok", longName, "user-1", "hello"); err != nil {
+		t.Fatalf("SendText: %v", err)
+	}
+	sender, _ := got["sender"].(map[string]any)
+	if got["receiver"] != "user-1" || got["text"] != "hello" || got["type"] != "text" || len(sender["name"].(string)) != viberSenderNameMax {
+		t.Fatalf("send_message body = %v", got)
+	}
+}
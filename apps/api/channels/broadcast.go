@@ -0,0 +1,348 @@
+package channels
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	broadcastPollInterval = time.Second
+	broadcastBatchSize    = 30
+	// broadcastSendInterval keeps a replica under Telegram's overall limit of
+	// about 30 messages per second.
+	broadcastSendInterval = time.Second / 30
+	// broadcastChatInterval is the minimum gap between two broadcast messages
+	// to the same chat.
+	broadcastChatInterval = time.Second
+)
+
+var (
+	// ErrBroadcastNotFound is returned when a broadcast does not exist or
+	// belongs to another tenant.
+	ErrBroadcastNotFound = errors.New("broadcast not found")
+	// ErrNoBroadcastRecipients is returned when a tenant has no chats on the
+	// requested channels to broadcast to.
+	ErrNoBroadcastRecipients = errors.New("no chats to broadcast to")
+)
+
+// Broadcast is a tenant announcement and the delivery state of its
+// recipients.
+type Broadcast struct {
+	ID          string    `json:"id"`
+	TenantID    string    `json:"tenant_id"`
+	Content     string    `json:"content"`
+	Channel     string    `json:"channel,omitempty"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	Sent        int       `json:"sent"`
+	Failed      int       `json:"failed"`
+	Pending     int       `json:"pending"`
+}
+
+// BroadcastStore persists broadcasts and their per-recipient deliveries.
+type BroadcastStore struct {
+	db *sql.DB
+}
+
+func NewBroadcastStore(db *sql.DB) *BroadcastStore {
+	return &BroadcastStore{db: db}
+}
+
+// Create records a broadcast to every chat that has messaged the tenant on
+// channel, or on any non-web channel when channel is empty. Delivery starts
+// once scheduledAt has passed.
+func (s *BroadcastStore) Create(ctx context.Context, tenantID, content, channel string, scheduledAt time.Time) (*Broadcast, error) {
+	tenantID = strings.TrimSpace(tenantID)
+	content = strings.TrimSpace(content)
+	if tenantID == "" {
+		return nil, errors.New("tenant id is required")
+	}
+	if content == "" {
+		return nil, errors.New("content is required")
+	}
+	if strings.TrimSpace(channel) != "" {
+		normalized, err := normalizeChannel(channel)
+		if err != nil {
+			return nil, err
+		}
+		if normalized == "web" {
+			return nil, fmt.Errorf("%w: web chats cannot receive broadcasts", ErrInvalidChannel)
+		}
+		channel = normalized
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	b := &Broadcast{TenantID: tenantID, Content: content, Channel: channel}
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO broadcasts (tenant_id, content, channel, scheduled_at)
+		 VALUES ($1, $2, NULLIF($3, ''), $4)
+		 RETURNING id, scheduled_at, created_at`,
+		tenantID, content, channel, scheduledAt,
+	).Scan(&b.ID, &b.ScheduledAt, &b.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert broadcast: %w", err)
+	}
+
+	// Inbound messages carry the chat they came from in channel_user_id,
+	// which is where the fanout sends replies.
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO broadcast_deliveries (broadcast_id, channel, recipient)
+		 SELECT DISTINCT $1::uuid, m.channel, m.metadata->>'channel_user_id'
+		 FROM messages m
+		 JOIN conversations c ON c.id = m.conversation_id
+		 WHERE c.tenant_id = $2
+		   AND m.role = 'user'
+		   AND m.channel <> 'web'
+		   AND ($3 = '' OR m.channel = $3)
+		   AND COALESCE(m.metadata->>'channel_user_id', '') <> ''
+		 ON CONFLICT DO NOTHING`,
+		b.ID, tenantID, channel,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert broadcast deliveries: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("count broadcast deliveries: %w", err)
+	}
+	if n == 0 {
+		return nil, ErrNoBroadcastRecipients
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	b.Pending = int(n)
+	return b, nil
+}
+
+// Get returns a tenant's broadcast with its delivery counts.
+func (s *BroadcastStore) Get(ctx context.Context, tenantID, broadcastID string) (*Broadcast, error) {
+	var (
+		b       Broadcast
+		channel sql.NullString
+	)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT b.id, b.tenant_id, b.content, b.channel, b.scheduled_at, b.created_at,
+		        COUNT(d.id) FILTER (WHERE d.status = 'sent'),
+		        COUNT(d.id) FILTER (WHERE d.status = 'failed'),
+		        COUNT(d.id) FILTER (WHERE d.status = 'pending')
+		 FROM broadcasts b
+		 LEFT JOIN broadcast_deliveries d ON d.broadcast_id = b.id
+		 WHERE b.id = $1 AND b.tenant_id = $2
+		 GROUP BY b.id`,
+		broadcastID, tenantID,
+	).Scan(&b.ID, &b.TenantID, &b.Content, &channel, &b.ScheduledAt, &b.CreatedAt, &b.Sent, &b.Failed, &b.Pending)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBroadcastNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get broadcast: %w", err)
+	}
+	b.Channel = channel.String
+	return &b, nil
+}
+
+// RetryFailed returns a broadcast's failed deliveries to pending and reports
+// how many were requeued. Recipients already sent to are left alone.
+func (s *BroadcastStore) RetryFailed(ctx context.Context, tenantID, broadcastID string) (int, error) {
+	if _, err := s.Get(ctx, tenantID, broadcastID); err != nil {
+		return 0, err
+	}
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE broadcast_deliveries
+		 SET status = 'pending', claimed_at = NULL
+		 WHERE broadcast_id = $1 AND status = 'failed'`,
+		broadcastID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("retry broadcast deliveries: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("count retried deliveries: %w", err)
+	}
+	return int(n), nil
+}
+
+type broadcastDelivery struct {
+	id          int64
+	broadcastID string
+	tenantID    string
+	content     string
+	channel     string
+	recipient   string
+}
+
+// claimDue leases up to limit pending deliveries of broadcasts whose schedule
+// has passed. SKIP LOCKED lets several replicas claim concurrently.
+func (s *BroadcastStore) claimDue(ctx context.Context, limit int) ([]broadcastDelivery, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`UPDATE broadcast_deliveries d
+		 SET claimed_at = NOW(), attempts = d.attempts + 1
+		 FROM broadcasts b
+		 WHERE b.id = d.broadcast_id
+		   AND d.id IN (
+		     SELECT d2.id
+		     FROM broadcast_deliveries d2
+		     JOIN broadcasts b2 ON b2.id = d2.broadcast_id
+		     WHERE d2.status = 'pending'
+		       AND b2.scheduled_at <= NOW()
+		       AND (d2.claimed_at IS NULL OR d2.claimed_at < NOW() - INTERVAL '5 minutes')
+		     ORDER BY b2.scheduled_at, d2.id
+		     LIMIT $1
+		     FOR UPDATE OF d2 SKIP LOCKED
+		   )
+		 RETURNING d.id, b.id, b.tenant_id, b.content, d.channel, d.recipient`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim broadcast deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var due []broadcastDelivery
+	for rows.Next() {
+		var d broadcastDelivery
+		if err := rows.Scan(&d.id, &d.broadcastID, &d.tenantID, &d.content, &d.channel, &d.recipient); err != nil {
+			return nil, fmt.Errorf("scan broadcast delivery: %w", err)
+		}
+		due = append(due, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate broadcast deliveries: %w", err)
+	}
+	return due, nil
+}
+
+func (s *BroadcastStore) markSent(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE broadcast_deliveries SET status = 'sent', sent_at = NOW(), last_error = NULL WHERE id = $1`, id)
+	return err
+}
+
+func (s *BroadcastStore) markFailed(ctx context.Context, id int64, reason string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE broadcast_deliveries SET status = 'failed', last_error = $2 WHERE id = $1`, id, reason)
+	return err
+}
+
+// release hands a claimed delivery back without counting it as an attempt.
+func (s *BroadcastStore) release(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE broadcast_deliveries SET claimed_at = NULL, attempts = attempts - 1 WHERE id = $1`, id)
+	return err
+}
+
+// Broadcaster delivers due broadcasts one recipient at a time through the
+// fanout, throttled to stay within provider rate limits.
+type Broadcaster struct {
+	store  *BroadcastStore
+	fanout *Fanout
+	log    *slog.Logger
+
+	sendInterval time.Duration
+	lastSent     map[string]time.Time
+}
+
+func NewBroadcaster(store *BroadcastStore, fanout *Fanout) *Broadcaster {
+	return &Broadcaster{
+		store:        store,
+		fanout:       fanout,
+		log:          slog.Default().With("component", "channels.broadcast"),
+		sendInterval: broadcastSendInterval,
+		lastSent:     make(map[string]time.Time),
+	}
+}
+
+// Start delivers due broadcasts until ctx is cancelled.
+func (b *Broadcaster) Start(ctx context.Context) {
+	ticker := time.NewTicker(broadcastPollInterval)
+	defer ticker.Stop()
+	for {
+		if err := b.deliverDue(ctx); err != nil && ctx.Err() == nil {
+			b.log.Error("broadcast delivery failed", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue sends one batch of due deliveries. A recipient messaged less
+// than broadcastChatInterval ago is released for a later batch.
+func (b *Broadcaster) deliverDue(ctx context.Context) error {
+	due, err := b.store.claimDue(ctx, broadcastBatchSize)
+	if err != nil {
+		return err
+	}
+
+	sent := 0
+	for _, d := range due {
+		chat := d.tenantID + "|" + d.channel + "|" + d.recipient
+		if time.Since(b.lastSent[chat]) < broadcastChatInterval {
+			if err := b.store.release(ctx, d.id); err != nil {
+				b.log.Error("failed to release broadcast delivery", "delivery", d.id, "err", err)
+			}
+			continue
+		}
+		if sent > 0 && b.sendInterval > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(b.sendInterval):
+			}
+		}
+
+		b.lastSent[chat] = time.Now()
+		sent++
+		if err := b.send(ctx, d); err != nil {
+			b.log.Warn("broadcast delivery failed", "tenant", d.tenantID, "broadcast", d.broadcastID, "channel", d.channel, "err", err)
+			if err := b.store.markFailed(ctx, d.id, err.Error()); err != nil {
+				b.log.Error("failed to record broadcast failure", "delivery", d.id, "err", err)
+			}
+			continue
+		}
+		if err := b.store.markSent(ctx, d.id); err != nil {
+			b.log.Error("failed to record broadcast delivery", "delivery", d.id, "err", err)
+		}
+	}
+
+	for chat, at := range b.lastSent {
+		if time.Since(at) >= broadcastChatInterval {
+			delete(b.lastSent, chat)
+		}
+	}
+	return nil
+}
+
+func (b *Broadcaster) send(ctx context.Context, d broadcastDelivery) error {
+	delivered, err := b.fanout.deliver(ctx, OutboundMessage{
+		TenantID: d.tenantID,
+		Content:  d.content,
+		Channel:  d.channel,
+		Metadata: map[string]string{
+			"channel_user_id": d.recipient,
+			"broadcast_id":    d.broadcastID,
+		},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	if len(delivered) == 0 {
+		return fmt.Errorf("%s channel is not linked or is muted", d.channel)
+	}
+	return nil
+}
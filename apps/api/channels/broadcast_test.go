@@ -0,0 +1,75 @@
+package channels
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBroadcasterDeliverDue(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	f := NewFanout(nil, NewLinkStore(db), NewCredentialsStore(db))
+	var chats []string
+	f.http = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		chats = append(chats, string(body))
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok")), Header: make(http.Header)}, nil
+	})}
+	b := NewBroadcaster(NewBroadcastStore(db), f)
+	b.sendInterval = 0
+
+	mock.ExpectQuery("UPDATE broadcast_deliveries d").WithArgs(broadcastBatchSize).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "broadcast_id", "tenant_id", "content", "channel", "recipient"}).
+			AddRow(1, "b1", "t1", "Sale today", "telegram", "100").
+			AddRow(2, "b2", "t1", "Also today", "telegram", "100").
+			AddRow(3, "b1", "t1", "Sale today", "whatsapp", "+15550001"))
+
+	// Delivery 1 goes out through the linked telegram bot.
+	mock.ExpectQuery("SELECT id, tenant_id, channel").WithArgs("t1").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "tenant_id", "channel", "channel_user_id", "linked_at", "muted", "notification_level"}).
+			AddRow("1", "t1", "telegram", "", time.Now(), false, "milestones"))
+	mock.ExpectQuery("SELECT tenant_id, channel, config::text").WithArgs("t1", "telegram").WillReturnRows(
+		sqlmock.NewRows([]string{"tenant_id", "channel", "config", "updated_at"}).AddRow("t1", "telegram", `{"bot_token":"tok"}`, time.Now()))
+	mock.ExpectExec("UPDATE broadcast_deliveries SET status = 'sent'").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Delivery 2 targets the same chat within a second and is handed back.
+	mock.ExpectExec("UPDATE broadcast_deliveries SET claimed_at = NULL").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Delivery 3 has no linked whatsapp channel.
+	mock.ExpectQuery("SELECT id, tenant_id, channel").WithArgs("t1").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "tenant_id", "channel", "channel_user_id", "linked_at", "muted", "notification_level"}).
+			AddRow("1", "t1", "telegram", "", time.Now(), false, "milestones"))
+	mock.ExpectExec("UPDATE broadcast_deliveries SET status = 'failed'").WithArgs(3, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := b.deliverDue(context.Background()); err != nil {
+		t.Fatalf("deliverDue: %v", err)
+	}
+	if len(chats) != 1 || !strings.Contains(chats[0], `"chat_id":"100"`) || !strings.Contains(chats[0], "Sale today") {
+		t.Fatalf("telegram requests = %v", chats)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestBroadcastStoreCreateRejectsWeb(t *testing.T) {
+	t.Parallel()
+	s := NewBroadcastStore(nil)
+	if _, err := s.Create(context.Background(), "t1", "hi", "web", time.Now()); err == nil {
+		t.Fatalf("expected web channel to be rejected")
+	}
+	if _, err := s.Create(context.Background(), "t1", " ", "", time.Now()); err == nil {
+		t.Fatalf("expected empty content to be rejected")
+	}
+}
@@ -0,0 +1,309 @@
+package channels
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/agentsquads/api/tools"
+)
+
+const (
+	upkeepJobTimeout     = 2 * time.Minute
+	titleContextMessages = 12
+	maxTitleRunes        = 80
+	maxTranscriptRunes   = 500
+	summaryContextPrefix = "Summary of the earlier conversation:\n"
+
+	defaultRetitleEvery = 20
+	defaultSummaryKeep  = 20
+	// summaryBatch is how many messages beyond summaryKeep accumulate before
+	// they are folded into the summary, so it is not rewritten every turn.
+	summaryBatch = 10
+
+	titlePrompt = "You write short titles for chat conversations. Reply with only the title: " +
+		"at most six words, no quotes and no trailing punctuation."
+	summaryPrompt = "You maintain a running summary of a conversation that is given to an assistant " +
+		"in place of the older messages. Merge the new messages into the existing summary. Keep facts, " +
+		"decisions, names, preferences and open questions. Reply with only the summary, under 200 words."
+)
+
+// ErrConversationNotFound is returned when a conversation does not exist or
+// belongs to another tenant.
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// upkeepConfig controls conversation titling and summarization.
+type upkeepConfig struct {
+	// model is used for titles and summaries; CONVERSATION_TITLE_MODEL
+	// overrides the router's default model.
+	model string
+	// retitleEvery regenerates the title once this many messages have been
+	// added since it was generated (CONVERSATION_RETITLE_EVERY, 0 disables).
+	retitleEvery int
+	// summaryKeep is how many recent messages are always sent verbatim
+	// (CONVERSATION_SUMMARY_KEEP, 0 disables summaries).
+	summaryKeep int
+}
+
+func upkeepConfigFromEnv() upkeepConfig {
+	return upkeepConfig{
+		model:        strings.TrimSpace(os.Getenv("CONVERSATION_TITLE_MODEL")),
+		retitleEvery: intFromEnv("CONVERSATION_RETITLE_EVERY", defaultRetitleEvery),
+		summaryKeep:  intFromEnv("CONVERSATION_SUMMARY_KEEP", defaultSummaryKeep),
+	}
+}
+
+func intFromEnv(name string, fallback int) int {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return fallback
+	}
+	return n
+}
+
+func (r *Router) upkeepModel() string {
+	if r.upkeep.model != "" {
+		return r.upkeep.model
+	}
+	return r.model
+}
+
+type conversationState struct {
+	title             sql.NullString
+	titledCount       int
+	summary           sql.NullString
+	summarizedThrough sql.NullTime
+	messageCount      int
+}
+
+func (r *Router) loadConversationState(ctx context.Context, tenantID, conversationID string) (conversationState, error) {
+	var st conversationState
+	err := r.db.QueryRowContext(ctx,
+		`SELECT c.title, c.titled_message_count, c.summary, c.summarized_through,
+		        (SELECT COUNT(*) FROM messages m WHERE m.conversation_id = c.id)
+		 FROM conversations c
+		 WHERE c.id = $1 AND c.tenant_id = $2`,
+		conversationID,
+		tenantID,
+	).Scan(&st.title, &st.titledCount, &st.summary, &st.summarizedThrough, &st.messageCount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return st, ErrConversationNotFound
+	}
+	if err != nil {
+		return st, fmt.Errorf("load conversation: %w", err)
+	}
+	return st, nil
+}
+
+func (r *Router) conversationSummary(ctx context.Context, conversationID string) (string, error) {
+	var summary sql.NullString
+	err := r.db.QueryRowContext(ctx,
+		"SELECT summary FROM conversations WHERE id = $1",
+		conversationID,
+	).Scan(&summary)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("load conversation summary: %w", err)
+	}
+	return strings.TrimSpace(summary.String), nil
+}
+
+// scheduleConversationUpkeep refreshes the conversation's title and summary
+// in the background so routing never waits on the extra completions.
+func (r *Router) scheduleConversationUpkeep(ctx context.Context, tenantID, conversationID string) {
+	if r.jobs == nil || r.db == nil {
+		return
+	}
+	r.jobs.Go(ctx, "conversation-upkeep:"+conversationID, func(ctx context.Context) error {
+		return r.updateConversation(ctx, tenantID, conversationID)
+	})
+}
+
+func (r *Router) updateConversation(ctx context.Context, tenantID, conversationID string) error {
+	st, err := r.loadConversationState(ctx, tenantID, conversationID)
+	if err != nil {
+		return err
+	}
+	if r.needsTitle(st) {
+		if _, err := r.retitle(ctx, tenantID, conversationID, st.messageCount); err != nil {
+			return err
+		}
+	}
+	return r.updateSummary(ctx, tenantID, conversationID, st)
+}
+
+// needsTitle is true after the first exchange and again every retitleEvery
+// messages, so the title follows a conversation that drifts.
+func (r *Router) needsTitle(st conversationState) bool {
+	if strings.TrimSpace(st.title.String) == "" {
+		return st.messageCount >= 2
+	}
+	return r.upkeep.retitleEvery > 0 && st.messageCount-st.titledCount >= r.upkeep.retitleEvery
+}
+
+// Retitle generates and stores a new title for the tenant's conversation
+// immediately.
+func (r *Router) Retitle(ctx context.Context, tenantID, conversationID string) (string, error) {
+	st, err := r.loadConversationState(ctx, tenantID, conversationID)
+	if err != nil {
+		return "", err
+	}
+	if st.messageCount == 0 {
+		return "", errors.New("conversation has no messages")
+	}
+	return r.retitle(ctx, tenantID, conversationID, st.messageCount)
+}
+
+func (r *Router) retitle(ctx context.Context, tenantID, conversationID string, messageCount int) (string, error) {
+	recent, err := r.loadTranscript(ctx,
+		`SELECT role, content, created_at
+		 FROM (
+		   SELECT role, content, created_at
+		   FROM messages
+		   WHERE conversation_id = $1
+		   ORDER BY created_at DESC
+		   LIMIT $2
+		 ) recent
+		 ORDER BY created_at ASC`,
+		conversationID, titleContextMessages,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := r.complete(ctx, tenantID, r.upkeepModel(), []tools.Message{
+		{Role: "system", Content: titlePrompt},
+		{Role: "user", Content: formatTranscript(recent)},
+	})
+	if err != nil {
+		return "", fmt.Errorf("generate title: %w", err)
+	}
+	title := cleanTitle(raw)
+	if title == "" {
+		return "", errors.New("generated title is empty")
+	}
+
+	if _, err := r.db.ExecContext(ctx,
+		`UPDATE conversations
+		 SET title = $2, titled_message_count = $3, title_updated_at = NOW()
+		 WHERE id = $1`,
+		conversationID, title, messageCount,
+	); err != nil {
+		return "", fmt.Errorf("store title: %w", err)
+	}
+	return title, nil
+}
+
+// updateSummary folds every message except the newest summaryKeep into the
+// rolling summary once summaryBatch of them have piled up.
+func (r *Router) updateSummary(ctx context.Context, tenantID, conversationID string, st conversationState) error {
+	keep := r.upkeep.summaryKeep
+	if keep == 0 || st.messageCount <= keep+summaryBatch {
+		return nil
+	}
+
+	var since any
+	if st.summarizedThrough.Valid {
+		since = st.summarizedThrough.Time
+	}
+	pending, err := r.loadTranscript(ctx,
+		`SELECT role, content, created_at
+		 FROM messages
+		 WHERE conversation_id = $1
+		   AND ($2::timestamptz IS NULL OR created_at > $2)
+		 ORDER BY created_at ASC`,
+		conversationID, since,
+	)
+	if err != nil {
+		return err
+	}
+	if len(pending) <= keep+summaryBatch {
+		return nil
+	}
+	fold := pending[:len(pending)-keep]
+
+	existing := strings.TrimSpace(st.summary.String)
+	if existing == "" {
+		existing = "(none yet)"
+	}
+	summary, err := r.complete(ctx, tenantID, r.upkeepModel(), []tools.Message{
+		{Role: "system", Content: summaryPrompt},
+		{Role: "user", Content: "Existing summary:\n" + existing + "\n\nNew messages:\n" + formatTranscript(fold)},
+	})
+	if err != nil {
+		return fmt.Errorf("generate summary: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx,
+		`UPDATE conversations
+		 SET summary = $2, summarized_through = $3, summary_updated_at = NOW()
+		 WHERE id = $1`,
+		conversationID, strings.TrimSpace(summary), fold[len(fold)-1].createdAt,
+	); err != nil {
+		return fmt.Errorf("store summary: %w", err)
+	}
+	return nil
+}
+
+type transcriptMessage struct {
+	role      string
+	content   string
+	createdAt time.Time
+}
+
+func (r *Router) loadTranscript(ctx context.Context, query string, args ...any) ([]transcriptMessage, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("load transcript: %w", err)
+	}
+	defer rows.Close()
+
+	var out []transcriptMessage
+	for rows.Next() {
+		var m transcriptMessage
+		if err := rows.Scan(&m.role, &m.content, &m.createdAt); err != nil {
+			return nil, fmt.Errorf("scan transcript message: %w", err)
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func formatTranscript(messages []transcriptMessage) string {
+	var b strings.Builder
+	for _, m := range messages {
+		b.WriteString(m.role)
+		b.WriteString(": ")
+		b.WriteString(truncateRunes(strings.TrimSpace(m.content), maxTranscriptRunes))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+const titleQuotes = "\"'`*“”‘’ "
+
+// cleanTitle keeps the first line of a model reply without surrounding
+// quotes or trailing punctuation.
+func cleanTitle(raw string) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(raw), "\n")
+	title = strings.TrimSpace(strings.TrimPrefix(title, "Title:"))
+	title = strings.TrimLeft(title, titleQuotes)
+	title = strings.TrimRight(title, titleQuotes+".!?:;")
+	return truncateRunes(title, maxTitleRunes)
+}
+
+func truncateRunes(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	return string([]rune(s)[:limit]) + "…"
+}
@@ -0,0 +1,173 @@
+package channels
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newUpkeepRouter(t *testing.T, reply string, prompts *[]string) (*Router, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		if body.Model != "cheap-model" {
+			t.Errorf("model = %q, want cheap-model", body.Model)
+		}
+		if prompts != nil && len(body.Messages) > 0 {
+			*prompts = append(*prompts, body.Messages[len(body.Messages)-1].Content)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": reply}}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	r := NewRouter(db, nil)
+	r.llmProxyURL = srv.URL
+	r.upkeep = upkeepConfig{model: "cheap-model", retitleEvery: 20, summaryKeep: 2}
+	return r, mock
+}
+
+func stateRows(title any, titled int, summary any, through any, count int) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"title", "titled_message_count", "summary", "summarized_through", "count"}).
+		AddRow(title, titled, summary, through, count)
+}
+
+func TestRetitleStoresCleanTitle(t *testing.T) {
+	t.Parallel()
+	r, mock := newUpkeepRouter(t, "\"Planning the Lisbon offsite.\"\nextra", nil)
+
+	now := time.Now()
+	mock.ExpectQuery("FROM conversations c").
+		WithArgs("conv-1", "t1").
+		WillReturnRows(stateRows("Old", 2, nil, nil, 4))
+	mock.ExpectQuery("FROM messages").
+		WithArgs("conv-1", titleContextMessages).
+		WillReturnRows(sqlmock.NewRows([]string{"role", "content", "created_at"}).
+			AddRow("user", "where should we go?", now).
+			AddRow("assistant", "Lisbon", now))
+	mock.ExpectExec("UPDATE conversations").
+		WithArgs("conv-1", "Planning the Lisbon offsite", 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	title, err := r.Retitle(context.Background(), "t1", "conv-1")
+	if err != nil {
+		t.Fatalf("Retitle: %v", err)
+	}
+	if title != "Planning the Lisbon offsite" {
+		t.Fatalf("title = %q", title)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRetitleOtherTenantNotFound(t *testing.T) {
+	t.Parallel()
+	r, mock := newUpkeepRouter(t, "unused", nil)
+	mock.ExpectQuery("FROM conversations c").
+		WithArgs("conv-1", "t2").
+		WillReturnError(sql.ErrNoRows)
+
+	if _, err := r.Retitle(context.Background(), "t2", "conv-1"); !errors.Is(err, ErrConversationNotFound) {
+		t.Fatalf("err = %v, want ErrConversationNotFound", err)
+	}
+}
+
+func TestNeedsTitle(t *testing.T) {
+	t.Parallel()
+	r := &Router{upkeep: upkeepConfig{retitleEvery: 20}}
+	tests := []struct {
+		name  string
+		state conversationState
+		want  bool
+	}{
+		{name: "first message only", state: conversationState{messageCount: 1}},
+		{name: "first exchange", state: conversationState{messageCount: 2}, want: true},
+		{name: "titled recently", state: conversationState{title: sql.NullString{String: "x", Valid: true}, titledCount: 2, messageCount: 10}},
+		{name: "drifted", state: conversationState{title: sql.NullString{String: "x", Valid: true}, titledCount: 2, messageCount: 22}, want: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := r.needsTitle(tt.state); got != tt.want {
+				t.Fatalf("needsTitle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateSummaryFoldsOlderMessages(t *testing.T) {
+	t.Parallel()
+	var prompts []string
+	r, mock := newUpkeepRouter(t, "They are planning an offsite.", &prompts)
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"role", "content", "created_at"})
+	total := r.upkeep.summaryKeep + summaryBatch + 1
+	for i := 0; i < total; i++ {
+		rows.AddRow("user", "message", base.Add(time.Duration(i)*time.Minute))
+	}
+	mock.ExpectQuery("FROM messages").
+		WithArgs("conv-1", nil).
+		WillReturnRows(rows)
+	lastFolded := base.Add(time.Duration(total-r.upkeep.summaryKeep-1) * time.Minute)
+	mock.ExpectExec("UPDATE conversations").
+		WithArgs("conv-1", "They are planning an offsite.", lastFolded).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	state := conversationState{messageCount: total}
+	if err := r.updateSummary(context.Background(), "t1", "conv-1", state); err != nil {
+		t.Fatalf("updateSummary: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "(none yet)") {
+		t.Fatalf("prompts = %q", prompts)
+	}
+}
+
+func TestUpdateSummarySkipsShortConversations(t *testing.T) {
+	t.Parallel()
+	r, mock := newUpkeepRouter(t, "unused", nil)
+	state := conversationState{messageCount: r.upkeep.summaryKeep + summaryBatch}
+	if err := r.updateSummary(context.Background(), "t1", "conv-1", state); err != nil {
+		t.Fatalf("updateSummary: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	t.Parallel()
+	if got := cleanTitle("Title: **Quarterly budget review**!"); got != "Quarterly budget review" {
+		t.Fatalf("cleanTitle = %q", got)
+	}
+	long := cleanTitle(strings.Repeat("é", 200))
+	if n := len([]rune(long)); n != maxTitleRunes+1 {
+		t.Fatalf("cleanTitle length = %d runes", n)
+	}
+}
@@ -135,6 +135,33 @@ func (s *CredentialsStore) FindTenantByWhatsAppPhoneNumberID(ctx context.Context
 	return strings.TrimSpace(tenantID), nil
 }
 
+// FindTenantByViberWebhookID resolves the tenant whose Viber webhook URL
+// carries webhookID.
+func (s *CredentialsStore) FindTenantByViberWebhookID(ctx context.Context, webhookID string) (string, error) {
+	if s == nil || s.db == nil {
+		return "", errors.New("credential store is not configured")
+	}
+	webhookID = strings.TrimSpace(webhookID)
+	if webhookID == "" {
+		return "", errors.New("viber webhook id is required")
+	}
+
+	var tenantID string
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT tenant_id
+		FROM channel_credentials
+		WHERE channel = 'viber' AND config->>'webhook_id' = $1
+		LIMIT 1
+	`, webhookID).Scan(&tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", sql.ErrNoRows
+		}
+		return "", fmt.Errorf("lookup tenant by viber webhook id: %w", err)
+	}
+
+	return strings.TrimSpace(tenantID), nil
+}
+
 func unmarshalConfig(raw []byte, cred *ChannelCredential) error {
 	var generic map[string]interface{}
 	if err := json.Unmarshal(raw, &generic); err != nil {
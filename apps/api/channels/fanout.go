@@ -8,84 +8,333 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-// Fanout subscribes to tenant response topics and relays responses to linked channels.
+const (
+	fanoutReadBlock     = 5 * time.Second
+	fanoutReadCount     = 32
+	fanoutClaimMinIdle  = time.Minute
+	fanoutClaimInterval = time.Minute
+)
+
+// Fanout consumes the outbound streams as part of a consumer group and relays
+// responses to linked channels. Entries are acked only after delivery so
+// replicas can share the load and pending work survives restarts.
 type Fanout struct {
-	redis *redis.Client
-	links *LinkStore
-	creds *CredentialsStore
-	http  *http.Client
-	log   *slog.Logger
+	redis    *redis.Client
+	links    ChannelLinks
+	creds    ChannelCredentials
+	http     *http.Client
+	log      *slog.Logger
+	consumer string
+	senders  map[string]Sender
+	filters  *OutboundFilters
+
+	// DryRun makes deliveries and Send record messages in the dry-run log
+	// instead of sending them, so routing can be tested without real
+	// Telegram or WhatsApp traffic. Set it before Start.
+	DryRun bool
+
+	dryRunMu sync.Mutex
+	// dryRunLog is a ring of the last dryRunLogSize recorded messages;
+	// dryRunNext is where the next one goes once it is full.
+	dryRunLog  []OutboundMessage
+	dryRunNext int
+}
+
+// dryRunLogSize caps the dry-run log, which only tests drain.
+const dryRunLogSize = 1000
+
+// ChannelLinks lists the channels a tenant has linked. LinkStore implements
+// it; tests can use an in-memory implementation instead.
+type ChannelLinks interface {
+	GetChannels(tenantID string) ([]TenantChannel, error)
+}
+
+// ChannelCredentials loads a tenant's provider credentials for a channel and
+// returns sql.ErrNoRows when there are none. CredentialsStore implements it.
+type ChannelCredentials interface {
+	GetByTenantChannel(ctx context.Context, tenantID, channel string) (ChannelCredential, error)
 }
 
-func NewFanout(redisClient *redis.Client, links *LinkStore, creds *CredentialsStore) *Fanout {
+// Sender delivers outbound messages for a channel implemented outside this
+// package, such as the adapters in channels/adapters. It follows the same
+// contract as the built-in senders: skip and return nil when the message
+// cannot be delivered, return an error when it should be retried.
+type Sender interface {
+	Send(ctx context.Context, channel TenantChannel, out OutboundMessage) error
+}
+
+// RegisterSender makes the fanout deliver to channel through s. Call it
+// before Start.
+func (f *Fanout) RegisterSender(channel string, s Sender) {
+	if f.senders == nil {
+		f.senders = make(map[string]Sender)
+	}
+	f.senders[channel] = s
+}
+
+func NewFanout(redisClient *redis.Client, links ChannelLinks, creds ChannelCredentials) *Fanout {
 	return &Fanout{
-		redis: redisClient,
-		links: links,
-		creds: creds,
-		http:  &http.Client{Timeout: 15 * time.Second},
-		log:   slog.Default().With("component", "channels.fanout"),
+		redis:    redisClient,
+		links:    links,
+		creds:    creds,
+		http:     &http.Client{Timeout: 15 * time.Second},
+		log:      slog.Default().With("component", "channels.fanout"),
+		consumer: fanoutConsumerName(),
 	}
 }
 
-// Start subscribes to tenant:*:response and dispatches each message to linked channels.
+// Start joins the fanout consumer group, reclaims entries left pending by dead
+// consumers, then dispatches new entries until ctx is cancelled.
 func (f *Fanout) Start(ctx context.Context) error {
 	if f.redis == nil {
 		return errors.New("redis is not configured")
 	}
 
-	pubsub := f.redis.PSubscribe(ctx, "tenant:*:response")
-	defer pubsub.Close()
+	streams := outboundStreamKeys()
+	for _, stream := range streams {
+		err := f.redis.XGroupCreateMkStream(ctx, stream, fanoutConsumerGroup, "0").Err()
+		if err != nil && !strings.Contains(err.Error(), "BUSYGROUP") {
+			return fmt.Errorf("create consumer group for %s: %w", stream, err)
+		}
+	}
+	f.log.Info("fanout consumer started", "consumer", f.consumer, "streams", len(streams))
+
+	f.claimPending(ctx, streams)
+	lastClaim := time.Now()
+	var lastPromote time.Time
+
+	readArgs := make([]string, 0, len(streams)*2)
+	readArgs = append(readArgs, streams...)
+	for range streams {
+		readArgs = append(readArgs, ">")
+	}
 
 	for {
-		message, err := pubsub.ReceiveMessage(ctx)
+		if time.Since(lastClaim) >= fanoutClaimInterval {
+			f.claimPending(ctx, streams)
+			lastClaim = time.Now()
+		}
+		if time.Since(lastPromote) >= fanoutRetryInterval {
+			f.promoteRetries(ctx)
+			lastPromote = time.Now()
+		}
+
+		results, err := f.redis.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    fanoutConsumerGroup,
+			Consumer: f.consumer,
+			Streams:  readArgs,
+			Count:    fanoutReadCount,
+			Block:    fanoutReadBlock,
+		}).Result()
 		if err != nil {
-			if errors.Is(err, context.Canceled) {
+			if errors.Is(err, context.Canceled) || ctx.Err() != nil {
 				return nil
 			}
-			return fmt.Errorf("receive pubsub message: %w", err)
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			return fmt.Errorf("read outbound streams: %w", err)
 		}
 
-		var out OutboundMessage
-		if err := json.Unmarshal([]byte(message.Payload), &out); err != nil {
-			f.log.Error("failed to decode outbound payload", "channel", message.Channel, "err", err)
-			continue
+		for _, result := range results {
+			for _, msg := range result.Messages {
+				f.handleEntry(ctx, result.Stream, msg)
+			}
 		}
+	}
+}
 
-		if out.TenantID == "" {
-			out.TenantID = tenantIDFromTopic(message.Channel)
+// claimPending takes over entries that another consumer read but never acked,
+// e.g. because its instance died mid-delivery.
+func (f *Fanout) claimPending(ctx context.Context, streams []string) {
+	for _, stream := range streams {
+		start := "0-0"
+		for {
+			msgs, next, err := f.redis.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+				Stream:   stream,
+				Group:    fanoutConsumerGroup,
+				Consumer: f.consumer,
+				MinIdle:  fanoutClaimMinIdle,
+				Start:    start,
+				Count:    fanoutReadCount,
+			}).Result()
+			if err != nil {
+				f.log.Error("failed to claim pending outbound entries", "stream", stream, "err", err)
+				break
+			}
+			for _, msg := range msgs {
+				f.handleEntry(ctx, stream, msg)
+			}
+			if next == "0-0" || len(msgs) == 0 {
+				break
+			}
+			start = next
 		}
-		if out.TenantID == "" {
-			f.log.Warn("skip fanout: tenant id is missing", "channel", message.Channel)
-			continue
+	}
+}
+
+// handleEntry delivers one stream entry. Failed deliveries are scheduled for
+// a retry with an incremented attempt count and exponential backoff (or
+// dead-lettered once attempts run out) before the original entry is acked.
+func (f *Fanout) handleEntry(ctx context.Context, stream string, msg redis.XMessage) {
+	entry, err := decodeOutboundEntry(msg.Values)
+	if err != nil {
+		f.log.Error("failed to decode outbound entry", "stream", stream, "id", msg.ID, "err", err)
+		f.deadLetter(ctx, stream, msg.ID, outboundEntry{LastError: err.Error()})
+		return
+	}
+	if entry.Message.TenantID == "" {
+		f.log.Warn("skip fanout: tenant id is missing", "stream", stream, "id", msg.ID)
+		f.ack(ctx, stream, msg.ID)
+		return
+	}
+
+	delivered, err := f.deliverAttempt(ctx, entry.Message, entry.Delivered, entry.Attempts+1)
+	entry.Delivered = append(entry.Delivered, delivered...)
+	if err == nil {
+		f.ack(ctx, stream, msg.ID)
+		return
+	}
+
+	entry.Attempts++
+	entry.LastError = err.Error()
+	f.log.Error("fanout failed", "tenant", entry.Message.TenantID, "attempts", entry.Attempts, "err", err)
+	if entry.Attempts >= maxDeliveryAttempts {
+		f.deadLetter(ctx, stream, msg.ID, entry)
+		return
+	}
+	if err := f.scheduleRetry(ctx, stream, msg.ID, entry); err != nil {
+		// Leave the original pending so it is reclaimed and retried later.
+		f.log.Error("failed to schedule outbound retry", "stream", stream, "id", msg.ID, "err", err)
+		return
+	}
+	f.ack(ctx, stream, msg.ID)
+}
+
+func (f *Fanout) deadLetter(ctx context.Context, stream, id string, entry outboundEntry) {
+	values, err := encodeOutboundEntry(entry)
+	if err != nil {
+		values = map[string]any{"last_error": entry.LastError}
+	}
+	values["source_stream"] = stream
+	values["source_id"] = id
+	if err := f.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: outboundDeadLetterStream,
+		MaxLen: outboundStreamMaxLen,
+		Approx: true,
+		Values: values,
+	}).Err(); err != nil {
+		f.log.Error("failed to dead-letter outbound entry", "stream", stream, "id", id, "err", err)
+		return
+	}
+	f.ack(ctx, stream, id)
+}
+
+func (f *Fanout) ack(ctx context.Context, stream, id string) {
+	if err := f.redis.XAck(ctx, stream, fanoutConsumerGroup, id).Err(); err != nil {
+		f.log.Error("failed to ack outbound entry", "stream", stream, "id", id, "err", err)
+	}
+}
+
+// Deliver sends out to the tenant's linked channels right away instead of
+// through the outbound stream, and returns the channels it reached.
+func (f *Fanout) Deliver(ctx context.Context, out OutboundMessage) ([]string, error) {
+	return f.deliver(ctx, out, nil)
+}
+
+// Send delivers out to one linked channel right away, without the outbound
+// filters. Unlike Deliver, a message that cannot be delivered, because the
+// channel's credentials or the recipient are missing, is an error rather than
+// a skip, so callers can report why nothing was sent.
+func (f *Fanout) Send(ctx context.Context, channel TenantChannel, out OutboundMessage) error {
+	if err := f.checkDeliverable(ctx, channel, out); err != nil {
+		return err
+	}
+	if f.DryRun {
+		f.recordDryRun(channel.Channel, out)
+		return nil
+	}
+	if _, err := f.dispatch(ctx, channel, out, 1); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkDeliverable reports why the built-in senders would skip out on
+// channel, or nil when they would attempt delivery.
+func (f *Fanout) checkDeliverable(ctx context.Context, channel TenantChannel, out OutboundMessage) error {
+	var required []string
+	switch channel.Channel {
+	case "telegram":
+		required = []string{"bot_token"}
+	case "whatsapp":
+		required = []string{"access_token", "phone_number_id"}
+	case "web":
+		return errors.New("web has no outbound delivery")
+	default:
+		if _, ok := f.senders[channel.Channel]; !ok {
+			return fmt.Errorf("%s delivery is not supported", channel.Channel)
 		}
+		return nil
+	}
 
-		if err := f.fanout(ctx, out); err != nil {
-			f.log.Error("fanout failed", "tenant", out.TenantID, "err", err)
+	if f.creds == nil {
+		return errors.New("credentials store is not configured")
+	}
+	cred, err := f.creds.GetByTenantChannel(ctx, channel.TenantID, channel.Channel)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s credentials are missing", channel.Channel)
+	}
+	if err != nil {
+		return fmt.Errorf("load credentials: %w", err)
+	}
+	for _, key := range required {
+		if strings.TrimSpace(cred.Config[key]) == "" {
+			return fmt.Errorf("%s credentials have no %s", channel.Channel, key)
 		}
 	}
+	if targetUserID(channel, out) == "" {
+		return fmt.Errorf("%s recipient is missing", channel.Channel)
+	}
+	return nil
 }
 
-func (f *Fanout) fanout(ctx context.Context, out OutboundMessage) error {
+// deliver sends out to every matching linked channel not already in skip and
+// returns the channels delivered to on this pass.
+func (f *Fanout) deliver(ctx context.Context, out OutboundMessage, skip []string) ([]string, error) {
+	return f.deliverAttempt(ctx, out, skip, 1)
+}
+
+// deliverAttempt is deliver for the given delivery attempt of a stream entry,
+// which is recorded in the delivery logs.
+func (f *Fanout) deliverAttempt(ctx context.Context, out OutboundMessage, skip []string, attempt int) ([]string, error) {
 	channels, err := f.links.GetChannels(out.TenantID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	out, err = f.filters.Apply(ctx, out)
+	if err != nil {
+		return nil, err
 	}
 
+	// Links identify the tenant's own bot or phone number, not the person
+	// being replied to, so only the channel narrows delivery. The recipient
+	// comes from the message metadata (see targetUserID).
 	targetChannel := strings.TrimSpace(out.Channel)
-	targetChannelUserID := ""
-	if out.Metadata != nil {
-		targetChannelUserID = strings.TrimSpace(out.Metadata["channel_user_id"])
-		if targetChannelUserID == "" {
-			targetChannelUserID = strings.TrimSpace(out.Metadata["user_id"])
-		}
-	}
 
+	var (
+		delivered []string
+		errs      []error
+	)
 	for _, channel := range channels {
 		if channel.Muted {
 			continue
@@ -93,36 +342,112 @@ func (f *Fanout) fanout(ctx context.Context, out OutboundMessage) error {
 		if targetChannel != "" && channel.Channel != targetChannel {
 			continue
 		}
-		if targetChannelUserID != "" && channel.ChannelUserID != "" && channel.ChannelUserID != targetChannelUserID {
+		if slices.Contains(skip, channel.Channel) {
+			continue
+		}
+
+		if f.DryRun && f.routes(channel.Channel) {
+			f.recordDryRun(channel.Channel, out)
+			delivered = append(delivered, channel.Channel)
+			continue
+		}
+
+		sent, sendErr := f.dispatch(ctx, channel, out, attempt)
+		if !sent {
+			continue
+		}
+		if sendErr != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", channel.Channel, sendErr))
 			continue
 		}
+		delivered = append(delivered, channel.Channel)
+	}
+
+	return delivered, errors.Join(errs...)
+}
 
-		switch channel.Channel {
-		case "web":
-			_ = FormatForWeb(out)
-		case "telegram":
-			payload := FormatForTelegram(out)
-			f.sendTelegram(ctx, channel, out, payload)
-		case "whatsapp":
-			payload := FormatForWhatsApp(out)
-			f.sendWhatsApp(ctx, channel, out, payload)
-		default:
+// dispatch sends out to one linked channel and logs the attempt with the
+// message's correlation id. It reports false when no sender handles the
+// channel.
+func (f *Fanout) dispatch(ctx context.Context, channel TenantChannel, out OutboundMessage, attempt int) (bool, error) {
+	start := time.Now()
+	var err error
+	switch channel.Channel {
+	case "web":
+		_ = FormatForWeb(out)
+	case "telegram":
+		err = f.sendTelegram(ctx, channel, out, FormatForTelegram(out))
+	case "whatsapp":
+		err = f.sendWhatsApp(ctx, channel, out, FormatForWhatsApp(out))
+	default:
+		sender, ok := f.senders[channel.Channel]
+		if !ok {
 			f.log.Warn("skip fanout for unknown channel", "tenant", out.TenantID, "channel", channel.Channel)
+			return false, nil
 		}
+		err = sender.Send(ctx, channel, out)
 	}
 
-	return nil
+	level, status := slog.LevelInfo, "sent"
+	attrs := []any{
+		"tenant_id", out.TenantID,
+		"channel", channel.Channel,
+		"correlation_id", out.Metadata[CorrelationIDKey],
+		"attempt", attempt,
+		"latency_ms", time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		level, status = slog.LevelWarn, "failed"
+		attrs = append(attrs, "err", err)
+	}
+	f.log.Log(ctx, level, "channel message delivery", append(attrs, "status", status)...)
+	return true, err
+}
+
+// routes reports whether the fanout has a sender for channel.
+func (f *Fanout) routes(channel string) bool {
+	switch channel {
+	case "web", "telegram", "whatsapp":
+		return true
+	}
+	_, ok := f.senders[channel]
+	return ok
 }
 
-func tenantIDFromTopic(topic string) string {
-	parts := strings.Split(topic, ":")
-	if len(parts) != 3 {
-		return ""
+// recordDryRun adds out, as it would have been sent to channel, to the
+// dry-run log, replacing the oldest entry once the log is full. The metadata
+// is copied and marked with dry_run=true.
+func (f *Fanout) recordDryRun(channel string, out OutboundMessage) {
+	metadata := make(map[string]string, len(out.Metadata)+1)
+	for k, v := range out.Metadata {
+		metadata[k] = v
 	}
-	if parts[0] != "tenant" || parts[2] != "response" {
-		return ""
+	metadata["dry_run"] = "true"
+	out.Channel = channel
+	out.Metadata = metadata
+
+	f.dryRunMu.Lock()
+	if len(f.dryRunLog) < dryRunLogSize {
+		f.dryRunLog = append(f.dryRunLog, out)
+	} else {
+		f.dryRunLog[f.dryRunNext] = out
+		f.dryRunNext = (f.dryRunNext + 1) % dryRunLogSize
 	}
-	return parts[1]
+	f.dryRunMu.Unlock()
+	f.log.Info("dry run fanout", "tenant", out.TenantID, "channel", channel)
+}
+
+// DrainDryRunLog returns the messages recorded in dry-run mode, oldest
+// first, and clears the log. Only the last dryRunLogSize are kept.
+func (f *Fanout) DrainDryRunLog() []OutboundMessage {
+	f.dryRunMu.Lock()
+	defer f.dryRunMu.Unlock()
+	drained := make([]OutboundMessage, 0, len(f.dryRunLog))
+	drained = append(drained, f.dryRunLog[f.dryRunNext:]...)
+	drained = append(drained, f.dryRunLog[:f.dryRunNext]...)
+	f.dryRunLog = nil
+	f.dryRunNext = 0
+	return drained
 }
 
 func FormatForWeb(msg OutboundMessage) string {
@@ -137,31 +462,34 @@ func FormatForWhatsApp(msg OutboundMessage) string {
 	return msg.Content
 }
 
-func (f *Fanout) sendTelegram(ctx context.Context, channel TenantChannel, out OutboundMessage, payload string) {
+// sendTelegram delivers payload via the Telegram Bot API. Missing credentials
+// or targets are logged and skipped; transport and API failures are returned
+// so the entry is retried.
+func (f *Fanout) sendTelegram(ctx context.Context, channel TenantChannel, out OutboundMessage, payload string) error {
 	if f.creds == nil {
 		f.log.Warn("skip telegram delivery: credentials store unavailable", "tenant", channel.TenantID)
-		return
+		return nil
 	}
 	cred, err := f.creds.GetByTenantChannel(ctx, channel.TenantID, "telegram")
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			f.log.Warn("skip telegram delivery: credentials missing", "tenant", channel.TenantID)
-			return
+			return nil
 		}
 		f.log.Error("failed loading telegram credentials", "tenant", channel.TenantID, "err", err)
-		return
+		return fmt.Errorf("load credentials: %w", err)
 	}
 
 	botToken := strings.TrimSpace(cred.Config["bot_token"])
 	if botToken == "" {
 		f.log.Warn("skip telegram delivery: bot token missing", "tenant", channel.TenantID)
-		return
+		return nil
 	}
 
 	chatID := targetUserID(channel, out)
 	if chatID == "" {
 		f.log.Warn("skip telegram delivery: target user missing", "tenant", channel.TenantID)
-		return
+		return nil
 	}
 
 	reqBody, _ := json.Marshal(map[string]string{
@@ -171,35 +499,45 @@ func (f *Fanout) sendTelegram(ctx context.Context, channel TenantChannel, out Ou
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", botToken), strings.NewReader(string(reqBody)))
 	if err != nil {
 		f.log.Error("build telegram request failed", "tenant", channel.TenantID, "err", err)
-		return
+		return fmt.Errorf("build request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := f.http.Do(req)
 	if err != nil {
+		// The request URL embeds the bot token; keep it out of logs and
+		// stream metadata.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
 		f.log.Error("telegram delivery failed", "tenant", channel.TenantID, "err", err)
-		return
+		return fmt.Errorf("send: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= http.StatusBadRequest {
 		f.log.Error("telegram delivery non-success status", "tenant", channel.TenantID, "status", resp.StatusCode)
+		return fmt.Errorf("telegram returned %d", resp.StatusCode)
 	}
+	return nil
 }
 
-func (f *Fanout) sendWhatsApp(ctx context.Context, channel TenantChannel, out OutboundMessage, payload string) {
+// sendWhatsApp delivers payload via the WhatsApp Cloud API with the same
+// skip/retry semantics as sendTelegram.
+func (f *Fanout) sendWhatsApp(ctx context.Context, channel TenantChannel, out OutboundMessage, payload string) error {
 	if f.creds == nil {
 		f.log.Warn("skip whatsapp delivery: credentials store unavailable", "tenant", channel.TenantID)
-		return
+		return nil
 	}
 	cred, err := f.creds.GetByTenantChannel(ctx, channel.TenantID, "whatsapp")
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			f.log.Warn("skip whatsapp delivery: credentials missing", "tenant", channel.TenantID)
-			return
+			return nil
 		}
 		f.log.Error("failed loading whatsapp credentials", "tenant", channel.TenantID, "err", err)
-		return
+		return fmt.Errorf("load credentials: %w", err)
 	}
 
 	accessToken := strings.TrimSpace(cred.Config["access_token"])
@@ -211,13 +549,13 @@ func (f *Fanout) sendWhatsApp(ctx context.Context, channel TenantChannel, out Ou
 
 	if accessToken == "" || phoneNumberID == "" {
 		f.log.Warn("skip whatsapp delivery: missing access token or phone number id", "tenant", channel.TenantID)
-		return
+		return nil
 	}
 
 	target := targetUserID(channel, out)
 	if target == "" {
 		f.log.Warn("skip whatsapp delivery: target user missing", "tenant", channel.TenantID)
-		return
+		return nil
 	}
 
 	reqBody, _ := json.Marshal(map[string]any{
@@ -232,7 +570,7 @@ func (f *Fanout) sendWhatsApp(ctx context.Context, channel TenantChannel, out Ou
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(string(reqBody)))
 	if err != nil {
 		f.log.Error("build whatsapp request failed", "tenant", channel.TenantID, "err", err)
-		return
+		return fmt.Errorf("build request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+accessToken)
@@ -240,13 +578,15 @@ func (f *Fanout) sendWhatsApp(ctx context.Context, channel TenantChannel, out Ou
 	resp, err := f.http.Do(req)
 	if err != nil {
 		f.log.Error("whatsapp delivery failed", "tenant", channel.TenantID, "err", err)
-		return
+		return fmt.Errorf("send: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= http.StatusBadRequest {
 		f.log.Error("whatsapp delivery non-success status", "tenant", channel.TenantID, "status", resp.StatusCode)
+		return fmt.Errorf("whatsapp returned %d", resp.StatusCode)
 	}
+	return nil
 }
 
 func targetUserID(channel TenantChannel, out OutboundMessage) string {
@@ -0,0 +1,43 @@
+package channels_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/agentsquads/api/channels"
+	"github.com/agentsquads/api/internal/testkit"
+)
+
+func TestFanoutDeliverWithMemoryStores(t *testing.T) {
+	t.Parallel()
+	links := testkit.NewMemoryLinks()
+	links.Link("t1", "viber", "acct")
+	links.Link("t1", "line", "acct")
+	links.Link("t2", "viber", "other")
+
+	viber := &testkit.FakeSender{}
+	line := &testkit.FakeSender{}
+	f := channels.NewFanout(nil, links, testkit.NewMemoryCredentials())
+	f.RegisterSender("viber", viber)
+	f.RegisterSender("line", line)
+
+	delivered, err := f.Deliver(context.Background(), channels.OutboundMessage{TenantID: "t1", Content: "hello"})
+	if err != nil || len(delivered) != 2 {
+		t.Fatalf("Deliver = %v, %v", delivered, err)
+	}
+	if sent := viber.Sent(); len(sent) != 1 || sent[0].Content != "hello" || sent[0].TenantID != "t1" {
+		t.Fatalf("viber sent = %+v", sent)
+	}
+
+	// Muted links are skipped and sender failures are reported.
+	links.Mute("t1", "line", true)
+	viber.Err = errors.New("viber down")
+	delivered, err = f.Deliver(context.Background(), channels.OutboundMessage{TenantID: "t1", Content: "again"})
+	if err == nil || len(delivered) != 0 {
+		t.Fatalf("Deliver after mute = %v, %v", delivered, err)
+	}
+	if len(line.Sent()) != 1 {
+		t.Fatalf("muted line link received %d messages", len(line.Sent()))
+	}
+}
@@ -0,0 +1,105 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// outboundRetryKey is a sorted set of failed outbound entries scored by
+	// the unix millisecond time of their next attempt.
+	outboundRetryKey    = "channels:outbound:retry"
+	fanoutRetryBase     = 5 * time.Second
+	fanoutRetryMax      = 5 * time.Minute
+	fanoutRetryInterval = time.Second
+	fanoutRetryBatch    = 64
+)
+
+// promoteRetriesScript moves due retries back onto their streams. Claiming
+// and re-adding happen in one script so replicas never promote an entry
+// twice and an instance dying in between cannot lose it.
+var promoteRetriesScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+for _, member in ipairs(due) do
+  local retry = cjson.decode(member)
+  local fields = {}
+  for k, v in pairs(retry.values) do
+    fields[#fields + 1] = k
+    fields[#fields + 1] = v
+  end
+  redis.call('XADD', retry.stream, 'MAXLEN', '~', ARGV[3], '*', unpack(fields))
+  redis.call('ZREM', KEYS[1], member)
+end
+return #due
+`)
+
+// fanoutRetryDelay is how long an entry waits after its nth failed attempt:
+// 5s, doubling each time, capped at five minutes.
+func fanoutRetryDelay(attempts int) time.Duration {
+	delay := fanoutRetryBase
+	for i := 1; i < attempts && delay < fanoutRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, fanoutRetryMax)
+}
+
+// outboundRetry is a member of the retry set: the stream an entry goes back
+// to and its encoded fields. sourceID keeps members of identical messages
+// apart.
+type outboundRetry struct {
+	Stream   string            `json:"stream"`
+	SourceID string            `json:"source_id"`
+	Values   map[string]string `json:"values"`
+}
+
+func encodeOutboundRetry(stream, sourceID string, entry outboundEntry) (string, error) {
+	values, err := encodeOutboundEntry(entry)
+	if err != nil {
+		return "", err
+	}
+	retry := outboundRetry{Stream: stream, SourceID: sourceID, Values: make(map[string]string, len(values))}
+	for k, v := range values {
+		retry.Values[k] = fmt.Sprint(v)
+	}
+	member, err := json.Marshal(retry)
+	if err != nil {
+		return "", fmt.Errorf("marshal outbound retry: %w", err)
+	}
+	return string(member), nil
+}
+
+// scheduleRetry puts entry in the retry set, due once its backoff has
+// passed.
+func (f *Fanout) scheduleRetry(ctx context.Context, stream, sourceID string, entry outboundEntry) error {
+	member, err := encodeOutboundRetry(stream, sourceID, entry)
+	if err != nil {
+		return err
+	}
+	due := time.Now().Add(fanoutRetryDelay(entry.Attempts))
+	if err := f.redis.ZAdd(ctx, outboundRetryKey, redis.Z{Score: float64(due.UnixMilli()), Member: member}).Err(); err != nil {
+		return fmt.Errorf("schedule outbound retry: %w", err)
+	}
+	return nil
+}
+
+// promoteRetries re-adds every due retry to its stream.
+func (f *Fanout) promoteRetries(ctx context.Context) {
+	for {
+		n, err := promoteRetriesScript.Run(ctx, f.redis, []string{outboundRetryKey},
+			strconv.FormatInt(time.Now().UnixMilli(), 10), fanoutRetryBatch, outboundStreamMaxLen).Int()
+		if err != nil {
+			if ctx.Err() == nil {
+				f.log.Error("failed to promote outbound retries", "err", err)
+			}
+			return
+		}
+		if n < fanoutRetryBatch {
+			return
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package channels
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestFanoutRetryDelay(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: 5 * time.Second},
+		{attempts: 1, want: 5 * time.Second},
+		{attempts: 2, want: 10 * time.Second},
+		{attempts: 4, want: 40 * time.Second},
+		{attempts: 7, want: 5 * time.Minute},
+		{attempts: 100, want: 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := fanoutRetryDelay(tt.attempts); got != tt.want {
+			t.Fatalf("fanoutRetryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestOutboundRetryRoundTrip(t *testing.T) {
+	t.Parallel()
+	in := outboundEntry{
+		Message:   OutboundMessage{TenantID: "t1", Content: "hi", Channel: "telegram"},
+		Attempts:  1,
+		LastError: "telegram: telegram returned 502",
+	}
+	member, err := encodeOutboundRetry("channels:outbound:3", "1-0", in)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	var retry outboundRetry
+	if err := json.Unmarshal([]byte(member), &retry); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if retry.Stream != "channels:outbound:3" || retry.SourceID != "1-0" {
+		t.Fatalf("retry = %+v", retry)
+	}
+
+	// The promote script re-adds the values as stream fields, which are
+	// read back as strings.
+	values := make(map[string]any, len(retry.Values))
+	for k, v := range retry.Values {
+		values[k] = v
+	}
+	out, err := decodeOutboundEntry(values)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.Message.TenantID != "t1" || out.Attempts != 1 || out.LastError != in.LastError {
+		t.Fatalf("round trip mismatch: %+v", out)
+	}
+
+	other, _ := encodeOutboundRetry("channels:outbound:3", "2-0", in)
+	if other == member {
+		t.Fatalf("retries of identical entries must be distinct members")
+	}
+}
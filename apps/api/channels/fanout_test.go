@@ -1,9 +1,14 @@
 package channels
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -45,15 +50,15 @@ func TestFanoutFanoutFiltering(t *testing.T) {
 		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok")), Header: make(http.Header)}, nil
 	})}
 
-	rows := sqlmock.NewRows([]string{"id", "tenant_id", "channel", "channel_user_id", "linked_at", "muted"}).
-		AddRow("1", "t1", "telegram", "u1", time.Now(), false).
-		AddRow("2", "t1", "whatsapp", "u2", time.Now(), true)
+	rows := sqlmock.NewRows([]string{"id", "tenant_id", "channel", "channel_user_id", "linked_at", "muted", "notification_level"}).
+		AddRow("1", "t1", "telegram", "u1", time.Now(), false, "milestones").
+		AddRow("2", "t1", "whatsapp", "u2", time.Now(), true, "milestones")
 	mock.ExpectQuery("SELECT id, tenant_id, channel").WithArgs("t1").WillReturnRows(rows)
 
 	tgCredRows := sqlmock.NewRows([]string{"tenant_id", "channel", "config", "updated_at"}).AddRow("t1", "telegram", `{"bot_token":"tok"}`, time.Now())
 	mock.ExpectQuery("SELECT tenant_id, channel, config::text").WithArgs("t1", "telegram").WillReturnRows(tgCredRows)
 
-	if err := f.fanout(context.Background(), OutboundMessage{TenantID: "t1", Content: "hello", Channel: "telegram", Metadata: map[string]string{"user_id": "u1"}}); err != nil {
+	if _, err := f.Deliver(context.Background(), OutboundMessage{TenantID: "t1", Content: "hello", Channel: "telegram", Metadata: map[string]string{"user_id": "u1"}}); err != nil {
 		t.Fatalf("fanout: %v", err)
 	}
 	if sentTelegram != 1 {
@@ -64,27 +69,6 @@ func TestFanoutFanoutFiltering(t *testing.T) {
 	}
 }
 
-func TestTenantIDFromTopic(t *testing.T) {
-	t.Parallel()
-	tests := []struct {
-		topic string
-		want  string
-	}{
-		{topic: "tenant:t1:response", want: "t1"},
-		{topic: "tenant:t1:bad", want: ""},
-		{topic: "bad", want: ""},
-	}
-	for _, tt := range tests {
-		tt := tt
-		t.Run(tt.topic, func(t *testing.T) {
-			t.Parallel()
-			if got := tenantIDFromTopic(tt.topic); got != tt.want {
-				t.Fatalf("tenantIDFromTopic=%q want %q", got, tt.want)
-			}
-		})
-	}
-}
-
 func TestFormatters(t *testing.T) {
 	t.Parallel()
 	msg := OutboundMessage{Content: "x"}
@@ -92,3 +76,190 @@ func TestFormatters(t *testing.T) {
 		t.Fatalf("unexpected formatter output")
 	}
 }
+
+type senderFunc func(context.Context, TenantChannel, OutboundMessage) error
+
+func (f senderFunc) Send(ctx context.Context, channel TenantChannel, out OutboundMessage) error {
+	return f(ctx, channel, out)
+}
+
+func TestFanoutRegisteredSender(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	f := NewFanout(nil, NewLinkStore(db), NewCredentialsStore(db))
+	var got []string
+	f.RegisterSender("viber", senderFunc(func(_ context.Context, channel TenantChannel, out OutboundMessage) error {
+		got = append(got, channel.Channel+":"+out.Content)
+		return nil
+	}))
+
+	rows := sqlmock.NewRows([]string{"id", "tenant_id", "channel", "channel_user_id", "linked_at", "muted", "notification_level"}).
+		AddRow("1", "t1", "viber", "acct", time.Now(), false, "milestones").
+		AddRow("2", "t1", "line", "acct", time.Now(), false, "milestones")
+	mock.ExpectQuery("SELECT id, tenant_id, channel").WithArgs("t1").WillReturnRows(rows)
+
+	if _, err := f.Deliver(context.Background(), OutboundMessage{TenantID: "t1", Content: "hello"}); err != nil {
+		t.Fatalf("fanout: %v", err)
+	}
+	if len(got) != 1 || got[0] != "viber:hello" {
+		t.Fatalf("registered sender calls = %v", got)
+	}
+}
+
+func TestFanoutSendReportsUndeliverable(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	f := NewFanout(nil, NewLinkStore(db), NewCredentialsStore(db))
+	var sent int
+	f.http = &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		sent++
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok")), Header: make(http.Header)}, nil
+	})}
+	link := TenantChannel{TenantID: "t1", Channel: "telegram", ChannelUserID: "bot"}
+	credRows := func(config string) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"tenant_id", "channel", "config", "updated_at"}).AddRow("t1", "telegram", config, time.Now())
+	}
+
+	mock.ExpectQuery("SELECT tenant_id, channel, config::text").WithArgs("t1", "telegram").WillReturnRows(credRows(`{}`))
+	if err := f.Send(context.Background(), link, OutboundMessage{TenantID: "t1", Content: "hi"}); err == nil || !strings.Contains(err.Error(), "bot_token") {
+		t.Fatalf("Send without bot token err = %v", err)
+	}
+
+	mock.ExpectQuery("SELECT tenant_id, channel, config::text").WithArgs("t1", "telegram").WillReturnRows(credRows(`{"bot_token":"tok"}`))
+	mock.ExpectQuery("SELECT tenant_id, channel, config::text").WithArgs("t1", "telegram").WillReturnRows(credRows(`{"bot_token":"tok"}`))
+	out := OutboundMessage{TenantID: "t1", Content: "hi", Metadata: map[string]string{"channel_user_id": "chat-1"}}
+	if err := f.Send(context.Background(), link, out); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if sent != 1 {
+		t.Fatalf("telegram requests = %d, want 1", sent)
+	}
+
+	if err := f.Send(context.Background(), TenantChannel{TenantID: "t1", Channel: "line"}, out); err == nil {
+		t.Fatal("expected an error for a channel without a sender")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestFanoutLogsDeliveryAttempts(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	var logs bytes.Buffer
+	f := NewFanout(nil, NewLinkStore(db), NewCredentialsStore(db))
+	f.log = slog.New(slog.NewJSONHandler(&logs, nil))
+	f.RegisterSender("viber", senderFunc(func(context.Context, TenantChannel, OutboundMessage) error {
+		return errors.New("viber down")
+	}))
+
+	mock.ExpectQuery("SELECT id, tenant_id, channel").WithArgs("t1").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "tenant_id", "channel", "channel_user_id", "linked_at", "muted", "notification_level"}).
+			AddRow("1", "t1", "web", "", time.Now(), false, "milestones").
+			AddRow("2", "t1", "viber", "acct", time.Now(), false, "milestones"))
+	out := OutboundMessage{TenantID: "t1", Content: "hello", Metadata: map[string]string{CorrelationIDKey: "corr-1"}}
+	if _, err := f.deliverAttempt(context.Background(), out, nil, 2); err == nil {
+		t.Fatal("expected viber failure")
+	}
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("decode log %q: %v", line, err)
+		}
+		records = append(records, rec)
+	}
+	if len(records) != 2 {
+		t.Fatalf("log records = %v", records)
+	}
+	for i, want := range []struct{ channel, status string }{{"web", "sent"}, {"viber", "failed"}} {
+		rec := records[i]
+		if rec["channel"] != want.channel || rec["status"] != want.status || rec["correlation_id"] != "corr-1" ||
+			rec["tenant_id"] != "t1" || rec["attempt"] != float64(2) || rec["latency_ms"] == nil {
+			t.Fatalf("record %d = %v", i, rec)
+		}
+	}
+}
+
+func TestFanoutDryRun(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	f := NewFanout(nil, NewLinkStore(db), NewCredentialsStore(db))
+	f.DryRun = true
+	f.http = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Fatalf("unexpected request in dry run: %s", req.URL)
+		return nil, nil
+	})}
+	var sent int
+	f.RegisterSender("viber", senderFunc(func(context.Context, TenantChannel, OutboundMessage) error {
+		sent++
+		return nil
+	}))
+
+	rows := sqlmock.NewRows([]string{"id", "tenant_id", "channel", "channel_user_id", "linked_at", "muted", "notification_level"}).
+		AddRow("1", "t1", "telegram", "u1", time.Now(), false, "milestones").
+		AddRow("2", "t1", "viber", "acct", time.Now(), false, "milestones").
+		AddRow("3", "t1", "whatsapp", "u2", time.Now(), true, "milestones").
+		AddRow("4", "t1", "line", "acct", time.Now(), false, "milestones")
+	mock.ExpectQuery("SELECT id, tenant_id, channel").WithArgs("t1").WillReturnRows(rows)
+
+	metadata := map[string]string{"user_id": "u1"}
+	if _, err := f.Deliver(context.Background(), OutboundMessage{TenantID: "t1", Content: "hello", Metadata: metadata}); err != nil {
+		t.Fatalf("fanout: %v", err)
+	}
+	if sent != 0 {
+		t.Fatalf("registered sender called %d times in dry run", sent)
+	}
+	log := f.DrainDryRunLog()
+	if len(log) != 2 || log[0].Channel != "telegram" || log[1].Channel != "viber" {
+		t.Fatalf("dry run log = %+v", log)
+	}
+	if log[0].Content != "hello" || log[0].Metadata["dry_run"] != "true" || log[0].Metadata["user_id"] != "u1" {
+		t.Fatalf("unexpected dry run entry: %+v", log[0])
+	}
+	if _, ok := metadata["dry_run"]; ok {
+		t.Fatalf("dry run mutated the caller's metadata")
+	}
+	if again := f.DrainDryRunLog(); len(again) != 0 {
+		t.Fatalf("log not drained: %+v", again)
+	}
+
+	// Test sends are recorded, not delivered.
+	viber := TenantChannel{TenantID: "t1", Channel: "viber", ChannelUserID: "acct"}
+	if err := f.Send(context.Background(), viber, OutboundMessage{TenantID: "t1", Content: "test"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if log := f.DrainDryRunLog(); sent != 0 || len(log) != 1 || log[0].Channel != "viber" || log[0].Content != "test" {
+		t.Fatalf("dry run Send delivered %d, logged %+v", sent, log)
+	}
+
+	// Only the most recent messages are kept.
+	for i := range dryRunLogSize + 5 {
+		f.recordDryRun("telegram", OutboundMessage{TenantID: "t1", Content: strconv.Itoa(i)})
+	}
+	log = f.DrainDryRunLog()
+	if len(log) != dryRunLogSize || log[0].Content != "5" || log[len(log)-1].Content != strconv.Itoa(dryRunLogSize+4) {
+		t.Fatalf("capped log has %d entries from %q to %q", len(log), log[0].Content, log[len(log)-1].Content)
+	}
+}
@@ -0,0 +1,467 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	inboundStreamPrefix  = "channels:inbound:"
+	inboundStreamShards  = 8
+	inboundConsumerGroup = "inbound"
+	inboundReadBlock     = 5 * time.Second
+	inboundReadBatch     = 32
+	// inboundShardChats is how many chats of one shard are routed at once.
+	inboundShardChats     = 16
+	inboundLeaseTTL       = 30 * time.Second
+	inboundLeaseRetry     = 5 * time.Second
+	inboundDepthInterval  = 15 * time.Second
+	defaultInboundTimeout = 5 * time.Minute
+)
+
+// inboundQueueStats counts queued webhook messages and tracks the queue
+// depth. It is published through expvar at /debug/vars.
+var inboundQueueStats = expvar.NewMap("channel_inbound_queue")
+
+// renewLease extends a shard lease only while this consumer still holds it,
+// and releaseLease deletes it under the same condition.
+var (
+	renewLease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	releaseLease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// InboundEntry is a webhook message waiting to be routed. Webhook and Headers
+// are the provider payload it was parsed from and the request's sanitized
+// headers, so a routing failure can be recorded for replay.
+type InboundEntry struct {
+	Message InboundMessage    `json:"message"`
+	Webhook []byte            `json:"webhook,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// InboundQueue lets webhooks acknowledge the provider right away and route
+// their messages in the background. Messages are sharded by chat across a
+// fixed set of Redis streams, and each shard is consumed by one worker at a
+// time across all replicas, holding a lease. The holder routes up to
+// inboundShardChats chats of its shard at once, so a slow tenant does not
+// hold up the others, while a chat's messages are routed one after another
+// in the order they arrived. Streams are never trimmed, so no message is lost
+// to a backlog; entries are deleted once routed, so a stream's length is its
+// backlog, published as the depth stat.
+type InboundQueue struct {
+	redis     *redis.Client
+	route     func(context.Context, InboundMessage) (OutboundMessage, error)
+	onFailure func(context.Context, InboundEntry, error)
+	timeout   time.Duration
+	consumer  string
+	log       *slog.Logger
+}
+
+// NewInboundQueue creates a queue whose workers call route for each message.
+func NewInboundQueue(redisClient *redis.Client, route func(context.Context, InboundMessage) (OutboundMessage, error)) *InboundQueue {
+	return &InboundQueue{
+		redis:    redisClient,
+		route:    route,
+		timeout:  defaultInboundTimeout,
+		consumer: fanoutConsumerName(),
+		log:      slog.Default().With("component", "channels.inbound"),
+	}
+}
+
+// SetFailureHandler makes the workers call fn for messages whose routing
+// failed. Messages of disabled channels are not failures. Call it before
+// Start.
+func (q *InboundQueue) SetFailureHandler(fn func(context.Context, InboundEntry, error)) {
+	q.onFailure = fn
+}
+
+// SetRouteTimeout bounds how long a worker waits for one message to be
+// routed. A cold tenant container or a swarm run can take a while, so the
+// default is defaultInboundTimeout.
+func (q *InboundQueue) SetRouteTimeout(d time.Duration) {
+	if d > 0 {
+		q.timeout = d
+	}
+}
+
+// inboundChatKey identifies the chat a message belongs to.
+func inboundChatKey(msg InboundMessage) string {
+	return msg.TenantID + "\x00" + msg.Channel + "\x00" + msg.Metadata["channel_user_id"]
+}
+
+// inboundStreamKey shards chats across the inbound streams.
+func inboundStreamKey(msg InboundMessage) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(inboundChatKey(msg)))
+	return fmt.Sprintf("%s%d", inboundStreamPrefix, h.Sum32()%inboundStreamShards)
+}
+
+func inboundStreamKeys() []string {
+	keys := make([]string, inboundStreamShards)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%s%d", inboundStreamPrefix, i)
+	}
+	return keys
+}
+
+func inboundLeaseKey(stream string) string {
+	return stream + ":lease"
+}
+
+// Enqueue adds entry to its chat's stream. Callers route the message
+// themselves when it fails, e.g. because Redis is down.
+func (q *InboundQueue) Enqueue(ctx context.Context, entry InboundEntry) error {
+	if q.redis == nil {
+		return errors.New("redis is not configured")
+	}
+	if strings.TrimSpace(entry.Message.TenantID) == "" {
+		return errors.New("tenant id is required")
+	}
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal inbound entry: %w", err)
+	}
+	if err := q.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: inboundStreamKey(entry.Message),
+		Values: map[string]any{"payload": string(payload)},
+	}).Err(); err != nil {
+		return fmt.Errorf("enqueue inbound message: %w", err)
+	}
+	inboundQueueStats.Add("enqueued", 1)
+	return nil
+}
+
+// Start runs a worker per shard and samples the queue depth until ctx is
+// cancelled.
+func (q *InboundQueue) Start(ctx context.Context) error {
+	if q.redis == nil {
+		return errors.New("redis is not configured")
+	}
+	streams := inboundStreamKeys()
+	for _, stream := range streams {
+		err := q.redis.XGroupCreateMkStream(ctx, stream, inboundConsumerGroup, "0").Err()
+		if err != nil && !strings.Contains(err.Error(), "BUSYGROUP") {
+			return fmt.Errorf("create consumer group for %s: %w", stream, err)
+		}
+	}
+	q.log.Info("inbound queue started", "consumer", q.consumer, "streams", len(streams))
+
+	var wg sync.WaitGroup
+	for _, stream := range streams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx, stream)
+		}()
+	}
+	q.sampleDepth(ctx, streams)
+	wg.Wait()
+	return nil
+}
+
+// sampleDepth publishes the number of messages waiting across all shards as
+// the depth stat.
+func (q *InboundQueue) sampleDepth(ctx context.Context, streams []string) {
+	depth := new(expvar.Int)
+	inboundQueueStats.Set("depth", depth)
+	ticker := time.NewTicker(inboundDepthInterval)
+	defer ticker.Stop()
+	for {
+		var total int64
+		for _, stream := range streams {
+			n, err := q.redis.XLen(ctx, stream).Result()
+			if err != nil {
+				if ctx.Err() == nil {
+					q.log.Warn("failed to read inbound queue depth", "stream", stream, "err", err)
+				}
+				continue
+			}
+			total += n
+		}
+		depth.Set(total)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// work consumes stream whenever this consumer holds its lease.
+func (q *InboundQueue) work(ctx context.Context, stream string) {
+	lease := inboundLeaseKey(stream)
+	for ctx.Err() == nil {
+		held, err := q.redis.SetNX(ctx, lease, q.consumer, inboundLeaseTTL).Result()
+		if err != nil && ctx.Err() == nil {
+			q.log.Warn("failed to acquire inbound shard lease", "stream", stream, "err", err)
+		}
+		if held {
+			q.consume(ctx, stream, lease)
+			if err := releaseLease.Run(context.WithoutCancel(ctx), q.redis, []string{lease}, q.consumer).Err(); err != nil {
+				q.log.Warn("failed to release inbound shard lease", "stream", stream, "err", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(inboundLeaseRetry):
+		}
+	}
+}
+
+// consume routes the stream's entries while the lease is renewed in the
+// background. Entries a previous holder read but never finished are routed
+// first; losing the lease cancels the messages in flight, which the next
+// holder then routes again. It returns once every message it started has
+// finished.
+func (q *InboundQueue) consume(ctx context.Context, stream, lease string) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		ticker := time.NewTicker(inboundLeaseTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			renewed, err := renewLease.Run(ctx, q.redis, []string{lease}, q.consumer, inboundLeaseTTL.Milliseconds()).Int()
+			if err != nil || renewed == 0 {
+				if ctx.Err() == nil {
+					q.log.Warn("lost inbound shard lease", "stream", stream, "err", err)
+				}
+				cancel()
+				return
+			}
+		}
+	}()
+
+	if err := q.claimPending(ctx, stream); err != nil {
+		if ctx.Err() == nil {
+			q.log.Error("failed to claim pending inbound entries", "stream", stream, "err", err)
+		}
+		return
+	}
+	chats := newChatDispatcher(inboundShardChats)
+	defer chats.wait()
+	dispatch := func(msgs []redis.XMessage) {
+		for _, msg := range msgs {
+			entry, err := decodeInboundEntry(msg)
+			if err != nil {
+				q.log.Error("dropping undecodable inbound entry", "stream", stream, "id", msg.ID, "err", err)
+				q.remove(ctx, stream, msg.ID)
+				continue
+			}
+			if !chats.dispatch(ctx, inboundChatKey(entry.Message), func() { q.handle(ctx, stream, msg.ID, entry) }) {
+				return
+			}
+		}
+	}
+
+	// Entries read before are only read from the pending list once, as the
+	// ones still pending later are in flight here.
+	for after := "0"; ctx.Err() == nil; {
+		msgs, err := q.read(ctx, stream, after, -1)
+		if err != nil {
+			if ctx.Err() == nil {
+				q.log.Error("failed to read pending inbound entries", "stream", stream, "err", err)
+			}
+			return
+		}
+		if len(msgs) == 0 {
+			break
+		}
+		dispatch(msgs)
+		after = msgs[len(msgs)-1].ID
+	}
+	for ctx.Err() == nil {
+		msgs, err := q.read(ctx, stream, ">", inboundReadBlock)
+		if err != nil {
+			if ctx.Err() == nil {
+				q.log.Error("failed to read inbound stream", "stream", stream, "err", err)
+			}
+			return
+		}
+		dispatch(msgs)
+	}
+}
+
+// claimPending moves every entry pending in the consumer group to this
+// consumer. Only the lease holder reads the stream, so pending entries
+// belong to a holder that is gone.
+func (q *InboundQueue) claimPending(ctx context.Context, stream string) error {
+	start := "0-0"
+	for {
+		msgs, next, err := q.redis.XAutoClaimJustID(ctx, &redis.XAutoClaimArgs{
+			Stream:   stream,
+			Group:    inboundConsumerGroup,
+			Consumer: q.consumer,
+			Start:    start,
+			Count:    100,
+		}).Result()
+		if err != nil {
+			return err
+		}
+		if next == "0-0" || len(msgs) == 0 {
+			return nil
+		}
+		start = next
+	}
+}
+
+// read returns up to inboundReadBatch entries: this consumer's pending
+// entries after id, or with id ">" new entries, waiting up to block for them.
+func (q *InboundQueue) read(ctx context.Context, stream, id string, block time.Duration) ([]redis.XMessage, error) {
+	results, err := q.redis.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    inboundConsumerGroup,
+		Consumer: q.consumer,
+		Streams:  []string{stream, id},
+		Count:    inboundReadBatch,
+		Block:    block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var msgs []redis.XMessage
+	for _, result := range results {
+		msgs = append(msgs, result.Messages...)
+	}
+	return msgs, nil
+}
+
+func decodeInboundEntry(msg redis.XMessage) (InboundEntry, error) {
+	var entry InboundEntry
+	payload, _ := msg.Values["payload"].(string)
+	err := json.Unmarshal([]byte(payload), &entry)
+	return entry, err
+}
+
+// handle routes one entry and removes it from the stream. Routing failures
+// go to the failure handler rather than being retried, as the synchronous
+// webhook path records them for replay too.
+func (q *InboundQueue) handle(ctx context.Context, stream, id string, entry InboundEntry) {
+	if !q.process(ctx, entry) && ctx.Err() != nil {
+		// Interrupted: leave the entry pending for the next lease holder.
+		return
+	}
+	q.remove(ctx, stream, id)
+}
+
+func (q *InboundQueue) remove(ctx context.Context, stream, id string) {
+	pipe := q.redis.TxPipeline()
+	pipe.XAck(ctx, stream, inboundConsumerGroup, id)
+	pipe.XDel(ctx, stream, id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		q.log.Error("failed to remove routed inbound entry", "stream", stream, "id", id, "err", err)
+	}
+}
+
+// chatDispatcher runs the work of different chats concurrently, up to a
+// limit, and the work of one chat in the order it was dispatched.
+type chatDispatcher struct {
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	mu sync.Mutex
+	// queued holds the work waiting behind each chat that has work running.
+	queued map[string][]func()
+}
+
+func newChatDispatcher(limit int) *chatDispatcher {
+	return &chatDispatcher{slots: make(chan struct{}, limit), queued: make(map[string][]func())}
+}
+
+// dispatch runs fn after the chat's earlier work. When the limit is reached
+// it waits for a free slot, and returns false if ctx ends first, with fn not
+// run.
+func (d *chatDispatcher) dispatch(ctx context.Context, chat string, fn func()) bool {
+	d.mu.Lock()
+	if queued, running := d.queued[chat]; running {
+		d.queued[chat] = append(queued, fn)
+		d.mu.Unlock()
+		return true
+	}
+	d.queued[chat] = nil
+	d.mu.Unlock()
+
+	select {
+	case d.slots <- struct{}{}:
+	case <-ctx.Done():
+		d.mu.Lock()
+		delete(d.queued, chat)
+		d.mu.Unlock()
+		return false
+	}
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer func() { <-d.slots }()
+		for {
+			fn()
+			d.mu.Lock()
+			queued := d.queued[chat]
+			if len(queued) == 0 {
+				delete(d.queued, chat)
+				d.mu.Unlock()
+				return
+			}
+			fn, d.queued[chat] = queued[0], queued[1:]
+			d.mu.Unlock()
+		}
+	}()
+	return true
+}
+
+// wait blocks until all dispatched work has run.
+func (d *chatDispatcher) wait() {
+	d.wg.Wait()
+}
+
+// process routes entry within the route timeout and reports whether it was
+// routed.
+func (q *InboundQueue) process(ctx context.Context, entry InboundEntry) bool {
+	routeCtx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+	start := time.Now()
+	_, err := q.route(routeCtx, entry.Message)
+	if err == nil || errors.Is(err, ErrChannelDisabled) || errors.Is(err, ErrMessageModerated) {
+		inboundQueueStats.Add("routed", 1)
+		return true
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+
+	inboundQueueStats.Add("failed", 1)
+	q.log.Warn("queued inbound message failed",
+		"tenant_id", entry.Message.TenantID,
+		"channel", entry.Message.Channel,
+		"latency_ms", time.Since(start).Milliseconds(),
+		"err", err)
+	if q.onFailure != nil {
+		q.onFailure(context.WithoutCancel(ctx), entry, err)
+	}
+	return false
+}
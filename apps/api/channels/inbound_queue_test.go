@@ -0,0 +1,117 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInboundStreamKeyKeepsChatsTogether(t *testing.T) {
+	t.Parallel()
+	chat := func(tenant, channel, user string) InboundMessage {
+		return InboundMessage{TenantID: tenant, Channel: channel, Metadata: map[string]string{"channel_user_id": user}}
+	}
+	if inboundStreamKey(chat("t1", "telegram", "42")) != inboundStreamKey(chat("t1", "telegram", "42")) {
+		t.Fatal("one chat's messages were sharded to different streams")
+	}
+	shards := map[string]bool{}
+	for _, user := range []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"} {
+		shards[inboundStreamKey(chat("t1", "telegram", user))] = true
+	}
+	if len(shards) < 2 {
+		t.Fatalf("chats of one tenant all landed on %v", shards)
+	}
+}
+
+func TestInboundQueueEnqueueWithoutRedis(t *testing.T) {
+	t.Parallel()
+	q := NewInboundQueue(nil, nil)
+	if err := q.Enqueue(context.Background(), InboundEntry{Message: InboundMessage{TenantID: "t1"}}); err == nil {
+		t.Fatal("expected an error without redis")
+	}
+	if err := q.Start(context.Background()); err == nil {
+		t.Fatal("expected Start to fail without redis")
+	}
+}
+
+func TestInboundQueueProcess(t *testing.T) {
+	t.Parallel()
+	var routeErr error
+	q := NewInboundQueue(nil, func(ctx context.Context, msg InboundMessage) (OutboundMessage, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("route called without a timeout")
+		}
+		return OutboundMessage{}, routeErr
+	})
+	var failures []error
+	q.SetFailureHandler(func(_ context.Context, entry InboundEntry, err error) {
+		if string(entry.Webhook) != "{}" {
+			t.Errorf("failure entry webhook = %q", entry.Webhook)
+		}
+		failures = append(failures, err)
+	})
+	entry := InboundEntry{Message: InboundMessage{TenantID: "t1", Channel: "telegram"}, Webhook: []byte("{}")}
+
+	if !q.process(context.Background(), entry) {
+		t.Fatal("routed message reported as failed")
+	}
+	routeErr = ErrChannelDisabled
+	if !q.process(context.Background(), entry) || len(failures) != 0 {
+		t.Fatalf("disabled channel treated as a failure: %v", failures)
+	}
+	routeErr = errors.New("container unreachable")
+	if q.process(context.Background(), entry) || len(failures) != 1 || failures[0] != routeErr {
+		t.Fatalf("failures = %v", failures)
+	}
+
+	// A cancelled worker leaves the message for the next one.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if q.process(ctx, entry) || len(failures) != 1 {
+		t.Fatalf("interrupted message recorded as a failure: %v", failures)
+	}
+}
+
+func TestChatDispatcherRunsChatsConcurrentlyAndInOrder(t *testing.T) {
+	t.Parallel()
+	d := newChatDispatcher(2)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var order []string
+	record := func(s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, s)
+	}
+	slow := make(chan struct{})
+	d.dispatch(ctx, "a", func() { <-slow; record("a1") })
+	d.dispatch(ctx, "a", func() { record("a2") })
+
+	// A slow chat does not hold up another one.
+	done := make(chan struct{})
+	d.dispatch(ctx, "b", func() { record("b1"); close(done) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("chat b waited for chat a")
+	}
+
+	// Both slots are taken until a finishes, so c waits for a slot.
+	blocked := make(chan struct{})
+	d.dispatch(ctx, "b", func() { <-blocked })
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if d.dispatch(cancelled, "c", func() { record("c1") }) {
+		t.Fatal("dispatch ran past the limit")
+	}
+
+	close(slow)
+	close(blocked)
+	d.wait()
+	if len(order) != 3 || order[0] != "b1" || order[1] != "a1" || order[2] != "a2" {
+		t.Fatalf("order = %v", order)
+	}
+}
@@ -18,6 +18,8 @@ type TenantChannel struct {
 	ChannelUserID string    `json:"channel_user_id,omitempty"`
 	LinkedAt      time.Time `json:"linked_at"`
 	Muted         bool      `json:"muted"`
+	// NotificationLevel is one of NotificationLevels.
+	NotificationLevel string `json:"notification_level"`
 }
 
 // LinkStore manages tenant channel links.
@@ -80,7 +82,7 @@ func (s *LinkStore) GetChannels(tenantID string) ([]TenantChannel, error) {
 	}
 
 	rows, err := s.db.Query(
-		`SELECT id, tenant_id, channel, channel_user_id, linked_at, muted
+		`SELECT id, tenant_id, channel, channel_user_id, linked_at, muted, notification_level
 		 FROM tenant_channels
 		 WHERE tenant_id = $1
 		 ORDER BY linked_at ASC`,
@@ -95,7 +97,7 @@ func (s *LinkStore) GetChannels(tenantID string) ([]TenantChannel, error) {
 	for rows.Next() {
 		var ch TenantChannel
 		var channelUserID sql.NullString
-		if err := rows.Scan(&ch.ID, &ch.TenantID, &ch.Channel, &channelUserID, &ch.LinkedAt, &ch.Muted); err != nil {
+		if err := rows.Scan(&ch.ID, &ch.TenantID, &ch.Channel, &channelUserID, &ch.LinkedAt, &ch.Muted, &ch.NotificationLevel); err != nil {
 			return nil, fmt.Errorf("scan channel: %w", err)
 		}
 		if channelUserID.Valid {
@@ -110,10 +112,73 @@ func (s *LinkStore) GetChannels(tenantID string) ([]TenantChannel, error) {
 	return result, nil
 }
 
+// ChannelStats is the stored activity of one linked channel.
+type ChannelStats struct {
+	MessageCount  int64      `json:"message_count"`
+	LastMessageAt *time.Time `json:"last_message_at"`
+	// FailedDeliveries counts broadcast deliveries to the channel that gave
+	// up after their retries.
+	FailedDeliveries int64 `json:"failed_deliveries"`
+	Muted            bool  `json:"muted"`
+}
+
+// GetChannelStats returns the stats of every channel linked to a tenant,
+// keyed by channel name, in one query.
+func (s *LinkStore) GetChannelStats(tenantID string) (map[string]ChannelStats, error) {
+	if strings.TrimSpace(tenantID) == "" {
+		return nil, errors.New("tenant id is required")
+	}
+
+	rows, err := s.db.Query(
+		`SELECT tc.channel, tc.muted, COALESCE(m.message_count, 0), m.last_message_at, COALESCE(f.failed, 0)
+		 FROM tenant_channels tc
+		 LEFT JOIN (
+		     SELECT m.channel, COUNT(*) AS message_count, MAX(m.created_at) AS last_message_at
+		     FROM messages m
+		     JOIN conversations c ON c.id = m.conversation_id
+		     WHERE c.tenant_id = $1
+		     GROUP BY m.channel
+		 ) m ON m.channel = tc.channel
+		 LEFT JOIN (
+		     SELECT d.channel, COUNT(*) AS failed
+		     FROM broadcast_deliveries d
+		     JOIN broadcasts b ON b.id = d.broadcast_id
+		     WHERE b.tenant_id = $1 AND d.status = 'failed'
+		     GROUP BY d.channel
+		 ) f ON f.channel = tc.channel
+		 WHERE tc.tenant_id = $1`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get channel stats: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]ChannelStats)
+	for rows.Next() {
+		var (
+			channel string
+			stats   ChannelStats
+			last    sql.NullTime
+		)
+		if err := rows.Scan(&channel, &stats.Muted, &stats.MessageCount, &last, &stats.FailedDeliveries); err != nil {
+			return nil, fmt.Errorf("scan channel stats: %w", err)
+		}
+		if last.Valid {
+			stats.LastMessageAt = &last.Time
+		}
+		result[channel] = stats
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate channel stats: %w", err)
+	}
+	return result, nil
+}
+
 func normalizeChannel(channel string) (string, error) {
 	normalized := strings.ToLower(strings.TrimSpace(channel))
 	switch normalized {
-	case "web", "telegram", "whatsapp":
+	case "web", "telegram", "whatsapp", "viber":
 		return normalized, nil
 	default:
 		return "", fmt.Errorf("%w: %q", ErrInvalidChannel, channel)
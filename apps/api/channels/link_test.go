@@ -1,6 +1,7 @@
 package channels
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -28,17 +29,85 @@ func TestLinkStoreLinkUnlinkList(t *testing.T) {
 	}
 
 	now := time.Now()
-	rows := sqlmock.NewRows([]string{"id", "tenant_id", "channel", "channel_user_id", "linked_at", "muted"}).AddRow("id1", "t1", "web", "", now, false)
+	rows := sqlmock.NewRows([]string{"id", "tenant_id", "channel", "channel_user_id", "linked_at", "muted", "notification_level"}).AddRow("id1", "t1", "web", "", now, false, "milestones")
 	mock.ExpectQuery("SELECT id, tenant_id, channel").WithArgs("t1").WillReturnRows(rows)
 	got, err := store.GetChannels("t1")
 	if err != nil {
 		t.Fatalf("GetChannels: %v", err)
 	}
-	if len(got) != 1 || got[0].Channel != "web" {
+	if len(got) != 1 || got[0].Channel != "web" || got[0].NotificationLevel != NotifyMilestones {
 		t.Fatalf("unexpected channels: %#v", got)
 	}
 }
 
+func TestLinkStoreNotificationLevel(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	store := NewLinkStore(db)
+
+	mock.ExpectQuery("SELECT notification_level FROM tenant_channels").WithArgs("t1", "telegram").
+		WillReturnRows(sqlmock.NewRows([]string{"notification_level"}))
+	if level, err := store.NotificationLevel("t1", "telegram"); err != nil || level != DefaultNotificationLevel {
+		t.Fatalf("unlinked level = %q, %v", level, err)
+	}
+
+	if _, err := store.SetNotificationLevel("t1", "telegram", "loud"); !errors.Is(err, ErrInvalidNotificationLevel) {
+		t.Fatalf("invalid level err = %v", err)
+	}
+
+	mock.ExpectQuery("UPDATE tenant_channels").WithArgs("t1", "telegram", "errors_only").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "channel", "channel_user_id", "linked_at", "muted", "notification_level"}).
+			AddRow("id1", "t1", "telegram", "42", time.Now(), false, "errors_only"))
+	ch, err := store.SetNotificationLevel("t1", "telegram", " Errors_Only ")
+	if err != nil || ch.NotificationLevel != NotifyErrorsOnly || ch.ChannelUserID != "42" {
+		t.Fatalf("SetNotificationLevel = %+v, %v", ch, err)
+	}
+
+	mock.ExpectQuery("UPDATE tenant_channels").WithArgs("t1", "viber", "all").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if _, err := store.SetNotificationLevel("t1", "viber", "all"); !errors.Is(err, ErrChannelNotLinked) {
+		t.Fatalf("unlinked channel err = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestLinkStoreGetChannelStats(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	last := time.Date(2026, 5, 1, 9, 30, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM tenant_channels tc").WithArgs("t1").WillReturnRows(
+		sqlmock.NewRows([]string{"channel", "muted", "message_count", "last_message_at", "failed"}).
+			AddRow("telegram", false, 42, last, 3).
+			AddRow("viber", true, 0, nil, 0))
+
+	stats, err := NewLinkStore(db).GetChannelStats("t1")
+	if err != nil {
+		t.Fatalf("GetChannelStats: %v", err)
+	}
+	tg := stats["telegram"]
+	if tg.MessageCount != 42 || tg.FailedDeliveries != 3 || tg.Muted || tg.LastMessageAt == nil || !tg.LastMessageAt.Equal(last) {
+		t.Fatalf("telegram stats = %+v", tg)
+	}
+	if vb := stats["viber"]; !vb.Muted || vb.LastMessageAt != nil || vb.MessageCount != 0 {
+		t.Fatalf("viber stats = %+v", vb)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
 func TestNormalizeChannel(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -0,0 +1,61 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/agentsquads/api/tools"
+)
+
+const (
+	// defaultMemoryContextTopK is how many persistent memories are added to a
+	// request's context unless MEMORY_CONTEXT_TOP_K says otherwise; 0 turns
+	// it off.
+	defaultMemoryContextTopK = 5
+	memoryContextPrefix      = "Remembered facts saved in earlier runs with memory_persist. They may be out of date; the conversation takes precedence:\n"
+)
+
+// SetMemoryStore gives assistant tool loops the memory_persist tool, lets
+// memory_recall fall back to persisted memories, and adds the memories most
+// relevant to the latest message to the context of each request.
+func (r *Router) SetMemoryStore(backend tools.MemoryBackend) {
+	r.memories = backend
+	r.memoryTopK = intFromEnv("MEMORY_CONTEXT_TOP_K", defaultMemoryContextTopK)
+	r.toolRegistry.SetMemoryBackend(backend)
+}
+
+// memoryContext returns a system message listing the persistent memories
+// that share the most words with the latest user message, or "" when there
+// are none. Lookup failures only cost the request its memories.
+func (r *Router) memoryContext(ctx context.Context, tenantID, conversationID string, messages []tools.Message) string {
+	if r.memories == nil || r.memoryTopK <= 0 {
+		return ""
+	}
+	var latest string
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			latest, _ = messages[i].Content.(string)
+			break
+		}
+	}
+	keywords := tools.MemoryKeywords(latest)
+	if len(keywords) == 0 {
+		return ""
+	}
+	memories, err := r.memories.RelevantMemories(ctx, tenantID, conversationID, keywords, r.memoryTopK)
+	if err != nil {
+		slog.Warn("load relevant memories failed", "tenant", tenantID, "conversation", conversationID, "err", err)
+		return ""
+	}
+	if len(memories) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(memoryContextPrefix)
+	for _, m := range memories {
+		fmt.Fprintf(&b, "- %s: %s\n", m.Key, m.Content)
+	}
+	return b.String()
+}
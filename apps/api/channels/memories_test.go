@@ -0,0 +1,58 @@
+package channels
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/agentsquads/api/tools"
+)
+
+type stubMemories struct {
+	keywords []string
+	found    []tools.Memory
+}
+
+func (s *stubMemories) PersistMemory(_ context.Context, _ string, m tools.Memory) (tools.Memory, error) {
+	return m, nil
+}
+
+func (s *stubMemories) RecallMemory(context.Context, string, string, string) (tools.Memory, bool, error) {
+	return tools.Memory{}, false, nil
+}
+
+func (s *stubMemories) RelevantMemories(_ context.Context, _, _ string, keywords []string, _ int) ([]tools.Memory, error) {
+	s.keywords = keywords
+	return s.found, nil
+}
+
+func TestMemoryContext(t *testing.T) {
+	t.Setenv("MEMORY_CONTEXT_TOP_K", "")
+	backend := &stubMemories{found: []tools.Memory{{Key: "launch_date", Content: "March 3"}}}
+	r := NewRouter(nil, nil)
+	r.SetMemoryStore(backend)
+	messages := []tools.Message{
+		{Role: "user", Content: "Tell me about pricing"},
+		{Role: "assistant", Content: "Sure."},
+		{Role: "user", Content: "When is the launch?"},
+	}
+
+	got := r.memoryContext(context.Background(), "t1", "c1", messages)
+	if !strings.HasPrefix(got, memoryContextPrefix) || !strings.Contains(got, "- launch_date: March 3\n") {
+		t.Fatalf("memory context = %q", got)
+	}
+	if strings.Join(backend.keywords, ",") != "when,launch" {
+		t.Fatalf("keywords = %v", backend.keywords)
+	}
+
+	backend.found = nil
+	if got := r.memoryContext(context.Background(), "t1", "c1", messages); got != "" {
+		t.Fatalf("context without memories = %q", got)
+	}
+	t.Setenv("MEMORY_CONTEXT_TOP_K", "0")
+	r.SetMemoryStore(backend)
+	backend.found = []tools.Memory{{Key: "k", Content: "v"}}
+	if got := r.memoryContext(context.Background(), "t1", "c1", messages); got != "" {
+		t.Fatalf("context with top-k 0 = %q", got)
+	}
+}
@@ -0,0 +1,26 @@
+package channels
+
+import "context"
+
+// RouteFunc routes an inbound message and returns the reply.
+type RouteFunc func(ctx context.Context, msg InboundMessage) (OutboundMessage, error)
+
+// Middleware wraps the routing of inbound messages. It may change the message
+// before calling next, or return an error without calling it to stop the
+// message from being stored and answered.
+type Middleware func(next RouteFunc) RouteFunc
+
+// Use adds middleware to Route. Middleware sees messages after normalization
+// and the channel policy check, and runs in the order added, the first
+// outermost. Call it before serving messages.
+func (r *Router) Use(mw ...Middleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+func (r *Router) chain(final RouteFunc) RouteFunc {
+	next := final
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		next = r.middleware[i](next)
+	}
+	return next
+}
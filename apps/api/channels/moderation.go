@@ -0,0 +1,211 @@
+package channels
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/agentsquads/api/policies"
+)
+
+// Moderation rule actions in tenant_moderation_rules.
+const (
+	ModerationBlock = "block"
+	ModerationFlag  = "flag"
+)
+
+// ErrMessageModerated is returned when a tenant's moderation rules block an
+// inbound message. The message is neither stored nor answered.
+var ErrMessageModerated = errors.New("message blocked by moderation")
+
+// moderationMatches counts moderated inbound messages per action. It is
+// published through expvar so they show up in /debug/vars.
+var moderationMatches = expvar.NewMap("channel_inbound_moderation")
+
+// ModerationRule is a row of tenant_moderation_rules. Pattern is a Go regular
+// expression matched case-insensitively against the message content.
+type ModerationRule struct {
+	Pattern string
+	Action  string
+}
+
+// ModerationMiddleware checks inbound messages of tenants with the
+// moderation_enabled policy against their tenant_moderation_rules. A "block"
+// match stops the message with ErrMessageModerated; a "flag" match routes it
+// with flagged=true in its metadata. Either is recorded in moderation_events
+// under a hash of the content, so the text itself is not kept when blocked.
+// checker is the shared policy store, so policy changes apply as soon as
+// its cache is invalidated.
+func ModerationMiddleware(db *sql.DB, checker policies.Checker) Middleware {
+	if db == nil || checker == nil {
+		return func(next RouteFunc) RouteFunc { return next }
+	}
+	m := &moderation{
+		db:       db,
+		policies: checker,
+		log:      slog.Default().With("component", "channels.moderation"),
+	}
+	return m.wrap
+}
+
+type moderation struct {
+	db       *sql.DB
+	policies policies.Checker
+	log      *slog.Logger
+	// patterns caches compiled rule patterns, invalid ones included, by
+	// pattern.
+	patterns sync.Map
+}
+
+// compiledModerationRule is a rule with its pattern compiled.
+type compiledModerationRule struct {
+	ModerationRule
+	re *regexp.Regexp
+}
+
+// wrap fails closed: when the policy or rules cannot be loaded the message
+// is not routed but fails like any other routing error, which the inbound
+// queue hands to its failure handler for replay rather than letting the
+// message through unchecked.
+func (m *moderation) wrap(next RouteFunc) RouteFunc {
+	return func(ctx context.Context, msg InboundMessage) (OutboundMessage, error) {
+		enabled, err := m.policies.FeatureEnabled(ctx, msg.TenantID, policies.FeatureModeration)
+		if err != nil {
+			return OutboundMessage{}, fmt.Errorf("check moderation policy: %w", err)
+		}
+		if !enabled {
+			return next(ctx, msg)
+		}
+		rules, err := m.rules(ctx, msg.TenantID)
+		if err != nil {
+			return OutboundMessage{}, err
+		}
+		rule, ok := matchModerationRule(rules, msg.Content)
+		if !ok {
+			return next(ctx, msg)
+		}
+
+		moderationMatches.Add(rule.Action, 1)
+		m.record(ctx, msg, rule)
+		if rule.Action == ModerationBlock {
+			m.log.WarnContext(ctx, "inbound message blocked",
+				"tenant", msg.TenantID,
+				"channel", msg.Channel,
+				"pattern", rule.Pattern,
+				"correlation_id", msg.Metadata[CorrelationIDKey],
+			)
+			return OutboundMessage{}, ErrMessageModerated
+		}
+
+		metadata := make(map[string]string, len(msg.Metadata)+1)
+		for k, v := range msg.Metadata {
+			metadata[k] = v
+		}
+		metadata["flagged"] = "true"
+		msg.Metadata = metadata
+		return next(ctx, msg)
+	}
+}
+
+// rules loads the tenant's usable rules, compiled. Invalid patterns and
+// unknown actions are skipped and logged so a single bad rule does not
+// disable the rest.
+func (m *moderation) rules(ctx context.Context, tenantID string) ([]compiledModerationRule, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT pattern, action
+		FROM tenant_moderation_rules
+		WHERE tenant_id = $1
+		ORDER BY created_at, id
+	`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("load moderation rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []compiledModerationRule
+	for rows.Next() {
+		var rule ModerationRule
+		if err := rows.Scan(&rule.Pattern, &rule.Action); err != nil {
+			return nil, fmt.Errorf("scan moderation rule: %w", err)
+		}
+		if compiled, ok := m.compile(rule, m.log.With("tenant", tenantID)); ok {
+			rules = append(rules, compiled)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("load moderation rules: %w", err)
+	}
+	return rules, nil
+}
+
+type compiledPattern struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// compile returns rule with its pattern compiled, reusing an earlier
+// compilation of the same pattern, and false for a rule that cannot be used.
+// An invalid pattern is logged the first time it is seen.
+func (m *moderation) compile(rule ModerationRule, log *slog.Logger) (compiledModerationRule, bool) {
+	if strings.TrimSpace(rule.Pattern) == "" {
+		return compiledModerationRule{}, false
+	}
+	if rule.Action != ModerationBlock && rule.Action != ModerationFlag {
+		log.Warn("skip moderation rule with unknown action", "pattern", rule.Pattern, "action", rule.Action)
+		return compiledModerationRule{}, false
+	}
+	cached, ok := m.patterns.Load(rule.Pattern)
+	if !ok {
+		re, err := regexp.Compile("(?i)" + rule.Pattern)
+		if err != nil {
+			log.Warn("skip invalid moderation pattern", "pattern", rule.Pattern, "err", err)
+		}
+		cached, _ = m.patterns.LoadOrStore(rule.Pattern, compiledPattern{re: re, err: err})
+	}
+	pattern := cached.(compiledPattern)
+	if pattern.err != nil {
+		return compiledModerationRule{}, false
+	}
+	return compiledModerationRule{ModerationRule: rule, re: pattern.re}, true
+}
+
+// record stores a moderation event. A failed insert is logged and does not
+// change the outcome for the message.
+func (m *moderation) record(ctx context.Context, msg InboundMessage, rule ModerationRule) {
+	sum := sha256.Sum256([]byte(msg.Content))
+	if _, err := m.db.ExecContext(ctx, `
+		INSERT INTO moderation_events (tenant_id, content_hash, pattern_matched, action)
+		VALUES ($1, $2, $3, $4)
+	`, msg.TenantID, hex.EncodeToString(sum[:]), rule.Pattern, rule.Action); err != nil {
+		m.log.WarnContext(ctx, "record moderation event failed", "tenant", msg.TenantID, "action", rule.Action, "err", err)
+	}
+}
+
+// matchModerationRule returns the rule content matches. A block rule wins
+// over flag rules; otherwise the first matching rule is returned.
+func matchModerationRule(rules []compiledModerationRule, content string) (ModerationRule, bool) {
+	var flagged *ModerationRule
+	for i, rule := range rules {
+		if !rule.re.MatchString(content) {
+			continue
+		}
+		if rule.Action == ModerationBlock {
+			return rule.ModerationRule, true
+		}
+		if flagged == nil {
+			flagged = &rules[i].ModerationRule
+		}
+	}
+	if flagged == nil {
+		return ModerationRule{}, false
+	}
+	return *flagged, true
+}
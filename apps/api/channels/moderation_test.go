@@ -0,0 +1,123 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/policies"
+)
+
+func TestModerationMiddleware(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	var routed []InboundMessage
+	route := ModerationMiddleware(db, policies.NewStore(db))(func(_ context.Context, msg InboundMessage) (OutboundMessage, error) {
+		routed = append(routed, msg)
+		return OutboundMessage{TenantID: msg.TenantID, Metadata: msg.Metadata}, nil
+	})
+	rules := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"pattern", "action"}).
+			AddRow(`\bspam\b`, ModerationFlag).
+			AddRow("forbidden", ModerationBlock)
+	}
+
+	mock.ExpectQuery("SELECT enabled FROM tenant_policies").WithArgs("t-off", "moderation_enabled").
+		WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(false))
+	if _, err := route(context.Background(), InboundMessage{TenantID: "t-off", Content: "forbidden"}); err != nil || len(routed) != 1 {
+		t.Fatalf("disabled: err=%v routed=%d", err, len(routed))
+	}
+
+	mock.ExpectQuery("SELECT enabled FROM tenant_policies").WithArgs("t1", "moderation_enabled").
+		WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(true))
+	mock.ExpectQuery("FROM tenant_moderation_rules").WithArgs("t1").WillReturnRows(rules())
+	mock.ExpectExec("INSERT INTO moderation_events").
+		WithArgs("t1", "80b2429aa51f26ceda6b74d08fcf87191e5d70f3cf4858538e9795d88ac0c120", "forbidden", ModerationBlock).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	_, err = route(context.Background(), InboundMessage{TenantID: "t1", Content: "SPAM and Forbidden"})
+	if !errors.Is(err, ErrMessageModerated) || len(routed) != 1 {
+		t.Fatalf("block: err=%v routed=%d", err, len(routed))
+	}
+
+	mock.ExpectQuery("FROM tenant_moderation_rules").WithArgs("t1").WillReturnRows(rules())
+	mock.ExpectExec("INSERT INTO moderation_events").WithArgs("t1", sqlmock.AnyArg(), `\bspam\b`, ModerationFlag).
+		WillReturnError(errors.New("db down"))
+	out, err := route(context.Background(), InboundMessage{TenantID: "t1", Content: "more Spam please", Metadata: map[string]string{"user_id": "u1"}})
+	if err != nil || len(routed) != 2 || out.Metadata["flagged"] != "true" || out.Metadata["user_id"] != "u1" {
+		t.Fatalf("flag: err=%v routed=%d metadata=%v", err, len(routed), out.Metadata)
+	}
+
+	mock.ExpectQuery("FROM tenant_moderation_rules").WithArgs("t1").WillReturnRows(rules())
+	out, err = route(context.Background(), InboundMessage{TenantID: "t1", Content: "spammer"})
+	if err != nil || len(routed) != 3 || out.Metadata["flagged"] != "" {
+		t.Fatalf("clean: err=%v routed=%d metadata=%v", err, len(routed), out.Metadata)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestMatchModerationRule(t *testing.T) {
+	t.Parallel()
+	rules := []ModerationRule{
+		{Pattern: "([", Action: ModerationBlock},
+		{Pattern: "promo", Action: "quarantine"},
+		{Pattern: "promo", Action: ModerationFlag},
+		{Pattern: "free", Action: ModerationFlag},
+	}
+	m := &moderation{}
+	var compiled []compiledModerationRule
+	for _, rule := range rules {
+		if c, ok := m.compile(rule, slog.Default()); ok {
+			compiled = append(compiled, c)
+		}
+	}
+	if len(compiled) != 2 {
+		t.Fatalf("compiled %d rules, want the 2 usable ones", len(compiled))
+	}
+	rule, ok := matchModerationRule(compiled, "Free PROMO codes")
+	if !ok || rule.Pattern != "promo" || rule.Action != ModerationFlag {
+		t.Fatalf("match = %+v, %v", rule, ok)
+	}
+	if _, ok := matchModerationRule(compiled, "hello"); ok {
+		t.Fatalf("unexpected match")
+	}
+
+	// A pattern is compiled once, however many rules and messages use it.
+	again, _ := m.compile(ModerationRule{Pattern: "promo", Action: ModerationBlock}, slog.Default())
+	if again.re != compiled[0].re {
+		t.Fatalf("pattern was compiled again")
+	}
+}
+
+func TestRouteStopsAtMiddleware(t *testing.T) {
+	t.Parallel()
+	r := NewRouter(nil, nil)
+	var seen []string
+	r.Use(func(next RouteFunc) RouteFunc {
+		return func(ctx context.Context, msg InboundMessage) (OutboundMessage, error) {
+			seen = append(seen, "outer:"+msg.Channel)
+			return next(ctx, msg)
+		}
+	}, func(RouteFunc) RouteFunc {
+		return func(_ context.Context, msg InboundMessage) (OutboundMessage, error) {
+			seen = append(seen, "inner:"+msg.Content)
+			return OutboundMessage{}, ErrMessageModerated
+		}
+	})
+
+	_, err := r.Route(context.Background(), InboundMessage{TenantID: "t1", Content: " hi ", Channel: "Telegram"})
+	if !errors.Is(err, ErrMessageModerated) {
+		t.Fatalf("err = %v, want ErrMessageModerated", err)
+	}
+	if len(seen) != 2 || seen[0] != "outer:telegram" || seen[1] != "inner:hi" {
+		t.Fatalf("middleware saw %v", seen)
+	}
+}
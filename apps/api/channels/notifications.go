@@ -0,0 +1,94 @@
+package channels
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Notification levels decide which swarm run updates a linked channel
+// receives. SSE subscribers and the run timeline always get every update.
+const (
+	NotifyAll        = "all"
+	NotifyMilestones = "milestones"  // queued, halfway and the final result
+	NotifyFinalOnly  = "final_only"  // only the final result
+	NotifyErrorsOnly = "errors_only" // failed subtasks and failed runs
+)
+
+// DefaultNotificationLevel applies to new links and to runs whose channel
+// link cannot be read.
+const DefaultNotificationLevel = NotifyMilestones
+
+// NotificationLevels lists the valid levels in the order they are offered.
+var NotificationLevels = []string{NotifyAll, NotifyMilestones, NotifyFinalOnly, NotifyErrorsOnly}
+
+var (
+	ErrInvalidNotificationLevel = errors.New("invalid notification level")
+	ErrChannelNotLinked         = errors.New("channel is not linked")
+)
+
+// ParseNotificationLevel normalizes level and checks it is one of
+// NotificationLevels.
+func ParseNotificationLevel(level string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(level))
+	for _, l := range NotificationLevels {
+		if l == normalized {
+			return l, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidNotificationLevel, level)
+}
+
+// NotificationLevel returns the tenant's notification level for channel,
+// or DefaultNotificationLevel when the channel is not linked.
+func (s *LinkStore) NotificationLevel(tenantID, channel string) (string, error) {
+	channel, err := normalizeChannel(channel)
+	if err != nil {
+		return "", err
+	}
+	var level string
+	err = s.db.QueryRow(
+		`SELECT notification_level FROM tenant_channels WHERE tenant_id = $1 AND channel = $2`,
+		tenantID, channel,
+	).Scan(&level)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultNotificationLevel, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get notification level: %w", err)
+	}
+	return level, nil
+}
+
+// SetNotificationLevel changes the notification level of a linked channel.
+func (s *LinkStore) SetNotificationLevel(tenantID, channel, level string) (TenantChannel, error) {
+	channel, err := normalizeChannel(channel)
+	if err != nil {
+		return TenantChannel{}, err
+	}
+	level, err = ParseNotificationLevel(level)
+	if err != nil {
+		return TenantChannel{}, err
+	}
+
+	var (
+		ch            TenantChannel
+		channelUserID sql.NullString
+	)
+	err = s.db.QueryRow(
+		`UPDATE tenant_channels
+		 SET notification_level = $3
+		 WHERE tenant_id = $1 AND channel = $2
+		 RETURNING id, tenant_id, channel, channel_user_id, linked_at, muted, notification_level`,
+		tenantID, channel, level,
+	).Scan(&ch.ID, &ch.TenantID, &ch.Channel, &channelUserID, &ch.LinkedAt, &ch.Muted, &ch.NotificationLevel)
+	if errors.Is(err, sql.ErrNoRows) {
+		return TenantChannel{}, ErrChannelNotLinked
+	}
+	if err != nil {
+		return TenantChannel{}, fmt.Errorf("set notification level: %w", err)
+	}
+	ch.ChannelUserID = channelUserID.String
+	return ch, nil
+}
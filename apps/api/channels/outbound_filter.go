@@ -0,0 +1,347 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/agentsquads/api/policies"
+	"github.com/lib/pq"
+)
+
+const (
+	redactedPlaceholder = "[redacted]"
+	truncatedSuffix     = "\n\n… (truncated, see dashboard for full output)"
+	moderationTimeout   = 10 * time.Second
+)
+
+// outboundRedactions counts redactions per filter. It is published through
+// expvar so they show up in /debug/vars.
+var outboundRedactions = expvar.NewMap("channel_outbound_redactions")
+
+// OutboundFilter rewrites reply content before it is delivered. Apply
+// returns the new content and how many redactions it made.
+type OutboundFilter interface {
+	Name() string
+	Apply(ctx context.Context, tenantID, content string) (string, int, error)
+}
+
+// OutboundFilterConfig is a tenant's row in tenant_outbound_filters.
+type OutboundFilterConfig struct {
+	RedactPatterns []string
+	MaxLength      int
+}
+
+// OutboundFilterSource loads a tenant's outbound filter settings. A tenant
+// without settings gets the zero config.
+type OutboundFilterSource interface {
+	OutboundFilterConfig(ctx context.Context, tenantID string) (OutboundFilterConfig, error)
+}
+
+// OutboundFilterStore reads tenant_outbound_filters.
+type OutboundFilterStore struct {
+	db *sql.DB
+}
+
+func NewOutboundFilterStore(db *sql.DB) *OutboundFilterStore {
+	return &OutboundFilterStore{db: db}
+}
+
+func (s *OutboundFilterStore) OutboundFilterConfig(ctx context.Context, tenantID string) (OutboundFilterConfig, error) {
+	var cfg OutboundFilterConfig
+	if s == nil || s.db == nil {
+		return cfg, nil
+	}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT redact_patterns, max_length
+		FROM tenant_outbound_filters
+		WHERE tenant_id = $1
+	`, tenantID).Scan(pq.Array(&cfg.RedactPatterns), &cfg.MaxLength)
+	if errors.Is(err, sql.ErrNoRows) {
+		return OutboundFilterConfig{}, nil
+	}
+	if err != nil {
+		return OutboundFilterConfig{}, fmt.Errorf("load outbound filter config: %w", err)
+	}
+	return cfg, nil
+}
+
+// OutboundFilters applies a tenant's filter chain to replies: redaction
+// patterns, then LLM moderation, then the length cap. The chain runs only for
+// tenants with the outbound_filter policy; moderation also needs
+// outbound_moderation and a moderator.
+type OutboundFilters struct {
+	policies  policies.Checker
+	configs   OutboundFilterSource
+	moderator OutboundFilter
+	log       *slog.Logger
+}
+
+func NewOutboundFilters(checker policies.Checker, configs OutboundFilterSource) *OutboundFilters {
+	return &OutboundFilters{
+		policies: checker,
+		configs:  configs,
+		log:      slog.Default().With("component", "channels.outbound_filter"),
+	}
+}
+
+// SetModerator enables the moderation step for tenants with the
+// outbound_moderation policy.
+func (o *OutboundFilters) SetModerator(m OutboundFilter) {
+	o.moderator = m
+}
+
+// SetOutboundFilters makes the fanout filter every reply before sending it.
+// Call it before Start.
+func (f *Fanout) SetOutboundFilters(filters *OutboundFilters) {
+	f.filters = filters
+}
+
+// Apply runs the tenant's filters over out.Content. When the content changes
+// the original is kept in the original_content metadata for the dashboard.
+// Policy and config lookups that fail return an error so delivery is retried
+// rather than sent unfiltered; a failing moderation call is skipped.
+func (o *OutboundFilters) Apply(ctx context.Context, out OutboundMessage) (OutboundMessage, error) {
+	if o == nil || o.policies == nil || strings.TrimSpace(out.Content) == "" {
+		return out, nil
+	}
+	chain, err := o.chain(ctx, out.TenantID)
+	if err != nil || len(chain) == 0 {
+		return out, err
+	}
+
+	content := out.Content
+	var applied []string
+	for _, filter := range chain {
+		next, redactions, err := filter.Apply(ctx, out.TenantID, content)
+		if err != nil {
+			o.log.Warn("outbound filter failed, delivering without it", "tenant", out.TenantID, "filter", filter.Name(), "err", err)
+			continue
+		}
+		if redactions > 0 {
+			outboundRedactions.Add(filter.Name(), int64(redactions))
+		}
+		if next != content {
+			applied = append(applied, filter.Name())
+			content = next
+		}
+	}
+	if len(applied) == 0 {
+		return out, nil
+	}
+
+	metadata := make(map[string]string, len(out.Metadata)+2)
+	for k, v := range out.Metadata {
+		metadata[k] = v
+	}
+	metadata["original_content"] = out.Content
+	metadata["filtered_by"] = strings.Join(applied, ",")
+	out.Content = content
+	out.Metadata = metadata
+	return out, nil
+}
+
+func (o *OutboundFilters) chain(ctx context.Context, tenantID string) ([]OutboundFilter, error) {
+	enabled, err := o.policies.FeatureEnabled(ctx, tenantID, policies.FeatureOutboundFilter)
+	if err != nil {
+		return nil, fmt.Errorf("check outbound filter policy: %w", err)
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	var cfg OutboundFilterConfig
+	if o.configs != nil {
+		if cfg, err = o.configs.OutboundFilterConfig(ctx, tenantID); err != nil {
+			return nil, err
+		}
+	}
+
+	var chain []OutboundFilter
+	if len(cfg.RedactPatterns) > 0 {
+		chain = append(chain, newRegexRedactor(cfg.RedactPatterns, o.log.With("tenant", tenantID)))
+	}
+	if o.moderator != nil {
+		moderate, err := o.policies.FeatureEnabled(ctx, tenantID, policies.FeatureOutboundModeration)
+		if err != nil {
+			return nil, fmt.Errorf("check outbound moderation policy: %w", err)
+		}
+		if moderate {
+			chain = append(chain, o.moderator)
+		}
+	}
+	if cfg.MaxLength > 0 {
+		chain = append(chain, lengthLimit(cfg.MaxLength))
+	}
+	return chain, nil
+}
+
+// regexRedactor replaces every match of its patterns with a placeholder.
+type regexRedactor struct {
+	patterns []*regexp.Regexp
+}
+
+// newRegexRedactor compiles patterns, skipping and logging invalid ones so a
+// single bad pattern does not disable the rest.
+func newRegexRedactor(patterns []string, log *slog.Logger) regexRedactor {
+	var r regexRedactor
+	for _, p := range patterns {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			log.Warn("skip invalid outbound redaction pattern", "pattern", p, "err", err)
+			continue
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r
+}
+
+func (r regexRedactor) Name() string { return "redact" }
+
+func (r regexRedactor) Apply(_ context.Context, _ string, content string) (string, int, error) {
+	count := 0
+	for _, re := range r.patterns {
+		content = re.ReplaceAllStringFunc(content, func(match string) string {
+			if match == "" || match == redactedPlaceholder {
+				return match
+			}
+			count++
+			return redactedPlaceholder
+		})
+	}
+	return content, count, nil
+}
+
+// lengthLimit cuts content to at most that many characters, suffix included.
+type lengthLimit int
+
+func (l lengthLimit) Name() string { return "truncate" }
+
+func (l lengthLimit) Apply(_ context.Context, _ string, content string) (string, int, error) {
+	limit := int(l)
+	if utf8.RuneCountInString(content) <= limit {
+		return content, 0, nil
+	}
+	keep := limit - utf8.RuneCountInString(truncatedSuffix)
+	if keep <= 0 {
+		return string([]rune(content)[:limit]), 0, nil
+	}
+	return strings.TrimRightFunc(string([]rune(content)[:keep]), func(r rune) bool { return r == ' ' || r == '\n' }) + truncatedSuffix, 0, nil
+}
+
+// LLMModerator asks a model behind the LLM proxy which parts of a reply
+// expose internal URLs, stack traces, credentials or other customers' data,
+// and redacts exactly those spans. The model never rewrites the reply.
+type LLMModerator struct {
+	url   string
+	model string
+	http  *http.Client
+}
+
+// NewLLMModerator sends moderation calls to the proxy's chat completions
+// endpoint at proxyURL, billed to the tenant whose reply is checked. An empty
+// model falls back to OUTBOUND_MODERATION_MODEL, then LLM_MODEL.
+func NewLLMModerator(proxyURL, model string) *LLMModerator {
+	for _, candidate := range []string{model, os.Getenv("OUTBOUND_MODERATION_MODEL"), os.Getenv("LLM_MODEL"), "openai/gpt-4.1-mini"} {
+		if model = strings.TrimSpace(candidate); model != "" {
+			break
+		}
+	}
+	base := strings.TrimRight(strings.TrimSpace(proxyURL), "/")
+	if !strings.HasSuffix(base, "/v1/chat/completions") {
+		base = strings.TrimSuffix(base, "/v1") + "/v1/chat/completions"
+	}
+	return &LLMModerator{
+		url:   base,
+		model: model,
+		http:  &http.Client{Timeout: moderationTimeout},
+	}
+}
+
+func (m *LLMModerator) Name() string { return "moderation" }
+
+const moderationPrompt = "You review an assistant reply before it is sent to a customer. " +
+	"List every exact substring that exposes internal URLs or hostnames, stack traces, file paths, credentials, " +
+	"or names and data of anyone other than the customer. Return strict JSON: {\"redact\":[\"...\"]}. " +
+	"Return {\"redact\":[]} when nothing needs removing."
+
+func (m *LLMModerator) Apply(ctx context.Context, tenantID, content string) (string, int, error) {
+	payload, err := json.Marshal(map[string]any{
+		"model": m.model,
+		"messages": []map[string]string{
+			{"role": "system", "content": moderationPrompt},
+			{"role": "user", "content": content},
+		},
+	})
+	if err != nil {
+		return content, 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, bytes.NewReader(payload))
+	if err != nil {
+		return content, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Tenant-ID", tenantID)
+	if serviceKey := strings.TrimSpace(os.Getenv("SERVICE_API_KEY")); serviceKey != "" {
+		req.Header.Set("X-Service-API-Key", serviceKey)
+	}
+
+	resp, err := m.http.Do(req)
+	if err != nil {
+		return content, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return content, 0, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return content, 0, fmt.Errorf("moderation returned %d", resp.StatusCode)
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &completion); err != nil || len(completion.Choices) == 0 {
+		return content, 0, errors.New("moderation returned an unreadable completion")
+	}
+	reply := completion.Choices[0].Message.Content
+	if start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}"); start >= 0 && end > start {
+		reply = reply[start : end+1]
+	}
+	var verdict struct {
+		Redact []string `json:"redact"`
+	}
+	if err := json.Unmarshal([]byte(reply), &verdict); err != nil {
+		return content, 0, fmt.Errorf("decode moderation verdict: %w", err)
+	}
+
+	count := 0
+	for _, span := range verdict.Redact {
+		if strings.TrimSpace(span) == "" || span == redactedPlaceholder {
+			continue
+		}
+		count += strings.Count(content, span)
+		content = strings.ReplaceAll(content, span, redactedPlaceholder)
+	}
+	return content, count, nil
+}
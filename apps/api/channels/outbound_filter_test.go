@@ -0,0 +1,181 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/policies"
+)
+
+type stubFilterConfigs OutboundFilterConfig
+
+func (s stubFilterConfigs) OutboundFilterConfig(context.Context, string) (OutboundFilterConfig, error) {
+	return OutboundFilterConfig(s), nil
+}
+
+type failingPolicies struct{}
+
+func (failingPolicies) FeatureEnabled(context.Context, string, string) (bool, error) {
+	return false, errors.New("db down")
+}
+
+func TestOutboundFiltersRedactAndTruncate(t *testing.T) {
+	t.Parallel()
+	filters := NewOutboundFilters(stubPolicies{policies.FeatureOutboundFilter: true}, stubFilterConfigs{
+		RedactPatterns: []string{`https?://[a-z0-9.-]+\.internal\S*`, `(`, `acme corp`},
+		MaxLength:      80,
+	})
+	before := redactionCount("redact")
+
+	content := "See http://billing.internal/trace?id=1 for acme corp. " + strings.Repeat("more detail ", 20)
+	out, err := filters.Apply(context.Background(), OutboundMessage{TenantID: "t1", Content: content, Metadata: map[string]string{"user_id": "u1"}})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if strings.Contains(out.Content, "internal") || strings.Contains(out.Content, "acme") {
+		t.Fatalf("content not redacted: %q", out.Content)
+	}
+	if !strings.HasPrefix(out.Content, "See [redacted] for [redacted].") || !strings.HasSuffix(out.Content, truncatedSuffix) {
+		t.Fatalf("content = %q", out.Content)
+	}
+	if n := utf8.RuneCountInString(out.Content); n > 80 {
+		t.Fatalf("content length = %d, want <= 80", n)
+	}
+	if out.Metadata["original_content"] != content || out.Metadata["filtered_by"] != "redact,truncate" || out.Metadata["user_id"] != "u1" {
+		t.Fatalf("metadata = %v", out.Metadata)
+	}
+	if got := redactionCount("redact"); got < before+2 {
+		t.Fatalf("redaction counter = %d, want at least %d", got, before+2)
+	}
+}
+
+func redactionCount(filter string) int64 {
+	if v, ok := outboundRedactions.Get(filter).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestOutboundFiltersPolicy(t *testing.T) {
+	t.Parallel()
+	cfg := stubFilterConfigs{RedactPatterns: []string{"secret"}}
+	in := OutboundMessage{TenantID: "t1", Content: "the secret"}
+
+	out, err := NewOutboundFilters(stubPolicies{}, cfg).Apply(context.Background(), in)
+	if err != nil || out.Content != "the secret" || out.Metadata != nil {
+		t.Fatalf("disabled policy: out=%+v err=%v", out, err)
+	}
+	if _, err := NewOutboundFilters(failingPolicies{}, cfg).Apply(context.Background(), in); err == nil {
+		t.Fatalf("expected policy lookup error")
+	}
+	var nilFilters *OutboundFilters
+	if out, err := nilFilters.Apply(context.Background(), in); err != nil || out.Content != in.Content {
+		t.Fatalf("nil filters: out=%+v err=%v", out, err)
+	}
+}
+
+func TestOutboundFiltersModeration(t *testing.T) {
+	t.Parallel()
+	var tenant string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = r.Header.Get("X-Tenant-ID")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"redact\":[\"at main.go:12\",\"\"]}"}}]}`))
+	}))
+	defer srv.Close()
+
+	filters := NewOutboundFilters(stubPolicies{policies.FeatureOutboundFilter: true, policies.FeatureOutboundModeration: true}, nil)
+	filters.SetModerator(NewLLMModerator(srv.URL, "m"))
+	out, err := filters.Apply(context.Background(), OutboundMessage{TenantID: "t1", Content: "panic at main.go:12"})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if out.Content != "panic [redacted]" || out.Metadata["filtered_by"] != "moderation" || tenant != "t1" {
+		t.Fatalf("out=%+v tenant=%q", out, tenant)
+	}
+
+	noModeration := NewOutboundFilters(stubPolicies{policies.FeatureOutboundFilter: true}, nil)
+	noModeration.SetModerator(NewLLMModerator(srv.URL, "m"))
+	if out, _ := noModeration.Apply(context.Background(), OutboundMessage{TenantID: "t1", Content: "panic at main.go:12"}); out.Content != "panic at main.go:12" {
+		t.Fatalf("moderation ran without policy: %q", out.Content)
+	}
+}
+
+func TestOutboundFiltersModerationFailsOpen(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	filters := NewOutboundFilters(
+		stubPolicies{policies.FeatureOutboundFilter: true, policies.FeatureOutboundModeration: true},
+		stubFilterConfigs{RedactPatterns: []string{"token-[0-9]+"}},
+	)
+	filters.SetModerator(NewLLMModerator(srv.URL+"/v1", "m"))
+	out, err := filters.Apply(context.Background(), OutboundMessage{TenantID: "t1", Content: "use token-42 now"})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if out.Content != "use [redacted] now" || out.Metadata["filtered_by"] != "redact" {
+		t.Fatalf("out = %+v", out)
+	}
+}
+
+func TestFanoutFiltersBeforeSend(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	f := NewFanout(nil, NewLinkStore(db), NewCredentialsStore(db))
+	f.SetOutboundFilters(NewOutboundFilters(stubPolicies{policies.FeatureOutboundFilter: true}, stubFilterConfigs{RedactPatterns: []string{"tenant-b"}}))
+	var got OutboundMessage
+	f.RegisterSender("viber", senderFunc(func(_ context.Context, _ TenantChannel, out OutboundMessage) error {
+		got = out
+		return nil
+	}))
+
+	rows := sqlmock.NewRows([]string{"id", "tenant_id", "channel", "channel_user_id", "linked_at", "muted", "notification_level"}).
+		AddRow("1", "t1", "viber", "acct", time.Now(), false, "milestones")
+	mock.ExpectQuery("SELECT id, tenant_id, channel").WithArgs("t1").WillReturnRows(rows)
+
+	if _, err := f.Deliver(context.Background(), OutboundMessage{TenantID: "t1", Content: "hello tenant-b"}); err != nil {
+		t.Fatalf("fanout: %v", err)
+	}
+	if got.Content != "hello [redacted]" || got.Metadata["original_content"] != "hello tenant-b" {
+		t.Fatalf("sent = %+v", got)
+	}
+}
+
+func TestOutboundFilterStore(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	store := NewOutboundFilterStore(db)
+	mock.ExpectQuery("FROM tenant_outbound_filters").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"redact_patterns", "max_length"}).AddRow(`{"a+","b"}`, 500))
+	cfg, err := store.OutboundFilterConfig(context.Background(), "t1")
+	if err != nil || len(cfg.RedactPatterns) != 2 || cfg.RedactPatterns[0] != "a+" || cfg.MaxLength != 500 {
+		t.Fatalf("cfg=%+v err=%v", cfg, err)
+	}
+
+	mock.ExpectQuery("FROM tenant_outbound_filters").WithArgs("t2").WillReturnRows(sqlmock.NewRows([]string{"redact_patterns", "max_length"}))
+	if cfg, err := store.OutboundFilterConfig(context.Background(), "t2"); err != nil || len(cfg.RedactPatterns) != 0 || cfg.MaxLength != 0 {
+		t.Fatalf("missing row: cfg=%+v err=%v", cfg, err)
+	}
+}
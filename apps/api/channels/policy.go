@@ -0,0 +1,45 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/agentsquads/api/policies"
+)
+
+// ErrChannelDisabled is returned when the tenant's policy disables the
+// channel a message arrived on.
+var ErrChannelDisabled = errors.New("channel is disabled for this tenant")
+
+// SetPolicyChecker makes Route reject messages on channels the tenant's
+// policies disable.
+func (r *Router) SetPolicyChecker(checker policies.Checker) {
+	r.policies = checker
+}
+
+// ChannelFeature maps a normalized channel name to its feature policy.
+func ChannelFeature(channel string) string {
+	if channel == "web" {
+		return policies.FeatureWebchat
+	}
+	return channel
+}
+
+// checkChannelPolicy returns ErrChannelDisabled when the tenant's policy
+// disables the channel. Without a policy checker every channel is allowed.
+func (r *Router) checkChannelPolicy(ctx context.Context, tenantID, channel string) error {
+	if r.policies == nil {
+		return nil
+	}
+	feature := ChannelFeature(channel)
+	enabled, err := r.policies.FeatureEnabled(ctx, tenantID, feature)
+	if err != nil {
+		return fmt.Errorf("check channel policy: %w", err)
+	}
+	if !enabled {
+		policies.RecordDenial(ctx, tenantID, feature, "channels.router")
+		return fmt.Errorf("%w: %s", ErrChannelDisabled, channel)
+	}
+	return nil
+}
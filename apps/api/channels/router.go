@@ -14,10 +14,24 @@ import (
 	"strings"
 	"time"
 
+	"github.com/agentsquads/api/jobs"
+	"github.com/agentsquads/api/media"
+	"github.com/agentsquads/api/middleware"
+	"github.com/agentsquads/api/policies"
+	"github.com/agentsquads/api/prompts"
 	"github.com/agentsquads/api/tools"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
+// CorrelationIDKey is the metadata key carrying the id that ties an inbound
+// message to the logs of its routing and outbound delivery.
+const CorrelationIDKey = "correlation_id"
+
+// AgentIDKey is the metadata key selecting the agent, or hand, that answers
+// a message: its prompt template and tools.
+const AgentIDKey = "agent_id"
+
 // InboundMessage is a normalized message payload entering the channel router.
 type InboundMessage struct {
 	TenantID string            `json:"tenant_id"`
@@ -66,6 +80,16 @@ type Router struct {
 	model        string
 	agentBridge  AgentBridge
 	toolRegistry *tools.Registry
+	policies     policies.Checker
+	prompts      PromptResolver
+	jobs         *jobs.Runner
+	upkeep       upkeepConfig
+	// memories supplies persistent memories to the context; see
+	// SetMemoryStore.
+	memories   tools.MemoryBackend
+	memoryTopK int
+	// middleware wraps routing of accepted messages; see Use.
+	middleware []Middleware
 }
 
 func NewRouter(db *sql.DB, redisClient *redis.Client) *Router {
@@ -76,6 +100,8 @@ func NewRouter(db *sql.DB, redisClient *redis.Client) *Router {
 		llmProxyURL:  resolveLLMProxyURL(),
 		model:        resolveModel(),
 		toolRegistry: tools.NewRegistry(),
+		jobs:         jobs.NewRunner(upkeepJobTimeout),
+		upkeep:       upkeepConfigFromEnv(),
 	}
 }
 
@@ -83,17 +109,81 @@ func (r *Router) SetAgentBridge(bridge AgentBridge) {
 	r.agentBridge = bridge
 }
 
+// SetDBQuery gives assistant tool loops the db_query tool for tenants whose
+// policy enables it and who have deployed a Supabase project.
+func (r *Router) SetDBQuery(backend tools.DBQueryBackend, checker policies.Checker) {
+	r.toolRegistry.SetDBQuery(backend, checker)
+}
+
+// SetWebhookTool gives assistant tool loops the webhook_call tool for tenants
+// whose webhook_tool policy enables it.
+func (r *Router) SetWebhookTool(source tools.PolicySettingsSource) {
+	r.toolRegistry.SetWebhookTool(tools.NewWebhookTool(source))
+}
+
+// SetFetchAllowlist lets tool requests of tenants whose internal_fetch policy
+// enables it reach the internal hosts listed in its settings.
+func (r *Router) SetFetchAllowlist(source tools.PolicySettingsSource) {
+	r.toolRegistry.SetFetchAllowlist(source)
+}
+
+// SetCustomTools gives assistant tool loops each tenant's custom tools.
+func (r *Router) SetCustomTools(backend tools.CustomToolBackend) {
+	r.toolRegistry.SetCustomTools(backend)
+}
+
+// SetMediaReader gives assistant tool loops the file_read tool for files
+// users attach to channel messages.
+func (r *Router) SetMediaReader(reader tools.MediaReader) {
+	r.toolRegistry.SetMediaReader(reader)
+}
+
+// PromptResolver looks up the active admin-managed prompt template for a
+// tenant.
+type PromptResolver interface {
+	Resolve(ctx context.Context, tenantID, name string) (prompts.Template, bool, error)
+}
+
+// SetPromptResolver makes agent-mode requests without an explicit
+// system_prompt use the active "agent.<agent_id>" template.
+func (r *Router) SetPromptResolver(resolver PromptResolver) {
+	r.prompts = resolver
+}
+
 // Route normalizes, persists, executes, persists response, publishes, and returns outbound payload.
 func (r *Router) Route(ctx context.Context, msg InboundMessage) (OutboundMessage, error) {
 	normalized, err := normalizeInbound(msg)
 	if err != nil {
 		return OutboundMessage{}, err
 	}
+	// Messages posted through the API are correlated by their request id.
+	if normalized.Metadata[CorrelationIDKey] == "" {
+		normalized.Metadata[CorrelationIDKey] = middleware.RequestIDFromContext(ctx)
+	}
+	if normalized.Metadata[CorrelationIDKey] == "" {
+		normalized.Metadata[CorrelationIDKey] = uuid.NewString()
+	}
+	slog.InfoContext(ctx, "channel message received",
+		"tenant_id", normalized.TenantID,
+		"channel", normalized.Channel,
+		"content_length", len(normalized.Content),
+		"has_attachments", len(media.RefsFromMetadata(normalized.Metadata)) > 0,
+		"correlation_id", normalized.Metadata[CorrelationIDKey],
+	)
+	if err := r.checkChannelPolicy(ctx, normalized.TenantID, normalized.Channel); err != nil {
+		return OutboundMessage{}, err
+	}
+	return r.chain(r.route)(ctx, normalized)
+}
 
+// route persists a normalized message, generates or delegates the reply and
+// publishes it. It is the innermost step of the middleware chain.
+func (r *Router) route(ctx context.Context, normalized InboundMessage) (OutboundMessage, error) {
 	conversationID, err := r.saveInbound(ctx, normalized)
 	if err != nil {
 		return OutboundMessage{}, err
 	}
+	r.recordMessageStats(ctx, normalized.TenantID, normalized.Channel, time.Now())
 
 	assistantContent := ""
 	outMetadata := map[string]string{}
@@ -131,6 +221,8 @@ func (r *Router) Route(ctx context.Context, msg InboundMessage) (OutboundMessage
 	if err := r.saveAssistant(ctx, conversationID, normalized.Channel, assistantContent); err != nil {
 		return OutboundMessage{}, err
 	}
+	r.recordMessageStats(ctx, normalized.TenantID, normalized.Channel, time.Now())
+	r.scheduleConversationUpkeep(ctx, normalized.TenantID, conversationID)
 
 	out := OutboundMessage{
 		TenantID:       normalized.TenantID,
@@ -147,6 +239,25 @@ func (r *Router) Route(ctx context.Context, msg InboundMessage) (OutboundMessage
 	return out, nil
 }
 
+// Record stores an inbound message in its conversation without generating a
+// response, for messages that are context rather than a task for the agent.
+// It returns the conversation id.
+func (r *Router) Record(ctx context.Context, msg InboundMessage) (string, error) {
+	normalized, err := normalizeInbound(msg)
+	if err != nil {
+		return "", err
+	}
+	if err := r.checkChannelPolicy(ctx, normalized.TenantID, normalized.Channel); err != nil {
+		return "", err
+	}
+	conversationID, err := r.saveInbound(ctx, normalized)
+	if err != nil {
+		return "", err
+	}
+	r.recordMessageStats(ctx, normalized.TenantID, normalized.Channel, time.Now())
+	return conversationID, nil
+}
+
 func normalizeInbound(msg InboundMessage) (InboundMessage, error) {
 	msg.TenantID = strings.TrimSpace(msg.TenantID)
 	msg.Content = strings.TrimSpace(msg.Content)
@@ -196,17 +307,24 @@ func (r *Router) saveInbound(ctx context.Context, msg InboundMessage) (string, e
 		return "", fmt.Errorf("marshal metadata: %w", err)
 	}
 
-	_, err = tx.ExecContext(ctx,
+	var messageID string
+	err = tx.QueryRowContext(ctx,
 		`INSERT INTO messages (conversation_id, role, content, channel, metadata)
-		 VALUES ($1, 'user', $2, $3, $4::jsonb)`,
+		 VALUES ($1, 'user', $2, $3, $4::jsonb)
+		 RETURNING id`,
 		conversationID,
 		msg.Content,
 		msg.Channel,
 		metadataJSON,
-	)
+	).Scan(&messageID)
 	if err != nil {
 		return "", fmt.Errorf("insert user message: %w", err)
 	}
+	if refs := media.RefsFromMetadata(msg.Metadata); len(refs) > 0 {
+		if err := media.LinkToMessage(ctx, tx, msg.TenantID, conversationID, messageID, refs); err != nil {
+			return "", err
+		}
+	}
 
 	if err := tx.Commit(); err != nil {
 		return "", fmt.Errorf("commit transaction: %w", err)
@@ -253,13 +371,16 @@ func conversationIDFromMetadata(metadata map[string]string) string {
 }
 
 func (r *Router) generateAssistantResponse(ctx context.Context, tenantID, conversationID string, metadata map[string]string) (string, error) {
+	// Messages folded into the rolling summary are replaced by the summary.
 	rows, err := r.db.QueryContext(ctx,
 		`SELECT role, content
 		 FROM (
-		   SELECT role, content, created_at
-		   FROM messages
-		   WHERE conversation_id = $1
-		   ORDER BY created_at DESC
+		   SELECT m.role, m.content, m.created_at
+		   FROM messages m
+		   JOIN conversations c ON c.id = m.conversation_id
+		   WHERE m.conversation_id = $1
+		     AND (c.summarized_through IS NULL OR m.created_at > c.summarized_through)
+		   ORDER BY m.created_at DESC
 		   LIMIT 50
 		 ) recent
 		 ORDER BY created_at ASC`,
@@ -285,14 +406,25 @@ func (r *Router) generateAssistantResponse(ctx context.Context, tenantID, conver
 		return "", errors.New("no conversation context available")
 	}
 
-	// Prepend system prompt from metadata (agent mode)
-	if sp, ok := metadata["system_prompt"]; ok && strings.TrimSpace(sp) != "" {
+	summary, err := r.conversationSummary(ctx, conversationID)
+	if err != nil {
+		return "", err
+	}
+	if summary != "" {
+		messages = append([]tools.Message{{Role: "system", Content: summaryContextPrefix + summary}}, messages...)
+	}
+	if remembered := r.memoryContext(ctx, tenantID, conversationID, messages); remembered != "" {
+		messages = append([]tools.Message{{Role: "system", Content: remembered}}, messages...)
+	}
+
+	// Prepend system prompt from metadata, or the agent type's template (agent mode)
+	agentID := strings.TrimSpace(metadata[AgentIDKey])
+	if sp := r.agentSystemPrompt(ctx, tenantID, agentID, metadata); sp != "" {
 		messages = append([]tools.Message{{Role: "system", Content: sp}}, messages...)
 	}
 
-	// Determine agent ID and get tools
-	agentID := strings.TrimSpace(metadata["agent_id"])
-	agentTools := r.toolRegistry.GetTools(agentID)
+	// Get tools for the agent
+	agentTools := r.toolRegistry.ToolsForTenant(ctx, tenantID, agentID)
 
 	// Set up memory context scoped to this conversation
 	toolCtx := tools.WithMemoryContext(ctx, tenantID, conversationID)
@@ -313,6 +445,12 @@ func (r *Router) generateAssistantResponse(ctx context.Context, tenantID, conver
 	}
 
 	// Fallback: plain chat completion (no tools)
+	return r.complete(ctx, tenantID, model, messages)
+}
+
+// complete sends messages to the LLM proxy as a plain chat completion billed
+// to tenantID and returns the assistant text.
+func (r *Router) complete(ctx context.Context, tenantID, model string, messages []tools.Message) (string, error) {
 	payloadBody, err := json.Marshal(map[string]any{
 		"model":    model,
 		"messages": messages,
@@ -327,7 +465,7 @@ func (r *Router) generateAssistantResponse(ctx context.Context, tenantID, conver
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Tenant-ID", tenantID)
-	if serviceKey != "" {
+	if serviceKey := strings.TrimSpace(os.Getenv("SERVICE_API_KEY")); serviceKey != "" {
 		req.Header.Set("X-Service-API-Key", serviceKey)
 	}
 
@@ -382,20 +520,7 @@ func (r *Router) saveAssistant(ctx context.Context, conversationID, channel, con
 }
 
 func (r *Router) publishResponse(ctx context.Context, out OutboundMessage) error {
-	if r.redis == nil {
-		return errors.New("redis is not configured")
-	}
-
-	payload, err := json.Marshal(out)
-	if err != nil {
-		return fmt.Errorf("marshal outbound message: %w", err)
-	}
-
-	topic := fmt.Sprintf("tenant:%s:response", out.TenantID)
-	if err := r.redis.Publish(ctx, topic, payload).Err(); err != nil {
-		return fmt.Errorf("publish outbound message: %w", err)
-	}
-	return nil
+	return PublishOutbound(ctx, r.redis, out)
 }
 
 func resolveLLMProxyURL() string {
@@ -413,6 +538,27 @@ func resolveLLMProxyURL() string {
 	return base + "/v1/chat/completions"
 }
 
+// agentSystemPrompt returns the request's system prompt: an explicit
+// metadata system_prompt wins, then the active template for the agent type.
+func (r *Router) agentSystemPrompt(ctx context.Context, tenantID, agentID string, metadata map[string]string) string {
+	if sp := strings.TrimSpace(metadata["system_prompt"]); sp != "" {
+		return metadata["system_prompt"]
+	}
+	if r.prompts == nil || agentID == "" {
+		return ""
+	}
+	t, found, err := r.prompts.Resolve(ctx, tenantID, prompts.AgentName(agentID))
+	if err != nil {
+		slog.Warn("resolve agent prompt failed", "tenant", tenantID, "agent_id", agentID, "err", err)
+		return ""
+	}
+	if !found {
+		return ""
+	}
+	slog.Debug("using agent prompt template", "tenant", tenantID, "template", t.Name, "version", t.Version)
+	return t.Content
+}
+
 func resolveRequestModel(metadata map[string]string, fallback string) string {
 	if m, ok := metadata["model"]; ok && strings.TrimSpace(m) != "" {
 		return strings.TrimSpace(m)
@@ -1,7 +1,11 @@
 package channels
 
 import (
+	"context"
+	"errors"
 	"testing"
+
+	"github.com/agentsquads/api/prompts"
 )
 
 func TestNormalizeInbound(t *testing.T) {
@@ -57,3 +61,48 @@ func TestConversationIDFromMetadata(t *testing.T) {
 		})
 	}
 }
+
+type stubPolicies map[string]bool
+
+func (s stubPolicies) FeatureEnabled(_ context.Context, _ string, feature string) (bool, error) {
+	return s[feature], nil
+}
+
+func TestRouteRejectsDisabledChannel(t *testing.T) {
+	t.Parallel()
+	r := NewRouter(nil, nil)
+	r.SetPolicyChecker(stubPolicies{"telegram": true})
+
+	_, err := r.Route(context.Background(), InboundMessage{TenantID: "t1", Content: "hi", Channel: "web"})
+	if !errors.Is(err, ErrChannelDisabled) {
+		t.Fatalf("err = %v, want ErrChannelDisabled", err)
+	}
+	if got := ChannelFeature("web"); got != "webchat" {
+		t.Fatalf("ChannelFeature(web) = %q", got)
+	}
+}
+
+type stubPromptResolver map[string]prompts.Template
+
+func (s stubPromptResolver) Resolve(_ context.Context, _ string, name string) (prompts.Template, bool, error) {
+	t, ok := s[name]
+	return t, ok, nil
+}
+
+func TestAgentSystemPrompt(t *testing.T) {
+	t.Parallel()
+	r := &Router{}
+	if got := r.agentSystemPrompt(context.Background(), "t1", "coder", nil); got != "" {
+		t.Fatalf("without resolver = %q", got)
+	}
+	r.SetPromptResolver(stubPromptResolver{"agent.coder": {Name: "agent.coder", Version: 2, Content: "You write Go."}})
+	if got := r.agentSystemPrompt(context.Background(), "t1", "Coder", nil); got != "You write Go." {
+		t.Fatalf("template prompt = %q", got)
+	}
+	if got := r.agentSystemPrompt(context.Background(), "t1", "coder", map[string]string{"system_prompt": "explicit"}); got != "explicit" {
+		t.Fatalf("metadata prompt = %q", got)
+	}
+	if got := r.agentSystemPrompt(context.Background(), "t1", "writer", nil); got != "" {
+		t.Fatalf("unknown agent prompt = %q", got)
+	}
+}
@@ -0,0 +1,88 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	messageStatsWindowDays = 7
+	messageStatsBucketTTL  = (messageStatsWindowDays + 1) * 24 * time.Hour
+)
+
+// ChannelActivity is the pre-aggregated message activity for one channel.
+type ChannelActivity struct {
+	MessageCount7d int64
+	LastMessageAt  time.Time
+}
+
+func messageCountKey(tenantID, channel string, day time.Time) string {
+	return fmt.Sprintf("tenant:%s:channel:%s:messages:%s", tenantID, channel, day.UTC().Format("20060102"))
+}
+
+func lastMessageKey(tenantID, channel string) string {
+	return fmt.Sprintf("tenant:%s:channel:%s:last_message_at", tenantID, channel)
+}
+
+// recordMessageStats bumps the daily message counter and last-message time
+// for a tenant channel. Failures are logged and never block routing.
+func (r *Router) recordMessageStats(ctx context.Context, tenantID, channel string, at time.Time) {
+	if r.redis == nil {
+		return
+	}
+	countKey := messageCountKey(tenantID, channel, at)
+	pipe := r.redis.TxPipeline()
+	pipe.Incr(ctx, countKey)
+	pipe.Expire(ctx, countKey, messageStatsBucketTTL)
+	pipe.Set(ctx, lastMessageKey(tenantID, channel), at.UTC().Unix(), messageStatsBucketTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.Warn("failed to record channel message stats", "tenant", tenantID, "channel", channel, "err", err)
+	}
+}
+
+// ChannelActivity reads the 7-day message count and last message time from
+// the Redis counters. ok is false when no counters exist for the channel, in
+// which case callers should fall back to the database.
+func (r *Router) ChannelActivity(ctx context.Context, tenantID, channel string, now time.Time) (ChannelActivity, bool, error) {
+	if r == nil || r.redis == nil {
+		return ChannelActivity{}, false, nil
+	}
+
+	keys := make([]string, 0, messageStatsWindowDays+1)
+	for i := range messageStatsWindowDays {
+		keys = append(keys, messageCountKey(tenantID, channel, now.AddDate(0, 0, -i)))
+	}
+	keys = append(keys, lastMessageKey(tenantID, channel))
+
+	values, err := r.redis.MGet(ctx, keys...).Result()
+	if err != nil && err != redis.Nil {
+		return ChannelActivity{}, false, fmt.Errorf("read channel message stats: %w", err)
+	}
+
+	var (
+		activity ChannelActivity
+		found    bool
+	)
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			continue
+		}
+		found = true
+		if i == len(values)-1 {
+			activity.LastMessageAt = time.Unix(n, 0).UTC()
+			continue
+		}
+		activity.MessageCount7d += n
+	}
+	return activity, found, nil
+}
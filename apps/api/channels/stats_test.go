@@ -0,0 +1,31 @@
+package channels
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMessageStatsKeys(t *testing.T) {
+	t.Parallel()
+	day := time.Date(2026, 2, 3, 23, 59, 0, 0, time.UTC)
+	if got := messageCountKey("t1", "telegram", day); got != "tenant:t1:channel:telegram:messages:20260203" {
+		t.Fatalf("messageCountKey = %q", got)
+	}
+	if got := lastMessageKey("t1", "web"); got != "tenant:t1:channel:web:last_message_at" {
+		t.Fatalf("lastMessageKey = %q", got)
+	}
+}
+
+func TestChannelActivityWithoutRedisFallsBack(t *testing.T) {
+	t.Parallel()
+	var nilRouter *Router
+	if _, ok, err := nilRouter.ChannelActivity(context.Background(), "t1", "web", time.Now()); ok || err != nil {
+		t.Fatalf("nil router: ok=%v err=%v", ok, err)
+	}
+	r := &Router{}
+	if _, ok, err := r.ChannelActivity(context.Background(), "t1", "web", time.Now()); ok || err != nil {
+		t.Fatalf("router without redis: ok=%v err=%v", ok, err)
+	}
+	r.recordMessageStats(context.Background(), "t1", "web", time.Now())
+}
@@ -0,0 +1,122 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	outboundStreamPrefix     = "channels:outbound:"
+	outboundDeadLetterStream = "channels:outbound:dead"
+	outboundStreamShards     = 8
+	outboundStreamMaxLen     = 10000
+	fanoutConsumerGroup      = "fanout"
+	maxDeliveryAttempts      = 5
+)
+
+// outboundEntry is an outbound message plus its delivery metadata as stored
+// on a stream entry. Retries are re-added as new entries carrying the updated
+// metadata, so every entry is self-describing.
+type outboundEntry struct {
+	Message   OutboundMessage
+	Attempts  int
+	LastError string
+	Delivered []string
+}
+
+// outboundStreamKey shards tenants across a fixed set of streams so a tenant's
+// messages stay ordered within one stream.
+func outboundStreamKey(tenantID string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(tenantID))
+	return fmt.Sprintf("%s%d", outboundStreamPrefix, h.Sum32()%outboundStreamShards)
+}
+
+func outboundStreamKeys() []string {
+	keys := make([]string, outboundStreamShards)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%s%d", outboundStreamPrefix, i)
+	}
+	return keys
+}
+
+// PublishOutbound appends an outbound message to the tenant's fanout stream.
+func PublishOutbound(ctx context.Context, rdb *redis.Client, out OutboundMessage) error {
+	if rdb == nil {
+		return errors.New("redis is not configured")
+	}
+	if strings.TrimSpace(out.TenantID) == "" {
+		return errors.New("tenant id is required")
+	}
+	return addOutboundEntry(ctx, rdb, outboundStreamKey(out.TenantID), outboundEntry{Message: out})
+}
+
+func addOutboundEntry(ctx context.Context, rdb *redis.Client, stream string, entry outboundEntry) error {
+	values, err := encodeOutboundEntry(entry)
+	if err != nil {
+		return err
+	}
+	if err := rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: outboundStreamMaxLen,
+		Approx: true,
+		Values: values,
+	}).Err(); err != nil {
+		return fmt.Errorf("publish outbound message: %w", err)
+	}
+	return nil
+}
+
+func encodeOutboundEntry(entry outboundEntry) (map[string]any, error) {
+	payload, err := json.Marshal(entry.Message)
+	if err != nil {
+		return nil, fmt.Errorf("marshal outbound message: %w", err)
+	}
+	return map[string]any{
+		"payload":    string(payload),
+		"tenant_id":  entry.Message.TenantID,
+		"attempts":   strconv.Itoa(entry.Attempts),
+		"last_error": entry.LastError,
+		"delivered":  strings.Join(entry.Delivered, ","),
+	}, nil
+}
+
+func decodeOutboundEntry(values map[string]any) (outboundEntry, error) {
+	field := func(name string) string {
+		v, _ := values[name].(string)
+		return v
+	}
+
+	var entry outboundEntry
+	if err := json.Unmarshal([]byte(field("payload")), &entry.Message); err != nil {
+		return outboundEntry{}, fmt.Errorf("decode outbound payload: %w", err)
+	}
+	if entry.Message.TenantID == "" {
+		entry.Message.TenantID = field("tenant_id")
+	}
+	entry.Attempts, _ = strconv.Atoi(field("attempts"))
+	entry.LastError = field("last_error")
+	if delivered := field("delivered"); delivered != "" {
+		entry.Delivered = strings.Split(delivered, ",")
+	}
+	return entry, nil
+}
+
+// fanoutConsumerName returns a unique consumer name for this process so
+// replicas never share pending entries.
+func fanoutConsumerName() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "fanout"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.New().String()[:8])
+}
@@ -0,0 +1,138 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestOutboundEntryRoundTrip(t *testing.T) {
+	t.Parallel()
+	in := outboundEntry{
+		Message:   OutboundMessage{TenantID: "t1", Content: "hi", Channel: "telegram"},
+		Attempts:  2,
+		LastError: "telegram: telegram returned 502",
+		Delivered: []string{"web"},
+	}
+	values, err := encodeOutboundEntry(in)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	out, err := decodeOutboundEntry(values)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.Message.TenantID != "t1" || out.Attempts != 2 || out.LastError != in.LastError || len(out.Delivered) != 1 || out.Delivered[0] != "web" {
+		t.Fatalf("round trip mismatch: %+v", out)
+	}
+
+	if _, err := decodeOutboundEntry(map[string]any{"payload": "{"}); err == nil {
+		t.Fatalf("expected decode error for bad payload")
+	}
+}
+
+func TestOutboundStreamKeyIsStable(t *testing.T) {
+	t.Parallel()
+	key := outboundStreamKey("tenant-a")
+	if key != outboundStreamKey("tenant-a") {
+		t.Fatalf("stream key is not deterministic")
+	}
+	found := false
+	for _, k := range outboundStreamKeys() {
+		if k == key {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("key %q not in shard set", key)
+	}
+}
+
+func TestPublishOutboundRequiresRedis(t *testing.T) {
+	t.Parallel()
+	if err := PublishOutbound(context.Background(), nil, OutboundMessage{TenantID: "t1"}); err == nil {
+		t.Fatalf("expected error without redis")
+	}
+}
+
+func TestFanoutDeliverSkipsDeliveredAndReportsFailures(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	f := NewFanout(nil, NewLinkStore(db), NewCredentialsStore(db))
+	f.http = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.Contains(req.URL.String(), "telegram") {
+			return nil, errors.New("connection reset")
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok")), Header: make(http.Header)}, nil
+	})}
+
+	rows := sqlmock.NewRows([]string{"id", "tenant_id", "channel", "channel_user_id", "linked_at", "muted", "notification_level"}).
+		AddRow("1", "t1", "telegram", "u1", time.Now(), false, "milestones").
+		AddRow("2", "t1", "whatsapp", "u1", time.Now(), false, "milestones").
+		AddRow("3", "t1", "web", "", time.Now(), false, "milestones")
+	mock.ExpectQuery("SELECT id, tenant_id, channel").WithArgs("t1").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT tenant_id, channel, config::text").WithArgs("t1", "telegram").
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "channel", "config", "updated_at"}).AddRow("t1", "telegram", `{"bot_token":"secret-token"}`, time.Now()))
+
+	delivered, err := f.deliver(context.Background(), OutboundMessage{TenantID: "t1", Content: "hello"}, []string{"whatsapp"})
+	if err == nil || !strings.Contains(err.Error(), "telegram") {
+		t.Fatalf("expected telegram failure, got %v", err)
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Fatalf("error leaks bot token: %v", err)
+	}
+	if len(delivered) != 1 || delivered[0] != "web" {
+		t.Fatalf("delivered = %v, want [web]", delivered)
+	}
+}
+
+func TestFanoutDeliverRepliesToSenderNotLinkedBot(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	var gotBody string
+	f := NewFanout(nil, NewLinkStore(db), NewCredentialsStore(db))
+	f.http = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		gotBody = string(body)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok")), Header: make(http.Header)}, nil
+	})}
+
+	// The telegram link stores the bot id; the reply targets the chat.
+	mock.ExpectQuery("SELECT id, tenant_id, channel").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "channel", "channel_user_id", "linked_at", "muted", "notification_level"}).
+			AddRow("1", "t1", "telegram", "4242", time.Now(), false, "milestones"))
+	mock.ExpectQuery("SELECT tenant_id, channel, config::text").WithArgs("t1", "telegram").
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "channel", "config", "updated_at"}).AddRow("t1", "telegram", `{"bot_token":"tok"}`, time.Now()))
+
+	delivered, err := f.deliver(context.Background(), OutboundMessage{
+		TenantID: "t1",
+		Content:  "hello",
+		Channel:  "telegram",
+		Metadata: map[string]string{"channel_user_id": "777"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	if len(delivered) != 1 || delivered[0] != "telegram" {
+		t.Fatalf("delivered = %v, want [telegram]", delivered)
+	}
+	if !strings.Contains(gotBody, `"chat_id":"777"`) {
+		t.Fatalf("telegram body = %s, want chat_id 777", gotBody)
+	}
+}
@@ -4,14 +4,23 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/agentsquads/api/channels"
+	"github.com/agentsquads/api/i18n"
+	"github.com/agentsquads/api/media"
+)
+
+const (
+	maxProxyAttempts         = 4
+	defaultProxyRetryBackoff = time.Second
 )
 
 // Bridge routes inbound channel messages into coordinator swarm runs.
@@ -20,19 +29,43 @@ type Bridge struct {
 	httpClient  *http.Client
 	llmProxyURL string
 	model       string
+	// retryBackoff is the first wait before retrying a proxy call that hit
+	// the concurrency limit; it doubles on every retry.
+	retryBackoff time.Duration
+	rubrics      RubricSource
+	now          func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]pendingClarification // clarificationKey -> task awaiting details
 }
 
 func NewBridge(handler *Handler) *Bridge {
 	return &Bridge{
-		handler:     handler,
-		httpClient:  &http.Client{Timeout: 30 * time.Second},
-		llmProxyURL: resolveLLMProxyURL(),
-		model:       resolveModel(),
+		handler:      handler,
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		llmProxyURL:  resolveLLMProxyURL(),
+		model:        resolveModel(),
+		retryBackoff: defaultProxyRetryBackoff,
+		now:          time.Now,
+		pending:      make(map[string]pendingClarification),
 	}
 }
 
 // HandleChannelMessage decides whether inbound channel traffic should trigger the agent swarm.
+// A reply to a pending clarification question completes that task instead,
+// and /notifications changes which run updates the channel receives.
 func (b *Bridge) HandleChannelMessage(ctx context.Context, req channels.AgentTaskRequest) (channels.AgentTaskResult, error) {
+	if level, ok := parseNotificationsCommand(req.Content); ok {
+		return b.handleNotificationsCommand(ctx, req, level)
+	}
+	key := clarificationKey(req)
+	if pending, ok := b.takePending(key); ok {
+		if strings.EqualFold(strings.TrimSpace(req.Content), cancelClarificationCommand) {
+			return channels.AgentTaskResult{Accepted: true, Ack: b.handler.text(ctx, req.TenantID, req.Metadata, i18n.MsgCommandTaskDropped)}, nil
+		}
+		return b.startChannelRun(ctx, req, mergeClarification(pending.task, req.Content), pending.triggerType)
+	}
+
 	task, triggerType, ok := parseExplicitCommand(req.Content)
 	if !ok {
 		classifiedTask, classified := b.classifyTask(ctx, req)
@@ -43,6 +76,19 @@ func (b *Bridge) HandleChannelMessage(ctx context.Context, req channels.AgentTas
 		triggerType = "classifier"
 	}
 
+	if key != "" && b.clarificationEnabled(ctx, req.TenantID) {
+		if question, unclear := b.clarifyingQuestion(ctx, req.TenantID, task); unclear {
+			b.storePending(key, pendingClarification{task: task, triggerType: triggerType})
+			return channels.AgentTaskResult{
+				Accepted: true,
+				Ack:      b.handler.text(ctx, req.TenantID, req.Metadata, i18n.MsgCommandClarify, "question", question),
+			}, nil
+		}
+	}
+	return b.startChannelRun(ctx, req, task, triggerType)
+}
+
+func (b *Bridge) startChannelRun(ctx context.Context, req channels.AgentTaskRequest, task, triggerType string) (channels.AgentTaskResult, error) {
 	channelCtx := &ChannelContext{
 		Channel:        req.Channel,
 		ConversationID: req.ConversationID,
@@ -56,22 +102,45 @@ func (b *Bridge) HandleChannelMessage(ctx context.Context, req channels.AgentTas
 	}
 
 	run, err := b.handler.StartRun(ctx, req.TenantID, RunRequest{
-		Task:           task,
+		Task:           withAttachedFiles(task, media.RefsFromMetadata(req.Metadata)),
 		TriggerType:    triggerType,
 		ChannelContext: channelCtx,
 	})
+	if errors.Is(err, ErrSwarmDisabled) {
+		// Classifier matches fall back to a normal assistant reply; explicit
+		// commands get told why nothing started.
+		if triggerType == "classifier" {
+			return channels.AgentTaskResult{}, nil
+		}
+		return channels.AgentTaskResult{Accepted: true, Ack: b.handler.text(ctx, req.TenantID, req.Metadata, i18n.MsgCommandSwarmDisabled)}, nil
+	}
 	if err != nil {
 		return channels.AgentTaskResult{}, err
 	}
 
-	ack := fmt.Sprintf("Agent swarm started (`%s`). I will stream progress updates here.", run.RunID)
 	return channels.AgentTaskResult{
 		Accepted: true,
-		Ack:      ack,
+		Ack:      b.handler.text(ctx, req.TenantID, req.Metadata, i18n.MsgCommandRunStarted, "run_id", run.RunID),
 		RunID:    run.RunID,
 	}, nil
 }
 
+// withAttachedFiles lists the files sent with the message under the task so
+// agents know they can read them. Files the task text already names, as an
+// explicit command keeps the message's attachment line, are not repeated.
+func withAttachedFiles(task string, refs []media.Ref) string {
+	var lines []string
+	for _, ref := range refs {
+		if !strings.Contains(task, ref.ID) {
+			lines = append(lines, "- "+ref.Describe())
+		}
+	}
+	if len(lines) == 0 {
+		return task
+	}
+	return task + "\n\nFiles attached to the request:\n" + strings.Join(lines, "\n")
+}
+
 func parseExplicitCommand(content string) (task, triggerType string, ok bool) {
 	trimmed := strings.TrimSpace(content)
 	lower := strings.ToLower(trimmed)
@@ -97,42 +166,44 @@ func (b *Bridge) classifyTask(ctx context.Context, req channels.AgentTaskRequest
 }
 
 func (b *Bridge) classifyWithLLM(ctx context.Context, req channels.AgentTaskRequest) (string, bool) {
+	prompt := "Decide whether this message should trigger an autonomous multi-step agent swarm. Return strict JSON: {\"trigger\":true|false,\"task\":\"...\"}. Set trigger=true only when the user asks for delegated execution."
+
+	var parsed struct {
+		Trigger bool   `json:"trigger"`
+		Task    string `json:"task"`
+	}
+	if !b.completeJSON(ctx, req.TenantID, prompt, req.Content, &parsed) || !parsed.Trigger {
+		return "", false
+	}
+	task := strings.TrimSpace(parsed.Task)
+	if task == "" {
+		task = strings.TrimSpace(req.Content)
+	}
+	return task, true
+}
+
+// completeJSON sends a system and user message through the LLM proxy and
+// decodes the JSON object in the reply into out. It reports false on any
+// failure so callers can fall back.
+func (b *Bridge) completeJSON(ctx context.Context, tenantID, system, user string, out any) bool {
 	type llmMessage struct {
 		Role    string `json:"role"`
 		Content string `json:"content"`
 	}
-	prompt := "Decide whether this message should trigger an autonomous multi-step agent swarm. Return strict JSON: {\"trigger\":true|false,\"task\":\"...\"}. Set trigger=true only when the user asks for delegated execution."
-
 	payload, err := json.Marshal(map[string]any{
 		"model": b.model,
 		"messages": []llmMessage{
-			{Role: "system", Content: prompt},
-			{Role: "user", Content: req.Content},
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
 		},
 	})
 	if err != nil {
-		return "", false
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, b.llmProxyURL, bytes.NewReader(payload))
-	if err != nil {
-		return "", false
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("X-Tenant-ID", req.TenantID)
-	if serviceKey := strings.TrimSpace(os.Getenv("SERVICE_API_KEY")); serviceKey != "" {
-		httpReq.Header.Set("X-Service-API-Key", serviceKey)
-	}
-
-	resp, err := b.httpClient.Do(httpReq)
-	if err != nil {
-		return "", false
+		return false
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil || resp.StatusCode >= http.StatusBadRequest {
-		return "", false
+	body, ok := b.postCompletion(ctx, tenantID, payload)
+	if !ok {
+		return false
 	}
 
 	var completion struct {
@@ -143,36 +214,86 @@ func (b *Bridge) classifyWithLLM(ctx context.Context, req channels.AgentTaskRequ
 		} `json:"choices"`
 	}
 	if err := json.Unmarshal(body, &completion); err != nil || len(completion.Choices) == 0 {
-		return "", false
+		return false
 	}
 
 	content := strings.TrimSpace(completion.Choices[0].Message.Content)
 	if content == "" {
-		return "", false
+		return false
 	}
-
-	var parsed struct {
-		Trigger bool   `json:"trigger"`
-		Task    string `json:"task"`
-	}
-	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
+	if err := json.Unmarshal([]byte(content), out); err != nil {
 		start := strings.Index(content, "{")
 		end := strings.LastIndex(content, "}")
 		if start < 0 || end <= start {
-			return "", false
+			return false
 		}
-		if err := json.Unmarshal([]byte(content[start:end+1]), &parsed); err != nil {
-			return "", false
+		if err := json.Unmarshal([]byte(content[start:end+1]), out); err != nil {
+			return false
 		}
 	}
-	if !parsed.Trigger {
-		return "", false
+	return true
+}
+
+// postCompletion sends a chat completion request to the LLM proxy and returns
+// the body of a successful response. A 429 concurrency_limit means the
+// tenant's proxy slots are busy, not that the request failed, so it is retried
+// with backoff, honoring Retry-After, up to maxProxyAttempts times.
+func (b *Bridge) postCompletion(ctx context.Context, tenantID string, payload []byte) ([]byte, bool) {
+	backoff := b.retryBackoff
+	for attempt := 1; ; attempt++ {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, b.llmProxyURL, bytes.NewReader(payload))
+		if err != nil {
+			return nil, false
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("X-Tenant-ID", tenantID)
+		if serviceKey := strings.TrimSpace(os.Getenv("SERVICE_API_KEY")); serviceKey != "" {
+			httpReq.Header.Set("X-Service-API-Key", serviceKey)
+		}
+
+		resp, err := b.httpClient.Do(httpReq)
+		if err != nil {
+			return nil, false
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, false
+		}
+		if resp.StatusCode < http.StatusBadRequest {
+			return body, true
+		}
+		if attempt >= maxProxyAttempts || !isConcurrencyLimit(resp.StatusCode, body) {
+			return nil, false
+		}
+
+		wait := backoff
+		if secs, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After"))); err == nil && secs > 0 {
+			wait = time.Duration(secs) * time.Second
+		}
+		backoff *= 2
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, false
+		case <-timer.C:
+		}
 	}
-	task := strings.TrimSpace(parsed.Task)
-	if task == "" {
-		task = strings.TrimSpace(req.Content)
+}
+
+// isConcurrencyLimit reports whether a proxy response is the 429 it sends
+// when a call waited too long for a free concurrency slot.
+func isConcurrencyLimit(status int, body []byte) bool {
+	if status != http.StatusTooManyRequests {
+		return false
 	}
-	return task, true
+	var parsed struct {
+		Error struct {
+			Type string `json:"type"`
+		} `json:"error"`
+	}
+	return json.Unmarshal(body, &parsed) == nil && parsed.Error.Type == "concurrency_limit"
 }
 
 func heuristicClassification(content string) (string, bool) {
@@ -227,3 +348,19 @@ func resolveModel() string {
 	}
 	return model
 }
+
+// loadAgentModels reads SWARM_AGENT_MODELS, a comma-separated list of
+// "<agent type>=<model>" pairs such as "Research Hand=openai/gpt-4.1".
+// Agent types it does not list run on LLM_MODEL.
+func loadAgentModels() map[string]string {
+	models := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv("SWARM_AGENT_MODELS"), ",") {
+		agentType, model, ok := strings.Cut(pair, "=")
+		agentType, model = strings.TrimSpace(agentType), strings.TrimSpace(model)
+		if !ok || agentType == "" || model == "" {
+			continue
+		}
+		models[agentType] = model
+	}
+	return models
+}
@@ -0,0 +1,156 @@
+package coordinator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/channels"
+	"github.com/agentsquads/api/policies"
+)
+
+const (
+	cancelClarificationCommand = "/cancel"
+	defaultClarificationRubric = "The task names what to change or build, where (which site, repo, page or system), and what a finished result looks like."
+)
+
+// RubricSource returns a tenant's clarification rubric. An empty rubric means
+// the configured default.
+type RubricSource interface {
+	ClarificationRubric(ctx context.Context, tenantID string) (string, error)
+}
+
+// RubricStore reads per-tenant rubrics from tenant_clarification_rubrics.
+type RubricStore struct {
+	db *sql.DB
+}
+
+func NewRubricStore(db *sql.DB) *RubricStore {
+	return &RubricStore{db: db}
+}
+
+func (s *RubricStore) ClarificationRubric(ctx context.Context, tenantID string) (string, error) {
+	var rubric string
+	err := s.db.QueryRowContext(ctx, `SELECT rubric FROM tenant_clarification_rubrics WHERE tenant_id = $1`, tenantID).Scan(&rubric)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("load clarification rubric: %w", err)
+	}
+	return strings.TrimSpace(rubric), nil
+}
+
+// SetRubricSource gives tenants their own clarification rubric.
+func (b *Bridge) SetRubricSource(src RubricSource) {
+	b.rubrics = src
+}
+
+// pendingClarification is a channel task held back until the user answers
+// the follow-up question about it.
+type pendingClarification struct {
+	task        string
+	triggerType string
+	expiresAt   time.Time
+}
+
+// clarificationKey identifies the conversation a follow-up answer arrives
+// in. It is empty when the message cannot be tied to one.
+func clarificationKey(req channels.AgentTaskRequest) string {
+	if id := strings.TrimSpace(req.ConversationID); id != "" {
+		return req.TenantID + "|" + id
+	}
+	user := strings.TrimSpace(req.Metadata["channel_user_id"])
+	if user == "" {
+		user = strings.TrimSpace(req.Metadata["user_id"])
+	}
+	if user == "" {
+		return ""
+	}
+	return req.TenantID + "|" + req.Channel + "|" + user
+}
+
+func (b *Bridge) storePending(key string, pending pendingClarification) {
+	now := b.now()
+	pending.expiresAt = now.Add(b.handler.swarmConfig().ClarificationTTL)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for k, p := range b.pending {
+		if !now.Before(p.expiresAt) {
+			delete(b.pending, k)
+		}
+	}
+	b.pending[key] = pending
+}
+
+// takePending removes and returns the conversation's pending task. Expired
+// tasks are dropped and not returned.
+func (b *Bridge) takePending(key string) (pendingClarification, bool) {
+	if key == "" {
+		return pendingClarification{}, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	pending, ok := b.pending[key]
+	if !ok {
+		return pendingClarification{}, false
+	}
+	delete(b.pending, key)
+	return pending, b.now().Before(pending.expiresAt)
+}
+
+// clarificationEnabled reports whether the tenant opted in to follow-up
+// questions through the task_clarification policy.
+func (b *Bridge) clarificationEnabled(ctx context.Context, tenantID string) bool {
+	if b.handler.policies == nil {
+		return false
+	}
+	enabled, err := b.handler.policies.FeatureEnabled(ctx, tenantID, policies.FeatureTaskClarification)
+	if err != nil {
+		slog.Warn("failed to check clarification policy", "tenant", tenantID, "err", err)
+		return false
+	}
+	return enabled
+}
+
+// clarifyingQuestion asks the LLM whether task meets the tenant's rubric and,
+// when it does not, for one question that would fill the gap. Any failure
+// lets the task through rather than blocking it.
+func (b *Bridge) clarifyingQuestion(ctx context.Context, tenantID, task string) (string, bool) {
+	if strings.TrimSpace(b.llmProxyURL) == "" {
+		return "", false
+	}
+	rubric := b.handler.swarmConfig().ClarificationRubric
+	if b.rubrics != nil {
+		custom, err := b.rubrics.ClarificationRubric(ctx, tenantID)
+		if err != nil {
+			slog.Warn("failed to load clarification rubric", "tenant", tenantID, "err", err)
+		} else if custom != "" {
+			rubric = custom
+		}
+	}
+
+	prompt := "Decide whether this task is specific enough for an autonomous agent swarm to plan and carry out without asking anything. " +
+		"Rubric: " + rubric + " Return strict JSON: {\"sufficient\":true|false,\"question\":\"...\"}. " +
+		"When sufficient is false, question is one short follow-up asking for the missing details."
+	var parsed struct {
+		Sufficient bool   `json:"sufficient"`
+		Question   string `json:"question"`
+	}
+	if !b.completeJSON(ctx, tenantID, prompt, task, &parsed) || parsed.Sufficient {
+		return "", false
+	}
+	question := strings.TrimSpace(parsed.Question)
+	if question == "" {
+		question = "Could you add more detail about what you want done?"
+	}
+	return question, true
+}
+
+func mergeClarification(task, answer string) string {
+	return strings.TrimSpace(task) + "\n\nAdditional details from the user: " + strings.TrimSpace(answer)
+}
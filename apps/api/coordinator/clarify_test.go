@@ -0,0 +1,133 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentsquads/api/channels"
+	"github.com/agentsquads/api/policies"
+)
+
+func newClarifyingBridge(t *testing.T, reply string) *Bridge {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"content": reply}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	h := NewHandler(nil)
+	h.SetPolicyChecker(stubPolicies{enabled: true, overrides: map[string]bool{policies.FeatureSwarm: false}})
+	b := NewBridge(h)
+	b.llmProxyURL = server.URL
+	return b
+}
+
+func TestBridgeAsksForMissingDetails(t *testing.T) {
+	t.Parallel()
+	b := newClarifyingBridge(t, `{"sufficient":false,"question":"Which website, and what is broken?"}`)
+	req := channels.AgentTaskRequest{TenantID: "t1", ConversationID: "c1", Channel: "telegram", Content: "/agent fix the website"}
+
+	res, err := b.HandleChannelMessage(context.Background(), req)
+	if err != nil || !res.Accepted || res.RunID != "" || !strings.Contains(res.Ack, "Which website") {
+		t.Fatalf("first message = %+v, %v", res, err)
+	}
+
+	// The answer is merged into the held task and submitted; the swarm
+	// policy is off here, so StartRun refuses it.
+	req.Content = "agentsquads.ai, the signup form 500s"
+	res, err = b.HandleChannelMessage(context.Background(), req)
+	if err != nil || !strings.Contains(res.Ack, "disabled") {
+		t.Fatalf("answer = %+v, %v", res, err)
+	}
+	if _, ok := b.takePending(clarificationKey(req)); ok {
+		t.Fatal("pending clarification not consumed")
+	}
+}
+
+func TestBridgeClarificationCancelAndExpiry(t *testing.T) {
+	t.Parallel()
+	b := newClarifyingBridge(t, `{"sufficient":false,"question":"What should change?"}`)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+	req := channels.AgentTaskRequest{TenantID: "t1", ConversationID: "c1", Channel: "telegram", Content: "/agent improve things"}
+
+	if res, _ := b.HandleChannelMessage(context.Background(), req); !strings.Contains(res.Ack, "What should change?") {
+		t.Fatalf("question = %+v", res)
+	}
+	req.Content = "/cancel"
+	if res, _ := b.HandleChannelMessage(context.Background(), req); !res.Accepted || !strings.Contains(res.Ack, "dropped") {
+		t.Fatalf("cancel = %+v", res)
+	}
+
+	b.storePending(clarificationKey(req), pendingClarification{task: "improve things", triggerType: "command"})
+	now = now.Add(b.handler.cfg.ClarificationTTL)
+	if _, ok := b.takePending(clarificationKey(req)); ok {
+		t.Fatal("expired clarification was returned")
+	}
+}
+
+func TestBridgeClarificationRequiresPolicy(t *testing.T) {
+	t.Parallel()
+	b := newClarifyingBridge(t, `{"sufficient":false,"question":"What should change?"}`)
+	b.handler.SetPolicyChecker(stubPolicies{enabled: false})
+
+	res, err := b.HandleChannelMessage(context.Background(), channels.AgentTaskRequest{TenantID: "t1", ConversationID: "c1", Content: "/agent improve things"})
+	if err != nil || strings.Contains(res.Ack, "What should change?") {
+		t.Fatalf("result = %+v, %v", res, err)
+	}
+}
+
+func TestMergeClarification(t *testing.T) {
+	t.Parallel()
+	got := mergeClarification("fix the website ", " the signup form on agentsquads.ai")
+	if got != "fix the website\n\nAdditional details from the user: the signup form on agentsquads.ai" {
+		t.Fatalf("merged = %q", got)
+	}
+}
+
+func TestBridgeRetriesProxyConcurrencyLimit(t *testing.T) {
+	t.Parallel()
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"message":"busy","type":"concurrency_limit","param":null,"code":"concurrency_limit_exceeded"}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"content": `{"ok":true}`}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	b := NewBridge(NewHandler(nil))
+	b.llmProxyURL = server.URL
+	b.retryBackoff = time.Millisecond
+	var out struct {
+		OK bool `json:"ok"`
+	}
+	if !b.completeJSON(context.Background(), "t1", "system", "user", &out) || !out.OK || calls != 3 {
+		t.Fatalf("completeJSON ok=%v calls=%d", out.OK, calls)
+	}
+
+	// Other 429s are not retried.
+	calls = 0
+	rateLimited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"message":"slow down","type":"rate_limit_error"}}`))
+	}))
+	t.Cleanup(rateLimited.Close)
+	b.llmProxyURL = rateLimited.URL
+	if b.completeJSON(context.Background(), "t1", "system", "user", &out) || calls != 1 {
+		t.Fatalf("rate limited call retried: calls=%d", calls)
+	}
+}
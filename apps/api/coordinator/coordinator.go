@@ -1,10 +1,14 @@
 package coordinator
 
 import (
+	"context"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/agentsquads/api/prompts"
 )
 
 // ChannelContext carries origin metadata for channel-triggered swarm tasks.
@@ -19,11 +23,19 @@ type ChannelContext struct {
 
 // RunEvent reports lifecycle updates for streaming progress.
 type RunEvent struct {
-	Type      string `json:"type"` // queued, subtask_started, subtask_update, complete, failed
+	Type      string `json:"type"` // queued, subtask_started, subtask_update, retry, paused, resumed, complete, failed
 	RunID     string `json:"run_id"`
 	SubTaskID string `json:"subtask_id,omitempty"`
 	Status    string `json:"status,omitempty"`
 	Message   string `json:"message,omitempty"`
+
+	// halfway marks the subtask update that finished half of the run's
+	// subtasks, a milestone for channel notifications.
+	halfway bool
+	// messageID and messageArgs are the catalog message behind Message, so
+	// channel updates can be translated; see withMessage.
+	messageID   string
+	messageArgs []string
 }
 
 // Coordinator manages a swarm of sub-agents for a tenant.
@@ -31,6 +43,18 @@ type Coordinator struct {
 	TenantID  string
 	MaxAgents int
 	Timeout   time.Duration
+
+	mu     sync.Mutex
+	active map[string]*SwarmRun // runID -> run being executed
+	// spawn replaces SpawnAgent when set.
+	spawn func(subtask *SubTask, channelCtx *ChannelContext) error
+	// agentModels maps agent types to the model they run on; others use
+	// LLM_MODEL.
+	agentModels map[string]string
+	// healthCheck replaces the LLM proxy ping of a model when set.
+	healthCheck func(ctx context.Context, model string) error
+	// health caches health check results; nil means sharedAgentHealth.
+	health *agentHealthCache
 }
 
 // SwarmConfig controls task decomposition and worker execution limits.
@@ -38,6 +62,11 @@ type SwarmConfig struct {
 	DefaultMaxAgents            int
 	DefaultTimeout              time.Duration
 	DecompositionPromptTemplate string
+	// ClarificationRubric and ClarificationTTL apply to tenants with the
+	// task_clarification policy: the default rubric a channel task is checked
+	// against, and how long a follow-up question waits for an answer.
+	ClarificationRubric string
+	ClarificationTTL    time.Duration
 }
 
 // SubTask represents a unit of work for a sub-agent.
@@ -49,6 +78,7 @@ type SubTask struct {
 	TmuxSession  string    `json:"tmux_session"`
 	StartedAt    time.Time `json:"started_at,omitempty"`
 	Output       string    `json:"output,omitempty"`
+	DependsOn    []string  `json:"depends_on,omitempty"`
 }
 
 // SwarmRun tracks an active swarm execution.
@@ -60,10 +90,17 @@ type SwarmRun struct {
 	TriggerType                 string          `json:"trigger_type,omitempty"`
 	SourceChannel               string          `json:"source_channel,omitempty"`
 	ChannelContext              *ChannelContext `json:"channel_context,omitempty"`
+	ConversationID              string          `json:"conversation_id,omitempty"`
 	SubTasks                    []SubTask       `json:"sub_tasks"`
 	StartedAt                   time.Time       `json:"started_at"`
 	DecompositionPromptTemplate string          `json:"decomposition_prompt_template,omitempty"`
+	PromptTemplates             []prompts.Ref   `json:"prompt_templates,omitempty"`
 	Output                      string          `json:"output,omitempty"`
+	Paused                      bool            `json:"paused,omitempty"`
+	// RequestID is the X-Request-ID of the API request that started the run.
+	RequestID string `json:"request_id,omitempty"`
+
+	gate *pauseGate
 }
 
 // NewCoordinator creates a Coordinator with config from environment.
@@ -75,9 +112,10 @@ func NewCoordinator(tenantID string) *Coordinator {
 // NewCoordinatorWithLimits creates a Coordinator with explicit limits.
 func NewCoordinatorWithLimits(tenantID string, maxAgents int, timeout time.Duration) *Coordinator {
 	return &Coordinator{
-		TenantID:  tenantID,
-		MaxAgents: clampPositive(maxAgents, 3),
-		Timeout:   clampDuration(timeout, 30*time.Minute),
+		TenantID:    tenantID,
+		MaxAgents:   clampPositive(maxAgents, 3),
+		Timeout:     clampDuration(timeout, 30*time.Minute),
+		agentModels: loadAgentModels(),
 	}
 }
 
@@ -102,10 +140,24 @@ func LoadSwarmConfigFromEnv() SwarmConfig {
 		template = "Break the task into clear subtasks assigned to specialist Hands. Task: {{task}}"
 	}
 
+	rubric := strings.TrimSpace(os.Getenv("SWARM_CLARIFICATION_RUBRIC"))
+	if rubric == "" {
+		rubric = defaultClarificationRubric
+	}
+
+	clarificationTTL := 30 * time.Minute
+	if v := strings.TrimSpace(os.Getenv("SWARM_CLARIFICATION_TTL")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			clarificationTTL = d
+		}
+	}
+
 	return SwarmConfig{
 		DefaultMaxAgents:            maxAgents,
 		DefaultTimeout:              timeout,
 		DecompositionPromptTemplate: template,
+		ClarificationRubric:         rubric,
+		ClarificationTTL:            clarificationTTL,
 	}
 }
 
@@ -1,9 +1,14 @@
 package coordinator
 
 import (
+	"context"
+	"errors"
 	"os"
 	"testing"
 	"time"
+
+	"github.com/agentsquads/api/middleware"
+	"github.com/agentsquads/api/prompts"
 )
 
 func TestNewCoordinatorWithLimits(t *testing.T) {
@@ -48,3 +53,53 @@ func TestClampHelpers(t *testing.T) {
 		t.Fatalf("clampDuration=%s", got)
 	}
 }
+
+type stubPrompts struct {
+	template prompts.Template
+	found    bool
+	err      error
+}
+
+func (s stubPrompts) Resolve(context.Context, string, string) (prompts.Template, bool, error) {
+	return s.template, s.found, s.err
+}
+
+func TestDecompositionPrompt(t *testing.T) {
+	t.Parallel()
+	h := NewHandler(nil)
+	h.cfg.DecompositionPromptTemplate = "env {{task}}"
+
+	if got, refs := h.decompositionPrompt(context.Background(), "t1"); got != "env {{task}}" || refs != nil {
+		t.Fatalf("without resolver = %q %v", got, refs)
+	}
+
+	h.SetPromptResolver(stubPrompts{found: true, template: prompts.Template{Name: prompts.Decomposition, TenantID: "t1", Version: 3, Content: "tenant {{task}}"}})
+	got, refs := h.decompositionPrompt(context.Background(), "t1")
+	if got != "tenant {{task}}" || len(refs) != 1 || refs[0] != (prompts.Ref{Name: prompts.Decomposition, Version: 3, Scope: "tenant"}) {
+		t.Fatalf("resolved = %q %v", got, refs)
+	}
+
+	for _, stub := range []stubPrompts{{}, {err: errors.New("db down")}} {
+		h.SetPromptResolver(stub)
+		if got, refs := h.decompositionPrompt(context.Background(), "t1"); got != "env {{task}}" || refs != nil {
+			t.Fatalf("fallback %+v = %q %v", stub, got, refs)
+		}
+	}
+}
+
+func TestStartRunKeepsRequestID(t *testing.T) {
+	t.Parallel()
+	h := NewHandler(nil)
+	h.spawnAgent = func(*SubTask, *ChannelContext) error { return errors.New("no workers in tests") }
+	ctx := middleware.WithRequestID(context.Background(), "req-42")
+	run, err := h.StartRun(ctx, "t1", RunRequest{Task: "research the market"})
+	if err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+	if run.RequestID != "req-42" {
+		t.Fatalf("RequestID = %q", run.RequestID)
+	}
+	if snapshot := h.taskSnapshot(run.RunID); snapshot == nil || snapshot.RequestID != "req-42" {
+		t.Fatalf("snapshot = %+v", snapshot)
+	}
+}
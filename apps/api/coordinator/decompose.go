@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/agentsquads/api/prompts"
 	"github.com/google/uuid"
 )
 
+const defaultDecompositionPrompt = "Break the task into clear subtasks. Task: {{task}}"
+
 var defaultHands = []string{
 	"Planner Hand",
 	"Research Hand",
@@ -23,7 +26,9 @@ func Decompose(task string, promptTemplate string) ([]SubTask, error) {
 		return nil, fmt.Errorf("empty task")
 	}
 
-	_ = renderDecompositionPrompt(promptTemplate, task)
+	if _, err := renderDecompositionPrompt(promptTemplate, task); err != nil {
+		return nil, fmt.Errorf("render decomposition prompt: %w", err)
+	}
 
 	parts := splitTask(task)
 	subtasks := make([]SubTask, 0, len(parts))
@@ -85,10 +90,12 @@ func splitTask(task string) []string {
 	return []string{task}
 }
 
-func renderDecompositionPrompt(promptTemplate, task string) string {
+// renderDecompositionPrompt fills the template with the task. The task is
+// user text, so it is escaped rather than substituted verbatim.
+func renderDecompositionPrompt(promptTemplate, task string) (string, error) {
 	template := strings.TrimSpace(promptTemplate)
 	if template == "" {
-		template = "Break the task into clear subtasks. Task: {{task}}"
+		template = defaultDecompositionPrompt
 	}
-	return strings.ReplaceAll(template, "{{task}}", task)
+	return prompts.Render(template, map[string]string{"task": task})
 }
@@ -0,0 +1,62 @@
+package coordinator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// HandInvocation is one subtask a hand ran to a terminal status.
+type HandInvocation struct {
+	HandID    string
+	TenantID  string
+	LatencyMs int64
+	Status    string // complete, failed, timeout
+	InvokedAt time.Time
+}
+
+// InvocationRecorder stores hand invocations for the performance endpoint.
+type InvocationRecorder interface {
+	RecordInvocation(ctx context.Context, inv HandInvocation) error
+}
+
+// InvocationStore writes hand invocations to hand_invocations.
+type InvocationStore struct {
+	db *sql.DB
+}
+
+func NewInvocationStore(db *sql.DB) *InvocationStore {
+	return &InvocationStore{db: db}
+}
+
+func (s *InvocationStore) RecordInvocation(ctx context.Context, inv HandInvocation) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO hand_invocations (hand_id, tenant_id, latency_ms, status, invoked_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, inv.HandID, inv.TenantID, inv.LatencyMs, inv.Status, inv.InvokedAt)
+	if err != nil {
+		return fmt.Errorf("insert hand invocation: %w", err)
+	}
+	return nil
+}
+
+// SetInvocationRecorder makes subtasks that finish record their hand's
+// latency and status.
+func (h *Handler) SetInvocationRecorder(r InvocationRecorder) {
+	h.invocations = r
+}
+
+// recordInvocation stores inv, logging failures: a missed sample must not
+// hold up the run.
+func (h *Handler) recordInvocation(inv *HandInvocation) {
+	if h.invocations == nil || inv == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.invocations.RecordInvocation(ctx, *inv); err != nil {
+		slog.Warn("failed to record hand invocation", "tenant", inv.TenantID, "hand", inv.HandID, "err", err)
+	}
+}
@@ -0,0 +1,70 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type recordingInvocations struct {
+	recorded []HandInvocation
+}
+
+func (r *recordingInvocations) RecordInvocation(_ context.Context, inv HandInvocation) error {
+	r.recorded = append(r.recorded, inv)
+	return nil
+}
+
+func TestApplySubTaskEventRecordsInvocations(t *testing.T) {
+	t.Parallel()
+	rec := &recordingInvocations{}
+	h := NewHandler(nil)
+	h.SetInvocationRecorder(rec)
+	started := time.Now().Add(-1500 * time.Millisecond)
+	h.tasks["r1"] = &SwarmRun{RunID: "r1", TenantID: "t1", SubTasks: []SubTask{
+		{ID: "r1-1", AssignedHand: "researcher", Status: "running", StartedAt: started},
+		{ID: "r1-2", AssignedHand: "coder", Status: "pending"},
+		{ID: "r1-3", AssignedHand: "writer", Status: "running", StartedAt: started},
+	}}
+
+	h.applySubTaskEvent("r1", RunEvent{Type: "subtask_update", SubTaskID: "r1-1", Status: "complete"})
+	// A repeated terminal update and a subtask that never started are not
+	// invocations.
+	h.applySubTaskEvent("r1", RunEvent{Type: "subtask_update", SubTaskID: "r1-1", Status: "complete"})
+	h.applySubTaskEvent("r1", RunEvent{Type: "subtask_update", SubTaskID: "r1-2", Status: "failed"})
+	h.applySubTaskEvent("r1", RunEvent{Type: "subtask_update", SubTaskID: "r1-3", Status: "timeout"})
+
+	if len(rec.recorded) != 2 {
+		t.Fatalf("recorded %d invocations, want 2: %+v", len(rec.recorded), rec.recorded)
+	}
+	got := rec.recorded[0]
+	if got.HandID != "researcher" || got.TenantID != "t1" || got.Status != "complete" || !got.InvokedAt.Equal(started) || got.LatencyMs < 1500 {
+		t.Fatalf("invocation = %+v", got)
+	}
+	if got := rec.recorded[1]; got.HandID != "writer" || got.Status != "timeout" {
+		t.Fatalf("timed out invocation = %+v", got)
+	}
+}
+
+func TestInvocationStoreRecordInvocation(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	at := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectExec("INSERT INTO hand_invocations").
+		WithArgs("researcher", "t1", int64(1200), "complete", at).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	inv := HandInvocation{HandID: "researcher", TenantID: "t1", LatencyMs: 1200, Status: "complete", InvokedAt: at}
+	if err := NewInvocationStore(db).RecordInvocation(context.Background(), inv); err != nil {
+		t.Fatalf("RecordInvocation: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
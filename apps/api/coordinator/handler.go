@@ -15,6 +15,12 @@ import (
 	"time"
 
 	"github.com/agentsquads/api/channels"
+	"github.com/agentsquads/api/i18n"
+	"github.com/agentsquads/api/middleware"
+	"github.com/agentsquads/api/openapi"
+	"github.com/agentsquads/api/plans"
+	"github.com/agentsquads/api/policies"
+	"github.com/agentsquads/api/prompts"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
@@ -27,45 +33,140 @@ type RunRequest struct {
 	Task           string          `json:"task"`
 	TriggerType    string          `json:"trigger_type,omitempty"`
 	ChannelContext *ChannelContext `json:"channel_context,omitempty"`
+	SubTaskSpec    []SubTaskSpec   `json:"subtask_spec,omitempty"`
+	// ConversationID is the conversation API-triggered runs write their
+	// result to; channel runs use ChannelContext.ConversationID.
+	ConversationID string `json:"conversation_id,omitempty"`
+}
+
+// ErrSubTaskSpecForbidden is returned when a tenant submits a subtask_spec
+// without the custom_subtask_spec policy enabled.
+var ErrSubTaskSpecForbidden = errors.New("custom subtask specs are not enabled for this tenant")
+
+// ErrSwarmDisabled is returned when the tenant's swarm policy is disabled.
+var ErrSwarmDisabled = errors.New("agent swarm is disabled for this tenant")
+
+// PromptResolver looks up the active admin-managed prompt template for a
+// tenant.
+type PromptResolver interface {
+	Resolve(ctx context.Context, tenantID, name string) (prompts.Template, bool, error)
 }
 
 // Handler manages HTTP endpoints for the swarm coordinator.
+//
+// mu guards the maps below and every field of the runs they hold. Runs are
+// only changed while holding mu, mostly through the helpers in run_state.go,
+// and code that reads a run without holding mu works on a snapshot from
+// cloneRun.
 type Handler struct {
-	mu          sync.RWMutex
-	runs        map[string]*SwarmRun   // tenantID -> latest run
-	history     map[string][]*SwarmRun // tenantID -> latest runs
-	tasks       map[string]*SwarmRun   // taskID(runID) -> run
-	taskOrder   []string               // newest first
-	subscribers map[string]map[chan []byte]struct{}
-	redis       *redis.Client
-	cfg         SwarmConfig
+	mu           sync.RWMutex
+	runs         map[string]*SwarmRun   // tenantID -> latest run
+	history      map[string][]*SwarmRun // tenantID -> latest runs
+	tasks        map[string]*SwarmRun   // taskID(runID) -> run
+	taskOrder    []string               // newest first
+	subscribers  map[string]map[*sseSubscriber]struct{}
+	coordinators map[string]*Coordinator // runID -> coordinator executing it
+	runLocks     map[string]*runLock     // runID -> tenant run lock it holds
+	redis        *redis.Client
+	cfg          SwarmConfig
+	plans        *plans.Resolver
+	policies     policies.Checker
+	prompts      PromptResolver
+	// tenantConfigs holds per-tenant overrides set by admins; see
+	// SetTenantConfigSource.
+	tenantConfigs TenantConfigSource
+	transcript    TranscriptWriter
+	// settings replaces cfg when platform defaults are loaded from the
+	// database; see SetSwarmConfigSource.
+	settings SwarmConfigSource
+	// notifications filters channel run updates; see
+	// SetNotificationPreferences.
+	notifications NotificationPreferences
+	// invocations records hand latency as subtasks finish; see
+	// SetInvocationRecorder.
+	invocations InvocationRecorder
+	// runRecorder persists runs as their status changes; see
+	// SetRunRecorder.
+	runRecorder RunRecorder
+	// messages and locales translate channel updates and command replies;
+	// see SetMessages.
+	messages *i18n.Catalog
+	locales  LocaleSource
+	// spawnAgent replaces Coordinator.SpawnAgent for the runs this handler
+	// executes; tests use it to run swarms without tmux.
+	spawnAgent func(subtask *SubTask, channelCtx *ChannelContext) error
+	// sse bounds how far an event stream subscriber may fall behind; see
+	// SetSSELimits.
+	sse sseLimits
 }
 
 // NewHandler creates a new coordinator HTTP handler.
 func NewHandler(redisClient *redis.Client) *Handler {
 	return &Handler{
-		runs:        make(map[string]*SwarmRun),
-		history:     make(map[string][]*SwarmRun),
-		tasks:       make(map[string]*SwarmRun),
-		taskOrder:   make([]string, 0, maxRunHistoryPerTenant),
-		subscribers: make(map[string]map[chan []byte]struct{}),
-		redis:       redisClient,
-		cfg:         LoadSwarmConfigFromEnv(),
+		runs:         make(map[string]*SwarmRun),
+		history:      make(map[string][]*SwarmRun),
+		tasks:        make(map[string]*SwarmRun),
+		taskOrder:    make([]string, 0, maxRunHistoryPerTenant),
+		subscribers:  make(map[string]map[*sseSubscriber]struct{}),
+		coordinators: make(map[string]*Coordinator),
+		runLocks:     make(map[string]*runLock),
+		redis:        redisClient,
+		cfg:          LoadSwarmConfigFromEnv(),
+		sse:          loadSSELimitsFromEnv(),
 	}
 }
 
+// SetPlanResolver makes swarm limits follow the tenant's plan.
+func (h *Handler) SetPlanResolver(resolver *plans.Resolver) {
+	h.plans = resolver
+}
+
+// SetPolicyChecker enables tenant feature policy checks such as
+// custom_subtask_spec.
+func (h *Handler) SetPolicyChecker(checker policies.Checker) {
+	h.policies = checker
+}
+
+// SetPromptResolver makes decomposition use the active prompt template,
+// falling back to SwarmConfig.DecompositionPromptTemplate when none is
+// active.
+func (h *Handler) SetPromptResolver(resolver PromptResolver) {
+	h.prompts = resolver
+}
+
 // Mount registers coordinator routes on the given mux.
 func (h *Handler) Mount(mux *http.ServeMux) {
-	mux.HandleFunc("POST /api/tenants/{id}/swarm/run", h.handleRun)
-	mux.HandleFunc("POST /api/tenants/{id}/swarm/channel-run", h.handleRun)
-	mux.HandleFunc("GET /api/tenants/{id}/swarm/status", h.handleStatus)
-	mux.HandleFunc("GET /api/tenants/{id}/swarm/runs", h.handleRuns)
-	mux.HandleFunc("POST /api/tenants/{id}/swarm/cancel", h.handleCancel)
-
-	mux.HandleFunc("POST /api/swarm/tasks", h.handleCreateTask)
-	mux.HandleFunc("GET /api/swarm/tasks", h.handleListTasks)
-	mux.HandleFunc("GET /api/swarm/tasks/{id}", h.handleGetTask)
-	mux.HandleFunc("GET /api/swarm/tasks/{id}/events", h.handleTaskEvents)
+	tags := []string{"Swarm"}
+	accepted := map[string]string{}
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/api/tenants/{id}/swarm/run", Summary: "Start a swarm run",
+		Tags: tags, Request: RunRequest{}, Response: accepted, Status: http.StatusAccepted}, h.handleRun)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/api/tenants/{id}/swarm/channel-run", Summary: "Start a swarm run from a channel conversation",
+		Tags: tags, Request: RunRequest{}, Response: accepted, Status: http.StatusAccepted}, h.handleRun)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodGet, Path: "/api/tenants/{id}/swarm/status", Summary: "Get the tenant's active swarm run",
+		Tags: tags, Response: SwarmRun{}}, h.handleStatus)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodGet, Path: "/api/tenants/{id}/swarm/runs", Summary: "List the tenant's recent swarm runs",
+		Tags: tags, Response: struct {
+			Runs []SwarmRun `json:"runs"`
+		}{}}, h.handleRuns)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/api/tenants/{id}/swarm/cancel", Summary: "Cancel the tenant's active swarm run",
+		Tags: tags, Response: map[string]string{}}, h.handleCancel)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/api/tenants/{id}/swarm/runs/{run_id}/subtasks/{subtask_id}/retry", Summary: "Retry a failed subtask",
+		Tags: tags, Response: accepted, Status: http.StatusAccepted}, h.handleRetrySubTask)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/api/tenants/{id}/swarm/runs/{run_id}/pause", Summary: "Pause a swarm run",
+		Tags: tags, Response: map[string]string{}}, h.handlePauseRun)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/api/tenants/{id}/swarm/runs/{run_id}/resume", Summary: "Resume a paused swarm run",
+		Tags: tags, Response: map[string]string{}}, h.handleResumeRun)
+
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/api/swarm/tasks", Summary: "Create a swarm task",
+		Tags: tags, Headers: []string{"X-Tenant-ID"}, Request: RunRequest{}, Response: accepted, Status: http.StatusAccepted}, h.handleCreateTask)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodGet, Path: "/api/swarm/tasks", Summary: "List swarm tasks",
+		Tags: tags, Query: []string{"tenant_id"}, Headers: []string{"X-Tenant-ID"}, Response: struct {
+			Tasks []SwarmRun `json:"tasks"`
+		}{}}, h.handleListTasks)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodGet, Path: "/api/swarm/tasks/{id}", Summary: "Get a swarm task",
+		Tags: tags, Headers: []string{"X-Tenant-ID"}, Response: SwarmRun{}}, h.handleGetTask)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodGet, Path: "/api/swarm/tasks/{id}/events", Summary: "Stream a swarm task's updates as server-sent events",
+		Tags: tags, Headers: []string{"X-Tenant-ID"}, ContentType: "text/event-stream"}, h.handleTaskEvents)
 }
 
 // StartRun starts a swarm run and streams lifecycle updates via channel fanout when channel context exists.
@@ -81,97 +182,215 @@ func (h *Handler) StartRun(ctx context.Context, tenantID string, req RunRequest)
 	if req.TriggerType == "" {
 		req.TriggerType = "manual"
 	}
+	req.ConversationID = strings.TrimSpace(req.ConversationID)
+	if req.ConversationID != "" {
+		if _, err := uuid.Parse(req.ConversationID); err != nil {
+			return nil, errors.New("invalid conversation_id")
+		}
+	}
+	if h.policies != nil {
+		if err := h.requireFeature(ctx, tenantID, policies.FeatureSwarm, ErrSwarmDisabled); err != nil {
+			return nil, err
+		}
+	}
 
-	h.mu.RLock()
-	existing := h.runs[tenantID]
-	h.mu.RUnlock()
-	if existing != nil && existing.Status == "running" {
-		return nil, errors.New("swarm already running for this tenant")
+	if h.tenantRunActive(tenantID) {
+		return nil, errSwarmAlreadyRunning
 	}
 
-	subtasks, err := Decompose(req.Task, h.cfg.DecompositionPromptTemplate)
+	runID := uuid.New().String()[:8]
+	// Other replicas only see the tenant's runs through the run lock.
+	lock, err := h.acquireRunLock(ctx, tenantID, runID)
 	if err != nil {
-		return nil, fmt.Errorf("decompose: %w", err)
+		return nil, err
+	}
+	started := false
+	defer func() {
+		if !started {
+			lock.release()
+		}
+	}()
+
+	template, templateRefs := h.decompositionPrompt(ctx, tenantID)
+	var subtasks []SubTask
+	if len(req.SubTaskSpec) > 0 {
+		if err := h.requireFeature(ctx, tenantID, policies.FeatureCustomSubtaskSpec, ErrSubTaskSpecForbidden); err != nil {
+			return nil, err
+		}
+		subtasks, err = SubTasksFromSpec(runID, req.SubTaskSpec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subtask_spec: %w", err)
+		}
+	} else {
+		subtasks, err = Decompose(req.Task, template)
+		if err != nil {
+			return nil, fmt.Errorf("decompose: %w", err)
+		}
 	}
 
 	run := &SwarmRun{
-		RunID:                       uuid.New().String()[:8],
+		RunID:                       runID,
 		TenantID:                    tenantID,
 		Task:                        req.Task,
 		Status:                      "running",
 		TriggerType:                 req.TriggerType,
 		ChannelContext:              req.ChannelContext,
+		ConversationID:              req.ConversationID,
 		SubTasks:                    subtasks,
 		StartedAt:                   time.Now().UTC(),
-		DecompositionPromptTemplate: h.cfg.DecompositionPromptTemplate,
+		DecompositionPromptTemplate: template,
+		PromptTemplates:             templateRefs,
+		RequestID:                   middleware.RequestIDFromContext(ctx),
 	}
 	if req.ChannelContext != nil {
 		run.SourceChannel = req.ChannelContext.Channel
 	}
 
-	h.mu.Lock()
-	h.runs[tenantID] = run
-	h.prependHistoryLocked(tenantID, run)
-	h.tasks[run.RunID] = run
-	h.taskOrder = append([]string{run.RunID}, h.taskOrder...)
-	h.mu.Unlock()
+	// Another request may have started a run since the check above.
+	snapshot, err := h.registerRun(run)
+	if err != nil {
+		return nil, err
+	}
+	started = true
+	h.holdRunLock(runID, lock)
+
+	h.publishRunUpdate(ctx, snapshot, RunEvent{
+		Type:   "queued",
+		RunID:  snapshot.RunID,
+		Status: snapshot.Status,
+	}.withMessage(i18n.MsgRunAccepted), true)
+	h.publishTaskSnapshot(snapshot, "queued")
+	h.recordRun(snapshot)
 
-	h.publishRunUpdate(ctx, run, RunEvent{
-		Type:    "queued",
-		RunID:   run.RunID,
-		Status:  run.Status,
-		Message: "Agent swarm run accepted.",
-	}, true)
-	h.publishTaskSnapshot(run, "queued")
+	h.execute(ctx, snapshot, snapshot.SubTasks)
+
+	return snapshot, nil
+}
+
+// decompositionPrompt returns the tenant's decomposition prompt and the
+// template versions it came from: the tenant's swarm config override, then
+// the active prompt template. Lookup failures fall back to the configured
+// template so a template store outage does not block runs.
+func (h *Handler) decompositionPrompt(ctx context.Context, tenantID string) (string, []prompts.Ref) {
+	if cfg := h.tenantSwarmConfig(ctx, tenantID); cfg != nil && cfg.DecompositionPrompt != "" {
+		return cfg.DecompositionPrompt, nil
+	}
+	fallback := h.swarmConfig().DecompositionPromptTemplate
+	if h.prompts == nil {
+		return fallback, nil
+	}
+	t, found, err := h.prompts.Resolve(ctx, tenantID, prompts.Decomposition)
+	if err != nil {
+		slog.Warn("resolve decomposition prompt failed", "tenant", tenantID, "err", err)
+		return fallback, nil
+	}
+	if !found || strings.TrimSpace(t.Content) == "" {
+		return fallback, nil
+	}
+	return t.Content, []prompts.Ref{t.Ref()}
+}
 
-	coord := NewCoordinatorWithLimits(tenantID, h.maxAgentsForTenant(tenantID), h.cfg.DefaultTimeout)
+// execute runs subtasks for the run in snapshot in the background and
+// records the result. Subtasks that are not pending are kept as they are,
+// which is how a retry re-runs a single subtask. The coordinator works on its
+// own copy of subtasks; the handler's run only changes through its events.
+func (h *Handler) execute(ctx context.Context, snapshot *SwarmRun, subtasks []SubTask) {
+	tenantID, runID := snapshot.TenantID, snapshot.RunID
+	coord := NewCoordinatorWithLimits(tenantID, h.maxAgentsForTenant(ctx, tenantID), h.timeoutForTenant(ctx, tenantID))
+	coord.spawn = h.spawnAgent
+	subtasks = cloneSubTasks(subtasks)
+	h.mu.Lock()
+	h.coordinators[runID] = coord
+	h.mu.Unlock()
 	go func() {
-		result, err := coord.RunWithSubTasks(context.Background(), req.Task, run.RunID, req.ChannelContext, subtasks, func(evt RunEvent) {
-			h.applySubTaskEvent(run.RunID, evt)
-			h.publishRunUpdate(context.Background(), run, evt, false)
+		defer func() {
+			h.mu.Lock()
+			if h.coordinators[runID] == coord {
+				delete(h.coordinators, runID)
+			}
+			h.mu.Unlock()
+		}()
+		result, err := coord.RunWithSubTasks(context.Background(), snapshot.Task, runID, snapshot.ChannelContext, subtasks, func(evt RunEvent) {
+			evt.halfway = h.applySubTaskEvent(runID, evt)
+			if current := h.taskSnapshot(runID); current != nil {
+				h.publishRunUpdate(context.Background(), current, evt, false)
+			}
 		})
 
 		if err != nil {
-			h.mu.Lock()
-			slog.Error("swarm run failed", "tenant", tenantID, "run", run.RunID, "err", err)
-			run.Status = "failed"
-			h.mu.Unlock()
-			h.publishRunUpdate(context.Background(), run, RunEvent{
-				Type:    "failed",
-				RunID:   run.RunID,
-				Status:  run.Status,
-				Message: "Swarm execution failed. Reply with /agent run <task> to retry.",
-			}, true)
+			slog.Error("swarm run failed", "tenant", tenantID, "run", runID, "request_id", snapshot.RequestID, "err", err)
+			run := h.failRun(runID)
+			h.releaseRunLock(runID)
+			if run == nil {
+				return
+			}
+			evt := RunEvent{
+				Type:   "failed",
+				RunID:  run.RunID,
+				Status: run.Status,
+			}.withMessage(i18n.MsgRunFailed)
+			h.saveRunResult(context.Background(), run, evt)
+			h.publishRunUpdate(context.Background(), run, evt, true)
 			h.publishTaskSnapshot(run, "failed")
+			h.recordRun(run)
 			return
 		}
 
-		h.mu.Lock()
-		run.Status = result.Status
-		run.SubTasks = result.SubTasks
-		run.Output = result.Output
-		h.runs[tenantID] = run
-		h.tasks[run.RunID] = run
-		h.mu.Unlock()
+		run, ok := h.finishRun(runID, result)
+		h.releaseRunLock(runID)
+		if !ok {
+			return
+		}
 
-		finalMessage := strings.TrimSpace(result.Output)
-		if finalMessage == "" {
+		evt := RunEvent{
+			Type:    result.Status,
+			RunID:   run.RunID,
+			Status:  result.Status,
+			Message: strings.TrimSpace(result.Output),
+		}
+		if evt.Message == "" {
 			if result.Status == "complete" {
-				finalMessage = "Agent swarm completed."
+				evt = evt.withMessage(i18n.MsgRunCompleted)
 			} else {
-				finalMessage = "Agent swarm completed with issues. Reply with more detail if you want a retry."
+				evt = evt.withMessage(i18n.MsgRunCompletedWithIssues)
 			}
 		}
-		h.publishRunUpdate(context.Background(), run, RunEvent{
-			Type:    result.Status,
-			RunID:   run.RunID,
-			Status:  result.Status,
-			Message: finalMessage,
-		}, true)
+		h.saveRunResult(context.Background(), run, evt)
+		h.publishRunUpdate(context.Background(), run, evt, true)
 		h.publishTaskSnapshot(run, result.Status)
+		h.recordRun(run)
 	}()
+}
+
+// requireFeature returns denied unless the tenant has the feature policy
+// enabled. Without a policy checker the feature is denied.
+func (h *Handler) requireFeature(ctx context.Context, tenantID, feature string, denied error) error {
+	if h.policies == nil {
+		return denied
+	}
+	enabled, err := h.policies.FeatureEnabled(ctx, tenantID, feature)
+	if err != nil {
+		return fmt.Errorf("check tenant policy: %w", err)
+	}
+	if !enabled {
+		policies.RecordDenial(ctx, tenantID, feature, "coordinator")
+		return denied
+	}
+	return nil
+}
 
-	return cloneRun(run), nil
+// startRunErrorStatus maps StartRun errors to HTTP status codes.
+func startRunErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrSubTaskSpecForbidden), errors.Is(err, ErrSwarmDisabled):
+		return http.StatusForbidden
+	case errors.Is(err, errSwarmAlreadyRunning):
+		return http.StatusConflict
+	case strings.HasPrefix(err.Error(), "check tenant policy"):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
 }
 
 func (h *Handler) handleRun(w http.ResponseWriter, r *http.Request) {
@@ -198,11 +417,7 @@ func (h *Handler) handleRun(w http.ResponseWriter, r *http.Request) {
 
 	run, err := h.StartRun(r.Context(), tenantID, body)
 	if err != nil {
-		status := http.StatusBadRequest
-		if strings.Contains(err.Error(), "already running") {
-			status = http.StatusConflict
-		}
-		h.writeJSONError(w, status, err.Error())
+		h.writeJSONError(w, startRunErrorStatus(err), err.Error())
 		return
 	}
 
@@ -222,17 +437,14 @@ func (h *Handler) handleStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.mu.RLock()
-	run := h.runs[tenantID]
-	h.mu.RUnlock()
-
+	run := h.tenantSnapshot(tenantID)
 	if run == nil {
 		h.writeJSONError(w, http.StatusNotFound, "no active swarm run")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(cloneRun(run))
+	_ = json.NewEncoder(w).Encode(run)
 }
 
 func (h *Handler) handleRuns(w http.ResponseWriter, r *http.Request) {
@@ -261,25 +473,21 @@ func (h *Handler) handleCancel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.mu.Lock()
-	run := h.runs[tenantID]
-	if run != nil && run.Status == "running" {
-		for i := range run.SubTasks {
-			if run.SubTasks[i].Status == "running" {
-				_ = Cleanup(&run.SubTasks[i])
-				run.SubTasks[i].Status = "failed"
-			}
+	if cancelled, stopped := h.cancelRun(tenantID); cancelled != nil {
+		h.releaseRunLock(cancelled.RunID)
+		for i := range stopped {
+			_ = Cleanup(&stopped[i])
 		}
-		run.Status = "cancelled"
-		h.publishRunUpdate(context.Background(), run, RunEvent{
-			Type:    "cancelled",
-			RunID:   run.RunID,
-			Status:  run.Status,
-			Message: "Agent swarm run cancelled.",
-		}, true)
-		h.publishTaskSnapshot(run, "cancelled")
+		evt := RunEvent{
+			Type:   "cancelled",
+			RunID:  cancelled.RunID,
+			Status: cancelled.Status,
+		}.withMessage(i18n.MsgRunCancelled)
+		h.publishRunUpdate(context.Background(), cancelled, evt, true)
+		h.publishTaskSnapshot(cancelled, "cancelled")
+		h.recordRun(cancelled)
+		h.saveRunResult(r.Context(), cancelled, evt)
 	}
-	h.mu.Unlock()
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "cancelled"})
@@ -303,11 +511,7 @@ func (h *Handler) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 
 	run, err := h.StartRun(r.Context(), tenantID, body)
 	if err != nil {
-		status := http.StatusBadRequest
-		if strings.Contains(err.Error(), "already running") {
-			status = http.StatusConflict
-		}
-		h.writeJSONError(w, status, err.Error())
+		h.writeJSONError(w, startRunErrorStatus(err), err.Error())
 		return
 	}
 
@@ -354,9 +558,7 @@ func (h *Handler) handleGetTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.mu.RLock()
-	run := h.tasks[taskID]
-	h.mu.RUnlock()
+	run := h.taskSnapshot(taskID)
 	if run == nil {
 		h.writeJSONError(w, http.StatusNotFound, "swarm task not found")
 		return
@@ -367,7 +569,7 @@ func (h *Handler) handleGetTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(cloneRun(run))
+	_ = json.NewEncoder(w).Encode(run)
 }
 
 func (h *Handler) handleTaskEvents(w http.ResponseWriter, r *http.Request) {
@@ -377,9 +579,7 @@ func (h *Handler) handleTaskEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.mu.RLock()
-	run := h.tasks[taskID]
-	h.mu.RUnlock()
+	run := h.taskSnapshot(taskID)
 	if run == nil {
 		h.writeJSONError(w, http.StatusNotFound, "swarm task not found")
 		return
@@ -402,6 +602,10 @@ func (h *Handler) handleTaskEvents(w http.ResponseWriter, r *http.Request) {
 	sub, unsubscribe := h.subscribe(taskID)
 	defer unsubscribe()
 
+	// Take the snapshot again once subscribed so no update falls in between.
+	if current := h.taskSnapshot(taskID); current != nil {
+		run = current
+	}
 	h.writeSSE(w, "snapshot", run)
 	flusher.Flush()
 
@@ -415,13 +619,42 @@ func (h *Handler) handleTaskEvents(w http.ResponseWriter, r *http.Request) {
 		case <-heartbeat.C:
 			_, _ = w.Write([]byte(": keep-alive\n\n"))
 			flusher.Flush()
-		case payload := <-sub:
+		case <-sub.tooSlow:
+			_, _ = w.Write(sseMessage("too_slow", map[string]any{"event": "too_slow", "dropped": sub.droppedEvents()}))
+			flusher.Flush()
+			return
+		case payload := <-sub.ch:
 			_, _ = w.Write(payload)
 			flusher.Flush()
 		}
 	}
 }
 
+// Run returns a copy of the tenant's run with the given id. Runs are kept in
+// memory, so runs from before the last restart are not found.
+func (h *Handler) Run(tenantID, runID string) (*SwarmRun, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	run := h.tasks[strings.TrimSpace(runID)]
+	if run == nil || run.TenantID != strings.TrimSpace(tenantID) {
+		return nil, false
+	}
+	return cloneRun(run), true
+}
+
+// HasCompletedRun reports whether any of the tenant's recent runs completed.
+// Like Run, it only sees runs since the last restart.
+func (h *Handler) HasCompletedRun(tenantID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, run := range h.history[strings.TrimSpace(tenantID)] {
+		if run.Status == "complete" {
+			return true
+		}
+	}
+	return false
+}
+
 func decodeJSONStrict(r *http.Request, dst any) error {
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
@@ -443,13 +676,16 @@ func (h *Handler) prependHistoryLocked(tenantID string, run *SwarmRun) {
 	h.history[tenantID] = history
 }
 
+// cloneRun returns a copy of run that shares no memory with it, so it can be
+// read and encoded after h.mu is released. The caller holds h.mu.
 func cloneRun(run *SwarmRun) *SwarmRun {
 	if run == nil {
 		return nil
 	}
 	clone := *run
-	if run.SubTasks != nil {
-		clone.SubTasks = append([]SubTask(nil), run.SubTasks...)
+	clone.SubTasks = cloneSubTasks(run.SubTasks)
+	if run.PromptTemplates != nil {
+		clone.PromptTemplates = append([]prompts.Ref(nil), run.PromptTemplates...)
 	}
 	if run.ChannelContext != nil {
 		ctxCopy := *run.ChannelContext
@@ -461,19 +697,38 @@ func cloneRun(run *SwarmRun) *SwarmRun {
 		}
 		clone.ChannelContext = &ctxCopy
 	}
+	// The pause gate belongs to the executing run, not to copies of it.
+	clone.gate = nil
 	return &clone
 }
 
+func cloneSubTasks(subtasks []SubTask) []SubTask {
+	if subtasks == nil {
+		return nil
+	}
+	clone := make([]SubTask, len(subtasks))
+	for i, st := range subtasks {
+		clone[i] = st
+		if st.DependsOn != nil {
+			clone[i].DependsOn = append([]string(nil), st.DependsOn...)
+		}
+	}
+	return clone
+}
+
+// publishRunUpdate sends evt to the run's channel when the channel's
+// notification level asks for it. run is a snapshot from cloneRun. SSE subscribers and the run timeline are
+// fed separately and always see every event.
 func (h *Handler) publishRunUpdate(ctx context.Context, run *SwarmRun, evt RunEvent, final bool) {
 	if h.redis == nil || run == nil || run.ChannelContext == nil {
 		return
 	}
-
-	content := strings.TrimSpace(evt.Message)
-	if content == "" {
-		content = fmt.Sprintf("Run %s: %s", run.RunID, evt.Type)
+	if !notifyChannel(h.notificationLevel(run), evt) {
+		return
 	}
 
+	content := h.channelContent(ctx, run, evt)
+
 	metadata := map[string]string{
 		"run_id":       run.RunID,
 		"event":        evt.Type,
@@ -483,6 +738,9 @@ func (h *Handler) publishRunUpdate(ctx context.Context, run *SwarmRun, evt RunEv
 	if evt.SubTaskID != "" {
 		metadata["subtask_id"] = evt.SubTaskID
 	}
+	if run.RequestID != "" {
+		metadata["request_id"] = run.RequestID
+	}
 	if run.ChannelContext.ThreadID != "" {
 		metadata["thread_id"] = run.ChannelContext.ThreadID
 	}
@@ -502,19 +760,27 @@ func (h *Handler) publishRunUpdate(ctx context.Context, run *SwarmRun, evt RunEv
 		Metadata:       metadata,
 	}
 
-	payload, err := json.Marshal(out)
-	if err != nil {
-		slog.Error("failed to marshal swarm channel update", "run", run.RunID, "err", err)
-		return
-	}
-
-	topic := fmt.Sprintf("tenant:%s:response", run.TenantID)
-	if err := h.redis.Publish(ctx, topic, payload).Err(); err != nil {
+	if err := channels.PublishOutbound(ctx, h.redis, out); err != nil {
 		slog.Error("failed to publish swarm channel update", "run", run.RunID, "err", err)
 	}
 }
 
-func (h *Handler) maxAgentsForTenant(tenantID string) int {
+// maxAgentsForTenant prefers the tenant's swarm config override, then its
+// plan limit, then the legacy MAX_SWARM_AGENTS_{TENANT} override, then the
+// global default.
+func (h *Handler) maxAgentsForTenant(ctx context.Context, tenantID string) int {
+	if cfg := h.tenantSwarmConfig(ctx, tenantID); cfg != nil && cfg.MaxAgents > 0 {
+		return cfg.MaxAgents
+	}
+	if h.plans != nil {
+		plan, err := h.plans.ForTenant(ctx, tenantID)
+		if err != nil {
+			slog.Warn("failed to resolve tenant plan", "tenant", tenantID, "err", err)
+		} else if plan != nil && plan.MaxSwarmAgents > 0 {
+			return plan.MaxSwarmAgents
+		}
+	}
+
 	key := sanitizeForEnv(tenantID)
 	if key != "" {
 		envKey := "MAX_SWARM_AGENTS_" + key
@@ -524,7 +790,7 @@ func (h *Handler) maxAgentsForTenant(tenantID string) int {
 			}
 		}
 	}
-	return h.cfg.DefaultMaxAgents
+	return h.swarmConfig().DefaultMaxAgents
 }
 
 func sanitizeForEnv(input string) string {
@@ -542,96 +808,67 @@ func sanitizeForEnv(input string) string {
 	return b.String()
 }
 
-func (h *Handler) applySubTaskEvent(taskID string, evt RunEvent) {
+// applySubTaskEvent records evt on the run's subtask and streams the run to
+// SSE subscribers. A subtask that started on a hand and now finished is
+// recorded as an invocation of that hand. It reports whether the update
+// finished half the run.
+func (h *Handler) applySubTaskEvent(taskID string, evt RunEvent) bool {
 	h.mu.Lock()
 	run := h.tasks[taskID]
 	if run == nil {
 		h.mu.Unlock()
-		return
+		return false
 	}
+	finishing := false
+	var invocation *HandInvocation
 	for i := range run.SubTasks {
 		if run.SubTasks[i].ID == evt.SubTaskID {
 			if evt.Status != "" {
+				finishing = !subTaskFinished(run.SubTasks[i].Status) && subTaskFinished(evt.Status)
 				run.SubTasks[i].Status = evt.Status
 			}
+			if st := run.SubTasks[i]; finishing && st.AssignedHand != "" && !st.StartedAt.IsZero() {
+				invocation = &HandInvocation{
+					HandID:    st.AssignedHand,
+					TenantID:  run.TenantID,
+					LatencyMs: time.Since(st.StartedAt).Milliseconds(),
+					Status:    st.Status,
+					InvokedAt: st.StartedAt,
+				}
+			}
+			if evt.Type == "subtask_started" {
+				run.SubTasks[i].TmuxSession = agentSessionName(evt.SubTaskID)
+				run.SubTasks[i].StartedAt = time.Now()
+			}
 			break
 		}
 	}
+	done := 0
+	for _, st := range run.SubTasks {
+		if subTaskFinished(st.Status) {
+			done++
+		}
+	}
+	halfway := finishing && halfwayReached(done, len(run.SubTasks))
 	clone := cloneRun(run)
 	h.mu.Unlock()
 
+	h.recordInvocation(invocation)
 	h.writeSSEPayload(taskID, "update", clone)
+	return halfway
 }
 
+// publishTaskSnapshot streams run, a snapshot from cloneRun, to the task's
+// SSE subscribers. It takes h.mu, so the caller must not hold it.
 func (h *Handler) publishTaskSnapshot(run *SwarmRun, event string) {
 	if run == nil {
 		return
 	}
-	h.writeSSEPayload(run.RunID, event, cloneRun(run))
-}
-
-func (h *Handler) writeSSEPayload(taskID, event string, run *SwarmRun) {
-	payload, err := json.Marshal(map[string]any{
-		"event": event,
-		"task":  run,
-	})
-	if err != nil {
-		return
-	}
-
-	msg := append([]byte("event: "+event+"\n"), []byte("data: ")...)
-	msg = append(msg, payload...)
-	msg = append(msg, []byte("\n\n")...)
-
-	h.mu.RLock()
-	subscribers := h.subscribers[taskID]
-	chans := make([]chan []byte, 0, len(subscribers))
-	for ch := range subscribers {
-		chans = append(chans, ch)
-	}
-	h.mu.RUnlock()
-
-	for _, ch := range chans {
-		select {
-		case ch <- msg:
-		default:
-		}
-	}
-}
-
-func (h *Handler) subscribe(taskID string) (chan []byte, func()) {
-	ch := make(chan []byte, 32)
-	h.mu.Lock()
-	if h.subscribers[taskID] == nil {
-		h.subscribers[taskID] = make(map[chan []byte]struct{})
-	}
-	h.subscribers[taskID][ch] = struct{}{}
-	h.mu.Unlock()
-
-	return ch, func() {
-		h.mu.Lock()
-		subs := h.subscribers[taskID]
-		delete(subs, ch)
-		if len(subs) == 0 {
-			delete(h.subscribers, taskID)
-		}
-		h.mu.Unlock()
-		close(ch)
-	}
+	h.writeSSEPayload(run.RunID, event, run)
 }
 
 func (h *Handler) writeSSE(w http.ResponseWriter, event string, run *SwarmRun) {
-	payload, err := json.Marshal(map[string]any{
-		"event": event,
-		"task":  cloneRun(run),
-	})
-	if err != nil {
-		return
-	}
-	_, _ = w.Write([]byte("event: " + event + "\n"))
-	_, _ = w.Write([]byte("data: "))
-	_, _ = w.Write(payload)
-	_, _ = w.Write([]byte("\n\n"))
+	_, _ = w.Write(sseMessage(event, map[string]any{"event": event, "task": run}))
 }
 
 func (h *Handler) writeJSONError(w http.ResponseWriter, status int, message string) {
@@ -0,0 +1,222 @@
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// agentHealthTimeout is how long the health checks of a subtask's
+	// candidate agents may take together.
+	agentHealthTimeout = 5 * time.Second
+	// agentHealthTTL is how long a health check result is reused.
+	agentHealthTTL = 30 * time.Second
+)
+
+// agentHealth is the last health check result for a model.
+type agentHealth struct {
+	err       error
+	checkedAt time.Time
+}
+
+// agentHealthProbe is a health check in flight; done is closed once err is
+// set.
+type agentHealthProbe struct {
+	done chan struct{}
+	err  error
+}
+
+// agentHealthCache holds health check results, failures included, for
+// agentHealthTTL. Results are keyed by tenant and model: a ping is billed to
+// the tenant, and agent types on the same model get the same answer. A check
+// for a key already being probed waits for that probe instead of sending
+// another.
+type agentHealthCache struct {
+	mu       sync.Mutex
+	results  map[string]agentHealth
+	inFlight map[string]*agentHealthProbe
+}
+
+func newAgentHealthCache() *agentHealthCache {
+	return &agentHealthCache{results: make(map[string]agentHealth), inFlight: make(map[string]*agentHealthProbe)}
+}
+
+// sharedAgentHealth is the process-wide cache, so every run of a tenant
+// reuses the results of the others.
+var sharedAgentHealth = newAgentHealthCache()
+
+// check returns the cached result for key or runs probe for it.
+func (h *agentHealthCache) check(ctx context.Context, key string, probe func(context.Context) error) error {
+	h.mu.Lock()
+	if last, ok := h.results[key]; ok && time.Since(last.checkedAt) < agentHealthTTL {
+		h.mu.Unlock()
+		return last.err
+	}
+	if p, ok := h.inFlight[key]; ok {
+		h.mu.Unlock()
+		select {
+		case <-p.done:
+			return p.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	p := &agentHealthProbe{done: make(chan struct{})}
+	h.inFlight[key] = p
+	h.mu.Unlock()
+
+	p.err = probe(ctx)
+	h.mu.Lock()
+	delete(h.inFlight, key)
+	// A cancelled run says nothing about the model; running out of time does.
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		h.results[key] = agentHealth{err: p.err, checkedAt: time.Now()}
+	}
+	h.mu.Unlock()
+	close(p.done)
+	return p.err
+}
+
+func (c *Coordinator) healthCache() *agentHealthCache {
+	if c.health != nil {
+		return c.health
+	}
+	return sharedAgentHealth
+}
+
+// agentModel is the model agentType runs on.
+func (c *Coordinator) agentModel(agentType string) string {
+	if model := c.agentModels[agentType]; model != "" {
+		return model
+	}
+	return resolveModel()
+}
+
+// AgentHealthCheck reports whether agentType can take a subtask: a ping of
+// its model sent through the LLM proxy on the tenant's behalf must be
+// answered within agentHealthTimeout. Without an LLM_PROXY_URL there is
+// nothing to probe and every agent counts as healthy.
+func (c *Coordinator) AgentHealthCheck(ctx context.Context, agentType string) error {
+	return c.agentHealth(ctx, []string{agentType})[agentType]
+}
+
+// agentHealth checks agentTypes in parallel under one agentHealthTimeout,
+// probing each of their models once, and returns each type's result.
+func (c *Coordinator) agentHealth(ctx context.Context, agentTypes []string) map[string]error {
+	probe := c.healthCheck
+	if probe == nil {
+		probe = c.pingAgent
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, agentHealthTimeout)
+	defer cancel()
+
+	models := make(map[string]bool)
+	for _, agentType := range agentTypes {
+		models[c.agentModel(agentType)] = true
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	byModel := make(map[string]error, len(models))
+	for model := range models {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := c.healthCache().check(probeCtx, c.TenantID+"\x00"+model, func(ctx context.Context) error {
+				return probe(ctx, model)
+			})
+			mu.Lock()
+			byModel[model] = err
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	results := make(map[string]error, len(agentTypes))
+	for _, agentType := range agentTypes {
+		err := byModel[c.agentModel(agentType)]
+		if ctx.Err() != nil {
+			// The run was cancelled, which says nothing about the agent.
+			err = ctx.Err()
+		} else if err != nil {
+			slog.Warn("agent health check failed", "tenant", c.TenantID, "agent_type", agentType, "err", err)
+		}
+		results[agentType] = err
+	}
+	return results
+}
+
+// assignHealthyAgent keeps st with its assigned agent type if that passes its
+// health check and otherwise reassigns it to the first healthy default hand.
+// It reports false when no agent type is healthy.
+func (c *Coordinator) assignHealthyAgent(ctx context.Context, st *SubTask) bool {
+	candidates := []string{st.AssignedHand}
+	for _, hand := range defaultHands {
+		if hand != st.AssignedHand {
+			candidates = append(candidates, hand)
+		}
+	}
+	health := c.agentHealth(ctx, candidates)
+	for _, hand := range candidates {
+		if health[hand] == nil {
+			st.AssignedHand = hand
+			return true
+		}
+	}
+	return false
+}
+
+// pingAgent sends a one-token "ping" completion for model through the LLM
+// proxy.
+func (c *Coordinator) pingAgent(ctx context.Context, model string) error {
+	proxyURL := strings.TrimSpace(os.Getenv("LLM_PROXY_URL"))
+	if proxyURL == "" {
+		return nil
+	}
+	payload, err := json.Marshal(map[string]any{
+		"model":      model,
+		"max_tokens": 1,
+		"messages":   []map[string]string{{"role": "user", "content": "ping"}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, resolveLLMProxyURL(), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Tenant-ID", c.TenantID)
+	if serviceKey := strings.TrimSpace(os.Getenv("SERVICE_API_KEY")); serviceKey != "" {
+		req.Header.Set("X-Service-API-Key", serviceKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ping %s: %w", model, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return fmt.Errorf("read %s ping: %w", model, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("ping %s: llm proxy returned %d", model, resp.StatusCode)
+	}
+	var completion struct {
+		Choices []json.RawMessage `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &completion); err != nil || len(completion.Choices) == 0 {
+		return errors.New("ping " + model + ": llm proxy returned no completion")
+	}
+	return nil
+}
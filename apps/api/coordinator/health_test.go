@@ -0,0 +1,162 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testAgentModels() map[string]string {
+	models := make(map[string]string, len(defaultHands))
+	for _, hand := range defaultHands {
+		models[hand] = "model/" + hand
+	}
+	return models
+}
+
+func TestAgentHealthCheck(t *testing.T) {
+	t.Parallel()
+	cache := newAgentHealthCache()
+	var mu sync.Mutex
+	probes := map[string]int{}
+	probe := func(_ context.Context, model string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		probes[model]++
+		if model == "model/Research Hand" || model == "model/Planner Hand" {
+			return errors.New("no response")
+		}
+		return nil
+	}
+	coordinator := func(tenantID string) *Coordinator {
+		c := NewCoordinatorWithLimits(tenantID, 1, time.Minute)
+		c.health, c.agentModels, c.healthCheck = cache, testAgentModels(), probe
+		return c
+	}
+
+	c := coordinator("t1")
+	st := &SubTask{ID: "s1", AssignedHand: "Research Hand"}
+	if !c.assignHealthyAgent(context.Background(), st) || st.AssignedHand != "Execution Hand" {
+		t.Fatalf("assigned to %q", st.AssignedHand)
+	}
+	// Results, failures included, are reused within the TTL, by the tenant's
+	// other runs too.
+	st = &SubTask{ID: "s2", AssignedHand: "Research Hand"}
+	if !coordinator("t1").assignHealthyAgent(context.Background(), st) || st.AssignedHand != "Execution Hand" {
+		t.Fatalf("second subtask assigned to %q", st.AssignedHand)
+	}
+	if probes["model/Research Hand"] != 1 || probes["model/Planner Hand"] != 1 || probes["model/Execution Hand"] != 1 || len(probes) != len(defaultHands) {
+		t.Fatalf("probes = %v", probes)
+	}
+	if err := coordinator("t2").AgentHealthCheck(context.Background(), "QA Hand"); err != nil || probes["model/QA Hand"] != 2 {
+		t.Fatalf("other tenant err=%v probes=%v", err, probes)
+	}
+
+	// Agent types on one model share its result.
+	shared := coordinator("t3")
+	shared.agentModels = map[string]string{}
+	shared.assignHealthyAgent(context.Background(), &SubTask{AssignedHand: "QA Hand"})
+	if probes[resolveModel()] != 1 {
+		t.Fatalf("probes = %v", probes)
+	}
+
+	cache.results["t1\x00model/Research Hand"] = agentHealth{err: errors.New("stale"), checkedAt: time.Now().Add(-agentHealthTTL)}
+	if err := c.AgentHealthCheck(context.Background(), "Research Hand"); err == nil || err.Error() != "no response" || probes["model/Research Hand"] != 2 {
+		t.Fatalf("expired result err=%v probes=%v", err, probes)
+	}
+
+	none := NewCoordinatorWithLimits("t1", 1, time.Minute)
+	none.health, none.agentModels = newAgentHealthCache(), testAgentModels()
+	none.healthCheck = func(context.Context, string) error { return errors.New("down") }
+	if none.assignHealthyAgent(context.Background(), &SubTask{AssignedHand: "QA Hand"}) {
+		t.Fatalf("expected no healthy agent")
+	}
+}
+
+func TestAgentHealthProbesInParallel(t *testing.T) {
+	t.Parallel()
+	c := NewCoordinatorWithLimits("t1", 1, time.Minute)
+	c.health, c.agentModels = newAgentHealthCache(), testAgentModels()
+	var started sync.WaitGroup
+	started.Add(len(defaultHands))
+	all := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(all)
+	}()
+	// Each probe only answers once every model is being probed, so probing
+	// one after another would run out the deadline.
+	c.healthCheck = func(ctx context.Context, model string) error {
+		started.Done()
+		select {
+		case <-all:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	start := time.Now()
+	st := &SubTask{AssignedHand: "QA Hand"}
+	if !c.assignHealthyAgent(context.Background(), st) || st.AssignedHand != "QA Hand" {
+		t.Fatalf("assigned to %q", st.AssignedHand)
+	}
+	if elapsed := time.Since(start); elapsed >= agentHealthTimeout {
+		t.Fatalf("health checks took %s", elapsed)
+	}
+}
+
+func TestPingAgent(t *testing.T) {
+	var tenant, model string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = r.Header.Get("X-Tenant-ID")
+		var body struct {
+			Model    string              `json:"model"`
+			Messages []map[string]string `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		model = body.Model
+		if len(body.Messages) != 1 || body.Messages[0]["content"] != "ping" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"content": "pong"}}},
+		})
+	}))
+	defer server.Close()
+
+	c := NewCoordinatorWithLimits("t1", 1, time.Minute)
+	t.Setenv("LLM_PROXY_URL", "")
+	if err := c.pingAgent(context.Background(), "openai/gpt-4.1"); err != nil {
+		t.Fatalf("ping without a proxy: %v", err)
+	}
+	t.Setenv("LLM_PROXY_URL", server.URL)
+	if err := c.pingAgent(context.Background(), "openai/gpt-4.1"); err != nil || tenant != "t1" || model != "openai/gpt-4.1" {
+		t.Fatalf("ping err=%v tenant=%q model=%q", err, tenant, model)
+	}
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	if err := c.pingAgent(context.Background(), "openai/gpt-4.1"); err == nil {
+		t.Fatalf("expected unavailable proxy to fail the ping")
+	}
+}
+
+func TestLoadAgentModels(t *testing.T) {
+	t.Setenv("SWARM_AGENT_MODELS", " Research Hand = openai/gpt-4.1 ,QA Hand=anthropic/claude-haiku,broken, =x")
+	c := NewCoordinatorWithLimits("t1", 1, time.Minute)
+	if got := c.agentModel("Research Hand"); got != "openai/gpt-4.1" {
+		t.Fatalf("Research Hand model = %q", got)
+	}
+	if got := c.agentModel("Planner Hand"); got != resolveModel() {
+		t.Fatalf("Planner Hand model = %q", got)
+	}
+	if len(c.agentModels) != 2 {
+		t.Fatalf("models = %v", c.agentModels)
+	}
+}
@@ -0,0 +1,70 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/agentsquads/api/i18n"
+)
+
+// LocaleSource returns the locale a tenant chose for its system messages, or
+// "" when it has not chosen one. i18n.TenantLocales implements it.
+type LocaleSource interface {
+	TenantLocale(ctx context.Context, tenantID string) (string, error)
+}
+
+// SetMessages makes channel run updates and command replies use catalog, in
+// the locale from locales. Without it they are sent in English.
+func (h *Handler) SetMessages(catalog *i18n.Catalog, locales LocaleSource) {
+	h.messages = catalog
+	h.locales = locales
+}
+
+// locale picks the locale for a message to the tenant's channel: the
+// tenant's setting, then the channel user's language from metadata. Lookup
+// failures fall back to the channel user's language.
+func (h *Handler) locale(ctx context.Context, tenantID string, metadata map[string]string) string {
+	if h.locales != nil {
+		locale, err := h.locales.TenantLocale(ctx, tenantID)
+		if err != nil && !errors.Is(err, i18n.ErrTenantNotFound) {
+			slog.Warn("load tenant locale failed", "tenant", tenantID, "err", err)
+		}
+		if locale != "" {
+			return locale
+		}
+	}
+	return i18n.Normalize(metadata["language_code"])
+}
+
+// text returns message id for the tenant's channel. args are placeholder
+// names and values in pairs.
+func (h *Handler) text(ctx context.Context, tenantID string, metadata map[string]string, id string, args ...string) string {
+	return h.messages.Text(h.locale(ctx, tenantID, metadata), id, args...)
+}
+
+// channelContent returns the text of evt for the run's channel: catalog
+// messages in the tenant's locale, anything else, such as the swarm's own
+// output, as it is.
+func (h *Handler) channelContent(ctx context.Context, run *SwarmRun, evt RunEvent) string {
+	metadata := run.ChannelContext.Metadata
+	content := strings.TrimSpace(evt.Message)
+	switch {
+	case evt.messageID != "":
+		return h.text(ctx, run.TenantID, metadata, evt.messageID, evt.messageArgs...)
+	case content == "":
+		return h.text(ctx, run.TenantID, metadata, i18n.MsgRunEvent, "run_id", run.RunID, "event", evt.Type)
+	}
+	return content
+}
+
+// withMessage sets the event's message to the English text of id, which is
+// what the API and stored results show, and keeps id so channel updates can
+// be sent in the tenant's locale.
+func (e RunEvent) withMessage(id string, args ...string) RunEvent {
+	e.Message = i18n.Text(i18n.DefaultLocale, id, args...)
+	e.messageID = id
+	e.messageArgs = args
+	return e
+}
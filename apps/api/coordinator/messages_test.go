@@ -0,0 +1,73 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/agentsquads/api/channels"
+	"github.com/agentsquads/api/i18n"
+)
+
+type stubLocales map[string]string
+
+func (s stubLocales) TenantLocale(_ context.Context, tenantID string) (string, error) {
+	if tenantID == "broken" {
+		return "", errors.New("db down")
+	}
+	return s[tenantID], nil
+}
+
+func TestChannelContentLocale(t *testing.T) {
+	t.Parallel()
+	h := NewHandler(nil)
+	h.SetMessages(i18n.NewCatalog(nil), stubLocales{"t-es": "es"})
+	portuguese := &ChannelContext{Channel: "telegram", Metadata: map[string]string{"language_code": "pt-br"}}
+	accepted := RunEvent{Type: "queued", RunID: "r1"}.withMessage(i18n.MsgRunAccepted)
+	if accepted.Message != "Agent swarm run accepted." {
+		t.Fatalf("event message = %q", accepted.Message)
+	}
+
+	tests := []struct {
+		name     string
+		tenantID string
+		channel  *ChannelContext
+		evt      RunEvent
+		want     string
+	}{
+		{name: "tenant locale", tenantID: "t-es", channel: portuguese, evt: accepted, want: "Ejecución del equipo de agentes aceptada."},
+		{name: "channel user language", tenantID: "t-unset", channel: portuguese, evt: accepted, want: "Execução da equipe de agentes aceita."},
+		{name: "lookup failure", tenantID: "broken", channel: &ChannelContext{Channel: "web"}, evt: accepted, want: "Agent swarm run accepted."},
+		{name: "placeholders", tenantID: "t-es", channel: portuguese,
+			evt:  RunEvent{Type: "retry", RunID: "r1"}.withMessage(i18n.MsgSubTaskRetrying, "subtask_id", "r1-2"),
+			want: "Reintentando la subtarea r1-2."},
+		{name: "swarm output", tenantID: "t-es", channel: portuguese, evt: RunEvent{Type: "complete", Message: "Here is the report."}, want: "Here is the report."},
+		{name: "no message", tenantID: "t-es", channel: portuguese, evt: RunEvent{Type: "custom"}, want: "Ejecución r1: custom"},
+	}
+	for _, tt := range tests {
+		run := &SwarmRun{RunID: "r1", TenantID: tt.tenantID, ChannelContext: tt.channel}
+		if got := h.channelContent(context.Background(), run, tt.evt); got != tt.want {
+			t.Errorf("%s: content = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBridgeRepliesInTenantLocale(t *testing.T) {
+	t.Parallel()
+	h := NewHandler(nil)
+	h.SetNotificationPreferences(&stubNotificationPrefs{levels: map[string]string{"telegram": channels.NotifyMilestones}})
+	h.SetMessages(i18n.NewCatalog(nil), stubLocales{"t1": "es"})
+	b := NewBridge(h)
+
+	res, err := b.HandleChannelMessage(context.Background(), channels.AgentTaskRequest{TenantID: "t1", Channel: "telegram", Content: "/notifications final_only"})
+	if err != nil || res.Ack != "Las notificaciones de ejecución aquí ahora están en final_only." {
+		t.Fatalf("ack = %q, %v", res.Ack, err)
+	}
+	res, err = b.HandleChannelMessage(context.Background(), channels.AgentTaskRequest{
+		TenantID: "t2", Channel: "whatsapp", Content: "/notifications all",
+		Metadata: map[string]string{"language_code": "pt"},
+	})
+	if err != nil || res.Ack != "Este canal não está vinculado ao seu espaço de trabalho." {
+		t.Fatalf("ack = %q, %v", res.Ack, err)
+	}
+}
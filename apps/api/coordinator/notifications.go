@@ -0,0 +1,125 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/agentsquads/api/channels"
+	"github.com/agentsquads/api/i18n"
+)
+
+const notificationsCommand = "/notifications"
+
+// NotificationPreferences reads and changes how many run updates a tenant's
+// linked channel receives. channels.LinkStore implements it.
+type NotificationPreferences interface {
+	NotificationLevel(tenantID, channel string) (string, error)
+	SetNotificationLevel(tenantID, channel, level string) (channels.TenantChannel, error)
+}
+
+// SetNotificationPreferences makes channel run updates follow each linked
+// channel's notification level. Without it every update is sent.
+func (h *Handler) SetNotificationPreferences(prefs NotificationPreferences) {
+	h.notifications = prefs
+}
+
+// notificationLevel returns the level for the run's channel. Lookup failures
+// fall back to the default rather than flooding or silencing the channel.
+func (h *Handler) notificationLevel(run *SwarmRun) string {
+	if h.notifications == nil {
+		return channels.NotifyAll
+	}
+	level, err := h.notifications.NotificationLevel(run.TenantID, run.ChannelContext.Channel)
+	if err != nil {
+		slog.Warn("load notification level failed", "tenant", run.TenantID, "channel", run.ChannelContext.Channel, "err", err)
+		return channels.DefaultNotificationLevel
+	}
+	return level
+}
+
+// notifyChannel reports whether evt should reach a channel at level.
+func notifyChannel(level string, evt RunEvent) bool {
+	switch level {
+	case channels.NotifyFinalOnly:
+		return runFinished(evt)
+	case channels.NotifyErrorsOnly:
+		if evt.SubTaskID != "" {
+			return evt.Status == "failed" || evt.Status == "timeout"
+		}
+		return evt.Type == "failed"
+	case channels.NotifyMilestones:
+		return evt.Type == "queued" || evt.halfway || runFinished(evt)
+	default:
+		return true
+	}
+}
+
+func runFinished(evt RunEvent) bool {
+	if evt.SubTaskID != "" {
+		return false
+	}
+	switch evt.Type {
+	case "complete", "failed", "cancelled":
+		return true
+	}
+	return false
+}
+
+func subTaskFinished(status string) bool {
+	return status == "complete" || status == "failed" || status == "timeout"
+}
+
+// halfwayReached reports whether finishing one more subtask took a run of
+// total subtasks from under half to at least half done.
+func halfwayReached(done, total int) bool {
+	return total > 1 && done*2 >= total && (done-1)*2 < total
+}
+
+// parseNotificationsCommand recognizes "/notifications" and
+// "/notifications <level>".
+func parseNotificationsCommand(content string) (level string, ok bool) {
+	fields := strings.Fields(strings.ToLower(content))
+	if len(fields) == 0 || fields[0] != notificationsCommand || len(fields) > 2 {
+		return "", false
+	}
+	if len(fields) == 2 {
+		level = fields[1]
+	}
+	return level, true
+}
+
+// handleNotificationsCommand shows or changes the notification level of the
+// channel the command came from.
+func (b *Bridge) handleNotificationsCommand(ctx context.Context, req channels.AgentTaskRequest, level string) (channels.AgentTaskResult, error) {
+	text := func(id string, args ...string) string {
+		return b.handler.text(ctx, req.TenantID, req.Metadata, id, args...)
+	}
+	prefs := b.handler.notifications
+	if prefs == nil {
+		return channels.AgentTaskResult{Accepted: true, Ack: text(i18n.MsgNotificationsUnavailable)}, nil
+	}
+	options := strings.Join(channels.NotificationLevels, ", ")
+	if level == "" {
+		current, err := prefs.NotificationLevel(req.TenantID, req.Channel)
+		if err != nil {
+			return channels.AgentTaskResult{}, err
+		}
+		return channels.AgentTaskResult{
+			Accepted: true,
+			Ack:      text(i18n.MsgNotificationsCurrent, "level", current, "options", options),
+		}, nil
+	}
+
+	_, err := prefs.SetNotificationLevel(req.TenantID, req.Channel, level)
+	switch {
+	case errors.Is(err, channels.ErrInvalidNotificationLevel):
+		return channels.AgentTaskResult{Accepted: true, Ack: text(i18n.MsgNotificationsUnknownLevel, "level", level, "options", options)}, nil
+	case errors.Is(err, channels.ErrChannelNotLinked), errors.Is(err, channels.ErrInvalidChannel):
+		return channels.AgentTaskResult{Accepted: true, Ack: text(i18n.MsgNotificationsNotLinked)}, nil
+	case err != nil:
+		return channels.AgentTaskResult{}, err
+	}
+	return channels.AgentTaskResult{Accepted: true, Ack: text(i18n.MsgNotificationsUpdated, "level", level)}, nil
+}
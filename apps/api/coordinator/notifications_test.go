@@ -0,0 +1,115 @@
+package coordinator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/agentsquads/api/channels"
+)
+
+type stubNotificationPrefs struct {
+	levels map[string]string // channel -> level
+}
+
+func (s *stubNotificationPrefs) NotificationLevel(_, channel string) (string, error) {
+	if level, ok := s.levels[channel]; ok {
+		return level, nil
+	}
+	return channels.DefaultNotificationLevel, nil
+}
+
+func (s *stubNotificationPrefs) SetNotificationLevel(tenantID, channel, level string) (channels.TenantChannel, error) {
+	level, err := channels.ParseNotificationLevel(level)
+	if err != nil {
+		return channels.TenantChannel{}, err
+	}
+	if _, ok := s.levels[channel]; !ok {
+		return channels.TenantChannel{}, channels.ErrChannelNotLinked
+	}
+	s.levels[channel] = level
+	return channels.TenantChannel{TenantID: tenantID, Channel: channel, NotificationLevel: level}, nil
+}
+
+func TestNotifyChannel(t *testing.T) {
+	t.Parallel()
+	queued := RunEvent{Type: "queued"}
+	started := RunEvent{Type: "subtask_started", SubTaskID: "s1", Status: "running"}
+	halfway := RunEvent{Type: "subtask_update", SubTaskID: "s1", Status: "complete", halfway: true}
+	subtaskFailed := RunEvent{Type: "subtask_update", SubTaskID: "s2", Status: "timeout"}
+	complete := RunEvent{Type: "complete", Status: "complete"}
+	failed := RunEvent{Type: "failed", Status: "failed"}
+
+	tests := []struct {
+		level string
+		evt   RunEvent
+		want  bool
+	}{
+		{channels.NotifyAll, started, true},
+		{channels.NotifyMilestones, queued, true},
+		{channels.NotifyMilestones, started, false},
+		{channels.NotifyMilestones, halfway, true},
+		{channels.NotifyMilestones, complete, true},
+		{channels.NotifyFinalOnly, queued, false},
+		{channels.NotifyFinalOnly, halfway, false},
+		{channels.NotifyFinalOnly, failed, true},
+		{channels.NotifyErrorsOnly, complete, false},
+		{channels.NotifyErrorsOnly, subtaskFailed, true},
+		{channels.NotifyErrorsOnly, failed, true},
+	}
+	for _, tt := range tests {
+		if got := notifyChannel(tt.level, tt.evt); got != tt.want {
+			t.Errorf("notifyChannel(%s, %+v) = %v, want %v", tt.level, tt.evt, got, tt.want)
+		}
+	}
+}
+
+func TestApplySubTaskEventHalfway(t *testing.T) {
+	t.Parallel()
+	h := NewHandler(nil)
+	h.tasks["r1"] = &SwarmRun{RunID: "r1", SubTasks: []SubTask{
+		{ID: "s1", Status: "running"}, {ID: "s2", Status: "running"},
+		{ID: "s3", Status: "running"}, {ID: "s4", Status: "running"},
+	}}
+
+	if h.applySubTaskEvent("r1", RunEvent{SubTaskID: "s1", Status: "complete"}) {
+		t.Fatal("one of four subtasks reported as halfway")
+	}
+	if !h.applySubTaskEvent("r1", RunEvent{SubTaskID: "s2", Status: "failed"}) {
+		t.Fatal("two of four subtasks not reported as halfway")
+	}
+	if h.applySubTaskEvent("r1", RunEvent{SubTaskID: "s3", Status: "complete"}) {
+		t.Fatal("three of four subtasks reported as halfway")
+	}
+}
+
+func TestBridgeNotificationsCommand(t *testing.T) {
+	t.Parallel()
+	h := NewHandler(nil)
+	prefs := &stubNotificationPrefs{levels: map[string]string{"telegram": channels.NotifyMilestones}}
+	h.SetNotificationPreferences(prefs)
+	b := NewBridge(h)
+	req := channels.AgentTaskRequest{TenantID: "t1", Channel: "telegram", Content: "/notifications"}
+
+	res, err := b.HandleChannelMessage(context.Background(), req)
+	if err != nil || !res.Accepted || !strings.Contains(res.Ack, "set to milestones") {
+		t.Fatalf("show = %+v, %v", res, err)
+	}
+
+	req.Content = "/notifications Final_Only"
+	res, err = b.HandleChannelMessage(context.Background(), req)
+	if err != nil || !strings.Contains(res.Ack, "now set to final_only") || prefs.levels["telegram"] != channels.NotifyFinalOnly {
+		t.Fatalf("set = %+v, %v, levels=%v", res, err, prefs.levels)
+	}
+
+	req.Content = "/notifications loud"
+	if res, _ := b.HandleChannelMessage(context.Background(), req); !strings.Contains(res.Ack, "Unknown notification level") {
+		t.Fatalf("invalid level = %+v", res)
+	}
+
+	req.Channel = "whatsapp"
+	req.Content = "/notifications all"
+	if res, _ := b.HandleChannelMessage(context.Background(), req); !strings.Contains(res.Ack, "not linked") {
+		t.Fatalf("unlinked channel = %+v", res)
+	}
+}
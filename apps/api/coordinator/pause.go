@@ -0,0 +1,221 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/agentsquads/api/i18n"
+)
+
+// ErrRunNotPausable is returned when a run cannot be paused or resumed in
+// its current state: it is not running, is already paused, or is not paused.
+var ErrRunNotPausable = errors.New("swarm run cannot be paused or resumed")
+
+// pauseGate holds back the next subtask of a run while the run is paused.
+// Subtasks already running are not interrupted.
+type pauseGate struct {
+	mu     sync.Mutex
+	paused bool
+	resume chan struct{}
+}
+
+// pause closes the gate. It reports false if the gate was already closed.
+func (g *pauseGate) pause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.paused {
+		return false
+	}
+	g.paused = true
+	g.resume = make(chan struct{})
+	return true
+}
+
+// release opens the gate and wakes every waiter. It reports false if the
+// gate was not closed.
+func (g *pauseGate) release() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.paused {
+		return false
+	}
+	g.paused = false
+	close(g.resume)
+	return true
+}
+
+// wait blocks while the gate is closed, or until ctx is done.
+func (g *pauseGate) wait(ctx context.Context) error {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	paused, resume := g.paused, g.resume
+	g.mu.Unlock()
+	if !paused {
+		return nil
+	}
+	select {
+	case <-resume:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PauseRun stops the run from starting further subtasks until ResumeRun is
+// called. Subtasks that are already running carry on.
+func (c *Coordinator) PauseRun(runID string) error {
+	run := c.activeRun(runID)
+	if run == nil {
+		return fmt.Errorf("%w: run is not active", ErrRunNotPausable)
+	}
+	if !run.gate.pause() {
+		return fmt.Errorf("%w: run is already paused", ErrRunNotPausable)
+	}
+	return nil
+}
+
+// ResumeRun lets a paused run start its remaining subtasks again.
+func (c *Coordinator) ResumeRun(runID string) error {
+	run := c.activeRun(runID)
+	if run == nil {
+		return fmt.Errorf("%w: run is not active", ErrRunNotPausable)
+	}
+	if !run.gate.release() {
+		return fmt.Errorf("%w: run is not paused", ErrRunNotPausable)
+	}
+	return nil
+}
+
+func (c *Coordinator) activeRun(runID string) *SwarmRun {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.active[strings.TrimSpace(runID)]
+}
+
+// trackRun makes run reachable by PauseRun and ResumeRun while it executes.
+func (c *Coordinator) trackRun(run *SwarmRun) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.active == nil {
+		c.active = make(map[string]*SwarmRun)
+	}
+	if run.gate == nil {
+		run.gate = &pauseGate{}
+	}
+	c.active[run.RunID] = run
+}
+
+// untrackRun forgets run once it finished, opening its gate in case it was
+// still paused.
+func (c *Coordinator) untrackRun(run *SwarmRun) {
+	c.mu.Lock()
+	delete(c.active, run.RunID)
+	c.mu.Unlock()
+	run.gate.release()
+}
+
+// PauseRun pauses the tenant's running run. The run stays "running" with
+// Paused set, so it still blocks new runs for the tenant.
+func (h *Handler) PauseRun(ctx context.Context, tenantID, runID string) (*SwarmRun, error) {
+	return h.setRunPaused(ctx, tenantID, runID, true)
+}
+
+// ResumeRun resumes a run paused with PauseRun.
+func (h *Handler) ResumeRun(ctx context.Context, tenantID, runID string) (*SwarmRun, error) {
+	return h.setRunPaused(ctx, tenantID, runID, false)
+}
+
+func (h *Handler) setRunPaused(ctx context.Context, tenantID, runID string, paused bool) (*SwarmRun, error) {
+	h.mu.Lock()
+	run := h.tasks[strings.TrimSpace(runID)]
+	if run == nil || run.TenantID != strings.TrimSpace(tenantID) {
+		h.mu.Unlock()
+		return nil, ErrRunNotFound
+	}
+	if run.Status != "running" {
+		h.mu.Unlock()
+		return nil, fmt.Errorf("%w: run is %s", ErrRunNotPausable, run.Status)
+	}
+	coord := h.coordinators[run.RunID]
+	if coord == nil {
+		h.mu.Unlock()
+		return nil, fmt.Errorf("%w: run is not active", ErrRunNotPausable)
+	}
+	var err error
+	if paused {
+		err = coord.PauseRun(run.RunID)
+	} else {
+		err = coord.ResumeRun(run.RunID)
+	}
+	if err != nil {
+		h.mu.Unlock()
+		return nil, err
+	}
+	run.Paused = paused
+	snapshot := cloneRun(run)
+	h.mu.Unlock()
+
+	evt := RunEvent{Type: "resumed", RunID: snapshot.RunID, Status: snapshot.Status}.withMessage(i18n.MsgRunResumed)
+	if paused {
+		evt = RunEvent{Type: "paused", RunID: snapshot.RunID, Status: snapshot.Status}.withMessage(i18n.MsgRunPaused)
+	}
+	h.publishRunUpdate(ctx, snapshot, evt, false)
+	h.publishTaskSnapshot(snapshot, evt.Type)
+	return snapshot, nil
+}
+
+func (h *Handler) handlePauseRun(w http.ResponseWriter, r *http.Request) {
+	h.handleSetRunPaused(w, r, true)
+}
+
+func (h *Handler) handleResumeRun(w http.ResponseWriter, r *http.Request) {
+	h.handleSetRunPaused(w, r, false)
+}
+
+func (h *Handler) handleSetRunPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	runID := strings.TrimSpace(r.PathValue("run_id"))
+	if tenantID == "" || runID == "" {
+		h.writeJSONError(w, http.StatusBadRequest, "missing tenant or run id")
+		return
+	}
+
+	var (
+		run *SwarmRun
+		err error
+	)
+	if paused {
+		run, err = h.PauseRun(r.Context(), tenantID, runID)
+	} else {
+		run, err = h.ResumeRun(r.Context(), tenantID, runID)
+	}
+	switch {
+	case errors.Is(err, ErrRunNotFound):
+		h.writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, ErrRunNotPausable):
+		h.writeJSONError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		h.writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	status := "resumed"
+	if paused {
+		status = "paused"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"status":    status,
+		"tenant_id": run.TenantID,
+		"run_id":    run.RunID,
+	})
+}
@@ -0,0 +1,144 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPauseGate(t *testing.T) {
+	t.Parallel()
+	g := &pauseGate{}
+	if err := g.wait(context.Background()); err != nil {
+		t.Fatalf("wait on open gate: %v", err)
+	}
+	if g.release() {
+		t.Fatalf("release of open gate reported true")
+	}
+	if !g.pause() || g.pause() {
+		t.Fatalf("pause should succeed once")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- g.wait(context.Background()) }()
+	select {
+	case err := <-done:
+		t.Fatalf("wait returned while paused: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if !g.release() {
+		t.Fatalf("release of paused gate reported false")
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("wait after release: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("wait did not return after release")
+	}
+
+	g.pause()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := g.wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("wait with cancelled context err = %v", err)
+	}
+}
+
+func TestCoordinatorPauseResumeRun(t *testing.T) {
+	t.Parallel()
+	c := NewCoordinatorWithLimits("t1", 1, time.Minute)
+	if err := c.PauseRun("r1"); !errors.Is(err, ErrRunNotPausable) {
+		t.Fatalf("pause of unknown run err = %v", err)
+	}
+
+	run := &SwarmRun{RunID: "r1", TenantID: "t1", Status: "running"}
+	c.trackRun(run)
+	if err := c.ResumeRun("r1"); !errors.Is(err, ErrRunNotPausable) {
+		t.Fatalf("resume of running run err = %v", err)
+	}
+	if err := c.PauseRun("r1"); err != nil {
+		t.Fatalf("PauseRun: %v", err)
+	}
+	if err := c.PauseRun("r1"); !errors.Is(err, ErrRunNotPausable) {
+		t.Fatalf("second pause err = %v", err)
+	}
+	if err := c.ResumeRun("r1"); err != nil {
+		t.Fatalf("ResumeRun: %v", err)
+	}
+
+	c.PauseRun("r1")
+	c.untrackRun(run)
+	if err := run.gate.wait(context.Background()); err != nil {
+		t.Fatalf("finished run still paused: %v", err)
+	}
+	if err := c.PauseRun("r1"); !errors.Is(err, ErrRunNotPausable) {
+		t.Fatalf("pause of finished run err = %v", err)
+	}
+}
+
+func TestHandlePauseResumeRun(t *testing.T) {
+	t.Parallel()
+	h := NewHandler(nil)
+	running := &SwarmRun{RunID: "r1", TenantID: "t1", Status: "running"}
+	done := &SwarmRun{RunID: "r2", TenantID: "t1", Status: "complete"}
+	h.tasks = map[string]*SwarmRun{"r1": running, "r2": done}
+	h.runs = map[string]*SwarmRun{"t1": running}
+
+	coord := NewCoordinatorWithLimits("t1", 1, time.Minute)
+	executing := &SwarmRun{RunID: "r1", TenantID: "t1", Status: "running"}
+	coord.trackRun(executing)
+	h.coordinators["r1"] = coord
+
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{name: "resume before pause", path: "/api/tenants/t1/swarm/runs/r1/resume", status: http.StatusConflict},
+		{name: "pause", path: "/api/tenants/t1/swarm/runs/r1/pause", status: http.StatusOK},
+		{name: "pause again", path: "/api/tenants/t1/swarm/runs/r1/pause", status: http.StatusConflict},
+		{name: "other tenant", path: "/api/tenants/t2/swarm/runs/r1/resume", status: http.StatusNotFound},
+		{name: "unknown run", path: "/api/tenants/t1/swarm/runs/nope/pause", status: http.StatusNotFound},
+		{name: "finished run", path: "/api/tenants/t1/swarm/runs/r2/pause", status: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Fatalf("%s: status = %d, want %d body=%s", tt.name, w.Code, tt.status, w.Body.String())
+		}
+	}
+	if !running.Paused {
+		t.Fatalf("run not marked paused")
+	}
+
+	waited := make(chan error, 1)
+	go func() { waited <- executing.gate.wait(context.Background()) }()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/tenants/t1/swarm/runs/r1/resume", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("resume status = %d body=%s", w.Code, w.Body.String())
+	}
+	if running.Paused {
+		t.Fatalf("run still marked paused")
+	}
+	select {
+	case err := <-waited:
+		if err != nil {
+			t.Fatalf("wait after resume: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("subtask spawn still blocked after resume")
+	}
+}
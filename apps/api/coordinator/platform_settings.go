@@ -0,0 +1,152 @@
+package coordinator
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/agentsquads/api/prompts"
+)
+
+// swarmSettingsKey is the platform_settings row holding the swarm defaults.
+const swarmSettingsKey = "swarm"
+
+// SwarmConfigSource returns the current platform swarm defaults.
+type SwarmConfigSource interface {
+	SwarmConfig() SwarmConfig
+}
+
+// swarmSettings is the platform_settings JSON for swarm defaults. Missing or
+// zero fields keep the environment value.
+type swarmSettings struct {
+	MaxAgents                   int    `json:"max_agents"`
+	DefaultTimeoutSeconds       int    `json:"default_timeout_seconds"`
+	DecompositionPromptTemplate string `json:"decomposition_prompt_template"`
+	ClarificationRubric         string `json:"clarification_rubric"`
+	ClarificationTTLSeconds     int    `json:"clarification_ttl_seconds"`
+}
+
+func (s swarmSettings) apply(cfg SwarmConfig) SwarmConfig {
+	if s.MaxAgents > 0 {
+		cfg.DefaultMaxAgents = s.MaxAgents
+	}
+	if s.DefaultTimeoutSeconds > 0 {
+		cfg.DefaultTimeout = time.Duration(s.DefaultTimeoutSeconds) * time.Second
+	}
+	if v := strings.TrimSpace(s.DecompositionPromptTemplate); v != "" {
+		cfg.DecompositionPromptTemplate = v
+	}
+	if v := strings.TrimSpace(s.ClarificationRubric); v != "" {
+		cfg.ClarificationRubric = v
+	}
+	if s.ClarificationTTLSeconds > 0 {
+		cfg.ClarificationTTL = time.Duration(s.ClarificationTTLSeconds) * time.Second
+	}
+	return cfg
+}
+
+// SwarmSettingsStore serves the swarm defaults from platform_settings, with
+// the environment config as the base so a fresh database still boots. The
+// config is held in memory and replaced whole by Reload.
+type SwarmSettingsStore struct {
+	db       *sql.DB
+	defaults SwarmConfig
+	current  atomic.Pointer[SwarmConfig]
+}
+
+func NewSwarmSettingsStore(db *sql.DB, defaults SwarmConfig) *SwarmSettingsStore {
+	s := &SwarmSettingsStore{db: db, defaults: defaults}
+	s.current.Store(&defaults)
+	return s
+}
+
+// SwarmConfig returns the config as of the last successful reload.
+func (s *SwarmSettingsStore) SwarmConfig() SwarmConfig {
+	return *s.current.Load()
+}
+
+// Reload reads platform_settings and swaps in the resulting config. It
+// returns the names of the fields that changed; on error the current config
+// is kept.
+func (s *SwarmSettingsStore) Reload(ctx context.Context) ([]string, error) {
+	var raw []byte
+	err := s.db.QueryRowContext(ctx,
+		`SELECT value FROM platform_settings WHERE key = $1`, swarmSettingsKey,
+	).Scan(&raw)
+	var settings swarmSettings
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return nil, fmt.Errorf("load swarm settings: %w", err)
+	default:
+		if err := json.Unmarshal(raw, &settings); err != nil {
+			return nil, fmt.Errorf("decode swarm settings: %w", err)
+		}
+		if err := prompts.ValidateDecomposition(settings.DecompositionPromptTemplate); err != nil {
+			return nil, fmt.Errorf("invalid decomposition_prompt_template: %w", err)
+		}
+	}
+
+	next := settings.apply(s.defaults)
+	previous := s.current.Swap(&next)
+	return swarmConfigChanges(*previous, next), nil
+}
+
+// Start reloads the settings every interval until ctx is done.
+func (s *SwarmSettingsStore) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := s.Reload(ctx)
+			if err != nil {
+				slog.Warn("swarm settings reload failed", "err", err)
+			} else if len(changed) > 0 {
+				slog.Info("swarm settings reloaded", "changed", changed)
+			}
+		}
+	}
+}
+
+func swarmConfigChanges(a, b SwarmConfig) []string {
+	changed := []string{}
+	if a.DefaultMaxAgents != b.DefaultMaxAgents {
+		changed = append(changed, "max_agents")
+	}
+	if a.DefaultTimeout != b.DefaultTimeout {
+		changed = append(changed, "default_timeout_seconds")
+	}
+	if a.DecompositionPromptTemplate != b.DecompositionPromptTemplate {
+		changed = append(changed, "decomposition_prompt_template")
+	}
+	if a.ClarificationRubric != b.ClarificationRubric {
+		changed = append(changed, "clarification_rubric")
+	}
+	if a.ClarificationTTL != b.ClarificationTTL {
+		changed = append(changed, "clarification_ttl_seconds")
+	}
+	return changed
+}
+
+// SetSwarmConfigSource makes the handler read platform swarm defaults from
+// src instead of the environment config it was created with.
+func (h *Handler) SetSwarmConfigSource(src SwarmConfigSource) {
+	h.settings = src
+}
+
+// swarmConfig returns one consistent snapshot of the platform defaults.
+func (h *Handler) swarmConfig() SwarmConfig {
+	if h.settings != nil {
+		return h.settings.SwarmConfig()
+	}
+	return h.cfg
+}
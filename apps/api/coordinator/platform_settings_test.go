@@ -0,0 +1,52 @@
+package coordinator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSwarmSettingsStoreReload(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	defaults := SwarmConfig{DefaultMaxAgents: 3, DefaultTimeout: 30 * time.Minute, DecompositionPromptTemplate: "env {{task}}", ClarificationTTL: time.Minute}
+	store := NewSwarmSettingsStore(db, defaults)
+	h := NewHandler(nil)
+	h.SetSwarmConfigSource(store)
+	if got := h.swarmConfig(); got != defaults {
+		t.Fatalf("initial config = %+v", got)
+	}
+
+	mock.ExpectQuery("SELECT value FROM platform_settings").WithArgs("swarm").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow([]byte(`{"max_agents":6,"default_timeout_seconds":600}`)))
+	changed, err := store.Reload(context.Background())
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if want := []string{"max_agents", "default_timeout_seconds"}; !reflect.DeepEqual(changed, want) {
+		t.Fatalf("changed = %v, want %v", changed, want)
+	}
+	if got := h.maxAgentsForTenant(context.Background(), "t1"); got != 6 {
+		t.Fatalf("max agents = %d", got)
+	}
+	if got := h.swarmConfig(); got.DefaultTimeout != 10*time.Minute || got.DecompositionPromptTemplate != "env {{task}}" {
+		t.Fatalf("reloaded config = %+v", got)
+	}
+
+	// Removing the row falls back to the environment defaults.
+	mock.ExpectQuery("SELECT value FROM platform_settings").WithArgs("swarm").WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	if changed, err = store.Reload(context.Background()); err != nil || len(changed) != 2 {
+		t.Fatalf("changed = %v err=%v", changed, err)
+	}
+	if got := store.SwarmConfig(); got != defaults {
+		t.Fatalf("config after row removal = %+v", got)
+	}
+}
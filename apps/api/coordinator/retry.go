@@ -0,0 +1,130 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/agentsquads/api/i18n"
+)
+
+var (
+	// ErrRunNotFound is returned when the tenant has no run with the id.
+	ErrRunNotFound = errors.New("swarm run not found")
+	// ErrSubTaskNotFound is returned when the run has no subtask with the id.
+	ErrSubTaskNotFound = errors.New("subtask not found in run")
+	// ErrRunNotRetryable is returned when the run, or another run for the
+	// tenant, is still running or the run did not fail.
+	ErrRunNotRetryable = errors.New("swarm run cannot be retried")
+)
+
+// RetrySubTask resets one failed subtask of a failed run to pending and runs
+// it again. Other subtasks keep their status and output, so subtasks that
+// were skipped because they depend on it stay failed until retried too.
+func (h *Handler) RetrySubTask(ctx context.Context, tenantID, runID, subTaskID string) (*SwarmRun, error) {
+	tenantID, runID = strings.TrimSpace(tenantID), strings.TrimSpace(runID)
+	if _, ok := h.Run(tenantID, runID); !ok {
+		return nil, ErrRunNotFound
+	}
+	lock, err := h.acquireRunLock(ctx, tenantID, runID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: swarm already running for this tenant", ErrRunNotRetryable)
+	}
+
+	h.mu.Lock()
+	run := h.tasks[runID]
+	if run == nil || run.TenantID != tenantID {
+		h.mu.Unlock()
+		lock.release()
+		return nil, ErrRunNotFound
+	}
+	if current := h.runs[run.TenantID]; current != nil && current != run && current.Status == "running" {
+		h.mu.Unlock()
+		lock.release()
+		return nil, fmt.Errorf("%w: swarm already running for this tenant", ErrRunNotRetryable)
+	}
+	if err := resetSubTaskForRetry(run, strings.TrimSpace(subTaskID)); err != nil {
+		h.mu.Unlock()
+		lock.release()
+		return nil, err
+	}
+	h.runs[run.TenantID] = run
+	snapshot := cloneRun(run)
+	h.mu.Unlock()
+	h.holdRunLock(runID, lock)
+
+	h.publishRunUpdate(ctx, snapshot, RunEvent{
+		Type:      "retry",
+		RunID:     snapshot.RunID,
+		SubTaskID: subTaskID,
+		Status:    "pending",
+	}.withMessage(i18n.MsgSubTaskRetrying, "subtask_id", subTaskID), false)
+	h.publishTaskSnapshot(snapshot, "retry")
+	h.recordRun(snapshot)
+
+	h.execute(ctx, snapshot, snapshot.SubTasks)
+	return snapshot, nil
+}
+
+// resetSubTaskForRetry marks the subtask pending and the run running. The
+// caller holds h.mu.
+func resetSubTaskForRetry(run *SwarmRun, subTaskID string) error {
+	idx := -1
+	for i := range run.SubTasks {
+		if run.SubTasks[i].ID == subTaskID {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return ErrSubTaskNotFound
+	}
+	if run.Status != "failed" {
+		return fmt.Errorf("%w: run is %s", ErrRunNotRetryable, run.Status)
+	}
+	st := &run.SubTasks[idx]
+	if st.Status != "failed" {
+		return fmt.Errorf("%w: subtask is %s", ErrRunNotRetryable, st.Status)
+	}
+
+	st.Status = "pending"
+	st.TmuxSession = ""
+	st.Output = ""
+	run.Status = "running"
+	return nil
+}
+
+func (h *Handler) handleRetrySubTask(w http.ResponseWriter, r *http.Request) {
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	runID := strings.TrimSpace(r.PathValue("run_id"))
+	subTaskID := strings.TrimSpace(r.PathValue("subtask_id"))
+	if tenantID == "" || runID == "" || subTaskID == "" {
+		h.writeJSONError(w, http.StatusBadRequest, "missing tenant, run or subtask id")
+		return
+	}
+
+	run, err := h.RetrySubTask(r.Context(), tenantID, runID, subTaskID)
+	switch {
+	case errors.Is(err, ErrRunNotFound), errors.Is(err, ErrSubTaskNotFound):
+		h.writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, ErrRunNotRetryable):
+		h.writeJSONError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		h.writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"status":     "accepted",
+		"tenant_id":  run.TenantID,
+		"run_id":     run.RunID,
+		"subtask_id": subTaskID,
+	})
+}
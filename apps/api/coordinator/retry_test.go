@@ -0,0 +1,77 @@
+package coordinator
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleRetrySubTaskErrors(t *testing.T) {
+	t.Parallel()
+	h := NewHandler(nil)
+	failed := &SwarmRun{RunID: "r1", TenantID: "t1", Status: "failed", SubTasks: []SubTask{
+		{ID: "a", Status: "complete"},
+		{ID: "b", Status: "failed"},
+	}}
+	running := &SwarmRun{RunID: "r2", TenantID: "t2", Status: "running", SubTasks: []SubTask{{ID: "a", Status: "failed"}}}
+	older := &SwarmRun{RunID: "r3", TenantID: "t2", Status: "failed", SubTasks: []SubTask{{ID: "a", Status: "failed"}}}
+	h.tasks = map[string]*SwarmRun{"r1": failed, "r2": running, "r3": older}
+	h.runs = map[string]*SwarmRun{"t1": failed, "t2": running}
+
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{name: "unknown run", path: "/api/tenants/t1/swarm/runs/nope/subtasks/b/retry", status: http.StatusNotFound},
+		{name: "other tenant", path: "/api/tenants/t2/swarm/runs/r1/subtasks/b/retry", status: http.StatusNotFound},
+		{name: "unknown subtask", path: "/api/tenants/t1/swarm/runs/r1/subtasks/zz/retry", status: http.StatusNotFound},
+		{name: "subtask not failed", path: "/api/tenants/t1/swarm/runs/r1/subtasks/a/retry", status: http.StatusConflict},
+		{name: "run still running", path: "/api/tenants/t2/swarm/runs/r2/subtasks/a/retry", status: http.StatusConflict},
+		{name: "another run running", path: "/api/tenants/t2/swarm/runs/r3/subtasks/a/retry", status: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Fatalf("%s: status = %d, want %d body=%s", tt.name, w.Code, tt.status, w.Body.String())
+		}
+	}
+	if failed.Status != "failed" || failed.SubTasks[0].Status != "complete" || older.Status != "failed" {
+		t.Fatalf("rejected retries changed run state")
+	}
+}
+
+func TestResetSubTaskForRetry(t *testing.T) {
+	t.Parallel()
+	run := &SwarmRun{RunID: "r1", Status: "failed", SubTasks: []SubTask{
+		{ID: "a", Status: "complete", Output: "done"},
+		{ID: "b", Status: "failed", TmuxSession: "agent-b", Output: "partial"},
+		{ID: "c", Status: "failed", DependsOn: []string{"b"}},
+	}}
+
+	if err := resetSubTaskForRetry(run, "b"); err != nil {
+		t.Fatalf("resetSubTaskForRetry: %v", err)
+	}
+	if run.Status != "running" {
+		t.Fatalf("run status = %q", run.Status)
+	}
+	want := []string{"complete", "pending", "failed"}
+	for i, st := range run.SubTasks {
+		if st.Status != want[i] {
+			t.Fatalf("subtask %s status = %q, want %q", st.ID, st.Status, want[i])
+		}
+	}
+	if run.SubTasks[0].Output != "done" || run.SubTasks[1].Output != "" || run.SubTasks[1].TmuxSession != "" {
+		t.Fatalf("subtasks = %+v", run.SubTasks)
+	}
+
+	if err := resetSubTaskForRetry(run, "c"); !errors.Is(err, ErrRunNotRetryable) {
+		t.Fatalf("retry while running err = %v", err)
+	}
+}
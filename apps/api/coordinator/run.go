@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"strings"
 
+	"github.com/agentsquads/api/i18n"
 	"github.com/google/uuid"
 )
 
@@ -35,6 +36,8 @@ func (c *Coordinator) RunWithSubTasks(ctx context.Context, task string, runID st
 	if channelCtx != nil {
 		run.SourceChannel = channelCtx.Channel
 	}
+	c.trackRun(run)
+	defer c.untrackRun(run)
 
 	slog.Info("starting swarm run", "run", run.RunID, "tenant", c.TenantID, "subtasks", len(subtasks))
 
@@ -44,87 +47,122 @@ func (c *Coordinator) RunWithSubTasks(ctx context.Context, task string, runID st
 		ptrs[i] = &run.SubTasks[i]
 	}
 
-	// Spawn up to MaxAgents concurrently; queue the rest
-	running := 0
-	queue := 0
+	byID := make(map[string]*SubTask, len(ptrs))
 	for _, st := range ptrs {
-		if running >= c.MaxAgents {
-			queue = 1 // remaining are queued
-			break
-		}
-		if err := c.SpawnAgent(st, channelCtx); err != nil {
-			slog.Error("failed to spawn agent", "subtask", st.ID, "err", err)
-			st.Status = "failed"
-			emitEvent(onEvent, RunEvent{
-				Type:      "subtask_update",
-				RunID:     run.RunID,
-				SubTaskID: st.ID,
-				Status:    st.Status,
-				Message:   "Failed to spawn sub-agent.",
-			})
-		} else {
-			running++
-			emitEvent(onEvent, RunEvent{
-				Type:      "subtask_started",
-				RunID:     run.RunID,
-				SubTaskID: st.ID,
-				Status:    st.Status,
-				Message:   "Sub-agent started.",
-			})
-		}
-		queue++
+		byID[st.ID] = st
 	}
+	// Subtasks that already finished, as on a retry, are not spawned again.
+	spawned := make([]bool, len(ptrs))
+	for i, st := range ptrs {
+		spawned[i] = st.Status != "" && st.Status != "pending"
+	}
+	running := 0
 
-	// Monitor and spawn queued tasks as slots free up
-	monCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	ch := c.MonitorAgents(monCtx, ptrs)
-	nextIdx := queue // index of next task to spawn
-
-	for completed := range ch {
-		_ = completed // status already updated in-place
-		running--
-
-		// Collect output for completed task
-		if completed.Status == "complete" {
-			output, err := CollectOutput(completed)
-			if err == nil {
-				completed.Output = output
+	// spawnReady starts pending subtasks whose dependencies have completed, up
+	// to MaxAgents concurrently. Subtasks behind a failed dependency are failed
+	// without being spawned.
+	spawnReady := func(queued bool) {
+		for i, st := range ptrs {
+			if running >= c.MaxAgents {
+				return
 			}
-		}
-		emitEvent(onEvent, RunEvent{
-			Type:      "subtask_update",
-			RunID:     run.RunID,
-			SubTaskID: completed.ID,
-			Status:    completed.Status,
-			Message:   fmt.Sprintf("Subtask %s is %s.", completed.ID, completed.Status),
-		})
-
-		// Spawn next queued task if available
-		for nextIdx < len(ptrs) && running < c.MaxAgents {
-			st := ptrs[nextIdx]
-			nextIdx++
-			if err := c.SpawnAgent(st, channelCtx); err != nil {
-				slog.Error("failed to spawn queued agent", "subtask", st.ID, "err", err)
+			if spawned[i] {
+				continue
+			}
+			ready, blocked := dependencyState(st, byID)
+			if blocked {
+				spawned[i] = true
 				st.Status = "failed"
 				emitEvent(onEvent, RunEvent{
 					Type:      "subtask_update",
 					RunID:     run.RunID,
 					SubTaskID: st.ID,
 					Status:    st.Status,
-					Message:   "Failed to spawn queued sub-agent.",
-				})
-			} else {
-				running++
+				}.withMessage(i18n.MsgSubTaskSkipped))
+				continue
+			}
+			if !ready {
+				continue
+			}
+			// A paused run holds back each next subtask until it is resumed.
+			if err := run.gate.wait(ctx); err != nil {
+				return
+			}
+			spawned[i] = true
+
+			assigned := st.AssignedHand
+			if !c.assignHealthyAgent(ctx, st) {
+				st.Status = "failed"
 				emitEvent(onEvent, RunEvent{
-					Type:      "subtask_started",
+					Type:      "subtask_update",
 					RunID:     run.RunID,
 					SubTaskID: st.ID,
 					Status:    st.Status,
-					Message:   "Queued sub-agent started.",
-				})
+				}.withMessage(i18n.MsgSubTaskNoHealthyAgent))
+				continue
 			}
+			if st.AssignedHand != assigned {
+				emitEvent(onEvent, RunEvent{
+					Type:      "subtask_update",
+					RunID:     run.RunID,
+					SubTaskID: st.ID,
+					Status:    st.Status,
+				}.withMessage(i18n.MsgSubTaskReassigned, "hand", st.AssignedHand, "previous_hand", assigned))
+			}
+
+			failMsg, startMsg := i18n.MsgSubTaskSpawnFailed, i18n.MsgSubTaskStarted
+			if queued {
+				failMsg, startMsg = i18n.MsgSubTaskQueuedFailed, i18n.MsgSubTaskQueuedStarted
+			}
+			if err := c.spawnAgent(st, channelCtx); err != nil {
+				slog.Error("failed to spawn agent", "subtask", st.ID, "err", err)
+				st.Status = "failed"
+				emitEvent(onEvent, RunEvent{
+					Type:      "subtask_update",
+					RunID:     run.RunID,
+					SubTaskID: st.ID,
+					Status:    st.Status,
+				}.withMessage(failMsg))
+				continue
+			}
+			running++
+			emitEvent(onEvent, RunEvent{
+				Type:      "subtask_started",
+				RunID:     run.RunID,
+				SubTaskID: st.ID,
+				Status:    st.Status,
+			}.withMessage(startMsg))
+		}
+	}
+
+	// Spawn up to MaxAgents concurrently; queue the rest
+	spawnReady(false)
+
+	// Monitor and spawn queued tasks as slots free up
+	monCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// A subtask spawned after the monitor last saw nothing running, as when
+	// the run was paused, needs a fresh monitor.
+	for running > 0 && monCtx.Err() == nil {
+		for completed := range c.MonitorAgents(monCtx, ptrs) {
+			running--
+
+			// Collect output for completed task
+			if completed.Status == "complete" {
+				output, err := CollectOutput(completed)
+				if err == nil {
+					completed.Output = output
+				}
+			}
+			emitEvent(onEvent, RunEvent{
+				Type:      "subtask_update",
+				RunID:     run.RunID,
+				SubTaskID: completed.ID,
+				Status:    completed.Status,
+			}.withMessage(i18n.MsgSubTaskStatus, "subtask_id", completed.ID, "status", completed.Status))
+
+			spawnReady(true)
 		}
 	}
 
@@ -156,6 +194,25 @@ func (c *Coordinator) RunWithSubTasks(ctx context.Context, task string, runID st
 	return run, nil
 }
 
+// dependencyState reports whether all of st's dependencies completed (ready)
+// or any of them ended without completing (blocked).
+func dependencyState(st *SubTask, byID map[string]*SubTask) (ready, blocked bool) {
+	for _, depID := range st.DependsOn {
+		dep, ok := byID[depID]
+		if !ok {
+			return false, true
+		}
+		switch dep.Status {
+		case "complete":
+		case "pending", "running", "":
+			return false, false
+		default:
+			return false, true
+		}
+	}
+	return true, false
+}
+
 func emitEvent(onEvent func(RunEvent), evt RunEvent) {
 	if onEvent != nil {
 		onEvent(evt)
@@ -0,0 +1,127 @@
+package coordinator
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const runLockPrefix = "swarm:lock:"
+
+// renewRunLock extends a tenant's run lock only while this replica still
+// holds it, and releaseRunLock deletes it under the same condition.
+var (
+	renewRunLock = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	releaseRunLock = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// runLock is a tenant's run lock in Redis, held by one of this replica's
+// runs. The in-process runs map only stops overlapping runs within a replica;
+// the lock stops them across replicas. It expires after ttl unless renewed,
+// so a replica that crashes mid-run blocks the tenant for at most one run
+// timeout.
+type runLock struct {
+	redis *redis.Client
+	key   string
+	token string
+	ttl   time.Duration
+	stop  chan struct{}
+}
+
+func runLockKey(tenantID string) string {
+	return runLockPrefix + tenantID
+}
+
+// acquireRunLock takes the tenant's run lock for runID, with a TTL of the
+// tenant's run timeout. It returns errSwarmAlreadyRunning when another run
+// holds it. Without Redis, or when Redis fails, it returns a nil lock and the
+// in-process check alone applies, so a Redis outage does not stop runs.
+func (h *Handler) acquireRunLock(ctx context.Context, tenantID, runID string) (*runLock, error) {
+	if h.redis == nil {
+		return nil, nil
+	}
+	lock := &runLock{
+		redis: h.redis,
+		key:   runLockKey(tenantID),
+		token: runID + ":" + uuid.NewString(),
+		ttl:   h.timeoutForTenant(ctx, tenantID),
+		stop:  make(chan struct{}),
+	}
+	ok, err := h.redis.SetNX(ctx, lock.key, lock.token, lock.ttl).Result()
+	if err != nil {
+		slog.Warn("acquire swarm run lock failed; using the local check only", "tenant", tenantID, "run", runID, "err", err)
+		return nil, nil
+	}
+	if !ok {
+		return nil, errSwarmAlreadyRunning
+	}
+	return lock, nil
+}
+
+// holdRunLock keeps lock for runID until releaseRunLock, renewing it every
+// third of its TTL. A nil lock is ignored.
+func (h *Handler) holdRunLock(runID string, lock *runLock) {
+	if lock == nil {
+		return
+	}
+	h.mu.Lock()
+	h.runLocks[runID] = lock
+	h.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(max(lock.ttl/3, time.Second))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-lock.stop:
+				return
+			case <-ticker.C:
+			}
+			renewed, err := renewRunLock.Run(context.Background(), lock.redis, []string{lock.key}, lock.token, lock.ttl.Milliseconds()).Int()
+			if err != nil || renewed == 0 {
+				slog.Warn("lost swarm run lock", "key", lock.key, "run", runID, "err", err)
+				return
+			}
+		}
+	}()
+}
+
+// releaseRunLock stops renewing runID's lock and deletes it. It is called
+// once the run reaches a terminal status; later calls do nothing.
+func (h *Handler) releaseRunLock(runID string) {
+	h.mu.Lock()
+	lock := h.runLocks[runID]
+	delete(h.runLocks, runID)
+	h.mu.Unlock()
+	lock.release()
+}
+
+// release deletes the lock unless it expired and another run took it. A nil
+// lock is ignored.
+func (l *runLock) release() {
+	if l == nil {
+		return
+	}
+	select {
+	case <-l.stop:
+		return
+	default:
+		close(l.stop)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := releaseRunLock.Run(ctx, l.redis, []string{l.key}, l.token).Err(); err != nil {
+		slog.Warn("release swarm run lock failed", "key", l.key, "err", err)
+	}
+}
@@ -0,0 +1,264 @@
+package coordinator
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// lockRedis is a Redis stand-in with just enough of SET, GET, DEL and EVAL
+// for the run lock, keys expiring like the real thing.
+type lockRedis struct {
+	mu      sync.Mutex
+	data    map[string]string
+	expires map[string]time.Time
+}
+
+func newLockRedis(t *testing.T) (*lockRedis, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &lockRedis{data: map[string]string{}, expires: map[string]time.Time{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s, ln.Addr().String()
+}
+
+func (s *lockRedis) get(key string) (string, bool) {
+	if exp, ok := s.expires[key]; ok && time.Now().After(exp) {
+		delete(s.data, key)
+		delete(s.expires, key)
+	}
+	v, ok := s.data[key]
+	return v, ok
+}
+
+func (s *lockRedis) value(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, _ := s.get(key)
+	return v
+}
+
+func (s *lockRedis) set(key, value string, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = value
+	s.expires[key] = time.Now().Add(ttl)
+}
+
+func (s *lockRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	for {
+		args, err := readRESP(rd)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		reply := "+OK\r\n"
+		switch strings.ToUpper(args[0]) {
+		case "HELLO":
+			reply = "-ERR unknown command\r\n"
+		case "EVALSHA":
+			reply = "-NOSCRIPT No matching script.\r\n"
+		case "SET":
+			key, nx, ttl := args[1], false, time.Duration(0)
+			for i := 3; i < len(args); i++ {
+				switch strings.ToUpper(args[i]) {
+				case "NX":
+					nx = true
+				case "PX":
+					n, _ := strconv.Atoi(args[i+1])
+					ttl = time.Duration(n) * time.Millisecond
+				case "EX":
+					n, _ := strconv.Atoi(args[i+1])
+					ttl = time.Duration(n) * time.Second
+				}
+			}
+			if _, held := s.get(key); nx && held {
+				reply = "$-1\r\n"
+				break
+			}
+			s.data[key] = args[2]
+			delete(s.expires, key)
+			if ttl > 0 {
+				s.expires[key] = time.Now().Add(ttl)
+			}
+		case "EVAL":
+			// Both lock scripts act only while ARGV[1] holds KEYS[1].
+			script, key, token := args[1], args[3], args[4]
+			reply = ":0\r\n"
+			if v, ok := s.get(key); ok && v == token {
+				if strings.Contains(script, "PEXPIRE") {
+					n, _ := strconv.Atoi(args[5])
+					s.expires[key] = time.Now().Add(time.Duration(n) * time.Millisecond)
+				} else {
+					delete(s.data, key)
+					delete(s.expires, key)
+				}
+				reply = ":1\r\n"
+			}
+		}
+		s.mu.Unlock()
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func readRESP(rd *bufio.Reader) ([]string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	// Bulk strings are read by length, as scripts span several lines.
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		header, err := rd.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "$")))
+		if err != nil {
+			return nil, err
+		}
+		arg := make([]byte, size+2)
+		if _, err := io.ReadFull(rd, arg); err != nil {
+			return nil, err
+		}
+		args = append(args, string(arg[:size]))
+	}
+	return args, nil
+}
+
+func TestRunLockAcrossReplicas(t *testing.T) {
+	t.Parallel()
+	server, addr := newLockRedis(t)
+	replica := func(spawn func(*SubTask, *ChannelContext) error) *Handler {
+		h := NewHandler(redis.NewClient(&redis.Options{Addr: addr, Protocol: 2}))
+		h.spawnAgent = spawn
+		return h
+	}
+	failFast := func(*SubTask, *ChannelContext) error { return errors.New("no workers in tests") }
+	gate := make(chan struct{})
+	first := replica(func(*SubTask, *ChannelContext) error {
+		<-gate
+		return errors.New("no workers in tests")
+	})
+	second := replica(failFast)
+	req := RunRequest{Task: "research the market"}
+	waitUnlocked := func(tenantID string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for server.value(runLockKey(tenantID)) != "" {
+			if time.Now().After(deadline) {
+				t.Fatalf("lock for %s was not released", tenantID)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	run, err := first.StartRun(context.Background(), "t1", req)
+	if err != nil {
+		t.Fatalf("first StartRun: %v", err)
+	}
+	if v := server.value(runLockKey("t1")); !strings.HasPrefix(v, run.RunID+":") {
+		t.Fatalf("lock value = %q", v)
+	}
+	if _, err := second.StartRun(context.Background(), "t1", req); !errors.Is(err, errSwarmAlreadyRunning) {
+		t.Fatalf("second replica StartRun err = %v", err)
+	}
+	if startRunErrorStatus(errSwarmAlreadyRunning) != 409 {
+		t.Fatalf("lock conflicts should map to 409")
+	}
+	if _, err := second.StartRun(context.Background(), "t2", req); err != nil {
+		t.Fatalf("other tenant StartRun: %v", err)
+	}
+
+	// The lock is released once the run fails.
+	close(gate)
+	waitUnlocked("t1")
+	if _, err := second.StartRun(context.Background(), "t1", req); err != nil {
+		t.Fatalf("StartRun after release: %v", err)
+	}
+	waitUnlocked("t1")
+
+	// A replica that crashed holding the lock blocks the tenant until it
+	// expires.
+	server.set(runLockKey("t3"), "gone:token", 50*time.Millisecond)
+	if _, err := second.StartRun(context.Background(), "t3", req); !errors.Is(err, errSwarmAlreadyRunning) {
+		t.Fatalf("StartRun with a held lock err = %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, err := second.StartRun(context.Background(), "t3", req); err != nil {
+		t.Fatalf("StartRun after expiry: %v", err)
+	}
+}
+
+func TestRunLockRenewAndRelease(t *testing.T) {
+	t.Parallel()
+	server, addr := newLockRedis(t)
+	h := NewHandler(redis.NewClient(&redis.Options{Addr: addr, Protocol: 2}))
+	h.cfg.DefaultTimeout = 1500 * time.Millisecond
+
+	lock, err := h.acquireRunLock(context.Background(), "t1", "r1")
+	if err != nil || lock == nil {
+		t.Fatalf("acquire: lock=%v err=%v", lock, err)
+	}
+	h.holdRunLock("r1", lock)
+	// Renewed every half second, the lock outlives its TTL.
+	time.Sleep(2 * time.Second)
+	if server.value(runLockKey("t1")) != lock.token {
+		t.Fatalf("lock was not renewed")
+	}
+	h.releaseRunLock("r1")
+	h.releaseRunLock("r1")
+	if v := server.value(runLockKey("t1")); v != "" {
+		t.Fatalf("lock after release = %q", v)
+	}
+
+	// A lock that expired and was taken by another run is left alone.
+	lock, _ = h.acquireRunLock(context.Background(), "t1", "r2")
+	server.set(runLockKey("t1"), "r3:other", time.Minute)
+	lock.release()
+	if v := server.value(runLockKey("t1")); v != "r3:other" {
+		t.Fatalf("released another run's lock: %q", v)
+	}
+}
+
+func TestRunLockFallsBackWithoutRedis(t *testing.T) {
+	t.Parallel()
+	down := NewHandler(redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond, MaxRetries: -1}))
+	down.spawnAgent = func(*SubTask, *ChannelContext) error { return fmt.Errorf("no workers in tests") }
+	if _, err := down.StartRun(context.Background(), "t1", RunRequest{Task: "research the market"}); err != nil {
+		t.Fatalf("StartRun with Redis down: %v", err)
+	}
+	if lock, err := NewHandler(nil).acquireRunLock(context.Background(), "t1", "r1"); lock != nil || err != nil {
+		t.Fatalf("without redis: lock=%v err=%v", lock, err)
+	}
+}
@@ -0,0 +1,106 @@
+package coordinator
+
+import "errors"
+
+var errSwarmAlreadyRunning = errors.New("swarm already running for this tenant")
+
+// The helpers below are the only places the handler's runs change outside
+// retry and pause. Each holds h.mu for the whole change and returns a snapshot
+// from cloneRun, so callers publish and store results without the lock.
+
+// tenantRunActive reports whether the tenant's latest run is still running.
+func (h *Handler) tenantRunActive(tenantID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	run := h.runs[tenantID]
+	return run != nil && run.Status == "running"
+}
+
+// registerRun makes run the tenant's latest run unless the tenant already has
+// a running one.
+func (h *Handler) registerRun(run *SwarmRun) (*SwarmRun, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if existing := h.runs[run.TenantID]; existing != nil && existing.Status == "running" {
+		return nil, errSwarmAlreadyRunning
+	}
+	h.runs[run.TenantID] = run
+	h.prependHistoryLocked(run.TenantID, run)
+	h.tasks[run.RunID] = run
+	h.taskOrder = append([]string{run.RunID}, h.taskOrder...)
+	return cloneRun(run), nil
+}
+
+// tenantSnapshot returns a copy of the tenant's latest run, or nil.
+func (h *Handler) tenantSnapshot(tenantID string) *SwarmRun {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return cloneRun(h.runs[tenantID])
+}
+
+// taskSnapshot returns a copy of the run with the given id, or nil.
+func (h *Handler) taskSnapshot(runID string) *SwarmRun {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return cloneRun(h.tasks[runID])
+}
+
+// failRun marks the run failed after its coordinator returned an error. It
+// returns nil for a run that was cancelled meanwhile.
+func (h *Handler) failRun(runID string) *SwarmRun {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	run := h.tasks[runID]
+	if run == nil || run.Status == "cancelled" {
+		return nil
+	}
+	run.Status = "failed"
+	run.Paused = false
+	return cloneRun(run)
+}
+
+// finishRun records the coordinator's result on the run. A run cancelled
+// while it executed keeps its cancelled status and finishRun reports false,
+// as its final update was already sent.
+func (h *Handler) finishRun(runID string, result *SwarmRun) (*SwarmRun, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	run := h.tasks[runID]
+	if run == nil {
+		return nil, false
+	}
+	run.SubTasks = cloneSubTasks(result.SubTasks)
+	run.Output = result.Output
+	run.Paused = false
+	if run.Status == "cancelled" {
+		return cloneRun(run), false
+	}
+	run.Status = result.Status
+	return cloneRun(run), true
+}
+
+// cancelRun cancels the tenant's running run. It returns nil when there is
+// none, and otherwise the cancelled run and copies of the subtasks that were
+// still running, whose workers the caller cleans up.
+func (h *Handler) cancelRun(tenantID string) (*SwarmRun, []SubTask) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	run := h.runs[tenantID]
+	if run == nil || run.Status != "running" {
+		return nil, nil
+	}
+	// Let a paused run's coordinator finish instead of waiting forever.
+	if coord := h.coordinators[run.RunID]; coord != nil && run.Paused {
+		_ = coord.ResumeRun(run.RunID)
+		run.Paused = false
+	}
+	var stopped []SubTask
+	for i := range run.SubTasks {
+		if run.SubTasks[i].Status == "running" {
+			stopped = append(stopped, run.SubTasks[i])
+			run.SubTasks[i].Status = "failed"
+		}
+	}
+	run.Status = "cancelled"
+	return cloneRun(run), stopped
+}
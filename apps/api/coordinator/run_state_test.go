@@ -0,0 +1,184 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agentsquads/api/prompts"
+)
+
+func TestCloneRunSharesNoMemory(t *testing.T) {
+	t.Parallel()
+	run := &SwarmRun{
+		RunID:           "r1",
+		ChannelContext:  &ChannelContext{Channel: "telegram", Metadata: map[string]string{"k": "v"}},
+		SubTasks:        []SubTask{{ID: "a", Status: "running", DependsOn: []string{"b"}}},
+		PromptTemplates: []prompts.Ref{{Name: prompts.Decomposition, Version: 1}},
+		gate:            &pauseGate{},
+	}
+	clone := cloneRun(run)
+
+	run.SubTasks[0].Status = "failed"
+	run.SubTasks[0].DependsOn[0] = "c"
+	run.ChannelContext.Metadata["k"] = "changed"
+	run.PromptTemplates[0].Version = 2
+	if clone.SubTasks[0].Status != "running" || clone.SubTasks[0].DependsOn[0] != "b" ||
+		clone.ChannelContext.Metadata["k"] != "v" || clone.PromptTemplates[0].Version != 1 {
+		t.Fatalf("clone changed with the run: %+v", clone)
+	}
+	if clone.gate != nil {
+		t.Fatalf("clone shares the run's pause gate")
+	}
+}
+
+func TestCancelRunKeepsCancelledStatus(t *testing.T) {
+	t.Parallel()
+	h := NewHandler(nil)
+	run := &SwarmRun{RunID: "r1", TenantID: "t1", Status: "running", SubTasks: []SubTask{
+		{ID: "a", Status: "running", TmuxSession: "agent-a"},
+		{ID: "b", Status: "complete"},
+	}}
+	if _, err := h.registerRun(run); err != nil {
+		t.Fatalf("registerRun: %v", err)
+	}
+	if _, err := h.registerRun(&SwarmRun{RunID: "r2", TenantID: "t1", Status: "running"}); !errors.Is(err, errSwarmAlreadyRunning) {
+		t.Fatalf("second registerRun err = %v", err)
+	}
+
+	cancelled, stopped := h.cancelRun("t1")
+	if cancelled == nil || cancelled.Status != "cancelled" || cancelled.SubTasks[0].Status != "failed" {
+		t.Fatalf("cancelled = %+v", cancelled)
+	}
+	if len(stopped) != 1 || stopped[0].TmuxSession != "agent-a" {
+		t.Fatalf("stopped = %+v", stopped)
+	}
+	if again, _ := h.cancelRun("t1"); again != nil {
+		t.Fatalf("cancelled a run that is not running")
+	}
+
+	result := &SwarmRun{Status: "complete", Output: "done", SubTasks: []SubTask{{ID: "a", Status: "complete"}, {ID: "b", Status: "complete"}}}
+	if _, ok := h.finishRun("r1", result); ok {
+		t.Fatalf("finishRun reported a cancelled run as finished")
+	}
+	if failed := h.failRun("r1"); failed != nil {
+		t.Fatalf("failRun changed a cancelled run")
+	}
+	if got := h.taskSnapshot("r1"); got.Status != "cancelled" || got.Output != "done" {
+		t.Fatalf("run = %+v", got)
+	}
+}
+
+// TestHandlerConcurrentRunAccess starts, reads, updates and cancels runs from
+// many goroutines at once. Run it with -race.
+func TestHandlerConcurrentRunAccess(t *testing.T) {
+	t.Parallel()
+	h := NewHandler(nil)
+	h.spawnAgent = func(st *SubTask, _ *ChannelContext) error {
+		time.Sleep(time.Millisecond)
+		st.Status = "failed"
+		return errors.New("no workers in tests")
+	}
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	const (
+		tenants    = 3
+		workers    = 4
+		iterations = 25
+	)
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, tenants*workers*4)
+	for i := 0; i < tenants; i++ {
+		tenantID := fmt.Sprintf("t%d", i)
+		for j := 0; j < workers; j++ {
+			wg.Add(4)
+			go func() {
+				defer wg.Done()
+				for k := 0; k < iterations; k++ {
+					req := RunRequest{Task: "research the market and write a report", ChannelContext: &ChannelContext{Channel: "telegram"}}
+					if _, err := h.StartRun(context.Background(), tenantID, req); err != nil && !errors.Is(err, errSwarmAlreadyRunning) {
+						errs <- err
+						return
+					}
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for k := 0; k < iterations; k++ {
+					w := serve(http.MethodGet, "/api/tenants/"+tenantID+"/swarm/status")
+					if w.Code == http.StatusNotFound {
+						continue
+					}
+					var run SwarmRun
+					if err := json.Unmarshal(w.Body.Bytes(), &run); err != nil {
+						errs <- fmt.Errorf("decode status: %w", err)
+						return
+					}
+					for _, st := range run.SubTasks {
+						h.applySubTaskEvent(run.RunID, RunEvent{Type: "subtask_update", SubTaskID: st.ID, Status: "complete"})
+					}
+					serve(http.MethodGet, "/api/swarm/tasks/"+run.RunID)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for k := 0; k < iterations; k++ {
+					if w := serve(http.MethodPost, "/api/tenants/"+tenantID+"/swarm/cancel"); w.Code != http.StatusOK {
+						errs <- fmt.Errorf("cancel status = %d", w.Code)
+						return
+					}
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for k := 0; k < iterations; k++ {
+					serve(http.MethodGet, "/api/tenants/"+tenantID+"/swarm/runs")
+					serve(http.MethodGet, "/api/swarm/tasks?tenant_id="+tenantID)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		h.mu.RLock()
+		executing := len(h.coordinators)
+		h.mu.RUnlock()
+		if executing == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d runs still executing", executing)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.tasks) == 0 {
+		t.Fatalf("no runs were started")
+	}
+	for id, run := range h.tasks {
+		if run.Status == "running" {
+			t.Errorf("run %s still running after its coordinator returned", id)
+		}
+	}
+}
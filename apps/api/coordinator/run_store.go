@@ -0,0 +1,76 @@
+package coordinator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// RunRecorder persists swarm runs as their status changes.
+type RunRecorder interface {
+	RecordRun(ctx context.Context, run *SwarmRun) error
+}
+
+// RunStore keeps swarm runs in swarm_runs.
+type RunStore struct {
+	db *sql.DB
+}
+
+func NewRunStore(db *sql.DB) *RunStore {
+	return &RunStore{db: db}
+}
+
+func (s *RunStore) RecordRun(ctx context.Context, run *SwarmRun) error {
+	triggerType := run.TriggerType
+	if triggerType == "" {
+		triggerType = "manual"
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO swarm_runs (run_id, tenant_id, task, status, trigger_type, started_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (tenant_id, run_id) DO UPDATE
+		SET status = EXCLUDED.status, updated_at = NOW()
+	`, run.RunID, run.TenantID, run.Task, run.Status, triggerType, run.StartedAt)
+	if err != nil {
+		return fmt.Errorf("upsert swarm run: %w", err)
+	}
+	return nil
+}
+
+// Run returns the tenant's stored run without its subtasks.
+func (s *RunStore) Run(ctx context.Context, tenantID, runID string) (*SwarmRun, bool, error) {
+	run := &SwarmRun{RunID: runID, TenantID: tenantID}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT task, status, trigger_type, started_at
+		FROM swarm_runs
+		WHERE tenant_id = $1 AND run_id = $2
+	`, tenantID, runID).Scan(&run.Task, &run.Status, &run.TriggerType, &run.StartedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("load swarm run: %w", err)
+	}
+	return run, true, nil
+}
+
+// SetRunRecorder makes runs persist their status as it changes.
+func (h *Handler) SetRunRecorder(r RunRecorder) {
+	h.runRecorder = r
+}
+
+// recordRun stores run, a snapshot from cloneRun, logging failures so a
+// database outage does not hold up the run.
+func (h *Handler) recordRun(run *SwarmRun) {
+	if h.runRecorder == nil || run == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.runRecorder.RecordRun(ctx, run); err != nil {
+		slog.Warn("failed to record swarm run", "tenant", run.TenantID, "run", run.RunID, "err", err)
+	}
+}
@@ -0,0 +1,83 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type recordingRuns struct {
+	mu       sync.Mutex
+	statuses []string
+}
+
+func (r *recordingRuns) RecordRun(_ context.Context, run *SwarmRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses = append(r.statuses, run.Status)
+	return nil
+}
+
+func (r *recordingRuns) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.statuses...)
+}
+
+func TestStartRunRecordsStatusChanges(t *testing.T) {
+	t.Parallel()
+	rec := &recordingRuns{}
+	h := NewHandler(nil)
+	h.SetRunRecorder(rec)
+	h.spawnAgent = func(*SubTask, *ChannelContext) error { return errors.New("no workers in tests") }
+	if _, err := h.StartRun(context.Background(), "t1", RunRequest{Task: "research the market"}); err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(rec.recorded()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	got := rec.recorded()
+	if len(got) != 2 || got[0] != "running" || got[1] == "running" {
+		t.Fatalf("recorded statuses = %v", got)
+	}
+}
+
+func TestRunStore(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	store := NewRunStore(db)
+
+	started := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectExec("INSERT INTO swarm_runs").
+		WithArgs("r1", "t1", "research", "complete", "manual", started).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	if err := store.RecordRun(context.Background(), &SwarmRun{RunID: "r1", TenantID: "t1", Task: "research", Status: "complete", StartedAt: started}); err != nil {
+		t.Fatalf("RecordRun: %v", err)
+	}
+
+	mock.ExpectQuery("SELECT task, status, trigger_type, started_at FROM swarm_runs").WithArgs("t1", "r1").
+		WillReturnRows(sqlmock.NewRows([]string{"task", "status", "trigger_type", "started_at"}).AddRow("research", "complete", "manual", started))
+	run, ok, err := store.Run(context.Background(), "t1", "r1")
+	if err != nil || !ok || run.Status != "complete" || run.RunID != "r1" || !run.StartedAt.Equal(started) {
+		t.Fatalf("Run = %+v %v %v", run, ok, err)
+	}
+
+	mock.ExpectQuery("SELECT task, status, trigger_type, started_at FROM swarm_runs").WithArgs("t1", "gone").
+		WillReturnRows(sqlmock.NewRows([]string{"task", "status", "trigger_type", "started_at"}))
+	if _, ok, err := store.Run(context.Background(), "t1", "gone"); ok || err != nil {
+		t.Fatalf("missing run = %v %v", ok, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package coordinator
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const maxSubTaskSpecs = 20
+
+var subTaskSpecIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// SubTaskSpec is a user-defined subtask that replaces automatic decomposition.
+type SubTaskSpec struct {
+	ID        string   `json:"id"`
+	Agent     string   `json:"agent,omitempty"`
+	Task      string   `json:"task"`
+	DependsOn []string `json:"depends_on,omitempty"`
+}
+
+// SubTasksFromSpec validates a subtask spec and maps it to runnable subtasks in
+// dependency order. Spec IDs are namespaced with runID so workspaces and tmux
+// sessions never collide across runs.
+func SubTasksFromSpec(runID string, spec []SubTaskSpec) ([]SubTask, error) {
+	if len(spec) == 0 {
+		return nil, errors.New("subtask_spec is empty")
+	}
+	if len(spec) > maxSubTaskSpecs {
+		return nil, fmt.Errorf("subtask_spec has more than %d entries", maxSubTaskSpecs)
+	}
+
+	byID := make(map[string]SubTaskSpec, len(spec))
+	for _, s := range spec {
+		s.ID = strings.TrimSpace(s.ID)
+		if !subTaskSpecIDPattern.MatchString(s.ID) {
+			return nil, fmt.Errorf("subtask id %q must be 1-32 letters, digits, '-' or '_'", s.ID)
+		}
+		if _, dup := byID[s.ID]; dup {
+			return nil, fmt.Errorf("duplicate subtask id %q", s.ID)
+		}
+		if strings.TrimSpace(s.Task) == "" {
+			return nil, fmt.Errorf("subtask %q is missing task", s.ID)
+		}
+		byID[s.ID] = s
+	}
+	for _, s := range spec {
+		for _, dep := range s.DependsOn {
+			if _, ok := byID[strings.TrimSpace(dep)]; !ok {
+				return nil, fmt.Errorf("subtask %q depends on unknown id %q", strings.TrimSpace(s.ID), dep)
+			}
+		}
+	}
+
+	ordered, err := topoSortSpec(spec, byID)
+	if err != nil {
+		return nil, err
+	}
+
+	subtasks := make([]SubTask, 0, len(ordered))
+	for i, s := range ordered {
+		deps := make([]string, 0, len(s.DependsOn))
+		for _, dep := range s.DependsOn {
+			deps = append(deps, specSubTaskID(runID, strings.TrimSpace(dep)))
+		}
+		subtasks = append(subtasks, SubTask{
+			ID:           specSubTaskID(runID, s.ID),
+			Brief:        strings.TrimSpace(s.Task),
+			AssignedHand: resolveSpecHand(s.Agent, i),
+			Status:       "pending",
+			DependsOn:    deps,
+		})
+	}
+	return subtasks, nil
+}
+
+// topoSortSpec orders specs so dependencies come first, preserving input order
+// where possible, and rejects cycles.
+func topoSortSpec(spec []SubTaskSpec, byID map[string]SubTaskSpec) ([]SubTaskSpec, error) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(spec))
+	ordered := make([]SubTaskSpec, 0, len(spec))
+
+	var visit func(id string, path []string) error
+	visit = func(id string, path []string) error {
+		switch state[id] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("subtask_spec has a dependency cycle: %s", strings.Join(append(path, id), " -> "))
+		}
+		state[id] = visiting
+		for _, dep := range byID[id].DependsOn {
+			if err := visit(strings.TrimSpace(dep), append(path, id)); err != nil {
+				return err
+			}
+		}
+		state[id] = done
+		ordered = append(ordered, byID[id])
+		return nil
+	}
+
+	for _, s := range spec {
+		if err := visit(strings.TrimSpace(s.ID), nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+func specSubTaskID(runID, specID string) string {
+	return fmt.Sprintf("sub-%s-%s", runID, specID)
+}
+
+// resolveSpecHand maps short agent names such as "research" onto the default
+// hand names; unknown agents are passed through unchanged.
+func resolveSpecHand(agent string, index int) string {
+	agent = strings.TrimSpace(agent)
+	if agent == "" {
+		return defaultHands[index%len(defaultHands)]
+	}
+	for _, hand := range defaultHands {
+		if strings.EqualFold(hand, agent) || strings.EqualFold(hand, agent+" Hand") {
+			return hand
+		}
+	}
+	return agent
+}
@@ -0,0 +1,168 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/agentsquads/api/channels"
+	"github.com/agentsquads/api/policies"
+)
+
+func TestSubTasksFromSpec(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		spec    []SubTaskSpec
+		wantIDs []string
+		wantErr string
+	}{
+		{
+			name: "orders dependencies first",
+			spec: []SubTaskSpec{
+				{ID: "write", Agent: "execution", Task: "write it", DependsOn: []string{"research"}},
+				{ID: "research", Agent: "research", Task: "look it up"},
+			},
+			wantIDs: []string{"sub-r1-research", "sub-r1-write"},
+		},
+		{
+			name:    "duplicate ids",
+			spec:    []SubTaskSpec{{ID: "a", Task: "x"}, {ID: "a", Task: "y"}},
+			wantErr: "duplicate",
+		},
+		{
+			name:    "unknown dependency",
+			spec:    []SubTaskSpec{{ID: "a", Task: "x", DependsOn: []string{"b"}}},
+			wantErr: "unknown id",
+		},
+		{
+			name: "cycle",
+			spec: []SubTaskSpec{
+				{ID: "a", Task: "x", DependsOn: []string{"b"}},
+				{ID: "b", Task: "y", DependsOn: []string{"a"}},
+			},
+			wantErr: "cycle",
+		},
+		{
+			name:    "unsafe id",
+			spec:    []SubTaskSpec{{ID: "../etc", Task: "x"}},
+			wantErr: "must be",
+		},
+		{
+			name:    "missing task",
+			spec:    []SubTaskSpec{{ID: "a"}},
+			wantErr: "missing task",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := SubTasksFromSpec("r1", tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("got %d subtasks, want %d", len(got), len(tt.wantIDs))
+			}
+			for i, id := range tt.wantIDs {
+				if got[i].ID != id {
+					t.Fatalf("subtask[%d].ID = %q, want %q", i, got[i].ID, id)
+				}
+			}
+			if got[0].AssignedHand != "Research Hand" || got[1].DependsOn[0] != "sub-r1-research" {
+				t.Fatalf("unexpected mapping: %+v", got)
+			}
+		})
+	}
+}
+
+func TestDependencyState(t *testing.T) {
+	t.Parallel()
+	byID := map[string]*SubTask{
+		"a": {ID: "a", Status: "complete"},
+		"b": {ID: "b", Status: "running"},
+		"c": {ID: "c", Status: "timeout"},
+	}
+	tests := []struct {
+		deps        []string
+		ready, blkd bool
+	}{
+		{deps: nil, ready: true},
+		{deps: []string{"a"}, ready: true},
+		{deps: []string{"a", "b"}},
+		{deps: []string{"c"}, blkd: true},
+	}
+	for _, tt := range tests {
+		ready, blocked := dependencyState(&SubTask{DependsOn: tt.deps}, byID)
+		if ready != tt.ready || blocked != tt.blkd {
+			t.Fatalf("deps %v: ready=%v blocked=%v", tt.deps, ready, blocked)
+		}
+	}
+}
+
+type stubPolicies struct {
+	enabled   bool
+	err       error
+	overrides map[string]bool
+}
+
+func (s stubPolicies) FeatureEnabled(_ context.Context, _ string, feature string) (bool, error) {
+	if v, ok := s.overrides[feature]; ok {
+		return v, nil
+	}
+	return s.enabled, s.err
+}
+
+func TestStartRunSubTaskSpecRequiresPolicy(t *testing.T) {
+	t.Parallel()
+	req := RunRequest{Task: "custom", SubTaskSpec: []SubTaskSpec{{ID: "a", Task: "x"}}}
+
+	h := NewHandler(nil)
+	if _, err := h.StartRun(context.Background(), "t1", req); !errors.Is(err, ErrSubTaskSpecForbidden) {
+		t.Fatalf("without checker err = %v", err)
+	}
+	if got := startRunErrorStatus(ErrSubTaskSpecForbidden); got != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403", got)
+	}
+
+	h.SetPolicyChecker(stubPolicies{enabled: false, overrides: map[string]bool{policies.FeatureSwarm: true}})
+	if _, err := h.StartRun(context.Background(), "t1", req); !errors.Is(err, ErrSubTaskSpecForbidden) {
+		t.Fatalf("disabled policy err = %v", err)
+	}
+
+	h.SetPolicyChecker(stubPolicies{err: errors.New("db down")})
+	_, err := h.StartRun(context.Background(), "t1", req)
+	if err == nil || startRunErrorStatus(err) != http.StatusInternalServerError {
+		t.Fatalf("policy error = %v", err)
+	}
+}
+
+func TestStartRunSwarmPolicy(t *testing.T) {
+	t.Parallel()
+	h := NewHandler(nil)
+	h.SetPolicyChecker(stubPolicies{enabled: true, overrides: map[string]bool{policies.FeatureSwarm: false}})
+
+	_, err := h.StartRun(context.Background(), "t1", RunRequest{Task: "build it"})
+	if !errors.Is(err, ErrSwarmDisabled) {
+		t.Fatalf("err = %v, want ErrSwarmDisabled", err)
+	}
+	if got := startRunErrorStatus(err); got != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403", got)
+	}
+
+	bridge := NewBridge(h)
+	res, err := bridge.HandleChannelMessage(context.Background(), channels.AgentTaskRequest{TenantID: "t1", Content: "/agent run build it", Channel: "web"})
+	if err != nil || !res.Accepted || res.RunID != "" || !strings.Contains(res.Ack, "disabled") {
+		t.Fatalf("bridge result = %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,182 @@
+package coordinator
+
+import (
+	"encoding/json"
+	"expvar"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sseStats counts task event stream backpressure: events dropped because a
+// subscriber's buffer was full, resync events sent in their place, and
+// subscribers closed for staying saturated. Published through expvar at
+// /debug/vars.
+var sseStats = expvar.NewMap("swarm_sse")
+
+// sseLimits bounds how far an event stream subscriber may fall behind.
+type sseLimits struct {
+	// buffer is how many events a subscriber can have unread.
+	buffer int
+	// slowAfter is how long a subscriber's buffer may stay full before the
+	// subscriber is closed.
+	slowAfter time.Duration
+}
+
+// loadSSELimitsFromEnv reads SWARM_SSE_BUFFER and SWARM_SSE_SLOW_TIMEOUT,
+// defaulting to 32 events and 30 seconds.
+func loadSSELimitsFromEnv() sseLimits {
+	limits := sseLimits{buffer: 32, slowAfter: 30 * time.Second}
+	if v := strings.TrimSpace(os.Getenv("SWARM_SSE_BUFFER")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limits.buffer = n
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("SWARM_SSE_SLOW_TIMEOUT")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			limits.slowAfter = d
+		}
+	}
+	return limits
+}
+
+// SetSSELimits sets how many events each task event stream subscriber can
+// have unread and how long its buffer may stay full before it is closed with
+// a final too_slow event. Zero values keep the current limits. It applies to
+// subscribers that connect afterwards.
+func (h *Handler) SetSSELimits(buffer int, slowAfter time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sse.buffer = clampPositive(buffer, h.sse.buffer)
+	h.sse.slowAfter = clampDuration(slowAfter, h.sse.slowAfter)
+}
+
+// sseSubscriber is one client of a task's event stream.
+//
+// Events are never blocked on: one that does not fit in ch is dropped and
+// counted, and the next event that fits is sent as a resync carrying the
+// latest full run so the client can refetch its state instead of applying an
+// update on top of the ones it missed. A subscriber whose buffer stays full
+// for longer than slowAfter is closed: tooSlow is closed and the stream
+// handler ends the stream with a too_slow event.
+type sseSubscriber struct {
+	ch        chan []byte
+	tooSlow   chan struct{}
+	slowAfter time.Duration
+
+	mu             sync.Mutex
+	dropped        int       // events dropped over the subscription
+	resync         bool      // an event was dropped since the last one sent
+	saturatedSince time.Time // when the buffer was first found full
+	closed         bool
+}
+
+func (s *sseSubscriber) droppedEvents() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// offer queues an event for the subscriber without blocking. run is the full
+// run state the event carries, used to build a resync event after drops.
+func (s *sseSubscriber) offer(taskID string, msg []byte, run *SwarmRun, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	resync := s.resync
+	if resync {
+		msg = sseMessage("resync", map[string]any{"event": "resync", "dropped": s.dropped, "task": run})
+	}
+	select {
+	case s.ch <- msg:
+		s.resync = false
+		s.saturatedSince = time.Time{}
+		if resync {
+			sseStats.Add("resyncs", 1)
+		}
+		return
+	default:
+	}
+
+	s.dropped++
+	s.resync = true
+	sseStats.Add("dropped", 1)
+	if s.saturatedSince.IsZero() {
+		s.saturatedSince = now
+		return
+	}
+	if now.Sub(s.saturatedSince) > s.slowAfter {
+		s.closed = true
+		close(s.tooSlow)
+		sseStats.Add("too_slow", 1)
+		slog.Warn("closing slow swarm event subscriber", "task", taskID, "dropped", s.dropped)
+	}
+}
+
+// writeSSEPayload streams event, carrying run, to the task's subscribers.
+func (h *Handler) writeSSEPayload(taskID, event string, run *SwarmRun) {
+	msg := sseMessage(event, map[string]any{"event": event, "task": run})
+	if msg == nil {
+		return
+	}
+
+	h.mu.RLock()
+	subscribers := h.subscribers[taskID]
+	subs := make([]*sseSubscriber, 0, len(subscribers))
+	for sub := range subscribers {
+		subs = append(subs, sub)
+	}
+	h.mu.RUnlock()
+
+	now := time.Now()
+	for _, sub := range subs {
+		sub.offer(taskID, msg, run, now)
+	}
+}
+
+func (h *Handler) subscribe(taskID string) (*sseSubscriber, func()) {
+	h.mu.Lock()
+	sub := &sseSubscriber{
+		ch:        make(chan []byte, h.sse.buffer),
+		tooSlow:   make(chan struct{}),
+		slowAfter: h.sse.slowAfter,
+	}
+	if h.subscribers[taskID] == nil {
+		h.subscribers[taskID] = make(map[*sseSubscriber]struct{})
+	}
+	h.subscribers[taskID][sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub, func() {
+		h.mu.Lock()
+		subs := h.subscribers[taskID]
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(h.subscribers, taskID)
+		}
+		h.mu.Unlock()
+
+		// A writer may still hold sub from before it was removed; closed
+		// keeps it from queueing for a stream nobody reads any more.
+		sub.mu.Lock()
+		sub.closed = true
+		sub.mu.Unlock()
+	}
+}
+
+// sseMessage formats data as a server-sent event, or returns nil when data
+// cannot be encoded.
+func sseMessage(event string, data map[string]any) []byte {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	msg := append([]byte("event: "+event+"\n"), []byte("data: ")...)
+	msg = append(msg, payload...)
+	return append(msg, []byte("\n\n")...)
+}
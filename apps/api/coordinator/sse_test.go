@@ -0,0 +1,78 @@
+package coordinator
+
+import (
+	"expvar"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sseCount(name string) int64 {
+	if v, ok := sseStats.Get(name).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestSSESubscriberBackpressure(t *testing.T) {
+	h := NewHandler(nil)
+	h.SetSSELimits(2, time.Hour)
+	run := &SwarmRun{RunID: "r1", TenantID: "t1", Status: "running"}
+	droppedBefore, resyncsBefore, slowBefore := sseCount("dropped"), sseCount("resyncs"), sseCount("too_slow")
+
+	// Nothing reads sub.ch until the buffer has overflowed.
+	sub, unsubscribe := h.subscribe("r1")
+	defer unsubscribe()
+	for i := 0; i < 3; i++ {
+		h.writeSSEPayload("r1", "update", run)
+	}
+	if sub.droppedEvents() != 1 || sseCount("dropped") != droppedBefore+1 {
+		t.Fatalf("dropped = %d, metric +%d", sub.droppedEvents(), sseCount("dropped")-droppedBefore)
+	}
+
+	<-sub.ch
+	run.Status = "complete"
+	h.writeSSEPayload("r1", "update", run)
+	if first := string(<-sub.ch); !strings.HasPrefix(first, "event: update\n") {
+		t.Fatalf("buffered event = %q", first)
+	}
+	resync := string(<-sub.ch)
+	if !strings.HasPrefix(resync, "event: resync\n") || !strings.Contains(resync, `"dropped":1`) || !strings.Contains(resync, `"status":"complete"`) {
+		t.Fatalf("event after drops = %q", resync)
+	}
+	if sseCount("resyncs") != resyncsBefore+1 {
+		t.Fatalf("resync metric +%d", sseCount("resyncs")-resyncsBefore)
+	}
+
+	// Back in step, events are sent as they are.
+	h.writeSSEPayload("r1", "update", run)
+	if next := string(<-sub.ch); !strings.HasPrefix(next, "event: update\n") {
+		t.Fatalf("event after resync = %q", next)
+	}
+
+	// A subscriber whose buffer stays full past slowAfter is closed.
+	sub.slowAfter = 10 * time.Millisecond
+	for i := 0; i < 3; i++ {
+		h.writeSSEPayload("r1", "update", run)
+	}
+	select {
+	case <-sub.tooSlow:
+		t.Fatalf("closed before slowAfter")
+	default:
+	}
+	time.Sleep(20 * time.Millisecond)
+	h.writeSSEPayload("r1", "update", run)
+	select {
+	case <-sub.tooSlow:
+	default:
+		t.Fatalf("saturated subscriber was not closed")
+	}
+	if sseCount("too_slow") != slowBefore+1 || sub.droppedEvents() != 3 {
+		t.Fatalf("too_slow metric +%d dropped=%d", sseCount("too_slow")-slowBefore, sub.droppedEvents())
+	}
+	// Nothing more is queued for a closed subscriber.
+	h.writeSSEPayload("r1", "update", run)
+	if sub.droppedEvents() != 3 {
+		t.Fatalf("closed subscriber still counting: %d", sub.droppedEvents())
+	}
+}
@@ -29,7 +29,7 @@ func (c *Coordinator) SpawnAgent(subtask *SubTask, channelCtx *ChannelContext) e
 		return err
 	}
 
-	sessionName := fmt.Sprintf("agent-%s", subtask.ID)
+	sessionName := agentSessionName(subtask.ID)
 	subtask.TmuxSession = sessionName
 	subtask.Status = "running"
 	subtask.StartedAt = time.Now()
@@ -50,6 +50,18 @@ func (c *Coordinator) SpawnAgent(subtask *SubTask, channelCtx *ChannelContext) e
 	return nil
 }
 
+func (c *Coordinator) spawnAgent(subtask *SubTask, channelCtx *ChannelContext) error {
+	if c.spawn != nil {
+		return c.spawn(subtask, channelCtx)
+	}
+	return c.SpawnAgent(subtask, channelCtx)
+}
+
+// agentSessionName is the tmux session a subtask's worker runs in.
+func agentSessionName(subTaskID string) string {
+	return fmt.Sprintf("agent-%s", subTaskID)
+}
+
 func writeChannelContextFile(dir string, ctx *ChannelContext) error {
 	if ctx == nil {
 		return nil
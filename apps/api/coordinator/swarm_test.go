@@ -3,7 +3,10 @@ package coordinator
 import (
 	"errors"
 	"os"
+	"strings"
 	"testing"
+
+	"github.com/agentsquads/api/media"
 )
 
 func TestCollectOutput(t *testing.T) {
@@ -40,3 +43,17 @@ func TestBridgeHelpers(t *testing.T) {
 		t.Fatalf("expected heuristic match")
 	}
 }
+
+func TestWithAttachedFiles(t *testing.T) {
+	t.Parallel()
+	pdf := media.Ref{ID: "m1", Kind: "document", FileName: "report.pdf", ContentType: "application/pdf", Size: 4096}
+	photo := media.Ref{ID: "m2", Kind: "photo", FileName: "photo.jpg", ContentType: "image/jpeg", Size: 100}
+
+	if got := withAttachedFiles("summarize", nil); got != "summarize" {
+		t.Fatalf("no files: %q", got)
+	}
+	got := withAttachedFiles("summarize this\n\n[attached "+pdf.Describe()+"]", []media.Ref{pdf, photo})
+	if strings.Count(got, "media_id=m1") != 1 || !strings.HasSuffix(got, "Files attached to the request:\n- "+photo.Describe()) {
+		t.Fatalf("task = %q", got)
+	}
+}
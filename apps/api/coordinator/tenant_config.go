@@ -0,0 +1,202 @@
+package coordinator
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/prompts"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	tenantSwarmConfigCacheTTL = 60 * time.Second
+
+	minTenantMaxAgents      = 1
+	maxTenantMaxAgents      = 20
+	minTenantTimeoutSeconds = 30
+	maxTenantTimeoutSeconds = 3600
+)
+
+var (
+	// ErrInvalidSwarmConfig is returned when a tenant override is out of range.
+	ErrInvalidSwarmConfig = errors.New("invalid swarm config")
+	// ErrTenantNotFound is returned when saving a config for an unknown tenant.
+	ErrTenantNotFound = errors.New("tenant not found")
+)
+
+// TenantSwarmConfig holds per-tenant swarm overrides. Zero values mean "not
+// overridden" and the platform defaults apply.
+type TenantSwarmConfig struct {
+	TenantID              string    `json:"tenant_id"`
+	MaxAgents             int       `json:"max_agents,omitempty"`
+	DefaultTimeoutSeconds int       `json:"default_timeout_seconds,omitempty"`
+	DecompositionPrompt   string    `json:"decomposition_prompt,omitempty"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// Validate checks the overrides that are set against the allowed ranges.
+func (c TenantSwarmConfig) Validate() error {
+	if c.MaxAgents != 0 && (c.MaxAgents < minTenantMaxAgents || c.MaxAgents > maxTenantMaxAgents) {
+		return fmt.Errorf("%w: max_agents must be between %d and %d", ErrInvalidSwarmConfig, minTenantMaxAgents, maxTenantMaxAgents)
+	}
+	if c.DefaultTimeoutSeconds != 0 && (c.DefaultTimeoutSeconds < minTenantTimeoutSeconds || c.DefaultTimeoutSeconds > maxTenantTimeoutSeconds) {
+		return fmt.Errorf("%w: default_timeout_seconds must be between %d and %d", ErrInvalidSwarmConfig, minTenantTimeoutSeconds, maxTenantTimeoutSeconds)
+	}
+	if err := prompts.ValidateDecomposition(c.DecompositionPrompt); err != nil {
+		return fmt.Errorf("%w: decomposition_prompt: %v", ErrInvalidSwarmConfig, err)
+	}
+	return nil
+}
+
+// TenantConfigSource looks up a tenant's swarm overrides. It returns nil when
+// the tenant has none.
+type TenantConfigSource interface {
+	TenantSwarmConfig(ctx context.Context, tenantID string) (*TenantSwarmConfig, error)
+}
+
+// TenantConfigStore reads and writes tenant_swarm_configs. Reads are cached
+// in Redis under swarm_config:{tenantID} so runs do not hit Postgres each
+// time; a nil Redis client disables caching.
+type TenantConfigStore struct {
+	db    *sql.DB
+	redis *redis.Client
+	ttl   time.Duration
+	log   *slog.Logger
+}
+
+func NewTenantConfigStore(db *sql.DB, redisClient *redis.Client) *TenantConfigStore {
+	return &TenantConfigStore{
+		db:    db,
+		redis: redisClient,
+		ttl:   tenantSwarmConfigCacheTTL,
+		log:   slog.Default().With("component", "swarm-config"),
+	}
+}
+
+func tenantSwarmConfigCacheKey(tenantID string) string {
+	return "swarm_config:" + tenantID
+}
+
+// TenantSwarmConfig returns the tenant's overrides, or nil when it has none.
+// Tenants without a row are cached too so they do not query on every run.
+func (s *TenantConfigStore) TenantSwarmConfig(ctx context.Context, tenantID string) (*TenantSwarmConfig, error) {
+	tenantID = strings.TrimSpace(tenantID)
+	if s.redis != nil {
+		cached, err := s.redis.Get(ctx, tenantSwarmConfigCacheKey(tenantID)).Bytes()
+		switch {
+		case err == nil:
+			var cfg *TenantSwarmConfig
+			if jerr := json.Unmarshal(cached, &cfg); jerr == nil {
+				return cfg, nil
+			}
+		case !errors.Is(err, redis.Nil):
+			s.log.Warn("swarm config cache read failed", "tenant", tenantID, "err", err)
+		}
+	}
+
+	cfg, err := s.load(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if s.redis != nil {
+		encoded, _ := json.Marshal(cfg)
+		if err := s.redis.Set(ctx, tenantSwarmConfigCacheKey(tenantID), encoded, s.ttl).Err(); err != nil {
+			s.log.Warn("swarm config cache write failed", "tenant", tenantID, "err", err)
+		}
+	}
+	return cfg, nil
+}
+
+func (s *TenantConfigStore) load(ctx context.Context, tenantID string) (*TenantSwarmConfig, error) {
+	var (
+		cfg       = TenantSwarmConfig{TenantID: tenantID}
+		maxAgents sql.NullInt64
+		timeout   sql.NullInt64
+		prompt    sql.NullString
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT max_agents, default_timeout_seconds, decomposition_prompt, updated_at
+		FROM tenant_swarm_configs
+		WHERE tenant_id = $1
+	`, tenantID).Scan(&maxAgents, &timeout, &prompt, &cfg.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load tenant swarm config: %w", err)
+	}
+	cfg.MaxAgents = int(maxAgents.Int64)
+	cfg.DefaultTimeoutSeconds = int(timeout.Int64)
+	cfg.DecompositionPrompt = strings.TrimSpace(prompt.String)
+	return &cfg, nil
+}
+
+// Put replaces the tenant's overrides and drops the cached copy.
+func (s *TenantConfigStore) Put(ctx context.Context, cfg TenantSwarmConfig) (*TenantSwarmConfig, error) {
+	cfg.TenantID = strings.TrimSpace(cfg.TenantID)
+	cfg.DecompositionPrompt = strings.TrimSpace(cfg.DecompositionPrompt)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO tenant_swarm_configs (tenant_id, max_agents, default_timeout_seconds, decomposition_prompt, updated_at)
+		SELECT t.id, NULLIF($2, 0), NULLIF($3, 0), NULLIF($4, ''), NOW()
+		FROM tenants t
+		WHERE t.id = $1
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET max_agents = EXCLUDED.max_agents,
+		    default_timeout_seconds = EXCLUDED.default_timeout_seconds,
+		    decomposition_prompt = EXCLUDED.decomposition_prompt,
+		    updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`, cfg.TenantID, cfg.MaxAgents, cfg.DefaultTimeoutSeconds, cfg.DecompositionPrompt).Scan(&cfg.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTenantNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("save tenant swarm config: %w", err)
+	}
+
+	if s.redis != nil {
+		if err := s.redis.Del(ctx, tenantSwarmConfigCacheKey(cfg.TenantID)).Err(); err != nil {
+			s.log.Warn("swarm config cache invalidation failed", "tenant", cfg.TenantID, "err", err)
+		}
+	}
+	return &cfg, nil
+}
+
+// SetTenantConfigSource makes swarm limits and the decomposition prompt honour
+// per-tenant overrides.
+func (h *Handler) SetTenantConfigSource(src TenantConfigSource) {
+	h.tenantConfigs = src
+}
+
+// tenantSwarmConfig returns the tenant's overrides, or nil when there are none
+// or they cannot be loaded.
+func (h *Handler) tenantSwarmConfig(ctx context.Context, tenantID string) *TenantSwarmConfig {
+	if h.tenantConfigs == nil {
+		return nil
+	}
+	cfg, err := h.tenantConfigs.TenantSwarmConfig(ctx, tenantID)
+	if err != nil {
+		slog.Warn("failed to load tenant swarm config", "tenant", tenantID, "err", err)
+		return nil
+	}
+	return cfg
+}
+
+// timeoutForTenant returns the tenant's subtask timeout override or the
+// configured default.
+func (h *Handler) timeoutForTenant(ctx context.Context, tenantID string) time.Duration {
+	if cfg := h.tenantSwarmConfig(ctx, tenantID); cfg != nil && cfg.DefaultTimeoutSeconds > 0 {
+		return time.Duration(cfg.DefaultTimeoutSeconds) * time.Second
+	}
+	return h.swarmConfig().DefaultTimeout
+}
@@ -0,0 +1,104 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type stubTenantConfigs map[string]*TenantSwarmConfig
+
+func (s stubTenantConfigs) TenantSwarmConfig(_ context.Context, tenantID string) (*TenantSwarmConfig, error) {
+	if tenantID == "broken" {
+		return nil, errors.New("db down")
+	}
+	return s[tenantID], nil
+}
+
+func TestTenantSwarmConfigOverrides(t *testing.T) {
+	t.Parallel()
+	h := NewHandler(nil)
+	h.cfg.DefaultMaxAgents = 4
+	h.cfg.DefaultTimeout = 5 * time.Minute
+	h.cfg.DecompositionPromptTemplate = "env {{task}}"
+	h.SetTenantConfigSource(stubTenantConfigs{
+		"t1": {TenantID: "t1", MaxAgents: 12, DefaultTimeoutSeconds: 90, DecompositionPrompt: "tenant {{task}}"},
+		"t2": {TenantID: "t2", MaxAgents: 2},
+	})
+	ctx := context.Background()
+
+	if got := h.maxAgentsForTenant(ctx, "t1"); got != 12 {
+		t.Fatalf("t1 max agents = %d", got)
+	}
+	if got := h.timeoutForTenant(ctx, "t1"); got != 90*time.Second {
+		t.Fatalf("t1 timeout = %v", got)
+	}
+	if got, refs := h.decompositionPrompt(ctx, "t1"); got != "tenant {{task}}" || refs != nil {
+		t.Fatalf("t1 prompt = %q %v", got, refs)
+	}
+
+	if got := h.timeoutForTenant(ctx, "t2"); got != 5*time.Minute {
+		t.Fatalf("t2 timeout = %v", got)
+	}
+	if got, _ := h.decompositionPrompt(ctx, "t2"); got != "env {{task}}" {
+		t.Fatalf("t2 prompt = %q", got)
+	}
+	for _, tenantID := range []string{"t3", "broken"} {
+		if got := h.maxAgentsForTenant(ctx, tenantID); got != 4 {
+			t.Fatalf("%s max agents = %d", tenantID, got)
+		}
+	}
+}
+
+func TestTenantSwarmConfigValidate(t *testing.T) {
+	t.Parallel()
+	valid := []TenantSwarmConfig{{}, {MaxAgents: 1, DefaultTimeoutSeconds: 30}, {MaxAgents: 20, DefaultTimeoutSeconds: 3600}}
+	for _, cfg := range valid {
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("%+v: %v", cfg, err)
+		}
+	}
+	invalid := []TenantSwarmConfig{{MaxAgents: -1}, {MaxAgents: 21}, {DefaultTimeoutSeconds: 29}, {DefaultTimeoutSeconds: 3601}}
+	for _, cfg := range invalid {
+		if err := cfg.Validate(); !errors.Is(err, ErrInvalidSwarmConfig) {
+			t.Fatalf("%+v: err = %v", cfg, err)
+		}
+	}
+}
+
+func TestTenantConfigStore(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	store := NewTenantConfigStore(db, nil)
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT max_agents, default_timeout_seconds, decomposition_prompt").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"max_agents", "default_timeout_seconds", "decomposition_prompt", "updated_at"}).AddRow(8, nil, " split it ", time.Now()))
+	cfg, err := store.TenantSwarmConfig(ctx, "t1")
+	if err != nil || cfg == nil || cfg.MaxAgents != 8 || cfg.DefaultTimeoutSeconds != 0 || cfg.DecompositionPrompt != "split it" {
+		t.Fatalf("config = %+v err=%v", cfg, err)
+	}
+
+	mock.ExpectQuery("SELECT max_agents, default_timeout_seconds, decomposition_prompt").WithArgs("t2").WillReturnRows(sqlmock.NewRows(nil))
+	if cfg, err := store.TenantSwarmConfig(ctx, "t2"); err != nil || cfg != nil {
+		t.Fatalf("missing config = %+v err=%v", cfg, err)
+	}
+
+	if _, err := store.Put(ctx, TenantSwarmConfig{TenantID: "t1", MaxAgents: 50}); !errors.Is(err, ErrInvalidSwarmConfig) {
+		t.Fatalf("out of range put err = %v", err)
+	}
+	mock.ExpectQuery("INSERT INTO tenant_swarm_configs").WithArgs("missing", 3, 0, "").WillReturnRows(sqlmock.NewRows([]string{"updated_at"}))
+	if _, err := store.Put(ctx, TenantSwarmConfig{TenantID: "missing", MaxAgents: 3}); !errors.Is(err, ErrTenantNotFound) {
+		t.Fatalf("unknown tenant put err = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package coordinator
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// RunMessage is a swarm run result written to the conversation the run came
+// from.
+type RunMessage struct {
+	TenantID       string
+	ConversationID string
+	Channel        string
+	RunID          string
+	Event          string // complete, failed, cancelled
+	SubTaskIDs     []string
+	Content        string
+}
+
+// dedupeKey identifies the message so a republished result is not stored
+// twice, while a retried run that produces a new result still is.
+func (m RunMessage) dedupeKey() string {
+	sum := sha256.Sum256([]byte(m.RunID + "\x00" + m.Event + "\x00" + m.Content))
+	return hex.EncodeToString(sum[:16])
+}
+
+// TranscriptWriter stores swarm run results as assistant messages.
+type TranscriptWriter interface {
+	SaveRunMessage(ctx context.Context, msg RunMessage) error
+}
+
+// TranscriptStore writes run results into messages. The unique index on
+// metadata->>'swarm_message_key' drops duplicates of the same result.
+type TranscriptStore struct {
+	db *sql.DB
+}
+
+func NewTranscriptStore(db *sql.DB) *TranscriptStore {
+	return &TranscriptStore{db: db}
+}
+
+// SaveRunMessage inserts msg on its conversation. Conversations that do not
+// belong to the tenant are ignored.
+func (s *TranscriptStore) SaveRunMessage(ctx context.Context, msg RunMessage) error {
+	subtasks := msg.SubTaskIDs
+	if subtasks == nil {
+		subtasks = []string{}
+	}
+	metadata, err := json.Marshal(map[string]any{
+		"source":            "swarm",
+		"run_id":            msg.RunID,
+		"event":             msg.Event,
+		"subtask_ids":       subtasks,
+		"swarm_message_key": msg.dedupeKey(),
+	})
+	if err != nil {
+		return fmt.Errorf("marshal metadata: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO messages (conversation_id, role, content, channel, metadata)
+		SELECT c.id, 'assistant', $3, $4, $5::jsonb
+		FROM conversations c
+		WHERE c.id = $1 AND c.tenant_id = $2
+		ON CONFLICT (conversation_id, (metadata->>'swarm_message_key'))
+		  WHERE metadata ? 'swarm_message_key'
+		DO NOTHING
+	`, msg.ConversationID, msg.TenantID, msg.Content, msg.Channel, metadata)
+	if err != nil {
+		return fmt.Errorf("insert swarm run message: %w", err)
+	}
+	return nil
+}
+
+// SetTranscriptWriter makes finished runs write their result to the
+// originating conversation.
+func (h *Handler) SetTranscriptWriter(w TranscriptWriter) {
+	h.transcript = w
+}
+
+// saveRunResult stores the final result of run on its conversation: the
+// channel conversation, or the conversation_id given with an API run. It is
+// called before the result is published so the transcript is complete by the
+// time the user sees the reply.
+func (h *Handler) saveRunResult(ctx context.Context, run *SwarmRun, evt RunEvent) {
+	if h.transcript == nil || run == nil {
+		return
+	}
+	conversationID, channel := run.ConversationID, "web"
+	if run.ChannelContext != nil {
+		if conversationID == "" {
+			conversationID = run.ChannelContext.ConversationID
+		}
+		if c := strings.TrimSpace(run.ChannelContext.Channel); c != "" {
+			channel = c
+		}
+	}
+	if conversationID == "" {
+		return
+	}
+
+	subtaskIDs := make([]string, 0, len(run.SubTasks))
+	for _, st := range run.SubTasks {
+		subtaskIDs = append(subtaskIDs, st.ID)
+	}
+	err := h.transcript.SaveRunMessage(ctx, RunMessage{
+		TenantID:       run.TenantID,
+		ConversationID: conversationID,
+		Channel:        channel,
+		RunID:          run.RunID,
+		Event:          evt.Type,
+		SubTaskIDs:     subtaskIDs,
+		Content:        evt.Message,
+	})
+	if err != nil {
+		slog.Error("failed to save swarm run result", "tenant", run.TenantID, "run", run.RunID, "err", err)
+	}
+}
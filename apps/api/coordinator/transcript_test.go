@@ -0,0 +1,73 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type recordingTranscript struct {
+	saved []RunMessage
+}
+
+func (r *recordingTranscript) SaveRunMessage(_ context.Context, msg RunMessage) error {
+	r.saved = append(r.saved, msg)
+	return nil
+}
+
+func TestSaveRunResultConversation(t *testing.T) {
+	t.Parallel()
+	rec := &recordingTranscript{}
+	h := NewHandler(nil)
+	h.SetTranscriptWriter(rec)
+	evt := RunEvent{Type: "complete", Message: "done"}
+
+	h.saveRunResult(context.Background(), &SwarmRun{
+		RunID:          "r1",
+		TenantID:       "t1",
+		ChannelContext: &ChannelContext{Channel: "telegram", ConversationID: "c1"},
+		SubTasks:       []SubTask{{ID: "r1-1"}, {ID: "r1-2"}},
+	}, evt)
+	h.saveRunResult(context.Background(), &SwarmRun{RunID: "r2", TenantID: "t1", ConversationID: "c2"}, evt)
+	h.saveRunResult(context.Background(), &SwarmRun{RunID: "r3", TenantID: "t1"}, evt)
+
+	if len(rec.saved) != 2 {
+		t.Fatalf("saved %d messages, want 2", len(rec.saved))
+	}
+	if got := rec.saved[0]; got.ConversationID != "c1" || got.Channel != "telegram" || len(got.SubTaskIDs) != 2 || got.Content != "done" {
+		t.Fatalf("channel run message = %+v", got)
+	}
+	if got := rec.saved[1]; got.ConversationID != "c2" || got.Channel != "web" {
+		t.Fatalf("api run message = %+v", got)
+	}
+}
+
+func TestTranscriptStoreSaveRunMessage(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	msg := RunMessage{TenantID: "t1", ConversationID: "c1", Channel: "web", RunID: "r1", Event: "complete", Content: "done"}
+	mock.ExpectExec("INSERT INTO messages").
+		WithArgs("c1", "t1", "done", "web", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := NewTranscriptStore(db).SaveRunMessage(context.Background(), msg); err != nil {
+		t.Fatalf("SaveRunMessage: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+
+	retried := msg
+	if retried.dedupeKey() != msg.dedupeKey() {
+		t.Fatalf("same result should share a dedupe key")
+	}
+	retried.Content = "done after retry"
+	if retried.dedupeKey() == msg.dedupeKey() {
+		t.Fatalf("new result should get a new dedupe key")
+	}
+}
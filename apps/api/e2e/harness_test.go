@@ -0,0 +1,524 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentsquads/api/channels"
+	"github.com/agentsquads/api/channels/adapters"
+	"github.com/agentsquads/api/coordinator"
+	"github.com/agentsquads/api/llmproxy"
+	"github.com/agentsquads/api/middleware"
+	"github.com/agentsquads/api/plans"
+	"github.com/agentsquads/api/policies"
+	"github.com/agentsquads/api/routes"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	postgresImage = "postgres:16"
+	redisImage    = "redis:7"
+
+	// DefaultModel is seeded at startup and used by the router and bridge.
+	// It bills a flat FlatRateCents per provider call (see ProviderStub).
+	DefaultModel  = "openai/e2e-flat-rate"
+	FlatRateCents = 10
+
+	adminJWTSecret = "e2e-admin-secret"
+)
+
+// Harness is a running API wired the way main.go wires it, backed by real
+// Postgres and Redis with Telegram, the LLM providers and tenant containers
+// stubbed out.
+type Harness struct {
+	DB       *sql.DB
+	Redis    *redis.Client
+	Policies *policies.Store
+	Orch     *FakeOrchestrator
+	Telegram *TelegramStub
+	Provider *ProviderStub
+	Server   *httptest.Server
+
+	proxy      *llmproxy.Proxy
+	adminToken string
+	cancel     context.CancelFunc
+	closers    []func()
+}
+
+// Start brings up dependencies, applies migrations and serves the API.
+func Start(ctx context.Context) (*Harness, error) {
+	h := &Harness{}
+	if err := h.start(ctx); err != nil {
+		h.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *Harness) start(ctx context.Context) error {
+	baseDSN, redisURL, err := h.startDependencies(ctx)
+	if err != nil {
+		return err
+	}
+
+	dsn, err := h.createDatabase(ctx, baseDSN)
+	if err != nil {
+		return err
+	}
+	h.DB, err = sql.Open("postgres", dsn)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	h.onClose(func() { h.DB.Close() })
+	if err := applyMigrations(ctx, h.DB, migrationsDir()); err != nil {
+		return err
+	}
+	if err := h.insertModel(ctx, DefaultModel, "openai", FlatRateCents*1_000_000/providerPromptTokens, 0, 0); err != nil {
+		return err
+	}
+
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return fmt.Errorf("parse redis url: %w", err)
+	}
+	h.Redis = redis.NewClient(opts)
+	h.onClose(func() { h.Redis.Close() })
+	if err := waitFor(ctx, "redis", func(ctx context.Context) error { return h.Redis.Ping(ctx).Err() }); err != nil {
+		return err
+	}
+
+	h.Telegram = NewTelegramStub()
+	h.Provider = NewProviderStub()
+	h.onClose(h.Telegram.Close)
+	h.onClose(h.Provider.Close)
+	h.Orch = NewFakeOrchestrator(h.DB)
+
+	// The router, bridge and fanout build their own http.Clients with the
+	// default transport, so redirecting it is the one place every outbound
+	// call to Telegram or a provider can be caught.
+	original := http.DefaultTransport
+	http.DefaultTransport = newRewriteTransport(original, map[string]string{
+		"api.telegram.org":                  h.Telegram.URL(),
+		"api.openai.com":                    h.Provider.URL(),
+		"api.anthropic.com":                 h.Provider.URL(),
+		"generativelanguage.googleapis.com": h.Provider.URL(),
+	})
+	h.onClose(func() { http.DefaultTransport = original })
+
+	// Handlers read their configuration from the environment when they are
+	// constructed, so the server must be listening (for LLM_PROXY_URL) and
+	// the environment set before anything below is built.
+	mux := http.NewServeMux()
+	os.Setenv("API_JWT_SECRET", adminJWTSecret)
+	h.Server = httptest.NewServer(middleware.ApplyAdmin(mux))
+	h.onClose(h.Server.Close)
+	for key, value := range map[string]string{
+		"LLM_PROXY_URL":       h.Server.URL,
+		"LLM_MODEL":           DefaultModel,
+		"OPENAI_API_KEY":      "e2e-openai-key",
+		"SWARM_AGENT_TIMEOUT": "30s",
+	} {
+		os.Setenv(key, value)
+	}
+	os.Unsetenv("SERVICE_API_KEY")
+
+	// Admin actions reference the acting user, so the token's subject must
+	// be a real row.
+	var adminID string
+	if err := h.DB.QueryRowContext(ctx, `
+		INSERT INTO users (email, name, is_admin) VALUES ('e2e-admin@agentsquads.test', 'e2e admin', true)
+		RETURNING id
+	`).Scan(&adminID); err != nil {
+		return fmt.Errorf("seed admin user: %w", err)
+	}
+	h.adminToken, err = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":      adminID,
+		"email":    "e2e-admin@agentsquads.test",
+		"is_admin": true,
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(adminJWTSecret))
+	if err != nil {
+		return fmt.Errorf("sign admin token: %w", err)
+	}
+
+	return h.mount(mux)
+}
+
+// mount mirrors the DATABASE_URL branch of main.go.
+func (h *Harness) mount(mux *http.ServeMux) error {
+	runCtx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+
+	planResolver := plans.NewResolver(h.DB)
+	h.Policies = policies.NewStore(h.DB)
+
+	coordHandler := coordinator.NewHandler(h.Redis)
+	coordHandler.SetPlanResolver(planResolver)
+	coordHandler.SetPolicyChecker(h.Policies)
+	runStore := coordinator.NewRunStore(h.DB)
+	coordHandler.SetRunRecorder(runStore)
+
+	links := channels.NewLinkStore(h.DB)
+	creds := channels.NewCredentialsStore(h.DB)
+	router := channels.NewRouter(h.DB, h.Redis)
+	router.SetAgentBridge(coordinator.NewBridge(coordHandler))
+	router.SetPolicyChecker(h.Policies)
+
+	fanout := channels.NewFanout(h.Redis, links, creds)
+	fanout.RegisterSender("viber", adapters.NewViberAdapter(creds))
+	go func() {
+		if err := fanout.Start(runCtx); err != nil {
+			fmt.Fprintf(os.Stderr, "e2e: channel fanout stopped: %v\n", err)
+		}
+	}()
+
+	reg, err := llmproxy.NewModelRegistry(h.DB)
+	if err != nil {
+		return fmt.Errorf("load model registry: %w", err)
+	}
+	h.proxy = llmproxy.NewProxy(h.DB, reg, h.Orch)
+	h.proxy.Plans = planResolver
+	h.proxy.Mount(mux)
+
+	channelHandler := routes.NewChannelHandler(h.DB, router, links, creds)
+	channelHandler.Policies = h.Policies
+	channelHandler.Mount(mux)
+
+	adminHandler := routes.NewAdminHandler(h.DB, h.Orch)
+	adminHandler.Plans = planResolver
+	adminHandler.Policies = h.Policies
+	adminHandler.Mount(mux)
+	routes.NewContainerHandler(h.DB).Mount(mux)
+
+	routes.MountSwarmRoutes(mux, coordHandler)
+	routes.NewSwarmFeedbackHandler(h.DB, runStore).Mount(mux)
+	return nil
+}
+
+// Close tears everything down in reverse order of creation.
+func (h *Harness) Close() {
+	if h.cancel != nil {
+		h.cancel()
+	}
+	for i := len(h.closers) - 1; i >= 0; i-- {
+		h.closers[i]()
+	}
+	h.closers = nil
+}
+
+func (h *Harness) onClose(fn func()) {
+	h.closers = append(h.closers, fn)
+}
+
+// startDependencies returns Postgres and Redis URLs, starting containers for
+// whichever one is not provided through the environment.
+func (h *Harness) startDependencies(ctx context.Context) (string, string, error) {
+	dsn := strings.TrimSpace(os.Getenv("E2E_DATABASE_URL"))
+	redisURL := strings.TrimSpace(os.Getenv("E2E_REDIS_URL"))
+	if dsn != "" && redisURL != "" {
+		return dsn, redisURL, nil
+	}
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return "", "", fmt.Errorf("docker client (or set E2E_DATABASE_URL and E2E_REDIS_URL): %w", err)
+	}
+	h.onClose(func() { cli.Close() })
+
+	if dsn == "" {
+		hostPort, err := h.runContainer(ctx, cli, postgresImage, "5432/tcp", []string{
+			"POSTGRES_USER=e2e",
+			"POSTGRES_PASSWORD=e2e",
+			"POSTGRES_DB=e2e",
+		})
+		if err != nil {
+			return "", "", err
+		}
+		dsn = fmt.Sprintf("postgres://e2e:e2e@%s/e2e?sslmode=disable", hostPort)
+	}
+	if redisURL == "" {
+		hostPort, err := h.runContainer(ctx, cli, redisImage, "6379/tcp", nil)
+		if err != nil {
+			return "", "", err
+		}
+		redisURL = "redis://" + hostPort
+	}
+	return dsn, redisURL, nil
+}
+
+// runContainer starts ref with its ports published and returns the host
+// address mapped to port. The container is force-removed on Close.
+func (h *Harness) runContainer(ctx context.Context, cli *client.Client, ref, port string, env []string) (string, error) {
+	if _, err := cli.ImageInspect(ctx, ref); err != nil {
+		reader, err := cli.ImagePull(ctx, ref, image.PullOptions{})
+		if err != nil {
+			return "", fmt.Errorf("pull %s: %w", ref, err)
+		}
+		_, _ = io.Copy(io.Discard, reader)
+		reader.Close()
+	}
+
+	resp, err := cli.ContainerCreate(ctx,
+		&container.Config{
+			Image:  ref,
+			Env:    env,
+			Labels: map[string]string{"agentsquads.e2e": "true"},
+		},
+		&container.HostConfig{PublishAllPorts: true},
+		nil, nil, "",
+	)
+	if err != nil {
+		return "", fmt.Errorf("create %s container: %w", ref, err)
+	}
+	h.onClose(func() {
+		_ = cli.ContainerRemove(context.Background(), resp.ID, container.RemoveOptions{Force: true, RemoveVolumes: true})
+	})
+	if err := cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return "", fmt.Errorf("start %s container: %w", ref, err)
+	}
+
+	var hostPort string
+	err = waitFor(ctx, ref+" port mapping", func(ctx context.Context) error {
+		info, err := cli.ContainerInspect(ctx, resp.ID)
+		if err != nil {
+			return err
+		}
+		for p, bindings := range info.NetworkSettings.Ports {
+			if string(p) == port && len(bindings) > 0 {
+				hostPort = "127.0.0.1:" + bindings[0].HostPort
+				return nil
+			}
+		}
+		return errors.New("port not published yet")
+	})
+	return hostPort, err
+}
+
+// createDatabase creates a uniquely named database on the server behind
+// baseDSN so runs never share state, and drops it on Close.
+func (h *Harness) createDatabase(ctx context.Context, baseDSN string) (string, error) {
+	admin, err := sql.Open("postgres", baseDSN)
+	if err != nil {
+		return "", fmt.Errorf("open postgres: %w", err)
+	}
+	h.onClose(func() { admin.Close() })
+	if err := waitFor(ctx, "postgres", admin.PingContext); err != nil {
+		return "", err
+	}
+
+	name := "e2e_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	if _, err := admin.ExecContext(ctx, "CREATE DATABASE "+name); err != nil {
+		return "", fmt.Errorf("create database: %w", err)
+	}
+	h.onClose(func() {
+		_, _ = admin.ExecContext(context.Background(), "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)")
+	})
+
+	u, err := url.Parse(baseDSN)
+	if err != nil {
+		return "", fmt.Errorf("E2E_DATABASE_URL must be a postgres:// URL: %w", err)
+	}
+	u.Path = "/" + name
+	return u.String(), nil
+}
+
+func migrationsDir() string {
+	if dir := strings.TrimSpace(os.Getenv("E2E_MIGRATIONS_DIR")); dir != "" {
+		return dir
+	}
+	return filepath.Join("..", "..", "..", "db", "migrations")
+}
+
+// applyMigrations runs every .sql file in dir in filename order.
+func applyMigrations(ctx context.Context, db *sql.DB, dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return fmt.Errorf("list migrations: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no migrations found in %s", dir)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("read migration %s: %w", filepath.Base(file), err)
+		}
+		if _, err := db.ExecContext(ctx, string(content)); err != nil {
+			return fmt.Errorf("apply migration %s: %w", filepath.Base(file), err)
+		}
+	}
+	return nil
+}
+
+// waitFor retries check every 250ms until it succeeds or ctx expires.
+func waitFor(ctx context.Context, what string, check func(context.Context) error) error {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		err := check(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for %s: %w (last error: %v)", what, ctx.Err(), err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// Eventually polls cond until it returns true or timeout elapses.
+func Eventually(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("timed out after %s waiting for %s", timeout, what)
+}
+
+// DoJSON sends body as JSON and decodes the response into out when out is
+// non-nil. It returns the response status.
+func (h *Harness) DoJSON(t *testing.T, method, path string, header http.Header, body, out any) int {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("marshal %s %s body: %v", method, path, err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, h.Server.URL+path, reader)
+	if err != nil {
+		t.Fatalf("build %s %s: %v", method, path, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+
+	resp, err := h.Server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read %s %s response: %v", method, path, err)
+	}
+	if out != nil && len(raw) > 0 {
+		if err := json.Unmarshal(raw, out); err != nil {
+			t.Fatalf("decode %s %s response (status %d): %v body=%s", method, path, resp.StatusCode, err, raw)
+		}
+	}
+	return resp.StatusCode
+}
+
+// Admin returns headers carrying an admin bearer token.
+func (h *Harness) Admin() http.Header {
+	return http.Header{"Authorization": {"Bearer " + h.adminToken}}
+}
+
+// Tenant returns the X-Tenant-ID header the LLM proxy and swarm routes use.
+func Tenant(tenantID string) http.Header {
+	return http.Header{"X-Tenant-ID": {tenantID}}
+}
+
+// TaskEvent is one server-sent event from /api/swarm/tasks/{id}/events.
+type TaskEvent struct {
+	Event  string
+	RunID  string
+	Status string
+}
+
+// Terminal reports whether the run has stopped.
+func (e TaskEvent) Terminal() bool {
+	switch e.Status {
+	case "complete", "failed", "timeout", "cancelled":
+		return true
+	}
+	return false
+}
+
+// WatchTask subscribes to a swarm task's SSE stream. The channel is closed
+// when the stream ends or the test finishes.
+func (h *Harness) WatchTask(t *testing.T, taskID string) <-chan TaskEvent {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.Server.URL+"/api/swarm/tasks/"+taskID+"/events", nil)
+	if err != nil {
+		t.Fatalf("build events request: %v", err)
+	}
+	resp, err := h.Server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("subscribe to task %s: %v", taskID, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		t.Fatalf("subscribe to task %s: status %d", taskID, resp.StatusCode)
+	}
+
+	events := make(chan TaskEvent, 32)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+		var name string
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				var payload struct {
+					Task struct {
+						RunID  string `json:"run_id"`
+						Status string `json:"status"`
+					} `json:"task"`
+				}
+				if json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &payload) != nil {
+					continue
+				}
+				select {
+				case events <- TaskEvent{Event: name, RunID: payload.Task.RunID, Status: payload.Task.Status}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events
+}
@@ -0,0 +1,211 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentsquads/api/policies"
+)
+
+func TestTenantProvisioning(t *testing.T) {
+	tenantID := env.ProvisionTenant(t, 500)
+
+	var resp struct {
+		Tenant struct {
+			Status    string `json:"status"`
+			Credits   int64  `json:"credits_balance_cents"`
+			Container struct {
+				State string `json:"state"`
+			} `json:"container"`
+		} `json:"tenant"`
+	}
+	if status := env.DoJSON(t, http.MethodGet, "/api/admin/tenants/"+tenantID, env.Admin(), nil, &resp); status != http.StatusOK {
+		t.Fatalf("get tenant status = %d", status)
+	}
+	if resp.Tenant.Status != "active" || resp.Tenant.Credits != 500 || resp.Tenant.Container.State != "running" {
+		t.Fatalf("tenant = %+v, want active with 500 credits and a running container", resp.Tenant)
+	}
+
+	if status := env.DoJSON(t, http.MethodGet, "/api/admin/tenants/"+tenantID, nil, nil, nil); status != http.StatusForbidden {
+		t.Fatalf("unauthenticated admin status = %d, want 403", status)
+	}
+}
+
+func TestTelegramConnect(t *testing.T) {
+	tenantID := env.ProvisionTenant(t, 500)
+	token := env.ConnectTelegram(t, tenantID)
+	if env.Telegram.WebhookSecret(token) == "" {
+		t.Fatalf("connect did not register a webhook secret with telegram")
+	}
+
+	var resp struct {
+		Channels []struct {
+			Channel string `json:"channel"`
+			Status  string `json:"status"`
+		} `json:"channels"`
+	}
+	if status := env.DoJSON(t, http.MethodGet, "/api/channels?tenant_id="+tenantID, nil, nil, &resp); status != http.StatusOK {
+		t.Fatalf("list channels status = %d", status)
+	}
+	if len(resp.Channels) != 1 || resp.Channels[0].Channel != "telegram" || resp.Channels[0].Status != "connected" {
+		t.Fatalf("channels = %+v, want one connected telegram channel", resp.Channels)
+	}
+
+	// Disabling the channel acknowledges updates without routing them.
+	env.SetPolicy(t, tenantID, policies.FeatureTelegram, false)
+	calls := env.Provider.Calls()
+	if status := env.SendTelegramUpdate(t, token, 1001, "hello"); status != http.StatusOK {
+		t.Fatalf("webhook for disabled channel status = %d, want 200", status)
+	}
+	if env.Provider.Calls() != calls || len(env.Telegram.Messages("1001")) != 0 {
+		t.Fatalf("disabled channel still produced a reply")
+	}
+}
+
+func TestTelegramSwarmRun(t *testing.T) {
+	tenantID := env.ProvisionTenant(t, 500)
+	token := env.ConnectTelegram(t, tenantID)
+	const chatID = 2002
+
+	if status := env.SendTelegramUpdate(t, token, chatID, "/agent run draft a launch plan"); status != http.StatusOK {
+		t.Fatalf("webhook status = %d", status)
+	}
+
+	var tasks struct {
+		Tasks []struct {
+			RunID       string `json:"run_id"`
+			TriggerType string `json:"trigger_type"`
+		} `json:"tasks"`
+	}
+	env.DoJSON(t, http.MethodGet, "/api/swarm/tasks?tenant_id="+tenantID, nil, nil, &tasks)
+	if len(tasks.Tasks) != 1 || tasks.Tasks[0].TriggerType != "command" {
+		t.Fatalf("tasks = %+v, want one command-triggered run", tasks.Tasks)
+	}
+	runID := tasks.Tasks[0].RunID
+
+	events := env.WatchTask(t, runID)
+	deadline := time.After(45 * time.Second)
+	var seen []string
+	for done := false; !done; {
+		select {
+		case evt, ok := <-events:
+			if !ok {
+				t.Fatalf("event stream closed before the run finished; saw %v", seen)
+			}
+			seen = append(seen, evt.Event+":"+evt.Status)
+			if evt.RunID != runID {
+				t.Fatalf("event for run %q on run %q stream", evt.RunID, runID)
+			}
+			done = evt.Terminal()
+		case <-deadline:
+			t.Fatalf("run %s did not finish; saw events %v", runID, seen)
+		}
+	}
+	if !strings.HasPrefix(seen[0], "snapshot:") {
+		t.Fatalf("first event = %q, want snapshot", seen[0])
+	}
+
+	chat := strconv.Itoa(chatID)
+	Eventually(t, 15*time.Second, "swarm ack on telegram", func() bool {
+		return containsText(env.Telegram.Messages(chat), "Agent swarm started (`"+runID+"`)")
+	})
+	Eventually(t, 15*time.Second, "swarm result on telegram", func() bool {
+		return containsText(env.Telegram.Messages(chat), "Agent swarm completed")
+	})
+	for _, msg := range env.Telegram.Messages(chat) {
+		if msg.BotToken != token {
+			t.Fatalf("message sent with bot %q, want tenant bot", msg.BotToken)
+		}
+	}
+}
+
+func TestChannelReplyDeductsCredits(t *testing.T) {
+	const start = 1000
+	tenantID := env.ProvisionTenant(t, start)
+	token := env.ConnectTelegram(t, tenantID)
+	env.Provider.SetReply("pong from e2e")
+	t.Cleanup(func() { env.Provider.SetReply("pong") })
+
+	if status := env.SendTelegramUpdate(t, token, 3003, "hello there"); status != http.StatusOK {
+		t.Fatalf("webhook status = %d", status)
+	}
+
+	// One billed call classifies the message for the swarm, one answers it
+	// and a third titles the conversation in the background.
+	Eventually(t, 15*time.Second, "conversation title", func() bool {
+		var titled int
+		err := env.DB.QueryRow(`SELECT COUNT(*) FROM conversations WHERE tenant_id = $1 AND title IS NOT NULL`, tenantID).Scan(&titled)
+		return err == nil && titled == 1
+	})
+	if got, want := env.Balance(t, tenantID), int64(start-3*FlatRateCents); got != want {
+		t.Fatalf("balance = %d, want %d", got, want)
+	}
+	var usageRows int
+	if err := env.DB.QueryRow(`SELECT COUNT(*) FROM usage_logs WHERE tenant_id = $1 AND model = $2`, tenantID, DefaultModel).Scan(&usageRows); err != nil {
+		t.Fatalf("count usage logs: %v", err)
+	}
+	if usageRows != 3 {
+		t.Fatalf("usage_logs rows = %d, want 3", usageRows)
+	}
+
+	Eventually(t, 15*time.Second, "assistant reply on telegram", func() bool {
+		return containsText(env.Telegram.Messages("3003"), "pong from e2e")
+	})
+}
+
+func TestAutoPauseAtZeroBalance(t *testing.T) {
+	tenantID := env.ProvisionTenant(t, FlatRateCents)
+	completion := map[string]any{
+		"model":    DefaultModel,
+		"messages": []map[string]string{{"role": "user", "content": "spend it all"}},
+	}
+
+	if status := env.DoJSON(t, http.MethodPost, "/v1/chat/completions", Tenant(tenantID), completion, nil); status != http.StatusOK {
+		t.Fatalf("completion status = %d", status)
+	}
+	if got := env.Balance(t, tenantID); got != 0 {
+		t.Fatalf("balance = %d, want 0", got)
+	}
+	if got := env.TenantStatus(t, tenantID); got != "paused" {
+		t.Fatalf("tenant status = %q, want paused", got)
+	}
+	if env.Orch.Running(tenantID) || env.Orch.Stops(tenantID) != 1 {
+		t.Fatalf("container running=%v stops=%d, want stopped once", env.Orch.Running(tenantID), env.Orch.Stops(tenantID))
+	}
+
+	var denied struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if status := env.DoJSON(t, http.MethodPost, "/v1/chat/completions", Tenant(tenantID), completion, &denied); status != http.StatusPaymentRequired || denied.Error.Code != "insufficient_credits" {
+		t.Fatalf("paused tenant completion = %d %+v, want 402 insufficient_credits", status, denied)
+	}
+
+	if status := env.DoJSON(t, http.MethodPost, "/api/admin/tenants/"+tenantID+"/credits", env.Admin(), map[string]any{
+		"amount": 500,
+		"reason": "e2e top-up",
+	}, nil); status != http.StatusOK {
+		t.Fatalf("top up status = %d", status)
+	}
+	if status := env.DoJSON(t, http.MethodPost, "/api/admin/tenants/"+tenantID+"/resume", env.Admin(), nil, nil); status != http.StatusOK {
+		t.Fatalf("resume status = %d", status)
+	}
+	if got := env.TenantStatus(t, tenantID); got != "active" || !env.Orch.Running(tenantID) {
+		t.Fatalf("after resume status=%q running=%v, want active and running", got, env.Orch.Running(tenantID))
+	}
+}
+
+func containsText(messages []TelegramMessage, text string) bool {
+	for _, m := range messages {
+		if strings.Contains(m.Text, text) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+//go:build e2e
+
+// Package e2e exercises the cross-package tenant lifecycle (webhook → router →
+// coordinator → fanout → channel adapter, plus the LLM proxy and billing)
+// against real Postgres and Redis.
+//
+// Run it with:
+//
+//	go test -tags=e2e -timeout 2m ./e2e
+//
+// Postgres and Redis are started as throwaway containers through the local
+// Docker daemon. Set E2E_DATABASE_URL and E2E_REDIS_URL to reuse running
+// servers instead (e.g. the docker-compose stack); the harness creates and
+// drops its own database on that server. Telegram and the LLM providers are
+// replaced by in-process stubs, and tenant containers by a fake orchestrator.
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// startupTimeout bounds dependency startup so the suite stays well inside its
+// two minute budget.
+const startupTimeout = 60 * time.Second
+
+var env *Harness
+
+func TestMain(m *testing.M) {
+	ctx, cancel := context.WithTimeout(context.Background(), startupTimeout)
+	harness, err := Start(ctx)
+	cancel()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "e2e: harness startup failed: %v\n", err)
+		os.Exit(1)
+	}
+	env = harness
+
+	code := m.Run()
+	env.Close()
+	os.Exit(code)
+}
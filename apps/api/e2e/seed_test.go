@@ -0,0 +1,146 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/agentsquads/api/llmproxy"
+	"github.com/google/uuid"
+)
+
+// SeedTenant inserts a user, its tenant and a credit balance, and returns the
+// tenant id. Tenants get the default feature policies from the database
+// trigger, so swarm and every channel are enabled.
+func (h *Harness) SeedTenant(t *testing.T, creditsCents int64) string {
+	t.Helper()
+	ctx := context.Background()
+	email := fmt.Sprintf("e2e-%s@agentsquads.test", uuid.NewString()[:8])
+
+	var userID, tenantID string
+	if err := h.DB.QueryRowContext(ctx, `INSERT INTO users (email, name) VALUES ($1, $2) RETURNING id`, email, t.Name()).Scan(&userID); err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+	if err := h.DB.QueryRowContext(ctx, `INSERT INTO tenants (user_id) VALUES ($1) RETURNING id`, userID).Scan(&tenantID); err != nil {
+		t.Fatalf("seed tenant: %v", err)
+	}
+	h.SeedCredits(t, tenantID, creditsCents)
+	return tenantID
+}
+
+// ProvisionTenant seeds a tenant and creates its container through the
+// orchestrator, the state a tenant is in after signup completes.
+func (h *Harness) ProvisionTenant(t *testing.T, creditsCents int64) string {
+	t.Helper()
+	tenantID := h.SeedTenant(t, creditsCents)
+	if _, err := h.Orch.Create(context.Background(), tenantID); err != nil {
+		t.Fatalf("provision tenant container: %v", err)
+	}
+	return tenantID
+}
+
+// SeedCredits sets the tenant's balance.
+func (h *Harness) SeedCredits(t *testing.T, tenantID string, cents int64) {
+	t.Helper()
+	if _, err := h.DB.ExecContext(context.Background(), `
+		INSERT INTO credits (tenant_id, balance_cents) VALUES ($1, $2)
+		ON CONFLICT (tenant_id) DO UPDATE SET balance_cents = EXCLUDED.balance_cents, updated_at = NOW()
+	`, tenantID, cents); err != nil {
+		t.Fatalf("seed credits: %v", err)
+	}
+}
+
+// Balance returns the tenant's current balance.
+func (h *Harness) Balance(t *testing.T, tenantID string) int64 {
+	t.Helper()
+	var cents int64
+	if err := h.DB.QueryRowContext(context.Background(), `SELECT balance_cents FROM credits WHERE tenant_id = $1`, tenantID).Scan(&cents); err != nil {
+		t.Fatalf("load balance: %v", err)
+	}
+	return cents
+}
+
+// TenantStatus returns tenants.status (active, paused or suspended).
+func (h *Harness) TenantStatus(t *testing.T, tenantID string) string {
+	t.Helper()
+	var status string
+	if err := h.DB.QueryRowContext(context.Background(), `SELECT status FROM tenants WHERE id = $1`, tenantID).Scan(&status); err != nil {
+		t.Fatalf("load tenant status: %v", err)
+	}
+	return status
+}
+
+// SetPolicy enables or disables a tenant feature.
+func (h *Harness) SetPolicy(t *testing.T, tenantID, feature string, enabled bool) {
+	t.Helper()
+	if err := h.Policies.Set(context.Background(), tenantID, feature, enabled); err != nil {
+		t.Fatalf("set %s policy: %v", feature, err)
+	}
+}
+
+// SeedModel adds a model and reloads the proxy's registry. The reload is not
+// synchronised with in-flight requests, so call it before a scenario sends
+// any traffic.
+func (h *Harness) SeedModel(t *testing.T, id, provider string, inputPerM, outputPerM, markupPct int) {
+	t.Helper()
+	ctx := context.Background()
+	if err := h.insertModel(ctx, id, provider, inputPerM, outputPerM, markupPct); err != nil {
+		t.Fatal(err)
+	}
+	reg, err := llmproxy.NewModelRegistry(h.DB)
+	if err != nil {
+		t.Fatalf("reload model registry: %v", err)
+	}
+	h.proxy.Registry = reg
+}
+
+func (h *Harness) insertModel(ctx context.Context, id, provider string, inputPerM, outputPerM, markupPct int) error {
+	_, err := h.DB.ExecContext(ctx, `
+		INSERT INTO models (id, name, provider, provider_cost_input_per_m, provider_cost_output_per_m, markup_pct, enabled)
+		VALUES ($1, $1, $2, $3, $4, $5, true)
+		ON CONFLICT (id) DO UPDATE SET
+			provider = EXCLUDED.provider,
+			provider_cost_input_per_m = EXCLUDED.provider_cost_input_per_m,
+			provider_cost_output_per_m = EXCLUDED.provider_cost_output_per_m,
+			markup_pct = EXCLUDED.markup_pct,
+			enabled = true
+	`, id, provider, inputPerM, outputPerM, markupPct)
+	if err != nil {
+		return fmt.Errorf("seed model %s: %w", id, err)
+	}
+	return nil
+}
+
+// ConnectTelegram connects a new stub bot to the tenant through the API and
+// returns its token.
+func (h *Harness) ConnectTelegram(t *testing.T, tenantID string) string {
+	t.Helper()
+	token := "e2e-" + uuid.NewString()
+	var resp map[string]any
+	if status := h.DoJSON(t, http.MethodPost, "/api/channels/telegram", nil, map[string]string{
+		"tenant_id": tenantID,
+		"bot_token": token,
+	}, &resp); status != http.StatusCreated {
+		t.Fatalf("connect telegram: status %d body=%v", status, resp)
+	}
+	return token
+}
+
+// SendTelegramUpdate posts a private-chat text message to the Telegram
+// webhook, authenticated with the bot's registered secret.
+func (h *Harness) SendTelegramUpdate(t *testing.T, botToken string, chatID int64, text string) int {
+	t.Helper()
+	update := map[string]any{
+		"update_id": chatID,
+		"message": map[string]any{
+			"text": text,
+			"chat": map[string]any{"id": chatID},
+			"from": map[string]any{"id": chatID},
+		},
+	}
+	header := http.Header{"X-Telegram-Bot-Api-Secret-Token": {h.Telegram.WebhookSecret(botToken)}}
+	return h.DoJSON(t, http.MethodPost, "/api/channels/telegram/webhook", header, update, nil)
+}
@@ -0,0 +1,327 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentsquads/api/orchestrator"
+)
+
+// rewriteTransport sends requests for the mapped hosts to local stub servers
+// and everything else to the wrapped transport.
+type rewriteTransport struct {
+	next  http.RoundTripper
+	hosts map[string]*url.URL
+}
+
+func newRewriteTransport(next http.RoundTripper, hosts map[string]string) *rewriteTransport {
+	rt := &rewriteTransport{next: next, hosts: make(map[string]*url.URL, len(hosts))}
+	for host, target := range hosts {
+		u, err := url.Parse(target)
+		if err != nil {
+			panic(fmt.Sprintf("e2e: bad stub url %q: %v", target, err))
+		}
+		rt.hosts[host] = u
+	}
+	return rt
+}
+
+func (rt *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	target, ok := rt.hosts[req.URL.Hostname()]
+	if !ok {
+		return rt.next.RoundTrip(req)
+	}
+	out := req.Clone(req.Context())
+	out.URL.Scheme = target.Scheme
+	out.URL.Host = target.Host
+	out.Host = target.Host
+	return rt.next.RoundTrip(out)
+}
+
+// FakeOrchestrator is an in-memory TenantOrchestrator. Create records the
+// container id on the tenant row like the Docker orchestrator does.
+type FakeOrchestrator struct {
+	db *sql.DB
+
+	mu         sync.Mutex
+	containers map[string]*fakeContainer
+}
+
+type fakeContainer struct {
+	id        string
+	running   bool
+	startedAt time.Time
+	starts    int
+	stops     int
+}
+
+var _ orchestrator.TenantOrchestrator = (*FakeOrchestrator)(nil)
+
+func NewFakeOrchestrator(db *sql.DB) *FakeOrchestrator {
+	return &FakeOrchestrator{db: db, containers: make(map[string]*fakeContainer)}
+}
+
+func (o *FakeOrchestrator) Create(ctx context.Context, tenantID string) (*orchestrator.Container, error) {
+	id := "fake-" + tenantID
+	if _, err := o.db.ExecContext(ctx, `UPDATE tenants SET container_id = $1 WHERE id = $2`, id, tenantID); err != nil {
+		return nil, fmt.Errorf("store container id: %w", err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.containers[tenantID] = &fakeContainer{id: id, running: true, startedAt: time.Now(), starts: 1}
+	return &orchestrator.Container{ID: id, TenantID: tenantID, Status: "running", IP: "127.0.0.1", Port: 8080}, nil
+}
+
+func (o *FakeOrchestrator) Start(_ context.Context, tenantID string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	c, ok := o.containers[tenantID]
+	if !ok {
+		return errors.New("no container for tenant")
+	}
+	c.running = true
+	c.startedAt = time.Now()
+	c.starts++
+	return nil
+}
+
+func (o *FakeOrchestrator) Stop(_ context.Context, tenantID string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	c, ok := o.containers[tenantID]
+	if !ok {
+		return errors.New("no container for tenant")
+	}
+	c.running = false
+	c.stops++
+	return nil
+}
+
+func (o *FakeOrchestrator) Delete(_ context.Context, tenantID string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.containers, tenantID)
+	return nil
+}
+
+func (o *FakeOrchestrator) Status(_ context.Context, tenantID string) (*orchestrator.ContainerStatus, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	c, ok := o.containers[tenantID]
+	if !ok {
+		return nil, errors.New("no container for tenant")
+	}
+	return &orchestrator.ContainerStatus{Running: c.running, StartedAt: c.startedAt, Health: "healthy"}, nil
+}
+
+func (o *FakeOrchestrator) Exec(_ context.Context, tenantID string, cmd []string) (string, error) {
+	return strings.Join(cmd, " "), nil
+}
+
+// Running reports whether the tenant's fake container is running.
+func (o *FakeOrchestrator) Running(tenantID string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	c, ok := o.containers[tenantID]
+	return ok && c.running
+}
+
+// Stops returns how many times the tenant's container was stopped.
+func (o *FakeOrchestrator) Stops(tenantID string) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if c, ok := o.containers[tenantID]; ok {
+		return c.stops
+	}
+	return 0
+}
+
+// TelegramStub answers the Bot API calls the API makes: getMe, setWebhook
+// and sendMessage. Bot ids are derived from the token so every tenant can
+// connect its own bot.
+type TelegramStub struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	secrets  map[string]string // bot token -> webhook secret
+	messages []TelegramMessage
+}
+
+// TelegramMessage is one sendMessage call.
+type TelegramMessage struct {
+	BotToken string
+	ChatID   string
+	Text     string
+}
+
+func NewTelegramStub() *TelegramStub {
+	s := &TelegramStub{secrets: make(map[string]string)}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *TelegramStub) URL() string { return s.server.URL }
+func (s *TelegramStub) Close()      { s.server.Close() }
+
+func (s *TelegramStub) serve(w http.ResponseWriter, r *http.Request) {
+	// Paths look like /bot<token>/<method>.
+	token, method, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+	if !ok || token == "" {
+		http.NotFound(w, r)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	w.Header().Set("Content-Type", "application/json")
+	switch method {
+	case "getMe":
+		json.NewEncoder(w).Encode(map[string]any{
+			"ok":     true,
+			"result": map[string]any{"id": BotID(token), "username": "e2e_" + strconv.FormatInt(BotID(token), 10) + "_bot"},
+		})
+	case "setWebhook":
+		var req struct {
+			SecretToken string `json:"secret_token"`
+		}
+		json.Unmarshal(body, &req)
+		s.mu.Lock()
+		s.secrets[token] = req.SecretToken
+		s.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": true})
+	case "sendMessage":
+		var req struct {
+			ChatID string `json:"chat_id"`
+			Text   string `json:"text"`
+		}
+		json.Unmarshal(body, &req)
+		s.mu.Lock()
+		s.messages = append(s.messages, TelegramMessage{BotToken: token, ChatID: req.ChatID, Text: req.Text})
+		s.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": map[string]any{"message_id": 1}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]any{"ok": false, "description": "Not Found"})
+	}
+}
+
+// WebhookSecret returns the secret_token registered through setWebhook.
+func (s *TelegramStub) WebhookSecret(botToken string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.secrets[botToken]
+}
+
+// Messages returns the messages sent to chatID so far.
+func (s *TelegramStub) Messages(chatID string) []TelegramMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []TelegramMessage
+	for _, m := range s.messages {
+		if m.ChatID == chatID {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// BotID is the bot id the stub reports for token.
+func BotID(token string) int64 {
+	var id int64 = 7
+	for _, r := range token {
+		id = (id*31 + int64(r)) % 1_000_000_007
+	}
+	return id
+}
+
+// providerPromptTokens is the usage every stubbed completion reports, so each
+// provider call costs a known amount.
+const providerPromptTokens = 10
+
+// ProviderStub is an OpenAI-compatible chat completions endpoint. Swarm
+// classification prompts get {"trigger":false}; everything else gets Reply.
+type ProviderStub struct {
+	server *httptest.Server
+
+	mu    sync.Mutex
+	reply string
+	calls int
+}
+
+func NewProviderStub() *ProviderStub {
+	s := &ProviderStub{reply: "pong"}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *ProviderStub) URL() string { return s.server.URL }
+func (s *ProviderStub) Close()      { s.server.Close() }
+
+func (s *ProviderStub) serve(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":{"message":"bad request"}}`, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.calls++
+	content := s.reply
+	s.mu.Unlock()
+	for _, m := range req.Messages {
+		if m.Role == "system" && strings.Contains(m.Content, "agent swarm") {
+			content = `{"trigger":false}`
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":     "chatcmpl-e2e",
+		"object": "chat.completion",
+		"model":  req.Model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]any{"role": "assistant", "content": content},
+			"finish_reason": "stop",
+		}},
+		"usage": map[string]any{
+			"prompt_tokens":     providerPromptTokens,
+			"completion_tokens": 0,
+			"total_tokens":      providerPromptTokens,
+		},
+	})
+}
+
+// SetReply changes the assistant text returned for non-classifier prompts.
+func (s *ProviderStub) SetReply(reply string) {
+	s.mu.Lock()
+	s.reply = reply
+	s.mu.Unlock()
+}
+
+// Calls returns how many completions the stub has served.
+func (s *ProviderStub) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
@@ -0,0 +1,224 @@
+// Package errorlog records ERROR-level slog records in platform_errors so
+// operators can review recent failures through the admin API.
+package errorlog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// MaxRows is how many platform_errors rows are kept.
+	MaxRows = 10000
+
+	queueSize    = 256
+	writeTimeout = 5 * time.Second
+)
+
+// Entry is one error record.
+type Entry struct {
+	Component string
+	Message   string
+	Details   map[string]any
+	CreatedAt time.Time
+}
+
+// Sink writes entries to platform_errors in the background so logging never
+// waits on the database. Entries are dropped while the queue is full.
+type Sink struct {
+	db      *sql.DB
+	entries chan Entry
+	done    chan struct{}
+	dropped atomic.Int64
+
+	closeOnce sync.Once
+}
+
+// NewSink starts a sink writing to db.
+func NewSink(db *sql.DB) *Sink {
+	s := &Sink{
+		db:      db,
+		entries: make(chan Entry, queueSize),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Record queues e without blocking.
+func (s *Sink) Record(e Entry) {
+	select {
+	case s.entries <- e:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Close writes the queued entries and stops the sink.
+func (s *Sink) Close() {
+	s.closeOnce.Do(func() { close(s.entries) })
+	<-s.done
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+	for e := range s.entries {
+		ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+		err := s.write(ctx, e)
+		cancel()
+		// Logged below ERROR so sink failures do not feed back into the sink.
+		if err != nil {
+			slog.Warn("failed to record platform error", "component", e.Component, "err", err)
+		}
+		if n := s.dropped.Swap(0); n > 0 {
+			slog.Warn("platform error sink dropped records", "count", n)
+		}
+	}
+}
+
+func (s *Sink) write(ctx context.Context, e Entry) error {
+	details, err := json.Marshal(e.Details)
+	if err != nil || e.Details == nil {
+		details = []byte("{}")
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO platform_errors (component, message, details_json, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (component, message, DATE_TRUNC('hour', created_at AT TIME ZONE 'UTC')) DO NOTHING
+	`, e.Component, e.Message, details, e.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("insert platform error: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM platform_errors
+		WHERE id IN (SELECT id FROM platform_errors ORDER BY created_at DESC, id DESC OFFSET $1)
+	`, MaxRows); err != nil {
+		return fmt.Errorf("prune platform errors: %w", err)
+	}
+	return nil
+}
+
+// Handler forwards every record to next and also sends ERROR-level records
+// to a Sink.
+type Handler struct {
+	next  slog.Handler
+	sink  *Sink
+	attrs []slog.Attr
+	group string
+}
+
+// NewHandler wraps next. A nil sink makes the handler a pass-through.
+func NewHandler(next slog.Handler, sink *Sink) *Handler {
+	return &Handler{next: next, sink: sink}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return (h.sink != nil && level >= slog.LevelError) || h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	var err error
+	if h.next.Enabled(ctx, r.Level) {
+		err = h.next.Handle(ctx, r)
+	}
+	if h.sink != nil && r.Level >= slog.LevelError {
+		h.sink.Record(h.entry(r))
+	}
+	return err
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithAttrs(attrs)
+	clone.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		clone.attrs = append(clone.attrs, slog.Attr{Key: h.group + a.Key, Value: a.Value})
+	}
+	return &clone
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.next = h.next.WithGroup(name)
+	clone.group = h.group + name + "."
+	return &clone
+}
+
+// entry flattens the record's attributes into details. The component is
+// the "component" attribute when set, otherwise the logging package.
+func (h *Handler) entry(r slog.Record) Entry {
+	details := make(map[string]any, len(h.attrs)+r.NumAttrs())
+	for _, a := range h.attrs {
+		addAttr(details, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(details, h.group, a)
+		return true
+	})
+
+	component, _ := details["component"].(string)
+	if component == "" {
+		component = callerPackage(r.PC)
+	}
+	return Entry{Component: component, Message: r.Message, Details: details, CreatedAt: r.Time}
+}
+
+func addAttr(details map[string]any, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addAttr(details, prefix, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	value := v.Any()
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	} else if s, ok := value.(fmt.Stringer); ok && v.Kind() == slog.KindAny {
+		value = s.String()
+	}
+	details[prefix+a.Key] = value
+}
+
+// callerPackage names the package that logged, e.g. "channels" for
+// github.com/agentsquads/api/channels.(*Router).Route.
+func callerPackage(pc uintptr) string {
+	if pc == 0 {
+		return "unknown"
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	fn := frame.Function
+	if fn == "" {
+		return "unknown"
+	}
+	if i := strings.LastIndex(fn, "/"); i >= 0 {
+		fn = fn[i+1:]
+	}
+	if i := strings.Index(fn, "."); i >= 0 {
+		fn = fn[:i]
+	}
+	return fn
+}
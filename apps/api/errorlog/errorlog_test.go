@@ -0,0 +1,75 @@
+package errorlog
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestHandlerForwardsAndRecordsErrors(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	sink := &Sink{entries: make(chan Entry, 4)}
+	logger := slog.New(NewHandler(slog.NewTextHandler(&out, nil), sink))
+
+	logger.Info("tenant created", "tenant", "t1")
+	logger.With("tenant", "t1").WithGroup("req").Error("swarm run failed", "err", errors.New("boom"), slog.Group("llm", "model", "gpt"))
+	logger.Error("send failed", "component", "fanout")
+
+	if !strings.Contains(out.String(), "tenant created") || !strings.Contains(out.String(), "swarm run failed") {
+		t.Fatalf("text handler output = %q", out.String())
+	}
+	if len(sink.entries) != 2 {
+		t.Fatalf("recorded %d entries, want 2", len(sink.entries))
+	}
+	first := <-sink.entries
+	if first.Component != "errorlog" || first.Message != "swarm run failed" {
+		t.Fatalf("first entry = %+v", first)
+	}
+	if first.Details["tenant"] != "t1" || first.Details["req.err"] != "boom" || first.Details["req.llm.model"] != "gpt" {
+		t.Fatalf("details = %v", first.Details)
+	}
+	if second := <-sink.entries; second.Component != "fanout" {
+		t.Fatalf("component attr ignored: %+v", second)
+	}
+}
+
+func TestSinkDropsWhenFull(t *testing.T) {
+	t.Parallel()
+	sink := &Sink{entries: make(chan Entry, 1)}
+	sink.Record(Entry{Message: "a"})
+	sink.Record(Entry{Message: "b"})
+	if sink.dropped.Load() != 1 {
+		t.Fatalf("dropped = %d", sink.dropped.Load())
+	}
+}
+
+func TestSinkWriteDedupesAndPrunes(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	at := time.Date(2026, 10, 16, 12, 30, 0, 0, time.UTC)
+
+	mock.ExpectExec("INSERT INTO platform_errors").WithArgs("channels", "route failed", []byte(`{"tenant":"t1"}`), at).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM platform_errors").WithArgs(MaxRows).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ON CONFLICT").WithArgs("channels", "route failed", []byte("{}"), at).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	sink := NewSink(db)
+	sink.Record(Entry{Component: "channels", Message: "route failed", Details: map[string]any{"tenant": "t1"}, CreatedAt: at})
+	sink.Record(Entry{Component: "channels", Message: "route failed", CreatedAt: at})
+	sink.Close()
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
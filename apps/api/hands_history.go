@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxHandHistoryLimit = 500
+	maxHandHistoryBytes = 10 << 20
+)
+
+// handUsageStats is the tenant's LLM usage logged in the same minute as a
+// history item. usage_logs is not keyed by hand, so this is the closest match
+// the database can give.
+type handUsageStats struct {
+	Requests     int64 `json:"requests"`
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	CostCents    int64 `json:"cost_cents"`
+}
+
+type handHistoryItem struct {
+	fields    map[string]any
+	timestamp time.Time
+}
+
+// handleHandHistory proxies a hand's history from OpenFang, forwarding since,
+// until and limit upstream. Because not every OpenFang version honours them,
+// a JSON array of items with a timestamp is filtered again here, enriched
+// with usage_stats and returned with the applied range. Any other response is
+// passed through untouched.
+func (p *handsProxy) handleHandHistory(w http.ResponseWriter, r *http.Request) {
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	handID := strings.TrimSpace(r.PathValue("hand_id"))
+	if tenantID == "" || handID == "" {
+		writeAPIError(w, http.StatusBadRequest, "missing tenant id or hand id")
+		return
+	}
+	if scoped := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); scoped != "" && scoped != tenantID {
+		writeAPIError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+
+	query := r.URL.Query()
+	since, err := parseHistoryTime(query.Get("since"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "since must be an RFC3339 timestamp")
+		return
+	}
+	until, err := parseHistoryTime(query.Get("until"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "until must be an RFC3339 timestamp")
+		return
+	}
+	if !since.IsZero() && !until.IsZero() && until.Before(since) {
+		writeAPIError(w, http.StatusBadRequest, "until must not be before since")
+		return
+	}
+	limit := 0
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxHandHistoryLimit {
+			writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxHandHistoryLimit))
+			return
+		}
+	}
+
+	target, err := p.buildHandsTarget(r.Context(), tenantID, fmt.Sprintf("/api/hands/%s/history", url.PathEscape(handID)))
+	if err != nil {
+		p.writeUnavailable(w, r, tenantID, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	upstreamQuery := target.Query()
+	if !since.IsZero() {
+		upstreamQuery.Set("since", since.Format(time.RFC3339Nano))
+	}
+	if !until.IsZero() {
+		upstreamQuery.Set("until", until.Format(time.RFC3339Nano))
+	}
+	if limit > 0 {
+		upstreamQuery.Set("limit", strconv.Itoa(limit))
+	}
+	target.RawQuery = upstreamQuery.Encode()
+
+	status, contentType, body, err := p.fetchHandsJSON(r.Context(), target, tenantID)
+	if err != nil {
+		if err == context.DeadlineExceeded {
+			writeAPIError(w, http.StatusGatewayTimeout, "OpenFang API timed out")
+			return
+		}
+		p.writeUnavailable(w, r, tenantID, http.StatusBadGateway, "failed to reach OpenFang API")
+		return
+	}
+
+	items, ok := parseHandHistory(body)
+	if status < 200 || status >= 300 || !ok {
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+		return
+	}
+
+	filtered := make([]handHistoryItem, 0, len(items))
+	for _, item := range items {
+		if !since.IsZero() && item.timestamp.Before(since) {
+			continue
+		}
+		if !until.IsZero() && item.timestamp.After(until) {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+	total := len(filtered)
+	if limit > 0 && len(filtered) > limit {
+		filtered = filtered[:limit]
+	}
+	p.attachHandUsageStats(r.Context(), tenantID, filtered)
+
+	out := make([]map[string]any, 0, len(filtered))
+	for _, item := range filtered {
+		out = append(out, item.fields)
+	}
+	resp := map[string]any{
+		"tenant_id": tenantID,
+		"hand_id":   handID,
+		"items":     out,
+		"total":     total,
+		"returned":  len(out),
+		"since":     nil,
+		"until":     nil,
+		"limit":     nil,
+	}
+	if !since.IsZero() {
+		resp["since"] = since
+	}
+	if !until.IsZero() {
+		resp["until"] = until
+	}
+	if limit > 0 {
+		resp["limit"] = limit
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func parseHistoryTime(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+// fetchHandsJSON reads an upstream hands response into memory so it can
+// be filtered. The exchange is bounded by the default OpenFang timeout.
+func (p *handsProxy) fetchHandsJSON(ctx context.Context, target *url.URL, tenantID string) (int, string, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Tenant-ID", tenantID)
+	if apiKey := strings.TrimSpace(os.Getenv("OPENFANG_API_KEY")); apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+
+	resp, err := p.httpClient().Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return 0, "", nil, context.DeadlineExceeded
+		}
+		return 0, "", nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHandHistoryBytes))
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return 0, "", nil, context.DeadlineExceeded
+		}
+		return 0, "", nil, err
+	}
+	return resp.StatusCode, strings.TrimSpace(resp.Header.Get("Content-Type")), body, nil
+}
+
+// parseHandHistory decodes body as a JSON array of objects that each carry an
+// RFC3339 timestamp. It reports false for anything else.
+func parseHandHistory(body []byte) ([]handHistoryItem, bool) {
+	var raw []map[string]any
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, false
+	}
+	items := make([]handHistoryItem, 0, len(raw))
+	for _, fields := range raw {
+		value, _ := fields["timestamp"].(string)
+		ts, err := time.Parse(time.RFC3339Nano, value)
+		if fields == nil || err != nil {
+			return nil, false
+		}
+		items = append(items, handHistoryItem{fields: fields, timestamp: ts})
+	}
+	return items, true
+}
+
+// attachHandUsageStats sets usage_stats on each item from one grouped query
+// over the items' time span. Lookup failures leave the items unenriched.
+func (p *handsProxy) attachHandUsageStats(ctx context.Context, tenantID string, items []handHistoryItem) {
+	if p.db == nil || len(items) == 0 {
+		return
+	}
+	from, to := items[0].timestamp, items[0].timestamp
+	for _, item := range items[1:] {
+		if item.timestamp.Before(from) {
+			from = item.timestamp
+		}
+		if item.timestamp.After(to) {
+			to = item.timestamp
+		}
+	}
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT date_trunc('minute', created_at) AS minute,
+		       COUNT(*), COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(cost_cents), 0)
+		FROM usage_logs
+		WHERE tenant_id = $1 AND created_at >= $2 AND created_at < $3
+		GROUP BY minute
+	`, tenantID, from.Truncate(time.Minute), to.Truncate(time.Minute).Add(time.Minute))
+	if err != nil {
+		slog.Warn("failed to load hand usage stats", "tenant", tenantID, "err", err)
+		return
+	}
+	defer rows.Close()
+
+	byMinute := make(map[int64]handUsageStats)
+	for rows.Next() {
+		var minute time.Time
+		var stats handUsageStats
+		if err := rows.Scan(&minute, &stats.Requests, &stats.InputTokens, &stats.OutputTokens, &stats.CostCents); err != nil {
+			slog.Warn("failed to scan hand usage stats", "tenant", tenantID, "err", err)
+			return
+		}
+		byMinute[minute.Unix()] = stats
+	}
+	if err := rows.Err(); err != nil {
+		slog.Warn("failed while reading hand usage stats", "tenant", tenantID, "err", err)
+		return
+	}
+
+	for _, item := range items {
+		item.fields["usage_stats"] = byMinute[item.timestamp.Truncate(time.Minute).Unix()]
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestHandHistoryFiltersAndEnriches(t *testing.T) {
+	var gotQuery string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[
+			{"id":"a","timestamp":"2026-01-01T09:00:00Z"},
+			{"id":"b","timestamp":"2026-01-01T10:00:30Z"},
+			{"id":"c","timestamp":"2026-01-01T10:05:00Z"},
+			{"id":"d","timestamp":"2026-01-01T12:00:00Z"}
+		]`))
+	}))
+	defer upstream.Close()
+	t.Setenv("OPENFANG_API_URL", upstream.URL)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	minute := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM usage_logs").
+		WithArgs("t1", minute, minute.Add(time.Minute)).
+		WillReturnRows(sqlmock.NewRows([]string{"minute", "count", "input", "output", "cost"}).AddRow(minute, 2, 100, 40, 3))
+
+	p := &handsProxy{db: db, timeout: time.Second}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tenants/{id}/hands/{hand_id}/history", p.handleHandHistory)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tenants/t1/hands/h1/history?since=2026-01-01T10:00:00Z&until=2026-01-01T11:00:00Z&limit=1", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	if gotQuery != "limit=1&since=2026-01-01T10%3A00%3A00Z&until=2026-01-01T11%3A00%3A00Z" {
+		t.Fatalf("upstream query = %q", gotQuery)
+	}
+
+	var body struct {
+		Items []struct {
+			ID         string         `json:"id"`
+			UsageStats handUsageStats `json:"usage_stats"`
+		} `json:"items"`
+		Total    int `json:"total"`
+		Returned int `json:"returned"`
+		Limit    int `json:"limit"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Total != 2 || body.Returned != 1 || body.Limit != 1 || len(body.Items) != 1 || body.Items[0].ID != "b" {
+		t.Fatalf("body = %+v", body)
+	}
+	if body.Items[0].UsageStats != (handUsageStats{Requests: 2, InputTokens: 100, OutputTokens: 40, CostCents: 3}) {
+		t.Fatalf("usage_stats = %+v", body.Items[0].UsageStats)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestHandHistoryPassesThroughOtherResponses(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"entries":[]}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("OPENFANG_API_URL", upstream.URL)
+
+	p := &handsProxy{timeout: time.Second}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tenants/{id}/hands/{hand_id}/history", p.handleHandHistory)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tenants/t1/hands/h1/history?since=2026-01-01T10:00:00Z", nil))
+	if w.Code != http.StatusOK || w.Body.String() != `{"entries":[]}` {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+
+	for _, query := range []string{"since=yesterday", "limit=0", "since=2026-01-02T00:00:00Z&until=2026-01-01T00:00:00Z"} {
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tenants/t1/hands/h1/history?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d", query, w.Code)
+		}
+	}
+}
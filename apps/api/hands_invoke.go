@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/channels"
+)
+
+// handTestTimeout bounds how long a test invocation waits for the agent.
+const handTestTimeout = 30 * time.Second
+
+// handRouter routes a channel message and returns the agent's reply.
+// *channels.Router satisfies it.
+type handRouter interface {
+	Route(ctx context.Context, msg channels.InboundMessage) (channels.OutboundMessage, error)
+}
+
+// handleTestHand sends {"message": ...} to one hand through the channel
+// router and waits for the reply, so a hand can be tried without a real chat.
+// With "ephemeral": true the conversation is deleted once the reply is in.
+func (p *handsProxy) handleTestHand(w http.ResponseWriter, r *http.Request) {
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	handID := strings.TrimSpace(r.PathValue("hand_id"))
+	if tenantID == "" || handID == "" {
+		writeAPIError(w, http.StatusBadRequest, "missing tenant id or hand id")
+		return
+	}
+	if scoped := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); scoped != "" && scoped != tenantID {
+		writeAPIError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	if p.router == nil || p.db == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "channel router is not configured")
+		return
+	}
+
+	var req struct {
+		Message   string `json:"message"`
+		Ephemeral bool   `json:"ephemeral"`
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	message := strings.TrimSpace(req.Message)
+	if message == "" {
+		writeAPIError(w, http.StatusBadRequest, "message is required")
+		return
+	}
+
+	metadata := map[string]string{channels.AgentIDKey: handID, "source": "hand_test"}
+	if req.Ephemeral {
+		// Create the conversation up front so it can be removed however the
+		// invocation ends, including on timeout.
+		conversationID, err := p.createConversation(r.Context(), tenantID)
+		if err != nil {
+			slog.Error("hand test conversation create failed", "tenant", tenantID, "hand", handID, "err", err)
+			writeAPIError(w, http.StatusInternalServerError, "failed to start test invocation")
+			return
+		}
+		metadata["conversation_id"] = conversationID
+		defer p.deleteConversation(context.WithoutCancel(r.Context()), tenantID, conversationID)
+	}
+
+	timeout := p.testTimeout
+	if timeout <= 0 {
+		timeout = handTestTimeout
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	start := time.Now()
+	out, err := p.router.Route(ctx, channels.InboundMessage{
+		TenantID: tenantID,
+		Content:  message,
+		Channel:  "web",
+		Metadata: metadata,
+	})
+	duration := time.Since(start)
+	if err != nil {
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			writeAPIError(w, http.StatusRequestTimeout, fmt.Sprintf("hand did not respond within %s", timeout))
+		case errors.Is(err, channels.ErrChannelDisabled):
+			writeAPIError(w, http.StatusForbidden, err.Error())
+		default:
+			slog.Error("hand test invocation failed", "tenant", tenantID, "hand", handID, "err", err)
+			writeAPIError(w, http.StatusBadGateway, "hand test invocation failed")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"response":        out.Content,
+		"duration_ms":     duration.Milliseconds(),
+		"conversation_id": out.ConversationID,
+	})
+}
+
+func (p *handsProxy) createConversation(ctx context.Context, tenantID string) (string, error) {
+	var id string
+	err := p.db.QueryRowContext(ctx, `INSERT INTO conversations (tenant_id) VALUES ($1) RETURNING id`, tenantID).Scan(&id)
+	return id, err
+}
+
+// deleteConversation removes an ephemeral test conversation; its messages go
+// with it.
+func (p *handsProxy) deleteConversation(ctx context.Context, tenantID, conversationID string) {
+	if _, err := p.db.ExecContext(ctx, `DELETE FROM conversations WHERE id = $1 AND tenant_id = $2`, conversationID, tenantID); err != nil {
+		slog.Error("hand test conversation cleanup failed", "tenant", tenantID, "conversation", conversationID, "err", err)
+	}
+}
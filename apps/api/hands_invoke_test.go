@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/channels"
+)
+
+type routeFunc func(ctx context.Context, msg channels.InboundMessage) (channels.OutboundMessage, error)
+
+func (f routeFunc) Route(ctx context.Context, msg channels.InboundMessage) (channels.OutboundMessage, error) {
+	return f(ctx, msg)
+}
+
+func TestTestHandEphemeral(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mock.ExpectQuery("INSERT INTO conversations").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("conv-1"))
+	mock.ExpectExec("DELETE FROM conversations").WithArgs("conv-1", "t1").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	var routed channels.InboundMessage
+	p := &handsProxy{db: db, router: routeFunc(func(ctx context.Context, msg channels.InboundMessage) (channels.OutboundMessage, error) {
+		routed = msg
+		return channels.OutboundMessage{Content: "pong", ConversationID: msg.Metadata["conversation_id"]}, nil
+	})}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/tenants/{id}/hands/{hand_id}/test", p.handleTestHand)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/tenants/t1/hands/h1/test",
+		bytes.NewBufferString(`{"message":"ping","ephemeral":true}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Response       string `json:"response"`
+		ConversationID string `json:"conversation_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Response != "pong" || body.ConversationID != "conv-1" {
+		t.Fatalf("body = %s", w.Body.String())
+	}
+	if routed.TenantID != "t1" || routed.Content != "ping" || routed.Metadata[channels.AgentIDKey] != "h1" {
+		t.Fatalf("routed = %+v", routed)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestTestHandTimesOut(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	p := &handsProxy{db: db, testTimeout: 10 * time.Millisecond, router: routeFunc(func(ctx context.Context, msg channels.InboundMessage) (channels.OutboundMessage, error) {
+		<-ctx.Done()
+		return channels.OutboundMessage{}, ctx.Err()
+	})}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/tenants/{id}/hands/{hand_id}/test", p.handleTestHand)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/tenants/t1/hands/h1/test", bytes.NewBufferString(`{"message":"ping"}`)))
+	if w.Code != http.StatusRequestTimeout {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/tenants/t1/hands/h1/test", bytes.NewBufferString(`{"message":" "}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("empty message status = %d", w.Code)
+	}
+}
+
+func TestTestHandRoutesToSelectedHand(t *testing.T) {
+	replies := map[string]string{"h1": "from h1", "h2": "from h2"}
+	received := map[string]string{}
+	p := &handsProxy{db: &sql.DB{}, router: routeFunc(func(ctx context.Context, msg channels.InboundMessage) (channels.OutboundMessage, error) {
+		hand := msg.Metadata[channels.AgentIDKey]
+		received[hand] = msg.Content
+		return channels.OutboundMessage{Content: replies[hand]}, nil
+	})}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/tenants/{id}/hands/{hand_id}/test", p.handleTestHand)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/tenants/t1/hands/h2/test", bytes.NewBufferString(`{"message":"ping"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Response string `json:"response"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Response != "from h2" || len(received) != 1 || received["h2"] != "ping" {
+		t.Fatalf("response = %q received = %v", body.Response, received)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// handsListFilter is the query of a hands list request. Nil booleans do not
+// filter.
+type handsListFilter struct {
+	enabled          *bool
+	hasCustomization *bool
+	sort             string // "", "name" or "last_active"
+	desc             bool
+}
+
+// parseHandsListFilter reads the filter from query, returning a message for
+// the first invalid parameter.
+func parseHandsListFilter(query url.Values) (handsListFilter, string) {
+	get := func(name string) string { return strings.TrimSpace(query.Get(name)) }
+	var f handsListFilter
+	for _, param := range []struct {
+		name string
+		dest **bool
+	}{{"enabled", &f.enabled}, {"has_customization", &f.hasCustomization}} {
+		raw := get(param.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return f, param.name + " must be true or false"
+		}
+		*param.dest = &value
+	}
+	switch f.sort = get("sort"); f.sort {
+	case "", "name", "last_active":
+	default:
+		return f, "sort must be name or last_active"
+	}
+	switch get("order") {
+	case "", "asc":
+	case "desc":
+		f.desc = true
+	default:
+		return f, "order must be asc or desc"
+	}
+	return f, ""
+}
+
+// handleListHands proxies the tenant's hands list from OpenFang. The full
+// list is always fetched; enabled and has_customization filter it here, and
+// sort with order arranges what is left. A response that is not a JSON array
+// of objects is passed through untouched.
+func (p *handsProxy) handleListHands(w http.ResponseWriter, r *http.Request) {
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeAPIError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	if scoped := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); scoped != "" && scoped != tenantID {
+		writeAPIError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	filter, problem := parseHandsListFilter(r.URL.Query())
+	if problem != "" {
+		writeAPIError(w, http.StatusBadRequest, problem)
+		return
+	}
+
+	target, err := p.buildHandsTarget(r.Context(), tenantID, "/api/hands")
+	if err != nil {
+		p.writeUnavailable(w, r, tenantID, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	status, contentType, body, err := p.fetchHandsJSON(r.Context(), target, tenantID)
+	if err != nil {
+		if err == context.DeadlineExceeded {
+			writeAPIError(w, http.StatusGatewayTimeout, "OpenFang API timed out")
+			return
+		}
+		p.writeUnavailable(w, r, tenantID, http.StatusBadGateway, "failed to reach OpenFang API")
+		return
+	}
+
+	var hands []map[string]any
+	if status < 200 || status >= 300 || json.Unmarshal(body, &hands) != nil {
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+		return
+	}
+	writeJSON(w, http.StatusOK, filter.apply(hands))
+}
+
+func (f handsListFilter) apply(hands []map[string]any) []map[string]any {
+	out := make([]map[string]any, 0, len(hands))
+	for _, hand := range hands {
+		if hand == nil {
+			continue
+		}
+		if f.enabled != nil && handEnabled(hand) != *f.enabled {
+			continue
+		}
+		if f.hasCustomization != nil && handCustomized(hand) != *f.hasCustomization {
+			continue
+		}
+		out = append(out, hand)
+	}
+
+	switch f.sort {
+	case "name":
+		sort.SliceStable(out, func(i, j int) bool {
+			a, b := strings.ToLower(handName(out[i])), strings.ToLower(handName(out[j]))
+			if f.desc {
+				return a > b
+			}
+			return a < b
+		})
+	case "last_active":
+		// Hands that were never active sort last in either order.
+		sort.SliceStable(out, func(i, j int) bool {
+			a, b := handLastActive(out[i]), handLastActive(out[j])
+			if a.IsZero() || b.IsZero() {
+				return !a.IsZero() && b.IsZero()
+			}
+			if f.desc {
+				return a.After(b)
+			}
+			return a.Before(b)
+		})
+	}
+	return out
+}
+
+// handEnabled reads status.enabled, falling back to a top-level enabled.
+func handEnabled(hand map[string]any) bool {
+	if status, ok := hand["status"].(map[string]any); ok {
+		if enabled, ok := status["enabled"].(bool); ok {
+			return enabled
+		}
+	}
+	enabled, _ := hand["enabled"].(bool)
+	return enabled
+}
+
+// handCustomized reports whether the hand carries a non-empty customization.
+func handCustomized(hand map[string]any) bool {
+	switch c := hand["customization"].(type) {
+	case nil:
+		return false
+	case map[string]any:
+		return len(c) > 0
+	case []any:
+		return len(c) > 0
+	default:
+		return true
+	}
+}
+
+func handName(hand map[string]any) string {
+	if name, ok := hand["name"].(string); ok && name != "" {
+		return name
+	}
+	id, _ := hand["id"].(string)
+	return id
+}
+
+func handLastActive(hand map[string]any) time.Time {
+	for _, key := range []string{"last_active", "last_active_at"} {
+		if raw, ok := hand[key].(string); ok {
+			if ts, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+				return ts
+			}
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListHandsFiltersAndSorts(t *testing.T) {
+	var gotPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[
+			{"id":"a","name":"Researcher","status":{"enabled":true},"customization":{"prompt":"x"},"last_active":"2026-01-01T09:00:00Z"},
+			{"id":"b","name":"builder","status":{"enabled":true},"customization":null,"last_active":"2026-01-02T09:00:00Z"},
+			{"id":"c","name":"Clip","status":{"enabled":false},"customization":{"schedule":"daily"}},
+			{"id":"d","name":"Analyst","status":{"enabled":true},"customization":{"model":"m"},"last_active":"2026-01-03T09:00:00Z"}
+		]`))
+	}))
+	defer upstream.Close()
+	t.Setenv("OPENFANG_API_URL", upstream.URL)
+
+	p := &handsProxy{timeout: time.Second}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tenants/{id}/hands", p.handleListHands)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "", want: "a,b,c,d"},
+		{query: "?enabled=false", want: "c"},
+		{query: "?enabled=true&has_customization=true&sort=name", want: "d,a"},
+		{query: "?has_customization=false", want: "b"},
+		{query: "?sort=name&order=desc", want: "a,c,b,d"},
+		{query: "?sort=last_active&order=desc", want: "d,b,a,c"},
+		{query: "?sort=last_active", want: "a,b,d,c"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tenants/t1/hands"+tt.query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d body=%s", tt.query, w.Code, w.Body.String())
+		}
+		var hands []struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &hands); err != nil {
+			t.Fatalf("%s: decode: %v", tt.query, err)
+		}
+		ids := make([]string, 0, len(hands))
+		for _, hand := range hands {
+			ids = append(ids, hand.ID)
+		}
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("%s: hands = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+	if gotPath != "/api/hands" {
+		t.Fatalf("upstream path = %q", gotPath)
+	}
+}
+
+func TestListHandsRejectsInvalidQuery(t *testing.T) {
+	p := &handsProxy{timeout: time.Second}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tenants/{id}/hands", p.handleListHands)
+	for _, query := range []string{"enabled=maybe", "has_customization=1x", "sort=created", "order=up"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tenants/t1/hands?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, w.Code)
+		}
+	}
+}
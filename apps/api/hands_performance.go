@@ -0,0 +1,80 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// handPerformancePeriods maps the periods the performance endpoint accepts to
+// the Postgres interval they cover.
+var handPerformancePeriods = map[string]string{
+	"1d":  "1 day",
+	"7d":  "7 days",
+	"30d": "30 days",
+}
+
+type handPerformance struct {
+	P50Ms            int64   `json:"p50_ms"`
+	P95Ms            int64   `json:"p95_ms"`
+	P99Ms            int64   `json:"p99_ms"`
+	AvgMs            int64   `json:"avg_ms"`
+	SuccessRate      float64 `json:"success_rate"`
+	TotalInvocations int64   `json:"total_invocations"`
+	Period           string  `json:"period"`
+}
+
+// handleHandPerformance reports latency percentiles and the success rate of
+// a hand's invocations over the last period (1d, 7d or 30d, default 7d),
+// from the hand_invocations the coordinator records as subtasks finish. A
+// hand without invocations reports zeros.
+func (p *handsProxy) handleHandPerformance(w http.ResponseWriter, r *http.Request) {
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	handID := strings.TrimSpace(r.PathValue("hand_id"))
+	if tenantID == "" || handID == "" {
+		writeAPIError(w, http.StatusBadRequest, "missing tenant id or hand id")
+		return
+	}
+	if scoped := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); scoped != "" && scoped != tenantID {
+		writeAPIError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	period := strings.TrimSpace(r.URL.Query().Get("period"))
+	if period == "" {
+		period = "7d"
+	}
+	interval, ok := handPerformancePeriods[period]
+	if !ok {
+		writeAPIError(w, http.StatusBadRequest, "period must be 1d, 7d or 30d")
+		return
+	}
+	if p.db == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+
+	var p50, p95, p99, avg, successRate float64
+	perf := handPerformance{Period: period}
+	err := p.db.QueryRowContext(r.Context(), `
+		SELECT COUNT(*),
+		       COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY latency_ms), 0),
+		       COALESCE(PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY latency_ms), 0),
+		       COALESCE(PERCENTILE_CONT(0.99) WITHIN GROUP (ORDER BY latency_ms), 0),
+		       COALESCE(AVG(latency_ms), 0)::float8,
+		       COALESCE(AVG(CASE WHEN status = 'complete' THEN 1 ELSE 0 END), 0)::float8
+		FROM hand_invocations
+		WHERE tenant_id = $1 AND hand_id = $2 AND invoked_at >= NOW() - $3::interval
+	`, tenantID, handID, interval).Scan(&perf.TotalInvocations, &p50, &p95, &p99, &avg, &successRate)
+	if err != nil {
+		slog.Error("hand performance query failed", "tenant", tenantID, "hand", handID, "err", err)
+		writeAPIError(w, http.StatusInternalServerError, "failed to load hand performance")
+		return
+	}
+	perf.P50Ms = int64(math.Round(p50))
+	perf.P95Ms = int64(math.Round(p95))
+	perf.P99Ms = int64(math.Round(p99))
+	perf.AvgMs = int64(math.Round(avg))
+	perf.SuccessRate = math.Round(successRate*10000) / 10000
+	writeJSON(w, http.StatusOK, perf)
+}
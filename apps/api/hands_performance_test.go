@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestHandPerformance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mock.ExpectQuery("PERCENTILE_CONT\\(0.95\\) WITHIN GROUP \\(ORDER BY latency_ms\\)").
+		WithArgs("t1", "researcher", "30 days").
+		WillReturnRows(sqlmock.NewRows([]string{"count", "p50", "p95", "p99", "avg", "success"}).
+			AddRow(40, 1200.4, 5400.6, 9100.0, 2050.25, 0.925))
+
+	p := &handsProxy{db: db}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tenants/{id}/hands/{hand_id}/performance", p.handleHandPerformance)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tenants/t1/hands/researcher/performance?period=30d", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var got handPerformance
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := handPerformance{P50Ms: 1200, P95Ms: 5401, P99Ms: 9100, AvgMs: 2050, SuccessRate: 0.925, TotalInvocations: 40, Period: "30d"}
+	if got != want {
+		t.Fatalf("performance = %+v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+
+	for _, tc := range []struct {
+		path, tenant string
+		status       int
+	}{
+		{"/api/tenants/t1/hands/researcher/performance?period=90d", "", http.StatusBadRequest},
+		{"/api/tenants/t1/hands/researcher/performance", "t2", http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.tenant != "" {
+			req.Header.Set("X-Tenant-ID", tc.tenant)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != tc.status {
+			t.Fatalf("%s: status = %d, want %d", tc.path, w.Code, tc.status)
+		}
+	}
+}
@@ -1,21 +1,120 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/agentsquads/api/channels"
+	"github.com/agentsquads/api/orchestrator"
+	"github.com/agentsquads/api/policies"
+)
+
+const (
+	defaultOpenFangTimeout         = 30 * time.Second
+	defaultOpenFangExtendedTimeout = 120 * time.Second
 )
 
-func mountHandsProxyRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /api/hands/events", handleHandsEvents)
-	mux.HandleFunc("POST /api/hands/{id}/approve/{actionId}", handleHandsApprove)
-	mux.HandleFunc("POST /api/hands/{id}/reject/{actionId}", handleHandsReject)
+// handsProxy forwards hand approval traffic to the OpenFang API. Approve and
+// reject calls run the action upstream, so they are bounded by a timeout that
+// tenants with the extended_timeout policy get raised.
+type handsProxy struct {
+	db              *sql.DB
+	policies        *policies.Store
+	orch            orchestrator.TenantOrchestrator
+	timeout         time.Duration
+	extendedTimeout time.Duration
+	// client sends upstream requests; nil means http.DefaultClient. Tests
+	// point it at an httptest server.
+	client *http.Client
+	// router delivers hand test invocations; nil disables them.
+	router handRouter
+	// testTimeout bounds a hand test invocation; zero means handTestTimeout.
+	testTimeout time.Duration
+	// waker starts a stopped tenant container when its traffic fails; nil
+	// reports the failure as is.
+	waker *orchestrator.Waker
+	// idle restarts the tenant's idle window on each request to its
+	// container; nil when no idle window is configured.
+	idle *orchestrator.IdleStopper
+}
+
+func (p *handsProxy) httpClient() *http.Client {
+	if p.client != nil {
+		return p.client
+	}
+	return http.DefaultClient
+}
+
+func mountHandsProxyRoutes(mux *http.ServeMux, db *sql.DB, policyStore *policies.Store, orch orchestrator.TenantOrchestrator, router *channels.Router, idle *orchestrator.IdleStopper) {
+	p := &handsProxy{
+		db:              db,
+		policies:        policyStore,
+		orch:            orch,
+		idle:            idle,
+		timeout:         durationSecondsFromEnv("OPENFANG_TIMEOUT_SECONDS", defaultOpenFangTimeout),
+		extendedTimeout: durationSecondsFromEnv("OPENFANG_EXTENDED_TIMEOUT_SECONDS", defaultOpenFangExtendedTimeout),
+	}
+	// Only a live router is stored, so a nil one does not become a non-nil
+	// interface.
+	if router != nil {
+		p.router = router
+	}
+	if db != nil && orch != nil {
+		p.waker = orchestrator.NewWaker(db, orch)
+	}
+	mux.HandleFunc("GET /api/hands/events", p.handleHandsEvents)
+	mux.HandleFunc("POST /api/hands/{id}/approve/{actionId}", p.handleHandsApprove)
+	mux.HandleFunc("POST /api/hands/{id}/reject/{actionId}", p.handleHandsReject)
+	mux.HandleFunc("GET /api/tenants/{id}/hands", p.handleListHands)
+	mux.HandleFunc("GET /api/tenants/{id}/hands/{hand_id}/history", p.handleHandHistory)
+	mux.HandleFunc("GET /api/tenants/{id}/hands/{hand_id}/performance", p.handleHandPerformance)
+	mux.HandleFunc("POST /api/tenants/{id}/hands/{hand_id}/test", p.handleTestHand)
+}
+
+// durationSecondsFromEnv reads a positive whole number of seconds from name.
+func durationSecondsFromEnv(name string, fallback time.Duration) time.Duration {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return fallback
+	}
+	secs, err := strconv.Atoi(raw)
+	if err != nil || secs <= 0 {
+		slog.Warn("ignoring invalid timeout", "env", name, "value", raw)
+		return fallback
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// timeoutForTenant returns the OpenFang request timeout for a tenant: the
+// extended timeout when its extended_timeout policy is enabled, otherwise the
+// default. Policy lookup failures fall back to the default.
+func (p *handsProxy) timeoutForTenant(ctx context.Context, tenantID string) time.Duration {
+	if p.policies == nil {
+		return p.timeout
+	}
+	enabled, err := p.policies.Get(ctx, tenantID, policies.FeatureExtendedTimeout)
+	if err != nil {
+		slog.Error("extended timeout policy check failed", "tenant", tenantID, "err", err)
+		return p.timeout
+	}
+	if !enabled {
+		return p.timeout
+	}
+	slog.Info("using extended OpenFang timeout", "tenant", tenantID, "timeout", p.extendedTimeout)
+	return p.extendedTimeout
 }
 
-func handleHandsEvents(w http.ResponseWriter, r *http.Request) {
+func (p *handsProxy) handleHandsEvents(w http.ResponseWriter, r *http.Request) {
 	tenantID := strings.TrimSpace(r.URL.Query().Get("tenant_id"))
 	if tenantID == "" {
 		tenantID = strings.TrimSpace(r.Header.Get("X-Tenant-ID"))
@@ -25,9 +124,9 @@ func handleHandsEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	target, err := buildHandsTarget("/api/hands/events", nil)
+	target, err := p.buildHandsTarget(r.Context(), tenantID, "/api/hands/events")
 	if err != nil {
-		writeAPIError(w, http.StatusServiceUnavailable, err.Error())
+		p.writeUnavailable(w, r, tenantID, http.StatusServiceUnavailable, err.Error())
 		return
 	}
 
@@ -35,10 +134,11 @@ func handleHandsEvents(w http.ResponseWriter, r *http.Request) {
 	q.Set("tenant_id", tenantID)
 	target.RawQuery = q.Encode()
 
-	forwardHandsRequest(w, r, http.MethodGet, target, tenantID)
+	// The events stream is long-lived, so it is not bounded by a timeout.
+	p.forward(w, r, http.MethodGet, target, tenantID, 0)
 }
 
-func handleHandsApprove(w http.ResponseWriter, r *http.Request) {
+func (p *handsProxy) handleHandsApprove(w http.ResponseWriter, r *http.Request) {
 	handID := strings.TrimSpace(r.PathValue("id"))
 	actionID := strings.TrimSpace(r.PathValue("actionId"))
 	tenantID := strings.TrimSpace(r.Header.Get("X-Tenant-ID"))
@@ -54,16 +154,16 @@ func handleHandsApprove(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	target, err := buildHandsTarget(fmt.Sprintf("/api/hands/%s/approve/%s", url.PathEscape(handID), url.PathEscape(actionID)), nil)
+	target, err := p.buildHandsTarget(r.Context(), tenantID, fmt.Sprintf("/api/hands/%s/approve/%s", url.PathEscape(handID), url.PathEscape(actionID)))
 	if err != nil {
-		writeAPIError(w, http.StatusServiceUnavailable, err.Error())
+		p.writeUnavailable(w, r, tenantID, http.StatusServiceUnavailable, err.Error())
 		return
 	}
 
-	forwardHandsRequest(w, r, http.MethodPost, target, tenantID)
+	p.forward(w, r, http.MethodPost, target, tenantID, p.timeoutForTenant(r.Context(), tenantID))
 }
 
-func handleHandsReject(w http.ResponseWriter, r *http.Request) {
+func (p *handsProxy) handleHandsReject(w http.ResponseWriter, r *http.Request) {
 	handID := strings.TrimSpace(r.PathValue("id"))
 	actionID := strings.TrimSpace(r.PathValue("actionId"))
 	tenantID := strings.TrimSpace(r.Header.Get("X-Tenant-ID"))
@@ -79,19 +179,28 @@ func handleHandsReject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	target, err := buildHandsTarget(fmt.Sprintf("/api/hands/%s/reject/%s", url.PathEscape(handID), url.PathEscape(actionID)), nil)
+	target, err := p.buildHandsTarget(r.Context(), tenantID, fmt.Sprintf("/api/hands/%s/reject/%s", url.PathEscape(handID), url.PathEscape(actionID)))
 	if err != nil {
-		writeAPIError(w, http.StatusServiceUnavailable, err.Error())
+		p.writeUnavailable(w, r, tenantID, http.StatusServiceUnavailable, err.Error())
 		return
 	}
 
-	forwardHandsRequest(w, r, http.MethodPost, target, tenantID)
+	p.forward(w, r, http.MethodPost, target, tenantID, p.timeoutForTenant(r.Context(), tenantID))
 }
 
-func buildHandsTarget(path string, rawQuery url.Values) (*url.URL, error) {
+// buildHandsTarget resolves path against OPENFANG_API_URL when a shared
+// OpenFang API is configured, otherwise against the tenant's own server as
+// located by the orchestrator.
+func (p *handsProxy) buildHandsTarget(ctx context.Context, tenantID, path string) (*url.URL, error) {
 	base := strings.TrimSpace(os.Getenv("OPENFANG_API_URL"))
 	if base == "" {
-		return nil, fmt.Errorf("OPENFANG_API_URL is not configured")
+		p.idle.Touch(tenantID)
+		containerURL, err := p.tenantContainerURL(ctx, tenantID)
+		if err != nil {
+			slog.Warn("failed to resolve tenant OpenFang endpoint", "tenant", tenantID, "err", err)
+			return nil, fmt.Errorf("OpenFang API is not configured for tenant")
+		}
+		base = containerURL
 	}
 
 	baseURL, err := url.Parse(base)
@@ -100,24 +209,76 @@ func buildHandsTarget(path string, rawQuery url.Values) (*url.URL, error) {
 	}
 
 	rel := &url.URL{Path: path}
-	target := baseURL.ResolveReference(rel)
-	if rawQuery != nil {
-		target.RawQuery = rawQuery.Encode()
+	return baseURL.ResolveReference(rel), nil
+}
+
+// writeUnavailable answers a request the tenant's OpenFang server could not
+// take. When the tenant has its own container and it is only stopped, the
+// container is started and the client is told how long that should take
+// rather than getting a bare error.
+func (p *handsProxy) writeUnavailable(w http.ResponseWriter, r *http.Request, tenantID string, status int, message string) {
+	if p.waker != nil && strings.TrimSpace(os.Getenv("OPENFANG_API_URL")) == "" {
+		progress, err := p.waker.Wake(r.Context(), tenantID)
+		if err == nil && !progress.Ready {
+			writeAgentStarting(w, progress)
+			return
+		}
+		if err != nil && !errors.Is(err, orchestrator.ErrTenantNotActive) {
+			slog.Warn("tenant wake check failed", "tenant", tenantID, "err", err)
+		}
+	}
+	writeAPIError(w, status, message)
+}
+
+// writeAgentStarting is a 503 with Retry-After telling the client the agent
+// is on its way, with the start's progress so far.
+func writeAgentStarting(w http.ResponseWriter, progress orchestrator.WakeProgress) {
+	remaining := max(progress.Estimate-progress.Elapsed, time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(int((remaining+time.Second-1)/time.Second)))
+	writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+		"error":       fmt.Sprintf("Starting your agent (~%ds)", int(progress.Estimate.Round(time.Second)/time.Second)),
+		"status":      "starting",
+		"elapsed_ms":  progress.Elapsed.Milliseconds(),
+		"estimate_ms": progress.Estimate.Milliseconds(),
+	})
+}
+
+// tenantContainerURL returns the base URL of the tenant's OpenFang server.
+// main wraps the orchestrator in an orchestrator.EndpointCache, so steady
+// traffic is served from Redis rather than a container inspect per request.
+func (p *handsProxy) tenantContainerURL(ctx context.Context, tenantID string) (string, error) {
+	u, err := orchestrator.TenantEndpoint(ctx, p.orch, tenantID)
+	if err != nil {
+		return "", err
 	}
-	return target, nil
+	return u.String(), nil
 }
 
-func forwardHandsRequest(w http.ResponseWriter, r *http.Request, method string, target *url.URL, tenantID string) {
-	var body io.Reader
-	if r.Body != nil {
+// forward proxies r to target. A positive timeout bounds the whole
+// upstream exchange, including copying the response body. The request body is
+// streamed through unread, keeping the client's Content-Length when it sent
+// one and falling back to chunked encoding otherwise.
+func (p *handsProxy) forward(w http.ResponseWriter, r *http.Request, method string, target *url.URL, tenantID string, timeout time.Duration) {
+	body := io.Reader(http.NoBody)
+	if r.Body != nil && r.ContentLength != 0 {
 		body = r.Body
 	}
 
-	req, err := http.NewRequestWithContext(r.Context(), method, target.String(), body)
+	ctx := r.Context()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
 	if err != nil {
 		writeAPIError(w, http.StatusInternalServerError, "failed to create upstream request")
 		return
 	}
+	if body != http.NoBody {
+		req.ContentLength = r.ContentLength
+	}
 
 	if accept := strings.TrimSpace(r.Header.Get("Accept")); accept != "" {
 		req.Header.Set("Accept", accept)
@@ -131,9 +292,18 @@ func forwardHandsRequest(w http.ResponseWriter, r *http.Request, method string,
 		req.Header.Set("X-API-Key", apiKey)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := p.httpClient().Do(req)
 	if err != nil {
-		writeAPIError(w, http.StatusBadGateway, "failed to reach OpenFang API")
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeAPIError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", maxErr.Limit))
+			return
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			writeAPIError(w, http.StatusGatewayTimeout, "OpenFang API timed out")
+			return
+		}
+		p.writeUnavailable(w, r, tenantID, http.StatusBadGateway, "failed to reach OpenFang API")
 		return
 	}
 	defer resp.Body.Close()
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/internal/testkit"
+	"github.com/agentsquads/api/orchestrator"
+	"github.com/agentsquads/api/policies"
+)
+
+func TestDurationSecondsFromEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: defaultOpenFangTimeout},
+		{value: "90", want: 90 * time.Second},
+		{value: "0", want: defaultOpenFangTimeout},
+		{value: "soon", want: defaultOpenFangTimeout},
+	}
+	for _, tt := range tests {
+		t.Setenv("OPENFANG_TIMEOUT_SECONDS", tt.value)
+		if got := durationSecondsFromEnv("OPENFANG_TIMEOUT_SECONDS", defaultOpenFangTimeout); got != tt.want {
+			t.Fatalf("value %q: got %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestHandsProxyTimeoutForTenant(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	p := &handsProxy{policies: policies.NewStore(db), timeout: 30 * time.Second, extendedTimeout: 120 * time.Second}
+	mock.ExpectQuery("SELECT enabled").WithArgs("t1", policies.FeatureExtendedTimeout).WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(true))
+	mock.ExpectQuery("SELECT enabled").WithArgs("t2", policies.FeatureExtendedTimeout).WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(false))
+
+	if got := p.timeoutForTenant(context.Background(), "t1"); got != 120*time.Second {
+		t.Fatalf("extended tenant timeout = %s", got)
+	}
+	if got := p.timeoutForTenant(context.Background(), "t2"); got != 30*time.Second {
+		t.Fatalf("default tenant timeout = %s", got)
+	}
+	if got := (&handsProxy{timeout: 30 * time.Second}).timeoutForTenant(context.Background(), "t1"); got != 30*time.Second {
+		t.Fatalf("timeout without policies = %s", got)
+	}
+}
+
+func TestHandsApproveTimesOut(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer upstream.Close()
+	t.Setenv("OPENFANG_API_URL", upstream.URL)
+
+	p := &handsProxy{timeout: 20 * time.Millisecond}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/hands/{id}/approve/{actionId}", p.handleHandsApprove)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/hands/h1/approve/a1", nil)
+	req.Header.Set("X-Tenant-ID", "t1")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestForwardHandsRequestStreamsBody(t *testing.T) {
+	var gotLength int64
+	var gotBody string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotLength = r.ContentLength
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	t.Setenv("OPENFANG_API_URL", upstream.URL)
+
+	p := &handsProxy{timeout: time.Second}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/hands/{id}/approve/{actionId}", p.handleHandsApprove)
+	handler := applyRequestBodyLimit(mux)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/hands/h1/approve/a1", strings.NewReader(`{"note":"ok"}`))
+	req.Header.Set("X-Tenant-ID", "t1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || gotLength != int64(len(`{"note":"ok"}`)) || gotBody != `{"note":"ok"}` {
+		t.Fatalf("status = %d, upstream length = %d body = %q", w.Code, gotLength, gotBody)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/hands/h1/approve/a1", strings.NewReader(strings.Repeat("a", int(maxRequestBodyBytes)+1)))
+	req.Header.Set("X-Tenant-ID", "t1")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), `"error"`) {
+		t.Fatalf("oversized body status = %d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandsProxyResolvesTenantEndpoint(t *testing.T) {
+	var gotPath, gotTenant string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotTenant = r.URL.RequestURI(), r.Header.Get("X-Tenant-ID")
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "event: hand\ndata: {}\n\n")
+	}))
+	defer upstream.Close()
+	t.Setenv("OPENFANG_API_URL", "")
+
+	orch := testkit.NewFakeOrchestrator()
+	orch.AddTenant("t1", upstream.URL)
+	p := &handsProxy{orch: orch, client: upstream.Client(), timeout: time.Second}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/hands/events", p.handleHandsEvents)
+	mux.HandleFunc("POST /api/hands/{id}/approve/{actionId}", p.handleHandsApprove)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/hands/events?tenant_id=t1", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "event: hand\ndata: {}\n\n" {
+		t.Fatalf("status = %d body=%q", w.Code, w.Body.String())
+	}
+	if gotPath != "/api/hands/events?tenant_id=t1" || gotTenant != "t1" {
+		t.Fatalf("upstream request = %s tenant=%q", gotPath, gotTenant)
+	}
+
+	// A tenant without a container has no endpoint to proxy to.
+	req := httptest.NewRequest(http.MethodPost, "/api/hands/h1/approve/a1", nil)
+	req.Header.Set("X-Tenant-ID", "t2")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unknown tenant status = %d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandsProxyStartsStoppedTenant(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	t.Setenv("OPENFANG_API_URL", "")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mock.ExpectQuery("SELECT status FROM tenants").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("active"))
+
+	orch := testkit.NewFakeOrchestrator()
+	orch.AddTenant("t1", closed.URL)
+	if err := orch.SetStatus("t1", orchestrator.ContainerStatus{}); err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+	p := &handsProxy{orch: orch, timeout: time.Second, waker: orchestrator.NewWaker(db, orch)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/hands/{id}/approve/{actionId}", p.handleHandsApprove)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/hands/h1/approve/a1", nil)
+	req.Header.Set("X-Tenant-ID", "t1")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("status = %d headers = %v", w.Code, w.Header())
+	}
+	if !strings.Contains(w.Body.String(), `"status":"starting"`) || !strings.Contains(w.Body.String(), "Starting your agent (~20s)") {
+		t.Fatalf("body = %s", w.Body.String())
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if tenant, _ := orch.Tenant("t1"); tenant.Status.Running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("tenant was not started: calls = %v", orch.Calls())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
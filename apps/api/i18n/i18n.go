@@ -0,0 +1,219 @@
+// Package i18n translates the system messages the platform sends to tenant
+// channels: run updates from the agent swarm and replies to channel
+// commands. Translations ship as embedded JSON catalogs, one per locale, and
+// operators can override any of them per locale in the message_overrides
+// table. Lookups fall back to English, so a message missing from a catalog
+// is still sent.
+package i18n
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLocale is the base catalog every message id must exist in.
+const DefaultLocale = "en"
+
+// Message ids. Texts name their placeholders in braces, such as {run_id},
+// and callers pass them as key/value pairs.
+const (
+	MsgRunAccepted            = "run.accepted"
+	MsgRunFailed              = "run.failed"
+	MsgRunCompleted           = "run.completed"
+	MsgRunCompletedWithIssues = "run.completed_with_issues"
+	MsgRunCancelled           = "run.cancelled"
+	MsgRunPaused              = "run.paused"
+	MsgRunResumed             = "run.resumed"
+	MsgRunEvent               = "run.event"
+	MsgSubTaskRetrying        = "subtask.retrying"
+	MsgSubTaskReassigned      = "subtask.reassigned"
+	MsgSubTaskStatus          = "subtask.status"
+	MsgSubTaskSkipped         = "subtask.skipped"
+	MsgSubTaskNoHealthyAgent  = "subtask.no_healthy_agent"
+	MsgSubTaskSpawnFailed     = "subtask.spawn_failed"
+	MsgSubTaskQueuedFailed    = "subtask.queued_spawn_failed"
+	MsgSubTaskStarted         = "subtask.started"
+	MsgSubTaskQueuedStarted   = "subtask.queued_started"
+
+	MsgCommandRunStarted    = "command.run_started"
+	MsgCommandSwarmDisabled = "command.swarm_disabled"
+	MsgCommandTaskDropped   = "command.task_dropped"
+	MsgCommandClarify       = "command.clarify"
+
+	MsgNotificationsUnavailable  = "notifications.unavailable"
+	MsgNotificationsCurrent      = "notifications.current"
+	MsgNotificationsUnknownLevel = "notifications.unknown_level"
+	MsgNotificationsNotLinked    = "notifications.not_linked"
+	MsgNotificationsUpdated      = "notifications.updated"
+)
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// base holds the embedded catalogs by locale, then message id.
+var base = mustLoadCatalogs()
+
+func mustLoadCatalogs() map[string]map[string]string {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: read embedded catalogs: %v", err))
+	}
+	catalogs := make(map[string]map[string]string, len(entries))
+	for _, entry := range entries {
+		raw, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: read %s: %v", entry.Name(), err))
+		}
+		messages := map[string]string{}
+		if err := json.Unmarshal(raw, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: decode %s: %v", entry.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+	if _, ok := catalogs[DefaultLocale]; !ok {
+		panic("i18n: missing the " + DefaultLocale + " catalog")
+	}
+	return catalogs
+}
+
+// Locales returns the supported locales, sorted.
+func Locales() []string {
+	locales := make([]string, 0, len(base))
+	for locale := range base {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Normalize maps a language tag such as "pt-BR", "es_MX" or Telegram's
+// language_code to a supported locale, or returns "" when there is none.
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return ""
+	}
+	if _, ok := base[tag]; ok {
+		return tag
+	}
+	language, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	if _, ok := base[language]; ok {
+		return language
+	}
+	return ""
+}
+
+// Text returns message id from the embedded catalogs in locale, falling back
+// to English and then to the id itself. args are placeholder names and
+// values in pairs.
+func Text(locale, id string, args ...string) string {
+	return (*Catalog)(nil).Text(locale, id, args...)
+}
+
+// Catalog serves the embedded catalogs with the operator overrides from
+// message_overrides on top. Overrides are held in memory and replaced whole
+// by Reload. A nil Catalog serves the embedded catalogs only.
+type Catalog struct {
+	db        *sql.DB
+	overrides atomic.Pointer[map[string]map[string]string]
+}
+
+func NewCatalog(db *sql.DB) *Catalog {
+	c := &Catalog{db: db}
+	c.overrides.Store(&map[string]map[string]string{})
+	return c
+}
+
+// Text returns message id in locale, preferring an override, then falling
+// back to English and then to the id itself. args are placeholder names and
+// values in pairs.
+func (c *Catalog) Text(locale, id string, args ...string) string {
+	text, ok := c.lookup(Normalize(locale), id)
+	if !ok {
+		text, ok = c.lookup(DefaultLocale, id)
+	}
+	if !ok {
+		text = id
+	}
+	if len(args) < 2 {
+		return text
+	}
+	pairs := make([]string, 0, len(args))
+	for i := 0; i+1 < len(args); i += 2 {
+		pairs = append(pairs, "{"+args[i]+"}", args[i+1])
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+func (c *Catalog) lookup(locale, id string) (string, bool) {
+	if locale == "" {
+		return "", false
+	}
+	if c != nil {
+		if text, ok := (*c.overrides.Load())[locale][id]; ok {
+			return text, true
+		}
+	}
+	text, ok := base[locale][id]
+	return text, ok
+}
+
+// Reload reads message_overrides and swaps them in, returning how many are
+// in use. Overrides for unknown message ids or unsupported locales are
+// skipped. On error the current overrides are kept.
+func (c *Catalog) Reload(ctx context.Context) (int, error) {
+	rows, err := c.db.QueryContext(ctx, `SELECT message_id, locale, text FROM message_overrides`)
+	if err != nil {
+		return 0, fmt.Errorf("load message overrides: %w", err)
+	}
+	defer rows.Close()
+
+	next := map[string]map[string]string{}
+	count := 0
+	for rows.Next() {
+		var id, locale, text string
+		if err := rows.Scan(&id, &locale, &text); err != nil {
+			return 0, fmt.Errorf("scan message override: %w", err)
+		}
+		normalized := Normalize(locale)
+		if _, known := base[DefaultLocale][id]; !known || normalized == "" {
+			slog.Warn("ignoring message override", "message_id", id, "locale", locale)
+			continue
+		}
+		if next[normalized] == nil {
+			next[normalized] = map[string]string{}
+		}
+		next[normalized][id] = text
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("load message overrides: %w", err)
+	}
+	c.overrides.Store(&next)
+	return count, nil
+}
+
+// Start reloads the overrides every interval until ctx is done.
+func (c *Catalog) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.Reload(ctx); err != nil {
+				slog.Warn("message overrides reload failed", "err", err)
+			}
+		}
+	}
+}
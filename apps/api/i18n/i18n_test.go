@@ -0,0 +1,170 @@
+package i18n
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestText(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		locale, id string
+		args       []string
+		want       string
+	}{
+		{locale: "es", id: MsgRunAccepted, want: "Ejecución del equipo de agentes aceptada."},
+		{locale: "pt-BR", id: MsgCommandRunStarted, args: []string{"run_id", "r1"}, want: "Equipe de agentes iniciada (`r1`). Vou enviar as atualizações de progresso aqui."},
+		{locale: "fr", id: MsgSubTaskStatus, args: []string{"subtask_id", "s1", "status", "complete"}, want: "Subtask s1 is complete."},
+		{locale: "", id: MsgRunFailed, want: "Swarm execution failed. Reply with /agent run <task> to retry."},
+		{locale: "es", id: "no.such.message", want: "no.such.message"},
+	}
+	for _, tt := range tests {
+		if got := Text(tt.locale, tt.id, tt.args...); got != tt.want {
+			t.Errorf("Text(%q, %q) = %q, want %q", tt.locale, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+	for tag, want := range map[string]string{"es": "es", "PT_br": "pt", "en-GB": "en", "fr": "", "": ""} {
+		if got := Normalize(tag); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestCatalogReload(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT message_id, locale, text FROM message_overrides").WillReturnRows(
+		sqlmock.NewRows([]string{"message_id", "locale", "text"}).
+			AddRow(MsgRunAccepted, "es", "¡Listo! Empezamos.").
+			AddRow("no.such.message", "es", "ignored").
+			AddRow(MsgRunAccepted, "fr", "ignored"))
+	c := NewCatalog(db)
+	n, err := c.Reload(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("Reload() = %d, %v", n, err)
+	}
+	if got := c.Text("es-MX", MsgRunAccepted); got != "¡Listo! Empezamos." {
+		t.Fatalf("override = %q", got)
+	}
+	if got := c.Text("pt", MsgRunAccepted); got != "Execução da equipe de agentes aceita." {
+		t.Fatalf("other locale = %q", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+var placeholderPattern = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// TestCatalogsCoverMessageIDs checks that every message id the code uses is
+// in the base catalog, and that translations only use known ids and the same
+// placeholders as English.
+func TestCatalogsCoverMessageIDs(t *testing.T) {
+	t.Parallel()
+	declared := declaredMessageIDs(t)
+	for name, id := range declared {
+		if _, ok := base[DefaultLocale][id]; !ok {
+			t.Errorf("%s (%q) is missing from the %s catalog", name, id, DefaultLocale)
+		}
+	}
+
+	used := 0
+	err := filepath.WalkDir("..", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok || !strings.HasPrefix(sel.Sel.Name, "Msg") {
+				return true
+			}
+			if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "i18n" {
+				return true
+			}
+			used++
+			if _, ok := declared[sel.Sel.Name]; !ok {
+				t.Errorf("%s uses undeclared message id i18n.%s", path, sel.Sel.Name)
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk sources: %v", err)
+	}
+	if used == 0 {
+		t.Fatalf("found no message ids in use")
+	}
+
+	for locale, messages := range base {
+		for id, text := range messages {
+			english, ok := base[DefaultLocale][id]
+			if !ok {
+				t.Errorf("%s catalog has unknown message id %q", locale, id)
+				continue
+			}
+			if got, want := placeholders(text), placeholders(english); got != want {
+				t.Errorf("%s %q uses placeholders %s, want %s", locale, id, got, want)
+			}
+		}
+	}
+}
+
+// declaredMessageIDs returns the Msg constants of this package by name.
+func declaredMessageIDs(t *testing.T) map[string]string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "i18n.go", nil, 0)
+	if err != nil {
+		t.Fatalf("parse i18n.go: %v", err)
+	}
+	ids := map[string]string{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok {
+			return true
+		}
+		for i, name := range spec.Names {
+			if !strings.HasPrefix(name.Name, "Msg") || i >= len(spec.Values) {
+				continue
+			}
+			if lit, ok := spec.Values[i].(*ast.BasicLit); ok {
+				ids[name.Name], _ = strconv.Unquote(lit.Value)
+			}
+		}
+		return true
+	})
+	return ids
+}
+
+func placeholders(text string) string {
+	found := placeholderPattern.FindAllString(text, -1)
+	sort.Strings(found)
+	return strings.Join(found, ",")
+}
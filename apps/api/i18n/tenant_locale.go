@@ -0,0 +1,46 @@
+package i18n
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrTenantNotFound is returned for a tenant that does not exist.
+var ErrTenantNotFound = errors.New("tenant not found")
+
+// TenantLocales stores the locale each tenant's system messages are sent in.
+type TenantLocales struct {
+	db *sql.DB
+}
+
+func NewTenantLocales(db *sql.DB) *TenantLocales {
+	return &TenantLocales{db: db}
+}
+
+// TenantLocale returns the tenant's locale, or "" when it has not set one.
+func (s *TenantLocales) TenantLocale(ctx context.Context, tenantID string) (string, error) {
+	var locale string
+	err := s.db.QueryRowContext(ctx, `SELECT COALESCE(locale, '') FROM tenants WHERE id = $1`, tenantID).Scan(&locale)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrTenantNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("load tenant locale: %w", err)
+	}
+	return locale, nil
+}
+
+// SetTenantLocale sets the tenant's locale. An empty locale clears it, so
+// messages follow the channel user's language again.
+func (s *TenantLocales) SetTenantLocale(ctx context.Context, tenantID, locale string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE tenants SET locale = NULLIF($2, '') WHERE id = $1`, tenantID, locale)
+	if err != nil {
+		return fmt.Errorf("update tenant locale: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrTenantNotFound
+	}
+	return nil
+}
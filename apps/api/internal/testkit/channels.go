@@ -0,0 +1,122 @@
+package testkit
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentsquads/api/channels"
+)
+
+// FakeSender is a channels.Sender that records every message it is given.
+// Register it on a Fanout for the channel under test.
+type FakeSender struct {
+	// Err, when set, is returned for every send after the message is
+	// recorded.
+	Err error
+
+	mu   sync.Mutex
+	sent []channels.OutboundMessage
+}
+
+func (s *FakeSender) Send(_ context.Context, _ channels.TenantChannel, out channels.OutboundMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, out)
+	return s.Err
+}
+
+// Sent returns the messages sent so far.
+func (s *FakeSender) Sent() []channels.OutboundMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]channels.OutboundMessage(nil), s.sent...)
+}
+
+// MemoryLinks is an in-memory channels.ChannelLinks.
+type MemoryLinks struct {
+	mu    sync.Mutex
+	links map[string][]channels.TenantChannel // tenant id -> links
+}
+
+func NewMemoryLinks() *MemoryLinks {
+	return &MemoryLinks{links: make(map[string][]channels.TenantChannel)}
+}
+
+// Link adds or replaces the tenant's link for channel.
+func (m *MemoryLinks) Link(tenantID, channel, channelUserID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	links := m.links[tenantID]
+	for i := range links {
+		if links[i].Channel == channel {
+			links[i].ChannelUserID, links[i].Muted, links[i].LinkedAt = channelUserID, false, time.Now()
+			return
+		}
+	}
+	m.links[tenantID] = append(links, channels.TenantChannel{
+		ID:                tenantID + "-" + channel,
+		TenantID:          tenantID,
+		Channel:           channel,
+		ChannelUserID:     channelUserID,
+		LinkedAt:          time.Now(),
+		NotificationLevel: channels.DefaultNotificationLevel,
+	})
+}
+
+// Mute sets whether the tenant's channel link is muted.
+func (m *MemoryLinks) Mute(tenantID, channel string, muted bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.links[tenantID] {
+		if m.links[tenantID][i].Channel == channel {
+			m.links[tenantID][i].Muted = muted
+		}
+	}
+}
+
+func (m *MemoryLinks) GetChannels(tenantID string) ([]channels.TenantChannel, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]channels.TenantChannel{}, m.links[tenantID]...), nil
+}
+
+// MemoryCredentials is an in-memory channels.ChannelCredentials.
+type MemoryCredentials struct {
+	mu    sync.Mutex
+	creds map[string]channels.ChannelCredential // tenant id + "/" + channel
+}
+
+func NewMemoryCredentials() *MemoryCredentials {
+	return &MemoryCredentials{creds: make(map[string]channels.ChannelCredential)}
+}
+
+// Set stores config as the tenant's credentials for channel.
+func (m *MemoryCredentials) Set(tenantID, channel string, config map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.creds[tenantID+"/"+channel] = channels.ChannelCredential{
+		TenantID:  tenantID,
+		Channel:   channel,
+		Config:    config,
+		UpdatedAt: time.Now(),
+	}
+}
+
+func (m *MemoryCredentials) GetByTenantChannel(_ context.Context, tenantID, channel string) (channels.ChannelCredential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cred, ok := m.creds[tenantID+"/"+strings.ToLower(strings.TrimSpace(channel))]
+	if !ok {
+		return channels.ChannelCredential{}, sql.ErrNoRows
+	}
+	return cred, nil
+}
+
+var (
+	_ channels.Sender             = (*FakeSender)(nil)
+	_ channels.ChannelLinks       = (*MemoryLinks)(nil)
+	_ channels.ChannelCredentials = (*MemoryCredentials)(nil)
+)
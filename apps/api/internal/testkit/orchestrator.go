@@ -0,0 +1,220 @@
+// Package testkit provides in-memory stand-ins for the orchestrator and the
+// channel stores and senders, so handlers can be tested without Docker,
+// Postgres or Redis.
+package testkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentsquads/api/orchestrator"
+)
+
+// ErrNoContainer is returned for tenants the fake has no container for. Its
+// text matches the Docker orchestrator's, so handlers treat it the same way.
+var ErrNoContainer = errors.New("no container for tenant")
+
+// FakeTenant is one tenant container held by FakeOrchestrator.
+type FakeTenant struct {
+	Container orchestrator.Container
+	Status    orchestrator.ContainerStatus
+	// Endpoint is the base URL of the tenant's OpenFang server, usually an
+	// httptest server. Empty means the endpoint cannot be resolved.
+	Endpoint string
+}
+
+// FakeOrchestrator is an in-memory orchestrator.TenantOrchestrator. Create,
+// Start, Stop and Delete update its tenant table, and statuses can be set
+// directly with SetStatus. It also resolves endpoints and lists tenants like
+// the Docker backend.
+type FakeOrchestrator struct {
+	// Err, when set, is returned by every call instead of touching the
+	// tenant table.
+	Err error
+	// ExecFunc answers Exec. Without it Exec returns an empty output.
+	ExecFunc func(tenantID string, cmd []string) (string, error)
+
+	mu      sync.Mutex
+	tenants map[string]*FakeTenant
+	calls   []string
+}
+
+func NewFakeOrchestrator() *FakeOrchestrator {
+	return &FakeOrchestrator{tenants: make(map[string]*FakeTenant)}
+}
+
+// AddTenant adds a running, healthy tenant whose OpenFang server is at
+// endpoint.
+func (o *FakeOrchestrator) AddTenant(tenantID, endpoint string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.tenants[tenantID] = &FakeTenant{
+		Container: orchestrator.Container{ID: "fake-" + tenantID, TenantID: tenantID, Status: "running", Port: 4200},
+		Status:    orchestrator.ContainerStatus{Running: true, StartedAt: time.Now(), Health: "healthy"},
+		Endpoint:  endpoint,
+	}
+}
+
+// SetStatus replaces the status Status reports for tenantID.
+func (o *FakeOrchestrator) SetStatus(tenantID string, status orchestrator.ContainerStatus) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	t, ok := o.tenants[tenantID]
+	if !ok {
+		return ErrNoContainer
+	}
+	t.Status = status
+	return nil
+}
+
+// Tenant returns a copy of the tenant's state.
+func (o *FakeOrchestrator) Tenant(tenantID string) (FakeTenant, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	t, ok := o.tenants[tenantID]
+	if !ok {
+		return FakeTenant{}, false
+	}
+	return *t, true
+}
+
+// Calls returns the calls made so far as "method:tenant", in order.
+func (o *FakeOrchestrator) Calls() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]string(nil), o.calls...)
+}
+
+// begin records a call and returns the tenant it targets, or an error when
+// Err is set or the tenant is unknown and must exist.
+func (o *FakeOrchestrator) begin(method, tenantID string, mustExist bool) (*FakeTenant, error) {
+	o.calls = append(o.calls, method+":"+tenantID)
+	if o.Err != nil {
+		return nil, o.Err
+	}
+	t, ok := o.tenants[tenantID]
+	if !ok && mustExist {
+		return nil, ErrNoContainer
+	}
+	return t, nil
+}
+
+func (o *FakeOrchestrator) Create(_ context.Context, tenantID string) (*orchestrator.Container, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, err := o.begin("create", tenantID, false); err != nil {
+		return nil, err
+	}
+	t := &FakeTenant{Container: orchestrator.Container{ID: "fake-" + tenantID, TenantID: tenantID, Status: "created", Port: 4200}}
+	o.tenants[tenantID] = t
+	c := t.Container
+	return &c, nil
+}
+
+func (o *FakeOrchestrator) Start(_ context.Context, tenantID string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	t, err := o.begin("start", tenantID, true)
+	if err != nil {
+		return err
+	}
+	t.Container.Status = "running"
+	t.Status = orchestrator.ContainerStatus{Running: true, StartedAt: time.Now(), Health: "healthy"}
+	return nil
+}
+
+func (o *FakeOrchestrator) Stop(_ context.Context, tenantID string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	t, err := o.begin("stop", tenantID, true)
+	if err != nil {
+		return err
+	}
+	t.Container.Status = "exited"
+	t.Status = orchestrator.ContainerStatus{}
+	return nil
+}
+
+func (o *FakeOrchestrator) Delete(_ context.Context, tenantID string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, err := o.begin("delete", tenantID, true); err != nil {
+		return err
+	}
+	delete(o.tenants, tenantID)
+	return nil
+}
+
+func (o *FakeOrchestrator) Status(_ context.Context, tenantID string) (*orchestrator.ContainerStatus, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	t, err := o.begin("status", tenantID, true)
+	if err != nil {
+		return nil, err
+	}
+	st := t.Status
+	return &st, nil
+}
+
+func (o *FakeOrchestrator) Exec(_ context.Context, tenantID string, cmd []string) (string, error) {
+	o.mu.Lock()
+	_, err := o.begin("exec", tenantID, true)
+	fn := o.ExecFunc
+	o.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	if fn == nil {
+		return "", nil
+	}
+	return fn(tenantID, cmd)
+}
+
+// Endpoint implements orchestrator.EndpointResolver.
+func (o *FakeOrchestrator) Endpoint(_ context.Context, tenantID string) (*url.URL, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	t, err := o.begin("endpoint", tenantID, true)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(t.Endpoint) == "" {
+		return nil, fmt.Errorf("no endpoint for tenant %s", tenantID)
+	}
+	return url.Parse(t.Endpoint)
+}
+
+// ListTenants implements orchestrator.TenantLister, sorted by tenant id.
+func (o *FakeOrchestrator) ListTenants(context.Context) ([]orchestrator.TenantContainer, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.Err != nil {
+		return nil, o.Err
+	}
+	list := make([]orchestrator.TenantContainer, 0, len(o.tenants))
+	for id, t := range o.tenants {
+		list = append(list, orchestrator.TenantContainer{
+			TenantID:    id,
+			ContainerID: t.Container.ID,
+			Status:      t.Container.Status,
+			Port:        t.Container.Port,
+			Healthy:     t.Status.Health == "healthy",
+			MemoryMB:    t.Status.MemoryMB,
+			CPUPct:      t.Status.CPUPct,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].TenantID < list[j].TenantID })
+	return list, nil
+}
+
+var (
+	_ orchestrator.TenantOrchestrator = (*FakeOrchestrator)(nil)
+	_ orchestrator.EndpointResolver   = (*FakeOrchestrator)(nil)
+	_ orchestrator.TenantLister       = (*FakeOrchestrator)(nil)
+)
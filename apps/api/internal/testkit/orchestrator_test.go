@@ -0,0 +1,54 @@
+package testkit
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/agentsquads/api/orchestrator"
+)
+
+func TestFakeOrchestratorLifecycle(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	o := NewFakeOrchestrator()
+
+	if _, err := o.Status(ctx, "t1"); !errors.Is(err, ErrNoContainer) {
+		t.Fatalf("status of unknown tenant err = %v", err)
+	}
+	if _, err := o.Create(ctx, "t1"); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if st, _ := o.Status(ctx, "t1"); st.Running {
+		t.Fatal("created container reported running")
+	}
+	if err := o.Start(ctx, "t1"); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := o.SetStatus("t1", orchestrator.ContainerStatus{Running: true, Health: "unhealthy"}); err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+	if st, _ := o.Status(ctx, "t1"); !st.Running || st.Health != "unhealthy" {
+		t.Fatalf("scripted status = %+v", st)
+	}
+
+	o.AddTenant("t2", "http://openfang.test")
+	if u, err := orchestrator.TenantEndpoint(ctx, o, "t2"); err != nil || u.Host != "openfang.test" {
+		t.Fatalf("endpoint = %v, %v", u, err)
+	}
+	if list, _ := o.ListTenants(ctx); len(list) != 2 || list[0].TenantID != "t1" || !list[1].Healthy {
+		t.Fatalf("ListTenants = %+v", list)
+	}
+
+	if err := o.Delete(ctx, "t1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := o.Tenant("t1"); ok {
+		t.Fatal("deleted tenant still present")
+	}
+	want := []string{"status:t1", "create:t1", "status:t1", "start:t1", "status:t1", "endpoint:t2", "delete:t1"}
+	if got := o.Calls(); !slices.Equal(got, want) {
+		t.Fatalf("calls = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,70 @@
+// Package jobs runs best-effort background work off the request path.
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Runner runs keyed jobs in goroutines. Jobs outlive the request that
+// scheduled them but keep its values, and at most one job per key runs at a
+// time.
+type Runner struct {
+	timeout time.Duration
+	log     *slog.Logger
+
+	mu      sync.Mutex
+	running map[string]struct{}
+	wg      sync.WaitGroup
+}
+
+// NewRunner creates a runner whose jobs are cancelled after timeout.
+func NewRunner(timeout time.Duration) *Runner {
+	return &Runner{
+		timeout: timeout,
+		log:     slog.Default().With("component", "jobs"),
+		running: make(map[string]struct{}),
+	}
+}
+
+// Go starts fn in the background and reports whether it was started; it is
+// skipped while a job with the same key is still running. Errors and panics
+// are logged, never returned to the caller.
+func (r *Runner) Go(ctx context.Context, key string, fn func(ctx context.Context) error) bool {
+	r.mu.Lock()
+	if _, busy := r.running[key]; busy {
+		r.mu.Unlock()
+		return false
+	}
+	r.running[key] = struct{}{}
+	r.wg.Add(1)
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.timeout)
+	go func() {
+		defer r.wg.Done()
+		defer func() {
+			r.mu.Lock()
+			delete(r.running, key)
+			r.mu.Unlock()
+		}()
+		defer cancel()
+		defer func() {
+			if p := recover(); p != nil {
+				r.log.Error("background job panicked", "key", key, "panic", p)
+			}
+		}()
+
+		if err := fn(ctx); err != nil {
+			r.log.Warn("background job failed", "key", key, "err", err)
+		}
+	}()
+	return true
+}
+
+// Wait blocks until every started job has finished.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
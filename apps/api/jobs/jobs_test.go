@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunnerSkipsBusyKey(t *testing.T) {
+	t.Parallel()
+	r := NewRunner(time.Second)
+	release := make(chan struct{})
+	var runs atomic.Int32
+
+	if !r.Go(context.Background(), "k", func(context.Context) error {
+		runs.Add(1)
+		<-release
+		return nil
+	}) {
+		t.Fatalf("first job was not started")
+	}
+	if r.Go(context.Background(), "k", func(context.Context) error { runs.Add(1); return nil }) {
+		t.Fatalf("second job with a busy key was started")
+	}
+	close(release)
+	r.Wait()
+
+	if !r.Go(context.Background(), "k", func(context.Context) error { runs.Add(1); return errors.New("logged") }) {
+		t.Fatalf("job after the key was released was not started")
+	}
+	r.Wait()
+	if got := runs.Load(); got != 2 {
+		t.Fatalf("runs = %d, want 2", got)
+	}
+}
+
+func TestRunnerDetachesFromCallerAndRecovers(t *testing.T) {
+	t.Parallel()
+	r := NewRunner(time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var ctxErr error
+	r.Go(ctx, "detached", func(ctx context.Context) error {
+		ctxErr = ctx.Err()
+		return nil
+	})
+	r.Go(ctx, "panics", func(context.Context) error { panic("boom") })
+	r.Wait()
+	if ctxErr != nil {
+		t.Fatalf("job saw caller cancellation: %v", ctxErr)
+	}
+}
@@ -0,0 +1,180 @@
+// Package keyring encrypts stored credentials with versioned AES-256-GCM keys
+// so the encryption key can be rotated.
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Payloads are "<key id>:<iv>:<ciphertext>:<tag>" with base64 parts. Legacy
+// payloads written before key versioning omit the key id and are tried
+// against every configured key.
+const (
+	ivBytes  = 12
+	tagBytes = 16
+)
+
+var (
+	ErrNotConfigured = errors.New("ENCRYPTION_KEY is not configured")
+	ErrInvalidKey    = errors.New("encryption keys must be 32-byte hex strings")
+	ErrUnknownKey    = errors.New("payload was encrypted with an unknown key")
+	ErrInvalidFormat = errors.New("invalid encrypted payload format")
+	ErrDecrypt       = errors.New("payload could not be decrypted")
+)
+
+// Keyring holds the primary key used for encryption and the retired keys
+// still accepted for decryption.
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+	order   []string
+}
+
+// FromEnv builds a keyring from ENCRYPTION_KEY (primary) and
+// ENCRYPTION_RETIRED_KEYS (comma-separated, decryption only).
+func FromEnv() (*Keyring, error) {
+	primary := strings.TrimSpace(os.Getenv("ENCRYPTION_KEY"))
+	if primary == "" {
+		return nil, ErrNotConfigured
+	}
+	var retired []string
+	for _, raw := range strings.Split(os.Getenv("ENCRYPTION_RETIRED_KEYS"), ",") {
+		if raw = strings.TrimSpace(raw); raw != "" {
+			retired = append(retired, raw)
+		}
+	}
+	return New(primary, retired...)
+}
+
+// New builds a keyring from hex-encoded 32-byte keys.
+func New(primaryHex string, retiredHex ...string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD)}
+	for i, keyHex := range append([]string{primaryHex}, retiredHex...) {
+		key, err := hex.DecodeString(strings.TrimSpace(keyHex))
+		if err != nil || len(key) != 32 {
+			return nil, ErrInvalidKey
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		id := KeyID(key)
+		if i == 0 {
+			k.primary = id
+		}
+		if _, dup := k.keys[id]; !dup {
+			k.keys[id] = aead
+			k.order = append(k.order, id)
+		}
+	}
+	return k, nil
+}
+
+// KeyID derives the stable identifier stored in payloads from the key
+// itself, so keys need no separate naming.
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return "k" + hex.EncodeToString(sum[:4])
+}
+
+// PrimaryID is the id of the key new payloads are encrypted with.
+func (k *Keyring) PrimaryID() string {
+	return k.primary
+}
+
+// Encrypt seals plaintext with the primary key.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	iv := make([]byte, ivBytes)
+	if _, err := rand.Read(iv); err != nil {
+		return "", fmt.Errorf("generate iv: %w", err)
+	}
+	aead := k.keys[k.primary]
+	sealed := aead.Seal(nil, iv, []byte(plaintext), nil)
+	tagStart := len(sealed) - aead.Overhead()
+	return strings.Join([]string{
+		k.primary,
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(sealed[:tagStart]),
+		base64.StdEncoding.EncodeToString(sealed[tagStart:]),
+	}, ":"), nil
+}
+
+// Decrypt opens a payload written with any key in the keyring.
+func (k *Keyring) Decrypt(payload string) (string, error) {
+	keyID, iv, sealed, err := parse(payload)
+	if err != nil {
+		return "", err
+	}
+	if keyID != "" {
+		aead, ok := k.keys[keyID]
+		if !ok {
+			return "", ErrUnknownKey
+		}
+		plaintext, err := aead.Open(nil, iv, sealed, nil)
+		if err != nil {
+			return "", ErrDecrypt
+		}
+		return string(plaintext), nil
+	}
+	for _, id := range k.order {
+		if plaintext, err := k.keys[id].Open(nil, iv, sealed, nil); err == nil {
+			return string(plaintext), nil
+		}
+	}
+	return "", ErrDecrypt
+}
+
+// IsCurrent reports whether payload is already encrypted with the primary
+// key.
+func (k *Keyring) IsCurrent(payload string) bool {
+	keyID, _, _, err := parse(payload)
+	return err == nil && keyID == k.primary
+}
+
+// IsEncrypted reports whether s has the shape of an encrypted payload, as
+// opposed to a plaintext value stored alongside encrypted ones.
+func IsEncrypted(s string) bool {
+	_, _, _, err := parse(s)
+	return err == nil
+}
+
+func parse(payload string) (keyID string, iv, sealed []byte, err error) {
+	parts := strings.Split(strings.TrimSpace(payload), ":")
+	switch len(parts) {
+	case 3:
+	case 4:
+		keyID, parts = parts[0], parts[1:]
+		if keyID == "" {
+			return "", nil, nil, ErrInvalidFormat
+		}
+	default:
+		return "", nil, nil, ErrInvalidFormat
+	}
+
+	decoded := make([][]byte, 3)
+	for i, part := range parts {
+		if part == "" && i != 1 {
+			return "", nil, nil, ErrInvalidFormat
+		}
+		if decoded[i], err = base64.StdEncoding.DecodeString(part); err != nil {
+			return "", nil, nil, ErrInvalidFormat
+		}
+	}
+	if len(decoded[0]) != ivBytes || len(decoded[2]) != tagBytes {
+		return "", nil, nil, ErrInvalidFormat
+	}
+	return keyID, decoded[0], append(decoded[1], decoded[2]...), nil
+}
@@ -0,0 +1,102 @@
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const (
+	oldKey = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	newKey = "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+)
+
+// legacyPayload encrypts the way the web app did before key ids existed.
+func legacyPayload(t *testing.T, keyHex, plaintext string) string {
+	t.Helper()
+	key, _ := hex.DecodeString(keyHex)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv := make([]byte, 12)
+	sealed := aead.Seal(nil, iv, []byte(plaintext), nil)
+	tag := len(sealed) - aead.Overhead()
+	return base64.StdEncoding.EncodeToString(iv) + ":" +
+		base64.StdEncoding.EncodeToString(sealed[:tag]) + ":" +
+		base64.StdEncoding.EncodeToString(sealed[tag:])
+}
+
+func TestKeyringRoundTripAndRotation(t *testing.T) {
+	t.Parallel()
+	before, err := New(oldKey)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	payload, err := before.Encrypt("vercel-token")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !strings.HasPrefix(payload, before.PrimaryID()+":") || !before.IsCurrent(payload) {
+		t.Fatalf("payload %q lacks primary key id %s", payload, before.PrimaryID())
+	}
+
+	after, err := New(newKey, oldKey)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if after.IsCurrent(payload) {
+		t.Fatalf("payload under retired key reported current")
+	}
+	if got, err := after.Decrypt(payload); err != nil || got != "vercel-token" {
+		t.Fatalf("Decrypt with retired key = %q, %v", got, err)
+	}
+	if got, err := after.Decrypt(legacyPayload(t, oldKey, "legacy")); err != nil || got != "legacy" {
+		t.Fatalf("Decrypt legacy = %q, %v", got, err)
+	}
+
+	dropped, _ := New(newKey)
+	if _, err := dropped.Decrypt(payload); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("err = %v, want ErrUnknownKey", err)
+	}
+	if _, err := dropped.Decrypt(legacyPayload(t, oldKey, "legacy")); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("err = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestNewRejectsInvalidKeys(t *testing.T) {
+	t.Parallel()
+	for _, keys := range [][]string{{"short"}, {oldKey, "zz"}} {
+		if _, err := New(keys[0], keys[1:]...); !errors.Is(err, ErrInvalidKey) {
+			t.Fatalf("New(%q) err = %v, want ErrInvalidKey", keys, err)
+		}
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	t.Parallel()
+	k, _ := New(oldKey)
+	current, _ := k.Encrypt("x")
+	tests := map[string]bool{
+		current:                          true,
+		legacyPayload(t, oldKey, "x"):    true,
+		"123456:ABC-DEF":                 false,
+		"https://example.com:443/path":   false,
+		"plain-webhook-secret":           false,
+		"a:b:c":                          false,
+		legacyPayload(t, oldKey, "")[:5]: false,
+	}
+	for value, want := range tests {
+		if got := IsEncrypted(value); got != want {
+			t.Fatalf("IsEncrypted(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,492 @@
+package keyring
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/jobs"
+)
+
+const (
+	rotationJobKey       = "crypto-rotation"
+	rotationTimeout      = time.Hour
+	defaultRotationBatch = 100
+	maxReportedFailures  = 100
+)
+
+var ErrRotationNotFound = errors.New("rotation not found")
+
+// rotationTarget is a table holding encrypted values. Each of columns holds
+// one payload; jsonColumn holds an object whose string fields may be
+// payloads, next to plaintext settings that are left alone.
+type rotationTarget struct {
+	table      string
+	columns    []string
+	jsonColumn string
+}
+
+// rotationTargets lists every table with encrypted values. Add new encrypted
+// columns here so rotations cover them; the table needs a uuid id column,
+// which rotations page by.
+var rotationTargets = []rotationTarget{
+	{table: "deploy_connections", columns: []string{"access_token_encrypted", "refresh_token_encrypted"}},
+	{table: "channel_credentials", jsonColumn: "config"},
+	{table: "tenant_tools", columns: []string{"auth_secret_encrypted", "signing_secret_encrypted"}},
+	{table: "deploy_env_vars", columns: []string{"value_encrypted"}},
+}
+
+// Rotation is the state of a re-encryption run.
+type Rotation struct {
+	ID          string            `json:"id"`
+	TargetKeyID string            `json:"target_key_id"`
+	Status      string            `json:"status"`
+	Error       string            `json:"error,omitempty"`
+	StartedAt   time.Time         `json:"started_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	FinishedAt  *time.Time        `json:"finished_at,omitempty"`
+	Tables      []TableProgress   `json:"tables,omitempty"`
+	Failures    []RotationFailure `json:"failures,omitempty"`
+}
+
+// TableProgress counts the rows a run has processed in one table.
+type TableProgress struct {
+	Table   string `json:"table"`
+	Scanned int    `json:"scanned"`
+	Rotated int    `json:"rotated"`
+	Failed  int    `json:"failed"`
+	Done    bool   `json:"done"`
+}
+
+// RotationFailure is a value that could not be decrypted with any configured
+// key. The row keeps its old payload.
+type RotationFailure struct {
+	Table     string    `json:"table"`
+	RowID     string    `json:"row_id"`
+	Column    string    `json:"column"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Rotator re-encrypts stored credentials with the primary key in the
+// background, one batch per transaction so an interrupted run resumes where
+// it stopped.
+type Rotator struct {
+	db        *sql.DB
+	keys      *Keyring
+	jobs      *jobs.Runner
+	batchSize int
+	log       *slog.Logger
+}
+
+func NewRotator(db *sql.DB, keys *Keyring) *Rotator {
+	return &Rotator{
+		db:        db,
+		keys:      keys,
+		jobs:      jobs.NewRunner(rotationTimeout),
+		batchSize: defaultRotationBatch,
+		log:       slog.Default().With("component", "key-rotation"),
+	}
+}
+
+// Start resumes the unfinished run for the primary key, or begins a new one,
+// and processes it in the background. A run left for a different primary key
+// is marked failed and superseded.
+func (r *Rotator) Start(ctx context.Context) (Rotation, error) {
+	id, err := r.claim(ctx)
+	if err != nil {
+		return Rotation{}, err
+	}
+	r.jobs.Go(ctx, rotationJobKey, func(ctx context.Context) error {
+		return r.run(ctx, id)
+	})
+	return r.Get(ctx, id)
+}
+
+func (r *Rotator) claim(ctx context.Context) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("begin rotation: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id, target string
+	err = tx.QueryRowContext(ctx, `
+		SELECT id::text, target_key_id
+		FROM crypto_rotations
+		WHERE status = 'running'
+		FOR UPDATE
+	`).Scan(&id, &target)
+	switch {
+	case err == nil && target == r.keys.PrimaryID():
+		return id, tx.Commit()
+	case err == nil:
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE crypto_rotations
+			SET status = 'failed', error = $2, updated_at = NOW(), finished_at = NOW()
+			WHERE id = $1
+		`, id, "superseded by rotation to key "+r.keys.PrimaryID()); err != nil {
+			return "", fmt.Errorf("supersede rotation: %w", err)
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return "", fmt.Errorf("load running rotation: %w", err)
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO crypto_rotations (target_key_id)
+		VALUES ($1)
+		RETURNING id::text
+	`, r.keys.PrimaryID()).Scan(&id); err != nil {
+		return "", fmt.Errorf("create rotation: %w", err)
+	}
+	for _, target := range rotationTargets {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO crypto_rotation_progress (rotation_id, table_name)
+			VALUES ($1, $2)
+		`, id, target.table); err != nil {
+			return "", fmt.Errorf("create rotation progress: %w", err)
+		}
+	}
+	return id, tx.Commit()
+}
+
+func (r *Rotator) run(ctx context.Context, id string) error {
+	for _, target := range rotationTargets {
+		if err := r.rotateTable(ctx, id, target); err != nil {
+			if ctx.Err() != nil {
+				// Left running so the next Start resumes it.
+				return err
+			}
+			r.finish(ctx, id, "failed", err.Error())
+			return err
+		}
+	}
+	r.finish(ctx, id, "completed", "")
+	r.log.Info("key rotation completed", "rotation", id, "key", r.keys.PrimaryID())
+	return nil
+}
+
+func (r *Rotator) finish(ctx context.Context, id, status, message string) {
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE crypto_rotations
+		SET status = $2, error = NULLIF($3, ''), updated_at = NOW(), finished_at = NOW()
+		WHERE id = $1
+	`, id, status, message); err != nil {
+		r.log.Error("failed to record rotation result", "rotation", id, "status", status, "err", err)
+	}
+}
+
+type encryptedRow struct {
+	id     string
+	values []sql.NullString
+}
+
+func (r *Rotator) rotateTable(ctx context.Context, rotationID string, target rotationTarget) error {
+	var (
+		lastID sql.NullString
+		done   bool
+	)
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT last_id::text, done
+		FROM crypto_rotation_progress
+		WHERE rotation_id = $1 AND table_name = $2
+	`, rotationID, target.table).Scan(&lastID, &done); err != nil {
+		return fmt.Errorf("load %s progress: %w", target.table, err)
+	}
+
+	for !done {
+		batch, err := r.loadBatch(ctx, target, lastID)
+		if err != nil {
+			return err
+		}
+		if done = len(batch) < r.batchSize; len(batch) > 0 {
+			lastID = sql.NullString{String: batch[len(batch)-1].id, Valid: true}
+		}
+		if err := r.rotateBatch(ctx, rotationID, target, batch, lastID, done); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Rotator) loadBatch(ctx context.Context, target rotationTarget, after sql.NullString) ([]encryptedRow, error) {
+	columns := target.columns
+	if target.jsonColumn != "" {
+		columns = []string{target.jsonColumn + "::text"}
+	}
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id::text, %s
+		FROM %s
+		WHERE ($1::uuid IS NULL OR id > $1::uuid)
+		ORDER BY id
+		LIMIT $2
+	`, strings.Join(columns, ", "), target.table), after, r.batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("load %s batch: %w", target.table, err)
+	}
+	defer rows.Close()
+
+	var batch []encryptedRow
+	for rows.Next() {
+		row := encryptedRow{values: make([]sql.NullString, len(columns))}
+		dest := []any{&row.id}
+		for i := range row.values {
+			dest = append(dest, &row.values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("scan %s row: %w", target.table, err)
+		}
+		batch = append(batch, row)
+	}
+	return batch, rows.Err()
+}
+
+// rotateBatch rewrites the batch and advances the table's progress in one
+// transaction, so a resumed run never skips or double-counts rows.
+func (r *Rotator) rotateBatch(ctx context.Context, rotationID string, target rotationTarget, batch []encryptedRow, lastID sql.NullString, done bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin %s batch: %w", target.table, err)
+	}
+	defer tx.Rollback()
+
+	var rotated, failed int
+	for _, row := range batch {
+		changed, failures, err := r.rotateRow(ctx, tx, target, row)
+		if err != nil {
+			return err
+		}
+		if changed {
+			rotated++
+		}
+		if len(failures) > 0 {
+			failed++
+		}
+		for column, cause := range failures {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO crypto_rotation_failures (rotation_id, table_name, row_id, column_name, error)
+				VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (rotation_id, table_name, row_id, column_name)
+				DO UPDATE SET error = EXCLUDED.error, created_at = NOW()
+			`, rotationID, target.table, row.id, column, cause.Error()); err != nil {
+				return fmt.Errorf("record rotation failure: %w", err)
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE crypto_rotation_progress
+		SET last_id = $3::uuid,
+		    scanned = scanned + $4,
+		    rotated = rotated + $5,
+		    failed = failed + $6,
+		    done = $7,
+		    updated_at = NOW()
+		WHERE rotation_id = $1 AND table_name = $2
+	`, rotationID, target.table, lastID, len(batch), rotated, failed, done); err != nil {
+		return fmt.Errorf("update %s progress: %w", target.table, err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE crypto_rotations SET updated_at = NOW() WHERE id = $1`, rotationID); err != nil {
+		return fmt.Errorf("touch rotation: %w", err)
+	}
+	return tx.Commit()
+}
+
+// rotateRow re-encrypts the row's values that use a retired key. Updates only
+// apply while the stored value is unchanged, so a credential rewritten
+// concurrently is left as the writer stored it.
+func (r *Rotator) rotateRow(ctx context.Context, tx *sql.Tx, target rotationTarget, row encryptedRow) (bool, map[string]error, error) {
+	failures := map[string]error{}
+
+	if target.jsonColumn != "" {
+		raw := row.values[0]
+		if !raw.Valid {
+			return false, nil, nil
+		}
+		var config map[string]any
+		if err := json.Unmarshal([]byte(raw.String), &config); err != nil {
+			failures[target.jsonColumn] = fmt.Errorf("decode config: %w", err)
+			return false, failures, nil
+		}
+		changed := false
+		for key, value := range config {
+			s, ok := value.(string)
+			if !ok {
+				continue
+			}
+			rotated, ok, err := r.rotateValue(s)
+			if err != nil {
+				failures[target.jsonColumn+"."+key] = err
+				continue
+			}
+			if ok {
+				config[key] = rotated
+				changed = true
+			}
+		}
+		if !changed {
+			return false, failures, nil
+		}
+		updated, err := json.Marshal(config)
+		if err != nil {
+			return false, nil, fmt.Errorf("encode %s config: %w", target.table, err)
+		}
+		res, err := tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s SET %s = $2::jsonb
+			WHERE id = $1 AND %[2]s = $3::jsonb
+		`, target.table, target.jsonColumn), row.id, string(updated), raw.String)
+		if err != nil {
+			return false, nil, fmt.Errorf("update %s row: %w", target.table, err)
+		}
+		n, _ := res.RowsAffected()
+		return n > 0, failures, nil
+	}
+
+	changed := false
+	for i, column := range target.columns {
+		value := row.values[i]
+		if !value.Valid {
+			continue
+		}
+		rotated, ok, err := r.rotateValue(value.String)
+		if err != nil {
+			failures[column] = err
+			continue
+		}
+		if !ok {
+			continue
+		}
+		res, err := tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s SET %s = $2
+			WHERE id = $1 AND %[2]s = $3
+		`, target.table, column), row.id, rotated, value.String)
+		if err != nil {
+			return false, nil, fmt.Errorf("update %s row: %w", target.table, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			changed = true
+		}
+	}
+	return changed, failures, nil
+}
+
+// rotateValue returns s re-encrypted with the primary key. ok is false for
+// plaintext values and payloads that already use the primary key.
+func (r *Rotator) rotateValue(s string) (string, bool, error) {
+	if !IsEncrypted(s) || r.keys.IsCurrent(s) {
+		return "", false, nil
+	}
+	plaintext, err := r.keys.Decrypt(s)
+	if err != nil {
+		return "", false, err
+	}
+	rotated, err := r.keys.Encrypt(plaintext)
+	if err != nil {
+		return "", false, err
+	}
+	return rotated, true, nil
+}
+
+// Get returns a run with its per-table counts and the first recorded
+// failures.
+func (r *Rotator) Get(ctx context.Context, id string) (Rotation, error) {
+	var (
+		rot        Rotation
+		errMsg     sql.NullString
+		finishedAt sql.NullTime
+	)
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id::text, target_key_id, status, error, started_at, updated_at, finished_at
+		FROM crypto_rotations
+		WHERE id::text = $1
+	`, id).Scan(&rot.ID, &rot.TargetKeyID, &rot.Status, &errMsg, &rot.StartedAt, &rot.UpdatedAt, &finishedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Rotation{}, ErrRotationNotFound
+	}
+	if err != nil {
+		return Rotation{}, fmt.Errorf("load rotation: %w", err)
+	}
+	rot.Error = errMsg.String
+	if finishedAt.Valid {
+		rot.FinishedAt = &finishedAt.Time
+	}
+
+	progress, err := r.db.QueryContext(ctx, `
+		SELECT table_name, scanned, rotated, failed, done
+		FROM crypto_rotation_progress
+		WHERE rotation_id = $1
+		ORDER BY table_name
+	`, rot.ID)
+	if err != nil {
+		return Rotation{}, fmt.Errorf("load rotation progress: %w", err)
+	}
+	defer progress.Close()
+	rot.Tables = []TableProgress{}
+	for progress.Next() {
+		var p TableProgress
+		if err := progress.Scan(&p.Table, &p.Scanned, &p.Rotated, &p.Failed, &p.Done); err != nil {
+			return Rotation{}, fmt.Errorf("scan rotation progress: %w", err)
+		}
+		rot.Tables = append(rot.Tables, p)
+	}
+	if err := progress.Err(); err != nil {
+		return Rotation{}, err
+	}
+
+	failures, err := r.db.QueryContext(ctx, `
+		SELECT table_name, row_id::text, column_name, error, created_at
+		FROM crypto_rotation_failures
+		WHERE rotation_id = $1
+		ORDER BY created_at, table_name, row_id
+		LIMIT $2
+	`, rot.ID, maxReportedFailures)
+	if err != nil {
+		return Rotation{}, fmt.Errorf("load rotation failures: %w", err)
+	}
+	defer failures.Close()
+	for failures.Next() {
+		var f RotationFailure
+		if err := failures.Scan(&f.Table, &f.RowID, &f.Column, &f.Error, &f.CreatedAt); err != nil {
+			return Rotation{}, fmt.Errorf("scan rotation failure: %w", err)
+		}
+		rot.Failures = append(rot.Failures, f)
+	}
+	return rot, failures.Err()
+}
+
+// List returns the most recent runs without their per-table detail.
+func (r *Rotator) List(ctx context.Context, limit int) ([]Rotation, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id::text, target_key_id, status, error, started_at, updated_at, finished_at
+		FROM crypto_rotations
+		ORDER BY started_at DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list rotations: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]Rotation, 0)
+	for rows.Next() {
+		var (
+			rot        Rotation
+			errMsg     sql.NullString
+			finishedAt sql.NullTime
+		)
+		if err := rows.Scan(&rot.ID, &rot.TargetKeyID, &rot.Status, &errMsg, &rot.StartedAt, &rot.UpdatedAt, &finishedAt); err != nil {
+			return nil, fmt.Errorf("scan rotation: %w", err)
+		}
+		rot.Error = errMsg.String
+		if finishedAt.Valid {
+			rot.FinishedAt = &finishedAt.Time
+		}
+		out = append(out, rot)
+	}
+	return out, rows.Err()
+}
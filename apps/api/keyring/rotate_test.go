@@ -0,0 +1,210 @@
+package keyring
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRotateBatchReencryptsAndFlagsFailures(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	old, _ := New(oldKey)
+	stale, _ := old.Encrypt("access")
+	keys, _ := New(newKey, oldKey)
+	current, _ := keys.Encrypt("refresh")
+	unknown, _ := New("11111111111111111111111111111111111111111111111111111111111111aa")
+	lost, _ := unknown.Encrypt("lost")
+
+	r := NewRotator(db, keys)
+	target := rotationTargets[0]
+	batch := []encryptedRow{
+		{id: "row-1", values: []sql.NullString{{String: stale, Valid: true}, {String: current, Valid: true}}},
+		{id: "row-2", values: []sql.NullString{{String: lost, Valid: true}, {}}},
+	}
+	lastID := sql.NullString{String: "row-2", Valid: true}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE deploy_connections SET access_token_encrypted").
+		WithArgs("row-1", sqlmock.AnyArg(), stale).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO crypto_rotation_failures").
+		WithArgs("rot-1", "deploy_connections", "row-2", "access_token_encrypted", ErrUnknownKey.Error()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE crypto_rotation_progress").
+		WithArgs("rot-1", "deploy_connections", lastID, 2, 1, 1, true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE crypto_rotations SET updated_at").
+		WithArgs("rot-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := r.rotateBatch(context.Background(), "rot-1", target, batch, lastID, true); err != nil {
+		t.Fatalf("rotateBatch: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRotateRowJSONConfigKeepsPlaintextFields(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	old, _ := New(oldKey)
+	stale, _ := old.Encrypt("bot-token")
+	keys, _ := New(newKey, oldKey)
+	r := NewRotator(db, keys)
+
+	raw, _ := json.Marshal(map[string]any{"bot_token": stale, "webhook_secret": "plain", "enabled": true})
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE channel_credentials SET config").
+		WithArgs("row-1", sqlmock.AnyArg(), string(raw)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := encryptedRow{id: "row-1", values: []sql.NullString{{String: string(raw), Valid: true}}}
+	changed, failures, err := r.rotateRow(context.Background(), tx, rotationTargets[1], row)
+	if err != nil || !changed || len(failures) != 0 {
+		t.Fatalf("rotateRow = %v, %v, %v", changed, failures, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, ok, err := r.rotateValue(stale)
+	if err != nil || !ok || !keys.IsCurrent(rotated) {
+		t.Fatalf("rotateValue = %q, %v, %v", rotated, ok, err)
+	}
+	if got, _ := keys.Decrypt(rotated); got != "bot-token" {
+		t.Fatalf("rotated plaintext = %q", got)
+	}
+	if _, ok, _ := r.rotateValue("plain"); ok {
+		t.Fatalf("plaintext value was rotated")
+	}
+}
+
+func TestRotateRowTenantToolSecrets(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	old, _ := New(oldKey)
+	authSecret, _ := old.Encrypt("api-key")
+	signingSecret, _ := old.Encrypt("signing")
+	keys, _ := New(newKey, oldKey)
+	r := NewRotator(db, keys)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE tenant_tools SET auth_secret_encrypted").
+		WithArgs("tool-1", sqlmock.AnyArg(), authSecret).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE tenant_tools SET signing_secret_encrypted").
+		WithArgs("tool-1", sqlmock.AnyArg(), signingSecret).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := encryptedRow{id: "tool-1", values: []sql.NullString{{String: authSecret, Valid: true}, {String: signingSecret, Valid: true}}}
+	changed, failures, err := r.rotateRow(context.Background(), tx, targetFor(t, "tenant_tools"), row)
+	if err != nil || !changed || len(failures) != 0 {
+		t.Fatalf("rotateRow = %v, %v, %v", changed, failures, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func targetFor(t *testing.T, table string) rotationTarget {
+	t.Helper()
+	for _, target := range rotationTargets {
+		if target.table == table {
+			return target
+		}
+	}
+	t.Fatalf("%s is not a rotation target", table)
+	return rotationTarget{}
+}
+
+// migrationEncryptedStmt matches the statements that create a table, with
+// its columns, or add a column to one.
+var migrationEncryptedStmt = regexp.MustCompile(`(?is)CREATE TABLE(?: IF NOT EXISTS)?\s+(\w+)\s*\((.*?)\n\);|ALTER TABLE(?: IF EXISTS)?\s+(\w+)\s+ADD COLUMN(?: IF NOT EXISTS)?\s+(\w+)\s+(\w+)`)
+
+var (
+	encryptedColumn = regexp.MustCompile(`(?im)^\s*(\w+_encrypted)\b`)
+	uuidIDColumn    = regexp.MustCompile(`(?im)^\s*id\s+UUID\b`)
+)
+
+func TestRotationTargetsCoverEncryptedColumns(t *testing.T) {
+	t.Parallel()
+	files, err := filepath.Glob(filepath.Join("..", "..", "..", "db", "migrations", "*.sql"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("migrations not found: %v", err)
+	}
+	sort.Strings(files)
+	encrypted := map[string]bool{}
+	uuidIDs := map[string]bool{}
+	for _, file := range files {
+		sql, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read %s: %v", file, err)
+		}
+		for _, m := range migrationEncryptedStmt.FindAllStringSubmatch(string(sql), -1) {
+			if m[1] != "" {
+				table := strings.ToLower(m[1])
+				for _, c := range encryptedColumn.FindAllStringSubmatch(m[2], -1) {
+					encrypted[table+"."+strings.ToLower(c[1])] = true
+				}
+				uuidIDs[table] = uuidIDColumn.MatchString(m[2])
+				continue
+			}
+			table, column := strings.ToLower(m[3]), strings.ToLower(m[4])
+			switch {
+			case strings.HasSuffix(column, "_encrypted"):
+				encrypted[table+"."+column] = true
+			case column == "id" && strings.EqualFold(m[5], "uuid"):
+				uuidIDs[table] = true
+			}
+		}
+	}
+
+	covered := map[string]bool{}
+	for _, target := range rotationTargets {
+		for _, column := range target.columns {
+			covered[target.table+"."+column] = true
+		}
+		if !uuidIDs[target.table] {
+			t.Errorf("rotation target %s has no uuid id column", target.table)
+		}
+	}
+	for column := range encrypted {
+		if !covered[column] {
+			t.Errorf("encrypted column %s is not rotated", column)
+		}
+	}
+}
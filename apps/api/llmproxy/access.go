@@ -0,0 +1,84 @@
+package llmproxy
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ModelAccess is a tenant's model allowlist from tenant_model_access. With no
+// rows every enabled model is permitted. Once any model is explicitly
+// allowed, only allowed models are permitted; denied models never are.
+type ModelAccess struct {
+	allowed map[string]bool
+	denied  map[string]bool
+}
+
+// LoadModelAccess reads the tenant's access rows.
+func LoadModelAccess(ctx context.Context, db *sql.DB, tenantID string) (ModelAccess, error) {
+	access := ModelAccess{allowed: map[string]bool{}, denied: map[string]bool{}}
+	rows, err := db.QueryContext(ctx, `SELECT model_id, allowed FROM tenant_model_access WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return access, fmt.Errorf("query model access: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			modelID string
+			allowed bool
+		)
+		if err := rows.Scan(&modelID, &allowed); err != nil {
+			return access, fmt.Errorf("scan model access: %w", err)
+		}
+		if allowed {
+			access.allowed[modelID] = true
+		} else {
+			access.denied[modelID] = true
+		}
+	}
+	return access, rows.Err()
+}
+
+// Permits reports whether the tenant may use the model.
+func (a ModelAccess) Permits(modelID string) bool {
+	if a.denied[modelID] {
+		return false
+	}
+	return len(a.allowed) == 0 || a.allowed[modelID]
+}
+
+// Filter returns the permitted models sorted by ID.
+func (a ModelAccess) Filter(models []*Model) []*Model {
+	out := make([]*Model, 0, len(models))
+	for _, m := range models {
+		if a.Permits(m.ID) {
+			out = append(out, m)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// writeModelNotAllowed is the OpenAI error envelope plus the IDs the tenant
+// may use instead.
+func writeModelNotAllowed(w http.ResponseWriter, modelID string, permitted []*Model) {
+	ids := make([]string, len(permitted))
+	for i, m := range permitted {
+		ids[i] = m.ID
+	}
+	message := fmt.Sprintf("model %s is not allowed for this tenant", modelID)
+	if len(ids) > 0 {
+		message += "; permitted models: " + strings.Join(ids, ", ")
+	}
+	code, param := "model_not_allowed", "model"
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error":            errorObject{Message: message, Type: "model_not_allowed", Code: &code, Param: &param},
+		"permitted_models": ids,
+	})
+}
@@ -0,0 +1,115 @@
+package llmproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// expectModelAccess expects the tenant's allowlist lookup. Each row is a
+// model ID and whether it is allowed; with no rows every model is permitted.
+func expectModelAccess(mock sqlmock.Sqlmock, tenantID string, rows ...any) {
+	result := sqlmock.NewRows([]string{"model_id", "allowed"})
+	for i := 0; i+1 < len(rows); i += 2 {
+		result.AddRow(rows[i], rows[i+1])
+	}
+	mock.ExpectQuery("SELECT model_id, allowed FROM tenant_model_access").WithArgs(tenantID).WillReturnRows(result)
+}
+
+func TestModelAccessPermits(t *testing.T) {
+	t.Parallel()
+	open := ModelAccess{}
+	if !open.Permits("gpt-4o") {
+		t.Fatal("empty access should permit every model")
+	}
+	blocked := ModelAccess{denied: map[string]bool{"o1": true}}
+	if blocked.Permits("o1") || !blocked.Permits("gpt-4o") {
+		t.Fatal("denied rows should only block their model")
+	}
+	listed := ModelAccess{allowed: map[string]bool{"gpt-4o": true, "o1": true}, denied: map[string]bool{"o1": true}}
+	if !listed.Permits("gpt-4o") || listed.Permits("o1") || listed.Permits("claude") {
+		t.Fatal("allowlist should permit only allowed, non-denied models")
+	}
+}
+
+func TestChatCompletionsModelNotAllowed(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	expectModelAccess(mock, "t1", "mini", true, "cheap", true)
+
+	reg := &ModelRegistry{models: map[string]*Model{
+		"o1":    {ID: "o1", Provider: "openai"},
+		"mini":  {ID: "mini", Provider: "openai"},
+		"cheap": {ID: "cheap", Provider: "openai"},
+	}}
+	proxy := &Proxy{DB: db, Registry: reg}
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(`{"model":"o1","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("X-Tenant-ID", "t1")
+	w := httptest.NewRecorder()
+	proxy.handleChatCompletions(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var got struct {
+		Error           errorObject `json:"error"`
+		PermittedModels []string    `json:"permitted_models"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Error.Type != "model_not_allowed" || len(got.PermittedModels) != 2 || got.PermittedModels[0] != "cheap" || got.PermittedModels[1] != "mini" {
+		t.Fatalf("body = %s", w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestListModelsFiltersByTenant(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	expectModelAccess(mock, "t1", "o1", false)
+
+	reg := &ModelRegistry{models: map[string]*Model{
+		"o1":   {ID: "o1", Provider: "openai"},
+		"mini": {ID: "mini", Provider: "openai"},
+	}}
+	proxy := &Proxy{DB: db, Registry: reg}
+
+	list := func(tenantID string) []map[string]any {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		if tenantID != "" {
+			req.Header.Set("X-Tenant-ID", tenantID)
+		}
+		w := httptest.NewRecorder()
+		proxy.handleListModels(w, req)
+		var body struct {
+			Data []map[string]any `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return body.Data
+	}
+
+	if got := list(""); len(got) != 2 {
+		t.Fatalf("unscoped models = %v", got)
+	}
+	if got := list("t1"); len(got) != 1 || got[0]["id"] != "mini" {
+		t.Fatalf("tenant models = %v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
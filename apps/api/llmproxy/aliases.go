@@ -0,0 +1,150 @@
+package llmproxy
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/agentsquads/api/policies"
+)
+
+// ModelAlias maps a deprecated model id to the model replacing it. From
+// WarnFrom the old id still works but is flagged as deprecated; from
+// EffectiveAt it is served by the replacement.
+type ModelAlias struct {
+	AliasID       string    `json:"alias_id"`
+	ReplacementID string    `json:"replacement_id"`
+	WarnFrom      time.Time `json:"warn_from"`
+	EffectiveAt   time.Time `json:"effective_at"`
+}
+
+// ModelResolution is the model a requested id resolved to. Alias is set when
+// the id is deprecated; Retired is set once its effective date has passed and
+// Model is the replacement.
+type ModelResolution struct {
+	Model   *Model
+	Alias   *ModelAlias
+	Retired bool
+}
+
+// Deprecated reports whether the requested id is in its warning window or
+// already retired.
+func (r ModelResolution) Deprecated() bool {
+	return r.Alias != nil
+}
+
+func loadModelAliases(ctx context.Context, db *sql.DB) (map[string]*ModelAlias, error) {
+	rows, err := db.QueryContext(ctx, `SELECT alias_id, replacement_id, warn_from, effective_at FROM model_aliases`)
+	if err != nil {
+		return nil, fmt.Errorf("query model aliases: %w", err)
+	}
+	defer rows.Close()
+
+	aliases := make(map[string]*ModelAlias)
+	for rows.Next() {
+		var a ModelAlias
+		if err := rows.Scan(&a.AliasID, &a.ReplacementID, &a.WarnFrom, &a.EffectiveAt); err != nil {
+			return nil, fmt.Errorf("scan model alias: %w", err)
+		}
+		aliases[a.AliasID] = &a
+	}
+	return aliases, rows.Err()
+}
+
+// Resolve looks up a requested model id, following its alias once the
+// alias's warning window has started. During the window the old model is
+// served while it is still enabled; after the effective date, or when the old
+// model is already gone, the replacement is.
+func (r *ModelRegistry) Resolve(id string, now time.Time) (ModelResolution, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	alias, ok := r.aliases[id]
+	if !ok || now.Before(alias.WarnFrom) {
+		m, ok := r.models[id]
+		if !ok {
+			return ModelResolution{}, fmt.Errorf("model not found: %s", id)
+		}
+		return ModelResolution{Model: m}, nil
+	}
+
+	retired := !now.Before(alias.EffectiveAt)
+	if !retired {
+		if m, ok := r.models[id]; ok {
+			return ModelResolution{Model: m, Alias: alias}, nil
+		}
+	}
+	m, ok := r.models[alias.ReplacementID]
+	if !ok {
+		return ModelResolution{}, fmt.Errorf("model not found: %s (replacement for deprecated %s)", alias.ReplacementID, id)
+	}
+	return ModelResolution{Model: m, Alias: alias, Retired: retired}, nil
+}
+
+// deprecatedModel is the deprecated id that was asked for, or "".
+func (r ModelResolution) deprecatedModel() string {
+	if r.Alias == nil {
+		return ""
+	}
+	return r.Alias.AliasID
+}
+
+// resolveModel resolves a requested model id and writes the error when it
+// cannot be served: the id is unknown, or it is retired and the tenant's
+// strict_model_deprecation policy rejects the replacement. Deprecated ids get
+// deprecation headers on the response. param names the request field for
+// error bodies.
+func (p *Proxy) resolveModel(w http.ResponseWriter, r *http.Request, tenantID, id, param string) (ModelResolution, bool) {
+	res, err := p.Registry.Resolve(id, time.Now())
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "model_not_found", err.Error(), param)
+		return res, false
+	}
+	if res.Retired && p.strictDeprecation(r.Context(), tenantID) {
+		msg := fmt.Sprintf("model %s was retired on %s; use %s instead", id, res.Alias.EffectiveAt.UTC().Format(time.DateOnly), res.Alias.ReplacementID)
+		writeOpenAIError(w, http.StatusGone, "invalid_request_error", "model_deprecated", msg, param)
+		return res, false
+	}
+	setDeprecationHeaders(w, res)
+	return res, true
+}
+
+// strictDeprecation reports whether the tenant opted to fail on retired
+// model ids. A failed lookup serves the replacement, which is the default.
+func (p *Proxy) strictDeprecation(ctx context.Context, tenantID string) bool {
+	if p.Policies == nil {
+		return false
+	}
+	strict, err := p.Policies.FeatureEnabled(ctx, tenantID, policies.FeatureStrictModelDeprecation)
+	if err != nil {
+		slog.Error("model deprecation policy lookup failed", "tenant", tenantID, "err", err)
+		return false
+	}
+	return strict
+}
+
+// ListAliases returns every model alias ordered by id.
+func (r *ModelRegistry) ListAliases() []*ModelAlias {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]*ModelAlias, 0, len(r.aliases))
+	for _, a := range r.aliases {
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].AliasID < out[j].AliasID })
+	return out
+}
+
+// setDeprecationHeaders tells the client the model id it asked for is
+// deprecated, what replaces it and when it is retired.
+func setDeprecationHeaders(w http.ResponseWriter, res ModelResolution) {
+	if !res.Deprecated() {
+		return
+	}
+	w.Header().Set("X-Model-Deprecated", res.Alias.AliasID)
+	w.Header().Set("X-Model-Replacement", res.Alias.ReplacementID)
+	w.Header().Set("Sunset", res.Alias.EffectiveAt.UTC().Format(http.TimeFormat))
+}
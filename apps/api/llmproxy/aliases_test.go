@@ -0,0 +1,136 @@
+package llmproxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type stubPolicies map[string]bool
+
+func (s stubPolicies) FeatureEnabled(_ context.Context, tenantID, feature string) (bool, error) {
+	return s[tenantID+"|"+feature], nil
+}
+
+func aliasRegistry(warnFrom, effectiveAt time.Time) *ModelRegistry {
+	return &ModelRegistry{
+		models: map[string]*Model{
+			"gpt-4-0613": {ID: "gpt-4-0613", Provider: "openai", ProviderCostInputM: 3000},
+			"gpt-4o":     {ID: "gpt-4o", Provider: "openai", ProviderCostInputM: 250},
+		},
+		aliases: map[string]*ModelAlias{
+			"gpt-4-0613": {AliasID: "gpt-4-0613", ReplacementID: "gpt-4o", WarnFrom: warnFrom, EffectiveAt: effectiveAt},
+		},
+	}
+}
+
+func TestModelRegistryResolve(t *testing.T) {
+	t.Parallel()
+	warnFrom := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	effectiveAt := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	reg := aliasRegistry(warnFrom, effectiveAt)
+
+	tests := []struct {
+		name           string
+		now            time.Time
+		wantModel      string
+		wantDeprecated bool
+		wantRetired    bool
+	}{
+		{name: "before warning window", now: warnFrom.Add(-time.Hour), wantModel: "gpt-4-0613"},
+		{name: "in warning window", now: warnFrom, wantModel: "gpt-4-0613", wantDeprecated: true},
+		{name: "after effective date", now: effectiveAt, wantModel: "gpt-4o", wantDeprecated: true, wantRetired: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := reg.Resolve("gpt-4-0613", tt.now)
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			if res.Model.ID != tt.wantModel || res.Deprecated() != tt.wantDeprecated || res.Retired != tt.wantRetired {
+				t.Fatalf("resolution = model %s deprecated %v retired %v", res.Model.ID, res.Deprecated(), res.Retired)
+			}
+		})
+	}
+
+	// A disabled old model is served by its replacement during the window.
+	delete(reg.models, "gpt-4-0613")
+	res, err := reg.Resolve("gpt-4-0613", warnFrom)
+	if err != nil || res.Model.ID != "gpt-4o" || res.Retired {
+		t.Fatalf("Resolve without old model = %+v, %v", res, err)
+	}
+	if _, err := reg.Resolve("gpt-4-0613", warnFrom.Add(-time.Hour)); err == nil {
+		t.Fatalf("expected model not found before the warning window")
+	}
+}
+
+func TestProxyServesRetiredModelWithReplacement(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	expectModelAccess(mock, "t1")
+	mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(100))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO usage_logs").
+		WithArgs("t1", "gpt-4o", 1000, 10, sqlmock.AnyArg(), 0, 1, "gpt-4-0613").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE credits SET balance_cents").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(90))
+
+	var upstreamModel string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		if strings.Contains(string(body), `"model":"gpt-4o"`) {
+			upstreamModel = "gpt-4o"
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"id":"1","choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1000,"completion_tokens":10}}`)), Header: make(http.Header)}, nil
+	})}
+
+	now := time.Now()
+	proxy := &Proxy{DB: db, Registry: aliasRegistry(now.Add(-48*time.Hour), now.Add(-time.Hour)), Client: client}
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(`{"model":"gpt-4-0613","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("X-Tenant-ID", "t1")
+	w := httptest.NewRecorder()
+	proxy.handleChatCompletions(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	if upstreamModel != "gpt-4o" {
+		t.Fatalf("upstream was not called with the replacement model")
+	}
+	if w.Header().Get("X-Model-Deprecated") != "gpt-4-0613" || w.Header().Get("X-Model-Replacement") != "gpt-4o" {
+		t.Fatalf("deprecation headers = %v", w.Header())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestProxyRejectsRetiredModelUnderStrictPolicy(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	proxy := &Proxy{
+		Registry: aliasRegistry(now.Add(-48*time.Hour), now.Add(-time.Hour)),
+		Policies: stubPolicies{"t1|strict_model_deprecation": true},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(`{"model":"gpt-4-0613","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("X-Tenant-ID", "t1")
+	w := httptest.NewRecorder()
+	proxy.handleChatCompletions(w, req)
+
+	if w.Code != http.StatusGone || !strings.Contains(w.Body.String(), "model_deprecated") {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,331 @@
+package llmproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errInvalidToolSpec marks tool definitions, tool choices or tool messages
+// that cannot be translated for the upstream provider. The handler maps it to
+// a 400 instead of an upstream error.
+var errInvalidToolSpec = errors.New("invalid tool specification")
+
+// validateChatMessage checks the OpenAI message shape. Content may be empty
+// on assistant messages that carry tool calls, and tool messages must name
+// the call they answer.
+func validateChatMessage(msg chatMessage) error {
+	role := strings.TrimSpace(msg.Role)
+	if role == "" {
+		return errors.New("messages must include role and content")
+	}
+	if role == "tool" && strings.TrimSpace(msg.ToolCallID) == "" {
+		return errors.New("tool messages must include tool_call_id")
+	}
+	if strings.TrimSpace(msg.Content) == "" && !(role == "assistant" && len(msg.ToolCalls) > 0) {
+		return errors.New("messages must include role and content")
+	}
+	return nil
+}
+
+// anthropicPromptCachingBeta is the beta flag Anthropic requires on requests
+// that set cache_control breakpoints.
+const anthropicPromptCachingBeta = "prompt-caching-2024-07-31"
+
+// anthropicMessages splits out the system prompt and converts the remaining
+// messages to Anthropic content blocks. Assistant tool calls become tool_use
+// blocks, and consecutive role "tool" messages are merged into a single user
+// message of tool_result blocks, which is what the Messages API expects. The
+// system prompt is nil when there is none, a string, or a list of blocks when
+// it was sent as blocks or carries cache_control.
+func anthropicMessages(in []chatMessage) (any, []map[string]any, error) {
+	var system any
+	messages := make([]map[string]any, 0, len(in))
+	for _, m := range in {
+		switch {
+		case m.Role == "system":
+			content, err := anthropicContent(m)
+			if err != nil {
+				return nil, nil, err
+			}
+			system = content
+		case m.Role == "tool":
+			block := map[string]any{
+				"type":        "tool_result",
+				"tool_use_id": m.ToolCallID,
+				"content":     m.Content,
+			}
+			if len(m.Blocks) > 0 {
+				blocks, err := contentBlocks(m)
+				if err != nil {
+					return nil, nil, err
+				}
+				block["content"] = blocks
+			}
+			if len(m.CacheControl) > 0 {
+				block["cache_control"] = m.CacheControl
+			}
+			if n := len(messages); n > 0 && isToolResultMessage(messages[n-1]) {
+				prev := messages[n-1]
+				prev["content"] = append(prev["content"].([]map[string]any), block)
+				continue
+			}
+			messages = append(messages, map[string]any{
+				"role":    "user",
+				"content": []map[string]any{block},
+			})
+		case m.Role == "assistant" && len(m.ToolCalls) > 0:
+			blocks, err := contentBlocks(m)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, call := range m.ToolCalls {
+				input, err := toolCallInput(call.Function.Arguments)
+				if err != nil {
+					return nil, nil, fmt.Errorf("%w: tool call %s: %v", errInvalidToolSpec, call.ID, err)
+				}
+				blocks = append(blocks, map[string]any{
+					"type":  "tool_use",
+					"id":    call.ID,
+					"name":  call.Function.Name,
+					"input": input,
+				})
+			}
+			if len(m.CacheControl) > 0 {
+				blocks[len(blocks)-1]["cache_control"] = m.CacheControl
+			}
+			messages = append(messages, map[string]any{"role": "assistant", "content": blocks})
+		default:
+			content, err := anthropicContent(m)
+			if err != nil {
+				return nil, nil, err
+			}
+			messages = append(messages, map[string]any{"role": m.Role, "content": content})
+		}
+	}
+	return system, messages, nil
+}
+
+// anthropicContent is m's content for the Messages API: the plain string,
+// or blocks when m was sent as blocks or marks a cache breakpoint. A
+// message-level cache_control goes on the last block.
+func anthropicContent(m chatMessage) (any, error) {
+	if len(m.Blocks) == 0 && len(m.CacheControl) == 0 {
+		return m.Content, nil
+	}
+	blocks, err := contentBlocks(m)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.CacheControl) > 0 && len(blocks) > 0 {
+		blocks[len(blocks)-1]["cache_control"] = m.CacheControl
+	}
+	return blocks, nil
+}
+
+// contentBlocks returns m's content blocks as sent, or its text as a single
+// text block. Empty text yields no blocks.
+func contentBlocks(m chatMessage) ([]map[string]any, error) {
+	if len(m.Blocks) == 0 {
+		if strings.TrimSpace(m.Content) == "" {
+			return []map[string]any{}, nil
+		}
+		return []map[string]any{{"type": "text", "text": m.Content}}, nil
+	}
+	blocks := make([]map[string]any, 0, len(m.Blocks))
+	for _, raw := range m.Blocks {
+		var block map[string]any
+		if err := json.Unmarshal(raw, &block); err != nil {
+			return nil, fmt.Errorf("decode content block: %w", err)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// usesAnthropicPromptCache reports whether req sets any cache_control
+// breakpoint, on a message, a content block or a tool.
+func usesAnthropicPromptCache(req chatRequest) bool {
+	for _, m := range req.Messages {
+		if len(m.CacheControl) > 0 || hasCacheControl(m.Blocks) {
+			return true
+		}
+	}
+	return hasCacheControl(req.Tools)
+}
+
+func hasCacheControl(raws []json.RawMessage) bool {
+	for _, raw := range raws {
+		var fields struct {
+			CacheControl json.RawMessage `json:"cache_control"`
+		}
+		if json.Unmarshal(raw, &fields) == nil && len(fields.CacheControl) > 0 && string(fields.CacheControl) != "null" {
+			return true
+		}
+	}
+	return false
+}
+
+// anthropicUsage reads an Anthropic response's usage. Input is reported in
+// the OpenAI sense, cached tokens included.
+func anthropicUsage(antResp map[string]any) tokenUsage {
+	input, output := ExtractAnthropicUsage(antResp)
+	cache := ExtractAnthropicCacheUsage(antResp)
+	return tokenUsage{
+		Input:  input + cache.CreationTokens + cache.ReadTokens,
+		Output: output,
+		Cache:  cache,
+	}
+}
+
+func isToolResultMessage(m map[string]any) bool {
+	blocks, ok := m["content"].([]map[string]any)
+	if !ok || m["role"] != "user" || len(blocks) == 0 {
+		return false
+	}
+	return blocks[0]["type"] == "tool_result"
+}
+
+// toolCallInput parses OpenAI's string-encoded arguments into the JSON object
+// Anthropic expects as tool_use input. Empty arguments mean no input.
+func toolCallInput(args string) (json.RawMessage, error) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		return json.RawMessage(`{}`), nil
+	}
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(args), &obj); err != nil {
+		return nil, errors.New("arguments must be a JSON object")
+	}
+	return json.RawMessage(args), nil
+}
+
+// anthropicTools converts OpenAI function tools ({"type":"function",
+// "function":{...}}) to Anthropic tools with an input_schema. Entries that
+// are already in Anthropic's shape are passed through unchanged.
+func anthropicTools(in []json.RawMessage) ([]json.RawMessage, error) {
+	out := make([]json.RawMessage, 0, len(in))
+	for i, raw := range in {
+		var tool struct {
+			Type     string `json:"type"`
+			Name     string `json:"name"`
+			Function *struct {
+				Name        string          `json:"name"`
+				Description string          `json:"description,omitempty"`
+				Parameters  json.RawMessage `json:"parameters,omitempty"`
+			} `json:"function"`
+		}
+		if err := json.Unmarshal(raw, &tool); err != nil {
+			return nil, fmt.Errorf("%w: tools[%d] is not an object", errInvalidToolSpec, i)
+		}
+		if tool.Function == nil {
+			if strings.TrimSpace(tool.Name) == "" {
+				return nil, fmt.Errorf("%w: tools[%d] is missing a name", errInvalidToolSpec, i)
+			}
+			out = append(out, raw)
+			continue
+		}
+		if strings.TrimSpace(tool.Function.Name) == "" {
+			return nil, fmt.Errorf("%w: tools[%d] is missing a function name", errInvalidToolSpec, i)
+		}
+		schema := tool.Function.Parameters
+		if len(schema) == 0 || string(schema) == "null" {
+			schema = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		converted, err := json.Marshal(map[string]any{
+			"name":         tool.Function.Name,
+			"description":  tool.Function.Description,
+			"input_schema": schema,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: tools[%d]: %v", errInvalidToolSpec, i, err)
+		}
+		out = append(out, converted)
+	}
+	return out, nil
+}
+
+// anthropicToolChoice maps OpenAI tool_choice values to Anthropic's:
+// "auto" -> auto, "required" -> any, "none" -> none, and a named function
+// -> tool. A nil result means the field should be omitted.
+func anthropicToolChoice(raw json.RawMessage) (map[string]any, error) {
+	if string(raw) == "null" {
+		return nil, nil
+	}
+	var mode string
+	if err := json.Unmarshal(raw, &mode); err == nil {
+		switch mode {
+		case "auto":
+			return map[string]any{"type": "auto"}, nil
+		case "required":
+			return map[string]any{"type": "any"}, nil
+		case "none":
+			return map[string]any{"type": "none"}, nil
+		default:
+			return nil, fmt.Errorf("%w: unsupported tool_choice %q", errInvalidToolSpec, mode)
+		}
+	}
+
+	var named struct {
+		Type     string `json:"type"`
+		Name     string `json:"name"`
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(raw, &named); err != nil {
+		return nil, fmt.Errorf("%w: tool_choice must be a string or object", errInvalidToolSpec)
+	}
+	switch named.Type {
+	case "function":
+		if named.Function.Name == "" {
+			return nil, fmt.Errorf("%w: tool_choice function name is required", errInvalidToolSpec)
+		}
+		return map[string]any{"type": "tool", "name": named.Function.Name}, nil
+	case "auto", "any", "none", "tool":
+		// Already Anthropic-shaped.
+		var choice map[string]any
+		json.Unmarshal(raw, &choice)
+		return choice, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported tool_choice type %q", errInvalidToolSpec, named.Type)
+	}
+}
+
+// anthropicResponseMessage joins the text blocks of an Anthropic response and
+// turns tool_use blocks into OpenAI tool_calls.
+func anthropicResponseMessage(antResp map[string]any) (chatMessage, error) {
+	msg := chatMessage{Role: "assistant"}
+	blocks, _ := antResp["content"].([]any)
+	var text strings.Builder
+	for _, b := range blocks {
+		block, ok := b.(map[string]any)
+		if !ok {
+			continue
+		}
+		switch block["type"] {
+		case "text":
+			s, _ := block["text"].(string)
+			text.WriteString(s)
+		case "tool_use":
+			id, _ := block["id"].(string)
+			name, _ := block["name"].(string)
+			input := block["input"]
+			if input == nil {
+				input = map[string]any{}
+			}
+			args, err := json.Marshal(input)
+			if err != nil {
+				return chatMessage{}, fmt.Errorf("encode tool_use input: %w", err)
+			}
+			msg.ToolCalls = append(msg.ToolCalls, toolCall{
+				ID:       id,
+				Type:     "function",
+				Function: toolCallFunction{Name: name, Arguments: string(args)},
+			})
+		}
+	}
+	msg.Content = text.String()
+	return msg, nil
+}
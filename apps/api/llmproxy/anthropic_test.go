@@ -0,0 +1,279 @@
+package llmproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestValidateChatMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     chatMessage
+		wantErr bool
+	}{
+		{name: "plain", msg: chatMessage{Role: "user", Content: "hi"}},
+		{name: "missing content", msg: chatMessage{Role: "user"}, wantErr: true},
+		{name: "assistant tool call without content", msg: chatMessage{Role: "assistant", ToolCalls: []toolCall{{ID: "c1"}}}},
+		{name: "tool without call id", msg: chatMessage{Role: "tool", Content: "42"}, wantErr: true},
+		{name: "tool result", msg: chatMessage{Role: "tool", Content: "42", ToolCallID: "c1"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if err := validateChatMessage(tt.msg); (err != nil) != tt.wantErr {
+				t.Fatalf("validateChatMessage() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAnthropicMessagesToolTurns(t *testing.T) {
+	t.Parallel()
+	system, msgs, err := anthropicMessages([]chatMessage{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "weather?"},
+		{Role: "assistant", ToolCalls: []toolCall{
+			{ID: "c1", Type: "function", Function: toolCallFunction{Name: "weather", Arguments: `{"city":"Paris"}`}},
+			{ID: "c2", Type: "function", Function: toolCallFunction{Name: "weather", Arguments: `{"city":"Rome"}`}},
+		}},
+		{Role: "tool", ToolCallID: "c1", Content: "sunny"},
+		{Role: "tool", ToolCallID: "c2", Content: "rain"},
+	})
+	if err != nil {
+		t.Fatalf("anthropicMessages: %v", err)
+	}
+	if system != "be brief" {
+		t.Fatalf("system = %q", system)
+	}
+	if len(msgs) != 3 {
+		t.Fatalf("len(msgs) = %d, want 3", len(msgs))
+	}
+	out, _ := json.Marshal(msgs)
+	got := string(out)
+	for _, want := range []string{
+		`{"id":"c1","input":{"city":"Paris"},"name":"weather","type":"tool_use"}`,
+		`{"content":[{"content":"sunny","tool_use_id":"c1","type":"tool_result"},{"content":"rain","tool_use_id":"c2","type":"tool_result"}],"role":"user"}`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("messages %s missing %s", got, want)
+		}
+	}
+
+	_, _, err = anthropicMessages([]chatMessage{{Role: "assistant", ToolCalls: []toolCall{{ID: "c1", Function: toolCallFunction{Name: "x", Arguments: "not json"}}}}})
+	if !errors.Is(err, errInvalidToolSpec) {
+		t.Fatalf("expected errInvalidToolSpec, got %v", err)
+	}
+}
+
+func TestAnthropicTools(t *testing.T) {
+	t.Parallel()
+	tools, err := anthropicTools([]json.RawMessage{
+		json.RawMessage(`{"type":"function","function":{"name":"weather","description":"lookup","parameters":{"type":"object","properties":{"city":{"type":"string"}}}}}`),
+		json.RawMessage(`{"name":"native","input_schema":{"type":"object"}}`),
+	})
+	if err != nil {
+		t.Fatalf("anthropicTools: %v", err)
+	}
+	var first map[string]any
+	json.Unmarshal(tools[0], &first)
+	if first["name"] != "weather" || first["description"] != "lookup" || first["input_schema"] == nil {
+		t.Fatalf("converted tool = %s", tools[0])
+	}
+	if string(tools[1]) != `{"name":"native","input_schema":{"type":"object"}}` {
+		t.Fatalf("native tool altered: %s", tools[1])
+	}
+
+	if _, err := anthropicTools([]json.RawMessage{json.RawMessage(`{"type":"function","function":{}}`)}); !errors.Is(err, errInvalidToolSpec) {
+		t.Fatalf("expected errInvalidToolSpec, got %v", err)
+	}
+}
+
+func TestAnthropicToolChoice(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: `"auto"`, want: `{"type":"auto"}`},
+		{in: `"required"`, want: `{"type":"any"}`},
+		{in: `"none"`, want: `{"type":"none"}`},
+		{in: `{"type":"function","function":{"name":"weather"}}`, want: `{"name":"weather","type":"tool"}`},
+		{in: `null`, want: `null`},
+		{in: `"sometimes"`, wantErr: true},
+		{in: `{"type":"function","function":{}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.in, func(t *testing.T) {
+			t.Parallel()
+			got, err := anthropicToolChoice(json.RawMessage(tt.in))
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidToolSpec) {
+					t.Fatalf("expected errInvalidToolSpec, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("anthropicToolChoice: %v", err)
+			}
+			out, _ := json.Marshal(got)
+			if string(out) != tt.want {
+				t.Fatalf("got %s, want %s", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyAnthropicToolUse(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	expectModelAccess(mock, "t1")
+	mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(100))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO usage_logs").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE credits SET balance_cents").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(90))
+
+	var upstream map[string]any
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		json.NewDecoder(req.Body).Decode(&upstream)
+		body := `{"id":"msg_1","content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"toolu_1","name":"weather","input":{"city":"Paris"}}],"stop_reason":"tool_use","usage":{"input_tokens":10,"output_tokens":5}}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+	})}
+
+	model := &Model{ID: "anthropic/claude-3-5-haiku-latest", Provider: "anthropic", ProviderCostInputM: 80, ProviderCostOutputM: 400}
+	proxy := &Proxy{DB: db, Registry: &ModelRegistry{models: map[string]*Model{model.ID: model}}, Client: client}
+
+	reqBody := `{"model":"anthropic/claude-3-5-haiku-latest","messages":[{"role":"user","content":"weather in Paris?"}],` +
+		`"tools":[{"type":"function","function":{"name":"weather","parameters":{"type":"object"}}}],"tool_choice":"auto"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(reqBody))
+	req.Header.Set("X-Tenant-ID", "t1")
+	w := httptest.NewRecorder()
+	proxy.handleChatCompletions(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	if upstream["model"] != "claude-3-5-haiku-latest" {
+		t.Fatalf("upstream model = %v", upstream["model"])
+	}
+	if tools, _ := upstream["tools"].([]any); len(tools) != 1 {
+		t.Fatalf("upstream tools = %v", upstream["tools"])
+	}
+	if choice, _ := upstream["tool_choice"].(map[string]any); choice["type"] != "auto" {
+		t.Fatalf("upstream tool_choice = %v", upstream["tool_choice"])
+	}
+
+	var resp chatResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	choice := resp.Choices[0]
+	if choice.FinishReason != "tool_calls" || choice.Message.Content != "Checking." {
+		t.Fatalf("choice = %+v", choice)
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].ID != "toolu_1" || choice.Message.ToolCalls[0].Function.Arguments != `{"city":"Paris"}` {
+		t.Fatalf("tool calls = %+v", choice.Message.ToolCalls)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestProxyAnthropicPromptCaching(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	expectModelAccess(mock, "t1")
+	mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(100))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO usage_logs").WithArgs("t1", "anthropic/claude-3-5-haiku-latest", 10100, 50, sqlmock.AnyArg(), 0, 1, 1000, 9000).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE credits SET balance_cents").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(90))
+
+	var upstream map[string]any
+	var beta string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		beta = req.Header.Get("anthropic-beta")
+		json.NewDecoder(req.Body).Decode(&upstream)
+		body := `{"id":"msg_1","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn",` +
+			`"usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":1000,"cache_read_input_tokens":9000}}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+	})}
+
+	model := &Model{ID: "anthropic/claude-3-5-haiku-latest", Provider: "anthropic", ProviderCostInputM: 80, ProviderCostOutputM: 400, ProviderCostCacheReadM: 8, ProviderCostCacheWriteM: 100}
+	proxy := &Proxy{DB: db, Registry: &ModelRegistry{models: map[string]*Model{model.ID: model}}, Client: client}
+
+	reqBody := `{"model":"anthropic/claude-3-5-haiku-latest","messages":[` +
+		`{"role":"system","content":[{"type":"text","text":"long rules","cache_control":{"type":"ephemeral"}}]},` +
+		`{"role":"user","content":"hello","cache_control":{"type":"ephemeral"}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(reqBody))
+	req.Header.Set("X-Tenant-ID", "t1")
+	w := httptest.NewRecorder()
+	proxy.handleChatCompletions(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	if beta != anthropicPromptCachingBeta {
+		t.Fatalf("anthropic-beta = %q", beta)
+	}
+	system, _ := json.Marshal(upstream["system"])
+	if string(system) != `[{"cache_control":{"type":"ephemeral"},"text":"long rules","type":"text"}]` {
+		t.Fatalf("upstream system = %s", system)
+	}
+	messages, _ := json.Marshal(upstream["messages"])
+	if string(messages) != `[{"content":[{"cache_control":{"type":"ephemeral"},"text":"hello","type":"text"}],"role":"user"}]` {
+		t.Fatalf("upstream messages = %s", messages)
+	}
+
+	var resp chatResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Usage.PromptTokens != 10100 || resp.Usage.PromptTokensDetails == nil || resp.Usage.PromptTokensDetails.CachedTokens != 9000 {
+		t.Fatalf("usage = %+v", resp.Usage)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestProxyAnthropicRejectsBadToolChoice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	expectModelAccess(mock, "t1")
+	mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(100))
+
+	model := &Model{ID: "anthropic/claude-3-5-haiku-latest", Provider: "anthropic"}
+	proxy := &Proxy{DB: db, Registry: &ModelRegistry{models: map[string]*Model{model.ID: model}}, Client: &http.Client{}}
+	reqBody := `{"model":"anthropic/claude-3-5-haiku-latest","messages":[{"role":"user","content":"hi"}],"tool_choice":"sometimes"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(reqBody))
+	req.Header.Set("X-Tenant-ID", "t1")
+	w := httptest.NewRecorder()
+	proxy.handleChatCompletions(w, req)
+
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "tool_choice") {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+}
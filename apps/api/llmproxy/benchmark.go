@@ -0,0 +1,142 @@
+package llmproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// MaxBenchmarkModels caps the models one benchmark run calls.
+	MaxBenchmarkModels = 8
+	// MaxBenchmarkPromptChars caps the benchmark prompt so a run stays cheap.
+	MaxBenchmarkPromptChars = 2000
+	// MaxBenchmarkTokens caps max_tokens for each benchmark call.
+	MaxBenchmarkTokens = 512
+
+	// benchmarkConcurrency is how many models a run calls at once.
+	benchmarkConcurrency = 4
+	// benchmarkTimeout bounds each model call of a run.
+	benchmarkTimeout = 60 * time.Second
+)
+
+var (
+	// ErrBenchmarkRunning is returned while another benchmark run is in
+	// progress; only one runs at a time.
+	ErrBenchmarkRunning = errors.New("a model benchmark is already running")
+	// ErrInvalidBenchmark is returned for a run outside the model, prompt or
+	// token limits, or naming an unknown model.
+	ErrInvalidBenchmark = errors.New("invalid benchmark")
+)
+
+// BenchmarkResult is one model's measurements in a benchmark run. TTFBMs is
+// nil when the transport did not report the first response byte.
+type BenchmarkResult struct {
+	Model             string  `json:"model"`
+	Provider          string  `json:"provider"`
+	TTFBMs            *int64  `json:"ttfb_ms"`
+	LatencyMs         int64   `json:"latency_ms"`
+	InputTokens       int     `json:"input_tokens"`
+	OutputTokens      int     `json:"output_tokens"`
+	ProviderCostCents float64 `json:"provider_cost_cents"`
+	PriceCents        int     `json:"price_cents"`
+	Attempts          int     `json:"attempts"`
+	Error             string  `json:"error,omitempty"`
+}
+
+// Benchmark sends prompt to each model through the provider functions the
+// proxy uses for tenants, benchmarkConcurrency at a time, and measures time
+// to first byte, total latency, tokens and cost. Tenant credits are never
+// touched; usage is logged against PlatformTenantID when it is set.
+func (p *Proxy) Benchmark(ctx context.Context, modelIDs []string, prompt string, maxTokens int) ([]BenchmarkResult, error) {
+	prompt = strings.TrimSpace(prompt)
+	switch {
+	case len(modelIDs) == 0 || len(modelIDs) > MaxBenchmarkModels:
+		return nil, fmt.Errorf("%w: between 1 and %d models are required", ErrInvalidBenchmark, MaxBenchmarkModels)
+	case prompt == "" || len([]rune(prompt)) > MaxBenchmarkPromptChars:
+		return nil, fmt.Errorf("%w: prompt must be 1 to %d characters", ErrInvalidBenchmark, MaxBenchmarkPromptChars)
+	case maxTokens < 1 || maxTokens > MaxBenchmarkTokens:
+		return nil, fmt.Errorf("%w: max_tokens must be 1 to %d", ErrInvalidBenchmark, MaxBenchmarkTokens)
+	}
+	models := make([]*Model, len(modelIDs))
+	for i, id := range modelIDs {
+		model, err := p.Registry.GetModel(strings.TrimSpace(id))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBenchmark, err)
+		}
+		models[i] = model
+	}
+
+	if !p.benchmarking.CompareAndSwap(false, true) {
+		return nil, ErrBenchmarkRunning
+	}
+	defer p.benchmarking.Store(false)
+
+	results := make([]BenchmarkResult, len(models))
+	slots := make(chan struct{}, benchmarkConcurrency)
+	var wg sync.WaitGroup
+	for i, model := range models {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = p.benchmarkModel(ctx, model, prompt, maxTokens)
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+func (p *Proxy) benchmarkModel(ctx context.Context, model *Model, prompt string, maxTokens int) BenchmarkResult {
+	result := BenchmarkResult{Model: model.ID, Provider: model.Provider}
+	upstreamModel := resolveProviderModelID(model)
+	if upstreamModel == "" {
+		result.Error = "invalid model id"
+		return result
+	}
+	req := chatRequest{
+		Model:     upstreamModel,
+		Messages:  []chatMessage{{Role: "user", Content: prompt}},
+		MaxTokens: &maxTokens,
+	}
+
+	// With retries the last attempt's first byte wins, measured from start.
+	start := time.Now()
+	var firstByte atomic.Int64
+	trace := &httptrace.ClientTrace{GotFirstResponseByte: func() {
+		firstByte.Store(int64(time.Since(start)))
+	}}
+	callCtx, cancel := context.WithTimeout(httptrace.WithClientTrace(ctx, trace), benchmarkTimeout)
+	defer cancel()
+
+	usage, attempts, err := p.callProvider(callCtx, &responseBuffer{}, model.Provider, req)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	result.Attempts = attempts
+	if ttfb := firstByte.Load(); ttfb > 0 {
+		ms := time.Duration(ttfb).Milliseconds()
+		result.TTFBMs = &ms
+	}
+	if err != nil {
+		result.Error = bestOfNErrorMessage(err)
+		return result
+	}
+
+	input, output := usage.Input, usage.Output
+	result.InputTokens = input
+	result.OutputTokens = output
+	result.ProviderCostCents = float64(int64(input)*int64(model.ProviderCostInputM)+int64(output)*int64(model.ProviderCostOutputM)) / 1_000_000
+	result.PriceCents = CalcCostCents(model, input, output)
+	if p.PlatformTenantID != "" && p.DB != nil {
+		if err := BillUsage(p.DB, p.PlatformTenantID, model.ID, input, output, result.PriceCents, attempts); err != nil {
+			slog.Error("failed to log benchmark usage", "model", model.ID, "err", err)
+		}
+	}
+	return result
+}
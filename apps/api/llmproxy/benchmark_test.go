@@ -0,0 +1,106 @@
+package llmproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBenchmark(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO usage_logs").WithArgs("platform", "gpt-4o", 1000, 500, sqlmock.AnyArg(), 0, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE credits SET balance_cents").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		var body struct {
+			Model     string `json:"model"`
+			MaxTokens int    `json:"max_tokens"`
+		}
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		if body.MaxTokens != 64 {
+			t.Errorf("max_tokens = %d, want 64", body.MaxTokens)
+		}
+		if body.Model == "gpt-4-turbo" {
+			return &http.Response{StatusCode: http.StatusBadRequest, Body: io.NopCloser(strings.NewReader(`{"error":{"message":"bad request"}}`)), Header: make(http.Header)}, nil
+		}
+		resp, _ := json.Marshal(map[string]any{
+			"id":      "chatcmpl-1",
+			"model":   body.Model,
+			"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": "Paris"}, "finish_reason": "stop"}},
+			"usage":   map[string]int{"prompt_tokens": 1000, "completion_tokens": 500},
+		})
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(resp)), Header: make(http.Header)}, nil
+	})}
+	registry := &ModelRegistry{models: map[string]*Model{
+		"gpt-4o":      {ID: "gpt-4o", Provider: "openai", ProviderCostInputM: 250, ProviderCostOutputM: 1000, MarkupPct: 20},
+		"gpt-4-turbo": {ID: "gpt-4-turbo", Provider: "openai"},
+	}}
+	proxy := &Proxy{DB: db, Registry: registry, Client: client, PlatformTenantID: "platform"}
+
+	results, err := proxy.Benchmark(context.Background(), []string{"gpt-4o", "gpt-4-turbo"}, "What is the capital of France?", 64)
+	if err != nil {
+		t.Fatalf("Benchmark: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results = %+v", results)
+	}
+	ok, failed := results[0], results[1]
+	if ok.Model != "gpt-4o" || ok.Provider != "openai" || ok.Error != "" || ok.InputTokens != 1000 || ok.OutputTokens != 500 {
+		t.Fatalf("ok result = %+v", ok)
+	}
+	if ok.ProviderCostCents != 0.75 || ok.PriceCents != CalcCostCents(registry.models["gpt-4o"], 1000, 500) || ok.Attempts != 1 {
+		t.Fatalf("ok cost = %+v", ok)
+	}
+	if failed.Model != "gpt-4-turbo" || failed.Error == "" || failed.InputTokens != 0 {
+		t.Fatalf("failed result = %+v", failed)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestBenchmarkLimits(t *testing.T) {
+	proxy := &Proxy{Registry: &ModelRegistry{models: map[string]*Model{"gpt-4o": {ID: "gpt-4o", Provider: "openai"}}}}
+	tooMany := make([]string, MaxBenchmarkModels+1)
+	for i := range tooMany {
+		tooMany[i] = "gpt-4o"
+	}
+
+	cases := []struct {
+		name      string
+		models    []string
+		prompt    string
+		maxTokens int
+	}{
+		{"no models", nil, "hi", 16},
+		{"too many models", tooMany, "hi", 16},
+		{"empty prompt", []string{"gpt-4o"}, "  ", 16},
+		{"long prompt", []string{"gpt-4o"}, strings.Repeat("a", MaxBenchmarkPromptChars+1), 16},
+		{"max tokens", []string{"gpt-4o"}, "hi", MaxBenchmarkTokens + 1},
+		{"unknown model", []string{"nope"}, "hi", 16},
+	}
+	for _, tc := range cases {
+		if _, err := proxy.Benchmark(context.Background(), tc.models, tc.prompt, tc.maxTokens); !errors.Is(err, ErrInvalidBenchmark) {
+			t.Errorf("%s: err = %v, want ErrInvalidBenchmark", tc.name, err)
+		}
+	}
+
+	proxy.benchmarking.Store(true)
+	if _, err := proxy.Benchmark(context.Background(), []string{"gpt-4o"}, "hi", 16); !errors.Is(err, ErrBenchmarkRunning) {
+		t.Fatalf("err = %v, want ErrBenchmarkRunning", err)
+	}
+}
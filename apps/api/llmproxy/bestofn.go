@@ -0,0 +1,333 @@
+package llmproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	// maxBestOfNModels caps the models one best-of-n request fans out to.
+	maxBestOfNModels = 5
+	// defaultBestOfNTimeout bounds all calls of a best-of-n request together.
+	defaultBestOfNTimeout = 60 * time.Second
+	// bestOfNIdealLength is the response length, in characters, at which the
+	// length part of the score stops growing.
+	bestOfNIdealLength = 1200
+)
+
+// bestOfNHedges are phrases that mark a response as unsure or a refusal.
+var bestOfNHedges = []string{
+	"i'm not sure",
+	"i am not sure",
+	"i don't know",
+	"i do not know",
+	"i cannot",
+	"i can't",
+	"as an ai",
+}
+
+// bestOfNRequest is the best-of-n request body: a chat request plus the
+// models to ask. It documents the body; handleBestOfN decodes it with
+// decodeChatRequestWith.
+type bestOfNRequest struct {
+	chatRequest
+	Models []string `json:"models"`
+}
+
+// bestOfNCandidate is one model's outcome in a best-of-n response.
+type bestOfNCandidate struct {
+	Model    string        `json:"model"`
+	Score    float64       `json:"score"`
+	Response *chatResponse `json:"response,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// bestOfNResponse is the winning completion plus every candidate.
+type bestOfNResponse struct {
+	chatResponse
+	Candidates []bestOfNCandidate `json:"candidates"`
+}
+
+type bestOfNCall struct {
+	model           *Model
+	deprecatedModel string
+	req             chatRequest
+	resp            *chatResponse
+	usage           tokenUsage
+	attempts        int
+	err             error
+}
+
+// responseBuffer is an http.ResponseWriter that keeps a provider's response
+// in memory so it can be compared with the others before anything is sent.
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *responseBuffer) Header() http.Header {
+	if b.header == nil {
+		b.header = http.Header{}
+	}
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(status int) { b.status = status }
+
+func (b *responseBuffer) Write(p []byte) (int, error) { return b.body.Write(p) }
+
+// handleBestOfN sends one prompt to every model in "models" concurrently and
+// returns the best-scoring completion, with all candidates and their scores.
+// Every successful call is billed.
+func (p *Proxy) handleBestOfN(w http.ResponseWriter, r *http.Request) {
+	tenantID := strings.TrimSpace(r.Header.Get("X-Tenant-ID"))
+	if tenantID == "" {
+		writeError(w, http.StatusUnauthorized, "missing X-Tenant-ID header")
+		return
+	}
+
+	var modelIDs []string
+	limit := p.maxRequestBytes()
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	req, err := decodeChatRequestWith(r.Body, func(key string, dec *json.Decoder) (bool, error) {
+		if key != "models" {
+			return false, nil
+		}
+		return true, dec.Decode(&modelIDs)
+	})
+	if err != nil {
+		if isBodyTooLarge(err) {
+			writeBodyTooLarge(w, limit)
+			return
+		}
+		if errors.Is(err, errTooManyMessages) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(modelIDs) == 0 {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "", "models is required", "models")
+		return
+	}
+	if len(modelIDs) > maxBestOfNModels {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "", fmt.Sprintf("at most %d models are allowed", maxBestOfNModels), "models")
+		return
+	}
+	if req.Stream {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "", "stream is not supported for best-of-n", "stream")
+		return
+	}
+	if err := validateChatMessages(req.Messages); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	calls := make([]*bestOfNCall, 0, len(modelIDs))
+	for _, id := range modelIDs {
+		resolved, ok := p.resolveModel(w, r, tenantID, strings.TrimSpace(id), "models")
+		if !ok {
+			return
+		}
+		model := resolved.Model
+		upstreamModel := resolveProviderModelID(model)
+		if upstreamModel == "" {
+			writeError(w, http.StatusBadRequest, "invalid model id: "+model.ID)
+			return
+		}
+		callReq := req
+		callReq.Model = upstreamModel
+		calls = append(calls, &bestOfNCall{model: model, deprecatedModel: resolved.deprecatedModel(), req: callReq})
+	}
+
+	models := make([]*Model, len(calls))
+	for i, call := range calls {
+		models[i] = call.model
+	}
+	if !p.checkModelAccess(w, r, tenantID, models...) || !p.checkCreditsAndLimits(w, r, tenantID) {
+		return
+	}
+
+	timeout := p.BestOfNTimeout
+	if timeout <= 0 {
+		timeout = defaultBestOfNTimeout
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, call := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.runBestOfNCall(ctx, tenantID, call)
+		}()
+	}
+	wg.Wait()
+
+	prompt := lastUserMessage(req.Messages)
+	candidates := make([]bestOfNCandidate, len(calls))
+	winner := -1
+	billed := false
+	for i, call := range calls {
+		candidates[i] = bestOfNCandidate{Model: call.model.ID}
+		if call.err != nil {
+			slog.Error("best-of-n upstream error", "provider", call.model.Provider, "model", call.model.ID, "attempts", call.attempts, "err", call.err)
+			candidates[i].Error = bestOfNErrorMessage(call.err)
+			continue
+		}
+		if p.billUsage(tenantID, call.model, call.deprecatedModel, call.usage, call.attempts, "") {
+			billed = true
+		}
+		candidates[i].Response = call.resp
+		candidates[i].Score = scoreCompletion(prompt, call.resp)
+		if winner < 0 || candidates[i].Score > candidates[winner].Score {
+			winner = i
+		}
+	}
+	if billed {
+		p.pauseIfCreditsExhausted(tenantID)
+	}
+
+	if winner < 0 {
+		err := calls[0].err
+		if errors.Is(err, errInvalidToolSpec) || errors.Is(err, errUnsupportedProvider) {
+			writeError(w, http.StatusBadRequest, bestOfNErrorMessage(err))
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			writeOpenAIError(w, http.StatusGatewayTimeout, "api_error", "timeout", "all models timed out", "")
+			return
+		}
+		var limitErr *concurrencyLimitError
+		if errors.As(err, &limitErr) {
+			writeAcquireError(w, tenantID, err)
+			return
+		}
+		writeUpstreamError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bestOfNResponse{chatResponse: *calls[winner].resp, Candidates: candidates})
+}
+
+// runBestOfNCall makes one model's call into a buffer and decodes the
+// OpenAI-shaped completion every provider writes on success. Each call takes
+// its own concurrency slot.
+func (p *Proxy) runBestOfNCall(ctx context.Context, tenantID string, call *bestOfNCall) {
+	release, err := p.acquireSlot(ctx, tenantID, call.model.Provider)
+	if err != nil {
+		call.err = err
+		return
+	}
+	defer release()
+	buf := &responseBuffer{}
+	call.usage, call.attempts, call.err = p.callProvider(ctx, buf, call.model.Provider, call.req)
+	if call.err != nil {
+		return
+	}
+	var resp chatResponse
+	if err := json.Unmarshal(buf.body.Bytes(), &resp); err != nil || len(resp.Choices) == 0 {
+		call.err = errors.New("upstream returned an unreadable completion")
+		return
+	}
+	call.resp = &resp
+}
+
+func bestOfNErrorMessage(err error) string {
+	var upstream *upstreamError
+	if errors.As(err, &upstream) {
+		_, _, _, message, _ := upstream.openAIError()
+		return message
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timed out"
+	}
+	return err.Error()
+}
+
+// scoreCompletion estimates confidence in a completion between 0 and 1. Up
+// to 0.6 comes from length, saturating at bestOfNIdealLength characters, and
+// up to 0.4 from how many of the prompt's keywords the answer mentions.
+// Hedging and truncated answers are marked down.
+func scoreCompletion(prompt string, resp *chatResponse) float64 {
+	if resp == nil || len(resp.Choices) == 0 {
+		return 0
+	}
+	choice := resp.Choices[0]
+	content := strings.TrimSpace(choice.Message.Content)
+	if content == "" && len(choice.Message.ToolCalls) == 0 {
+		return 0
+	}
+
+	score := 0.6 * math.Min(float64(len([]rune(content)))/bestOfNIdealLength, 1)
+	if keywords := promptKeywords(prompt); len(keywords) > 0 {
+		answer := strings.ToLower(content)
+		matched := 0
+		for _, kw := range keywords {
+			if strings.Contains(answer, kw) {
+				matched++
+			}
+		}
+		score += 0.4 * float64(matched) / float64(len(keywords))
+	} else {
+		score += 0.2
+	}
+	if len(choice.Message.ToolCalls) > 0 {
+		score = math.Max(score, 0.5)
+	}
+
+	lower := strings.ToLower(content)
+	for _, hedge := range bestOfNHedges {
+		if strings.Contains(lower, hedge) {
+			score -= 0.2
+			break
+		}
+	}
+	if choice.FinishReason == "length" {
+		score -= 0.1
+	}
+	return math.Round(math.Max(score, 0)*1000) / 1000
+}
+
+// promptKeywords returns the distinct words of four or more letters in
+// prompt, lowercased.
+func promptKeywords(prompt string) []string {
+	seen := make(map[string]struct{})
+	var keywords []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(prompt), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) < 4 {
+			continue
+		}
+		if _, ok := seen[word]; ok {
+			continue
+		}
+		seen[word] = struct{}{}
+		keywords = append(keywords, word)
+	}
+	return keywords
+}
+
+func lastUserMessage(messages []chatMessage) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+package llmproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestHandleBestOfN(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	expectModelAccess(mock, "t1")
+	mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(100))
+	for range 2 {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO usage_logs").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE credits SET balance_cents").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+	}
+	mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(90))
+
+	replies := map[string]string{
+		"gpt-4o-mini": "I'm not sure.",
+		"gpt-4o":      "Paris is the capital of France, on the Seine.",
+		"gpt-4-turbo": "",
+	}
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		if body.Model == "gpt-4-turbo" {
+			return &http.Response{StatusCode: http.StatusBadRequest, Body: io.NopCloser(strings.NewReader(`{"error":{"message":"bad request"}}`)), Header: make(http.Header)}, nil
+		}
+		resp, _ := json.Marshal(map[string]any{
+			"id":      "chatcmpl-" + body.Model,
+			"model":   body.Model,
+			"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": replies[body.Model]}, "finish_reason": "stop"}},
+			"usage":   map[string]int{"prompt_tokens": 10, "completion_tokens": 10},
+		})
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(resp)), Header: make(http.Header)}, nil
+	})}
+
+	registry := &ModelRegistry{models: map[string]*Model{
+		"gpt-4o":      {ID: "gpt-4o", Provider: "openai"},
+		"gpt-4o-mini": {ID: "gpt-4o-mini", Provider: "openai"},
+		"gpt-4-turbo": {ID: "gpt-4-turbo", Provider: "openai"},
+	}}
+	proxy := &Proxy{DB: db, Registry: registry, Client: client, BestOfNTimeout: time.Second}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions/best-of-n", strings.NewReader(`{"models":["gpt-4o-mini","gpt-4o","gpt-4-turbo"],"messages":[{"role":"user","content":"What is the capital of France?"}]}`))
+	req.Header.Set("X-Tenant-ID", "t1")
+	w := httptest.NewRecorder()
+	proxy.handleBestOfN(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+
+	var resp bestOfNResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Model != "gpt-4o" || len(resp.Choices) != 1 || !strings.Contains(resp.Choices[0].Message.Content, "Paris") {
+		t.Fatalf("winner = %+v", resp.chatResponse)
+	}
+	if len(resp.Candidates) != 3 || resp.Candidates[0].Score >= resp.Candidates[1].Score || resp.Candidates[2].Error == "" || resp.Candidates[2].Response != nil {
+		t.Fatalf("candidates = %+v", resp.Candidates)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestHandleBestOfNValidation(t *testing.T) {
+	t.Parallel()
+	registry := &ModelRegistry{models: map[string]*Model{"gpt-4o": {ID: "gpt-4o", Provider: "openai"}}}
+	proxy := &Proxy{Registry: registry, Client: &http.Client{}}
+
+	tests := map[string]string{
+		"models is required":      `{"messages":[{"role":"user","content":"hi"}]}`,
+		"at most 5 models":        `{"models":["gpt-4o","gpt-4o","gpt-4o","gpt-4o","gpt-4o","gpt-4o"],"messages":[{"role":"user","content":"hi"}]}`,
+		"stream is not supported": `{"models":["gpt-4o"],"stream":true,"messages":[{"role":"user","content":"hi"}]}`,
+		"model not found":         `{"models":["missing"],"messages":[{"role":"user","content":"hi"}]}`,
+		"messages are required":   `{"models":["gpt-4o"]}`,
+		"invalid request body":    `{"models":["gpt-4o"],"extra":1}`,
+	}
+	for want, body := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions/best-of-n", strings.NewReader(body))
+		req.Header.Set("X-Tenant-ID", "t1")
+		w := httptest.NewRecorder()
+		proxy.handleBestOfN(w, req)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), want) {
+			t.Fatalf("%s: status = %d body=%s", want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestScoreCompletion(t *testing.T) {
+	t.Parallel()
+	reply := func(content, finish string) *chatResponse {
+		return &chatResponse{Choices: []chatChoice{{Message: chatMessage{Role: "assistant", Content: content}, FinishReason: finish}}}
+	}
+	prompt := "Explain how goroutines are scheduled"
+	relevant := scoreCompletion(prompt, reply("Goroutines are scheduled by the Go runtime onto OS threads.", "stop"))
+	offTopic := scoreCompletion(prompt, reply("Bananas are yellow and grow in bunches on large plants.", "stop"))
+	hedged := scoreCompletion(prompt, reply("I'm not sure how goroutines are scheduled.", "stop"))
+	truncated := scoreCompletion(prompt, reply("Goroutines are scheduled by the Go runtime onto OS threads.", "length"))
+	if !(relevant > offTopic && relevant > hedged && relevant > truncated) {
+		t.Fatalf("relevant=%v offTopic=%v hedged=%v truncated=%v", relevant, offTopic, hedged, truncated)
+	}
+	if got := scoreCompletion(prompt, reply("  ", "stop")); got != 0 {
+		t.Fatalf("empty score = %v", got)
+	}
+}
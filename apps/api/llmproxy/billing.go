@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"strings"
 )
 
 // CheckCredits returns the tenant's balance in cents. Returns error on DB failure.
@@ -17,8 +18,34 @@ func CheckCredits(db *sql.DB, tenantID string) (int, error) {
 }
 
 // BillUsage records a usage log and deducts credits.
-// costCents is the total cost including markup.
-func BillUsage(db *sql.DB, tenantID string, modelID string, inputTokens, outputTokens, costCents int) error {
+// costCents is the total cost including markup; attempts is the number of
+// upstream requests made, including retries.
+func BillUsage(db *sql.DB, tenantID string, modelID string, inputTokens, outputTokens, costCents, attempts int) error {
+	return BillUsageWithFinishReason(db, tenantID, modelID, inputTokens, outputTokens, costCents, attempts, "")
+}
+
+// BillUsageWithFinishReason is BillUsage for a call that ended unusually,
+// such as a stream the client abandoned. An empty finishReason is not
+// recorded.
+func BillUsageWithFinishReason(db *sql.DB, tenantID string, modelID string, inputTokens, outputTokens, costCents, attempts int, finishReason string) error {
+	return BillCachedUsage(db, tenantID, modelID, inputTokens, outputTokens, CacheUsage{}, costCents, attempts, finishReason)
+}
+
+// BillCachedUsage is BillUsageWithFinishReason for a call that went through
+// the provider's prompt cache. inputTokens includes the cached tokens; the
+// cache split is recorded alongside it when there is one.
+func BillCachedUsage(db *sql.DB, tenantID string, modelID string, inputTokens, outputTokens int, cache CacheUsage, costCents, attempts int, finishReason string) error {
+	return BillAliasedUsage(db, tenantID, modelID, "", inputTokens, outputTokens, cache, costCents, attempts, finishReason)
+}
+
+// BillAliasedUsage is BillCachedUsage for a call that asked for a deprecated
+// model id. modelID is the model that served and priced the call;
+// deprecatedModel, the id the caller asked for, is recorded when not empty.
+func BillAliasedUsage(db *sql.DB, tenantID string, modelID string, deprecatedModel string, inputTokens, outputTokens int, cache CacheUsage, costCents, attempts int, finishReason string) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+
 	tx, err := db.Begin()
 	if err != nil {
 		return fmt.Errorf("begin tx: %w", err)
@@ -26,9 +53,42 @@ func BillUsage(db *sql.DB, tenantID string, modelID string, inputTokens, outputT
 	defer tx.Rollback()
 
 	// Insert usage log
+	columns := []string{"tenant_id", "model", "input_tokens", "output_tokens", "cost_cents", "margin_cents", "attempts"}
+	args := []any{tenantID, modelID, inputTokens, outputTokens, costCents, 0, attempts}
+	if finishReason != "" {
+		columns = append(columns, "finish_reason")
+		args = append(args, finishReason)
+	}
+	if !cache.Empty() {
+		columns = append(columns, "cache_creation_tokens", "cache_read_tokens")
+		args = append(args, cache.CreationTokens, cache.ReadTokens)
+	}
+	if deprecatedModel != "" {
+		columns = append(columns, "deprecated_model")
+		args = append(args, deprecatedModel)
+	}
+	placeholders := make([]string, len(args))
+	for i := range args {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	// The log is added to today's usage_daily row in the same statement so
+	// dashboards reading the rollup see it straight away.
 	_, err = tx.Exec(
-		`INSERT INTO usage_logs (tenant_id, model, input_tokens, output_tokens, cost_cents, margin_cents) VALUES ($1, $2, $3, $4, $5, $6)`,
-		tenantID, modelID, inputTokens, outputTokens, costCents, 0,
+		`WITH logged AS (
+			INSERT INTO usage_logs (`+strings.Join(columns, ", ")+`) VALUES (`+strings.Join(placeholders, ", ")+`)
+			RETURNING tenant_id, model, input_tokens, output_tokens, cost_cents, margin_cents, created_at
+		)
+		INSERT INTO usage_daily (tenant_id, model, day, requests, input_tokens, output_tokens, cost_cents, margin_cents, last_used_at)
+		SELECT tenant_id, model, (created_at AT TIME ZONE 'UTC')::date, 1, input_tokens, output_tokens, cost_cents, margin_cents, created_at
+		FROM logged
+		ON CONFLICT (tenant_id, model, day) DO UPDATE
+		SET requests = usage_daily.requests + 1,
+			input_tokens = usage_daily.input_tokens + EXCLUDED.input_tokens,
+			output_tokens = usage_daily.output_tokens + EXCLUDED.output_tokens,
+			cost_cents = usage_daily.cost_cents + EXCLUDED.cost_cents,
+			margin_cents = usage_daily.margin_cents + EXCLUDED.margin_cents,
+			last_used_at = GREATEST(usage_daily.last_used_at, EXCLUDED.last_used_at)`,
+		args...,
 	)
 	if err != nil {
 		return fmt.Errorf("insert usage_log: %w", err)
@@ -47,15 +107,25 @@ func BillUsage(db *sql.DB, tenantID string, modelID string, inputTokens, outputT
 		return fmt.Errorf("commit: %w", err)
 	}
 
-	slog.Info("billed usage", "tenant", tenantID, "model", modelID, "input", inputTokens, "output", outputTokens, "cost_cents", costCents)
+	slog.Info("billed usage", "tenant", tenantID, "model", modelID, "input", inputTokens, "output", outputTokens, "cache_creation", cache.CreationTokens, "cache_read", cache.ReadTokens, "cost_cents", costCents, "attempts", attempts, "finish_reason", finishReason, "deprecated_model", deprecatedModel)
 	return nil
 }
 
 // CalcCostCents calculates the cost in cents given token counts and model pricing.
 func CalcCostCents(m *Model, inputTokens, outputTokens int) int {
+	return CalcCachedCostCents(m, inputTokens, outputTokens, CacheUsage{})
+}
+
+// CalcCachedCostCents is CalcCostCents for a call that went through the
+// prompt cache. inputTokens includes the cached tokens, which are billed at
+// the model's cache rates instead of its input rate.
+func CalcCachedCostCents(m *Model, inputTokens, outputTokens int, cache CacheUsage) int {
 	// Cost = (input_tokens * input_per_m / 1_000_000 + output_tokens * output_per_m / 1_000_000) * (1 + markup/100)
 	// All in cents. Use int64 to avoid overflow.
-	inputCost := int64(inputTokens) * int64(m.ProviderCostInputM)
+	uncached := max(inputTokens-cache.CreationTokens-cache.ReadTokens, 0)
+	inputCost := int64(uncached)*int64(m.ProviderCostInputM) +
+		int64(cache.CreationTokens)*int64(m.cacheWriteRate()) +
+		int64(cache.ReadTokens)*int64(m.cacheReadRate())
 	outputCost := int64(outputTokens) * int64(m.ProviderCostOutputM)
 	baseCost := (inputCost + outputCost) / 1_000_000
 	totalCost := baseCost * int64(100+m.MarkupPct) / 100
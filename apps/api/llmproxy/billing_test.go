@@ -76,7 +76,7 @@ func TestBillUsage(t *testing.T) {
 			name: "happy path",
 			setup: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectExec("INSERT INTO usage_logs").WithArgs("tenant-1", "m1", 10, 20, 3, 0).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO usage_logs").WithArgs("tenant-1", "m1", 10, 20, 3, 0, 1).WillReturnResult(sqlmock.NewResult(1, 1))
 				mock.ExpectExec("UPDATE credits SET balance_cents").WithArgs(3, "tenant-1").WillReturnResult(sqlmock.NewResult(1, 1))
 				mock.ExpectCommit()
 			},
@@ -113,7 +113,7 @@ func TestBillUsage(t *testing.T) {
 			defer db.Close()
 			tt.setup(mock)
 
-			err = BillUsage(db, "tenant-1", "m1", 10, 20, 3)
+			err = BillUsage(db, "tenant-1", "m1", 10, 20, 3, 1)
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("BillUsage() err = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -146,7 +146,7 @@ func TestBillUsageConcurrentDeductions(t *testing.T) {
 			mock.ExpectExec("UPDATE credits SET balance_cents").WillReturnResult(sqlmock.NewResult(1, 1))
 			mock.ExpectCommit()
 
-			if err := BillUsage(db, "tenant-c", "m1", i+1, i+2, 1); err != nil {
+			if err := BillUsage(db, "tenant-c", "m1", i+1, i+2, 1, 1); err != nil {
 				errCh <- err
 				return
 			}
@@ -189,6 +189,42 @@ func TestCalcCostCents(t *testing.T) {
 	}
 }
 
+func TestCalcCachedCostCents(t *testing.T) {
+	t.Parallel()
+	cache := CacheUsage{CreationTokens: 250_000, ReadTokens: 500_000}
+	priced := &Model{ProviderCostInputM: 100, ProviderCostOutputM: 200, ProviderCostCacheReadM: 10, ProviderCostCacheWriteM: 125}
+	// 250k uncached at 100, 250k written at 125, 500k read at 10.
+	if got := CalcCachedCostCents(priced, 1_000_000, 0, cache); got != 61 {
+		t.Fatalf("CalcCachedCostCents() = %d, want 61", got)
+	}
+	// Without cache rates every input token is billed at the input rate.
+	unpriced := &Model{ProviderCostInputM: 100, ProviderCostOutputM: 200}
+	if got := CalcCachedCostCents(unpriced, 1_000_000, 0, cache); got != 100 {
+		t.Fatalf("CalcCachedCostCents() without cache rates = %d, want 100", got)
+	}
+}
+
+func TestBillCachedUsageRecordsSplit(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO usage_logs \(tenant_id, model, input_tokens, output_tokens, cost_cents, margin_cents, attempts, cache_creation_tokens, cache_read_tokens\)`).
+		WithArgs("tenant-1", "m1", 1000, 20, 3, 0, 1, 100, 800).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE credits SET balance_cents").WithArgs(3, "tenant-1").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := BillCachedUsage(db, "tenant-1", "m1", 1000, 20, CacheUsage{CreationTokens: 100, ReadTokens: 800}, 3, 1, ""); err != nil {
+		t.Fatalf("BillCachedUsage: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
 type assertErr struct{}
 
 func (assertErr) Error() string { return "boom" }
@@ -0,0 +1,374 @@
+package llmproxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultMaxRequestBytes leaves room for long-context prompts; the API's
+	// global 1 MiB body limit does not apply to the chat completions route.
+	defaultMaxRequestBytes   int64 = 16 << 20
+	maxRequestBytesEnvName         = "LLM_PROXY_MAX_REQUEST_BYTES"
+	requestTooLargeErrorCode       = "request_too_large"
+)
+
+// loadMaxRequestBytesFromEnv reads LLM_PROXY_MAX_REQUEST_BYTES. Invalid or
+// non-positive values fall back to the default.
+func loadMaxRequestBytesFromEnv() int64 {
+	if v := strings.TrimSpace(os.Getenv(maxRequestBytesEnvName)); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultMaxRequestBytes
+}
+
+func (p *Proxy) maxRequestBytes() int64 {
+	if p.MaxRequestBytes > 0 {
+		return p.MaxRequestBytes
+	}
+	return defaultMaxRequestBytes
+}
+
+// isBodyTooLarge reports whether err came from reading past an
+// http.MaxBytesReader limit.
+func isBodyTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
+
+func writeBodyTooLarge(w http.ResponseWriter, limit int64) {
+	writeOpenAIError(w, http.StatusRequestEntityTooLarge, "invalid_request_error", requestTooLargeErrorCode,
+		fmt.Sprintf("request body exceeds %d bytes", limit), "")
+}
+
+// errTooManyMessages stops decoding as soon as the limit is passed, before
+// the rest of an oversized conversation is read.
+var errTooManyMessages = errors.New("too many messages")
+
+// decodeChatRequest decodes a chat completion request token by token. A
+// plain json.Decoder.Decode buffers the whole document before unmarshalling;
+// here only one message is held in the decoder at a time. Unknown fields and
+// trailing data are rejected.
+func decodeChatRequest(r io.Reader) (chatRequest, error) {
+	return decodeChatRequestWith(r, nil)
+}
+
+// decodeChatRequestWith is decodeChatRequest for endpoints that accept
+// fields beyond a chat completion. extra is offered each unknown key first
+// and reports whether it consumed the value.
+func decodeChatRequestWith(r io.Reader, extra func(key string, dec *json.Decoder) (bool, error)) (chatRequest, error) {
+	var req chatRequest
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := expectDelim(dec, '{'); err != nil {
+		return req, err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return req, err
+		}
+		key, _ := tok.(string)
+		switch key {
+		case "model":
+			err = dec.Decode(&req.Model)
+		case "messages":
+			req.Messages, err = decodeChatMessages(dec)
+		case "temperature":
+			err = dec.Decode(&req.Temperature)
+		case "max_tokens":
+			err = dec.Decode(&req.MaxTokens)
+		case "stream":
+			err = dec.Decode(&req.Stream)
+		case "tools":
+			err = dec.Decode(&req.Tools)
+		case "tool_choice":
+			err = dec.Decode(&req.ToolChoice)
+		case "prompt_cache_key":
+			err = dec.Decode(&req.PromptCacheKey)
+		case "prompt_cache_retention":
+			err = dec.Decode(&req.PromptCacheRetention)
+		default:
+			handled := false
+			if extra != nil {
+				handled, err = extra(key, dec)
+			}
+			if err == nil && !handled {
+				return req, fmt.Errorf("json: unknown field %q", key)
+			}
+		}
+		if err != nil {
+			return req, err
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return req, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		if isBodyTooLarge(err) {
+			return req, err
+		}
+		return req, errors.New("request body must contain a single JSON object")
+	}
+	return req, nil
+}
+
+func decodeChatMessages(dec *json.Decoder) ([]chatMessage, error) {
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("messages must be an array")
+	}
+	var messages []chatMessage
+	for dec.More() {
+		if len(messages) == maxChatMessages {
+			return nil, errTooManyMessages
+		}
+		var wire chatMessageJSON
+		if err := dec.Decode(&wire); err != nil {
+			return nil, err
+		}
+		msg, err := wire.message()
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, expectDelim(dec, ']')
+}
+
+// chatMessageJSON is a chat message as clients send it. It is decoded with
+// the request's decoder, so unknown fields are still rejected.
+type chatMessageJSON struct {
+	Role         string          `json:"role"`
+	Content      json.RawMessage `json:"content"`
+	ToolCalls    []toolCall      `json:"tool_calls"`
+	ToolCallID   string          `json:"tool_call_id"`
+	CacheControl json.RawMessage `json:"cache_control"`
+}
+
+// message converts m, accepting content as a string, null or an array of
+// content blocks. For blocks, Content is set to their text joined by
+// newlines.
+func (m chatMessageJSON) message() (chatMessage, error) {
+	msg := chatMessage{Role: m.Role, ToolCalls: m.ToolCalls, ToolCallID: m.ToolCallID}
+	if len(m.CacheControl) > 0 && string(m.CacheControl) != "null" {
+		msg.CacheControl = m.CacheControl
+	}
+	content := bytes.TrimSpace(m.Content)
+	switch {
+	case len(content) == 0 || string(content) == "null":
+	case content[0] == '"':
+		if err := json.Unmarshal(content, &msg.Content); err != nil {
+			return msg, err
+		}
+	case content[0] == '[':
+		if err := json.Unmarshal(content, &msg.Blocks); err != nil {
+			return msg, err
+		}
+		var texts []string
+		for _, raw := range msg.Blocks {
+			var block struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal(raw, &block); err != nil {
+				return msg, errors.New("content blocks must be objects")
+			}
+			if block.Type == "text" {
+				texts = append(texts, block.Text)
+			}
+		}
+		msg.Content = strings.Join(texts, "\n")
+	default:
+		return msg, errors.New("content must be a string or an array of content blocks")
+	}
+	return msg, nil
+}
+
+// MarshalJSON writes m in the OpenAI shape, with content as the original
+// blocks when it arrived in array form. cache_control is Anthropic-only and
+// is left out, including on the blocks.
+func (m chatMessage) MarshalJSON() ([]byte, error) {
+	type plain chatMessage
+	if len(m.Blocks) == 0 {
+		return json.Marshal(plain(m))
+	}
+	blocks := make([]json.RawMessage, len(m.Blocks))
+	for i, raw := range m.Blocks {
+		blocks[i] = withoutCacheControl(raw)
+	}
+	return json.Marshal(struct {
+		plain
+		Content []json.RawMessage `json:"content"`
+	}{plain(m), blocks})
+}
+
+// withoutCacheControl returns block without its cache_control key. Blocks
+// without one are returned unchanged.
+func withoutCacheControl(block json.RawMessage) json.RawMessage {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(block, &fields) != nil {
+		return block
+	}
+	if _, ok := fields["cache_control"]; !ok {
+		return block
+	}
+	delete(fields, "cache_control")
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return block
+	}
+	return out
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("json: expected %q", want)
+	}
+	return nil
+}
+
+// openAIRequestBody is req as sent to OpenAI, in the map form jsonBody
+// streams. It matches chatRequest's omitempty tags.
+func openAIRequestBody(req chatRequest) map[string]any {
+	body := map[string]any{"model": req.Model, "messages": req.Messages}
+	if req.Temperature != nil {
+		body["temperature"] = *req.Temperature
+	}
+	if req.MaxTokens != nil {
+		body["max_tokens"] = *req.MaxTokens
+	}
+	if req.Stream {
+		body["stream"] = true
+	}
+	if len(req.Tools) > 0 {
+		body["tools"] = req.Tools
+	}
+	if len(req.ToolChoice) > 0 {
+		body["tool_choice"] = req.ToolChoice
+	}
+	if req.PromptCacheKey != "" {
+		body["prompt_cache_key"] = req.PromptCacheKey
+	}
+	if req.PromptCacheRetention != "" {
+		body["prompt_cache_retention"] = req.PromptCacheRetention
+	}
+	return body
+}
+
+// jsonBody streams v into the upstream request instead of marshalling it
+// into one buffer first. Maps and message lists are written an element at a
+// time, so a large prompt is not copied again in full. The length is unknown
+// up front and the request is sent chunked. The encoder goroutine exits once
+// the body is read or closed.
+func jsonBody(v any) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		bw := bufio.NewWriter(pw)
+		err := writeJSONStream(bw, v)
+		if err == nil {
+			err = bw.Flush()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+func writeJSONStream(w *bufio.Writer, v any) error {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		w.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := writeJSONValue(w, k); err != nil {
+				return err
+			}
+			w.WriteByte(':')
+			if err := writeJSONStream(w, v[k]); err != nil {
+				return err
+			}
+		}
+		return w.WriteByte('}')
+	case []chatMessage:
+		return writeJSONArray(w, v)
+	case []map[string]any:
+		return writeJSONArray(w, v)
+	default:
+		return writeJSONValue(w, v)
+	}
+}
+
+func writeJSONArray[T any](w *bufio.Writer, items []T) error {
+	if items == nil {
+		_, err := w.WriteString("null")
+		return err
+	}
+	w.WriteByte('[')
+	for i, item := range items {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		if err := writeJSONStream(w, item); err != nil {
+			return err
+		}
+	}
+	return w.WriteByte(']')
+}
+
+func writeJSONValue(w *bufio.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// copyOpenAIResponse streams an OpenAI completion to w while decoding only
+// its usage block. A body that is not JSON is still forwarded, with no usage.
+func copyOpenAIResponse(w io.Writer, body io.Reader) (usage tokenUsage, err error) {
+	tee := io.TeeReader(body, w)
+	var parsed struct {
+		Usage map[string]any `json:"usage"`
+	}
+	if decodeErr := json.NewDecoder(tee).Decode(&parsed); decodeErr == nil {
+		usage = openAIUsage(parsed.Usage)
+	}
+	// Forward whatever the decoder did not consume, such as a trailing
+	// newline or the rest of a body it could not parse.
+	_, err = io.Copy(io.Discard, tee)
+	return usage, err
+}
+
+// openAIUsage reads an OpenAI usage block. prompt_tokens already includes
+// the cached tokens.
+func openAIUsage(block map[string]any) tokenUsage {
+	body := map[string]any{"usage": block}
+	input, output := ExtractOpenAIUsage(body)
+	return tokenUsage{Input: input, Output: output, Cache: ExtractOpenAICacheUsage(body)}
+}
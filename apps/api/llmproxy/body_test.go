@@ -0,0 +1,208 @@
+package llmproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestChatCompletionsBodyTooLarge(t *testing.T) {
+	t.Parallel()
+	proxy := &Proxy{Registry: &ModelRegistry{models: map[string]*Model{}}, MaxRequestBytes: 64}
+
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":"` + strings.Repeat("a", 128) + `"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("X-Tenant-ID", "t1")
+	w := httptest.NewRecorder()
+	proxy.handleChatCompletions(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var got errorBody
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Error.Code == nil || *got.Error.Code != requestTooLargeErrorCode {
+		t.Fatalf("error = %+v", got.Error)
+	}
+}
+
+func TestProxyOpenAIStreamsRequestAndResponse(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	expectModelAccess(mock, "t1")
+	mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(100))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO usage_logs").WithArgs("t1", "openai/gpt-4.1", 12, 3, sqlmock.AnyArg(), sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE credits SET balance_cents").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(90))
+
+	upstreamBody := `{"id":"chatcmpl-1","choices":[{"index":0,"message":{"role":"assistant","content":"hi"}}],"usage":{"prompt_tokens":12,"completion_tokens":3}}` + "\n"
+	var upstream chatRequest
+	var chunked bool
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		chunked = req.ContentLength <= 0
+		json.NewDecoder(req.Body).Decode(&upstream)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(upstreamBody)), Header: make(http.Header)}, nil
+	})}
+
+	model := &Model{ID: "openai/gpt-4.1", Provider: "openai"}
+	proxy := &Proxy{DB: db, Registry: &ModelRegistry{models: map[string]*Model{model.ID: model}}, Client: client}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(`{"model":"openai/gpt-4.1","messages":[{"role":"user","content":"hello"}]}`))
+	req.Header.Set("X-Tenant-ID", "t1")
+	w := httptest.NewRecorder()
+	proxy.handleChatCompletions(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != upstreamBody {
+		t.Fatalf("status = %d body=%q", w.Code, w.Body.String())
+	}
+	if upstream.Model != "gpt-4.1" || len(upstream.Messages) != 1 || upstream.Messages[0].Content != "hello" || !chunked {
+		t.Fatalf("upstream request = %+v chunked=%v", upstream, chunked)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestCopyOpenAIResponseForwardsUnparseableBody(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	usage, err := copyOpenAIResponse(&out, strings.NewReader("data: not json\n\ndata: [DONE]\n"))
+	if err != nil || usage != (tokenUsage{}) {
+		t.Fatalf("copyOpenAIResponse = %+v, %v", usage, err)
+	}
+	if out.String() != "data: not json\n\ndata: [DONE]\n" {
+		t.Fatalf("forwarded body = %q", out.String())
+	}
+}
+
+func TestDecodeChatRequest(t *testing.T) {
+	t.Parallel()
+	req, err := decodeChatRequest(strings.NewReader(`{"messages":[{"role":"user","content":"hi"}],"model":"gpt-4o","temperature":0.5,"tool_choice":"auto"}`))
+	if err != nil {
+		t.Fatalf("decodeChatRequest: %v", err)
+	}
+	if req.Model != "gpt-4o" || len(req.Messages) != 1 || req.Temperature == nil || *req.Temperature != 0.5 || string(req.ToolChoice) != `"auto"` {
+		t.Fatalf("request = %+v", req)
+	}
+
+	many := `{"model":"m","messages":[` + strings.TrimSuffix(strings.Repeat(`{"role":"user","content":"x"},`, maxChatMessages+1), ",") + `]}`
+	for body, want := range map[string]string{
+		`{"model":"m","extra":1}`:                          "unknown field",
+		`{"model":"m","messages":[{"role":"user","x":1}]}`: "unknown field",
+		`{"model":"m"}{}`:                                  "single JSON object",
+		`["model"]`:                                        "expected",
+		many:                                               errTooManyMessages.Error(),
+	} {
+		if _, err := decodeChatRequest(strings.NewReader(body)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("decodeChatRequest(%.40s) err = %v, want %q", body, err, want)
+		}
+	}
+}
+
+func TestDecodeChatRequestContentBlocks(t *testing.T) {
+	t.Parallel()
+	body := `{"model":"gpt-4o","prompt_cache_key":"agent-7","messages":[` +
+		`{"role":"system","content":[{"type":"text","text":"rules"},{"type":"text","text":"more","cache_control":{"type":"ephemeral"}}]},` +
+		`{"role":"user","content":"hi","cache_control":{"type":"ephemeral"}}]}`
+	req, err := decodeChatRequest(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("decodeChatRequest: %v", err)
+	}
+	system, user := req.Messages[0], req.Messages[1]
+	if system.Content != "rules\nmore" || len(system.Blocks) != 2 || len(system.CacheControl) != 0 {
+		t.Fatalf("system = %+v", system)
+	}
+	if user.Content != "hi" || len(user.Blocks) != 0 || string(user.CacheControl) != `{"type":"ephemeral"}` {
+		t.Fatalf("user = %+v", user)
+	}
+
+	// OpenAI gets the blocks and the cache key, but no cache_control.
+	out, err := io.ReadAll(jsonBody(openAIRequestBody(req)))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	got := string(out)
+	if !strings.Contains(got, `"prompt_cache_key":"agent-7"`) || !strings.Contains(got, `"content":[{"type":"text","text":"rules"},{"text":"more","type":"text"}]`) ||
+		strings.Contains(got, "cache_control") {
+		t.Fatalf("openai body = %s", got)
+	}
+
+	for _, bad := range []string{
+		`{"model":"m","messages":[{"role":"user","content":5}]}`,
+		`{"model":"m","messages":[{"role":"user","content":["text"]}]}`,
+	} {
+		if _, err := decodeChatRequest(strings.NewReader(bad)); err == nil {
+			t.Fatalf("decodeChatRequest(%s) succeeded", bad)
+		}
+	}
+}
+
+func TestJSONBodyMatchesMarshal(t *testing.T) {
+	t.Parallel()
+	temp := 0.2
+	req := chatRequest{Model: "gpt-4.1", Messages: []chatMessage{{Role: "user", Content: "a <b> & c"}}, Temperature: &temp, Stream: true}
+	streamed, err := io.ReadAll(jsonBody(openAIRequestBody(req)))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	var got, want map[string]any
+	json.Unmarshal(streamed, &got)
+	buffered, _ := json.Marshal(req)
+	json.Unmarshal(buffered, &want)
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if string(gotJSON) != string(wantJSON) {
+		t.Fatalf("streamed = %s, want %s", streamed, buffered)
+	}
+}
+
+// BenchmarkChatRequestBody compares the previous request path, which decoded
+// the whole document and marshalled the upstream body into one buffer, with
+// decodeChatRequest and jsonBody, for a 5 MB prompt split across messages.
+// Run with -benchmem: B/op falls from about 28 MB to 11 MB, the decoded
+// messages themselves.
+func BenchmarkChatRequestBody(b *testing.B) {
+	messages := make([]chatMessage, 50)
+	for i := range messages {
+		messages[i] = chatMessage{Role: "user", Content: strings.Repeat("lorem ipsum ", 5<<20/12/len(messages))}
+	}
+	raw, _ := json.Marshal(chatRequest{Model: "gpt-4.1", Messages: messages})
+
+	b.Run("buffered", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(raw)))
+		for i := 0; i < b.N; i++ {
+			var req chatRequest
+			if err := json.NewDecoder(bytes.NewReader(raw)).Decode(&req); err != nil {
+				b.Fatal(err)
+			}
+			body, _ := json.Marshal(req)
+			io.Copy(io.Discard, bytes.NewReader(body))
+		}
+	})
+	b.Run("streamed", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(raw)))
+		for i := 0; i < b.N; i++ {
+			req, err := decodeChatRequest(bytes.NewReader(raw))
+			if err != nil {
+				b.Fatal(err)
+			}
+			io.Copy(io.Discard, jsonBody(openAIRequestBody(req)))
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package llmproxy
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTenantConcurrency       = 4
+	defaultProviderConcurrency     = 64
+	defaultConcurrencyQueueTimeout = 10 * time.Second
+	tenantConcurrencyEnvName       = "LLM_PROXY_TENANT_CONCURRENCY"
+	providerConcurrencyEnvName     = "LLM_PROXY_PROVIDER_CONCURRENCY"
+	concurrencyQueueTimeoutEnvName = "LLM_PROXY_CONCURRENCY_QUEUE_TIMEOUT"
+	concurrencyLimitErrorType      = "concurrency_limit"
+)
+
+// In-flight upstream calls per tenant and per provider, and how many calls
+// had to queue or were turned away. Published through expvar at /debug/vars.
+var (
+	tenantInFlight   = expvar.NewMap("llmproxy_inflight_tenants")
+	providerInFlight = expvar.NewMap("llmproxy_inflight_providers")
+	concurrencyStats = expvar.NewMap("llmproxy_concurrency")
+)
+
+// concurrencyLimitError is returned when a call waited the full queue timeout
+// for a free tenant or provider slot.
+type concurrencyLimitError struct {
+	scope string // "tenant" or "provider"
+	limit int
+}
+
+func (e *concurrencyLimitError) Error() string {
+	return fmt.Sprintf("%s concurrency limit of %d in-flight requests reached", e.scope, e.limit)
+}
+
+// concurrencyLimiter counts in-flight upstream calls per tenant and per
+// provider. Limits are passed on every acquire because a tenant's limit
+// follows its plan, which can change while calls are running.
+type concurrencyLimiter struct {
+	mu        sync.Mutex
+	tenants   map[string]int
+	providers map[string]int
+	// released is closed and replaced whenever a slot frees up, waking every
+	// waiter to try again.
+	released chan struct{}
+}
+
+func newConcurrencyLimiter() *concurrencyLimiter {
+	return &concurrencyLimiter{
+		tenants:   make(map[string]int),
+		providers: make(map[string]int),
+		released:  make(chan struct{}),
+	}
+}
+
+// acquire takes one tenant slot and one provider slot, waiting up to timeout
+// for both to be free. A non-positive provider limit leaves the provider
+// uncapped. The returned release must be called once the call is done.
+func (l *concurrencyLimiter) acquire(ctx context.Context, tenantID string, tenantLimit int, provider string, providerLimit int, timeout time.Duration) (func(), error) {
+	var deadline <-chan time.Time
+	queued := false
+	for {
+		l.mu.Lock()
+		tenantFull := l.tenants[tenantID] >= tenantLimit
+		providerFull := providerLimit > 0 && l.providers[provider] >= providerLimit
+		if !tenantFull && !providerFull {
+			l.tenants[tenantID]++
+			l.providers[provider]++
+			l.mu.Unlock()
+			tenantInFlight.Add(tenantID, 1)
+			providerInFlight.Add(provider, 1)
+			var once sync.Once
+			return func() { once.Do(func() { l.release(tenantID, provider) }) }, nil
+		}
+		released := l.released
+		l.mu.Unlock()
+
+		if !queued {
+			queued = true
+			concurrencyStats.Add("queued", 1)
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+		select {
+		case <-released:
+		case <-deadline:
+			concurrencyStats.Add("rejected", 1)
+			if tenantFull {
+				return nil, &concurrencyLimitError{scope: "tenant", limit: tenantLimit}
+			}
+			return nil, &concurrencyLimitError{scope: "provider", limit: providerLimit}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (l *concurrencyLimiter) release(tenantID, provider string) {
+	l.mu.Lock()
+	if l.tenants[tenantID]--; l.tenants[tenantID] <= 0 {
+		delete(l.tenants, tenantID)
+	}
+	if l.providers[provider]--; l.providers[provider] <= 0 {
+		delete(l.providers, provider)
+	}
+	close(l.released)
+	l.released = make(chan struct{})
+	l.mu.Unlock()
+	tenantInFlight.Add(tenantID, -1)
+	providerInFlight.Add(provider, -1)
+}
+
+// TenantInFlight is one tenant's in-flight upstream calls.
+type TenantInFlight struct {
+	TenantID string `json:"tenant_id"`
+	InFlight int    `json:"in_flight"`
+}
+
+// InFlightSnapshot is the proxy's current upstream concurrency.
+type InFlightSnapshot struct {
+	Tenants       []TenantInFlight `json:"tenants"`
+	Providers     map[string]int   `json:"providers"`
+	TenantLimit   int              `json:"default_tenant_limit"`
+	ProviderLimit int              `json:"provider_limit"`
+}
+
+// InFlight reports the upstream calls currently running, busiest tenant
+// first. Tenants with nothing in flight are left out.
+func (p *Proxy) InFlight() InFlightSnapshot {
+	l := p.concurrencyLimiter()
+	snap := InFlightSnapshot{
+		Tenants:       []TenantInFlight{},
+		Providers:     map[string]int{},
+		TenantLimit:   p.defaultTenantConcurrency(),
+		ProviderLimit: p.ProviderConcurrency,
+	}
+	l.mu.Lock()
+	for id, n := range l.tenants {
+		snap.Tenants = append(snap.Tenants, TenantInFlight{TenantID: id, InFlight: n})
+	}
+	for provider, n := range l.providers {
+		snap.Providers[provider] = n
+	}
+	l.mu.Unlock()
+	sort.Slice(snap.Tenants, func(i, j int) bool {
+		if snap.Tenants[i].InFlight != snap.Tenants[j].InFlight {
+			return snap.Tenants[i].InFlight > snap.Tenants[j].InFlight
+		}
+		return snap.Tenants[i].TenantID < snap.Tenants[j].TenantID
+	})
+	return snap
+}
+
+func (p *Proxy) concurrencyLimiter() *concurrencyLimiter {
+	p.concurrencyOnce.Do(func() { p.concurrency = newConcurrencyLimiter() })
+	return p.concurrency
+}
+
+func (p *Proxy) defaultTenantConcurrency() int {
+	if p.TenantConcurrency > 0 {
+		return p.TenantConcurrency
+	}
+	return defaultTenantConcurrency
+}
+
+// tenantConcurrency is the tenant plan's in-flight limit, or the proxy
+// default for tenants without a plan or whose plan does not set one.
+func (p *Proxy) tenantConcurrency(ctx context.Context, tenantID string) (int, error) {
+	if p.Plans != nil {
+		plan, err := p.Plans.ForTenant(ctx, tenantID)
+		if err != nil {
+			return 0, err
+		}
+		if plan != nil && plan.MaxConcurrentRequests > 0 {
+			return plan.MaxConcurrentRequests, nil
+		}
+	}
+	return p.defaultTenantConcurrency(), nil
+}
+
+// acquireSlot waits for a free tenant and provider slot for one upstream
+// call. Errors are concurrencyLimitError, a plan lookup failure or the
+// context's error.
+func (p *Proxy) acquireSlot(ctx context.Context, tenantID, provider string) (func(), error) {
+	limit, err := p.tenantConcurrency(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	timeout := p.ConcurrencyQueueTimeout
+	if timeout <= 0 {
+		timeout = defaultConcurrencyQueueTimeout
+	}
+	return p.concurrencyLimiter().acquire(ctx, tenantID, limit, provider, p.ProviderConcurrency, timeout)
+}
+
+// writeAcquireError writes the response for a failed acquireSlot.
+func writeAcquireError(w http.ResponseWriter, tenantID string, err error) {
+	var limitErr *concurrencyLimitError
+	switch {
+	case errors.As(err, &limitErr):
+		w.Header().Set("Retry-After", "1")
+		writeOpenAIError(w, http.StatusTooManyRequests, concurrencyLimitErrorType, "concurrency_limit_exceeded", limitErr.Error(), "")
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		writeOpenAIError(w, http.StatusGatewayTimeout, "api_error", "timeout", "request ended while queued for a free slot", "")
+	default:
+		slog.Error("plan concurrency lookup failed", "tenant", tenantID, "err", err)
+		writeError(w, http.StatusInternalServerError, "plan lookup error")
+	}
+}
+
+// loadConcurrencyFromEnv reads LLM_PROXY_TENANT_CONCURRENCY,
+// LLM_PROXY_PROVIDER_CONCURRENCY and LLM_PROXY_CONCURRENCY_QUEUE_TIMEOUT.
+// Invalid or non-positive values fall back to the defaults.
+func loadConcurrencyFromEnv() (tenant, provider int, queueTimeout time.Duration) {
+	tenant, provider, queueTimeout = defaultTenantConcurrency, defaultProviderConcurrency, defaultConcurrencyQueueTimeout
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv(tenantConcurrencyEnvName))); err == nil && n > 0 {
+		tenant = n
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv(providerConcurrencyEnvName))); err == nil && n > 0 {
+		provider = n
+	}
+	if d, err := time.ParseDuration(strings.TrimSpace(os.Getenv(concurrencyQueueTimeoutEnvName))); err == nil && d > 0 {
+		queueTimeout = d
+	}
+	return tenant, provider, queueTimeout
+}
@@ -0,0 +1,105 @@
+package llmproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestConcurrencyLimiterQueuesUntilRelease(t *testing.T) {
+	t.Parallel()
+	l := newConcurrencyLimiter()
+	ctx := context.Background()
+
+	first, err := l.acquire(ctx, "t1", 1, "openai", 0, time.Second)
+	if err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+	// Another tenant is not held back by t1's limit.
+	other, err := l.acquire(ctx, "t2", 1, "openai", 0, time.Millisecond)
+	if err != nil {
+		t.Fatalf("other tenant: %v", err)
+	}
+	other()
+
+	got := make(chan error, 1)
+	go func() {
+		release, err := l.acquire(ctx, "t1", 1, "openai", 0, time.Second)
+		if err == nil {
+			release()
+		}
+		got <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	first()
+	first() // releasing twice must not free a second slot
+	if err := <-got; err != nil {
+		t.Fatalf("queued acquire: %v", err)
+	}
+	if len(l.tenants) != 0 || len(l.providers) != 0 {
+		t.Fatalf("counts after release: tenants=%v providers=%v", l.tenants, l.providers)
+	}
+}
+
+func TestConcurrencyLimiterTimesOut(t *testing.T) {
+	t.Parallel()
+	l := newConcurrencyLimiter()
+	ctx := context.Background()
+
+	held, err := l.acquire(ctx, "t1", 4, "anthropic", 1, time.Second)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	defer held()
+	_, err = l.acquire(ctx, "t2", 4, "anthropic", 1, 10*time.Millisecond)
+	var limitErr *concurrencyLimitError
+	if !errors.As(err, &limitErr) || limitErr.scope != "provider" || limitErr.limit != 1 {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestChatCompletionsConcurrencyLimit(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	expectModelAccess(mock, "t1")
+	mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(100))
+
+	model := &Model{ID: "openai/gpt-4.1", Provider: "openai"}
+	proxy := &Proxy{DB: db, Registry: &ModelRegistry{models: map[string]*Model{model.ID: model}}, TenantConcurrency: 1, ConcurrencyQueueTimeout: 10 * time.Millisecond}
+	release, err := proxy.acquireSlot(context.Background(), "t1", "openai")
+	if err != nil {
+		t.Fatalf("acquireSlot: %v", err)
+	}
+	defer release()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(`{"model":"openai/gpt-4.1","messages":[{"role":"user","content":"hello"}]}`))
+	req.Header.Set("X-Tenant-ID", "t1")
+	w := httptest.NewRecorder()
+	proxy.handleChatCompletions(w, req)
+
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("status = %d headers=%v body=%s", w.Code, w.Header(), w.Body.String())
+	}
+	var got errorBody
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Error.Type != concurrencyLimitErrorType {
+		t.Fatalf("body = %s (%v)", w.Body.String(), err)
+	}
+	snap := proxy.InFlight()
+	if len(snap.Tenants) != 1 || snap.Tenants[0].TenantID != "t1" || snap.Tenants[0].InFlight != 1 || snap.Providers["openai"] != 1 {
+		t.Fatalf("in flight = %+v", snap)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
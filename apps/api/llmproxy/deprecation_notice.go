@@ -0,0 +1,171 @@
+package llmproxy
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/channels"
+	"github.com/redis/go-redis/v9"
+)
+
+// deprecationNoticePeriod is how often a tenant is reminded about one
+// deprecated model id, and how far back its usage is looked up.
+const deprecationNoticePeriod = 7 * 24 * time.Hour
+
+// DeprecationNotifier tells tenants through their linked channels that they
+// still call deprecated model ids. Each tenant hears about an id at most once
+// per week, however many API instances run the job.
+type DeprecationNotifier struct {
+	db      *sql.DB
+	publish func(ctx context.Context, out channels.OutboundMessage) error
+	now     func() time.Time
+}
+
+// NewDeprecationNotifier creates a notifier that publishes to the tenants'
+// outbound channel streams.
+func NewDeprecationNotifier(db *sql.DB, rdb *redis.Client) *DeprecationNotifier {
+	return &DeprecationNotifier{
+		db: db,
+		publish: func(ctx context.Context, out channels.OutboundMessage) error {
+			return channels.PublishOutbound(ctx, rdb, out)
+		},
+		now: time.Now,
+	}
+}
+
+// deprecatedUsage is one tenant's calls to one deprecated id in the last week.
+type deprecatedUsage struct {
+	aliasID       string
+	replacementID string
+	effectiveAt   time.Time
+	requests      int64
+}
+
+// Start notifies tenants every interval until ctx is done. The interval only
+// sets how quickly new usage is noticed; reminders stay weekly.
+func (n *DeprecationNotifier) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			notified, err := n.Notify(ctx)
+			if err != nil {
+				slog.Warn("model deprecation notices failed", "err", err)
+			} else if notified > 0 {
+				slog.Info("sent model deprecation notices", "tenants", notified)
+			}
+		}
+	}
+}
+
+// Notify sends one message to every tenant that called a deprecated id in
+// the last week and was not told about it in that time. It returns the number
+// of tenants notified.
+func (n *DeprecationNotifier) Notify(ctx context.Context) (int, error) {
+	now := n.now()
+	rows, err := n.db.QueryContext(ctx, `
+		SELECT u.tenant_id, u.deprecated_model, a.replacement_id, a.effective_at, COUNT(*)
+		FROM usage_logs u
+		JOIN model_aliases a ON a.alias_id = u.deprecated_model
+		WHERE u.deprecated_model IS NOT NULL AND u.created_at >= $1
+		GROUP BY u.tenant_id, u.deprecated_model, a.replacement_id, a.effective_at
+		ORDER BY u.tenant_id, u.deprecated_model`,
+		now.Add(-deprecationNoticePeriod),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("query deprecated model usage: %w", err)
+	}
+	var tenants []string
+	byTenant := make(map[string][]deprecatedUsage)
+	for rows.Next() {
+		var (
+			tenantID string
+			u        deprecatedUsage
+		)
+		if err := rows.Scan(&tenantID, &u.aliasID, &u.replacementID, &u.effectiveAt, &u.requests); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan deprecated model usage: %w", err)
+		}
+		if _, ok := byTenant[tenantID]; !ok {
+			tenants = append(tenants, tenantID)
+		}
+		byTenant[tenantID] = append(byTenant[tenantID], u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("iterate deprecated model usage: %w", err)
+	}
+
+	notified := 0
+	for _, tenantID := range tenants {
+		var due []deprecatedUsage
+		for _, u := range byTenant[tenantID] {
+			claimed, err := n.claim(ctx, tenantID, u.aliasID, now)
+			if err != nil {
+				return notified, err
+			}
+			if claimed {
+				due = append(due, u)
+			}
+		}
+		if len(due) == 0 {
+			continue
+		}
+		out := channels.OutboundMessage{
+			TenantID: tenantID,
+			Content:  deprecationNoticeText(due, now),
+			Metadata: map[string]string{"event": "model_deprecation"},
+		}
+		if err := n.publish(ctx, out); err != nil {
+			slog.Error("failed to publish model deprecation notice", "tenant", tenantID, "err", err)
+			continue
+		}
+		notified++
+	}
+	return notified, nil
+}
+
+// claim records that the tenant is being told about aliasID now, unless it
+// already was within the last week. The hour of slack keeps a daily job from
+// drifting a day late every week.
+func (n *DeprecationNotifier) claim(ctx context.Context, tenantID, aliasID string, now time.Time) (bool, error) {
+	var claimed bool
+	err := n.db.QueryRowContext(ctx, `
+		INSERT INTO model_deprecation_notices (tenant_id, alias_id, notified_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id, alias_id) DO UPDATE
+		SET notified_at = EXCLUDED.notified_at
+		WHERE model_deprecation_notices.notified_at <= $4
+		RETURNING TRUE`,
+		tenantID, aliasID, now, now.Add(-deprecationNoticePeriod+time.Hour),
+	).Scan(&claimed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("claim deprecation notice: %w", err)
+	}
+	return claimed, nil
+}
+
+func deprecationNoticeText(usage []deprecatedUsage, now time.Time) string {
+	var b strings.Builder
+	b.WriteString("Your agents are still using deprecated models:")
+	for _, u := range usage {
+		date := u.effectiveAt.UTC().Format(time.DateOnly)
+		if now.Before(u.effectiveAt) {
+			fmt.Fprintf(&b, "\n- %s (%d requests this week) is retired on %s; requests will then be served by %s.", u.aliasID, u.requests, date, u.replacementID)
+		} else {
+			fmt.Fprintf(&b, "\n- %s (%d requests this week) was retired on %s and is served by %s.", u.aliasID, u.requests, date, u.replacementID)
+		}
+	}
+	b.WriteString("\nUpdate your agent configuration to the replacement model.")
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package llmproxy
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/channels"
+)
+
+func TestDeprecationNotifierNotifiesOncePerWeek(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2026, 5, 10, 9, 0, 0, 0, time.UTC)
+	var sent []channels.OutboundMessage
+	n := &DeprecationNotifier{
+		db:  db,
+		now: func() time.Time { return now },
+		publish: func(_ context.Context, out channels.OutboundMessage) error {
+			sent = append(sent, out)
+			return nil
+		},
+	}
+
+	mock.ExpectQuery("FROM usage_logs u").WithArgs(now.Add(-deprecationNoticePeriod)).WillReturnRows(
+		sqlmock.NewRows([]string{"tenant_id", "deprecated_model", "replacement_id", "effective_at", "count"}).
+			AddRow("t1", "claude-2", "claude-3-5-haiku", now.Add(-24*time.Hour), 4).
+			AddRow("t1", "gpt-4-0613", "gpt-4o", now.Add(72*time.Hour), 12).
+			AddRow("t2", "gpt-4-0613", "gpt-4o", now.Add(72*time.Hour), 1))
+	claimed := sqlmock.NewRows([]string{"claimed"}).AddRow(true)
+	mock.ExpectQuery("INSERT INTO model_deprecation_notices").WithArgs("t1", "claude-2", now, sqlmock.AnyArg()).WillReturnRows(claimed)
+	mock.ExpectQuery("INSERT INTO model_deprecation_notices").WithArgs("t1", "gpt-4-0613", now, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"claimed"}).AddRow(true))
+	// t2 was already told within the last week.
+	mock.ExpectQuery("INSERT INTO model_deprecation_notices").WithArgs("t2", "gpt-4-0613", now, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"claimed"}))
+
+	notified, err := n.Notify(context.Background())
+	if err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if notified != 1 || len(sent) != 1 || sent[0].TenantID != "t1" {
+		t.Fatalf("notified = %d, sent = %+v", notified, sent)
+	}
+	text := sent[0].Content
+	if !strings.Contains(text, "claude-2 (4 requests this week) was retired") || !strings.Contains(text, "gpt-4-0613 (12 requests this week) is retired on 2026-05-13") {
+		t.Fatalf("notice text = %q", text)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
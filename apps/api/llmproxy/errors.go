@@ -0,0 +1,169 @@
+package llmproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const maxUpstreamMessageLen = 512
+
+// errorBody is the OpenAI error envelope. param and code are always present
+// (null when unset) because SDKs switch on them.
+type errorBody struct {
+	Error errorObject `json:"error"`
+}
+
+type errorObject struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"`
+	Code    *string `json:"code"`
+}
+
+func writeOpenAIError(w http.ResponseWriter, status int, errType, code, message, param string) {
+	obj := errorObject{Message: message, Type: errType}
+	if code != "" {
+		obj.Code = &code
+	}
+	if param != "" {
+		obj.Param = &param
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorBody{Error: obj})
+}
+
+// upstreamError is a non-2xx response from a provider.
+type upstreamError struct {
+	provider   string
+	status     int
+	body       []byte
+	retryAfter string
+}
+
+func newUpstreamError(provider string, resp *http.Response, body []byte) *upstreamError {
+	return &upstreamError{
+		provider:   provider,
+		status:     resp.StatusCode,
+		body:       body,
+		retryAfter: resp.Header.Get("Retry-After"),
+	}
+}
+
+func (e *upstreamError) Error() string {
+	return fmt.Sprintf("%s returned %d: %s", e.provider, e.status, string(e.body))
+}
+
+// providerError pulls the message, code and param out of an upstream error
+// body. OpenAI, Anthropic and Gemini all nest them under "error"; some
+// gateways send "error" as a bare string.
+func (e *upstreamError) providerError() (message, code, param string) {
+	var parsed struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(e.body, &parsed); err == nil && len(parsed.Error) > 0 {
+		var obj struct {
+			Message string `json:"message"`
+			Code    any    `json:"code"`
+			Param   any    `json:"param"`
+		}
+		if err := json.Unmarshal(parsed.Error, &obj); err == nil {
+			message = obj.Message
+			code, _ = obj.Code.(string)
+			param, _ = obj.Param.(string)
+		} else {
+			json.Unmarshal(parsed.Error, &message)
+		}
+	}
+	if strings.TrimSpace(message) == "" {
+		message = string(e.body)
+	}
+	if strings.TrimSpace(message) == "" {
+		message = http.StatusText(e.status)
+	}
+	return sanitizeUpstreamMessage(message), code, sanitizeUpstreamMessage(param)
+}
+
+// openAIError maps the upstream failure to an OpenAI-compatible status, type
+// and code. Auth and server failures on the provider side are ours, not the
+// caller's, so they surface as 502.
+func (e *upstreamError) openAIError() (status int, errType, code, message, param string) {
+	message, upstreamCode, param := e.providerError()
+	switch {
+	case e.status == http.StatusTooManyRequests:
+		return http.StatusTooManyRequests, "rate_limit_error", "rate_limit_exceeded", message, ""
+	case isContextLengthError(upstreamCode, message):
+		return http.StatusBadRequest, "invalid_request_error", "context_length_exceeded", message, param
+	case e.status == http.StatusBadRequest, e.status == http.StatusNotFound,
+		e.status == http.StatusRequestEntityTooLarge, e.status == http.StatusUnprocessableEntity:
+		return http.StatusBadRequest, "invalid_request_error", upstreamCode, message, param
+	default:
+		return http.StatusBadGateway, "upstream_error", "", fmt.Sprintf("upstream error: %s returned %d: %s", e.provider, e.status, message), ""
+	}
+}
+
+var contextLengthMarkers = []string{
+	"context_length_exceeded",
+	"maximum context length",
+	"context window",
+	"context limit",
+	"prompt is too long",
+	"exceeds the maximum number of tokens",
+}
+
+func isContextLengthError(code, message string) bool {
+	if code == "context_length_exceeded" {
+		return true
+	}
+	lower := strings.ToLower(message)
+	for _, marker := range contextLengthMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+var apiKeyParam = regexp.MustCompile(`(?i)(key=)[^&\s"]+`)
+
+// sanitizeUpstreamMessage makes provider text safe to echo to callers: it
+// drops control characters, redacts key= query params and caps the length.
+func sanitizeUpstreamMessage(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+	s = apiKeyParam.ReplaceAllString(strings.TrimSpace(s), "${1}REDACTED")
+	if runes := []rune(s); len(runes) > maxUpstreamMessageLen {
+		s = string(runes[:maxUpstreamMessageLen]) + "..."
+	}
+	return s
+}
+
+// writeUpstreamError writes the OpenAI-compatible error for a failed provider
+// call. Transport errors are unwrapped so request URLs (which carry the
+// Gemini API key) never reach the client.
+func writeUpstreamError(w http.ResponseWriter, err error) {
+	var upErr *upstreamError
+	if errors.As(err, &upErr) {
+		status, errType, code, message, param := upErr.openAIError()
+		if status == http.StatusTooManyRequests && upErr.retryAfter != "" {
+			w.Header().Set("Retry-After", upErr.retryAfter)
+		}
+		writeOpenAIError(w, status, errType, code, message, param)
+		return
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	writeOpenAIError(w, http.StatusBadGateway, "upstream_error", "", "upstream error: "+sanitizeUpstreamMessage(err.Error()), "")
+}
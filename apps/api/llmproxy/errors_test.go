@@ -0,0 +1,180 @@
+package llmproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUpstreamErrorMapping(t *testing.T) {
+	models := map[string]*Model{
+		"openai/gpt-4.1":              {ID: "openai/gpt-4.1", Provider: "openai"},
+		"anthropic/claude-sonnet-4-6": {ID: "anthropic/claude-sonnet-4-6", Provider: "anthropic"},
+		"google/gemini-2.5-pro":       {ID: "google/gemini-2.5-pro", Provider: "google"},
+	}
+
+	tests := []struct {
+		name           string
+		model          string
+		upstreamStatus int
+		upstreamBody   string
+		retryAfter     string
+		wantStatus     int
+		wantType       string
+		wantCode       string
+		wantMessage    string
+	}{
+		{
+			name: "openai rate limit", model: "openai/gpt-4.1",
+			upstreamStatus: 429, retryAfter: "7",
+			upstreamBody: `{"error":{"message":"Rate limit reached for gpt-4.1","type":"requests","code":"rate_limit_exceeded"}}`,
+			wantStatus:   429, wantType: "rate_limit_error", wantCode: "rate_limit_exceeded", wantMessage: "Rate limit reached",
+		},
+		{
+			name: "anthropic rate limit", model: "anthropic/claude-sonnet-4-6",
+			upstreamStatus: 429, retryAfter: "3",
+			upstreamBody: `{"type":"error","error":{"type":"rate_limit_error","message":"Number of request tokens has exceeded your per-minute rate limit"}}`,
+			wantStatus:   429, wantType: "rate_limit_error", wantCode: "rate_limit_exceeded", wantMessage: "per-minute rate limit",
+		},
+		{
+			name: "gemini rate limit", model: "google/gemini-2.5-pro",
+			upstreamStatus: 429, retryAfter: "5",
+			upstreamBody: `{"error":{"code":429,"message":"Resource has been exhausted (e.g. check quota).","status":"RESOURCE_EXHAUSTED"}}`,
+			wantStatus:   429, wantType: "rate_limit_error", wantCode: "rate_limit_exceeded", wantMessage: "Resource has been exhausted",
+		},
+		{
+			name: "openai context length", model: "openai/gpt-4.1",
+			upstreamStatus: 400,
+			upstreamBody:   `{"error":{"message":"This model's maximum context length is 128000 tokens.","type":"invalid_request_error","param":"messages","code":"context_length_exceeded"}}`,
+			wantStatus:     400, wantType: "invalid_request_error", wantCode: "context_length_exceeded", wantMessage: "maximum context length",
+		},
+		{
+			name: "anthropic context length", model: "anthropic/claude-sonnet-4-6",
+			upstreamStatus: 400,
+			upstreamBody:   `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`,
+			wantStatus:     400, wantType: "invalid_request_error", wantCode: "context_length_exceeded", wantMessage: "prompt is too long",
+		},
+		{
+			name: "gemini context length", model: "google/gemini-2.5-pro",
+			upstreamStatus: 400,
+			upstreamBody:   `{"error":{"code":400,"message":"The input token count (1200000) exceeds the maximum number of tokens allowed (1048576).","status":"INVALID_ARGUMENT"}}`,
+			wantStatus:     400, wantType: "invalid_request_error", wantCode: "context_length_exceeded", wantMessage: "exceeds the maximum number of tokens",
+		},
+		{
+			name: "openai bad request", model: "openai/gpt-4.1",
+			upstreamStatus: 400,
+			upstreamBody:   `{"error":{"message":"Invalid value for temperature","type":"invalid_request_error","param":"temperature","code":"invalid_value"}}`,
+			wantStatus:     400, wantType: "invalid_request_error", wantCode: "invalid_value", wantMessage: "Invalid value for temperature",
+		},
+		{
+			name: "anthropic overloaded", model: "anthropic/claude-sonnet-4-6",
+			upstreamStatus: 529,
+			upstreamBody:   `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			wantStatus:     502, wantType: "upstream_error", wantMessage: "upstream error: anthropic returned 529: Overloaded",
+		},
+		{
+			name: "gemini auth failure", model: "google/gemini-2.5-pro",
+			upstreamStatus: 403,
+			upstreamBody:   `{"error":{"code":403,"message":"Permission denied for key=AIzaSECRET","status":"PERMISSION_DENIED"}}`,
+			wantStatus:     502, wantType: "upstream_error", wantMessage: "key=REDACTED",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New: %v", err)
+			}
+			defer db.Close()
+			expectModelAccess(mock, "t1")
+			mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(100))
+
+			proxy := &Proxy{
+				DB:       db,
+				Registry: &ModelRegistry{models: models},
+				Client: &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+					header := make(http.Header)
+					if tt.retryAfter != "" {
+						header.Set("Retry-After", tt.retryAfter)
+					}
+					return &http.Response{StatusCode: tt.upstreamStatus, Body: io.NopCloser(strings.NewReader(tt.upstreamBody)), Header: header}, nil
+				})},
+				sleep: func(time.Duration) {},
+			}
+
+			body := `{"model":"` + tt.model + `","messages":[{"role":"user","content":"hi"}]}`
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(body))
+			req.Header.Set("X-Tenant-ID", "t1")
+			w := httptest.NewRecorder()
+			proxy.handleChatCompletions(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d body=%s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			var got errorBody
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.Error.Type != tt.wantType {
+				t.Fatalf("type = %q, want %q", got.Error.Type, tt.wantType)
+			}
+			gotCode := ""
+			if got.Error.Code != nil {
+				gotCode = *got.Error.Code
+			}
+			if gotCode != tt.wantCode {
+				t.Fatalf("code = %q, want %q", gotCode, tt.wantCode)
+			}
+			if !strings.Contains(got.Error.Message, tt.wantMessage) {
+				t.Fatalf("message %q does not contain %q", got.Error.Message, tt.wantMessage)
+			}
+			if tt.wantStatus == http.StatusTooManyRequests && w.Header().Get("Retry-After") != tt.retryAfter {
+				t.Fatalf("Retry-After = %q, want %q", w.Header().Get("Retry-After"), tt.retryAfter)
+			}
+			if strings.Contains(w.Body.String(), "AIzaSECRET") {
+				t.Fatalf("response leaks api key: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestLocalErrorShapes(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	expectModelAccess(mock, "t1")
+	mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(0))
+
+	proxy := &Proxy{DB: db, Registry: &ModelRegistry{models: map[string]*Model{"gpt-4o": {ID: "gpt-4o", Provider: "openai"}}}}
+	send := func(model string) (int, errorObject) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(`{"model":"`+model+`","messages":[{"role":"user","content":"hi"}]}`))
+		req.Header.Set("X-Tenant-ID", "t1")
+		w := httptest.NewRecorder()
+		proxy.handleChatCompletions(w, req)
+		var got errorBody
+		json.Unmarshal(w.Body.Bytes(), &got)
+		return w.Code, got.Error
+	}
+
+	status, obj := send("gpt-4o")
+	if status != http.StatusPaymentRequired || obj.Type != "insufficient_quota" || obj.Code == nil || *obj.Code != "insufficient_credits" {
+		t.Fatalf("insufficient credits = %d %+v", status, obj)
+	}
+	status, obj = send("nope")
+	if status != http.StatusBadRequest || obj.Code == nil || *obj.Code != "model_not_found" || obj.Param == nil || *obj.Param != "model" {
+		t.Fatalf("unknown model = %d %+v", status, obj)
+	}
+}
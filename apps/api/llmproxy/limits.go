@@ -0,0 +1,101 @@
+package llmproxy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/agentsquads/api/plans"
+)
+
+// planLimitError is returned when a tenant request exceeds a plan limit.
+type planLimitError struct {
+	status  int
+	errType string
+	code    string
+	message string
+}
+
+func (e *planLimitError) Error() string { return e.message }
+
+// rateLimiter is a fixed one-minute window counter per tenant.
+type rateLimiter struct {
+	mu      sync.Mutex
+	windows map[string]rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{windows: make(map[string]rateWindow)}
+}
+
+// allow records a request and reports whether it fits within limit per minute.
+func (l *rateLimiter) allow(tenantID string, limit int, now time.Time) bool {
+	if limit <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	win := l.windows[tenantID]
+	if now.Sub(win.start) >= time.Minute {
+		win = rateWindow{start: now}
+	}
+	if win.count >= limit {
+		l.windows[tenantID] = win
+		return false
+	}
+	win.count++
+	l.windows[tenantID] = win
+	return true
+}
+
+// enforcePlanLimits applies the tenant plan's request rate and monthly token
+// cap. Tenants without a plan are not limited here.
+func (p *Proxy) enforcePlanLimits(ctx context.Context, tenantID string) error {
+	if p.Plans == nil {
+		return nil
+	}
+	plan, err := p.Plans.ForTenant(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	if plan == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if plan.RPMLimit > 0 {
+		p.limiterOnce.Do(func() { p.limiter = newRateLimiter() })
+		if !p.limiter.allow(tenantID, plan.RPMLimit, now) {
+			return &planLimitError{
+				status:  429,
+				errType: "rate_limit_error",
+				code:    "rate_limit_exceeded",
+				message: fmt.Sprintf("rate limit of %d requests per minute exceeded for plan %s", plan.RPMLimit, plan.Name),
+			}
+		}
+	}
+
+	if plan.MonthlyTokenCap > 0 {
+		used, err := p.Plans.MonthlyTokenUsage(ctx, tenantID, now)
+		if err != nil {
+			return err
+		}
+		if used >= plan.MonthlyTokenCap {
+			_, renewal := plans.BillingPeriod(now)
+			return &planLimitError{
+				status:  402,
+				errType: "insufficient_quota",
+				code:    "monthly_token_cap_exceeded",
+				message: fmt.Sprintf("monthly token cap of %d tokens reached for plan %s; usage resets on %s", plan.MonthlyTokenCap, plan.Name, renewal.Format("2006-01-02")),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package llmproxy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	t.Parallel()
+	l := newRateLimiter()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := range 3 {
+		if !l.allow("t1", 3, now.Add(time.Duration(i)*time.Second)) {
+			t.Fatalf("request %d unexpectedly limited", i)
+		}
+	}
+	if l.allow("t1", 3, now.Add(10*time.Second)) {
+		t.Fatalf("fourth request in window should be limited")
+	}
+	if !l.allow("t2", 3, now) {
+		t.Fatalf("other tenants must not share the window")
+	}
+	if !l.allow("t1", 3, now.Add(time.Minute)) {
+		t.Fatalf("new window should allow requests")
+	}
+	if !l.allow("t1", 0, now) {
+		t.Fatalf("zero limit means unlimited")
+	}
+}
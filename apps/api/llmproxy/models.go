@@ -1,10 +1,14 @@
 package llmproxy
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
 	"sync"
+	"time"
 )
 
 // Model represents an LLM model from the database.
@@ -14,34 +18,147 @@ type Model struct {
 	Provider             string // "openai", "anthropic", "google"
 	ProviderCostInputM   int    // cents per million input tokens
 	ProviderCostOutputM  int    // cents per million output tokens
+	// Prompt cache rates in cents per million tokens. Zero bills cached
+	// tokens at the input rate.
+	ProviderCostCacheReadM  int
+	ProviderCostCacheWriteM int
 	MarkupPct            int
 	Enabled              bool
 }
 
-// ModelRegistry caches active models in memory.
+func (m *Model) cacheReadRate() int {
+	if m.ProviderCostCacheReadM > 0 {
+		return m.ProviderCostCacheReadM
+	}
+	return m.ProviderCostInputM
+}
+
+func (m *Model) cacheWriteRate() int {
+	if m.ProviderCostCacheWriteM > 0 {
+		return m.ProviderCostCacheWriteM
+	}
+	return m.ProviderCostInputM
+}
+
+// ModelRegistry caches active models and model aliases in memory. Reload
+// replaces the whole map, so a *Model a handler holds never changes under it.
 type ModelRegistry struct {
-	mu     sync.RWMutex
-	models map[string]*Model // keyed by id
+	db      *sql.DB
+	mu      sync.RWMutex
+	models  map[string]*Model      // keyed by id
+	aliases map[string]*ModelAlias // keyed by deprecated id
+}
+
+// ModelChanges lists the model ids a reload added, removed or updated.
+type ModelChanges struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Updated []string `json:"updated"`
+}
+
+// Empty reports whether the reload changed nothing.
+func (c ModelChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Updated) == 0
 }
 
-// NewModelRegistry loads active models from the database.
+// NewModelRegistry loads active models and model aliases from the database.
+// Models are required; without aliases deprecated ids simply stop resolving.
 func NewModelRegistry(db *sql.DB) (*ModelRegistry, error) {
-	rows, err := db.Query(`SELECT id, name, provider, provider_cost_input_per_m, provider_cost_output_per_m, markup_pct, enabled FROM models WHERE enabled = true`)
+	models, err := loadEnabledModels(context.Background(), db)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range models {
+		slog.Info("loaded model", "id", m.ID, "provider", m.Provider)
+	}
+	aliases, err := loadModelAliases(context.Background(), db)
+	if err != nil {
+		slog.Warn("model aliases not loaded", "err", err)
+		aliases = map[string]*ModelAlias{}
+	}
+	return &ModelRegistry{db: db, models: models, aliases: aliases}, nil
+}
+
+func loadEnabledModels(ctx context.Context, db *sql.DB) (map[string]*Model, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, name, provider, provider_cost_input_per_m, provider_cost_output_per_m, provider_cost_cache_read_per_m, provider_cost_cache_write_per_m, markup_pct, enabled FROM models WHERE enabled = true`)
 	if err != nil {
 		return nil, fmt.Errorf("query models: %w", err)
 	}
 	defer rows.Close()
 
-	reg := &ModelRegistry{models: make(map[string]*Model)}
+	models := make(map[string]*Model)
 	for rows.Next() {
 		var m Model
-		if err := rows.Scan(&m.ID, &m.Name, &m.Provider, &m.ProviderCostInputM, &m.ProviderCostOutputM, &m.MarkupPct, &m.Enabled); err != nil {
+		if err := rows.Scan(&m.ID, &m.Name, &m.Provider, &m.ProviderCostInputM, &m.ProviderCostOutputM, &m.ProviderCostCacheReadM, &m.ProviderCostCacheWriteM, &m.MarkupPct, &m.Enabled); err != nil {
 			return nil, fmt.Errorf("scan model: %w", err)
 		}
-		reg.models[m.ID] = &m
-		slog.Info("loaded model", "id", m.ID, "provider", m.Provider)
+		models[m.ID] = &m
+	}
+	return models, rows.Err()
+}
+
+// Reload reads the enabled models and model aliases again and swaps them in
+// at once. On error the current models are kept; aliases that fail to load
+// keep their current set too.
+func (r *ModelRegistry) Reload(ctx context.Context) (ModelChanges, error) {
+	if r.db == nil {
+		return ModelChanges{}, errors.New("model registry has no database")
+	}
+	models, err := loadEnabledModels(ctx, r.db)
+	if err != nil {
+		return ModelChanges{}, err
+	}
+	aliases, aliasErr := loadModelAliases(ctx, r.db)
+	if aliasErr != nil {
+		slog.Warn("model aliases not reloaded", "err", aliasErr)
+	}
+
+	r.mu.Lock()
+	previous := r.models
+	r.models = models
+	if aliasErr == nil {
+		r.aliases = aliases
+	}
+	r.mu.Unlock()
+
+	changes := ModelChanges{Added: []string{}, Removed: []string{}, Updated: []string{}}
+	for id, m := range models {
+		old, ok := previous[id]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, id)
+		case *old != *m:
+			changes.Updated = append(changes.Updated, id)
+		}
+	}
+	for id := range previous {
+		if _, ok := models[id]; !ok {
+			changes.Removed = append(changes.Removed, id)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Updated)
+	return changes, nil
+}
+
+// Start reloads the registry every interval until ctx is done.
+func (r *ModelRegistry) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changes, err := r.Reload(ctx)
+			if err != nil {
+				slog.Warn("model registry reload failed", "err", err)
+			} else if !changes.Empty() {
+				slog.Info("model registry reloaded", "added", changes.Added, "removed", changes.Removed, "updated", changes.Updated)
+			}
+		}
 	}
-	return reg, rows.Err()
 }
 
 // GetModel returns a model by ID or an error if not found.
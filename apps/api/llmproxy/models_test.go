@@ -1,7 +1,10 @@
 package llmproxy
 
 import (
+	"context"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 )
@@ -17,9 +20,9 @@ func TestNewModelRegistry(t *testing.T) {
 		{
 			name: "loads enabled models",
 			setup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "name", "provider", "provider_cost_input_per_m", "provider_cost_output_per_m", "markup_pct", "enabled"}).
-					AddRow("gpt-4o", "GPT-4o", "openai", 50, 150, 20, true).
-					AddRow("claude", "Claude", "anthropic", 30, 120, 25, true)
+				rows := sqlmock.NewRows([]string{"id", "name", "provider", "provider_cost_input_per_m", "provider_cost_output_per_m", "provider_cost_cache_read_per_m", "provider_cost_cache_write_per_m", "markup_pct", "enabled"}).
+					AddRow("gpt-4o", "GPT-4o", "openai", 50, 150, 0, 0, 20, true).
+					AddRow("claude", "Claude", "anthropic", 30, 120, 0, 0, 25, true)
 				mock.ExpectQuery("SELECT id, name, provider").WillReturnRows(rows)
 			},
 			wantLen: 2,
@@ -34,8 +37,8 @@ func TestNewModelRegistry(t *testing.T) {
 		{
 			name: "scan error",
 			setup: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "name", "provider", "provider_cost_input_per_m", "provider_cost_output_per_m", "markup_pct", "enabled"}).
-					AddRow("gpt-4o", "GPT-4o", "openai", "bad", 150, 20, true)
+				rows := sqlmock.NewRows([]string{"id", "name", "provider", "provider_cost_input_per_m", "provider_cost_output_per_m", "provider_cost_cache_read_per_m", "provider_cost_cache_write_per_m", "markup_pct", "enabled"}).
+					AddRow("gpt-4o", "GPT-4o", "openai", "bad", 150, 0, 0, 20, true)
 				mock.ExpectQuery("SELECT id, name, provider").WillReturnRows(rows)
 			},
 			wantErr: true,
@@ -110,3 +113,53 @@ func TestModelPricingLookupViaCalcCost(t *testing.T) {
 		t.Fatalf("CalcCostCents() = %d, want 200", got)
 	}
 }
+
+func TestModelRegistryReload(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "name", "provider", "provider_cost_input_per_m", "provider_cost_output_per_m", "provider_cost_cache_read_per_m", "provider_cost_cache_write_per_m", "markup_pct", "enabled"}
+	mock.ExpectQuery("SELECT id, name, provider").WillReturnRows(sqlmock.NewRows(columns).
+		AddRow("gpt-4o", "GPT-4o", "openai", 50, 150, 0, 0, 20, true).
+		AddRow("claude", "Claude", "anthropic", 30, 120, 0, 0, 25, true))
+	aliasColumns := []string{"alias_id", "replacement_id", "warn_from", "effective_at"}
+	mock.ExpectQuery("FROM model_aliases").WillReturnRows(sqlmock.NewRows(aliasColumns))
+	reg, err := NewModelRegistry(db)
+	if err != nil {
+		t.Fatalf("NewModelRegistry: %v", err)
+	}
+	held, _ := reg.GetModel("gpt-4o")
+
+	mock.ExpectQuery("SELECT id, name, provider").WillReturnRows(sqlmock.NewRows(columns).
+		AddRow("gpt-4o", "GPT-4o", "openai", 50, 150, 0, 0, 35, true).
+		AddRow("gemini", "Gemini", "google", 10, 40, 0, 0, 20, true))
+	effective := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM model_aliases").WillReturnRows(sqlmock.NewRows(aliasColumns).
+		AddRow("claude", "gemini", effective.AddDate(0, -1, 0), effective))
+	changes, err := reg.Reload(context.Background())
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	want := ModelChanges{Added: []string{"gemini"}, Removed: []string{"claude"}, Updated: []string{"gpt-4o"}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("changes = %+v, want %+v", changes, want)
+	}
+	if m, _ := reg.GetModel("gpt-4o"); m.MarkupPct != 35 || held.MarkupPct != 20 {
+		t.Fatalf("reloaded markup = %d, held markup = %d", m.MarkupPct, held.MarkupPct)
+	}
+	if res, err := reg.Resolve("claude", effective); err != nil || res.Model.ID != "gemini" || !res.Retired {
+		t.Fatalf("Resolve(claude) = %+v, %v", res, err)
+	}
+
+	mock.ExpectQuery("SELECT id, name, provider").WillReturnError(assertErr{})
+	if _, err := reg.Reload(context.Background()); err == nil {
+		t.Fatalf("expected reload error")
+	}
+	if len(reg.ListModels()) != 2 {
+		t.Fatalf("failed reload should keep models, got %d", len(reg.ListModels()))
+	}
+}
@@ -0,0 +1,100 @@
+package llmproxy
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	modelTestPrompt    = "Say hello in one word."
+	modelTestMaxTokens = 16
+	modelTestTimeout   = 30 * time.Second
+)
+
+// ErrModelNotFound is returned by TestModel for an id that is neither loaded
+// nor in the models table.
+var ErrModelNotFound = errors.New("model not found")
+
+// ModelTestResult reports whether a model answered a sample prompt. Upstream
+// failures are reported in Error with OK false rather than returned.
+type ModelTestResult struct {
+	Model        string `json:"model"`
+	Response     string `json:"response"`
+	LatencyMs    int64  `json:"latency_ms"`
+	InputTokens  int    `json:"input_tokens"`
+	OutputTokens int    `json:"output_tokens"`
+	OK           bool   `json:"ok"`
+	Error        string `json:"error,omitempty"`
+}
+
+// TestModel sends a one-line prompt to the model through the provider call
+// tenant requests use, so operators can check a new model is reachable.
+// Models not yet in the registry, including disabled ones, are read from
+// the database. Nothing is billed.
+func (p *Proxy) TestModel(ctx context.Context, modelID string) (ModelTestResult, error) {
+	modelID = strings.TrimSpace(modelID)
+	model, err := p.Registry.GetModel(modelID)
+	if err != nil {
+		model, err = p.loadModel(ctx, modelID)
+		if err != nil {
+			return ModelTestResult{}, err
+		}
+	}
+
+	result := ModelTestResult{Model: model.ID}
+	upstreamModel := resolveProviderModelID(model)
+	if upstreamModel == "" {
+		result.Error = "invalid model id"
+		return result, nil
+	}
+	maxTokens := modelTestMaxTokens
+	req := chatRequest{
+		Model:     upstreamModel,
+		Messages:  []chatMessage{{Role: "user", Content: modelTestPrompt}},
+		MaxTokens: &maxTokens,
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, modelTestTimeout)
+	defer cancel()
+	start := time.Now()
+	buf := &responseBuffer{}
+	usage, _, err := p.callProvider(callCtx, buf, model.Provider, req)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = bestOfNErrorMessage(err)
+		return result, nil
+	}
+
+	var resp chatResponse
+	if err := json.Unmarshal(buf.body.Bytes(), &resp); err != nil || len(resp.Choices) == 0 {
+		result.Error = "upstream returned an unreadable completion"
+		return result, nil
+	}
+	result.Response = strings.TrimSpace(resp.Choices[0].Message.Content)
+	result.InputTokens = usage.Input
+	result.OutputTokens = usage.Output
+	result.OK = true
+	return result, nil
+}
+
+func (p *Proxy) loadModel(ctx context.Context, id string) (*Model, error) {
+	if p.DB == nil {
+		return nil, fmt.Errorf("%w: %s", ErrModelNotFound, id)
+	}
+	var m Model
+	err := p.DB.QueryRowContext(ctx,
+		`SELECT id, name, provider, provider_cost_input_per_m, provider_cost_output_per_m, provider_cost_cache_read_per_m, provider_cost_cache_write_per_m, markup_pct, enabled FROM models WHERE id = $1`, id,
+	).Scan(&m.ID, &m.Name, &m.Provider, &m.ProviderCostInputM, &m.ProviderCostOutputM, &m.ProviderCostCacheReadM, &m.ProviderCostCacheWriteM, &m.MarkupPct, &m.Enabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrModelNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+	return &m, nil
+}
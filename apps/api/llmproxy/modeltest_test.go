@@ -0,0 +1,67 @@
+package llmproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestTestModel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		var body struct {
+			Model    string        `json:"model"`
+			Messages []chatMessage `json:"messages"`
+		}
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		if len(body.Messages) != 1 || body.Messages[0].Content != modelTestPrompt {
+			t.Errorf("messages = %+v", body.Messages)
+		}
+		if body.Model == "gpt-broken" {
+			return &http.Response{StatusCode: http.StatusUnauthorized, Body: io.NopCloser(strings.NewReader(`{"error":{"message":"invalid api key"}}`)), Header: make(http.Header)}, nil
+		}
+		resp, _ := json.Marshal(map[string]any{
+			"id":      "chatcmpl-1",
+			"model":   body.Model,
+			"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": " Hello "}, "finish_reason": "stop"}},
+			"usage":   map[string]int{"prompt_tokens": 12, "completion_tokens": 1},
+		})
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(resp)), Header: make(http.Header)}, nil
+	})}
+	registry := &ModelRegistry{models: map[string]*Model{"gpt-4o": {ID: "gpt-4o", Provider: "openai"}}}
+	proxy := &Proxy{DB: db, Registry: registry, Client: client, PlatformTenantID: "platform"}
+
+	got, err := proxy.TestModel(context.Background(), "gpt-4o")
+	if err != nil || !got.OK || got.Response != "Hello" || got.InputTokens != 12 || got.OutputTokens != 1 {
+		t.Fatalf("TestModel = %+v err=%v", got, err)
+	}
+
+	// Models created since the registry loaded are read from the table.
+	mock.ExpectQuery("FROM models WHERE id").WithArgs("gpt-broken").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "provider", "in", "out", "cache_read", "cache_write", "markup", "enabled"}).AddRow("gpt-broken", "Broken", "openai", 0, 0, 0, 0, 0, false))
+	got, err = proxy.TestModel(context.Background(), "gpt-broken")
+	if err != nil || got.OK || got.Error == "" {
+		t.Fatalf("failing TestModel = %+v err=%v", got, err)
+	}
+
+	mock.ExpectQuery("FROM models WHERE id").WithArgs("missing").WillReturnRows(sqlmock.NewRows(nil))
+	if _, err := proxy.TestModel(context.Background(), "missing"); !errors.Is(err, ErrModelNotFound) {
+		t.Fatalf("missing model err = %v", err)
+	}
+	// Nothing is billed.
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
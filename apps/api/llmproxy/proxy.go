@@ -1,19 +1,23 @@
 package llmproxy
 
 import (
-	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/agentsquads/api/openapi"
 	"github.com/agentsquads/api/orchestrator"
+	"github.com/agentsquads/api/plans"
+	"github.com/agentsquads/api/policies"
 )
 
 // Proxy is the LLM proxy handler.
@@ -22,24 +26,79 @@ type Proxy struct {
 	Orch     orchestrator.TenantOrchestrator
 	Registry *ModelRegistry
 	Client   *http.Client
+	Plans    *plans.Resolver
+	// Policies decides whether a tenant gets an error instead of the
+	// replacement for a retired model id. Nil always serves the replacement.
+	Policies policies.Checker
+	// Activity restarts the calling tenant's idle window on each chat
+	// completion, so containers at work are not stopped. Nil records nothing.
+	Activity *orchestrator.IdleStopper
+
+	// RetryBudget caps the total backoff added by provider retries.
+	RetryBudget time.Duration
+
+	// MaxRequestBytes caps the chat completion request body.
+	MaxRequestBytes int64
+
+	// BestOfNTimeout bounds all model calls of one best-of-n request.
+	BestOfNTimeout time.Duration
+
+	// TenantConcurrency caps a tenant's in-flight upstream calls when its
+	// plan sets no limit. ProviderConcurrency caps each provider's in-flight
+	// calls across all tenants; zero leaves providers uncapped. A call over
+	// either cap waits up to ConcurrencyQueueTimeout for a slot.
+	TenantConcurrency       int
+	ProviderConcurrency     int
+	ConcurrencyQueueTimeout time.Duration
+
+	// PlatformTenantID is the pseudo-tenant admin benchmark usage is logged
+	// against. Empty skips usage logging for benchmarks.
+	PlatformTenantID string
+
+	sleep func(time.Duration)
+
+	benchmarking atomic.Bool
+
+	limiterOnce sync.Once
+	limiter     *rateLimiter
+
+	concurrencyOnce sync.Once
+	concurrency     *concurrencyLimiter
 }
 
 // NewProxy creates a new LLM proxy.
 func NewProxy(db *sql.DB, reg *ModelRegistry, orch orchestrator.TenantOrchestrator) *Proxy {
+	tenantConcurrency, providerConcurrency, queueTimeout := loadConcurrencyFromEnv()
 	return &Proxy{
 		DB:       db,
 		Orch:     orch,
 		Registry: reg,
 		Client:   &http.Client{Timeout: 120 * time.Second},
+
+		RetryBudget:             loadRetryBudgetFromEnv(),
+		MaxRequestBytes:         loadMaxRequestBytesFromEnv(),
+		PlatformTenantID:        strings.TrimSpace(os.Getenv("PLATFORM_TENANT_ID")),
+		TenantConcurrency:       tenantConcurrency,
+		ProviderConcurrency:     providerConcurrency,
+		ConcurrencyQueueTimeout: queueTimeout,
 	}
 }
 
 // Mount registers all proxy routes on the given mux.
 func (p *Proxy) Mount(mux *http.ServeMux) {
-	mux.HandleFunc("POST /v1/chat/completions", p.handleChatCompletions)
-	mux.HandleFunc("GET /v1/models", p.handleListModels)
+	tags := []string{"Chat"}
+	tenant := []string{"X-Tenant-ID"}
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/v1/chat/completions", Summary: "Create an OpenAI-compatible chat completion",
+		Tags: tags, Headers: tenant, Request: chatRequest{}, Response: chatResponse{}}, p.handleChatCompletions)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/v1/chat/completions/best-of-n", Summary: "Ask several models and return the best completion",
+		Tags: tags, Headers: tenant, Request: bestOfNRequest{}, Response: bestOfNResponse{}}, p.handleBestOfN)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodGet, Path: "/v1/models", Summary: "List the models the tenant may use",
+		Tags: tags, Headers: tenant, Response: modelList{}}, p.handleListModels)
 }
 
+// maxChatMessages caps the messages in one chat completion request.
+const maxChatMessages = 100
+
 // OpenAI-compatible request/response types.
 type chatRequest struct {
 	Model       string        `json:"model"`
@@ -47,11 +106,43 @@ type chatRequest struct {
 	Temperature *float64      `json:"temperature,omitempty"`
 	MaxTokens   *int          `json:"max_tokens,omitempty"`
 	Stream      bool          `json:"stream,omitempty"`
+
+	// Tools and ToolChoice use the OpenAI function-calling shape. They are
+	// forwarded as-is to OpenAI and translated for Anthropic.
+	Tools      []json.RawMessage `json:"tools,omitempty"`
+	ToolChoice json.RawMessage   `json:"tool_choice,omitempty"`
+
+	// PromptCacheKey and PromptCacheRetention tune OpenAI's automatic prompt
+	// caching. They are forwarded untouched to OpenAI and ignored elsewhere.
+	PromptCacheKey       string `json:"prompt_cache_key,omitempty"`
+	PromptCacheRetention string `json:"prompt_cache_retention,omitempty"`
 }
 
+// chatMessage is one OpenAI chat message. Content may arrive as a string or
+// as an array of content blocks; see UnmarshalJSON.
 type chatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []toolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+
+	// Blocks holds content sent in array form, as-is. Content is then the
+	// text of its text blocks, so validation and token estimates still work.
+	Blocks []json.RawMessage `json:"-"`
+	// CacheControl is an Anthropic cache breakpoint for the whole message,
+	// such as {"type":"ephemeral"}. Other providers ignore it.
+	CacheControl json.RawMessage `json:"-"`
+}
+
+type toolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function toolCallFunction `json:"function"`
+}
+
+type toolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 type chatResponse struct {
@@ -69,9 +160,36 @@ type chatChoice struct {
 }
 
 type usageInfo struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens        int                  `json:"prompt_tokens"`
+	CompletionTokens    int                  `json:"completion_tokens"`
+	TotalTokens         int                  `json:"total_tokens"`
+	PromptTokensDetails *promptTokensDetails `json:"prompt_tokens_details,omitempty"`
+}
+
+type promptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// tokenUsage is what one upstream call consumed. Input counts every prompt
+// token, cached or not; Cache says how many of them went through the
+// provider's prompt cache.
+type tokenUsage struct {
+	Input  int
+	Output int
+	Cache  CacheUsage
+}
+
+// usageInfo returns u in the OpenAI response shape.
+func (u tokenUsage) usageInfo() *usageInfo {
+	info := &usageInfo{
+		PromptTokens:     u.Input,
+		CompletionTokens: u.Output,
+		TotalTokens:      u.Input + u.Output,
+	}
+	if u.Cache.ReadTokens > 0 {
+		info.PromptTokensDetails = &promptTokensDetails{CachedTokens: u.Cache.ReadTokens}
+	}
+	return info
 }
 
 func (p *Proxy) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
@@ -85,10 +203,22 @@ func (p *Proxy) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusUnauthorized, "invalid X-Tenant-ID header")
 		return
 	}
+	p.Activity.Touch(tenantID)
 
-	// Parse request
-	var req chatRequest
-	if err := decodeJSONStrict(r, &req); err != nil {
+	// Parse request. The body is decoded as it arrives rather than read into
+	// a buffer first.
+	limit := p.maxRequestBytes()
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	req, err := decodeChatRequest(r.Body)
+	if err != nil {
+		if isBodyTooLarge(err) {
+			writeBodyTooLarge(w, limit)
+			return
+		}
+		if errors.Is(err, errTooManyMessages) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
@@ -97,25 +227,18 @@ func (p *Proxy) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "model is required")
 		return
 	}
-	if len(req.Messages) == 0 {
-		writeError(w, http.StatusBadRequest, "messages are required")
+	if err := validateChatMessages(req.Messages); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if len(req.Messages) > 100 {
-		writeError(w, http.StatusBadRequest, "too many messages")
+
+	// Look up model, following the alias of a deprecated id.
+	resolved, ok := p.resolveModel(w, r, tenantID, req.Model, "model")
+	if !ok {
 		return
 	}
-	for _, msg := range req.Messages {
-		if strings.TrimSpace(msg.Role) == "" || strings.TrimSpace(msg.Content) == "" {
-			writeError(w, http.StatusBadRequest, "messages must include role and content")
-			return
-		}
-	}
-
-	// Look up model
-	model, err := p.Registry.GetModel(req.Model)
-	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	model := resolved.Model
+	if !p.checkModelAccess(w, r, tenantID, model) {
 		return
 	}
 	upstreamModel := resolveProviderModelID(model)
@@ -125,95 +248,199 @@ func (p *Proxy) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
 	}
 	req.Model = upstreamModel
 
-	// Credit check
+	if !p.checkCreditsAndLimits(w, r, tenantID) {
+		return
+	}
+	release, err := p.acquireSlot(r.Context(), tenantID, model.Provider)
+	if err != nil {
+		writeAcquireError(w, tenantID, err)
+		return
+	}
+	defer release()
+
+	// Route to provider. Each provider writes the response itself on success,
+	// so errors below are only returned before anything reached the client.
+	usage, attempts, err := p.callProvider(r.Context(), w, model.Provider, req)
+	if errors.Is(err, errUnsupportedProvider) {
+		writeError(w, http.StatusBadRequest, "unsupported provider: "+model.Provider)
+		return
+	}
+	if errors.Is(err, errInvalidToolSpec) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		slog.Error("upstream error", "provider", model.Provider, "attempts", attempts, "err", err)
+		writeUpstreamError(w, err)
+		return
+	}
+
+	// Bill after the response was sent; billing is best-effort either way.
+	// A stream the client abandoned is billed for what was generated.
+	finishReason := ""
+	if r.Context().Err() != nil {
+		finishReason = finishReasonClientDisconnect
+	}
+	if p.billUsage(tenantID, model, resolved.deprecatedModel(), usage, attempts, finishReason) {
+		p.pauseIfCreditsExhausted(tenantID)
+	}
+}
+
+// errUnsupportedProvider is returned by callProvider for a model whose
+// provider the proxy cannot route to.
+var errUnsupportedProvider = errors.New("unsupported provider")
+
+// validateChatMessages checks the message list of a chat completion request.
+func validateChatMessages(messages []chatMessage) error {
+	if len(messages) == 0 {
+		return errors.New("messages are required")
+	}
+	if len(messages) > maxChatMessages {
+		return errors.New("too many messages")
+	}
+	for _, msg := range messages {
+		if err := validateChatMessage(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkModelAccess writes a 403 and reports false when the tenant's
+// allowlist does not permit every one of models.
+func (p *Proxy) checkModelAccess(w http.ResponseWriter, r *http.Request, tenantID string, models ...*Model) bool {
+	if p.DB == nil {
+		return true
+	}
+	access, err := LoadModelAccess(r.Context(), p.DB, tenantID)
+	if err != nil {
+		slog.Error("model access check failed", "tenant", tenantID, "err", err)
+		writeError(w, http.StatusInternalServerError, "model access lookup error")
+		return false
+	}
+	for _, model := range models {
+		if !access.Permits(model.ID) {
+			writeModelNotAllowed(w, model.ID, access.Filter(p.Registry.ListModels()))
+			return false
+		}
+	}
+	return true
+}
+
+// checkCreditsAndLimits writes an error and reports false when the tenant is
+// out of credits or over its plan limits.
+func (p *Proxy) checkCreditsAndLimits(w http.ResponseWriter, r *http.Request, tenantID string) bool {
 	balance, err := CheckCredits(p.DB, tenantID)
 	if err != nil {
 		slog.Error("credit check failed", "err", err)
 		writeError(w, http.StatusInternalServerError, "billing error")
-		return
+		return false
 	}
 	if balance <= 0 {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusPaymentRequired)
-		json.NewEncoder(w).Encode(map[string]any{
-			"error": map[string]any{
-				"message": "Insufficient credits",
-				"type":    "billing_error",
-			},
-		})
-		return
+		writeOpenAIError(w, http.StatusPaymentRequired, "insufficient_quota", "insufficient_credits", "Insufficient credits", "")
+		return false
 	}
 
-	// Route to provider
-	var inputTokens, outputTokens int
-	var respBody []byte
+	if err := p.enforcePlanLimits(r.Context(), tenantID); err != nil {
+		var limitErr *planLimitError
+		if errors.As(err, &limitErr) {
+			writeOpenAIError(w, limitErr.status, limitErr.errType, limitErr.code, limitErr.message, "")
+			return false
+		}
+		slog.Error("plan limit check failed", "tenant", tenantID, "err", err)
+		writeError(w, http.StatusInternalServerError, "plan lookup error")
+		return false
+	}
+	return true
+}
 
-	switch model.Provider {
+// callProvider sends req to provider, which writes the response to w on
+// success.
+func (p *Proxy) callProvider(ctx context.Context, w http.ResponseWriter, provider string, req chatRequest) (tokenUsage, int, error) {
+	switch provider {
 	case "openai":
-		respBody, inputTokens, outputTokens, err = p.proxyOpenAI(req)
+		return p.proxyOpenAI(ctx, w, req)
 	case "anthropic":
-		respBody, inputTokens, outputTokens, err = p.proxyAnthropic(req)
+		return p.proxyAnthropic(ctx, w, req)
 	case "google":
-		respBody, inputTokens, outputTokens, err = p.proxyGemini(req)
+		return p.proxyGemini(ctx, w, req)
 	default:
-		writeError(w, http.StatusBadRequest, "unsupported provider: "+model.Provider)
-		return
+		return tokenUsage{}, 0, errUnsupportedProvider
 	}
+}
 
-	if err != nil {
-		slog.Error("upstream error", "provider", model.Provider, "err", err)
-		writeError(w, http.StatusBadGateway, "upstream error: "+err.Error())
-		return
+// billUsage records one upstream call and reports whether it was billed.
+// deprecatedModel is the deprecated id the call asked for, if any.
+func (p *Proxy) billUsage(tenantID string, model *Model, deprecatedModel string, usage tokenUsage, attempts int, finishReason string) bool {
+	costCents := CalcCachedCostCents(model, usage.Input, usage.Output, usage.Cache)
+	if err := BillAliasedUsage(p.DB, tenantID, model.ID, deprecatedModel, usage.Input, usage.Output, usage.Cache, costCents, attempts, finishReason); err != nil {
+		slog.Error("billing failed", "err", err)
+		return false
 	}
+	return true
+}
 
-	// Bill
-	costCents := CalcCostCents(model, inputTokens, outputTokens)
-	if err := BillUsage(p.DB, tenantID, model.ID, inputTokens, outputTokens, costCents); err != nil {
-		slog.Error("billing failed", "err", err)
-		// Still return the response — billing is best-effort
-	} else {
-		remainingBalance, err := CheckCredits(p.DB, tenantID)
-		if err != nil {
-			slog.Error("post-billing credit check failed", "tenant", tenantID, "err", err)
-		} else if remainingBalance <= 0 {
-			if err := PauseTenant(p.DB, p.Orch, tenantID); err != nil {
-				slog.Error("tenant auto-pause failed", "tenant", tenantID, "err", err)
-			} else {
-				slog.Info(fmt.Sprintf("tenant %s auto-paused: credits exhausted", tenantID))
-			}
+// pauseIfCreditsExhausted pauses the tenant once billing took its balance to
+// zero or below.
+func (p *Proxy) pauseIfCreditsExhausted(tenantID string) {
+	remainingBalance, err := CheckCredits(p.DB, tenantID)
+	if err != nil {
+		slog.Error("post-billing credit check failed", "tenant", tenantID, "err", err)
+	} else if remainingBalance <= 0 {
+		if err := PauseTenant(p.DB, p.Orch, tenantID); err != nil {
+			slog.Error("tenant auto-pause failed", "tenant", tenantID, "err", err)
+		} else {
+			slog.Info(fmt.Sprintf("tenant %s auto-paused: credits exhausted", tenantID))
 		}
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(respBody)
 }
 
-// proxyOpenAI forwards directly to OpenAI (already compatible format).
-func (p *Proxy) proxyOpenAI(req chatRequest) ([]byte, int, int, error) {
-	body, _ := json.Marshal(req)
-	httpReq, _ := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewReader(body))
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+os.Getenv("OPENAI_API_KEY"))
-
-	resp, err := p.Client.Do(httpReq)
+// proxyOpenAI forwards directly to OpenAI (already compatible format). The
+// upstream response is streamed to w rather than buffered. The upstream
+// request is bound to ctx, so a client that disconnects mid-stream stops
+// generation upstream.
+func (p *Proxy) proxyOpenAI(ctx context.Context, w http.ResponseWriter, req chatRequest) (tokenUsage, int, error) {
+	body := openAIRequestBody(req)
+	if req.Stream {
+		// Without include_usage OpenAI sends no token counts on streams.
+		body["stream_options"] = map[string]any{"include_usage": true}
+	}
+	resp, respBody, attempts, err := p.doWithRetry("openai", func() (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", jsonBody(body))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Authorization", "Bearer "+os.Getenv("OPENAI_API_KEY"))
+		return httpReq, nil
+	})
 	if err != nil {
-		return nil, 0, 0, err
+		return tokenUsage{}, attempts, err
+	}
+	if resp.StatusCode >= 400 {
+		return tokenUsage{}, attempts, newUpstreamError("openai", resp, respBody)
 	}
 	defer resp.Body.Close()
-	respBody, _ := io.ReadAll(resp.Body)
 
-	if resp.StatusCode >= 400 {
-		return nil, 0, 0, fmt.Errorf("openai returned %d: %s", resp.StatusCode, string(respBody))
+	if req.Stream {
+		usage, err := relayOpenAIStream(ctx, w, resp.Body, req.Messages)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("openai stream relay failed", "err", err)
+		}
+		return usage, attempts, nil
 	}
 
-	var parsed map[string]any
-	json.Unmarshal(respBody, &parsed)
-	input, output := ExtractOpenAIUsage(parsed)
-	return respBody, input, output, nil
+	w.Header().Set("Content-Type", "application/json")
+	usage, err := copyOpenAIResponse(w, resp.Body)
+	if err != nil {
+		// Headers are already sent, so the client just sees a short body.
+		slog.Error("openai response copy failed", "err", err)
+	}
+	return usage, attempts, nil
 }
 
 // proxyAnthropic translates to/from Anthropic Messages API.
-func (p *Proxy) proxyAnthropic(req chatRequest) ([]byte, int, int, error) {
+func (p *Proxy) proxyAnthropic(ctx context.Context, w http.ResponseWriter, req chatRequest) (tokenUsage, int, error) {
 	// Build Anthropic request
 	antReq := map[string]any{
 		"model":      req.Model,
@@ -226,45 +453,62 @@ func (p *Proxy) proxyAnthropic(req chatRequest) ([]byte, int, int, error) {
 		antReq["temperature"] = *req.Temperature
 	}
 
-	// Separate system message
-	var messages []map[string]string
-	for _, m := range req.Messages {
-		if m.Role == "system" {
-			antReq["system"] = m.Content
-		} else {
-			messages = append(messages, map[string]string{"role": m.Role, "content": m.Content})
-		}
+	system, messages, err := anthropicMessages(req.Messages)
+	if err != nil {
+		return tokenUsage{}, 0, err
+	}
+	if system != nil {
+		antReq["system"] = system
 	}
 	antReq["messages"] = messages
+	caching := usesAnthropicPromptCache(req)
 
-	body, _ := json.Marshal(antReq)
-	httpReq, _ := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewReader(body))
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("x-api-key", os.Getenv("ANTHROPIC_API_KEY"))
-	httpReq.Header.Set("anthropic-version", "2023-06-01")
+	if len(req.Tools) > 0 {
+		tools, err := anthropicTools(req.Tools)
+		if err != nil {
+			return tokenUsage{}, 0, err
+		}
+		antReq["tools"] = tools
+	}
+	if len(req.ToolChoice) > 0 {
+		choice, err := anthropicToolChoice(req.ToolChoice)
+		if err != nil {
+			return tokenUsage{}, 0, err
+		}
+		if choice != nil {
+			antReq["tool_choice"] = choice
+		}
+	}
 
-	resp, err := p.Client.Do(httpReq)
+	resp, respBody, attempts, err := p.doWithRetry("anthropic", func() (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", jsonBody(antReq))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("x-api-key", os.Getenv("ANTHROPIC_API_KEY"))
+		httpReq.Header.Set("anthropic-version", "2023-06-01")
+		if caching {
+			httpReq.Header.Set("anthropic-beta", anthropicPromptCachingBeta)
+		}
+		return httpReq, nil
+	})
 	if err != nil {
-		return nil, 0, 0, err
+		return tokenUsage{}, attempts, err
 	}
-	defer resp.Body.Close()
-	respBody, _ := io.ReadAll(resp.Body)
-
 	if resp.StatusCode >= 400 {
-		return nil, 0, 0, fmt.Errorf("anthropic returned %d: %s", resp.StatusCode, string(respBody))
+		return tokenUsage{}, attempts, newUpstreamError("anthropic", resp, respBody)
 	}
+	defer resp.Body.Close()
 
 	// Parse and translate to OpenAI format
 	var antResp map[string]any
-	json.Unmarshal(respBody, &antResp)
-	input, output := ExtractAnthropicUsage(antResp)
+	json.NewDecoder(resp.Body).Decode(&antResp)
+	usage := anthropicUsage(antResp)
 
-	// Extract text content
-	content := ""
-	if contentArr, ok := antResp["content"].([]any); ok && len(contentArr) > 0 {
-		if block, ok := contentArr[0].(map[string]any); ok {
-			content, _ = block["text"].(string)
-		}
+	message, err := anthropicResponseMessage(antResp)
+	if err != nil {
+		return tokenUsage{}, attempts, err
 	}
 
 	finishReason := "stop"
@@ -274,6 +518,8 @@ func (p *Proxy) proxyAnthropic(req chatRequest) ([]byte, int, int, error) {
 			finishReason = "stop"
 		case "max_tokens":
 			finishReason = "length"
+		case "tool_use":
+			finishReason = "tool_calls"
 		default:
 			finishReason = sr
 		}
@@ -285,21 +531,17 @@ func (p *Proxy) proxyAnthropic(req chatRequest) ([]byte, int, int, error) {
 		Model:  req.Model,
 		Choices: []chatChoice{{
 			Index:        0,
-			Message:      chatMessage{Role: "assistant", Content: content},
+			Message:      message,
 			FinishReason: finishReason,
 		}},
-		Usage: &usageInfo{
-			PromptTokens:     input,
-			CompletionTokens: output,
-			TotalTokens:      input + output,
-		},
-	}
-	out, _ := json.Marshal(oaiResp)
-	return out, input, output, nil
+		Usage: usage.usageInfo(),
+	}
+	writeChatResponse(w, oaiResp)
+	return usage, attempts, nil
 }
 
 // proxyGemini translates to/from Gemini generateContent API.
-func (p *Proxy) proxyGemini(req chatRequest) ([]byte, int, int, error) {
+func (p *Proxy) proxyGemini(ctx context.Context, w http.ResponseWriter, req chatRequest) (tokenUsage, int, error) {
 	gemReq := map[string]any{}
 
 	var contents []map[string]any
@@ -333,24 +575,26 @@ func (p *Proxy) proxyGemini(req chatRequest) ([]byte, int, int, error) {
 	}
 	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/%s:generateContent?key=%s", modelName, apiKey)
 
-	body, _ := json.Marshal(gemReq)
-	httpReq, _ := http.NewRequest("POST", url, bytes.NewReader(body))
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := p.Client.Do(httpReq)
+	resp, respBody, attempts, err := p.doWithRetry("google", func() (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", url, jsonBody(gemReq))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		return httpReq, nil
+	})
 	if err != nil {
-		return nil, 0, 0, err
+		return tokenUsage{}, attempts, err
 	}
-	defer resp.Body.Close()
-	respBody, _ := io.ReadAll(resp.Body)
-
 	if resp.StatusCode >= 400 {
-		return nil, 0, 0, fmt.Errorf("gemini returned %d: %s", resp.StatusCode, string(respBody))
+		return tokenUsage{}, attempts, newUpstreamError("gemini", resp, respBody)
 	}
+	defer resp.Body.Close()
 
 	var gemResp map[string]any
-	json.Unmarshal(respBody, &gemResp)
+	json.NewDecoder(resp.Body).Decode(&gemResp)
 	input, output := ExtractGeminiUsage(gemResp)
+	usage := tokenUsage{Input: input, Output: output}
 
 	// Extract text
 	content := ""
@@ -375,54 +619,52 @@ func (p *Proxy) proxyGemini(req chatRequest) ([]byte, int, int, error) {
 			Message:      chatMessage{Role: "assistant", Content: content},
 			FinishReason: "stop",
 		}},
-		Usage: &usageInfo{
-			PromptTokens:     input,
-			CompletionTokens: output,
-			TotalTokens:      input + output,
-		},
-	}
-	out, _ := json.Marshal(oaiResp)
-	return out, input, output, nil
+		Usage: usage.usageInfo(),
+	}
+	writeChatResponse(w, oaiResp)
+	return usage, attempts, nil
 }
 
+func writeChatResponse(w http.ResponseWriter, resp chatResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleListModels lists the enabled models, narrowed to the tenant's
+// allowlist when X-Tenant-ID is set.
 func (p *Proxy) handleListModels(w http.ResponseWriter, r *http.Request) {
 	models := p.Registry.ListModels()
-	data := make([]map[string]any, len(models))
-	for i, m := range models {
-		data[i] = map[string]any{
-			"id":       m.ID,
-			"object":   "model",
-			"owned_by": m.Provider,
+	if tenantID := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); tenantID != "" && p.DB != nil {
+		access, err := LoadModelAccess(r.Context(), p.DB, tenantID)
+		if err != nil {
+			slog.Error("model access check failed", "tenant", tenantID, "err", err)
+			writeError(w, http.StatusInternalServerError, "model access lookup error")
+			return
 		}
+		models = access.Filter(models)
+	}
+	list := modelList{Object: "list", Data: make([]modelListEntry, len(models))}
+	for i, m := range models {
+		list.Data[i] = modelListEntry{ID: m.ID, Object: "model", OwnedBy: m.Provider}
 	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{
-		"object": "list",
-		"data":   data,
-	})
+	json.NewEncoder(w).Encode(list)
 }
 
-func writeError(w http.ResponseWriter, code int, msg string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(map[string]any{
-		"error": map[string]any{
-			"message": msg,
-			"type":    "invalid_request_error",
-		},
-	})
+// modelList is the OpenAI-compatible /v1/models response.
+type modelList struct {
+	Object string           `json:"object"`
+	Data   []modelListEntry `json:"data"`
 }
 
-func decodeJSONStrict(r *http.Request, dst any) error {
-	decoder := json.NewDecoder(r.Body)
-	decoder.DisallowUnknownFields()
-	if err := decoder.Decode(dst); err != nil {
-		return err
-	}
-	if err := decoder.Decode(&struct{}{}); err != io.EOF {
-		return errors.New("request body must contain a single JSON object")
-	}
-	return nil
+type modelListEntry struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	OwnedBy string `json:"owned_by"`
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeOpenAIError(w, code, "invalid_request_error", "", msg, "")
 }
 
 func resolveProviderModelID(model *Model) string {
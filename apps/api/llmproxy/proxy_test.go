@@ -3,6 +3,7 @@ package llmproxy
 import (
 	"bytes"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
@@ -59,6 +60,7 @@ func TestProxyHandleChatCompletions(t *testing.T) {
 			registry: &ModelRegistry{models: map[string]*Model{"gpt-4o": {ID: "gpt-4o", Provider: "openai"}}},
 			setupDB: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{"balance_cents"}).AddRow(0)
+				expectModelAccess(mock, "t1")
 				mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(rows)
 			},
 			wantStatus: http.StatusPaymentRequired,
@@ -70,6 +72,7 @@ func TestProxyHandleChatCompletions(t *testing.T) {
 			tenantID: "t1",
 			registry: &ModelRegistry{models: map[string]*Model{"gpt-4o": {ID: "gpt-4o", Provider: "openai"}}},
 			setupDB: func(mock sqlmock.Sqlmock) {
+				expectModelAccess(mock, "t1")
 				mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(100))
 			},
 			client: &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
@@ -87,6 +90,7 @@ func TestProxyHandleChatCompletions(t *testing.T) {
 			tenantID: "t1",
 			registry: &ModelRegistry{models: map[string]*Model{"gpt-4o": {ID: "gpt-4o", Provider: "openai"}}},
 			setupDB: func(mock sqlmock.Sqlmock) {
+				expectModelAccess(mock, "t1")
 				mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(100))
 			},
 			client: &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
@@ -101,6 +105,7 @@ func TestProxyHandleChatCompletions(t *testing.T) {
 			tenantID: "t1",
 			registry: &ModelRegistry{models: map[string]*Model{"gpt-4o": {ID: "gpt-4o", Provider: "openai", ProviderCostInputM: 100, ProviderCostOutputM: 100, MarkupPct: 0}}},
 			setupDB: func(mock sqlmock.Sqlmock) {
+				expectModelAccess(mock, "t1")
 				mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(100))
 				mock.ExpectBegin()
 				mock.ExpectExec("INSERT INTO usage_logs").WillReturnResult(sqlmock.NewResult(1, 1))
@@ -162,3 +167,65 @@ func TestProxyHandleChatCompletions(t *testing.T) {
 		})
 	}
 }
+
+func TestProxyOpenAIToolCallsPassThrough(t *testing.T) {
+	upstream := `{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":1000,"completion_tokens":1000,"total_tokens":2000},"system_fingerprint":"fp_1"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"get_weather"`) {
+			t.Errorf("tools not forwarded: %s", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, upstream)
+	}))
+	defer server.Close()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	expectModelAccess(mock, "t1")
+	mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(100))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO usage_logs").WithArgs("t1", "gpt-4o", 1000, 1000, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE credits SET balance_cents").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(50))
+
+	// Send the hard-coded OpenAI URL to the test server instead.
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req.URL.Scheme = "http"
+		req.URL.Host = strings.TrimPrefix(server.URL, "http://")
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+	proxy := &Proxy{
+		DB:       db,
+		Registry: &ModelRegistry{models: map[string]*Model{"gpt-4o": {ID: "gpt-4o", Provider: "openai", ProviderCostInputM: 100, ProviderCostOutputM: 100}}},
+		Client:   client,
+	}
+
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":"weather in Paris?"}],"tools":[{"type":"function","function":{"name":"get_weather","parameters":{"type":"object"}}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("X-Tenant-ID", "t1")
+	w := httptest.NewRecorder()
+	proxy.handleChatCompletions(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	if w.Body.String() != upstream {
+		t.Fatalf("body was rewritten:\n got %s\nwant %s", w.Body.String(), upstream)
+	}
+	var resp chatResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	choice := resp.Choices[0]
+	if choice.FinishReason != "tool_calls" || len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Arguments != `{"city":"Paris"}` {
+		t.Fatalf("choice = %+v", choice)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package llmproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	maxProviderRetries  = 3
+	defaultRetryBudget  = 10 * time.Second
+	baseRetryBackoff    = 250 * time.Millisecond
+	statusOverloaded    = 529
+	retryBudgetEnvName  = "LLM_PROXY_RETRY_BUDGET"
+	overloadedErrorType = "overloaded_error"
+)
+
+// providerRetries counts retried upstream attempts per provider. It is
+// published through expvar so provider flakiness shows up in /debug/vars.
+var providerRetries = expvar.NewMap("llmproxy_provider_retries")
+
+// loadRetryBudgetFromEnv reads LLM_PROXY_RETRY_BUDGET (a Go duration string).
+// Invalid or non-positive values fall back to the default budget.
+func loadRetryBudgetFromEnv() time.Duration {
+	if v := strings.TrimSpace(os.Getenv(retryBudgetEnvName)); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultRetryBudget
+}
+
+// doWithRetry sends a provider request, retrying transient failures (429,
+// 5xx, Anthropic overloads and connection resets) up to maxProviderRetries
+// times. Backoff is jittered exponential, honors Retry-After, and stops once
+// the added latency would exceed the proxy's retry budget.
+//
+// Only error responses (status >= 400) are buffered, since retry decisions
+// and error mapping need their body; the returned body is nil otherwise and
+// the caller must read and close resp.Body. Success is never retried, so a
+// retry never follows bytes that were already streamed to the client.
+//
+// newReq must build a fresh request for every attempt. The returned attempt
+// count is at least 1 and includes the final attempt.
+func (p *Proxy) doWithRetry(provider string, newReq func() (*http.Request, error)) (*http.Response, []byte, int, error) {
+	budget := p.RetryBudget
+	if budget <= 0 {
+		budget = defaultRetryBudget
+	}
+	sleep := p.sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+
+	var waited time.Duration
+	for attempt := 1; ; attempt++ {
+		httpReq, err := newReq()
+		if err != nil {
+			return nil, nil, attempt, err
+		}
+
+		resp, err := p.Client.Do(httpReq)
+		if httpReq.Body != nil {
+			// Transports close the request body, but a streaming encoder
+			// (see jsonBody) must not be left blocked if one did not.
+			httpReq.Body.Close()
+		}
+		var body []byte
+		if err == nil && resp.StatusCode >= 400 {
+			body, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+
+		retryable := false
+		if err != nil {
+			retryable = isRetryableNetError(err)
+		} else {
+			retryable = isRetryableStatus(resp.StatusCode, body)
+		}
+		if !retryable || attempt > maxProviderRetries {
+			return resp, body, attempt, err
+		}
+
+		delay := backoffDelay(attempt)
+		if resp != nil {
+			if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				delay = d
+			}
+		}
+		if waited+delay > budget {
+			return resp, body, attempt, err
+		}
+
+		providerRetries.Add(provider, 1)
+		sleep(delay)
+		waited += delay
+	}
+}
+
+// isRetryableStatus reports whether an upstream status is transient. Any 4xx
+// other than 429 is treated as a caller error, except for Anthropic overload
+// bodies which are retried regardless of status.
+func isRetryableStatus(code int, body []byte) bool {
+	switch {
+	case code == http.StatusTooManyRequests, code == statusOverloaded:
+		return true
+	case code >= 500:
+		return true
+	case code >= 400:
+		return isAnthropicOverloaded(body)
+	default:
+		return false
+	}
+}
+
+func isAnthropicOverloaded(body []byte) bool {
+	var parsed struct {
+		Error struct {
+			Type string `json:"type"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return false
+	}
+	return parsed.Error.Type == overloadedErrorType
+}
+
+func isRetryableNetError(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// backoffDelay returns a jittered exponential delay for the given attempt
+// (1-based): a random duration in [d/2, d) where d = base * 2^(attempt-1).
+func backoffDelay(attempt int) time.Duration {
+	d := baseRetryBackoff << (attempt - 1)
+	half := d / 2
+	return half + rand.N(half)
+}
+
+// parseRetryAfter accepts either delay-seconds or an HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
@@ -0,0 +1,153 @@
+package llmproxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDoWithRetry(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		responses    []func() (*http.Response, error)
+		budget       time.Duration
+		wantStatus   int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "success first try",
+			responses:    []func() (*http.Response, error){respond(200, "", `{}`)},
+			wantStatus:   200,
+			wantAttempts: 1,
+		},
+		{
+			name:         "429 then success",
+			responses:    []func() (*http.Response, error){respond(429, "", `{}`), respond(200, "", `{}`)},
+			wantStatus:   200,
+			wantAttempts: 2,
+		},
+		{
+			name:         "anthropic overloaded then success",
+			responses:    []func() (*http.Response, error){respond(529, "", `{"type":"error","error":{"type":"overloaded_error"}}`), respond(200, "", `{}`)},
+			wantStatus:   200,
+			wantAttempts: 2,
+		},
+		{
+			name: "connection reset then success",
+			responses: []func() (*http.Response, error){
+				func() (*http.Response, error) { return nil, fmt.Errorf("read: %w", syscall.ECONNRESET) },
+				respond(200, "", `{}`),
+			},
+			wantStatus:   200,
+			wantAttempts: 2,
+		},
+		{
+			name:         "400 is not retried",
+			responses:    []func() (*http.Response, error){respond(400, "", `{}`), respond(200, "", `{}`)},
+			wantStatus:   400,
+			wantAttempts: 1,
+		},
+		{
+			name:         "gives up after max retries",
+			responses:    []func() (*http.Response, error){respond(500, "", ``), respond(500, "", ``), respond(500, "", ``), respond(500, "", ``), respond(200, "", `{}`)},
+			wantStatus:   500,
+			wantAttempts: 4,
+		},
+		{
+			name:         "retry-after beyond budget stops",
+			responses:    []func() (*http.Response, error){respond(429, "30", `{}`), respond(200, "", `{}`)},
+			budget:       5 * time.Second,
+			wantStatus:   429,
+			wantAttempts: 1,
+		},
+		{
+			name:         "unrelated transport error is not retried",
+			responses:    []func() (*http.Response, error){func() (*http.Response, error) { return nil, fmt.Errorf("timeout") }},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			calls := 0
+			p := &Proxy{
+				RetryBudget: tt.budget,
+				Client: &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+					r := tt.responses[calls]
+					calls++
+					return r()
+				})},
+				sleep: func(time.Duration) {},
+			}
+
+			resp, _, attempts, err := p.doWithRetry("test", func() (*http.Request, error) {
+				return http.NewRequest(http.MethodPost, "http://upstream.test", strings.NewReader(`{}`))
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("doWithRetry() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Fatalf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if !tt.wantErr && resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "seconds", value: "3", want: 3 * time.Second, wantOK: true},
+		{name: "http date", value: now.Add(2 * time.Second).Format(http.TimeFormat), want: 2 * time.Second, wantOK: true},
+		{name: "empty", value: ""},
+		{name: "garbage", value: "soon"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := parseRetryAfter(tt.value, now)
+			if ok != tt.wantOK || got != tt.want {
+				t.Fatalf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestBackoffDelayBounds(t *testing.T) {
+	t.Parallel()
+	for attempt := 1; attempt <= maxProviderRetries; attempt++ {
+		d := backoffDelay(attempt)
+		full := baseRetryBackoff << (attempt - 1)
+		if d < full/2 || d >= full {
+			t.Fatalf("backoffDelay(%d) = %v, want in [%v, %v)", attempt, d, full/2, full)
+		}
+	}
+}
+
+func respond(code int, retryAfter, body string) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		h := make(http.Header)
+		if retryAfter != "" {
+			h.Set("Retry-After", retryAfter)
+		}
+		return &http.Response{StatusCode: code, Body: io.NopCloser(strings.NewReader(body)), Header: h}, nil
+	}
+}
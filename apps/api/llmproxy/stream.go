@@ -0,0 +1,124 @@
+package llmproxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// finishReasonClientDisconnect tags usage billed for a stream the client
+// abandoned before it finished.
+const finishReasonClientDisconnect = "client_disconnect"
+
+var sseDataPrefix = []byte("data:")
+
+// streamUsage is what relayOpenAIStream learned about a streamed completion.
+type streamUsage struct {
+	reported     tokenUsage
+	hasUsage     bool
+	contentChars int
+}
+
+// relayOpenAIStream copies an OpenAI server-sent event stream to w line by
+// line, flushing each line as it arrives. Token counts come from the usage
+// frame when the upstream sent one; otherwise output is estimated from the
+// streamed content. The relay stops as soon as ctx is cancelled, which also
+// aborts the upstream body read.
+func relayOpenAIStream(ctx context.Context, w http.ResponseWriter, body io.Reader, prompt []chatMessage) (tokenUsage, error) {
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	var (
+		usage streamUsage
+		err   error
+	)
+	reader := bufio.NewReader(body)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			usage.observe(line)
+			if _, writeErr := w.Write(line); writeErr != nil {
+				err = writeErr
+				break
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if readErr != nil {
+			if readErr != io.EOF {
+				err = readErr
+			}
+			break
+		}
+		if ctx.Err() != nil {
+			err = ctx.Err()
+			break
+		}
+	}
+	if ctx.Err() != nil && (err == nil || errors.Is(err, context.Canceled)) {
+		err = ctx.Err()
+	}
+
+	return usage.tokens(prompt), err
+}
+
+// observe records the usage frame and content length of one SSE line.
+func (u *streamUsage) observe(line []byte) {
+	payload, ok := bytes.CutPrefix(bytes.TrimSpace(line), sseDataPrefix)
+	if !ok {
+		return
+	}
+	payload = bytes.TrimSpace(payload)
+	if len(payload) == 0 || bytes.Equal(payload, []byte("[DONE]")) {
+		return
+	}
+	var chunk struct {
+		Choices []struct {
+			Delta struct {
+				Content string `json:"content"`
+			} `json:"delta"`
+		} `json:"choices"`
+		Usage map[string]any `json:"usage"`
+	}
+	if json.Unmarshal(payload, &chunk) != nil {
+		return
+	}
+	for _, choice := range chunk.Choices {
+		u.contentChars += len(choice.Delta.Content)
+	}
+	if chunk.Usage != nil {
+		u.reported = openAIUsage(chunk.Usage)
+		u.hasUsage = true
+	}
+}
+
+// tokens returns the reported usage, or an estimate from the prompt and the
+// streamed content when the stream ended before a usage frame.
+func (u *streamUsage) tokens(prompt []chatMessage) tokenUsage {
+	if u.hasUsage {
+		return u.reported
+	}
+	var input, output int
+	promptChars := 0
+	for _, msg := range prompt {
+		promptChars += len(msg.Content)
+	}
+	if promptChars > 0 {
+		input = max(promptChars/4, 1)
+	}
+	if u.contentChars > 0 {
+		output = max(u.contentChars/4, 1)
+	}
+	return tokenUsage{Input: input, Output: output}
+}
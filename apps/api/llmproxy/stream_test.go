@@ -0,0 +1,118 @@
+package llmproxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRelayOpenAIStreamUsesUsageFrame(t *testing.T) {
+	t.Parallel()
+	stream := "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"content\":\"lo\"},\"finish_reason\":\"stop\"}]}\n\n" +
+		"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":7,\"completion_tokens\":2}}\n\n" +
+		"data: [DONE]\n\n"
+	w := httptest.NewRecorder()
+	usage, err := relayOpenAIStream(context.Background(), w, strings.NewReader(stream), nil)
+	if err != nil || usage.Input != 7 || usage.Output != 2 {
+		t.Fatalf("relayOpenAIStream = %+v, %v", usage, err)
+	}
+	if w.Body.String() != stream || w.Header().Get("Content-Type") != "text/event-stream" || !w.Flushed {
+		t.Fatalf("relayed body = %q headers = %v flushed = %v", w.Body.String(), w.Header(), w.Flushed)
+	}
+
+	// Without a usage frame, tokens are estimated from the text.
+	content := strings.Repeat("a", 40)
+	usage, _ = relayOpenAIStream(context.Background(), httptest.NewRecorder(),
+		strings.NewReader(fmt.Sprintf("data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", content)),
+		[]chatMessage{{Role: "user", Content: strings.Repeat("b", 80)}})
+	if usage.Input != 20 || usage.Output != 10 {
+		t.Fatalf("estimated tokens = %d, %d", usage.Input, usage.Output)
+	}
+}
+
+func TestStreamClientDisconnectCancelsUpstreamAndBills(t *testing.T) {
+	t.Parallel()
+	upstreamCancelled := make(chan time.Time, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\""+strings.Repeat("x", 400)+"\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+			upstreamCancelled <- time.Now()
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	expectModelAccess(mock, "t1")
+	mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(100))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO usage_logs").WithArgs("t1", "gpt-4o", 1, 100, sqlmock.AnyArg(), 0, 1, finishReasonClientDisconnect).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE credits SET balance_cents").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT balance_cents FROM credits").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(90))
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+		return transport.RoundTrip(req)
+	})}
+	model := &Model{ID: "gpt-4o", Provider: "openai"}
+	proxy := &Proxy{DB: db, Registry: &ModelRegistry{models: map[string]*Model{model.ID: model}}, Client: client}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"hi"}]}`)).WithContext(ctx)
+	req.Header.Set("X-Tenant-ID", "t1")
+	browser := &firstWriteRecorder{ResponseRecorder: httptest.NewRecorder(), wrote: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		proxy.handleChatCompletions(browser, req)
+	}()
+
+	<-browser.wrote
+	cancelledAt := time.Now()
+	cancel()
+	select {
+	case at := <-upstreamCancelled:
+		if d := at.Sub(cancelledAt); d > 100*time.Millisecond {
+			t.Fatalf("upstream cancelled after %s", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("upstream request was not cancelled")
+	}
+	<-done
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+// firstWriteRecorder signals once the first body bytes reach the client.
+type firstWriteRecorder struct {
+	*httptest.ResponseRecorder
+	wrote chan struct{}
+	once  sync.Once
+}
+
+func (r *firstWriteRecorder) Write(p []byte) (int, error) {
+	n, err := r.ResponseRecorder.Write(p)
+	r.once.Do(func() { close(r.wrote) })
+	return n, err
+}
@@ -22,6 +22,43 @@ func ExtractAnthropicUsage(body map[string]any) (input, output int) {
 	return
 }
 
+// CacheUsage is the part of a call's input tokens that went through the
+// provider's prompt cache.
+type CacheUsage struct {
+	CreationTokens int // written to the cache on this call
+	ReadTokens     int // served from the cache
+}
+
+// Empty reports whether no tokens went through the cache.
+func (c CacheUsage) Empty() bool {
+	return c.CreationTokens == 0 && c.ReadTokens == 0
+}
+
+// ExtractOpenAICacheUsage extracts cached prompt tokens from an OpenAI
+// response body map. OpenAI caches automatically and does not report writes.
+func ExtractOpenAICacheUsage(body map[string]any) CacheUsage {
+	usage, _ := body["usage"].(map[string]any)
+	details, ok := usage["prompt_tokens_details"].(map[string]any)
+	if !ok {
+		return CacheUsage{}
+	}
+	return CacheUsage{ReadTokens: jsonInt(details, "cached_tokens")}
+}
+
+// ExtractAnthropicCacheUsage extracts prompt cache token counts from an
+// Anthropic response body map. Anthropic does not include them in
+// input_tokens.
+func ExtractAnthropicCacheUsage(body map[string]any) CacheUsage {
+	usage, ok := body["usage"].(map[string]any)
+	if !ok {
+		return CacheUsage{}
+	}
+	return CacheUsage{
+		CreationTokens: jsonInt(usage, "cache_creation_input_tokens"),
+		ReadTokens:     jsonInt(usage, "cache_read_input_tokens"),
+	}
+}
+
 // ExtractGeminiUsage extracts token counts from a Gemini response body map.
 func ExtractGeminiUsage(body map[string]any) (input, output int) {
 	meta, ok := body["usageMetadata"].(map[string]any)
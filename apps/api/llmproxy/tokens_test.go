@@ -97,3 +97,22 @@ func TestEstimateTokens(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractCacheUsage(t *testing.T) {
+	t.Parallel()
+	anthropic := ExtractAnthropicCacheUsage(map[string]any{"usage": map[string]any{
+		"input_tokens": 5.0, "cache_creation_input_tokens": 10.0, "cache_read_input_tokens": 20.0,
+	}})
+	if anthropic != (CacheUsage{CreationTokens: 10, ReadTokens: 20}) {
+		t.Fatalf("ExtractAnthropicCacheUsage() = %+v", anthropic)
+	}
+	openai := ExtractOpenAICacheUsage(map[string]any{"usage": map[string]any{
+		"prompt_tokens": 50.0, "prompt_tokens_details": map[string]any{"cached_tokens": 32.0},
+	}})
+	if openai != (CacheUsage{ReadTokens: 32}) {
+		t.Fatalf("ExtractOpenAICacheUsage() = %+v", openai)
+	}
+	if got := ExtractOpenAICacheUsage(map[string]any{}); !got.Empty() {
+		t.Fatalf("ExtractOpenAICacheUsage(empty) = %+v", got)
+	}
+}
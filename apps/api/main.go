@@ -10,14 +10,26 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/agentsquads/api/channels"
+	"github.com/agentsquads/api/channels/adapters"
 	"github.com/agentsquads/api/coordinator"
+	"github.com/agentsquads/api/errorlog"
+	"github.com/agentsquads/api/i18n"
+	"github.com/agentsquads/api/keyring"
 	"github.com/agentsquads/api/llmproxy"
+	"github.com/agentsquads/api/media"
 	"github.com/agentsquads/api/middleware"
+	"github.com/agentsquads/api/openapi"
 	"github.com/agentsquads/api/orchestrator"
+	"github.com/agentsquads/api/plans"
+	"github.com/agentsquads/api/policies"
+	"github.com/agentsquads/api/prompts"
 	"github.com/agentsquads/api/routes"
 	"github.com/agentsquads/api/terminal"
+	"github.com/agentsquads/api/tools"
+	"github.com/agentsquads/api/usage"
 	"github.com/agentsquads/api/workflows"
 
 	_ "github.com/lib/pq"
@@ -25,6 +37,7 @@ import (
 )
 
 func main() {
+	slog.SetDefault(slog.New(middleware.NewRequestIDLogHandler(slog.NewTextHandler(os.Stderr, nil))))
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("GET /", func(w http.ResponseWriter, _ *http.Request) {
@@ -35,11 +48,12 @@ func main() {
 		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 	})
 
+	var workflowRunner *workflows.Runner
 	workflowDefs, workflowDir, err := workflows.LoadWorkflowsFromDefaultPaths()
 	if err != nil {
 		slog.Error("failed to load workflow templates", "err", err)
 	} else {
-		workflowRunner := workflows.NewRunner(workflowDefs)
+		workflowRunner = workflows.NewRunner(workflowDefs)
 		workflowHandler := workflows.NewHandler(workflowRunner)
 		workflowHandler.Mount(mux)
 		slog.Info("workflow handler mounted", "dir", workflowDir, "count", len(workflowDefs))
@@ -52,6 +66,24 @@ func main() {
 	var channelLinks *channels.LinkStore
 	var channelCreds *channels.CredentialsStore
 	var redisClient *redis.Client
+	var planResolver *plans.Resolver
+	var policyStore *policies.Store
+	var promptStore *prompts.Store
+	var llmProxy *llmproxy.Proxy
+	var mediaService *media.Service
+	var blobStore media.BlobStore
+	var broadcastStore *channels.BroadcastStore
+	var customTools *tools.CustomToolStore
+	var memoryStore *tools.MemoryStore
+	var channelFanout *channels.Fanout
+	var swarmConfigs *coordinator.TenantConfigStore
+	var swarmSettings *coordinator.SwarmSettingsStore
+	var modelRegistry *llmproxy.ModelRegistry
+	var usageRollup *usage.Rollup
+	var idleStopper *orchestrator.IdleStopper
+	var messageCatalog *i18n.Catalog
+	var tenantLocales *i18n.TenantLocales
+	reloadInterval := configReloadInterval()
 
 	coordHandler := coordinator.NewHandler(nil)
 
@@ -61,40 +93,113 @@ func main() {
 		if err != nil {
 			slog.Error("failed to connect to database", "err", err)
 		} else {
+			slog.SetDefault(slog.New(middleware.NewRequestIDLogHandler(errorlog.NewHandler(slog.NewTextHandler(os.Stderr, nil), errorlog.NewSink(db)))))
+			planResolver = plans.NewResolver(db)
+			usageRollup = usage.NewRollup(db)
+			go usageRollup.Start(context.Background(), usageRollupInterval())
+			policyStore = policies.NewStore(db)
+			promptStore = prompts.NewStore(db)
 			redisClient = initRedisClient()
 			coordHandler = coordinator.NewHandler(redisClient)
+			coordHandler.SetPlanResolver(planResolver)
+			coordHandler.SetPolicyChecker(policyStore)
+			coordHandler.SetPromptResolver(promptStore)
+			swarmConfigs = coordinator.NewTenantConfigStore(db, redisClient)
+			coordHandler.SetTenantConfigSource(swarmConfigs)
+			swarmSettings = coordinator.NewSwarmSettingsStore(db, coordinator.LoadSwarmConfigFromEnv())
+			if _, err := swarmSettings.Reload(context.Background()); err != nil {
+				slog.Warn("using environment swarm config", "err", err)
+			}
+			coordHandler.SetSwarmConfigSource(swarmSettings)
+			go swarmSettings.Start(context.Background(), reloadInterval)
+			coordHandler.SetTranscriptWriter(coordinator.NewTranscriptStore(db))
+			coordHandler.SetInvocationRecorder(coordinator.NewInvocationStore(db))
+			coordHandler.SetRunRecorder(coordinator.NewRunStore(db))
 			channelLinks = channels.NewLinkStore(db)
+			coordHandler.SetNotificationPreferences(channelLinks)
+			messageCatalog = i18n.NewCatalog(db)
+			if _, err := messageCatalog.Reload(context.Background()); err != nil {
+				slog.Warn("using embedded message catalogs", "err", err)
+			}
+			go messageCatalog.Start(context.Background(), reloadInterval)
+			tenantLocales = i18n.NewTenantLocales(db)
+			coordHandler.SetMessages(messageCatalog, tenantLocales)
 			channelCreds = channels.NewCredentialsStore(db)
+			broadcastStore = channels.NewBroadcastStore(db)
 			channelRouter = channels.NewRouter(db, redisClient)
-			channelRouter.SetAgentBridge(coordinator.NewBridge(coordHandler))
+			bridge := coordinator.NewBridge(coordHandler)
+			bridge.SetRubricSource(coordinator.NewRubricStore(db))
+			channelRouter.SetAgentBridge(bridge)
+			channelRouter.SetPolicyChecker(policyStore)
+			channelRouter.SetPromptResolver(promptStore)
+			channelRouter.SetDBQuery(tools.NewSupabaseDeployments(db), policyStore)
+			customTools = tools.NewCustomToolStore(db)
+			channelRouter.SetCustomTools(customTools)
+			memoryStore = tools.NewMemoryStore(db)
+			channelRouter.SetMemoryStore(memoryStore)
+			channelRouter.SetWebhookTool(policyStore)
+			channelRouter.SetFetchAllowlist(policyStore)
+			channelRouter.Use(channels.ModerationMiddleware(db, policyStore))
+			if blobs, err := media.NewBlobStoreFromEnv(); err != nil {
+				slog.Error("media storage disabled", "err", err)
+			} else {
+				blobStore = blobs
+				mediaService = media.NewService(db, blobs)
+				channelRouter.SetMediaReader(mediaService)
+			}
 
 			if redisClient != nil {
 				fanout := channels.NewFanout(redisClient, channelLinks, channelCreds)
+				fanout.RegisterSender("viber", adapters.NewViberAdapter(channelCreds))
+				fanout.DryRun = strings.EqualFold(strings.TrimSpace(os.Getenv("CHANNEL_FANOUT_DRY_RUN")), "true")
+				outboundFilters := channels.NewOutboundFilters(policyStore, channels.NewOutboundFilterStore(db))
+				if proxyURL := strings.TrimSpace(os.Getenv("LLM_PROXY_URL")); proxyURL != "" {
+					outboundFilters.SetModerator(channels.NewLLMModerator(proxyURL, ""))
+				}
+				fanout.SetOutboundFilters(outboundFilters)
 				go func() {
 					if err := fanout.Start(context.Background()); err != nil {
 						slog.Error("channel fanout stopped", "err", err)
 					}
 				}()
+				go channels.NewBroadcaster(broadcastStore, fanout).Start(context.Background())
+				channelFanout = fanout
 			}
 
-			orchImpl, err := orchestrator.NewDockerOrchestrator(
-				db,
-				os.Getenv("PLATFORM_API_URL"),
-				os.Getenv("PLATFORM_API_KEY"),
-				os.Getenv("LLM_PROXY_URL"),
-			)
+			orch, err = newOrchestrator(db, planResolver)
 			if err != nil {
 				slog.Error("failed to initialize orchestrator", "err", err)
 			} else {
-				orch = orchImpl
+				if q, ok := orch.(orchestrator.StorageQuotaEnforcer); ok && !q.EnforcesStorageQuota() {
+					go orchestrator.NewDiskQuotaMonitor(db, orch, planResolver).Start(context.Background(), diskQuotaCheckInterval())
+					slog.Info("container disk quotas enforced by periodic usage checks")
+				}
+				if redisClient != nil {
+					orch = orchestrator.NewEndpointCache(orch, redisClient)
+				}
+				if window := orchestrator.IdleWindowFromEnv(); window > 0 {
+					idleStopper = orchestrator.NewIdleStopper(db, orch, window)
+					go idleStopper.Start(context.Background())
+					slog.Info("tenant idle window enabled", "window", window)
+				}
 			}
 
 			reg, err := llmproxy.NewModelRegistry(db)
 			if err != nil {
 				slog.Error("failed to load model registry", "err", err)
 			} else {
-				proxy := llmproxy.NewProxy(db, reg, orch)
-				proxy.Mount(mux)
+				modelRegistry = reg
+				go reg.Start(context.Background(), reloadInterval)
+				llmProxy = llmproxy.NewProxy(db, reg, orch)
+				llmProxy.Plans = planResolver
+				llmProxy.Policies = policyStore
+				llmProxy.Activity = idleStopper
+				llmProxy.Mount(mux)
+				if redisClient != nil {
+					// Reminders go out weekly per tenant; checking daily picks
+					// up new deprecated usage without waiting a week.
+					go llmproxy.NewDeprecationNotifier(db, redisClient).Start(context.Background(), 24*time.Hour)
+				}
 				slog.Info("LLM proxy mounted")
 			}
 		}
@@ -103,7 +208,27 @@ func main() {
 	}
 
 	channelHandler := routes.NewChannelHandler(db, channelRouter, channelLinks, channelCreds)
+	channelHandler.Policies = policyStore
+	channelHandler.Media = mediaService
+	channelHandler.Blobs = blobStore
+	channelHandler.ExportSecret = conversationExportSecret()
+	channelHandler.Fanout = channelFanout
+	channelHandler.Redis = redisClient
+	if redisClient != nil && channelRouter != nil {
+		inbound := channels.NewInboundQueue(redisClient, channelRouter.Route)
+		channelHandler.UseInboundQueue(inbound)
+		go func() {
+			if err := inbound.Start(context.Background()); err != nil {
+				slog.Error("inbound queue stopped", "err", err)
+			}
+		}()
+	}
 	channelHandler.Mount(mux)
+	go channelHandler.PurgeWebhookFailures(context.Background())
+	routes.NewBroadcastHandler(broadcastStore).Mount(mux)
+	routes.NewCustomToolHandler(customTools).Mount(mux)
+	routes.NewMemoryHandler(memoryStore).Mount(mux)
+	routes.NewTenantSettingsHandler(tenantLocales).Mount(mux)
 	slog.Info("channel routes mounted")
 
 	mux.HandleFunc("POST /api/tenants/{id}/resume", func(w http.ResponseWriter, r *http.Request) {
@@ -143,30 +268,168 @@ func main() {
 		writeJSON(w, http.StatusOK, map[string]string{"status": "active"})
 	})
 
+	routes.NewActivityHandler(db).Mount(mux)
+	invoiceHandler := routes.NewInvoiceHandler(db)
+	invoiceHandler.Blobs = blobStore
+	invoiceHandler.Mount(mux)
+
+	if workflowRunner != nil {
+		workflowRuns := routes.NewWorkflowRunHandler(db, workflowRunner)
+		workflowRuns.Policies = policyStore
+		workflowRuns.Mount(mux)
+	}
+
 	eventsHandler := routes.NewEventsHandler(db)
+	eventsHandler.Orch = orch
 	eventsHandler.Mount(mux)
 	slog.Info("events handler mounted")
 
 	adminHandler := routes.NewAdminHandler(db, orch)
+	adminHandler.Plans = planResolver
+	adminHandler.Policies = policyStore
+	adminHandler.Prompts = promptStore
+	adminHandler.Redis = redisClient
+	adminHandler.SwarmConfigs = swarmConfigs
+	adminHandler.Models = modelRegistry
+	adminHandler.SwarmSettings = swarmSettings
+	adminHandler.Messages = messageCatalog
+	adminHandler.Usage = usageRollup
+	adminHandler.Blobs = blobStore
+	if llmProxy != nil {
+		adminHandler.Benchmarks = llmProxy
+		adminHandler.ModelTests = llmProxy
+		adminHandler.InFlight = llmProxy
+	}
+	if db != nil {
+		if keys, err := keyring.FromEnv(); err != nil {
+			slog.Warn("key rotation disabled", "err", err)
+		} else {
+			adminHandler.Rotation = keyring.NewRotator(db, keys)
+		}
+	}
 	adminHandler.Mount(mux)
 	slog.Info("admin routes mounted")
 
+	containerHandler := routes.NewContainerHandler(db)
+	containerHandler.Orch = orch
+	containerHandler.Mount(mux)
+	slog.Info("container routes mounted")
+
+	routes.NewTenantModelsHandler(db).Mount(mux)
+	slog.Info("tenant model routes mounted")
+
 	routes.MountSwarmRoutes(mux, coordHandler)
+	routes.NewSwarmFeedbackHandler(db, coordinator.NewRunStore(db)).Mount(mux)
 	slog.Info("coordinator handler mounted")
 
-	mountHandsProxyRoutes(mux)
+	onboardingHandler := routes.NewOnboardingHandler(db)
+	onboardingHandler.Orch = orch
+	onboardingHandler.Swarm = coordHandler
+	onboardingHandler.Mount(mux)
+	slog.Info("onboarding routes mounted")
+
+	mountDeployRoutes(mux, db, policyStore, redisClient)
+	slog.Info("deploy routes mounted")
+
+	mountHandsProxyRoutes(mux, db, policyStore, orch, channelRouter, idleStopper)
 	slog.Info("hands proxy routes mounted")
 
 	if db != nil {
-		mux.Handle("GET /api/tenants/{id}/terminal", terminal.Handler(db))
+		mux.Handle("GET /api/tenants/{id}/terminal", terminal.Handler(db, policyStore))
 		slog.Info("terminal handler mounted")
 	}
 
+	if apiDocsEnabled() {
+		mountAPIDocs(mux, openapi.DefaultRegistry)
+		slog.Info("API docs mounted", "spec", apiSpecPath, "ui", apiDocsPath)
+	}
+
 	log.Println("API server listening on :8080")
-	handler := applyRequestBodyLimit(applyAuth(middleware.ApplyAdmin(mux)))
+	handler := middleware.ApplyRequestID(middleware.ApplyGzip(applyRequestBodyLimit(applyAuth(middleware.ApplyImpersonation(db)(middleware.ApplyAdmin(mux))))))
 	log.Fatal(http.ListenAndServe(":8080", handler))
 }
 
+// newOrchestrator builds the tenant backend selected by ORCHESTRATOR: docker
+// (the default) or kubernetes.
+func newOrchestrator(db *sql.DB, planResolver *plans.Resolver) (orchestrator.TenantOrchestrator, error) {
+	platformAPIURL := os.Getenv("PLATFORM_API_URL")
+	platformAPIKey := os.Getenv("PLATFORM_API_KEY")
+	llmProxyURL := os.Getenv("LLM_PROXY_URL")
+
+	switch backend := strings.ToLower(strings.TrimSpace(os.Getenv("ORCHESTRATOR"))); backend {
+	case "", "docker":
+		o, err := orchestrator.NewDockerOrchestrator(db, platformAPIURL, platformAPIKey, llmProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		o.SetPlanResolver(planResolver)
+		return o, nil
+	case "kubernetes", "k8s":
+		o, err := orchestrator.NewKubernetesOrchestrator(db, platformAPIURL, platformAPIKey, llmProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		o.SetPlanResolver(planResolver)
+		slog.Info("using kubernetes orchestrator")
+		return o, nil
+	default:
+		return nil, fmt.Errorf("unknown ORCHESTRATOR %q (want docker or kubernetes)", backend)
+	}
+}
+
+// mountDeployRoutes mounts the Vercel and Supabase deploy endpoints. Deploys
+// are gated by the tenant deploy policy, and status changes reach the
+// tenant's linked chats when redis is available.
+func mountDeployRoutes(mux *http.ServeMux, db *sql.DB, policyStore *policies.Store, redisClient *redis.Client) {
+	h := routes.NewDeployHandler(db)
+	h.SetPolicyStore(policyStore)
+	h.SetRedis(redisClient)
+	h.Mount(mux)
+}
+
+// diskQuotaCheckInterval is how often tenant disk usage is measured when the
+// container runtime cannot cap it (DISK_QUOTA_CHECK_INTERVAL, default 15m).
+func diskQuotaCheckInterval() time.Duration {
+	if v := strings.TrimSpace(os.Getenv("DISK_QUOTA_CHECK_INTERVAL")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return 15 * time.Minute
+}
+
+// conversationExportSecret signs conversation export download URLs
+// (CONVERSATION_EXPORT_SECRET, falling back to API_JWT_SECRET).
+func conversationExportSecret() string {
+	if v := strings.TrimSpace(os.Getenv("CONVERSATION_EXPORT_SECRET")); v != "" {
+		return v
+	}
+	return strings.TrimSpace(os.Getenv("API_JWT_SECRET"))
+}
+
+// usageRollupInterval is how often the usage_daily rollup is reconciled with
+// usage_logs (USAGE_ROLLUP_INTERVAL, default 24h).
+func usageRollupInterval() time.Duration {
+	if v := strings.TrimSpace(os.Getenv("USAGE_ROLLUP_INTERVAL")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return 24 * time.Hour
+}
+
+// configReloadInterval is how often the model registry, platform swarm
+// settings and message overrides are re-read from the database
+// (CONFIG_RELOAD_INTERVAL, default 60s).
+func configReloadInterval() time.Duration {
+	if v := strings.TrimSpace(os.Getenv("CONFIG_RELOAD_INTERVAL")); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return 60 * time.Second
+}
+
 func initRedisClient() *redis.Client {
 	redisURL := strings.TrimSpace(os.Getenv("REDIS_URL"))
 	if redisURL == "" {
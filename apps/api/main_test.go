@@ -0,0 +1,49 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/policies"
+)
+
+func TestMountDeployRoutes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mux := http.NewServeMux()
+	mountDeployRoutes(mux, db, policies.NewStore(db), nil)
+
+	mock.ExpectQuery("FROM deploy_env_vars").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value_encrypted", "secret", "updated_at", "synced_at", "synced_project"}))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/deploy/env?tenant_id=t1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/deploy/env status = %d body = %s", w.Code, w.Body.String())
+	}
+
+	// The shared policy store gates deploys.
+	mock.ExpectQuery("SELECT enabled FROM tenant_policies").WithArgs("t1", policies.FeatureDeploy).
+		WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(false))
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/deploy/env?tenant_id=t1", strings.NewReader(`{"vars":[{"key":"API_URL","value":"https://x"}]}`)))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("PUT /api/deploy/env with deploy disabled status = %d body = %s", w.Code, w.Body.String())
+	}
+
+	mock.ExpectQuery("FROM deployment_runs").WithArgs("r1").WillReturnError(sql.ErrNoRows)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/deploy/status/r1", nil))
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "deployment not found") {
+		t.Fatalf("GET /api/deploy/status/{id} status = %d body = %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
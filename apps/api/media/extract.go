@@ -0,0 +1,284 @@
+package media
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxPDFStreamBytes caps one inflated PDF stream so a compression bomb
+// cannot exhaust memory.
+const maxPDFStreamBytes = 8 << 20
+
+// ErrNoText is returned by ExtractText for files it cannot read as text,
+// such as images and scanned PDFs.
+var ErrNoText = errors.New("file has no extractable text")
+
+var textExtensions = map[string]bool{
+	".txt": true, ".md": true, ".markdown": true, ".csv": true, ".tsv": true,
+	".json": true, ".xml": true, ".yaml": true, ".yml": true, ".log": true,
+}
+
+// ExtractText returns the readable text of a file: plain text and common
+// text formats as is, HTML without markup, and the text operators of a PDF's
+// content streams. PDF extraction is best effort; PDFs with embedded font
+// encodings may come back garbled or empty.
+func ExtractText(contentType, fileName string, data []byte) (string, error) {
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	ext := strings.ToLower(filepath.Ext(fileName))
+
+	var text string
+	switch {
+	case contentType == "application/pdf" || ext == ".pdf":
+		text = extractPDFText(data)
+	case contentType == "text/html" || ext == ".html" || ext == ".htm":
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+		if err != nil {
+			return "", err
+		}
+		doc.Find("script, style").Remove()
+		text = doc.Text()
+	case strings.HasPrefix(contentType, "text/"),
+		contentType == "application/json", contentType == "application/xml",
+		contentType == "application/x-yaml", contentType == "application/csv",
+		textExtensions[ext]:
+		text = strings.ToValidUTF8(string(data), "�")
+	default:
+		return "", ErrNoText
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", ErrNoText
+	}
+	return text, nil
+}
+
+// extractPDFText inflates every stream of the PDF and collects the strings
+// shown by text operators of the ones that are page content.
+func extractPDFText(data []byte) string {
+	var sb strings.Builder
+	for rest := data; ; {
+		start := bytes.Index(rest, []byte("stream"))
+		if start < 0 {
+			break
+		}
+		dict := rest[max(0, start-512):start]
+		body := rest[start+len("stream"):]
+		body = bytes.TrimPrefix(body, []byte("\r"))
+		body = bytes.TrimPrefix(body, []byte("\n"))
+		end := bytes.Index(body, []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		stream := body[:end]
+		rest = body[end+len("endstream"):]
+
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			inflated, err := io.ReadAll(io.LimitReader(flateReader(stream), maxPDFStreamBytes))
+			if len(inflated) == 0 && err != nil {
+				continue
+			}
+			stream = inflated
+		}
+		if !bytes.Contains(stream, []byte("BT")) {
+			continue
+		}
+		if text := pdfContentText(stream); text != "" {
+			sb.WriteString(text)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+func flateReader(data []byte) io.Reader {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return bytes.NewReader(nil)
+	}
+	return r
+}
+
+// pdfContentText walks a content stream's tokens, emitting the operands of
+// Tj, TJ, ' and " and a line break for text positioning operators.
+func pdfContentText(content []byte) string {
+	var sb strings.Builder
+	var operands []string
+	newline := func() {
+		if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+			sb.WriteString("\n")
+		}
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '(':
+			s, n := pdfLiteralString(content[i:])
+			operands = append(operands, s)
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] != '<':
+			s, n := pdfHexString(content[i:])
+			operands = append(operands, s)
+			i += n
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '/':
+			// A name, such as a font resource; never text.
+			i++
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+		case isPDFSpace(c) || isPDFDelimiter(c):
+			i++
+		default:
+			j := i + 1
+			for j < len(content) && !isPDFSpace(content[j]) && !isPDFDelimiter(content[j]) {
+				j++
+			}
+			token := string(content[i:j])
+			i = j
+			if n, err := strconv.ParseFloat(token, 64); err == nil {
+				// Large negative TJ adjustments separate words.
+				if n < -200 && len(operands) > 0 {
+					operands = append(operands, " ")
+				}
+				continue
+			}
+			switch token {
+			case "Tj", "TJ":
+				sb.WriteString(strings.Join(operands, ""))
+			case "'", "\"":
+				newline()
+				sb.WriteString(strings.Join(operands, ""))
+			case "Td", "TD", "T*", "ET":
+				newline()
+			}
+			operands = operands[:0]
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// pdfLiteralString decodes a (...) string at the start of b and returns it
+// with the number of bytes consumed.
+func pdfLiteralString(b []byte) (string, int) {
+	var sb strings.Builder
+	depth := 0
+	i := 0
+	for ; i < len(b); i++ {
+		c := b[i]
+		switch c {
+		case '(':
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfDecode(sb.String()), i + 1
+			}
+		case '\\':
+			i++
+			if i >= len(b) {
+				continue
+			}
+			switch e := b[i]; e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation.
+			default:
+				if e >= '0' && e <= '7' {
+					v, n := 0, 0
+					for n < 3 && i < len(b) && b[i] >= '0' && b[i] <= '7' {
+						v = v*8 + int(b[i]-'0')
+						i++
+						n++
+					}
+					i--
+					sb.WriteByte(byte(v))
+				} else {
+					sb.WriteByte(e)
+				}
+			}
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return pdfDecode(sb.String()), i
+}
+
+// pdfHexString decodes a <...> string at the start of b.
+func pdfHexString(b []byte) (string, int) {
+	end := bytes.IndexByte(b, '>')
+	if end < 0 {
+		return "", len(b)
+	}
+	digits := make([]byte, 0, end)
+	for _, c := range b[1:end] {
+		if !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, 0, len(digits)/2)
+	for k := 0; k+1 < len(digits); k += 2 {
+		v, err := strconv.ParseUint(string(digits[k:k+2]), 16, 8)
+		if err != nil {
+			return "", end + 1
+		}
+		out = append(out, byte(v))
+	}
+	return pdfDecode(string(out)), end + 1
+}
+
+// pdfDecode turns a PDF string into UTF-8: UTF-16BE when it starts with a
+// byte order mark, otherwise Latin-1 as an approximation of PDFDocEncoding.
+func pdfDecode(s string) string {
+	if strings.HasPrefix(s, "\xfe\xff") {
+		var sb strings.Builder
+		for k := 2; k+1 < len(s); k += 2 {
+			sb.WriteRune(rune(s[k])<<8 | rune(s[k+1]))
+		}
+		return sb.String()
+	}
+	if utf8.ValidString(s) {
+		return s
+	}
+	runes := make([]rune, len(s))
+	for k := 0; k < len(s); k++ {
+		runes[k] = rune(s[k])
+	}
+	return string(runes)
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/':
+		return true
+	}
+	return false
+}
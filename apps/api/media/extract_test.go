@@ -0,0 +1,73 @@
+package media
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testPDF builds a minimal PDF whose page content stream is Flate-compressed.
+func testPDF(t *testing.T, content string) []byte {
+	t.Helper()
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+	pdf.Write(compressed.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestExtractTextPDF(t *testing.T) {
+	t.Parallel()
+	content := "BT /F1 12 Tf 72 720 Td (Quarterly report \\(draft\\)) Tj 0 -14 Td [(Rev) -20 (enue) -300 (grew)] TJ T* <48656c6c6f> Tj ET"
+	text, err := ExtractText("application/pdf", "report.pdf", testPDF(t, content))
+	if err != nil {
+		t.Fatalf("ExtractText: %v", err)
+	}
+	if text != "Quarterly report (draft)\nRevenue grew\nHello" {
+		t.Fatalf("text = %q", text)
+	}
+
+	if _, err := ExtractText("application/pdf", "scan.pdf", testPDF(t, "q 100 0 0 100 0 0 cm /Im0 Do Q")); !errors.Is(err, ErrNoText) {
+		t.Fatalf("image-only pdf err = %v", err)
+	}
+}
+
+func TestExtractTextFormats(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		contentType string
+		fileName    string
+		data        string
+		want        string
+		wantErr     error
+	}{
+		{name: "plain", contentType: "text/plain; charset=utf-8", data: " notes \n", want: "notes"},
+		{name: "markdown by extension", contentType: "application/octet-stream", fileName: "README.md", data: "# Title", want: "# Title"},
+		{name: "json", contentType: "application/json", data: `{"a":1}`, want: `{"a":1}`},
+		{name: "html", contentType: "text/html", data: "<html><head><style>p{}</style></head><body><p>Hi <b>there</b></p></body></html>", want: "Hi there"},
+		{name: "image", contentType: "image/jpeg", fileName: "photo.jpg", data: "\xff\xd8\xff", wantErr: ErrNoText},
+		{name: "empty text", contentType: "text/plain", data: "  ", wantErr: ErrNoText},
+	}
+	for _, tt := range tests {
+		got, err := ExtractText(tt.contentType, tt.fileName, []byte(tt.data))
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if strings.TrimSpace(got) != tt.want {
+			t.Fatalf("%s: text = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+// Package media ingests files sent over messaging channels (photos,
+// documents) so agents can read them instead of a "[document]" placeholder.
+package media
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/tools"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	// DefaultMaxBytes caps a downloaded file. Telegram's Bot API will not
+	// serve files above 20 MB either.
+	DefaultMaxBytes = 20 << 20
+
+	// MetadataKey is the inbound message metadata key carrying the JSON
+	// encoded []Ref of the message's files.
+	MetadataKey = "media"
+
+	telegramAPIBase = "https://api.telegram.org"
+	whatsAppAPIBase = "https://graph.facebook.com"
+)
+
+var (
+	ErrTooLarge = errors.New("file exceeds the media size limit")
+	ErrNotFound = errors.New("media not found")
+)
+
+// Attachment is a file carried by an inbound channel message, as the
+// provider describes it. ProviderFileID is Telegram's file_id or WhatsApp's
+// media id.
+type Attachment struct {
+	Channel        string
+	Kind           string // photo, document
+	ProviderFileID string
+	FileName       string
+	ContentType    string
+}
+
+// Ref points at an ingested file. Inbound messages carry their refs in
+// metadata under MetadataKey.
+type Ref struct {
+	ID          string `json:"id"`
+	Kind        string `json:"kind"`
+	FileName    string `json:"file_name,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size"`
+}
+
+// Describe is the line agents see for the file, including how to read it.
+func (r Ref) Describe() string {
+	name := r.FileName
+	if name == "" {
+		name = r.Kind
+	}
+	return fmt.Sprintf("%s %q (%s, %s) - read it with file_read media_id=%s", r.Kind, name, r.ContentType, formatSize(r.Size), r.ID)
+}
+
+// EncodeRefs renders refs for the MetadataKey metadata value.
+func EncodeRefs(refs []Ref) string {
+	raw, _ := json.Marshal(refs)
+	return string(raw)
+}
+
+// RefsFromMetadata returns the files recorded in an inbound message's
+// metadata, or nil when there are none.
+func RefsFromMetadata(metadata map[string]string) []Ref {
+	raw := strings.TrimSpace(metadata[MetadataKey])
+	if raw == "" {
+		return nil
+	}
+	var refs []Ref
+	if err := json.Unmarshal([]byte(raw), &refs); err != nil {
+		return nil
+	}
+	return refs
+}
+
+// Service downloads channel attachments, keeps their bytes in a BlobStore and
+// records them in message_media.
+type Service struct {
+	HTTPClient *http.Client
+
+	db       *sql.DB
+	blobs    BlobStore
+	maxBytes int64
+}
+
+// NewService stores files in blobs. MEDIA_MAX_BYTES overrides the
+// DefaultMaxBytes download cap.
+func NewService(db *sql.DB, blobs BlobStore) *Service {
+	maxBytes := int64(DefaultMaxBytes)
+	if n, err := strconv.ParseInt(strings.TrimSpace(os.Getenv("MEDIA_MAX_BYTES")), 10, 64); err == nil && n > 0 {
+		maxBytes = n
+	}
+	return &Service{
+		HTTPClient: &http.Client{Timeout: 60 * time.Second},
+		db:         db,
+		blobs:      blobs,
+		maxBytes:   maxBytes,
+	}
+}
+
+// IngestTelegram downloads att with the bot's token and stores it.
+func (s *Service) IngestTelegram(ctx context.Context, tenantID, botToken string, att Attachment) (Ref, error) {
+	var file struct {
+		OK     bool `json:"ok"`
+		Result struct {
+			FilePath string `json:"file_path"`
+			FileSize int64  `json:"file_size"`
+		} `json:"result"`
+		Description string `json:"description"`
+	}
+	infoURL := fmt.Sprintf("%s/bot%s/getFile?file_id=%s", telegramAPIBase, botToken, url.QueryEscape(att.ProviderFileID))
+	if err := s.getJSON(ctx, infoURL, "", &file); err != nil {
+		return Ref{}, fmt.Errorf("telegram getFile: %w", err)
+	}
+	if !file.OK || file.Result.FilePath == "" {
+		return Ref{}, fmt.Errorf("telegram getFile: %s", file.Description)
+	}
+	if file.Result.FileSize > s.maxBytes {
+		return Ref{}, ErrTooLarge
+	}
+	if att.FileName == "" {
+		att.FileName = path.Base(file.Result.FilePath)
+	}
+
+	data, err := s.download(ctx, fmt.Sprintf("%s/file/bot%s/%s", telegramAPIBase, botToken, file.Result.FilePath), "", s.maxBytes)
+	if err != nil {
+		return Ref{}, fmt.Errorf("telegram download: %w", err)
+	}
+	return s.save(ctx, tenantID, att, data)
+}
+
+// IngestWhatsApp resolves att's media id through the Graph API and stores
+// the file.
+func (s *Service) IngestWhatsApp(ctx context.Context, tenantID, accessToken, apiVersion string, att Attachment) (Ref, error) {
+	var info struct {
+		URL      string `json:"url"`
+		MimeType string `json:"mime_type"`
+		FileSize int64  `json:"file_size"`
+	}
+	infoURL := fmt.Sprintf("%s/%s/%s", whatsAppAPIBase, apiVersion, url.PathEscape(att.ProviderFileID))
+	if err := s.getJSON(ctx, infoURL, accessToken, &info); err != nil {
+		return Ref{}, fmt.Errorf("whatsapp media lookup: %w", err)
+	}
+	if info.URL == "" {
+		return Ref{}, errors.New("whatsapp media lookup returned no url")
+	}
+	if info.FileSize > s.maxBytes {
+		return Ref{}, ErrTooLarge
+	}
+	if att.ContentType == "" {
+		att.ContentType = info.MimeType
+	}
+
+	data, err := s.download(ctx, info.URL, accessToken, s.maxBytes)
+	if err != nil {
+		return Ref{}, fmt.Errorf("whatsapp download: %w", err)
+	}
+	return s.save(ctx, tenantID, att, data)
+}
+
+func (s *Service) save(ctx context.Context, tenantID string, att Attachment, data []byte) (Ref, error) {
+	contentType := strings.TrimSpace(strings.Split(att.ContentType, ";")[0])
+	if contentType == "" {
+		contentType = strings.Split(http.DetectContentType(data), ";")[0]
+	}
+	ref := Ref{
+		ID:          uuid.NewString(),
+		Kind:        att.Kind,
+		FileName:    strings.TrimSpace(att.FileName),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+	blobKey := tenantID + "/" + ref.ID
+	if err := s.blobs.Put(ctx, blobKey, contentType, data); err != nil {
+		return Ref{}, fmt.Errorf("store media blob: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO message_media (id, tenant_id, channel, kind, provider_file_id, file_name, content_type, size_bytes, blob_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, ref.ID, tenantID, att.Channel, ref.Kind, att.ProviderFileID, sql.NullString{String: ref.FileName, Valid: ref.FileName != ""}, ref.ContentType, ref.Size, blobKey); err != nil {
+		return Ref{}, fmt.Errorf("insert message media: %w", err)
+	}
+	return ref, nil
+}
+
+// LinkToMessage ties refs to the message that carried them, in the
+// transaction that inserted it.
+func LinkToMessage(ctx context.Context, tx *sql.Tx, tenantID, conversationID, messageID string, refs []Ref) error {
+	ids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		ids = append(ids, ref.ID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE message_media
+		SET message_id = $1, conversation_id = $2
+		WHERE tenant_id = $3 AND id::text = ANY($4)
+	`, messageID, conversationID, tenantID, pq.Array(ids)); err != nil {
+		return fmt.Errorf("link message media: %w", err)
+	}
+	return nil
+}
+
+// ReadMedia loads a tenant's file and extracts its text. It implements
+// tools.MediaReader; Text is empty for files without extractable text, such
+// as photos.
+func (s *Service) ReadMedia(ctx context.Context, tenantID, mediaID string) (tools.MediaFile, error) {
+	if _, err := uuid.Parse(strings.TrimSpace(mediaID)); err != nil {
+		return tools.MediaFile{}, ErrNotFound
+	}
+	var (
+		file     tools.MediaFile
+		fileName sql.NullString
+		blobKey  string
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT file_name, content_type, size_bytes, blob_key
+		FROM message_media
+		WHERE id = $1 AND tenant_id = $2
+	`, strings.TrimSpace(mediaID), tenantID).Scan(&fileName, &file.ContentType, &file.Size, &blobKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return tools.MediaFile{}, ErrNotFound
+	}
+	if err != nil {
+		return tools.MediaFile{}, fmt.Errorf("load message media: %w", err)
+	}
+	file.Name = fileName.String
+
+	data, err := s.blobs.Get(ctx, blobKey)
+	if err != nil {
+		return tools.MediaFile{}, fmt.Errorf("load media blob: %w", err)
+	}
+	text, err := ExtractText(file.ContentType, file.Name, data)
+	if err != nil && !errors.Is(err, ErrNoText) {
+		return tools.MediaFile{}, err
+	}
+	file.Text = text
+	return file, nil
+}
+
+func (s *Service) getJSON(ctx context.Context, reqURL, bearer string, dst any) error {
+	body, err := s.download(ctx, reqURL, bearer, 1<<20)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, dst); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// download GETs reqURL, refusing bodies over limit bytes.
+func (s *Service) download(ctx context.Context, reqURL, bearer string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > limit {
+		return nil, ErrTooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%d KB", n>>10)
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
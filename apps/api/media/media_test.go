@@ -0,0 +1,229 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func reply(status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header), ContentLength: int64(len(body))}
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	t.Parallel()
+	store := NewLocalBlobStore(t.TempDir())
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "t1/abc", "text/plain", []byte("hello")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if data, err := store.Get(ctx, "t1/abc"); err != nil || string(data) != "hello" {
+		t.Fatalf("Get = %q, %v", data, err)
+	}
+	if _, err := store.Get(ctx, "t1/missing"); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("missing blob err = %v", err)
+	}
+	if err := store.Put(ctx, "../escape", "", []byte("x")); err == nil {
+		t.Fatalf("expected traversal key to be rejected")
+	}
+	for range 2 {
+		if err := store.Delete(ctx, "t1/abc"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+	}
+	if _, err := store.Get(ctx, "t1/abc"); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("deleted blob err = %v", err)
+	}
+}
+
+func TestS3BlobStoreSignsRequests(t *testing.T) {
+	t.Parallel()
+	objects := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AK/20261016/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=") ||
+			r.Header.Get("X-Amz-Date") != "20261016T120000Z" {
+			t.Errorf("unsigned request: %s %v", r.Method, r.Header)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			if r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body) {
+				t.Errorf("payload hash mismatch")
+			}
+			objects[r.URL.Path] = string(body)
+		case http.MethodGet:
+			body, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = io.WriteString(w, body)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	store, err := NewS3BlobStore(S3Config{Endpoint: srv.URL + "/", Bucket: "media", Region: "eu-west-1", AccessKeyID: "AK", SecretAccessKey: "SK"})
+	if err != nil {
+		t.Fatalf("NewS3BlobStore: %v", err)
+	}
+	store.now = func() time.Time { return time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC) }
+
+	ctx := context.Background()
+	if err := store.Put(ctx, "t1/abc", "application/pdf", []byte("%PDF")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if objects["/media/t1/abc"] != "%PDF" {
+		t.Fatalf("objects = %v", objects)
+	}
+	if data, err := store.Get(ctx, "t1/abc"); err != nil || string(data) != "%PDF" {
+		t.Fatalf("Get = %q, %v", data, err)
+	}
+	if _, err := store.Get(ctx, "t1/missing"); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("missing object err = %v", err)
+	}
+	if err := store.Delete(ctx, "t1/abc"); err != nil || len(objects) != 0 {
+		t.Fatalf("Delete err = %v objects = %v", err, objects)
+	}
+
+	if _, err := NewS3BlobStore(S3Config{Endpoint: srv.URL}); err == nil {
+		t.Fatalf("expected incomplete config to be rejected")
+	}
+}
+
+func TestIngestTelegram(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	blobs := NewLocalBlobStore(t.TempDir())
+	svc := NewService(db, blobs)
+	svc.HTTPClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/botTOKEN/getFile":
+			if req.URL.Query().Get("file_id") != "F1" {
+				t.Errorf("file_id = %q", req.URL.Query().Get("file_id"))
+			}
+			return reply(http.StatusOK, `{"ok":true,"result":{"file_path":"documents/file_7.txt","file_size":11}}`), nil
+		case "/file/botTOKEN/documents/file_7.txt":
+			return reply(http.StatusOK, "hello world"), nil
+		}
+		return reply(http.StatusNotFound, ""), nil
+	})}
+	mock.ExpectExec("INSERT INTO message_media").
+		WithArgs(sqlmock.AnyArg(), "t1", "telegram", "document", "F1", "file_7.txt", "text/plain", int64(11), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	ref, err := svc.IngestTelegram(context.Background(), "t1", "TOKEN", Attachment{Channel: "telegram", Kind: "document", ProviderFileID: "F1"})
+	if err != nil {
+		t.Fatalf("IngestTelegram: %v", err)
+	}
+	if ref.ID == "" || ref.FileName != "file_7.txt" || ref.ContentType != "text/plain" || ref.Size != 11 {
+		t.Fatalf("ref = %+v", ref)
+	}
+	if data, err := blobs.Get(context.Background(), "t1/"+ref.ID); err != nil || string(data) != "hello world" {
+		t.Fatalf("blob = %q, %v", data, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestIngestWhatsAppSizeCap(t *testing.T) {
+	t.Parallel()
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	svc := NewService(db, NewLocalBlobStore(t.TempDir()))
+	svc.maxBytes = 4
+	var auth []string
+	svc.HTTPClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		auth = append(auth, req.Header.Get("Authorization"))
+		if req.URL.Host == "graph.facebook.com" {
+			if req.URL.Path != "/v21.0/M1" {
+				t.Errorf("lookup path = %s", req.URL.Path)
+			}
+			return reply(http.StatusOK, `{"url":"https://lookaside.fbsbx.com/whatsapp/M1","mime_type":"application/pdf"}`), nil
+		}
+		return reply(http.StatusOK, "%PDF-1.4 too big"), nil
+	})}
+
+	_, err = svc.IngestWhatsApp(context.Background(), "t1", "tok", "v21.0", Attachment{Channel: "whatsapp", Kind: "document", ProviderFileID: "M1"})
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("err = %v, want ErrTooLarge", err)
+	}
+	if len(auth) != 2 || auth[0] != "Bearer tok" || auth[1] != "Bearer tok" {
+		t.Fatalf("authorization headers = %v", auth)
+	}
+}
+
+func TestReadMedia(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	blobs := NewLocalBlobStore(t.TempDir())
+	const id = "7d2c4a3e-0d1b-4c6e-9a55-3f1c2b8e4d10"
+	if err := blobs.Put(context.Background(), "t1/"+id, "text/csv", []byte("a,b\n1,2\n")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	svc := NewService(db, blobs)
+	mock.ExpectQuery("FROM message_media").WithArgs(id, "t1").
+		WillReturnRows(sqlmock.NewRows([]string{"file_name", "content_type", "size_bytes", "blob_key"}).AddRow("data.csv", "text/csv", 8, "t1/"+id))
+	mock.ExpectQuery("FROM message_media").WithArgs(id, "t2").WillReturnRows(sqlmock.NewRows([]string{"file_name", "content_type", "size_bytes", "blob_key"}))
+
+	file, err := svc.ReadMedia(context.Background(), "t1", id)
+	if err != nil {
+		t.Fatalf("ReadMedia: %v", err)
+	}
+	if file.Name != "data.csv" || file.Size != 8 || file.Text != "a,b\n1,2" {
+		t.Fatalf("file = %+v", file)
+	}
+	if _, err := svc.ReadMedia(context.Background(), "t2", id); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("other tenant err = %v", err)
+	}
+	if _, err := svc.ReadMedia(context.Background(), "t1", "not-a-uuid"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("invalid id err = %v", err)
+	}
+}
+
+func TestRefsMetadataRoundTrip(t *testing.T) {
+	t.Parallel()
+	refs := []Ref{{ID: "m1", Kind: "document", FileName: "report.pdf", ContentType: "application/pdf", Size: 2048}}
+	got := RefsFromMetadata(map[string]string{MetadataKey: EncodeRefs(refs)})
+	if len(got) != 1 || got[0] != refs[0] {
+		t.Fatalf("refs = %+v", got)
+	}
+	if RefsFromMetadata(map[string]string{}) != nil || RefsFromMetadata(map[string]string{MetadataKey: "{"}) != nil {
+		t.Fatalf("expected nil refs")
+	}
+	if d := refs[0].Describe(); !strings.Contains(d, `"report.pdf"`) || !strings.Contains(d, "2 KB") || !strings.Contains(d, "media_id=m1") {
+		t.Fatalf("Describe = %q", d)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -28,7 +29,8 @@ type AdminIdentity struct {
 	IsAdmin bool   `json:"is_admin"`
 }
 
-// ApplyAdmin enforces admin-only access on /api/admin/* routes.
+// ApplyAdmin enforces admin-only access on /api/admin/* routes and the
+// admin-only tenant routes listed in adminTenantRoutes.
 func ApplyAdmin(next http.Handler) http.Handler {
 	jwtSecret := strings.TrimSpace(os.Getenv("API_JWT_SECRET"))
 
@@ -72,8 +74,20 @@ func AdminFromContext(ctx context.Context) (AdminIdentity, bool) {
 	return identity, ok
 }
 
+// adminTenantRoutes are /api/tenants/{id}/... suffixes that only platform
+// admins may call.
+var adminTenantRoutes = []string{"container/snapshot"}
+
 func isAdminPath(path string) bool {
-	return path == "/api/admin" || strings.HasPrefix(path, "/api/admin/")
+	if path == "/api/admin" || strings.HasPrefix(path, "/api/admin/") {
+		return true
+	}
+	rest, ok := strings.CutPrefix(path, "/api/tenants/")
+	if !ok {
+		return false
+	}
+	_, route, ok := strings.Cut(strings.TrimSuffix(rest, "/"), "/")
+	return ok && slices.Contains(adminTenantRoutes, route)
 }
 
 func bearerToken(header string) string {
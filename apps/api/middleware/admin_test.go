@@ -24,6 +24,8 @@ func TestApplyAdmin(t *testing.T) {
 		{name: "valid admin role", path: "/api/admin/tenants", secret: "s", token: signedToken(t, "s", jwt.MapClaims{"sub": "1", "email": "a@b.com", "role": "admin"}), wantStatus: 200, wantNext: true},
 		{name: "non admin blocked", path: "/api/admin/tenants", secret: "s", token: signedToken(t, "s", jwt.MapClaims{"sub": "1", "email": "u@b.com", "role": "member"}), wantStatus: 403},
 		{name: "allowlist email", path: "/api/admin/tenants", secret: "s", token: signedToken(t, "s", jwt.MapClaims{"sub": "1", "email": "michal.szalinski@gmail.com"}), wantStatus: 200, wantNext: true},
+		{name: "admin tenant route requires admin", path: "/api/tenants/t1/container/snapshot", secret: "s", token: signedToken(t, "s", jwt.MapClaims{"sub": "1", "email": "u@b.com", "role": "member"}), wantStatus: 403},
+		{name: "other tenant routes bypassed", path: "/api/tenants/t1/container/status", secret: "s", wantStatus: 200, wantNext: true},
 		{name: "missing secret config", path: "/api/admin/tenants", secret: "", token: "abc", wantStatus: 500},
 	}
 
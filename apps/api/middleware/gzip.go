@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// gzipMinSizeBytes is the smallest response body worth compressing; below
+// it the gzip header and trailer outweigh the savings.
+const gzipMinSizeBytes = 1024
+
+var gzipWriterPool = sync.Pool{
+	New: func() any {
+		gz, _ := gzip.NewWriterLevel(nil, gzip.BestSpeed)
+		return gz
+	},
+}
+
+// ApplyGzip compresses responses for callers that accept gzip. Bodies under
+// gzipMinSizeBytes, server-sent event streams, responses that already carry a
+// Content-Encoding and websocket upgrades are sent as-is.
+func ApplyGzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r.Header.Get("Accept-Encoding")) || r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		return q > 0
+	}
+	return false
+}
+
+// gzipResponseWriter holds back the start of a response until it knows
+// whether to compress it: once gzipMinSizeBytes are written, or the handler
+// flushes or returns.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	decided     bool
+	gz          *gzip.Writer
+	buf         []byte
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.wroteHeader {
+		return
+	}
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		g.ResponseWriter.WriteHeader(status)
+		return
+	}
+	g.wroteHeader = true
+	g.status = status
+	if !g.compressible() {
+		g.passthrough()
+	}
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !g.wroteHeader {
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.decided {
+		if g.gz != nil {
+			return g.gz.Write(p)
+		}
+		return g.ResponseWriter.Write(p)
+	}
+	g.buf = append(g.buf, p...)
+	if len(g.buf) >= gzipMinSizeBytes {
+		if err := g.startGzip(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends what has been written so far. A streaming handler that flushes
+// before reaching gzipMinSizeBytes gets an uncompressed response.
+func (g *gzipResponseWriter) Flush() {
+	if !g.wroteHeader {
+		g.WriteHeader(http.StatusOK)
+	}
+	if !g.decided {
+		g.passthrough()
+	}
+	if g.gz != nil {
+		_ = g.gz.Flush()
+	}
+	if flusher, ok := g.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets a handler take over the connection before anything was sent.
+func (g *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := g.ResponseWriter.(http.Hijacker)
+	if !ok || g.decided {
+		return nil, nil, errors.New("gzip: connection cannot be hijacked")
+	}
+	g.decided = true
+	return hijacker.Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// compressible reports whether the response may be gzipped at all, based on
+// its status and the headers the handler set.
+func (g *gzipResponseWriter) compressible() bool {
+	if g.status == http.StatusNoContent || g.status == http.StatusNotModified || g.status == http.StatusSwitchingProtocols {
+		return false
+	}
+	header := g.ResponseWriter.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	return mediaType != "text/event-stream"
+}
+
+func (g *gzipResponseWriter) passthrough() {
+	g.decided = true
+	g.ResponseWriter.WriteHeader(g.status)
+	if len(g.buf) > 0 {
+		_, _ = g.ResponseWriter.Write(g.buf)
+		g.buf = nil
+	}
+}
+
+func (g *gzipResponseWriter) startGzip() error {
+	g.decided = true
+	header := g.ResponseWriter.Header()
+	if header.Get("Content-Type") == "" {
+		// Sniff the plain body; net/http would otherwise sniff the gzip bytes.
+		header.Set("Content-Type", http.DetectContentType(g.buf))
+	}
+	header.Set("Content-Encoding", "gzip")
+	header.Del("Content-Length")
+	g.ResponseWriter.WriteHeader(g.status)
+
+	g.gz = gzipWriterPool.Get().(*gzip.Writer)
+	g.gz.Reset(g.ResponseWriter)
+	_, err := g.gz.Write(g.buf)
+	g.buf = nil
+	return err
+}
+
+// close finishes the response once the handler returns.
+func (g *gzipResponseWriter) close() {
+	if !g.decided {
+		if !g.wroteHeader && len(g.buf) == 0 {
+			return
+		}
+		g.passthrough()
+	}
+	if g.gz != nil {
+		_ = g.gz.Close()
+		gzipWriterPool.Put(g.gz)
+		g.gz = nil
+	}
+}
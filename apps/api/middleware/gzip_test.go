@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// adminTenantList builds a body shaped like GET /api/admin/tenants for n
+// tenants.
+func adminTenantList(n int) []byte {
+	tenants := make([]map[string]any, 0, n)
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range n {
+		tenants = append(tenants, map[string]any{
+			"id":                    fmt.Sprintf("8f2c6a1e-0000-4000-8000-%012d", i),
+			"user_id":               fmt.Sprintf("3b9d7e44-0000-4000-8000-%012d", i),
+			"email":                 fmt.Sprintf("owner%d@example.com", i),
+			"status":                "active",
+			"container_id":          fmt.Sprintf("%064x", i*7919),
+			"container":             map[string]any{"status": "running", "health": "healthy"},
+			"credits_balance_cents": 1000 + i%97,
+			"usage": map[string]any{
+				"total_input_tokens":  i * 1311,
+				"total_output_tokens": i * 417,
+				"total_tokens":        i * 1728,
+				"total_revenue_cents": i * 3,
+				"tokens_24h":          i % 500,
+			},
+			"created_at": created.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	body, _ := json.Marshal(map[string]any{"tenants": tenants})
+	return body
+}
+
+func serveJSON(body []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+		_, _ = w.Write(body)
+	})
+}
+
+func TestApplyGzipCompressesLargeJSON(t *testing.T) {
+	t.Parallel()
+	body := adminTenantList(1000)
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/tenants", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip;q=0.8")
+	w := httptest.NewRecorder()
+	ApplyGzip(serveJSON(body)).ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Content-Length") != "" || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("headers = %v", w.Header())
+	}
+	if ratio := float64(len(body)) / float64(w.Body.Len()); ratio < 3 {
+		t.Fatalf("compression ratio = %.1fx (%d -> %d bytes)", ratio, len(body), w.Body.Len())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	plain, err := io.ReadAll(zr)
+	if err != nil || !bytes.Equal(plain, body) {
+		t.Fatalf("decompressed body differs (err %v)", err)
+	}
+}
+
+func TestApplyGzipSkips(t *testing.T) {
+	t.Parallel()
+	large := bytes.Repeat([]byte("a"), 4*gzipMinSizeBytes)
+	sse := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write(large)
+	})
+	encoded := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		_, _ = w.Write(large)
+	})
+	tests := []struct {
+		name           string
+		handler        http.Handler
+		acceptEncoding string
+	}{
+		{name: "small body", handler: serveJSON([]byte(`{"ok":true}`)), acceptEncoding: "gzip"},
+		{name: "no gzip accepted", handler: serveJSON(large), acceptEncoding: "br"},
+		{name: "gzip refused", handler: serveJSON(large), acceptEncoding: "gzip;q=0"},
+		{name: "event stream", handler: sse, acceptEncoding: "gzip"},
+		{name: "already encoded", handler: encoded, acceptEncoding: "gzip"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/x", nil)
+		req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+		w := httptest.NewRecorder()
+		ApplyGzip(tt.handler).ServeHTTP(w, req)
+		if w.Header().Get("Content-Encoding") == "gzip" || strings.HasPrefix(w.Body.String(), "\x1f\x8b") {
+			t.Fatalf("%s: response was gzipped", tt.name)
+		}
+		if w.Body.Len() == 0 {
+			t.Fatalf("%s: empty body", tt.name)
+		}
+	}
+}
+
+func TestApplyGzipKeepsStatusAndFlushes(t *testing.T) {
+	t.Parallel()
+	req := httptest.NewRequest(http.MethodPost, "/api/x", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	ApplyGzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte(" second"))
+	})).ServeHTTP(w, req)
+	if w.Code != http.StatusCreated || !w.Flushed || w.Body.String() != "first second" || w.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("status = %d flushed = %v body = %q headers = %v", w.Code, w.Flushed, w.Body.String(), w.Header())
+	}
+}
+
+// BenchmarkAdminTenantListGzip reports the size of a 1000-tenant admin list
+// with and without compression.
+func BenchmarkAdminTenantListGzip(b *testing.B) {
+	body := adminTenantList(1000)
+	for _, encoding := range []string{"identity", "gzip"} {
+		b.Run(encoding, func(b *testing.B) {
+			handler := ApplyGzip(serveJSON(body))
+			var size int
+			for b.Loop() {
+				req := httptest.NewRequest(http.MethodGet, "/api/admin/tenants", nil)
+				req.Header.Set("Accept-Encoding", encoding)
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+				size = w.Body.Len()
+			}
+			b.ReportMetric(float64(size), "bytes/resp")
+			b.ReportMetric(float64(len(body))/float64(size), "ratio")
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// ImpersonatorClaim names the admin a support token was issued to.
+	ImpersonatorClaim = "impersonator"
+	// ImpersonatedByHeader is set on every response to an impersonated
+	// request so the dashboard can show a banner.
+	ImpersonatedByHeader = "X-Impersonated-By"
+	// ImpersonationTTL is how long an impersonation token is valid.
+	ImpersonationTTL = 15 * time.Minute
+)
+
+const impersonationContextKey adminContextKey = "impersonation"
+
+// Impersonation identifies an admin acting as a tenant.
+type Impersonation struct {
+	AdminID  string
+	TenantID string
+}
+
+// IssueImpersonationToken signs a token that authenticates as tenantID on
+// behalf of adminID, using the same secret as regular API tokens.
+func IssueImpersonationToken(secret, adminID, tenantID string, now time.Time) (string, time.Time, error) {
+	expiresAt := now.Add(ImpersonationTTL)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":             adminID,
+		"tenant_id":       tenantID,
+		ImpersonatorClaim: adminID,
+		"iat":             now.Unix(),
+		"exp":             expiresAt.Unix(),
+	})
+	signed, err := token.SignedString([]byte(secret))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// impersonationWrites are the only writes an impersonation token may make:
+// handlers that store the impersonating admin with what they write (see
+// routes.withImpersonator). Other writes are refused so nothing is changed on
+// a tenant's behalf without recording who did it.
+var impersonationWrites = map[string]bool{
+	"POST /api/channels/inbound": true,
+}
+
+// ApplyImpersonation marks requests carrying an impersonation token. They are
+// confined to the token's tenant, kept off admin routes, limited to reads and
+// impersonationWrites, tagged with X-Impersonated-By and recorded in
+// admin_audit_log. Other requests pass through untouched.
+func ApplyImpersonation(db *sql.DB) func(http.Handler) http.Handler {
+	jwtSecret := strings.TrimSpace(os.Getenv("API_JWT_SECRET"))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString := bearerToken(r.Header.Get("Authorization"))
+			if jwtSecret == "" || tokenString == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			claims, err := parseJWTClaims(tokenString, jwtSecret)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			impersonation := Impersonation{
+				AdminID:  strings.TrimSpace(firstStringClaim(claims, ImpersonatorClaim)),
+				TenantID: strings.TrimSpace(firstStringClaim(claims, "tenant_id")),
+			}
+			if impersonation.AdminID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if impersonation.TenantID == "" {
+				writeError(w, http.StatusForbidden, "forbidden")
+				return
+			}
+			if isAdminPath(r.URL.Path) {
+				writeError(w, http.StatusForbidden, "impersonation tokens cannot call admin endpoints")
+				return
+			}
+			if !impersonationInScope(r, impersonation.TenantID) {
+				writeError(w, http.StatusForbidden, "impersonation token is scoped to another tenant")
+				return
+			}
+			if !impersonationMethodAllowed(r) {
+				writeError(w, http.StatusForbidden, "impersonation tokens are read-only apart from sending messages")
+				return
+			}
+
+			r.Header.Set("X-Tenant-ID", impersonation.TenantID)
+			w.Header().Set(ImpersonatedByHeader, impersonation.AdminID)
+			auditImpersonatedRequest(r.Context(), db, impersonation, r)
+
+			ctx := context.WithValue(r.Context(), impersonationContextKey, impersonation)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ImpersonationFromContext returns the impersonation injected by
+// ApplyImpersonation.
+func ImpersonationFromContext(ctx context.Context) (Impersonation, bool) {
+	impersonation, ok := ctx.Value(impersonationContextKey).(Impersonation)
+	return impersonation, ok
+}
+
+func impersonationMethodAllowed(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return impersonationWrites[r.Method+" "+r.URL.Path]
+}
+
+// impersonationInScope rejects requests that name a different tenant in the
+// X-Tenant-ID header, the tenant_id query parameter or a /api/tenants/{id}
+// path.
+func impersonationInScope(r *http.Request, tenantID string) bool {
+	if header := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); header != "" && header != tenantID {
+		return false
+	}
+	if query := strings.TrimSpace(r.URL.Query().Get("tenant_id")); query != "" && query != tenantID {
+		return false
+	}
+	if rest, ok := strings.CutPrefix(r.URL.Path, "/api/tenants/"); ok {
+		pathTenant, _, _ := strings.Cut(rest, "/")
+		if pathTenant != tenantID {
+			return false
+		}
+	}
+	return true
+}
+
+func auditImpersonatedRequest(ctx context.Context, db *sql.DB, impersonation Impersonation, r *http.Request) {
+	if db == nil {
+		return
+	}
+	details, _ := json.Marshal(map[string]string{
+		"method":    r.Method,
+		"path":      r.URL.Path,
+		"tenant_id": impersonation.TenantID,
+	})
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO admin_audit_log (admin_id, action, target_id, details)
+		VALUES ($1, $2, $3, $4::jsonb)
+	`, impersonation.AdminID, "admin.impersonation.request", impersonation.TenantID, string(details)); err != nil {
+		slog.Error("failed to audit impersonated request", "admin_id", impersonation.AdminID, "tenant_id", impersonation.TenantID, "path", r.URL.Path, "err", err)
+	}
+}
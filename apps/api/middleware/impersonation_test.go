@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestApplyImpersonation(t *testing.T) {
+	t.Setenv("API_JWT_SECRET", "s")
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	token, expiresAt, err := IssueImpersonationToken("s", "admin-1", "t1", time.Now())
+	if err != nil {
+		t.Fatalf("IssueImpersonationToken: %v", err)
+	}
+	if time.Until(expiresAt) > ImpersonationTTL {
+		t.Fatalf("expires_at = %s", expiresAt)
+	}
+
+	var got Impersonation
+	var gotTenantHeader string
+	h := ApplyImpersonation(db)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = ImpersonationFromContext(r.Context())
+		gotTenantHeader = r.Header.Get("X-Tenant-ID")
+	}))
+
+	mock.ExpectExec("INSERT INTO admin_audit_log").
+		WithArgs("admin-1", "admin.impersonation.request", "t1", `{"method":"GET","path":"/api/tenants/t1/hands/h1/history","tenant_id":"t1"}`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	req := httptest.NewRequest(http.MethodGet, "/api/tenants/t1/hands/h1/history", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get(ImpersonatedByHeader) != "admin-1" {
+		t.Fatalf("status = %d headers = %v", w.Code, w.Header())
+	}
+	if got != (Impersonation{AdminID: "admin-1", TenantID: "t1"}) || gotTenantHeader != "t1" {
+		t.Fatalf("impersonation = %+v tenant header = %q", got, gotTenantHeader)
+	}
+
+	for _, path := range []string{"/api/admin/tenants", "/api/tenants/t2/models", "/api/hands/events?tenant_id=t2"} {
+		req = httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("%s: status = %d", path, w.Code)
+		}
+	}
+
+	for _, path := range []string{"/api/tenants/t1/hands/h1/invoke", "/api/tenants/t1/swarm/run", "/api/channels/inbound/batch"} {
+		req = httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("POST %s: status = %d", path, w.Code)
+		}
+	}
+	mock.ExpectExec("INSERT INTO admin_audit_log").
+		WithArgs("admin-1", "admin.impersonation.request", "t1", `{"method":"POST","path":"/api/channels/inbound","tenant_id":"t1"}`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	req = httptest.NewRequest(http.MethodPost, "/api/channels/inbound", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/channels/inbound: status = %d", w.Code)
+	}
+
+	got = Impersonation{}
+	req = httptest.NewRequest(http.MethodGet, "/api/tenants/t2/models", nil)
+	req.Header.Set("Authorization", "Bearer "+signedToken(t, "s", jwt.MapClaims{"sub": "u1", "tenant_id": "t2"}))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get(ImpersonatedByHeader) != "" || got.AdminID != "" {
+		t.Fatalf("regular token: status = %d impersonation = %+v", w.Code, got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the id that correlates a request's logs across the
+// gateway, this API and the agent runs it starts.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds caller-supplied ids; longer ones are replaced.
+const maxRequestIDLength = 128
+
+type requestIDContextKey struct{}
+
+// ApplyRequestID gives every request an id: the caller's X-Request-ID when
+// it is usable, otherwise a new UUID. The id is stored in the request
+// context, where RequestIDLogHandler adds it to log records, and echoed in
+// the X-Request-ID response header.
+func ApplyRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSpace(r.Header.Get(RequestIDHeader))
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID accepts ids of printable ASCII without spaces, so a caller
+// cannot inject log or header syntax.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// WithRequestID returns ctx carrying the request id, for work that outlives
+// the request, such as swarm runs, to keep logging under it.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestIDFromContext returns the request id in ctx, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// RequestIDLogHandler adds a request_id attribute to records logged with a
+// context that carries one (slog.InfoContext and friends).
+type RequestIDLogHandler struct {
+	next slog.Handler
+}
+
+func NewRequestIDLogHandler(next slog.Handler) *RequestIDLogHandler {
+	return &RequestIDLogHandler{next: next}
+}
+
+func (h *RequestIDLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RequestIDLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestIDFromContext(ctx); id != "" {
+		r = r.Clone()
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *RequestIDLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &RequestIDLogHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *RequestIDLogHandler) WithGroup(name string) slog.Handler {
+	return &RequestIDLogHandler{next: h.next.WithGroup(name)}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestApplyRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewRequestIDLogHandler(slog.NewTextHandler(&buf, nil))).With("component", "test")
+	var seen string
+	handler := ApplyRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+		logger.InfoContext(r.Context(), "handled")
+	}))
+	serve := func(header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/tenants", nil)
+		if header != "" {
+			req.Header.Set(RequestIDHeader, header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("gw-7f3a9c")
+	if seen != "gw-7f3a9c" || w.Header().Get(RequestIDHeader) != "gw-7f3a9c" {
+		t.Fatalf("passthrough: context=%q header=%q", seen, w.Header().Get(RequestIDHeader))
+	}
+	if line := buf.String(); !strings.Contains(line, "component=test") || !strings.Contains(line, "request_id=gw-7f3a9c") {
+		t.Fatalf("log line = %q", line)
+	}
+
+	for _, header := range []string{"", "bad id", strings.Repeat("x", maxRequestIDLength+1)} {
+		w := serve(header)
+		if _, err := uuid.Parse(seen); err != nil || w.Header().Get(RequestIDHeader) != seen {
+			t.Fatalf("header %q: context=%q response=%q", header, seen, w.Header().Get(RequestIDHeader))
+		}
+	}
+
+	buf.Reset()
+	logger.Info("outside a request")
+	if strings.Contains(buf.String(), "request_id") {
+		t.Fatalf("log line without request = %q", buf.String())
+	}
+}
@@ -0,0 +1,188 @@
+package openapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Version is the OpenAPI version of generated documents.
+const Version = "3.1.0"
+
+// Security scheme names. Protected routes accept either.
+const (
+	ServiceKeyScheme = "serviceKey"
+	BearerScheme     = "bearerAuth"
+)
+
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []SecurityRequirement `json:"security"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lowercase HTTP methods to operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	// Security points to an empty list for public operations, which
+	// overrides the document's requirement.
+	Security *[]SecurityRequirement `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"` // path, query or header
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+// SecurityRequirement maps scheme names to required scopes.
+type SecurityRequirement map[string][]string
+
+// Document generates the OpenAPI document for the registered routes.
+func (reg *Registry) Document(info Info) *Document {
+	b := newSchemaBuilder()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: b.components,
+			SecuritySchemes: map[string]*SecurityScheme{
+				ServiceKeyScheme: {Type: "apiKey", In: "header", Name: "X-Service-API-Key"},
+				BearerScheme:     {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+		Security: []SecurityRequirement{{ServiceKeyScheme: {}}, {BearerScheme: {}}},
+	}
+	for _, route := range reg.Routes() {
+		path := specPath(route.Path)
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = b.operation(route, path)
+	}
+	return doc
+}
+
+func (b *schemaBuilder) operation(route Route, path string) *Operation {
+	op := &Operation{
+		OperationID: operationID(route.Method, path),
+		Summary:     route.Summary,
+		Tags:        route.Tags,
+		Responses:   make(map[string]*Response),
+	}
+	if route.Public {
+		op.Security = &[]SecurityRequirement{}
+	}
+	for _, name := range pathParams(path) {
+		op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, name := range route.Query {
+		op.Parameters = append(op.Parameters, Parameter{Name: name, In: "query", Schema: &Schema{Type: "string"}})
+	}
+	for _, name := range route.Headers {
+		op.Parameters = append(op.Parameters, Parameter{Name: name, In: "header", Schema: &Schema{Type: "string"}})
+	}
+	if route.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: b.valueSchema(route.Request)}},
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	if status != http.StatusNoContent {
+		contentType := route.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		schema := b.valueSchema(route.Response)
+		if schema == nil && contentType == "application/json" {
+			schema = &Schema{Type: "object"}
+		}
+		success.Content = map[string]*MediaType{contentType: {Schema: schema}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+	op.Responses["default"] = &Response{Description: "Error"}
+	return op
+}
+
+// specPath converts a ServeMux pattern path to an OpenAPI path template:
+// {name...} becomes {name} and a trailing {$} is dropped.
+func specPath(pattern string) string {
+	pattern = strings.TrimSuffix(pattern, "{$}")
+	return strings.ReplaceAll(pattern, "...}", "}")
+}
+
+func pathParams(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, segment[1:len(segment)-1])
+		}
+	}
+	return names
+}
+
+// operationID derives a stable id from the method and path, such as
+// post_api_tenants_id_swarm_run.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		b.WriteByte('_')
+		b.WriteString(part)
+	}
+	return b.String()
+}
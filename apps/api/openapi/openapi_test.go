@@ -0,0 +1,152 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testBase struct {
+	ID string `json:"id"`
+}
+
+type testNode struct {
+	testBase
+	Name     string            `json:"name"`
+	Parent   *testNode         `json:"parent"`
+	Children []testNode        `json:"children,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Seen     *time.Time        `json:"seen"`
+	Raw      json.RawMessage   `json:"raw,omitempty"`
+	Skipped  string            `json:"-"`
+	internal string
+}
+
+type testCreate struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count,omitempty"`
+}
+
+func testRegistry() (*Registry, *http.ServeMux) {
+	reg := NewRegistry()
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+	reg.HandleFunc(mux, Route{Method: "post", Path: "/api/tenants/{id}/nodes", Summary: "Create a node", Tags: []string{"Nodes"},
+		Request: testCreate{}, Response: testNode{}, Status: http.StatusCreated}, ok)
+	reg.HandleFunc(mux, Route{Method: http.MethodGet, Path: "/api/nodes", Query: []string{"tenant_id"}, Headers: []string{"X-Tenant-ID"},
+		Response: struct {
+			Nodes []testNode `json:"nodes"`
+		}{}}, ok)
+	reg.HandleFunc(mux, Route{Method: http.MethodDelete, Path: "/api/nodes/{id}", Status: http.StatusNoContent}, ok)
+	reg.HandleFunc(mux, Route{Method: http.MethodGet, Path: "/api/nodes/{id}/events", ContentType: "text/event-stream"}, ok)
+	reg.HandleFunc(mux, Route{Method: http.MethodPost, Path: "/api/hooks/{rest...}", Public: true}, ok)
+	return reg, mux
+}
+
+func TestDocumentIsValidOpenAPI(t *testing.T) {
+	t.Parallel()
+	reg, mux := testRegistry()
+	// Mounting the same route again replaces its description.
+	reg.Add(Route{Method: http.MethodDelete, Path: "/api/nodes/{id}", Summary: "Delete a node", Status: http.StatusNoContent})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/nodes", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("registered handler status = %d", w.Code)
+	}
+
+	raw, err := json.Marshal(reg.Document(Info{Title: "Test API", Version: "1.0.0"}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if err := Validate(raw); err != nil {
+		t.Fatalf("invalid document: %v\n%s", err, raw)
+	}
+
+	var doc Document
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(doc.Paths) != 5 {
+		t.Fatalf("paths = %d, want 5", len(doc.Paths))
+	}
+	create := doc.Paths["/api/tenants/{id}/nodes"]["post"]
+	if create == nil || create.OperationID != "post_api_tenants_id_nodes" || create.Responses["201"] == nil {
+		t.Fatalf("create operation = %+v", create)
+	}
+	if got := create.RequestBody.Content["application/json"].Schema.Ref; got != "#/components/schemas/testCreate" {
+		t.Fatalf("request ref = %q", got)
+	}
+	if del := doc.Paths["/api/nodes/{id}"]["delete"]; del.Summary != "Delete a node" || del.Responses["204"].Content != nil {
+		t.Fatalf("delete operation = %+v", del)
+	}
+	if hook := doc.Paths["/api/hooks/{rest}"]["post"]; hook == nil || hook.Security == nil || len(*hook.Security) != 0 {
+		t.Fatalf("public operation = %+v", hook)
+	}
+
+	node := doc.Components.Schemas["testNode"]
+	if node == nil {
+		t.Fatalf("components = %v", doc.Components.Schemas)
+	}
+	for _, name := range []string{"id", "name", "parent", "children", "labels", "seen", "raw"} {
+		if node.Properties[name] == nil {
+			t.Fatalf("testNode lacks %q: %+v", name, node.Properties)
+		}
+	}
+	if len(node.Properties) != 7 {
+		t.Fatalf("testNode properties = %v", node.Properties)
+	}
+	if parent := node.Properties["parent"]; len(parent.OneOf) != 2 || parent.OneOf[0].Ref != "#/components/schemas/testNode" {
+		t.Fatalf("parent = %+v", parent)
+	}
+	if seen := node.Properties["seen"]; seen.Format != "date-time" || len(seen.Type.([]any)) != 2 {
+		t.Fatalf("seen = %+v", seen)
+	}
+	if labels := node.Properties["labels"]; labels.AdditionalProperties == nil || labels.AdditionalProperties.Type != "string" {
+		t.Fatalf("labels = %+v", labels)
+	}
+}
+
+func TestValidateReportsProblems(t *testing.T) {
+	t.Parallel()
+	raw := []byte(`{
+		"openapi": "3.0.3",
+		"info": {"title": "x"},
+		"paths": {
+			"/a/{id}": {"get": {"operationId": "op", "responses": {"200": {}}}},
+			"/b": {"get": {"operationId": "op", "security": [{"nope": []}],
+				"responses": {"ok": {"description": "d", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Missing"}}}}}}}
+		},
+		"components": {"schemas": {}, "securitySchemes": {}}
+	}`)
+	err := Validate(raw)
+	if err == nil {
+		t.Fatal("expected problems")
+	}
+	for _, want := range []string{
+		"is not an OpenAPI 3.1 version",
+		"version is required",
+		`path template parameter "id" is not declared`,
+		"description is required",
+		`operationId "op" is also used`,
+		`security scheme "nope" is not defined`,
+		"invalid response code",
+		"does not resolve",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in: %v", want, err)
+		}
+	}
+}
+
+func TestDocsHandler(t *testing.T) {
+	t.Parallel()
+	w := httptest.NewRecorder()
+	DocsHandler("Test API", "/api/openapi.json")(w, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	if !strings.Contains(w.Body.String(), `url: "/api/openapi.json"`) || !strings.Contains(w.Body.String(), "<title>Test API</title>") {
+		t.Fatalf("unexpected page: %s", w.Body.String())
+	}
+}
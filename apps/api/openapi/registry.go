@@ -0,0 +1,96 @@
+// Package openapi records the API's routes as they are registered on the mux
+// and generates an OpenAPI 3.1 document from them, so the published spec
+// cannot drift from the routes the server actually serves.
+package openapi
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Route describes one endpoint. Request and Response are example values of
+// the body types, usually zero values; their JSON schemas are derived from
+// the Go types through reflection.
+type Route struct {
+	Method string
+	// Path is the ServeMux pattern path, such as /api/tenants/{id}/swarm/run.
+	Path    string
+	Summary string
+	Tags    []string
+	// Query and Headers name the optional query parameters and request
+	// headers the handler reads.
+	Query   []string
+	Headers []string
+	// Request is the JSON request body, or nil when the endpoint takes none.
+	Request any
+	// Response is the success response body. Nil documents a JSON object of
+	// unspecified shape, or no body when Status is 204.
+	Response any
+	// Status is the success status code, http.StatusOK when zero.
+	Status int
+	// ContentType is the success response's media type, application/json
+	// when empty.
+	ContentType string
+	// Public routes need no API key or bearer token.
+	Public bool
+}
+
+func (r Route) key() string {
+	return r.Method + " " + r.Path
+}
+
+// Registry collects described routes. Registering the same method and path
+// again replaces the earlier description, so mounting a handler twice (as
+// tests do) does not duplicate operations.
+type Registry struct {
+	mu     sync.Mutex
+	routes map[string]Route
+}
+
+func NewRegistry() *Registry {
+	return &Registry{routes: make(map[string]Route)}
+}
+
+// DefaultRegistry holds the routes registered with the package-level
+// HandleFunc.
+var DefaultRegistry = NewRegistry()
+
+// HandleFunc registers handler on mux for the route's method and path and
+// records the route's description.
+func (reg *Registry) HandleFunc(mux *http.ServeMux, route Route, handler http.HandlerFunc) {
+	route.Method = strings.ToUpper(strings.TrimSpace(route.Method))
+	mux.HandleFunc(route.key(), handler)
+	reg.Add(route)
+}
+
+// Add records a route without registering a handler.
+func (reg *Registry) Add(route Route) {
+	route.Method = strings.ToUpper(strings.TrimSpace(route.Method))
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.routes[route.key()] = route
+}
+
+// Routes returns the recorded routes ordered by path, then method.
+func (reg *Registry) Routes() []Route {
+	reg.mu.Lock()
+	list := make([]Route, 0, len(reg.routes))
+	for _, route := range reg.routes {
+		list = append(list, route)
+	}
+	reg.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Path != list[j].Path {
+			return list[i].Path < list[j].Path
+		}
+		return list[i].Method < list[j].Method
+	})
+	return list
+}
+
+// HandleFunc registers handler on mux and records route in DefaultRegistry.
+func HandleFunc(mux *http.ServeMux, route Route, handler http.HandlerFunc) {
+	DefaultRegistry.HandleFunc(mux, route, handler)
+}
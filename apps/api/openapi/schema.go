@@ -0,0 +1,166 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of a JSON Schema (draft 2020-12, as used by OpenAPI
+// 3.1) that Go types map to.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"` // a type name, or a list of them for nullable values
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+	componentChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// schemaBuilder turns Go types into schemas. Named struct types become
+// components referenced with $ref; anonymous structs are inlined.
+type schemaBuilder struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{components: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+}
+
+// valueSchema returns the schema of v's type, or nil for a nil v.
+func (b *schemaBuilder) valueSchema(v any) *Schema {
+	if v == nil {
+		return nil
+	}
+	return b.schema(reflect.TypeOf(v))
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schema(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + b.component(t)}
+	default:
+		// Interfaces and anything else JSON can hold without a fixed shape.
+		return &Schema{}
+	}
+}
+
+// component returns the component name of a named struct type, building its
+// schema the first time. The name is claimed before the fields are walked so
+// recursive types terminate.
+func (b *schemaBuilder) component(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+	name := componentChars.ReplaceAllString(t.Name(), "_")
+	if _, taken := b.components[name]; taken {
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+		name = componentChars.ReplaceAllString(pkg, "_") + "." + name
+	}
+	b.names[t] = name
+	b.components[name] = &Schema{}
+	*b.components[name] = *b.structSchema(t)
+	return name
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	b.addFields(s, t)
+	return s
+}
+
+// addFields adds t's JSON fields to s following encoding/json's rules:
+// unexported and "-" fields are skipped and untagged embedded structs are
+// flattened.
+func (b *schemaBuilder) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.addFields(s, ft)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		prop := b.schema(field.Type)
+		// A pointer that is always written may be null.
+		if field.Type.Kind() == reflect.Pointer && !hasOption(opts, "omitempty") {
+			prop = nullable(prop)
+		}
+		s.Properties[name] = prop
+	}
+}
+
+func hasOption(opts, want string) bool {
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == want {
+			return true
+		}
+	}
+	return false
+}
+
+func nullable(s *Schema) *Schema {
+	if typ, ok := s.Type.(string); ok && s.Ref == "" {
+		s.Type = []string{typ, "null"}
+		return s
+	}
+	if s.Ref == "" && s.Type == nil {
+		return s // already accepts any value
+	}
+	return &Schema{OneOf: []*Schema{s, {Type: "null"}}}
+}
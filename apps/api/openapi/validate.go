@@ -0,0 +1,424 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	versionPattern       = regexp.MustCompile(`^3\.1\.\d+(-.+)?$`)
+	statusCodePattern    = regexp.MustCompile(`^[1-5](?:[0-9]{2}|XX)$`)
+	componentNamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+	templateParamPattern = regexp.MustCompile(`\{([^{}]+)\}`)
+)
+
+var (
+	rootFields      = fieldSet("openapi", "info", "jsonSchemaDialect", "servers", "paths", "webhooks", "components", "security", "tags", "externalDocs")
+	infoFields      = fieldSet("title", "summary", "description", "termsOfService", "contact", "license", "version")
+	pathItemFields  = fieldSet("$ref", "summary", "description", "servers", "parameters")
+	operationFields = fieldSet("tags", "summary", "description", "externalDocs", "operationId", "parameters", "requestBody", "responses", "callbacks", "deprecated", "security", "servers")
+	httpMethods     = fieldSet("get", "put", "post", "delete", "options", "head", "patch", "trace")
+	parameterIn     = fieldSet("query", "header", "path", "cookie")
+	schemaTypes     = fieldSet("null", "boolean", "object", "array", "number", "string", "integer")
+	schemeTypes     = fieldSet("apiKey", "http", "mutualTLS", "oauth2", "openIdConnect")
+)
+
+func fieldSet(names ...string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// Validate checks a serialized document against the structural rules of the
+// OpenAPI 3.1 schema: required fields and allowed keys of each object,
+// parameter locations, response codes, security requirements naming
+// defined schemes, path templates matching their path parameters, unique
+// operation ids and $ref targets that exist. All problems are reported
+// together.
+func Validate(raw []byte) error {
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("document is not a JSON object: %w", err)
+	}
+	v := &validator{doc: doc, operationIDs: make(map[string]string)}
+	v.root()
+	if len(v.problems) == 0 {
+		return nil
+	}
+	sort.Strings(v.problems)
+	return errors.New(strings.Join(v.problems, "; "))
+}
+
+type validator struct {
+	doc          map[string]any
+	schemes      map[string]any
+	operationIDs map[string]string
+	problems     []string
+}
+
+func (v *validator) fail(at, format string, args ...any) {
+	v.problems = append(v.problems, at+": "+fmt.Sprintf(format, args...))
+}
+
+// object returns value as a JSON object, or reports it.
+func (v *validator) object(at string, value any) (map[string]any, bool) {
+	obj, ok := value.(map[string]any)
+	if !ok {
+		v.fail(at, "must be an object")
+	}
+	return obj, ok
+}
+
+func (v *validator) allowed(at string, obj map[string]any, fields map[string]bool) {
+	for key := range obj {
+		if !fields[key] && !strings.HasPrefix(key, "x-") {
+			v.fail(at, "unexpected field %q", key)
+		}
+	}
+}
+
+func (v *validator) requireString(at string, obj map[string]any, key string) string {
+	s, ok := obj[key].(string)
+	if !ok || s == "" {
+		v.fail(at, "%s is required and must be a non-empty string", key)
+	}
+	return s
+}
+
+func (v *validator) root() {
+	v.allowed("#", v.doc, rootFields)
+	if version := v.requireString("#", v.doc, "openapi"); version != "" && !versionPattern.MatchString(version) {
+		v.fail("#/openapi", "%q is not an OpenAPI 3.1 version", version)
+	}
+	if info, ok := v.object("#/info", v.doc["info"]); ok {
+		v.allowed("#/info", info, infoFields)
+		v.requireString("#/info", info, "title")
+		v.requireString("#/info", info, "version")
+	}
+	if v.doc["paths"] == nil && v.doc["components"] == nil && v.doc["webhooks"] == nil {
+		v.fail("#", "one of paths, components or webhooks is required")
+	}
+
+	v.schemes = map[string]any{}
+	if raw, ok := v.doc["components"]; ok {
+		v.components(raw)
+	}
+	if raw, ok := v.doc["security"]; ok {
+		v.security("#/security", raw)
+	}
+	if raw, ok := v.doc["paths"]; ok {
+		if paths, ok := v.object("#/paths", raw); ok {
+			for path, item := range paths {
+				v.pathItem(path, item)
+			}
+		}
+	}
+}
+
+func (v *validator) components(raw any) {
+	components, ok := v.object("#/components", raw)
+	if !ok {
+		return
+	}
+	if raw, ok := components["schemas"]; ok {
+		if schemas, ok := v.object("#/components/schemas", raw); ok {
+			for name, schema := range schemas {
+				at := "#/components/schemas/" + name
+				if !componentNamePattern.MatchString(name) {
+					v.fail(at, "invalid component name")
+				}
+				v.schema(at, schema)
+			}
+		}
+	}
+	if raw, ok := components["securitySchemes"]; ok {
+		if schemes, ok := v.object("#/components/securitySchemes", raw); ok {
+			v.schemes = schemes
+			for name, raw := range schemes {
+				v.securityScheme("#/components/securitySchemes/"+name, raw)
+			}
+		}
+	}
+}
+
+func (v *validator) securityScheme(at string, raw any) {
+	scheme, ok := v.object(at, raw)
+	if !ok {
+		return
+	}
+	typ := v.requireString(at, scheme, "type")
+	if typ != "" && !schemeTypes[typ] {
+		v.fail(at, "unknown security scheme type %q", typ)
+	}
+	switch typ {
+	case "apiKey":
+		v.requireString(at, scheme, "name")
+		if in, _ := scheme["in"].(string); in != "query" && in != "header" && in != "cookie" {
+			v.fail(at, "apiKey in must be query, header or cookie")
+		}
+	case "http":
+		v.requireString(at, scheme, "scheme")
+	}
+}
+
+func (v *validator) security(at string, raw any) {
+	list, ok := raw.([]any)
+	if !ok {
+		v.fail(at, "must be an array")
+		return
+	}
+	for i, item := range list {
+		requirement, ok := v.object(fmt.Sprintf("%s/%d", at, i), item)
+		if !ok {
+			continue
+		}
+		for name, scopes := range requirement {
+			if _, defined := v.schemes[name]; !defined {
+				v.fail(at, "security scheme %q is not defined", name)
+			}
+			if _, ok := scopes.([]any); !ok {
+				v.fail(at, "scopes of %q must be an array", name)
+			}
+		}
+	}
+}
+
+func (v *validator) pathItem(path string, raw any) {
+	at := "#/paths/" + path
+	if !strings.HasPrefix(path, "/") {
+		v.fail(at, "path must start with /")
+	}
+	item, ok := v.object(at, raw)
+	if !ok {
+		return
+	}
+	template := map[string]bool{}
+	for _, m := range templateParamPattern.FindAllStringSubmatch(path, -1) {
+		template[m[1]] = true
+	}
+	for key, value := range item {
+		switch {
+		case httpMethods[key]:
+			v.operation(at+"/"+key, value, template)
+		case !pathItemFields[key] && !strings.HasPrefix(key, "x-"):
+			v.fail(at, "unexpected field %q", key)
+		}
+	}
+}
+
+func (v *validator) operation(at string, raw any, template map[string]bool) {
+	op, ok := v.object(at, raw)
+	if !ok {
+		return
+	}
+	v.allowed(at, op, operationFields)
+
+	if id, ok := op["operationId"]; ok {
+		s, _ := id.(string)
+		if s == "" {
+			v.fail(at, "operationId must be a non-empty string")
+		} else if other, dup := v.operationIDs[s]; dup {
+			v.fail(at, "operationId %q is also used by %s", s, other)
+		} else {
+			v.operationIDs[s] = at
+		}
+	}
+	if raw, ok := op["tags"]; ok {
+		tags, ok := raw.([]any)
+		if !ok {
+			v.fail(at, "tags must be an array")
+		}
+		for _, tag := range tags {
+			if _, ok := tag.(string); !ok {
+				v.fail(at, "tags must be strings")
+			}
+		}
+	}
+
+	pathParams := map[string]bool{}
+	if raw, ok := op["parameters"]; ok {
+		params, ok := raw.([]any)
+		if !ok {
+			v.fail(at, "parameters must be an array")
+		}
+		seen := map[string]bool{}
+		for i, raw := range params {
+			pat := fmt.Sprintf("%s/parameters/%d", at, i)
+			param, ok := v.object(pat, raw)
+			if !ok {
+				continue
+			}
+			name := v.requireString(pat, param, "name")
+			in, _ := param["in"].(string)
+			if !parameterIn[in] {
+				v.fail(pat, "in must be query, header, path or cookie")
+			}
+			if seen[in+":"+name] {
+				v.fail(pat, "duplicate %s parameter %q", in, name)
+			}
+			seen[in+":"+name] = true
+			_, hasSchema := param["schema"]
+			_, hasContent := param["content"]
+			if hasSchema == hasContent {
+				v.fail(pat, "exactly one of schema or content is required")
+			}
+			if hasSchema {
+				v.schema(pat+"/schema", param["schema"])
+			}
+			if in == "path" {
+				pathParams[name] = true
+				if required, _ := param["required"].(bool); !required {
+					v.fail(pat, "path parameter %q must be required", name)
+				}
+				if !template[name] {
+					v.fail(pat, "path parameter %q is not in the path template", name)
+				}
+			}
+		}
+	}
+	for name := range template {
+		if !pathParams[name] {
+			v.fail(at, "path template parameter %q is not declared", name)
+		}
+	}
+
+	if raw, ok := op["requestBody"]; ok {
+		if body, ok := v.object(at+"/requestBody", raw); ok {
+			if _, ok := body["content"]; !ok {
+				v.fail(at+"/requestBody", "content is required")
+			} else {
+				v.content(at+"/requestBody/content", body["content"])
+			}
+		}
+	}
+
+	responses, ok := v.object(at+"/responses", op["responses"])
+	if ok && len(responses) == 0 {
+		v.fail(at+"/responses", "at least one response is required")
+	}
+	for code, raw := range responses {
+		rat := at + "/responses/" + code
+		if code != "default" && !statusCodePattern.MatchString(code) {
+			v.fail(rat, "invalid response code")
+		}
+		resp, ok := v.object(rat, raw)
+		if !ok {
+			continue
+		}
+		if _, ok := resp["description"].(string); !ok {
+			v.fail(rat, "description is required")
+		}
+		if raw, ok := resp["content"]; ok {
+			v.content(rat+"/content", raw)
+		}
+	}
+
+	if raw, ok := op["security"]; ok {
+		v.security(at+"/security", raw)
+	}
+}
+
+func (v *validator) content(at string, raw any) {
+	content, ok := v.object(at, raw)
+	if !ok {
+		return
+	}
+	for mediaType, raw := range content {
+		media, ok := v.object(at+"/"+mediaType, raw)
+		if !ok {
+			continue
+		}
+		if schema, ok := media["schema"]; ok {
+			v.schema(at+"/"+mediaType+"/schema", schema)
+		}
+	}
+}
+
+// schema checks the JSON Schema keywords the generator emits: types,
+// nested schemas and $ref targets.
+func (v *validator) schema(at string, raw any) {
+	if _, ok := raw.(bool); ok {
+		return
+	}
+	schema, ok := v.object(at, raw)
+	if !ok {
+		return
+	}
+	if ref, ok := schema["$ref"]; ok {
+		s, _ := ref.(string)
+		if !v.resolves(s) {
+			v.fail(at, "$ref %q does not resolve", s)
+		}
+	}
+	if typ, ok := schema["type"]; ok {
+		switch t := typ.(type) {
+		case string:
+			if !schemaTypes[t] {
+				v.fail(at, "unknown type %q", t)
+			}
+		case []any:
+			if len(t) == 0 {
+				v.fail(at, "type list must not be empty")
+			}
+			for _, item := range t {
+				if s, _ := item.(string); !schemaTypes[s] {
+					v.fail(at, "unknown type %v", item)
+				}
+			}
+		default:
+			v.fail(at, "type must be a string or an array of strings")
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties", "not"} {
+		if sub, ok := schema[key]; ok {
+			v.schema(at+"/"+key, sub)
+		}
+	}
+	if raw, ok := schema["properties"]; ok {
+		if props, ok := v.object(at+"/properties", raw); ok {
+			for name, sub := range props {
+				v.schema(at+"/properties/"+name, sub)
+			}
+		}
+	}
+	for _, key := range []string{"oneOf", "anyOf", "allOf"} {
+		raw, ok := schema[key]
+		if !ok {
+			continue
+		}
+		list, ok := raw.([]any)
+		if !ok || len(list) == 0 {
+			v.fail(at, "%s must be a non-empty array", key)
+			continue
+		}
+		for i, sub := range list {
+			v.schema(fmt.Sprintf("%s/%s/%d", at, key, i), sub)
+		}
+	}
+}
+
+// resolves reports whether ref is a local JSON pointer to a value in the
+// document.
+func (v *validator) resolves(ref string) bool {
+	if !strings.HasPrefix(ref, "#/") {
+		return false
+	}
+	var node any = v.doc
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return false
+		}
+		if node, ok = obj[token]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package orchestrator
+
+import (
+	"context"
+	"database/sql"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentsquads/api/plans"
+)
+
+// diskQuotaStats counts disk quota checks, warnings, stops and failed usage
+// reads. It is published through expvar at /debug/vars.
+var diskQuotaStats = expvar.NewMap("tenant_disk_quota")
+
+// diskUsageCmd reports the size of the container's root filesystem in MB,
+// staying on that filesystem so mounted volumes are not counted.
+var diskUsageCmd = []string{"du", "-sxm", "/"}
+
+// DiskQuotaMonitor enforces plan disk allowances where the container runtime
+// cannot. Each check measures active tenants with du; a tenant over its
+// allowance is warned first and paused if it is still over on the next check.
+type DiskQuotaMonitor struct {
+	db    *sql.DB
+	orch  TenantOrchestrator
+	plans *plans.Resolver
+	log   *slog.Logger
+
+	mu     sync.Mutex
+	warned map[string]bool
+}
+
+func NewDiskQuotaMonitor(db *sql.DB, orch TenantOrchestrator, resolver *plans.Resolver) *DiskQuotaMonitor {
+	return &DiskQuotaMonitor{
+		db:     db,
+		orch:   orch,
+		plans:  resolver,
+		log:    slog.Default().With("component", "disk-quota"),
+		warned: make(map[string]bool),
+	}
+}
+
+// Start runs Check every interval until ctx is done.
+func (m *DiskQuotaMonitor) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Check(ctx); err != nil {
+				m.log.Error("disk quota check failed", "err", err)
+			}
+		}
+	}
+}
+
+// Check measures every active tenant container once.
+func (m *DiskQuotaMonitor) Check(ctx context.Context) error {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT id FROM tenants WHERE status = 'active' AND container_id IS NOT NULL`)
+	if err != nil {
+		return fmt.Errorf("list active tenants: %w", err)
+	}
+	var tenantIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan tenant: %w", err)
+		}
+		tenantIDs = append(tenantIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("list active tenants: %w", err)
+	}
+
+	for _, tenantID := range tenantIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		m.checkTenant(ctx, tenantID)
+	}
+	return nil
+}
+
+func (m *DiskQuotaMonitor) checkTenant(ctx context.Context, tenantID string) {
+	diskQuotaStats.Add("checks", 1)
+	output, err := m.orch.Exec(ctx, tenantID, diskUsageCmd)
+	if err != nil {
+		diskQuotaStats.Add("errors", 1)
+		m.log.Warn("measure tenant disk usage failed", "tenant", tenantID, "err", err)
+		return
+	}
+	usedMB, err := parseDiskUsage(output)
+	if err != nil {
+		diskQuotaStats.Add("errors", 1)
+		m.log.Warn("parse tenant disk usage failed", "tenant", tenantID, "err", err)
+		return
+	}
+	limitMB := TenantDiskMB(ctx, m.plans, tenantID)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if usedMB <= limitMB {
+		delete(m.warned, tenantID)
+		return
+	}
+	if !m.warned[tenantID] {
+		m.warned[tenantID] = true
+		diskQuotaStats.Add("warnings", 1)
+		m.log.Warn("tenant over disk allowance", "tenant", tenantID, "used_mb", usedMB, "limit_mb", limitMB)
+		return
+	}
+
+	// Paused rather than only stopped so the usual resume flow brings the
+	// tenant back once it has been cleaned up.
+	if _, err := m.db.ExecContext(ctx, `UPDATE tenants SET status = 'paused' WHERE id = $1`, tenantID); err != nil {
+		m.log.Error("pause tenant over disk allowance failed", "tenant", tenantID, "err", err)
+		return
+	}
+	if err := m.orch.Stop(ctx, tenantID); err != nil {
+		m.log.Error("stop tenant over disk allowance failed", "tenant", tenantID, "err", err)
+		return
+	}
+	delete(m.warned, tenantID)
+	diskQuotaStats.Add("stops", 1)
+	m.log.Warn("paused tenant over disk allowance", "tenant", tenantID, "used_mb", usedMB, "limit_mb", limitMB)
+}
+
+// parseDiskUsage reads the MB figure from du -sm output ("1234\t/").
+func parseDiskUsage(output string) (int64, error) {
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty du output")
+	}
+	mb, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected du output %q", strings.TrimSpace(output))
+	}
+	return mb, nil
+}
@@ -0,0 +1,78 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type diskUsageOrchestrator struct {
+	testOrchestrator
+	usage   map[string]string
+	stopped []string
+}
+
+func (o *diskUsageOrchestrator) Exec(_ context.Context, tenantID string, _ []string) (string, error) {
+	return o.usage[tenantID], nil
+}
+
+func (o *diskUsageOrchestrator) Stop(_ context.Context, tenantID string) error {
+	o.stopped = append(o.stopped, tenantID)
+	return nil
+}
+
+func TestDiskQuotaMonitorWarnsThenPauses(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	orch := &diskUsageOrchestrator{usage: map[string]string{
+		"t1": "20480\t/\ndu: cannot read directory '/proc/1': Permission denied",
+		"t2": "100\t/",
+	}}
+	m := NewDiskQuotaMonitor(db, orch, nil)
+	tenants := func() *sqlmock.Rows { return sqlmock.NewRows([]string{"id"}).AddRow("t1").AddRow("t2") }
+
+	mock.ExpectQuery("FROM tenants WHERE status = 'active'").WillReturnRows(tenants())
+	if err := m.Check(context.Background()); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(orch.stopped) != 0 || !m.warned["t1"] || m.warned["t2"] {
+		t.Fatalf("after first check stopped=%v warned=%v", orch.stopped, m.warned)
+	}
+
+	mock.ExpectQuery("FROM tenants WHERE status = 'active'").WillReturnRows(tenants())
+	mock.ExpectExec("UPDATE tenants SET status = 'paused'").WithArgs("t1").WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := m.Check(context.Background()); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(orch.stopped) != 1 || orch.stopped[0] != "t1" || m.warned["t1"] {
+		t.Fatalf("after second check stopped=%v warned=%v", orch.stopped, m.warned)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestSupportsStorageQuota(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		driver string
+		status [][2]string
+		want   bool
+	}{
+		{driver: "overlay2", status: [][2]string{{"Backing Filesystem", "xfs"}}, want: true},
+		{driver: "overlay2", status: [][2]string{{"Backing Filesystem", "extfs"}}},
+		{driver: "btrfs", want: true},
+		{driver: "vfs"},
+	}
+	for _, tt := range tests {
+		if got := supportsStorageQuota(tt.driver, tt.status); got != tt.want {
+			t.Fatalf("supportsStorageQuota(%q, %v) = %v", tt.driver, tt.status, got)
+		}
+	}
+}
@@ -7,14 +7,21 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/agentsquads/api/plans"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
 )
 
 const (
@@ -23,6 +30,7 @@ const (
 	memoryLimit   = 512 * 1024 * 1024 // 512MB
 	cpuQuota      = 50000             // 0.5 cores (50% of 100000)
 	cpuPeriod     = 100000
+	diskLimitMB   = 10 * 1024 // 10GB
 	tenantPort    = 4200
 )
 
@@ -35,6 +43,12 @@ type DockerOrchestrator struct {
 	platformAPIURL string
 	platformAPIKey string
 	llmProxyURL    string
+
+	plans *plans.Resolver
+
+	// storageQuota is set when the storage driver accepts a per-container
+	// size limit (StorageOpt "size").
+	storageQuota bool
 }
 
 // NewDockerOrchestrator creates a new Docker-based orchestrator.
@@ -57,9 +71,40 @@ func NewDockerOrchestrator(db *sql.DB, platformAPIURL, platformAPIKey, llmProxyU
 		return nil, err
 	}
 
+	if info, err := cli.Info(context.Background()); err != nil {
+		o.log.Warn("docker info failed, container disk quotas disabled", "err", err)
+	} else {
+		o.storageQuota = supportsStorageQuota(info.Driver, info.DriverStatus)
+		o.log.Info("docker storage driver", "driver", info.Driver, "disk_quota", o.storageQuota)
+	}
+
 	return o, nil
 }
 
+// supportsStorageQuota reports whether the storage driver enforces
+// StorageOpt size limits. overlay2 only does on xfs mounted with pquota;
+// Docker rejects the option at create time otherwise, so xfs is taken as
+// the signal.
+func supportsStorageQuota(driver string, status [][2]string) bool {
+	switch driver {
+	case "btrfs", "zfs", "devicemapper", "windowsfilter":
+		return true
+	case "overlay2":
+		for _, kv := range status {
+			if kv[0] == "Backing Filesystem" {
+				return kv[1] == "xfs"
+			}
+		}
+	}
+	return false
+}
+
+// EnforcesStorageQuota reports whether containers are created with a disk
+// size limit. When they are not, a DiskQuotaMonitor checks usage instead.
+func (o *DockerOrchestrator) EnforcesStorageQuota() bool {
+	return o.storageQuota
+}
+
 // EnsureNetwork creates the tenant network if it doesn't exist.
 func (o *DockerOrchestrator) EnsureNetwork(ctx context.Context) error {
 	nets, err := o.cli.NetworkList(ctx, network.ListOptions{
@@ -87,6 +132,69 @@ func (o *DockerOrchestrator) EnsureNetwork(ctx context.Context) error {
 	return nil
 }
 
+// SetPlanResolver makes container resources follow the tenant's plan on create.
+func (o *DockerOrchestrator) SetPlanResolver(resolver *plans.Resolver) {
+	o.plans = resolver
+}
+
+// resourcesForTenant returns the container resources for the tenant's plan,
+// falling back to the platform defaults for unset plan limits.
+func (o *DockerOrchestrator) resourcesForTenant(ctx context.Context, tenantID string) container.Resources {
+	return tenantResources(ctx, o.plans, o.log, tenantID)
+}
+
+// tenantResources resolves the tenant's plan limits on top of the platform
+// defaults. Every backend sizes tenants from it so plans mean the same thing
+// on Docker and Kubernetes.
+func tenantResources(ctx context.Context, resolver *plans.Resolver, log *slog.Logger, tenantID string) container.Resources {
+	res := container.Resources{
+		Memory:    memoryLimit,
+		CPUQuota:  cpuQuota,
+		CPUPeriod: cpuPeriod,
+	}
+	if resolver == nil {
+		return res
+	}
+	plan, err := resolver.ForTenant(ctx, tenantID)
+	if err != nil {
+		log.Warn("failed to resolve tenant plan, using default resources", "tenant", tenantID, "err", err)
+		return res
+	}
+	return planResources(plan, res)
+}
+
+// TenantDiskMB is the disk allowance the tenant's plan gives its container,
+// or the platform default.
+func TenantDiskMB(ctx context.Context, resolver *plans.Resolver, tenantID string) int64 {
+	if resolver == nil {
+		return diskLimitMB
+	}
+	plan, err := resolver.ForTenant(ctx, tenantID)
+	if err != nil || plan == nil || plan.ContainerDiskMB <= 0 {
+		return diskLimitMB
+	}
+	return int64(plan.ContainerDiskMB)
+}
+
+// TenantMemoryMB is the container memory limit the tenant's plan gives it,
+// or the platform default.
+func TenantMemoryMB(ctx context.Context, resolver *plans.Resolver, tenantID string) int64 {
+	return tenantResources(ctx, resolver, slog.Default(), tenantID).Memory / (1024 * 1024)
+}
+
+func planResources(plan *plans.Plan, res container.Resources) container.Resources {
+	if plan == nil {
+		return res
+	}
+	if plan.ContainerMemoryMB > 0 {
+		res.Memory = int64(plan.ContainerMemoryMB) * 1024 * 1024
+	}
+	if plan.ContainerCPU > 0 {
+		res.CPUQuota = int64(plan.ContainerCPU * cpuPeriod)
+	}
+	return res
+}
+
 func containerName(tenantID string) string {
 	short := tenantID
 	if len(short) > 8 {
@@ -95,6 +203,13 @@ func containerName(tenantID string) string {
 	return "at-tenant-" + short
 }
 
+// TenantAlias is the tenant container's DNS name on the tenant network.
+// Docker's embedded DNS resolves it for every container on that network, so
+// no host port has to be published.
+func TenantAlias(tenantID string) string {
+	return "at-tenant-" + tenantID
+}
+
 // Create creates a new tenant container.
 func (o *DockerOrchestrator) Create(ctx context.Context, tenantID string) (*Container, error) {
 	o.log.Info("creating container", "tenant", tenantID)
@@ -109,6 +224,18 @@ func (o *DockerOrchestrator) Create(ctx context.Context, tenantID string) (*Cont
 	}
 
 	name := containerName(tenantID)
+	alias := TenantAlias(tenantID)
+
+	hostConfig := &container.HostConfig{
+		Resources:     o.resourcesForTenant(ctx, tenantID),
+		RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
+		NetworkMode:   container.NetworkMode(tenantNetwork),
+	}
+	if o.storageQuota {
+		hostConfig.StorageOpt = map[string]string{
+			"size": strconv.FormatInt(TenantDiskMB(ctx, o.plans, tenantID), 10) + "M",
+		}
+	}
 
 	resp, err := o.cli.ContainerCreate(ctx,
 		&container.Config{
@@ -123,16 +250,13 @@ func (o *DockerOrchestrator) Create(ctx context.Context, tenantID string) (*Cont
 				"agentsquads.tenant": tenantID,
 			},
 		},
-		&container.HostConfig{
-			Resources: container.Resources{
-				Memory:   memoryLimit,
-				CPUQuota: cpuQuota,
-				CPUPeriod: cpuPeriod,
+		hostConfig,
+		&network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{
+				tenantNetwork: {Aliases: []string{alias}},
 			},
-			RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
-			NetworkMode:   container.NetworkMode(tenantNetwork),
 		},
-		nil, nil, name,
+		nil, name,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("container create: %w", err)
@@ -140,8 +264,8 @@ func (o *DockerOrchestrator) Create(ctx context.Context, tenantID string) (*Cont
 
 	// Update DB
 	_, err = o.db.ExecContext(ctx,
-		"UPDATE tenants SET container_id = $1 WHERE id = $2",
-		resp.ID, tenantID,
+		"UPDATE tenants SET container_id = $1, container_alias = $2, container_port = $3 WHERE id = $4",
+		resp.ID, alias, tenantPort, tenantID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("db update: %w", err)
@@ -227,7 +351,7 @@ func (o *DockerOrchestrator) Delete(ctx context.Context, tenantID string) error
 	}
 
 	_, err = o.db.ExecContext(ctx,
-		"UPDATE tenants SET container_id = NULL WHERE id = $1", tenantID,
+		"UPDATE tenants SET container_id = NULL, container_alias = NULL WHERE id = $1", tenantID,
 	)
 	if err != nil {
 		return fmt.Errorf("db update: %w", err)
@@ -235,7 +359,8 @@ func (o *DockerOrchestrator) Delete(ctx context.Context, tenantID string) error
 	return nil
 }
 
-// Status returns the current status of a tenant container.
+// Status returns the current status of a tenant container, with resource
+// usage and OpenFang's version and uptime when the container is running.
 func (o *DockerOrchestrator) Status(ctx context.Context, tenantID string) (*ContainerStatus, error) {
 	cid, err := o.getContainerID(ctx, tenantID)
 	if err != nil {
@@ -248,8 +373,10 @@ func (o *DockerOrchestrator) Status(ctx context.Context, tenantID string) (*Cont
 	}
 
 	status := &ContainerStatus{
-		Running: info.State.Running,
-		Health:  "unknown",
+		Running:      info.State.Running,
+		Health:       "unknown",
+		RestartCount: info.RestartCount,
+		OOMKilled:    info.State.OOMKilled,
 	}
 
 	if info.State.StartedAt != "" {
@@ -260,9 +387,50 @@ func (o *DockerOrchestrator) Status(ctx context.Context, tenantID string) (*Cont
 		status.Health = string(info.State.Health.Status)
 	}
 
+	if status.Running {
+		o.probeRunning(ctx, tenantID, cid, status)
+	}
 	return status, nil
 }
 
+// ListTenants returns every tenant container, running or not, from a single
+// container list call. Docker only reports resource usage through a stats
+// call per container, so MemoryMB and CPUPct are left zero.
+func (o *DockerOrchestrator) ListTenants(ctx context.Context) ([]TenantContainer, error) {
+	containers, err := o.cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", "agentsquads.tenant")),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("container list: %w", err)
+	}
+
+	out := make([]TenantContainer, 0, len(containers))
+	for _, c := range containers {
+		out = append(out, tenantContainerFromSummary(c))
+	}
+	return out, nil
+}
+
+// tenantContainerFromSummary converts a container list entry. Health is only
+// exposed in the human-readable status, e.g. "Up 2 hours (healthy)".
+func tenantContainerFromSummary(c container.Summary) TenantContainer {
+	tc := TenantContainer{
+		TenantID:    c.Labels["agentsquads.tenant"],
+		ContainerID: c.ID,
+		Status:      string(c.State),
+		Port:        tenantPort,
+	}
+	tc.Healthy = tc.Status == "running" && strings.Contains(c.Status, "(healthy)")
+	for _, p := range c.Ports {
+		if p.PrivatePort == tenantPort && p.PublicPort > 0 {
+			tc.Port = int(p.PublicPort)
+			break
+		}
+	}
+	return tc
+}
+
 // Exec runs a command inside a tenant container and returns the output.
 func (o *DockerOrchestrator) Exec(ctx context.Context, tenantID string, cmd []string) (string, error) {
 	cid, err := o.getContainerID(ctx, tenantID)
@@ -299,5 +467,115 @@ func (o *DockerOrchestrator) Exec(ctx context.Context, tenantID string, cmd []st
 	if errStr := stderr.String(); errStr != "" {
 		output += "\n" + errStr
 	}
+
+	var exitCode *int
+	if inspect, err := o.cli.ContainerExecInspect(ctx, execResp.ID); err != nil {
+		o.log.Warn("exec inspect failed", "tenant", tenantID, "err", err)
+	} else {
+		exitCode = &inspect.ExitCode
+	}
+	recordExec(ctx, o.db, o.log, tenantID, cmd, output, exitCode)
 	return output, nil
 }
+
+// ContainerPorts lists every port the tenant's running container exposes,
+// with its host bindings.
+func (o *DockerOrchestrator) ContainerPorts(ctx context.Context, tenantID string) ([]ContainerPort, error) {
+	cid, err := o.getContainerID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	info, err := o.cli.ContainerInspect(ctx, cid)
+	if err != nil {
+		return nil, fmt.Errorf("inspect: %w", err)
+	}
+	if info.State == nil || !info.State.Running {
+		return nil, ErrContainerNotRunning
+	}
+	return containerPorts(info), nil
+}
+
+// containerPorts flattens the inspected port map into one entry per host
+// binding, or one unpublished entry for exposed ports without bindings.
+func containerPorts(info container.InspectResponse) []ContainerPort {
+	ports := []ContainerPort{}
+	if info.NetworkSettings == nil {
+		return ports
+	}
+	for port, bindings := range info.NetworkSettings.Ports {
+		entry := ContainerPort{ContainerPort: port.Int(), Protocol: port.Proto()}
+		if len(bindings) == 0 {
+			ports = append(ports, entry)
+			continue
+		}
+		for _, b := range bindings {
+			entry := entry
+			if hostPort, err := strconv.Atoi(b.HostPort); err == nil && hostPort > 0 {
+				entry.HostPort = &hostPort
+			}
+			entry.Binding = b.HostIP
+			ports = append(ports, entry)
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].ContainerPort != ports[j].ContainerPort {
+			return ports[i].ContainerPort < ports[j].ContainerPort
+		}
+		if ports[i].Protocol != ports[j].Protocol {
+			return ports[i].Protocol < ports[j].Protocol
+		}
+		return ports[i].Binding < ports[j].Binding
+	})
+	return ports
+}
+
+// Endpoint returns the tenant's OpenFang URL on the tenant network. Tenants
+// whose container was created with an alias are addressed by it without
+// asking Docker; older containers are inspected for their published port or
+// network address until they are recreated.
+func (o *DockerOrchestrator) Endpoint(ctx context.Context, tenantID string) (*url.URL, error) {
+	var cid, alias sql.NullString
+	err := o.db.QueryRowContext(ctx,
+		"SELECT container_id, container_alias FROM tenants WHERE id = $1", tenantID,
+	).Scan(&cid, &alias)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("tenant not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("db query: %w", err)
+	}
+	if !cid.Valid || cid.String == "" {
+		return nil, fmt.Errorf("no container for tenant")
+	}
+	if alias.Valid && alias.String != "" {
+		return aliasEndpoint(alias.String), nil
+	}
+
+	info, err := o.cli.ContainerInspect(ctx, cid.String)
+	if err != nil {
+		return nil, fmt.Errorf("inspect: %w", err)
+	}
+	return dockerEndpoint(info)
+}
+
+func aliasEndpoint(alias string) *url.URL {
+	return &url.URL{Scheme: "http", Host: net.JoinHostPort(alias, strconv.Itoa(tenantPort))}
+}
+
+// dockerEndpoint resolves containers created before aliases: the host port
+// published for the OpenFang port when there is one, otherwise the
+// container's address on the tenant network.
+func dockerEndpoint(info container.InspectResponse) (*url.URL, error) {
+	if info.NetworkSettings == nil {
+		return nil, fmt.Errorf("container network settings are missing")
+	}
+	for _, binding := range info.NetworkSettings.Ports[nat.Port(fmt.Sprintf("%d/tcp", tenantPort))] {
+		if port, err := strconv.Atoi(binding.HostPort); err == nil && port > 0 {
+			return &url.URL{Scheme: "http", Host: net.JoinHostPort("localhost", binding.HostPort)}, nil
+		}
+	}
+	if ep, ok := info.NetworkSettings.Networks[tenantNetwork]; ok && ep != nil && ep.IPAddress != "" {
+		return &url.URL{Scheme: "http", Host: net.JoinHostPort(ep.IPAddress, strconv.Itoa(tenantPort))}, nil
+	}
+	return nil, fmt.Errorf("container has no published port or %s address", tenantNetwork)
+}
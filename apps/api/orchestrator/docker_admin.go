@@ -0,0 +1,322 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// notFound wraps Docker's not-found errors as ErrContainerNotFound.
+func notFound(err error) error {
+	if client.IsErrNotFound(err) {
+		return fmt.Errorf("%w: %v", ErrContainerNotFound, err)
+	}
+	return err
+}
+
+// ContainerNetwork inspects the tenant container's networks and published
+// ports.
+func (o *DockerOrchestrator) ContainerNetwork(ctx context.Context, tenantID string) (ContainerNetwork, bool, error) {
+	cid, err := o.getContainerID(ctx, tenantID)
+	if err != nil {
+		return ContainerNetwork{}, false, err
+	}
+	info, err := o.cli.ContainerInspect(ctx, cid)
+	if err != nil {
+		return ContainerNetwork{}, false, fmt.Errorf("docker inspect: %w", notFound(err))
+	}
+	running := info.ContainerJSONBase != nil && info.State != nil && info.State.Running
+	return containerNetworkFromInspect(info), running, nil
+}
+
+func containerNetworkFromInspect(info container.InspectResponse) ContainerNetwork {
+	out := ContainerNetwork{
+		Networks: make([]NetworkAttachment, 0),
+		Ports:    make([]PublishedPort, 0),
+	}
+	if info.Config != nil {
+		out.Hostname = info.Config.Hostname
+	}
+	if info.NetworkSettings == nil {
+		return out
+	}
+
+	for name, endpoint := range info.NetworkSettings.Networks {
+		if endpoint == nil {
+			continue
+		}
+		out.Networks = append(out.Networks, NetworkAttachment{
+			Name:       name,
+			IPAddress:  endpoint.IPAddress,
+			Gateway:    endpoint.Gateway,
+			MacAddress: endpoint.MacAddress,
+			NetworkID:  endpoint.NetworkID,
+		})
+	}
+	sort.Slice(out.Networks, func(i, j int) bool { return out.Networks[i].Name < out.Networks[j].Name })
+
+	for port, bindings := range info.NetworkSettings.Ports {
+		containerPort := port.Int()
+		for _, binding := range bindings {
+			hostPort, err := strconv.Atoi(strings.TrimSpace(binding.HostPort))
+			if err != nil || hostPort <= 0 {
+				continue
+			}
+			out.Ports = append(out.Ports, PublishedPort{Container: containerPort, Host: hostPort})
+		}
+	}
+	sort.Slice(out.Ports, func(i, j int) bool {
+		if out.Ports[i].Container != out.Ports[j].Container {
+			return out.Ports[i].Container < out.Ports[j].Container
+		}
+		return out.Ports[i].Host < out.Ports[j].Host
+	})
+
+	return out
+}
+
+// ContainerEvents streams the Docker lifecycle events of the tenant
+// container until ctx is done.
+func (o *DockerOrchestrator) ContainerEvents(ctx context.Context, tenantID string) (<-chan ContainerEvent, <-chan error, error) {
+	cid, err := o.getContainerID(ctx, tenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := o.cli.ContainerInspect(ctx, cid); err != nil {
+		return nil, nil, fmt.Errorf("docker inspect: %w", notFound(err))
+	}
+	messages, errs := o.cli.Events(ctx, events.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("type", string(events.ContainerEventType)), filters.Arg("container", cid)),
+	})
+	return containerEvents(ctx, messages), errs, nil
+}
+
+// containerEvents converts Docker event messages until ctx is done.
+func containerEvents(ctx context.Context, messages <-chan events.Message) <-chan ContainerEvent {
+	out := make(chan ContainerEvent)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-messages:
+				select {
+				case out <- containerEventFromMessage(msg):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+func containerEventFromMessage(msg events.Message) ContainerEvent {
+	evt := ContainerEvent{Action: string(msg.Action), Time: msg.Time, Status: string(msg.Action)}
+	switch msg.Action {
+	case events.ActionStart, events.ActionRestart, events.ActionUnPause:
+		evt.Status = "running"
+	case events.ActionPause:
+		evt.Status = "paused"
+	case events.ActionStop, events.ActionKill, events.ActionDie:
+		evt.Status = "exited"
+		if code := msg.Actor.Attributes["exitCode"]; code != "" {
+			evt.Status = "exited (" + code + ")"
+		}
+	case events.ActionOOM:
+		evt.Status = "oom_killed"
+	case events.ActionDestroy:
+		evt.Status = "removed"
+	}
+	return evt
+}
+
+// ExportFilesystem exports the stopped tenant container's filesystem.
+// Docker's root filesystem size is checked against maxBytes before the
+// export starts; it is an estimate, so callers should still cap the stream.
+func (o *DockerOrchestrator) ExportFilesystem(ctx context.Context, tenantID string, maxBytes int64) (io.ReadCloser, int64, error) {
+	cid, err := o.getContainerID(ctx, tenantID)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, _, err := o.cli.ContainerInspectWithRaw(ctx, cid, true)
+	if err != nil {
+		return nil, 0, fmt.Errorf("docker inspect: %w", notFound(err))
+	}
+	if info.ContainerJSONBase != nil && info.State != nil && (info.State.Running || info.State.Restarting) {
+		return nil, 0, ErrContainerRunning
+	}
+	var size int64
+	if info.SizeRootFs != nil {
+		size = *info.SizeRootFs
+	}
+	if maxBytes > 0 && size > maxBytes {
+		return nil, size, ErrFilesystemTooLarge
+	}
+	export, err := o.cli.ContainerExport(ctx, cid)
+	if err != nil {
+		return nil, size, fmt.Errorf("docker export: %w", notFound(err))
+	}
+	return export, size, nil
+}
+
+// CopyToContainer unpacks archive into dir of the tenant container. A
+// missing dir is reported as ErrContainerNotFound, like a missing container.
+func (o *DockerOrchestrator) CopyToContainer(ctx context.Context, tenantID, dir string, archive io.Reader) error {
+	cid, err := o.getContainerID(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	if err := o.cli.CopyToContainer(ctx, cid, dir, archive, container.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("docker copy: %w", notFound(err))
+	}
+	return nil
+}
+
+// Relabel replaces the tenant container with one that has the patched
+// labels. Docker cannot change the labels of an existing container, so it is
+// stopped and committed to an image, so its filesystem survives, and a
+// container with the same configuration and the new labels is created from
+// that image and swapped in under the same name and network alias. The
+// tenant is unreachable only for the stop and start. If anything fails
+// before the swap completes, the original container is restored.
+func (o *DockerOrchestrator) Relabel(ctx context.Context, tenantID string, patch map[string]*string) (string, error) {
+	cid, err := o.getContainerID(ctx, tenantID)
+	if err != nil {
+		return "", err
+	}
+	newID, err := relabelContainer(ctx, o.cli, cid, patch)
+	if err != nil {
+		return "", notFound(err)
+	}
+	return newID, nil
+}
+
+// dockerRelabelAPI is the part of the Docker client relabelContainer uses.
+type dockerRelabelAPI interface {
+	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
+	ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error
+	ContainerCommit(ctx context.Context, containerID string, options container.CommitOptions) (container.CommitResponse, error)
+	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error)
+	ContainerRename(ctx context.Context, containerID, newContainerName string) error
+	ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error
+	ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error
+	ImageRemove(ctx context.Context, imageID string, options image.RemoveOptions) ([]image.DeleteResponse, error)
+}
+
+// relabelContainer replaces the container with one that has the patched
+// labels and returns the new container's id.
+func relabelContainer(ctx context.Context, cli dockerRelabelAPI, oldID string, patch map[string]*string) (string, error) {
+	info, err := cli.ContainerInspect(ctx, oldID)
+	if err != nil {
+		return "", err
+	}
+	if info.ContainerJSONBase == nil || info.Config == nil {
+		return "", errors.New("container inspect returned no configuration")
+	}
+	name := strings.TrimPrefix(info.Name, "/")
+	wasRunning := info.State != nil && (info.State.Running || info.State.Restarting)
+
+	config := *info.Config
+	config.Labels = make(map[string]string, len(info.Config.Labels)+len(patch))
+	for k, v := range info.Config.Labels {
+		config.Labels[k] = v
+	}
+	for k, v := range patch {
+		if v == nil {
+			delete(config.Labels, k)
+		} else {
+			config.Labels[k] = *v
+		}
+	}
+	endpoints := make(map[string]*network.EndpointSettings)
+	if info.NetworkSettings != nil {
+		for netName, ep := range info.NetworkSettings.Networks {
+			if ep != nil {
+				endpoints[netName] = &network.EndpointSettings{Aliases: ep.Aliases}
+			}
+		}
+	}
+
+	if wasRunning {
+		timeout := 10
+		if err := cli.ContainerStop(ctx, oldID, container.StopOptions{Timeout: &timeout}); err != nil {
+			return "", fmt.Errorf("stop container: %w", err)
+		}
+	}
+	restoreOld := func() {
+		if !wasRunning {
+			return
+		}
+		if err := cli.ContainerStart(context.WithoutCancel(ctx), oldID, container.StartOptions{}); err != nil {
+			slog.Error("failed to restart original container after relabel failure", "container_id", oldID, "err", err)
+		}
+	}
+
+	committed, err := cli.ContainerCommit(ctx, oldID, container.CommitOptions{
+		Reference: name + ":relabel",
+		Comment:   "filesystem carried over by a container label update",
+	})
+	if err != nil {
+		restoreOld()
+		return "", fmt.Errorf("commit container: %w", err)
+	}
+	config.Image = committed.ID
+
+	created, err := cli.ContainerCreate(ctx, &config, info.HostConfig, &network.NetworkingConfig{EndpointsConfig: endpoints}, nil, name+"-relabel")
+	// The committed image is only a vehicle for the filesystem: a created
+	// container holds its layers, so the image is removed either way rather
+	// than left behind on every relabel.
+	if _, err := cli.ImageRemove(context.WithoutCancel(ctx), committed.ID, image.RemoveOptions{Force: true}); err != nil {
+		slog.Warn("failed to remove relabel image", "image_id", committed.ID, "err", err)
+	}
+	if err != nil {
+		restoreOld()
+		return "", fmt.Errorf("create container: %w", err)
+	}
+	removeNew := func() {
+		if err := cli.ContainerRemove(context.WithoutCancel(ctx), created.ID, container.RemoveOptions{Force: true}); err != nil {
+			slog.Error("failed to remove replacement container after relabel failure", "container_id", created.ID, "err", err)
+		}
+	}
+
+	if err := cli.ContainerRename(ctx, oldID, name+"-old"); err != nil {
+		removeNew()
+		restoreOld()
+		return "", fmt.Errorf("rename original container: %w", err)
+	}
+	err = cli.ContainerRename(ctx, created.ID, name)
+	if err != nil {
+		err = fmt.Errorf("rename replacement container: %w", err)
+	} else if wasRunning {
+		if err = cli.ContainerStart(ctx, created.ID, container.StartOptions{}); err != nil {
+			err = fmt.Errorf("start container: %w", err)
+		}
+	}
+	if err != nil {
+		removeNew()
+		if renameErr := cli.ContainerRename(context.WithoutCancel(ctx), oldID, name); renameErr != nil {
+			slog.Error("failed to restore original container name after relabel failure", "container_id", oldID, "err", renameErr)
+		}
+		restoreOld()
+		return "", err
+	}
+
+	if err := cli.ContainerRemove(ctx, oldID, container.RemoveOptions{}); err != nil {
+		slog.Warn("failed to remove original container after relabel", "container_id", oldID, "err", err)
+	}
+	return created.ID, nil
+}
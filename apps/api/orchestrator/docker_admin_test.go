@@ -0,0 +1,173 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestContainerNetworkFromInspect(t *testing.T) {
+	t.Parallel()
+	info := container.InspectResponse{
+		Config: &container.Config{Hostname: "tenant-host"},
+		NetworkSettings: &container.NetworkSettings{
+			NetworkSettingsBase: container.NetworkSettingsBase{
+				Ports: nat.PortMap{
+					"4200/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "32768"}},
+					"9000/tcp": nil,
+				},
+			},
+			Networks: map[string]*network.EndpointSettings{
+				"agentsquads-tenant-net": {IPAddress: "172.20.0.5", Gateway: "172.20.0.1", MacAddress: "02:42:ac:14:00:05", NetworkID: "net-1"},
+				"bridge":                 {IPAddress: "172.17.0.2", Gateway: "172.17.0.1", NetworkID: "net-0"},
+			},
+		},
+	}
+
+	got := containerNetworkFromInspect(info)
+	if got.Hostname != "tenant-host" {
+		t.Fatalf("hostname = %q", got.Hostname)
+	}
+	if len(got.Networks) != 2 || got.Networks[0].Name != "agentsquads-tenant-net" || got.Networks[0].IPAddress != "172.20.0.5" {
+		t.Fatalf("unexpected networks: %+v", got.Networks)
+	}
+	if len(got.Ports) != 1 || got.Ports[0] != (PublishedPort{Container: 4200, Host: 32768}) {
+		t.Fatalf("unexpected ports: %+v", got.Ports)
+	}
+}
+
+func TestContainerEvents(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages := make(chan events.Message, 2)
+	messages <- events.Message{Action: events.ActionStart, Time: 100}
+	messages <- events.Message{Action: events.ActionDie, Time: 101, Actor: events.Actor{Attributes: map[string]string{"exitCode": "137"}}}
+
+	out := containerEvents(ctx, messages)
+	for _, want := range []ContainerEvent{
+		{Action: "start", Time: 100, Status: "running"},
+		{Action: "die", Time: 101, Status: "exited (137)"},
+	} {
+		if got := <-out; got != want {
+			t.Fatalf("event = %+v, want %+v", got, want)
+		}
+	}
+	if got := containerEventFromMessage(events.Message{Action: events.ActionOOM}); got.Status != "oom_killed" {
+		t.Fatalf("oom status = %q", got.Status)
+	}
+}
+
+type fakeDockerRelabel struct {
+	calls     []string
+	created   *container.Config
+	createErr error
+	startErr  error
+}
+
+func (f *fakeDockerRelabel) ContainerInspect(_ context.Context, id string) (container.InspectResponse, error) {
+	f.calls = append(f.calls, "inspect "+id)
+	return container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			Name:       "/at-tenant-t1",
+			State:      &container.State{Running: true},
+			HostConfig: &container.HostConfig{NetworkMode: "agentsquads-tenants"},
+		},
+		Config: &container.Config{Image: "agentsquads/tenant", Labels: map[string]string{"agentsquads.tenant": "t1", "team": "old"}},
+		NetworkSettings: &container.NetworkSettings{Networks: map[string]*network.EndpointSettings{
+			"agentsquads-tenants": {Aliases: []string{"at-tenant-t1"}, IPAddress: "10.0.0.5"},
+		}},
+	}, nil
+}
+
+func (f *fakeDockerRelabel) ContainerStop(_ context.Context, id string, _ container.StopOptions) error {
+	f.calls = append(f.calls, "stop "+id)
+	return nil
+}
+
+func (f *fakeDockerRelabel) ContainerCommit(_ context.Context, id string, opts container.CommitOptions) (container.CommitResponse, error) {
+	f.calls = append(f.calls, "commit "+id+" "+opts.Reference)
+	return container.CommitResponse{ID: "sha256:img"}, nil
+}
+
+func (f *fakeDockerRelabel) ContainerCreate(_ context.Context, config *container.Config, _ *container.HostConfig, _ *network.NetworkingConfig, _ *ocispec.Platform, name string) (container.CreateResponse, error) {
+	f.calls = append(f.calls, "create "+name)
+	if f.createErr != nil {
+		return container.CreateResponse{}, f.createErr
+	}
+	f.created = config
+	return container.CreateResponse{ID: "new"}, nil
+}
+
+func (f *fakeDockerRelabel) ContainerRename(_ context.Context, id, name string) error {
+	f.calls = append(f.calls, "rename "+id+" "+name)
+	return nil
+}
+
+func (f *fakeDockerRelabel) ContainerStart(_ context.Context, id string, _ container.StartOptions) error {
+	f.calls = append(f.calls, "start "+id)
+	if id == "new" {
+		return f.startErr
+	}
+	return nil
+}
+
+func (f *fakeDockerRelabel) ContainerRemove(_ context.Context, id string, _ container.RemoveOptions) error {
+	f.calls = append(f.calls, "remove "+id)
+	return nil
+}
+
+func (f *fakeDockerRelabel) ImageRemove(_ context.Context, id string, _ image.RemoveOptions) ([]image.DeleteResponse, error) {
+	f.calls = append(f.calls, "rmi "+id)
+	return []image.DeleteResponse{{Untagged: id}}, nil
+}
+
+func TestRelabelContainer(t *testing.T) {
+	t.Parallel()
+	fake := &fakeDockerRelabel{}
+	production := "production"
+	newID, err := relabelContainer(context.Background(), fake, "old", map[string]*string{"environment": &production, "team": nil})
+	if err != nil || newID != "new" {
+		t.Fatalf("relabelContainer() = %q, %v", newID, err)
+	}
+	want := []string{
+		"inspect old", "stop old", "commit old at-tenant-t1:relabel", "create at-tenant-t1-relabel", "rmi sha256:img",
+		"rename old at-tenant-t1-old", "rename new at-tenant-t1", "start new", "remove old",
+	}
+	if strings.Join(fake.calls, "|") != strings.Join(want, "|") {
+		t.Fatalf("docker calls = %v", fake.calls)
+	}
+	if labels := fake.created.Labels; labels["environment"] != "production" || labels["agentsquads.tenant"] != "t1" || labels["team"] != "" || fake.created.Image != "sha256:img" {
+		t.Fatalf("created config = %+v", fake.created)
+	}
+}
+
+func TestRelabelContainerRestoresOriginalOnFailure(t *testing.T) {
+	t.Parallel()
+	fake := &fakeDockerRelabel{startErr: errors.New("port in use")}
+	platform := "platform"
+	if _, err := relabelContainer(context.Background(), fake, "old", map[string]*string{"team": &platform}); err == nil {
+		t.Fatalf("relabelContainer() succeeded")
+	}
+	tail := strings.Join(fake.calls[len(fake.calls)-3:], "|")
+	if tail != "remove new|rename old at-tenant-t1|start old" || !slices.Contains(fake.calls, "rmi sha256:img") {
+		t.Fatalf("rollback calls = %v", fake.calls)
+	}
+
+	fake = &fakeDockerRelabel{createErr: errors.New("no space left on device")}
+	if _, err := relabelContainer(context.Background(), fake, "old", map[string]*string{"team": &platform}); err == nil {
+		t.Fatalf("relabelContainer() succeeded")
+	}
+	if tail := strings.Join(fake.calls[len(fake.calls)-3:], "|"); tail != "create at-tenant-t1-relabel|rmi sha256:img|start old" {
+		t.Fatalf("create failure calls = %v", fake.calls)
+	}
+}
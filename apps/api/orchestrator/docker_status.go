@@ -0,0 +1,152 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+const (
+	// statusProbeTimeout bounds the stats call and the OpenFang health check
+	// made for a status. Docker takes about a second to sample CPU usage and
+	// longer under load; a probe that runs out leaves its fields empty.
+	statusProbeTimeout = 3 * time.Second
+	openFangHealthPath = "/v1/health"
+)
+
+var openFangHealthClient = &http.Client{Timeout: statusProbeTimeout}
+
+// probeRunning fills in status with the resource usage and OpenFang health
+// of the running container cid. Both probes run at once and failures only
+// leave their fields empty.
+func (o *DockerOrchestrator) probeRunning(ctx context.Context, tenantID, cid string, status *ContainerStatus) {
+	ctx, cancel := context.WithTimeout(ctx, statusProbeTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		stats, err := o.containerStats(ctx, cid)
+		if err != nil {
+			o.log.Warn("container stats unavailable", "tenant", tenantID, "err", err)
+			return
+		}
+		applyContainerStats(status, stats)
+	}()
+	var health openFangHealth
+	go func() {
+		defer wg.Done()
+		var err error
+		if health, err = o.openFangHealth(ctx, tenantID); err != nil {
+			o.log.Debug("openfang health unavailable", "tenant", tenantID, "err", err)
+		}
+	}()
+	wg.Wait()
+	status.OpenFangVersion = health.Version
+	status.OpenFangUptimeSeconds = health.UptimeSeconds
+}
+
+// containerStats takes a single stats sample. Without streaming Docker waits
+// for a second sample, so the CPU delta is filled in.
+func (o *DockerOrchestrator) containerStats(ctx context.Context, cid string) (container.StatsResponse, error) {
+	resp, err := o.cli.ContainerStats(ctx, cid, false)
+	if err != nil {
+		return container.StatsResponse{}, fmt.Errorf("stats: %w", err)
+	}
+	defer resp.Body.Close()
+	var stats container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return container.StatsResponse{}, fmt.Errorf("decode stats: %w", err)
+	}
+	return stats, nil
+}
+
+// applyContainerStats sets memory and CPU usage the way docker stats reports
+// them: memory without the page cache, CPU as a percentage of one core.
+func applyContainerStats(status *ContainerStatus, stats container.StatsResponse) {
+	mem := stats.MemoryStats
+	used := mem.Usage
+	// cgroup v2 reports inactive_file, v1 total_inactive_file.
+	for _, key := range []string{"inactive_file", "total_inactive_file"} {
+		if cache, ok := mem.Stats[key]; ok {
+			if cache < used {
+				used -= cache
+			}
+			break
+		}
+	}
+	status.MemoryMB = int64(used / (1 << 20))
+	status.MemoryLimitMB = int64(mem.Limit / (1 << 20))
+
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	cpus := float64(stats.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+	status.CPUPct = 0
+	if cpuDelta > 0 && systemDelta > 0 && cpus > 0 {
+		status.CPUPct = math.Round(cpuDelta/systemDelta*cpus*10000) / 100
+	}
+	status.SampledAt = stats.Read
+	if status.SampledAt.IsZero() {
+		status.SampledAt = time.Now()
+	}
+}
+
+// openFangHealth is the part of OpenFang's health response the status uses.
+type openFangHealth struct {
+	Version       string `json:"version"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+}
+
+func (o *DockerOrchestrator) openFangHealth(ctx context.Context, tenantID string) (openFangHealth, error) {
+	endpoint, err := o.Endpoint(ctx, tenantID)
+	if err != nil {
+		return openFangHealth{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.JoinPath(openFangHealthPath).String(), nil)
+	if err != nil {
+		return openFangHealth{}, err
+	}
+	resp, err := openFangHealthClient.Do(req)
+	if err != nil {
+		return openFangHealth{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return openFangHealth{}, fmt.Errorf("openfang health returned %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return openFangHealth{}, err
+	}
+	return parseOpenFangHealth(body)
+}
+
+// parseOpenFangHealth reads the version and uptime, which older OpenFang
+// builds report as "uptime" in seconds.
+func parseOpenFangHealth(body []byte) (openFangHealth, error) {
+	var raw struct {
+		openFangHealth
+		Uptime *float64 `json:"uptime"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return openFangHealth{}, fmt.Errorf("decode openfang health: %w", err)
+	}
+	health := raw.openFangHealth
+	health.Version = strings.TrimSpace(health.Version)
+	if health.UptimeSeconds == 0 && raw.Uptime != nil {
+		health.UptimeSeconds = int64(*raw.Uptime)
+	}
+	return health, nil
+}
@@ -0,0 +1,141 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+func TestApplyContainerStats(t *testing.T) {
+	t.Parallel()
+	var stats container.StatsResponse
+	stats.Read = time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	stats.MemoryStats = container.MemoryStats{
+		Usage: 530 << 20,
+		Limit: 512 << 20,
+		Stats: map[string]uint64{"inactive_file": 32 << 20},
+	}
+	stats.CPUStats.CPUUsage.TotalUsage = 3_000_000
+	stats.CPUStats.SystemUsage = 40_000_000
+	stats.CPUStats.OnlineCPUs = 4
+	stats.PreCPUStats.CPUUsage.TotalUsage = 1_000_000
+	stats.PreCPUStats.SystemUsage = 20_000_000
+
+	var status ContainerStatus
+	applyContainerStats(&status, stats)
+	if status.MemoryMB != 498 || status.MemoryLimitMB != 512 || status.CPUPct != 40 || !status.SampledAt.Equal(stats.Read) {
+		t.Fatalf("status = %+v", status)
+	}
+
+	// The first sample of a one-shot call has no previous CPU reading.
+	stats.PreCPUStats = container.CPUStats{}
+	stats.CPUStats.SystemUsage = 0
+	applyContainerStats(&status, stats)
+	if status.CPUPct != 0 {
+		t.Fatalf("cpu without a delta = %v", status.CPUPct)
+	}
+}
+
+func TestParseOpenFangHealth(t *testing.T) {
+	t.Parallel()
+	for body, want := range map[string]openFangHealth{
+		`{"status":"ok","version":" 0.9.2 ","uptime_seconds":3600}`: {Version: "0.9.2", UptimeSeconds: 3600},
+		`{"version":"0.8.0","uptime":12.7}`:                         {Version: "0.8.0", UptimeSeconds: 12},
+		`{"status":"ok"}`:                                           {},
+	} {
+		got, err := parseOpenFangHealth([]byte(body))
+		if err != nil || got != want {
+			t.Fatalf("%s: got %+v err=%v", body, got, err)
+		}
+	}
+	if _, err := parseOpenFangHealth([]byte("ok")); err == nil {
+		t.Fatalf("expected an error for a non-JSON body")
+	}
+}
+
+func TestDockerStatusDetail(t *testing.T) {
+	t.Parallel()
+	openfang := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != openFangHealthPath {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"version":"0.9.2","uptime_seconds":7200}`))
+	}))
+	defer openfang.Close()
+	openfangURL, _ := url.Parse(openfang.URL)
+
+	statsOK := true
+	docker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/cid/json"):
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"Id":           "cid",
+				"RestartCount": 4,
+				"State":        map[string]any{"Running": true, "OOMKilled": true, "StartedAt": "2026-01-01T09:00:00Z"},
+				"NetworkSettings": map[string]any{
+					"Ports": map[string]any{"4200/tcp": []map[string]string{{"HostIp": "127.0.0.1", "HostPort": openfangURL.Port()}}},
+				},
+			})
+		case strings.HasSuffix(r.URL.Path, "/containers/cid/stats") && statsOK:
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"read":         "2026-01-01T10:00:00Z",
+				"memory_stats": map[string]any{"usage": 498 << 20, "limit": 512 << 20},
+			})
+		default:
+			http.Error(w, `{"message":"unavailable"}`, http.StatusInternalServerError)
+		}
+	}))
+	defer docker.Close()
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+strings.TrimPrefix(docker.URL, "http://")), client.WithVersion("1.47"))
+	if err != nil {
+		t.Fatalf("docker client: %v", err)
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	o := &DockerOrchestrator{cli: cli, db: db, log: slog.Default()}
+	expectLookups := func() {
+		mock.ExpectQuery("SELECT container_id FROM tenants").WithArgs("t1").
+			WillReturnRows(sqlmock.NewRows([]string{"container_id"}).AddRow("cid"))
+		mock.ExpectQuery("SELECT container_id, container_alias FROM tenants").WithArgs("t1").
+			WillReturnRows(sqlmock.NewRows([]string{"container_id", "container_alias"}).AddRow("cid", nil))
+	}
+
+	expectLookups()
+	status, err := o.Status(context.Background(), "t1")
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if !status.Running || status.RestartCount != 4 || !status.OOMKilled || status.MemoryMB != 498 || status.MemoryLimitMB != 512 ||
+		status.SampledAt.IsZero() || status.OpenFangVersion != "0.9.2" || status.OpenFangUptimeSeconds != 7200 {
+		t.Fatalf("status = %+v", status)
+	}
+
+	// Without stats the rest of the status is still reported.
+	statsOK = false
+	expectLookups()
+	status, err = o.Status(context.Background(), "t1")
+	if err != nil {
+		t.Fatalf("Status without stats: %v", err)
+	}
+	if !status.SampledAt.IsZero() || status.MemoryMB != 0 || status.RestartCount != 4 || status.OpenFangVersion != "0.9.2" {
+		t.Fatalf("status without stats = %+v", status)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
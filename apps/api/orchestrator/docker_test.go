@@ -2,9 +2,14 @@ package orchestrator
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/plans"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
 )
 
 func TestContainerName(t *testing.T) {
@@ -75,3 +80,111 @@ func TestGetContainerID(t *testing.T) {
 		})
 	}
 }
+
+func TestPlanResources(t *testing.T) {
+	t.Parallel()
+	defaults := container.Resources{Memory: memoryLimit, CPUQuota: cpuQuota, CPUPeriod: cpuPeriod}
+
+	if got := planResources(nil, defaults); got.Memory != memoryLimit || got.CPUQuota != cpuQuota {
+		t.Fatalf("nil plan should keep defaults, got %+v", got)
+	}
+
+	got := planResources(&plans.Plan{ContainerMemoryMB: 2048, ContainerCPU: 2}, defaults)
+	if got.Memory != 2048*1024*1024 {
+		t.Fatalf("memory = %d", got.Memory)
+	}
+	if got.CPUQuota != 2*cpuPeriod || got.CPUPeriod != cpuPeriod {
+		t.Fatalf("cpu quota = %d period = %d", got.CPUQuota, got.CPUPeriod)
+	}
+}
+
+func TestDockerEndpoint(t *testing.T) {
+	t.Parallel()
+	info := container.InspectResponse{NetworkSettings: &container.NetworkSettings{
+		Networks: map[string]*network.EndpointSettings{tenantNetwork: {IPAddress: "172.20.0.5"}},
+	}}
+	if got, err := dockerEndpoint(info); err != nil || got.String() != "http://172.20.0.5:4200" {
+		t.Fatalf("network endpoint = %v err=%v", got, err)
+	}
+
+	info.NetworkSettings.Ports = nat.PortMap{"4200/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "32768"}}}
+	if got, err := dockerEndpoint(info); err != nil || got.String() != "http://localhost:32768" {
+		t.Fatalf("published endpoint = %v err=%v", got, err)
+	}
+
+	if _, err := dockerEndpoint(container.InspectResponse{NetworkSettings: &container.NetworkSettings{}}); err == nil {
+		t.Fatalf("expected error without network or ports")
+	}
+}
+
+func TestTenantContainerFromSummary(t *testing.T) {
+	t.Parallel()
+	got := tenantContainerFromSummary(container.Summary{
+		ID:     "0123456789abcdef",
+		Labels: map[string]string{"agentsquads.tenant": "t1"},
+		State:  "running",
+		Status: "Up 2 hours (healthy)",
+		Ports:  []container.Port{{PrivatePort: 4200, PublicPort: 32768, Type: "tcp"}},
+	})
+	if got.TenantID != "t1" || got.Status != "running" || !got.Healthy || got.Port != 32768 {
+		t.Fatalf("running container = %+v", got)
+	}
+
+	got = tenantContainerFromSummary(container.Summary{
+		Labels: map[string]string{"agentsquads.tenant": "t2"},
+		State:  "exited",
+		Status: "Exited (0) 5 minutes ago",
+	})
+	if got.Healthy || got.Status != "exited" || got.Port != tenantPort {
+		t.Fatalf("exited container = %+v", got)
+	}
+}
+
+func TestContainerPorts(t *testing.T) {
+	t.Parallel()
+	info := container.InspectResponse{NetworkSettings: &container.NetworkSettings{
+		NetworkSettingsBase: container.NetworkSettingsBase{Ports: nat.PortMap{
+			"9000/udp": nil,
+			"4200/tcp": {{HostIP: "127.0.0.1", HostPort: "5201"}, {HostIP: "::1", HostPort: "5201"}},
+		}},
+	}}
+	got := containerPorts(info)
+	if len(got) != 3 {
+		t.Fatalf("ports = %+v", got)
+	}
+	if got[0].ContainerPort != 4200 || got[0].Binding != "127.0.0.1" || got[0].HostPort == nil || *got[0].HostPort != 5201 {
+		t.Fatalf("first port = %+v", got[0])
+	}
+	if got[1].Binding != "::1" {
+		t.Fatalf("second port = %+v", got[1])
+	}
+	if got[2].ContainerPort != 9000 || got[2].Protocol != "udp" || got[2].HostPort != nil {
+		t.Fatalf("unpublished port = %+v", got[2])
+	}
+}
+
+func TestAliasEndpoint(t *testing.T) {
+	t.Parallel()
+	alias := TenantAlias("0b6f2a4e-1111-2222-3333-444455556666")
+	if alias != "at-tenant-0b6f2a4e-1111-2222-3333-444455556666" {
+		t.Fatalf("alias = %q", alias)
+	}
+	if got := aliasEndpoint(alias).String(); got != "http://"+alias+":4200" {
+		t.Fatalf("alias endpoint = %q", got)
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	t.Parallel()
+	if got := truncateOutput("short", maxExecHistoryOutput); got != "short" {
+		t.Fatalf("short output = %q", got)
+	}
+	long := strings.Repeat("a", maxExecHistoryOutput-1) + "é" + "tail"
+	got := truncateOutput(long, maxExecHistoryOutput)
+	if len(got) != maxExecHistoryOutput-1 || strings.ContainsRune(got, 'é') {
+		t.Fatalf("split rune not dropped: len=%d", len(got))
+	}
+	if got := truncateOutput("a\x00b", maxExecHistoryOutput); got != "ab" {
+		t.Fatalf("NUL not dropped: %q", got)
+	}
+}
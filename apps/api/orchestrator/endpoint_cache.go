@@ -0,0 +1,113 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultEndpointCacheTTL = 30 * time.Second
+
+// EndpointCache wraps an orchestrator so resolved tenant endpoints are kept in
+// Redis under container_url:{tenantID} instead of inspecting the container on
+// every proxied request. Lifecycle calls that can move or remove the
+// container invalidate the entry.
+type EndpointCache struct {
+	TenantOrchestrator
+	redis *redis.Client
+	ttl   time.Duration
+	log   *slog.Logger
+}
+
+// NewEndpointCache wraps orch. The TTL comes from
+// CONTAINER_URL_CACHE_TTL_SECONDS and defaults to 30 seconds. A nil Redis
+// client disables caching.
+func NewEndpointCache(orch TenantOrchestrator, redisClient *redis.Client) *EndpointCache {
+	ttl := defaultEndpointCacheTTL
+	if raw := strings.TrimSpace(os.Getenv("CONTAINER_URL_CACHE_TTL_SECONDS")); raw != "" {
+		if secs, err := strconv.Atoi(raw); err == nil && secs > 0 {
+			ttl = time.Duration(secs) * time.Second
+		} else {
+			slog.Warn("ignoring invalid CONTAINER_URL_CACHE_TTL_SECONDS", "value", raw)
+		}
+	}
+	return &EndpointCache{
+		TenantOrchestrator: orch,
+		redis:              redisClient,
+		ttl:                ttl,
+		log:                slog.Default().With("component", "endpoint-cache"),
+	}
+}
+
+func endpointCacheKey(tenantID string) string {
+	return "container_url:" + tenantID
+}
+
+// Endpoint returns the cached endpoint, resolving and storing it on a miss.
+// Redis failures fall through to the wrapped orchestrator.
+func (c *EndpointCache) Endpoint(ctx context.Context, tenantID string) (*url.URL, error) {
+	if c.redis != nil {
+		cached, err := c.redis.Get(ctx, endpointCacheKey(tenantID)).Result()
+		switch {
+		case err == nil:
+			if u, perr := url.Parse(cached); perr == nil {
+				return u, nil
+			}
+		case !errors.Is(err, redis.Nil):
+			c.log.Warn("endpoint cache read failed", "tenant", tenantID, "err", err)
+		}
+	}
+
+	u, err := TenantEndpoint(ctx, c.TenantOrchestrator, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if c.redis != nil {
+		if err := c.redis.Set(ctx, endpointCacheKey(tenantID), u.String(), c.ttl).Err(); err != nil {
+			c.log.Warn("endpoint cache write failed", "tenant", tenantID, "err", err)
+		}
+	}
+	return u, nil
+}
+
+// Invalidate drops the cached endpoint for tenantID.
+func (c *EndpointCache) Invalidate(ctx context.Context, tenantID string) {
+	if c.redis == nil {
+		return
+	}
+	if err := c.redis.Del(ctx, endpointCacheKey(tenantID)).Err(); err != nil {
+		c.log.Warn("endpoint cache invalidation failed", "tenant", tenantID, "err", err)
+	}
+}
+
+// Unwrap returns the wrapped orchestrator.
+func (c *EndpointCache) Unwrap() TenantOrchestrator {
+	return c.TenantOrchestrator
+}
+
+func (c *EndpointCache) Create(ctx context.Context, tenantID string) (*Container, error) {
+	defer c.Invalidate(ctx, tenantID)
+	return c.TenantOrchestrator.Create(ctx, tenantID)
+}
+
+func (c *EndpointCache) Start(ctx context.Context, tenantID string) error {
+	defer c.Invalidate(ctx, tenantID)
+	return c.TenantOrchestrator.Start(ctx, tenantID)
+}
+
+func (c *EndpointCache) Stop(ctx context.Context, tenantID string) error {
+	defer c.Invalidate(ctx, tenantID)
+	return c.TenantOrchestrator.Stop(ctx, tenantID)
+}
+
+func (c *EndpointCache) Delete(ctx context.Context, tenantID string) error {
+	defer c.Invalidate(ctx, tenantID)
+	return c.TenantOrchestrator.Delete(ctx, tenantID)
+}
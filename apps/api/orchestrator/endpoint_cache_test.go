@@ -0,0 +1,175 @@
+package orchestrator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type resolvingOrchestrator struct {
+	testOrchestrator
+	mu    sync.Mutex
+	calls int
+}
+
+func (o *resolvingOrchestrator) Endpoint(_ context.Context, tenantID string) (*url.URL, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.calls++
+	return url.Parse(fmt.Sprintf("http://%s-%d:4200", tenantID, o.calls))
+}
+
+// fakeRedis answers the GET, SET and DEL commands the cache issues.
+func fakeRedis(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var mu sync.Mutex
+	data := map[string]string{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				rd := bufio.NewReader(conn)
+				for {
+					args, err := readRESPArray(rd)
+					if err != nil {
+						return
+					}
+					mu.Lock()
+					var reply string
+					switch strings.ToUpper(args[0]) {
+					case "GET":
+						if v, ok := data[args[1]]; ok {
+							reply = fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+						} else {
+							reply = "$-1\r\n"
+						}
+					case "SET":
+						data[args[1]] = args[2]
+						reply = "+OK\r\n"
+					case "DEL":
+						delete(data, args[1])
+						reply = ":1\r\n"
+					case "HELLO":
+						reply = "-ERR unknown command\r\n"
+					default:
+						reply = "+OK\r\n"
+					}
+					mu.Unlock()
+					if _, err := conn.Write([]byte(reply)); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func readRESPArray(rd *bufio.Reader) ([]string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if _, err := rd.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := rd.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, strings.TrimSuffix(arg, "\r\n"))
+	}
+	return args, nil
+}
+
+func TestEndpointCacheHitsAndInvalidates(t *testing.T) {
+	t.Parallel()
+	client := redis.NewClient(&redis.Options{Addr: fakeRedis(t), Protocol: 2})
+	t.Cleanup(func() { client.Close() })
+
+	inner := &resolvingOrchestrator{}
+	cache := NewEndpointCache(inner, client)
+	ctx := context.Background()
+
+	first, err := TenantEndpoint(ctx, cache, "t1")
+	if err != nil {
+		t.Fatalf("first endpoint: %v", err)
+	}
+	second, err := cache.Endpoint(ctx, "t1")
+	if err != nil {
+		t.Fatalf("second endpoint: %v", err)
+	}
+	if first.String() != second.String() || inner.calls != 1 {
+		t.Fatalf("cache miss: %s then %s after %d resolutions", first, second, inner.calls)
+	}
+
+	if err := cache.Stop(ctx, "t1"); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	third, err := cache.Endpoint(ctx, "t1")
+	if err != nil {
+		t.Fatalf("third endpoint: %v", err)
+	}
+	if third.String() == first.String() || inner.calls != 2 {
+		t.Fatalf("stop did not invalidate: %s after %d resolutions", third, inner.calls)
+	}
+}
+
+func TestEndpointCacheFallsBackWithoutRedis(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	inner := &resolvingOrchestrator{}
+	if _, err := NewEndpointCache(inner, nil).Endpoint(ctx, "t1"); err != nil || inner.calls != 1 {
+		t.Fatalf("nil redis: err=%v calls=%d", err, inner.calls)
+	}
+
+	down := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond, MaxRetries: -1})
+	t.Cleanup(func() { down.Close() })
+	if _, err := NewEndpointCache(inner, down).Endpoint(ctx, "t1"); err != nil || inner.calls != 2 {
+		t.Fatalf("unreachable redis: err=%v calls=%d", err, inner.calls)
+	}
+
+	if _, err := NewEndpointCache(testOrchestrator{}, nil).Endpoint(ctx, "t1"); err != ErrNoEndpoint {
+		t.Fatalf("err = %v, want ErrNoEndpoint", err)
+	}
+}
+
+type listingOrchestrator struct{ testOrchestrator }
+
+func (listingOrchestrator) ListTenants(context.Context) ([]TenantContainer, error) { return nil, nil }
+
+func TestCapabilityLooksThroughEndpointCache(t *testing.T) {
+	t.Parallel()
+	if _, ok := Capability[TenantLister](NewEndpointCache(listingOrchestrator{}, nil)); !ok {
+		t.Fatalf("TenantLister not found through EndpointCache")
+	}
+	if _, ok := Capability[TenantLister](NewEndpointCache(testOrchestrator{}, nil)); ok {
+		t.Fatalf("TenantLister found on an orchestrator without it")
+	}
+}
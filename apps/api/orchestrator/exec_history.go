@@ -0,0 +1,46 @@
+package orchestrator
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"strings"
+)
+
+// maxExecHistoryOutput caps the output stored per exec_history row.
+const maxExecHistoryOutput = 4 * 1024
+
+// recordExec stores an executed command for auditing. A nil exitCode means
+// the backend did not report one. Recording is best-effort and never fails
+// the exec itself.
+func recordExec(ctx context.Context, db *sql.DB, log *slog.Logger, tenantID string, cmd []string, output string, exitCode *int) {
+	if db == nil {
+		return
+	}
+	command, err := json.Marshal(cmd)
+	if err != nil {
+		log.Error("failed to encode exec command", "tenant", tenantID, "err", err)
+		return
+	}
+	var code any
+	if exitCode != nil {
+		code = *exitCode
+	}
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO exec_history (tenant_id, command_json, output_truncated, exit_code)
+		VALUES ($1, $2::jsonb, $3, $4)
+	`, tenantID, string(command), truncateOutput(output, maxExecHistoryOutput), code); err != nil {
+		log.Error("failed to record exec history", "tenant", tenantID, "err", err)
+	}
+}
+
+// truncateOutput cuts s to at most limit bytes. Invalid UTF-8, including a
+// sequence split by the cut, and NUL bytes are dropped because Postgres
+// rejects them in TEXT columns.
+func truncateOutput(s string, limit int) string {
+	if len(s) > limit {
+		s = s[:limit]
+	}
+	return strings.ReplaceAll(strings.ToValidUTF8(s, ""), "\x00", "")
+}
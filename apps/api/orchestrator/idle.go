@@ -0,0 +1,218 @@
+package orchestrator
+
+import (
+	"context"
+	"database/sql"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// idleStats counts idle-window sweeps and the containers they stop. It is
+// published through expvar at /debug/vars: sweeps, stops, stop_failures,
+// warm (active tenants inside the window at the last sweep), idle (outside
+// it) and window_s.
+var idleStats = expvar.NewMap("tenant_idle")
+
+// idleSweepInterval is how often activity is written out and idle tenants
+// are stopped.
+const idleSweepInterval = 30 * time.Second
+
+// IdleWindowFromEnv returns TENANT_IDLE_WINDOW, how long an active tenant's
+// container keeps running after its last traffic. Zero, the default, leaves
+// containers running until something else stops them.
+func IdleWindowFromEnv() time.Duration {
+	raw := strings.TrimSpace(os.Getenv("TENANT_IDLE_WINDOW"))
+	if raw == "" {
+		return 0
+	}
+	window, err := time.ParseDuration(raw)
+	if err != nil || window < 0 {
+		slog.Warn("ignoring invalid idle window", "env", "TENANT_IDLE_WINDOW", "value", raw)
+		return 0
+	}
+	return window
+}
+
+// IdleStopper keeps recently active tenants running for a sliding window
+// and stops the containers of active tenants that had no traffic for that
+// long. Traffic is recorded in memory with Touch and written to
+// tenant_activity every sweep, so every API replica sees it. A stopped
+// tenant stays active and the Waker starts it on its next request.
+type IdleStopper struct {
+	db     *sql.DB
+	orch   TenantOrchestrator
+	window time.Duration
+	log    *slog.Logger
+
+	mu      sync.Mutex
+	touched map[string]struct{}
+	// stopped holds the tenants this stopper stopped that have stayed idle,
+	// so later sweeps do not ask the backend about them again.
+	stopped map[string]bool
+}
+
+func NewIdleStopper(db *sql.DB, orch TenantOrchestrator, window time.Duration) *IdleStopper {
+	idleStats.Set("window_s", expvarInt(int64(window/time.Second)))
+	return &IdleStopper{
+		db:      db,
+		orch:    orch,
+		window:  window,
+		log:     slog.Default().With("component", "tenant-idle"),
+		touched: make(map[string]struct{}),
+		stopped: make(map[string]bool),
+	}
+}
+
+// Touch records traffic for a tenant, restarting its idle window. A nil
+// IdleStopper records nothing.
+func (s *IdleStopper) Touch(tenantID string) {
+	if s == nil || tenantID == "" {
+		return
+	}
+	s.mu.Lock()
+	s.touched[tenantID] = struct{}{}
+	delete(s.stopped, tenantID)
+	s.mu.Unlock()
+}
+
+// Start runs Sweep every idleSweepInterval until ctx is done.
+func (s *IdleStopper) Start(ctx context.Context) {
+	ticker := time.NewTicker(idleSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Sweep(ctx); err != nil {
+				s.log.Error("idle tenant sweep failed", "err", err)
+			}
+		}
+	}
+}
+
+// Sweep writes out recorded traffic and stops the running containers of
+// active tenants whose last traffic is older than the window. A tenant with
+// no recorded traffic is measured from its creation.
+func (s *IdleStopper) Sweep(ctx context.Context) error {
+	if err := s.flush(ctx); err != nil {
+		return err
+	}
+	idleStats.Add("sweeps", 1)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT t.id::text, COALESCE(a.last_active_at, t.created_at) < NOW() - $1::interval
+		FROM tenants t
+		LEFT JOIN tenant_activity a ON a.tenant_id = t.id
+		WHERE t.status = 'active' AND t.container_id IS NOT NULL
+	`, fmt.Sprintf("%d seconds", int64(s.window/time.Second)))
+	if err != nil {
+		return fmt.Errorf("list active tenants: %w", err)
+	}
+	var warm int64
+	var idle []string
+	for rows.Next() {
+		var id string
+		var isIdle bool
+		if err := rows.Scan(&id, &isIdle); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan tenant: %w", err)
+		}
+		if isIdle {
+			idle = append(idle, id)
+			continue
+		}
+		warm++
+		// Traffic through another replica brought it back.
+		s.mu.Lock()
+		delete(s.stopped, id)
+		s.mu.Unlock()
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("list active tenants: %w", err)
+	}
+	idleStats.Set("warm", expvarInt(warm))
+	idleStats.Set("idle", expvarInt(int64(len(idle))))
+
+	for _, tenantID := range idle {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		s.stopIdle(ctx, tenantID)
+	}
+	return nil
+}
+
+func (s *IdleStopper) stopIdle(ctx context.Context, tenantID string) {
+	s.mu.Lock()
+	stopped := s.stopped[tenantID]
+	s.mu.Unlock()
+	if stopped {
+		return
+	}
+	status, err := s.orch.Status(ctx, tenantID)
+	if err != nil {
+		s.log.Warn("idle tenant status check failed", "tenant", tenantID, "err", err)
+		return
+	}
+	if status != nil && status.Running {
+		if err := s.orch.Stop(ctx, tenantID); err != nil {
+			idleStats.Add("stop_failures", 1)
+			s.log.Error("stop idle tenant failed", "tenant", tenantID, "err", err)
+			return
+		}
+		idleStats.Add("stops", 1)
+		s.log.Info("stopped idle tenant", "tenant", tenantID, "window", s.window)
+	}
+	s.mu.Lock()
+	// Traffic that arrived while stopping restarts the window instead.
+	if _, touched := s.touched[tenantID]; !touched {
+		s.stopped[tenantID] = true
+	}
+	s.mu.Unlock()
+}
+
+// flush writes the tenants touched since the last sweep to tenant_activity.
+// Ids that are not tenants are dropped. On failure they are kept for the
+// next sweep.
+func (s *IdleStopper) flush(ctx context.Context) error {
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.touched))
+	for id := range s.touched {
+		ids = append(ids, id)
+	}
+	s.touched = make(map[string]struct{})
+	s.mu.Unlock()
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO tenant_activity (tenant_id, last_active_at)
+		SELECT id, NOW() FROM tenants WHERE id::text = ANY($1::text[])
+		ON CONFLICT (tenant_id) DO UPDATE SET last_active_at = EXCLUDED.last_active_at
+	`, pq.Array(ids))
+	if err != nil {
+		s.mu.Lock()
+		for _, id := range ids {
+			s.touched[id] = struct{}{}
+		}
+		s.mu.Unlock()
+		return fmt.Errorf("record tenant activity: %w", err)
+	}
+	return nil
+}
+
+func expvarInt(v int64) *expvar.Int {
+	i := new(expvar.Int)
+	i.Set(v)
+	return i
+}
@@ -0,0 +1,91 @@
+package orchestrator
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+// idleOrchestrator reports the tenants in running as running and records
+// status checks and stops.
+type idleOrchestrator struct {
+	testOrchestrator
+
+	mu       sync.Mutex
+	running  map[string]bool
+	statuses []string
+	stops    []string
+}
+
+func (o *idleOrchestrator) Status(_ context.Context, tenantID string) (*ContainerStatus, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.statuses = append(o.statuses, tenantID)
+	return &ContainerStatus{Running: o.running[tenantID]}, nil
+}
+
+func (o *idleOrchestrator) Stop(_ context.Context, tenantID string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.stops = append(o.stops, tenantID)
+	o.running[tenantID] = false
+	return nil
+}
+
+func TestIdleStopperStopsTenantsPastTheWindow(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	orch := &idleOrchestrator{running: map[string]bool{"idle-running": true, "warm": true}}
+	s := NewIdleStopper(db, orch, 30*time.Minute)
+	s.Touch("warm")
+	ctx := context.Background()
+
+	sweepRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "idle"}).
+			AddRow("idle-running", true).
+			AddRow("idle-stopped", true).
+			AddRow("warm", false)
+	}
+	mock.ExpectExec("INSERT INTO tenant_activity").WithArgs(pq.Array([]string{"warm"})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("FROM tenants t").WithArgs("1800 seconds").WillReturnRows(sweepRows())
+	if err := s.Sweep(ctx); err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	if len(orch.stops) != 1 || orch.stops[0] != "idle-running" || len(orch.statuses) != 2 {
+		t.Fatalf("first sweep stops = %v statuses = %v", orch.stops, orch.statuses)
+	}
+
+	// Tenants already found stopped are not checked again while they stay
+	// idle; traffic puts a tenant back under watch.
+	s.Touch("idle-running")
+	mock.ExpectExec("INSERT INTO tenant_activity").WithArgs(pq.Array([]string{"idle-running"})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("FROM tenants t").WithArgs("1800 seconds").WillReturnRows(sweepRows())
+	if err := s.Sweep(ctx); err != nil {
+		t.Fatalf("second Sweep: %v", err)
+	}
+	if len(orch.stops) != 1 || len(orch.statuses) != 3 || orch.statuses[2] != "idle-running" {
+		t.Fatalf("second sweep stops = %v statuses = %v", orch.stops, orch.statuses)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestIdleWindowFromEnv(t *testing.T) {
+	for raw, want := range map[string]time.Duration{"": 0, "30m": 30 * time.Minute, "soon": 0, "-5m": 0} {
+		t.Setenv("TENANT_IDLE_WINDOW", raw)
+		if got := IdleWindowFromEnv(); got != want {
+			t.Fatalf("IdleWindowFromEnv(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
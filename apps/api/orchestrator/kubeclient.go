@@ -0,0 +1,131 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	kubeFieldManager  = "agentsquads"
+)
+
+// kubeExecFunc runs cmd in a pod container and returns what it wrote to
+// stdout and stderr, and its exit code when the API server reported one.
+type kubeExecFunc func(ctx context.Context, namespace, pod, containerName string, cmd []string) (string, string, *int, error)
+
+// kubeRESTConfig builds a client config from K8S_API_URL, K8S_TOKEN and
+// K8S_CA_FILE, defaulting to the pod's in-cluster service account.
+func kubeRESTConfig() (*rest.Config, error) {
+	apiURL := strings.TrimSpace(os.Getenv("K8S_API_URL"))
+	if apiURL == "" {
+		if os.Getenv("KUBERNETES_SERVICE_HOST") == "" || os.Getenv("KUBERNETES_SERVICE_PORT") == "" {
+			return nil, errors.New("K8S_API_URL is not set and not running in a cluster")
+		}
+		apiURL = "https://" + net.JoinHostPort(os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT"))
+	}
+
+	cfg := &rest.Config{
+		Host:        apiURL,
+		BearerToken: strings.TrimSpace(os.Getenv("K8S_TOKEN")),
+	}
+	if cfg.BearerToken == "" {
+		// Projected service account tokens rotate; client-go rereads the
+		// file.
+		cfg.BearerTokenFile = serviceAccountDir + "/token"
+	}
+	caFile := strings.TrimSpace(os.Getenv("K8S_CA_FILE"))
+	if caFile == "" {
+		caFile = serviceAccountDir + "/ca.crt"
+	}
+	if _, err := os.Stat(caFile); err == nil {
+		cfg.TLSClientConfig.CAFile = caFile
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read CA file: %w", err)
+	}
+	return cfg, nil
+}
+
+// newKubeClient returns a clientset and an exec function for cfg.
+func newKubeClient(cfg *rest.Config) (kubernetes.Interface, kubeExecFunc, error) {
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	exec := func(ctx context.Context, namespace, pod, containerName string, cmd []string) (string, string, *int, error) {
+		return kubeExec(ctx, cfg, clientset, namespace, pod, containerName, cmd)
+	}
+	return clientset, exec, nil
+}
+
+// kubeExec runs cmd through the pod exec subresource, over websockets with
+// a SPDY fallback for older API servers. A non-zero exit status is not an
+// error, matching Docker exec.
+func kubeExec(ctx context.Context, cfg *rest.Config, clientset kubernetes.Interface, namespace, pod, containerName string, cmd []string) (string, string, *int, error) {
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(namespace).Name(pod).SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: containerName,
+			Command:   cmd,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	websocketExec, err := remotecommand.NewWebSocketExecutor(cfg, "GET", req.URL().String())
+	if err != nil {
+		return "", "", nil, fmt.Errorf("exec: %w", err)
+	}
+	spdyExec, err := remotecommand.NewSPDYExecutor(cfg, "POST", req.URL())
+	if err != nil {
+		return "", "", nil, fmt.Errorf("exec: %w", err)
+	}
+	executor, err := remotecommand.NewFallbackExecutor(websocketExec, spdyExec, execFallsBackToSPDY)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("exec: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr})
+	code, err := execExitCode(err)
+	if err != nil {
+		return "", "", nil, err
+	}
+	return stdout.String(), stderr.String(), code, nil
+}
+
+// execFallsBackToSPDY reports whether a websocket exec failed before the
+// command started, as kubectl decides it: the API server refused the
+// upgrade or the proxy could not carry it. Any other error may come after
+// the command ran, and running it again over SPDY would repeat it.
+func execFallsBackToSPDY(err error) bool {
+	return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+}
+
+// execExitCode interprets the error a finished exec stream returned: nil is
+// a zero exit code and a non-zero exit is reported as its code, not an
+// error.
+func execExitCode(err error) (*int, error) {
+	if err == nil {
+		code := 0
+		return &code, nil
+	}
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		code := exitErr.ExitStatus()
+		return &code, nil
+	}
+	return nil, fmt.Errorf("exec: %w", err)
+}
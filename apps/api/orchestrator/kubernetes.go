@@ -0,0 +1,395 @@
+package orchestrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/plans"
+	"github.com/docker/docker/api/types/container"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	appsv1ac "k8s.io/client-go/applyconfigurations/apps/v1"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	tenantContainerName    = "openfang"
+	tenantLabel            = "agentsquads.tenant"
+	defaultClusterDomain   = "cluster.local"
+	defaultKubeReadyWait   = 3 * time.Minute
+	kubeReadyPollInterval  = 2 * time.Second
+	tenantHealthPath       = "/healthz"
+	tenantLivenessDelaySec = 30
+)
+
+// KubernetesOrchestrator implements TenantOrchestrator by running each tenant
+// as a single-replica Deployment with a ClusterIP Service. Tenants get their
+// own namespace unless K8S_NAMESPACE puts them all in one shared namespace,
+// where they are told apart by the agentsquads.tenant label.
+type KubernetesOrchestrator struct {
+	kube kubernetes.Interface
+	exec kubeExecFunc
+	db   *sql.DB
+	log  *slog.Logger
+
+	namespace     string
+	clusterDomain string
+	readyTimeout  time.Duration
+
+	platformAPIURL string
+	platformAPIKey string
+	llmProxyURL    string
+
+	plans *plans.Resolver
+}
+
+// NewKubernetesOrchestrator creates an orchestrator that talks to the API
+// server configured by K8S_API_URL, or the in-cluster one by default.
+// K8S_NAMESPACE, K8S_CLUSTER_DOMAIN and K8S_READY_TIMEOUT tune placement,
+// service DNS and how long Create waits for the readiness probe.
+func NewKubernetesOrchestrator(db *sql.DB, platformAPIURL, platformAPIKey, llmProxyURL string) (*KubernetesOrchestrator, error) {
+	cfg, err := kubeRESTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes client: %w", err)
+	}
+	kube, exec, err := newKubeClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes client: %w", err)
+	}
+
+	o := &KubernetesOrchestrator{
+		kube:           kube,
+		exec:           exec,
+		db:             db,
+		log:            slog.Default().With("component", "orchestrator", "backend", "kubernetes"),
+		namespace:      strings.TrimSpace(os.Getenv("K8S_NAMESPACE")),
+		clusterDomain:  defaultClusterDomain,
+		readyTimeout:   defaultKubeReadyWait,
+		platformAPIURL: platformAPIURL,
+		platformAPIKey: platformAPIKey,
+		llmProxyURL:    llmProxyURL,
+	}
+	if domain := strings.TrimSpace(os.Getenv("K8S_CLUSTER_DOMAIN")); domain != "" {
+		o.clusterDomain = domain
+	}
+	if raw := strings.TrimSpace(os.Getenv("K8S_READY_TIMEOUT")); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("K8S_READY_TIMEOUT must be a positive duration")
+		}
+		o.readyTimeout = d
+	}
+	return o, nil
+}
+
+// EnforcesStorageQuota is always true: pods carry an ephemeral-storage limit.
+func (o *KubernetesOrchestrator) EnforcesStorageQuota() bool {
+	return true
+}
+
+// SetPlanResolver makes pod resources follow the tenant's plan on create.
+func (o *KubernetesOrchestrator) SetPlanResolver(resolver *plans.Resolver) {
+	o.plans = resolver
+}
+
+// tenantNamespace is the shared namespace when one is configured, otherwise
+// the tenant's own.
+func (o *KubernetesOrchestrator) tenantNamespace(tenantID string) string {
+	if o.namespace != "" {
+		return o.namespace
+	}
+	return containerName(tenantID)
+}
+
+func tenantLabels(tenantID string) map[string]string {
+	return map[string]string{
+		tenantLabel:           tenantID,
+		"agentsquads.managed": "true",
+	}
+}
+
+// applyOptions makes repeated creates converge instead of conflicting.
+var applyOptions = metav1.ApplyOptions{FieldManager: kubeFieldManager, Force: true}
+
+// kubeResources converts Docker resources to Kubernetes quantities. Requests
+// equal limits so tenants get the Guaranteed QoS class, like a fixed Docker
+// allocation. The kubelet evicts pods that write past ephemeral-storage.
+func kubeResources(res container.Resources, diskMB int64) corev1.ResourceList {
+	return corev1.ResourceList{
+		corev1.ResourceMemory:           *resource.NewQuantity(res.Memory, resource.BinarySI),
+		corev1.ResourceCPU:              *resource.NewMilliQuantity(res.CPUQuota*1000/res.CPUPeriod, resource.DecimalSI),
+		corev1.ResourceEphemeralStorage: *resource.NewQuantity(diskMB<<20, resource.BinarySI),
+	}
+}
+
+func (o *KubernetesOrchestrator) deploymentManifest(ctx context.Context, tenantID, namespace, name string) *appsv1ac.DeploymentApplyConfiguration {
+	labels := tenantLabels(tenantID)
+	probe := func() *corev1ac.ProbeApplyConfiguration {
+		return corev1ac.Probe().
+			WithHTTPGet(corev1ac.HTTPGetAction().WithPath(tenantHealthPath).WithPort(intstr.FromInt32(tenantPort))).
+			WithTimeoutSeconds(5).
+			WithFailureThreshold(3)
+	}
+	resources := kubeResources(tenantResources(ctx, o.plans, o.log, tenantID), TenantDiskMB(ctx, o.plans, tenantID))
+
+	return appsv1ac.Deployment(name, namespace).
+		WithLabels(labels).
+		WithSpec(appsv1ac.DeploymentSpec().
+			WithReplicas(1).
+			WithSelector(metav1ac.LabelSelector().WithMatchLabels(map[string]string{tenantLabel: tenantID})).
+			// Tenants keep state on local storage, so never run two at once.
+			WithStrategy(appsv1ac.DeploymentStrategy().WithType(appsv1.RecreateDeploymentStrategyType)).
+			WithTemplate(corev1ac.PodTemplateSpec().
+				WithLabels(labels).
+				WithSpec(corev1ac.PodSpec().
+					WithAutomountServiceAccountToken(false).
+					WithContainers(corev1ac.Container().
+						WithName(tenantContainerName).
+						WithImage(tenantImage).
+						WithEnv(
+							corev1ac.EnvVar().WithName("TENANT_ID").WithValue(tenantID),
+							corev1ac.EnvVar().WithName("PLATFORM_API_URL").WithValue(o.platformAPIURL),
+							corev1ac.EnvVar().WithName("PLATFORM_API_KEY").WithValue(o.platformAPIKey),
+							corev1ac.EnvVar().WithName("LLM_PROXY_URL").WithValue(o.llmProxyURL),
+						).
+						WithPorts(corev1ac.ContainerPort().WithName("openfang").WithContainerPort(tenantPort)).
+						WithResources(corev1ac.ResourceRequirements().WithLimits(resources).WithRequests(resources)).
+						WithReadinessProbe(probe().WithPeriodSeconds(5)).
+						WithLivenessProbe(probe().WithPeriodSeconds(30).WithInitialDelaySeconds(tenantLivenessDelaySec))))))
+}
+
+func serviceManifest(tenantID, namespace, name string) *corev1ac.ServiceApplyConfiguration {
+	return corev1ac.Service(name, namespace).
+		WithLabels(tenantLabels(tenantID)).
+		WithSpec(corev1ac.ServiceSpec().
+			WithType(corev1.ServiceTypeClusterIP).
+			WithSelector(map[string]string{tenantLabel: tenantID}).
+			WithPorts(corev1ac.ServicePort().WithName("openfang").WithPort(tenantPort).WithTargetPort(intstr.FromInt32(tenantPort))))
+}
+
+// serviceHost is the cluster DNS name of the tenant's Service.
+func (o *KubernetesOrchestrator) serviceHost(tenantID string) string {
+	return fmt.Sprintf("%s.%s.svc.%s", containerName(tenantID), o.tenantNamespace(tenantID), o.clusterDomain)
+}
+
+// Create applies the tenant's namespace, Deployment and Service, records the
+// Deployment name as the tenant's container id and waits for the pod to pass
+// its readiness probe.
+func (o *KubernetesOrchestrator) Create(ctx context.Context, tenantID string) (*Container, error) {
+	o.log.Info("creating deployment", "tenant", tenantID)
+	name := containerName(tenantID)
+	namespace := o.tenantNamespace(tenantID)
+
+	if o.namespace == "" {
+		ns := corev1ac.Namespace(namespace).WithLabels(tenantLabels(tenantID))
+		if _, err := o.kube.CoreV1().Namespaces().Apply(ctx, ns, applyOptions); err != nil {
+			return nil, fmt.Errorf("namespace apply: %w", err)
+		}
+	}
+	if _, err := o.kube.AppsV1().Deployments(namespace).Apply(ctx, o.deploymentManifest(ctx, tenantID, namespace, name), applyOptions); err != nil {
+		return nil, fmt.Errorf("deployment apply: %w", err)
+	}
+	if _, err := o.kube.CoreV1().Services(namespace).Apply(ctx, serviceManifest(tenantID, namespace, name), applyOptions); err != nil {
+		return nil, fmt.Errorf("service apply: %w", err)
+	}
+
+	if _, err := o.db.ExecContext(ctx,
+		"UPDATE tenants SET container_id = $1 WHERE id = $2",
+		name, tenantID,
+	); err != nil {
+		return nil, fmt.Errorf("db update: %w", err)
+	}
+
+	if err := o.waitReady(ctx, namespace, name); err != nil {
+		return nil, err
+	}
+
+	o.log.Info("deployment ready", "tenant", tenantID, "namespace", namespace, "deployment", name)
+	return &Container{
+		ID:       name,
+		TenantID: tenantID,
+		Status:   "running",
+		IP:       o.serviceHost(tenantID),
+		Port:     tenantPort,
+	}, nil
+}
+
+// waitReady polls the Deployment until a replica is ready or readyTimeout
+// passes.
+func (o *KubernetesOrchestrator) waitReady(ctx context.Context, namespace, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, o.readyTimeout)
+	defer cancel()
+	ticker := time.NewTicker(kubeReadyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		dep, err := o.kube.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("deployment get: %w", err)
+		}
+		if err == nil && dep.Status.ReadyReplicas > 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("deployment %s/%s not ready after %s", namespace, name, o.readyTimeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o *KubernetesOrchestrator) scale(ctx context.Context, tenantID string, replicas int) error {
+	patch := []byte(`{"spec":{"replicas":` + strconv.Itoa(replicas) + `}}`)
+	_, err := o.kube.AppsV1().Deployments(o.tenantNamespace(tenantID)).Patch(ctx, containerName(tenantID), types.MergePatchType, patch, metav1.PatchOptions{})
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("no container for tenant")
+	}
+	return err
+}
+
+// Start scales the tenant's Deployment back to one replica.
+func (o *KubernetesOrchestrator) Start(ctx context.Context, tenantID string) error {
+	o.log.Info("starting deployment", "tenant", tenantID)
+	return o.scale(ctx, tenantID, 1)
+}
+
+// Stop scales the tenant's Deployment to zero, keeping its spec.
+func (o *KubernetesOrchestrator) Stop(ctx context.Context, tenantID string) error {
+	o.log.Info("stopping deployment", "tenant", tenantID)
+	return o.scale(ctx, tenantID, 0)
+}
+
+// Delete removes the tenant's namespace, or its Deployment and Service in a
+// shared namespace, and clears the container id.
+func (o *KubernetesOrchestrator) Delete(ctx context.Context, tenantID string) error {
+	o.log.Info("deleting deployment", "tenant", tenantID)
+	name := containerName(tenantID)
+	namespace := o.tenantNamespace(tenantID)
+	foreground := metav1.DeletePropagationForeground
+	opts := metav1.DeleteOptions{PropagationPolicy: &foreground}
+
+	if o.namespace == "" {
+		if err := o.kube.CoreV1().Namespaces().Delete(ctx, namespace, opts); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("delete namespace %s: %w", namespace, err)
+		}
+	} else {
+		if err := o.kube.CoreV1().Services(namespace).Delete(ctx, name, opts); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("delete service %s/%s: %w", namespace, name, err)
+		}
+		if err := o.kube.AppsV1().Deployments(namespace).Delete(ctx, name, opts); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("delete deployment %s/%s: %w", namespace, name, err)
+		}
+	}
+
+	if _, err := o.db.ExecContext(ctx,
+		"UPDATE tenants SET container_id = NULL WHERE id = $1", tenantID,
+	); err != nil {
+		return fmt.Errorf("db update: %w", err)
+	}
+	return nil
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// runningPod returns the tenant's running pod, or nil when there is none.
+func (o *KubernetesOrchestrator) runningPod(ctx context.Context, tenantID string) (*corev1.Pod, error) {
+	pods, err := o.kube.CoreV1().Pods(o.tenantNamespace(tenantID)).List(ctx, metav1.ListOptions{
+		LabelSelector: tenantLabel + "=" + tenantID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("pod list: %w", err)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil {
+			return pod, nil
+		}
+	}
+	return nil, nil
+}
+
+// Status reports the tenant's pod state. Health is healthy when the pod
+// passes its readiness probe, starting until the ready timeout has passed
+// since it started, and unhealthy after that.
+func (o *KubernetesOrchestrator) Status(ctx context.Context, tenantID string) (*ContainerStatus, error) {
+	if _, err := o.kube.AppsV1().Deployments(o.tenantNamespace(tenantID)).Get(ctx, containerName(tenantID), metav1.GetOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("no container for tenant")
+		}
+		return nil, fmt.Errorf("deployment get: %w", err)
+	}
+
+	status := &ContainerStatus{Health: "unknown"}
+	pod, err := o.runningPod(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if pod == nil {
+		return status, nil
+	}
+
+	status.Running = true
+	if pod.Status.StartTime != nil {
+		status.StartedAt = pod.Status.StartTime.Time
+	}
+	switch {
+	case podReady(pod):
+		status.Health = "healthy"
+	case time.Since(status.StartedAt) < o.readyTimeout:
+		status.Health = "starting"
+	default:
+		status.Health = "unhealthy"
+	}
+	return status, nil
+}
+
+// Exec runs a command in the tenant's pod through the pod exec API and
+// returns the output.
+func (o *KubernetesOrchestrator) Exec(ctx context.Context, tenantID string, cmd []string) (string, error) {
+	pod, err := o.runningPod(ctx, tenantID)
+	if err != nil {
+		return "", err
+	}
+	if pod == nil {
+		return "", fmt.Errorf("no running pod for tenant")
+	}
+
+	o.log.Info("exec in pod", "tenant", tenantID, "pod", pod.Name, "cmd", cmd)
+	stdout, stderr, exitCode, err := o.exec(ctx, o.tenantNamespace(tenantID), pod.Name, tenantContainerName, cmd)
+	if err != nil {
+		return "", err
+	}
+	if stderr != "" {
+		stdout += "\n" + stderr
+	}
+	recordExec(ctx, o.db, o.log, tenantID, cmd, stdout, exitCode)
+	return stdout, nil
+}
+
+// Endpoint returns the tenant's Service DNS name, which stays stable across
+// pod restarts and rescheduling.
+func (o *KubernetesOrchestrator) Endpoint(_ context.Context, tenantID string) (*url.URL, error) {
+	return &url.URL{Scheme: "http", Host: net.JoinHostPort(o.serviceHost(tenantID), strconv.Itoa(tenantPort))}, nil
+}
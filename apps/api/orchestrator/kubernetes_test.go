@@ -0,0 +1,238 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/docker/docker/api/types/container"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// kubeActions lists the clientset's calls as "verb resource".
+func kubeActions(clientset *fake.Clientset) []string {
+	var out []string
+	for _, action := range clientset.Actions() {
+		out = append(out, action.GetVerb()+" "+action.GetResource().Resource)
+	}
+	return out
+}
+
+func TestKubeResources(t *testing.T) {
+	t.Parallel()
+	got := kubeResources(container.Resources{Memory: 512 * 1024 * 1024, CPUQuota: 150000, CPUPeriod: cpuPeriod}, 2048)
+	memory, cpu, disk := got[corev1.ResourceMemory], got[corev1.ResourceCPU], got[corev1.ResourceEphemeralStorage]
+	if memory.String() != "512Mi" || cpu.String() != "1500m" || disk.String() != "2Gi" {
+		t.Fatalf("resources = %v", got)
+	}
+}
+
+func TestKubernetesCreatePerTenantNamespace(t *testing.T) {
+	t.Parallel()
+	clientset := fake.NewClientset()
+	clientset.PrependReactor("get", "deployments", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &appsv1.Deployment{Status: appsv1.DeploymentStatus{ReadyReplicas: 1}}, nil
+	})
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	const tenantID = "0123456789abcdef"
+	name := containerName(tenantID)
+	mock.ExpectExec("UPDATE tenants SET container_id").WithArgs(name, tenantID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	o := &KubernetesOrchestrator{kube: clientset, db: db, log: testLogger(), clusterDomain: defaultClusterDomain, readyTimeout: time.Second}
+	c, err := o.Create(context.Background(), tenantID)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if c.ID != name || c.IP != name+"."+name+".svc.cluster.local" || c.Port != tenantPort {
+		t.Fatalf("container = %+v", c)
+	}
+
+	want := []string{"patch namespaces", "patch deployments", "patch services", "get deployments"}
+	if got := kubeActions(clientset); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("actions = %v, want %v", got, want)
+	}
+	apply := clientset.Actions()[1].(k8stesting.PatchAction)
+	if apply.GetPatchType() != "application/apply-patch+yaml" || apply.GetNamespace() != name {
+		t.Fatalf("deployment apply = %s in %s", apply.GetPatchType(), apply.GetNamespace())
+	}
+	dep := o.deploymentManifest(context.Background(), tenantID, name, name)
+	ctr := dep.Spec.Template.Spec.Containers[0]
+	if *ctr.Image != tenantImage || *ctr.ReadinessProbe.HTTPGet.Path != tenantHealthPath || *dep.Spec.Strategy.Type != appsv1.RecreateDeploymentStrategyType {
+		t.Fatalf("deployment = %+v", dep.Spec)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+
+	endpoint, err := o.Endpoint(context.Background(), tenantID)
+	if err != nil || endpoint.String() != "http://"+name+"."+name+".svc.cluster.local:4200" {
+		t.Fatalf("endpoint = %v err=%v", endpoint, err)
+	}
+}
+
+func TestKubernetesStopAndStatusSharedNamespace(t *testing.T) {
+	t.Parallel()
+	name := containerName("t1")
+	replicas := int32(1)
+	clientset := fake.NewClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "tenants"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "p1", Namespace: "tenants", Labels: map[string]string{tenantLabel: "t1"}},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				StartTime:  &metav1.Time{Time: time.Now().Add(-time.Hour)},
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "tenants", Labels: map[string]string{tenantLabel: "t2"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+	)
+	o := &KubernetesOrchestrator{kube: clientset, log: testLogger(), namespace: "tenants", readyTimeout: time.Minute}
+
+	if err := o.Stop(context.Background(), "t1"); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	dep, err := clientset.AppsV1().Deployments("tenants").Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil || *dep.Spec.Replicas != 0 {
+		t.Fatalf("deployment after stop = %+v err=%v", dep, err)
+	}
+
+	status, err := o.Status(context.Background(), "t1")
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if !status.Running || status.Health != "unhealthy" || status.StartedAt.IsZero() {
+		t.Fatalf("status = %+v", status)
+	}
+
+	if _, err := o.Status(context.Background(), "t2"); err == nil || !strings.Contains(err.Error(), "no container") {
+		t.Fatalf("missing deployment err = %v", err)
+	}
+	if err := o.Start(context.Background(), "t2"); err == nil || !strings.Contains(err.Error(), "no container") {
+		t.Fatalf("missing deployment start err = %v", err)
+	}
+}
+
+func TestKubernetesExec(t *testing.T) {
+	t.Parallel()
+	clientset := fake.NewClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "p1", Namespace: "tenants", Labels: map[string]string{tenantLabel: "t1"}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	})
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mock.ExpectExec("INSERT INTO exec_history").
+		WithArgs("t1", `["ls","-la"]`, "file.txt\nwarning", 0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	exec := func(_ context.Context, namespace, pod, containerName string, cmd []string) (string, string, *int, error) {
+		if namespace != "tenants" || pod != "p1" || containerName != tenantContainerName || strings.Join(cmd, " ") != "ls -la" {
+			t.Errorf("exec %s/%s/%s %v", namespace, pod, containerName, cmd)
+		}
+		code := 0
+		return "file.txt", "warning", &code, nil
+	}
+	o := &KubernetesOrchestrator{kube: clientset, exec: exec, db: db, log: testLogger(), namespace: "tenants"}
+	out, err := o.Exec(context.Background(), "t1", []string{"ls", "-la"})
+	if err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if out != "file.txt\nwarning" {
+		t.Fatalf("output = %q", out)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+
+	if _, err := o.Exec(context.Background(), "t2", []string{"ls"}); err == nil || !strings.Contains(err.Error(), "no running pod") {
+		t.Fatalf("exec without pod err = %v", err)
+	}
+}
+
+func TestExecExitCode(t *testing.T) {
+	t.Parallel()
+	if code, err := execExitCode(nil); err != nil || code == nil || *code != 0 {
+		t.Fatalf("success = %v, %v", code, err)
+	}
+	code, err := execExitCode(utilexec.CodeExitError{Err: errors.New("command terminated with exit code 2"), Code: 2})
+	if err != nil || code == nil || *code != 2 {
+		t.Fatalf("non-zero exit = %v, %v; want 2 and no error", code, err)
+	}
+	if _, err := execExitCode(errors.New("stream reset")); err == nil {
+		t.Fatalf("expected error for a broken stream")
+	}
+}
+
+// countingExecutor fails every stream with err and counts the attempts.
+type countingExecutor struct {
+	err   error
+	calls int
+}
+
+func (e *countingExecutor) Stream(remotecommand.StreamOptions) error {
+	return e.StreamWithContext(context.Background(), remotecommand.StreamOptions{})
+}
+
+func (e *countingExecutor) StreamWithContext(context.Context, remotecommand.StreamOptions) error {
+	e.calls++
+	return e.err
+}
+
+func TestExecFallsBackToSPDYOnlyBeforeTheCommandStarts(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name     string
+		err      error
+		fallback bool
+	}{
+		{name: "upgrade refused", err: &httpstream.UpgradeFailureError{Cause: errors.New("403 Forbidden")}, fallback: true},
+		{name: "https proxy", err: errors.New("proxy: unknown scheme: https"), fallback: true},
+		{name: "stream broke mid-command", err: errors.New("websocket: close 1006 (abnormal closure): unexpected EOF")},
+		{name: "cancelled", err: context.Canceled},
+	} {
+		websocketExec := &countingExecutor{err: tc.err}
+		spdyExec := &countingExecutor{}
+		executor, err := remotecommand.NewFallbackExecutor(websocketExec, spdyExec, execFallsBackToSPDY)
+		if err != nil {
+			t.Fatalf("NewFallbackExecutor: %v", err)
+		}
+		err = executor.StreamWithContext(context.Background(), remotecommand.StreamOptions{})
+		if retried := spdyExec.calls > 0; retried != tc.fallback || websocketExec.calls != 1 {
+			t.Fatalf("%s: spdy calls = %d, websocket calls = %d", tc.name, spdyExec.calls, websocketExec.calls)
+		}
+		if !tc.fallback && !errors.Is(err, tc.err) {
+			t.Fatalf("%s: err = %v", tc.name, err)
+		}
+	}
+}
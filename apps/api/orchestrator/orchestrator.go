@@ -2,6 +2,9 @@ package orchestrator
 
 import (
 	"context"
+	"errors"
+	"io"
+	"net/url"
 	"time"
 )
 
@@ -24,11 +27,177 @@ type Container struct {
 	Port     int    `json:"port"`
 }
 
-// ContainerStatus holds runtime info about a tenant container.
+// ContainerStatus holds runtime info about a tenant container. MemoryMB,
+// MemoryLimitMB and CPUPct are only meaningful when SampledAt is set, as
+// resource usage is skipped when the backend is slow to report it. The
+// OpenFang fields are empty when its health endpoint did not answer.
 type ContainerStatus struct {
-	Running   bool      `json:"running"`
-	StartedAt time.Time `json:"started_at"`
-	Health    string    `json:"health"` // healthy, unhealthy, starting
-	MemoryMB  int64     `json:"memory_mb"`
-	CPUPct    float64   `json:"cpu_pct"`
+	Running       bool      `json:"running"`
+	StartedAt     time.Time `json:"started_at"`
+	Health        string    `json:"health"` // healthy, unhealthy, starting
+	MemoryMB      int64     `json:"memory_mb"`
+	MemoryLimitMB int64     `json:"memory_limit_mb"`
+	CPUPct        float64   `json:"cpu_pct"`
+	SampledAt     time.Time `json:"sampled_at,omitempty"`
+	RestartCount  int       `json:"restart_count"`
+	OOMKilled     bool      `json:"oom_killed"`
+
+	OpenFangVersion       string `json:"openfang_version,omitempty"`
+	OpenFangUptimeSeconds int64  `json:"openfang_uptime_seconds,omitempty"`
+}
+
+// TenantContainer is one tenant container as reported by ListTenants.
+type TenantContainer struct {
+	TenantID    string  `json:"tenant_id"`
+	ContainerID string  `json:"container_id"`
+	Status      string  `json:"status"` // running, exited, created, ...
+	Port        int     `json:"port"`
+	Healthy     bool    `json:"healthy"`
+	MemoryMB    int64   `json:"memory_mb"`
+	CPUPct      float64 `json:"cpu_pct"`
+}
+
+// TenantLister is implemented by orchestrators that can report every tenant
+// container in a single backend call.
+type TenantLister interface {
+	ListTenants(ctx context.Context) ([]TenantContainer, error)
+}
+
+// ContainerPort is one port a tenant container exposes. HostPort is nil when
+// the port is not published on the host.
+type ContainerPort struct {
+	ContainerPort int    `json:"container_port"`
+	HostPort      *int   `json:"host_port"`
+	Protocol      string `json:"protocol"`
+	Binding       string `json:"binding"`
+}
+
+// ErrContainerNotRunning is returned by ContainerPorts for a stopped
+// container, whose port bindings are not known.
+var ErrContainerNotRunning = errors.New("container is not running")
+
+// PortLister is implemented by orchestrators that can report the ports a
+// running tenant container exposes.
+type PortLister interface {
+	ContainerPorts(ctx context.Context, tenantID string) ([]ContainerPort, error)
+}
+
+// ErrContainerNotFound is returned when the backend has no container for a
+// tenant that has one recorded.
+var ErrContainerNotFound = errors.New("tenant container not found")
+
+// ContainerNetwork is the network topology of a tenant container.
+type ContainerNetwork struct {
+	Networks []NetworkAttachment `json:"networks"`
+	Hostname string              `json:"hostname"`
+	Ports    []PublishedPort     `json:"ports"`
+}
+
+// NetworkAttachment is one network a tenant container is attached to.
+type NetworkAttachment struct {
+	Name       string `json:"name"`
+	IPAddress  string `json:"ip_address"`
+	Gateway    string `json:"gateway"`
+	MacAddress string `json:"mac_address"`
+	NetworkID  string `json:"network_id"`
+}
+
+// PublishedPort is a container port published on a host port.
+type PublishedPort struct {
+	Container int `json:"container"`
+	Host      int `json:"host"`
+}
+
+// NetworkInspector is implemented by orchestrators that can report a tenant
+// container's networks. running reports whether the container is running.
+type NetworkInspector interface {
+	ContainerNetwork(ctx context.Context, tenantID string) (topology ContainerNetwork, running bool, err error)
+}
+
+// ContainerEvent is a lifecycle event of a tenant container. Status is the
+// state the event leaves the container in, e.g. "running" or "exited (137)".
+type ContainerEvent struct {
+	Action string `json:"action"`
+	Time   int64  `json:"time"`
+	Status string `json:"status"`
+}
+
+// EventStreamer is implemented by orchestrators that can stream a tenant
+// container's lifecycle events. Both channels stay open until ctx is done; a
+// value on the error channel ends the stream.
+type EventStreamer interface {
+	ContainerEvents(ctx context.Context, tenantID string) (<-chan ContainerEvent, <-chan error, error)
+}
+
+var (
+	// ErrContainerRunning is returned by ExportFilesystem for a running
+	// container, which cannot be exported consistently.
+	ErrContainerRunning = errors.New("container is running")
+	// ErrFilesystemTooLarge is returned by ExportFilesystem when the
+	// container filesystem is over the requested limit.
+	ErrFilesystemTooLarge = errors.New("container filesystem is too large")
+)
+
+// FilesystemExporter is implemented by orchestrators that can export a
+// stopped tenant container's filesystem as a tar stream. size is the
+// backend's estimate of the filesystem size in bytes.
+type FilesystemExporter interface {
+	ExportFilesystem(ctx context.Context, tenantID string, maxBytes int64) (export io.ReadCloser, size int64, err error)
+}
+
+// FileCopier is implemented by orchestrators that can unpack a tar archive
+// into a directory of a tenant container.
+type FileCopier interface {
+	CopyToContainer(ctx context.Context, tenantID, dir string, archive io.Reader) error
+}
+
+// Relabeler is implemented by orchestrators that can change a tenant
+// container's labels. A nil value in patch removes the label. It returns the
+// container id, which changes when the backend has to replace the container.
+type Relabeler interface {
+	Relabel(ctx context.Context, tenantID string, patch map[string]*string) (string, error)
+}
+
+// Capability returns orch as T, looking through wrappers such as
+// EndpointCache that embed another orchestrator, so optional interfaces
+// like TenantLister are still found once the backend is wrapped.
+func Capability[T any](orch TenantOrchestrator) (T, bool) {
+	for orch != nil {
+		if c, ok := orch.(T); ok {
+			return c, true
+		}
+		w, ok := orch.(interface{ Unwrap() TenantOrchestrator })
+		if !ok {
+			break
+		}
+		orch = w.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// StorageQuotaEnforcer is implemented by orchestrators that can tell whether
+// the runtime itself caps tenant disk usage.
+type StorageQuotaEnforcer interface {
+	EnforcesStorageQuota() bool
+}
+
+// ErrNoEndpoint is returned by TenantEndpoint when the orchestrator cannot
+// locate tenant servers.
+var ErrNoEndpoint = errors.New("orchestrator does not resolve tenant endpoints")
+
+// EndpointResolver is implemented by orchestrators that know where a tenant's
+// OpenFang server can be reached from the API.
+type EndpointResolver interface {
+	Endpoint(ctx context.Context, tenantID string) (*url.URL, error)
+}
+
+// TenantEndpoint returns the base URL of the tenant's OpenFang server. The
+// hands, chat and events proxies use it so they work on every backend.
+func TenantEndpoint(ctx context.Context, orch TenantOrchestrator, tenantID string) (*url.URL, error) {
+	resolver, ok := orch.(EndpointResolver)
+	if !ok || resolver == nil {
+		return nil, ErrNoEndpoint
+	}
+	return resolver.Endpoint(ctx, tenantID)
 }
@@ -0,0 +1,165 @@
+package orchestrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// wakeStats counts on-demand tenant starts. It is published through expvar
+// at /debug/vars: starts, failures, in_progress and last_start_ms.
+var wakeStats = expvar.NewMap("tenant_wake")
+
+const (
+	// DefaultWakeEstimate is how long a start is expected to take before any
+	// start has been measured.
+	DefaultWakeEstimate = 20 * time.Second
+
+	wakeTimeout      = 2 * time.Minute
+	wakePollInterval = time.Second
+)
+
+// ErrTenantNotActive is returned by Wake for a paused or suspended tenant,
+// whose container is stopped on purpose.
+var ErrTenantNotActive = errors.New("tenant is not active")
+
+// WakeProgress is where a tenant's container is on its way to serving
+// traffic. Ready is set once it is running; otherwise Elapsed is how long the
+// start has been going and Estimate how long starts usually take.
+type WakeProgress struct {
+	Ready    bool
+	Elapsed  time.Duration
+	Estimate time.Duration
+}
+
+// Waker starts the stopped container of an active tenant when traffic
+// arrives for it. Starts run in the background and are shared, so a burst
+// of requests starts a tenant once and each caller can report progress
+// instead of failing.
+type Waker struct {
+	db   *sql.DB
+	orch TenantOrchestrator
+	log  *slog.Logger
+
+	pollInterval time.Duration
+	now          func() time.Time
+
+	mu       sync.Mutex
+	starting map[string]time.Time // tenant id -> start time
+	estimate time.Duration
+}
+
+func NewWaker(db *sql.DB, orch TenantOrchestrator) *Waker {
+	return &Waker{
+		db:           db,
+		orch:         orch,
+		log:          slog.Default().With("component", "tenant-wake"),
+		pollInterval: wakePollInterval,
+		now:          time.Now,
+		starting:     make(map[string]time.Time),
+		estimate:     DefaultWakeEstimate,
+	}
+}
+
+// Wake reports whether the tenant's container is ready and starts it when
+// it is stopped. Only the status check runs under ctx; the start itself
+// outlives the request that triggered it.
+func (w *Waker) Wake(ctx context.Context, tenantID string) (WakeProgress, error) {
+	if progress, ok := w.progress(tenantID); ok {
+		return progress, nil
+	}
+	status, err := w.orch.Status(ctx, tenantID)
+	if err != nil {
+		return WakeProgress{}, err
+	}
+	if status != nil && status.Running {
+		return WakeProgress{Ready: true}, nil
+	}
+
+	var tenantStatus string
+	err = w.db.QueryRowContext(ctx, `SELECT status FROM tenants WHERE id = $1`, tenantID).Scan(&tenantStatus)
+	if errors.Is(err, sql.ErrNoRows) {
+		return WakeProgress{}, fmt.Errorf("tenant not found")
+	}
+	if err != nil {
+		return WakeProgress{}, fmt.Errorf("load tenant status: %w", err)
+	}
+	if tenantStatus != "active" {
+		return WakeProgress{}, ErrTenantNotActive
+	}
+
+	w.mu.Lock()
+	if _, ok := w.starting[tenantID]; !ok {
+		began := w.now()
+		w.starting[tenantID] = began
+		wakeStats.Add("starts", 1)
+		wakeStats.Add("in_progress", 1)
+		go w.start(tenantID, began)
+	}
+	w.mu.Unlock()
+	progress, _ := w.progress(tenantID)
+	return progress, nil
+}
+
+func (w *Waker) progress(tenantID string) (WakeProgress, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	began, ok := w.starting[tenantID]
+	if !ok {
+		return WakeProgress{}, false
+	}
+	return WakeProgress{Elapsed: w.now().Sub(began), Estimate: w.estimate}, true
+}
+
+func (w *Waker) start(tenantID string, began time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), wakeTimeout)
+	defer cancel()
+	w.log.Info("starting tenant on traffic", "tenant", tenantID)
+	err := w.orch.Start(ctx, tenantID)
+	if err == nil {
+		err = w.waitRunning(ctx, tenantID)
+	}
+
+	took := w.now().Sub(began)
+	w.mu.Lock()
+	delete(w.starting, tenantID)
+	if err == nil {
+		// Smooth the estimate so one slow start does not dominate it.
+		w.estimate = (w.estimate + took) / 2
+	}
+	w.mu.Unlock()
+
+	wakeStats.Add("in_progress", -1)
+	if err != nil {
+		wakeStats.Add("failures", 1)
+		w.log.Error("tenant start on traffic failed", "tenant", tenantID, "err", err)
+		return
+	}
+	last := new(expvar.Int)
+	last.Set(took.Milliseconds())
+	wakeStats.Set("last_start_ms", last)
+	w.log.Info("tenant started on traffic", "tenant", tenantID, "took_ms", took.Milliseconds())
+}
+
+// waitRunning polls until the container runs and its health check, if it
+// has one, no longer reports starting.
+func (w *Waker) waitRunning(ctx context.Context, tenantID string) error {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+	for {
+		status, err := w.orch.Status(ctx, tenantID)
+		if err == nil && status != nil && status.Running && status.Health != "starting" {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait for tenant container: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// startingOrchestrator reports a container as stopped until Start is called,
+// and Start blocks until release is closed.
+type startingOrchestrator struct {
+	testOrchestrator
+	release chan struct{}
+
+	mu      sync.Mutex
+	starts  int
+	running bool
+}
+
+func (o *startingOrchestrator) Start(context.Context, string) error {
+	<-o.release
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.starts++
+	o.running = true
+	return nil
+}
+
+func (o *startingOrchestrator) Status(context.Context, string) (*ContainerStatus, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return &ContainerStatus{Running: o.running, Health: "healthy"}, nil
+}
+
+func TestWakerStartsStoppedTenantOnce(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mock.ExpectQuery("SELECT status FROM tenants").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("active"))
+
+	orch := &startingOrchestrator{release: make(chan struct{})}
+	w := NewWaker(db, orch)
+	w.pollInterval = time.Millisecond
+	ctx := context.Background()
+
+	first, err := w.Wake(ctx, "t1")
+	if err != nil || first.Ready || first.Estimate != DefaultWakeEstimate {
+		t.Fatalf("first Wake = %+v, %v", first, err)
+	}
+	// A second request while the start is in flight joins it.
+	if second, err := w.Wake(ctx, "t1"); err != nil || second.Ready {
+		t.Fatalf("second Wake = %+v, %v", second, err)
+	}
+	close(orch.release)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		progress, err := w.Wake(ctx, "t1")
+		if err != nil {
+			t.Fatalf("Wake: %v", err)
+		}
+		if progress.Ready {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("tenant never became ready")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	orch.mu.Lock()
+	defer orch.mu.Unlock()
+	if orch.starts != 1 {
+		t.Fatalf("starts = %d, want 1", orch.starts)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestWakerLeavesPausedTenantStopped(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mock.ExpectQuery("SELECT status FROM tenants").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("paused"))
+
+	orch := &startingOrchestrator{release: make(chan struct{})}
+	if _, err := NewWaker(db, orch).Wake(context.Background(), "t1"); !errors.Is(err, ErrTenantNotActive) {
+		t.Fatalf("Wake err = %v, want ErrTenantNotActive", err)
+	}
+	if orch.starts != 0 {
+		t.Fatalf("paused tenant was started")
+	}
+}
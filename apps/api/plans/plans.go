@@ -0,0 +1,312 @@
+// Package plans resolves the subscription tier attached to a tenant and the
+// limits it implies (swarm size, container resources, LLM rate and token caps).
+package plans
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultCacheTTL = 30 * time.Second
+
+var (
+	// ErrPlanNotFound is returned when a plan id does not exist.
+	ErrPlanNotFound = errors.New("plan not found")
+	// ErrTenantNotFound is returned when assigning a plan to an unknown tenant.
+	ErrTenantNotFound = errors.New("tenant not found")
+)
+
+// Plan describes the limits granted to tenants on a tier. Zero values mean
+// "no plan-specific limit" and callers fall back to platform defaults.
+type Plan struct {
+	ID                string  `json:"id"`
+	Name              string  `json:"name"`
+	MaxSwarmAgents    int     `json:"max_swarm_agents"`
+	ContainerMemoryMB int     `json:"container_memory_mb"`
+	ContainerCPU      float64 `json:"container_cpu"`
+	ContainerDiskMB   int     `json:"container_disk_mb"`
+	RPMLimit          int     `json:"rpm_limit"`
+	MonthlyTokenCap   int64   `json:"monthly_token_cap"`
+	// MaxConcurrentRequests caps the tenant's in-flight LLM proxy calls. 0
+	// means the proxy default.
+	MaxConcurrentRequests int             `json:"max_concurrent_requests"`
+	Features              map[string]bool `json:"features"`
+	CreatedAt             time.Time       `json:"created_at"`
+	UpdatedAt             time.Time       `json:"updated_at"`
+}
+
+// HasFeature reports whether the plan enables the named feature flag.
+func (p *Plan) HasFeature(name string) bool {
+	if p == nil {
+		return false
+	}
+	return p.Features[name]
+}
+
+type cachedPlan struct {
+	plan      *Plan
+	expiresAt time.Time
+}
+
+// Resolver loads plans from Postgres and caches the tenant -> plan mapping for
+// a short TTL so plan changes take effect without restarts.
+type Resolver struct {
+	db  *sql.DB
+	ttl time.Duration
+	now func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedPlan
+}
+
+// NewResolver creates a plan resolver backed by db.
+func NewResolver(db *sql.DB) *Resolver {
+	return &Resolver{
+		db:    db,
+		ttl:   defaultCacheTTL,
+		now:   time.Now,
+		cache: make(map[string]cachedPlan),
+	}
+}
+
+// ForTenant returns the tenant's plan, or nil when the tenant has no plan
+// assigned.
+func (r *Resolver) ForTenant(ctx context.Context, tenantID string) (*Plan, error) {
+	if r == nil || r.db == nil {
+		return nil, nil
+	}
+	tenantID = strings.TrimSpace(tenantID)
+	if tenantID == "" {
+		return nil, nil
+	}
+
+	r.mu.Lock()
+	if entry, ok := r.cache[tenantID]; ok && r.now().Before(entry.expiresAt) {
+		r.mu.Unlock()
+		return entry.plan, nil
+	}
+	r.mu.Unlock()
+
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+planColumns("p")+`
+		FROM tenants t
+		JOIN plans p ON p.id = t.plan_id
+		WHERE t.id = $1
+	`, tenantID)
+	plan, err := scanPlan(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		plan, err = nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("resolve tenant plan: %w", err)
+	}
+
+	r.mu.Lock()
+	r.cache[tenantID] = cachedPlan{plan: plan, expiresAt: r.now().Add(r.ttl)}
+	r.mu.Unlock()
+	return plan, nil
+}
+
+// Invalidate drops the cached plan for a tenant.
+func (r *Resolver) Invalidate(tenantID string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	delete(r.cache, strings.TrimSpace(tenantID))
+	r.mu.Unlock()
+}
+
+// InvalidateAll drops every cached tenant plan, e.g. after a plan is edited.
+func (r *Resolver) InvalidateAll() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.cache = make(map[string]cachedPlan)
+	r.mu.Unlock()
+}
+
+// List returns all plans ordered by name.
+func (r *Resolver) List(ctx context.Context) ([]*Plan, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+planColumns("p")+` FROM plans p ORDER BY p.name ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list plans: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]*Plan, 0)
+	for rows.Next() {
+		plan, err := scanPlan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan plan: %w", err)
+		}
+		out = append(out, plan)
+	}
+	return out, rows.Err()
+}
+
+// Get returns a single plan by id.
+func (r *Resolver) Get(ctx context.Context, id string) (*Plan, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+planColumns("p")+` FROM plans p WHERE p.id = $1`, id)
+	plan, err := scanPlan(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPlanNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get plan: %w", err)
+	}
+	return plan, nil
+}
+
+// Create inserts a plan and returns the stored row.
+func (r *Resolver) Create(ctx context.Context, p Plan) (*Plan, error) {
+	features, err := marshalFeatures(p.Features)
+	if err != nil {
+		return nil, err
+	}
+	row := r.db.QueryRowContext(ctx, `
+		INSERT INTO plans (name, max_swarm_agents, container_memory_mb, container_cpu, container_disk_mb, rpm_limit, monthly_token_cap, max_concurrent_requests, features)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::jsonb)
+		RETURNING `+planColumns("plans"),
+		p.Name, p.MaxSwarmAgents, p.ContainerMemoryMB, p.ContainerCPU, p.ContainerDiskMB, p.RPMLimit, p.MonthlyTokenCap, p.MaxConcurrentRequests, features,
+	)
+	plan, err := scanPlan(row)
+	if err != nil {
+		return nil, fmt.Errorf("create plan: %w", err)
+	}
+	return plan, nil
+}
+
+// Update replaces a plan's limits and invalidates cached tenant lookups.
+func (r *Resolver) Update(ctx context.Context, p Plan) (*Plan, error) {
+	features, err := marshalFeatures(p.Features)
+	if err != nil {
+		return nil, err
+	}
+	row := r.db.QueryRowContext(ctx, `
+		UPDATE plans
+		SET name = $2,
+			max_swarm_agents = $3,
+			container_memory_mb = $4,
+			container_cpu = $5,
+			container_disk_mb = $6,
+			rpm_limit = $7,
+			monthly_token_cap = $8,
+			max_concurrent_requests = $9,
+			features = $10::jsonb,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+planColumns("plans"),
+		p.ID, p.Name, p.MaxSwarmAgents, p.ContainerMemoryMB, p.ContainerCPU, p.ContainerDiskMB, p.RPMLimit, p.MonthlyTokenCap, p.MaxConcurrentRequests, features,
+	)
+	plan, err := scanPlan(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPlanNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update plan: %w", err)
+	}
+	r.InvalidateAll()
+	return plan, nil
+}
+
+// Delete removes a plan. Tenants on it fall back to platform defaults.
+func (r *Resolver) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM plans WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete plan: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrPlanNotFound
+	}
+	r.InvalidateAll()
+	return nil
+}
+
+// AssignTenant moves a tenant onto a plan. An empty planID clears the plan.
+func (r *Resolver) AssignTenant(ctx context.Context, tenantID, planID string) error {
+	var planArg any
+	if strings.TrimSpace(planID) != "" {
+		planArg = strings.TrimSpace(planID)
+	}
+	res, err := r.db.ExecContext(ctx, `UPDATE tenants SET plan_id = $2 WHERE id = $1`, tenantID, planArg)
+	if err != nil {
+		return fmt.Errorf("assign tenant plan: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrTenantNotFound
+	}
+	r.Invalidate(tenantID)
+	return nil
+}
+
+// MonthlyTokenUsage sums the tenant's input and output tokens for the billing
+// period containing now.
+func (r *Resolver) MonthlyTokenUsage(ctx context.Context, tenantID string, now time.Time) (int64, error) {
+	start, _ := BillingPeriod(now)
+	var used int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(input_tokens + output_tokens), 0)
+		FROM usage_logs
+		WHERE tenant_id = $1 AND created_at >= $2
+	`, tenantID, start).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("monthly token usage: %w", err)
+	}
+	return used, nil
+}
+
+// BillingPeriod returns the UTC calendar month containing now as
+// [start, renewal).
+func BillingPeriod(now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func planColumns(alias string) string {
+	cols := []string{"id", "name", "max_swarm_agents", "container_memory_mb", "container_cpu", "container_disk_mb", "rpm_limit", "monthly_token_cap", "max_concurrent_requests", "features", "created_at", "updated_at"}
+	for i, c := range cols {
+		cols[i] = alias + "." + c
+	}
+	return strings.Join(cols, ", ")
+}
+
+func scanPlan(row rowScanner) (*Plan, error) {
+	var (
+		p        Plan
+		features []byte
+	)
+	if err := row.Scan(&p.ID, &p.Name, &p.MaxSwarmAgents, &p.ContainerMemoryMB, &p.ContainerCPU, &p.ContainerDiskMB, &p.RPMLimit, &p.MonthlyTokenCap, &p.MaxConcurrentRequests, &features, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	p.Features = map[string]bool{}
+	if len(features) > 0 {
+		if err := json.Unmarshal(features, &p.Features); err != nil {
+			return nil, fmt.Errorf("decode plan features: %w", err)
+		}
+	}
+	return &p, nil
+}
+
+func marshalFeatures(features map[string]bool) (string, error) {
+	if features == nil {
+		features = map[string]bool{}
+	}
+	raw, err := json.Marshal(features)
+	if err != nil {
+		return "", fmt.Errorf("encode plan features: %w", err)
+	}
+	return string(raw), nil
+}
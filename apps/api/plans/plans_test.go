@@ -0,0 +1,84 @@
+package plans
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var planRowColumns = []string{"id", "name", "max_swarm_agents", "container_memory_mb", "container_cpu", "container_disk_mb", "rpm_limit", "monthly_token_cap", "max_concurrent_requests", "features", "created_at", "updated_at"}
+
+func TestResolverForTenantCachesPlan(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM tenants t").WithArgs("t1").WillReturnRows(
+		sqlmock.NewRows(planRowColumns).AddRow("p1", "pro", 8, 1024, 1.5, 10240, 60, int64(1_000_000), 6, []byte(`{"swarm":true}`), now, now),
+	)
+
+	r := NewResolver(db)
+	r.now = func() time.Time { return now }
+
+	for range 2 {
+		plan, err := r.ForTenant(context.Background(), "t1")
+		if err != nil {
+			t.Fatalf("ForTenant() err = %v", err)
+		}
+		if plan == nil || plan.Name != "pro" || plan.MaxSwarmAgents != 8 || !plan.HasFeature("swarm") {
+			t.Fatalf("unexpected plan: %+v", plan)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+
+	r.Invalidate("t1")
+	mock.ExpectQuery("FROM tenants t").WithArgs("t1").WillReturnRows(sqlmock.NewRows(planRowColumns))
+	plan, err := r.ForTenant(context.Background(), "t1")
+	if err != nil || plan != nil {
+		t.Fatalf("ForTenant() after invalidate = %+v, %v; want nil, nil", plan, err)
+	}
+}
+
+func TestResolverNilSafe(t *testing.T) {
+	t.Parallel()
+	var r *Resolver
+	plan, err := r.ForTenant(context.Background(), "t1")
+	if plan != nil || err != nil {
+		t.Fatalf("nil resolver returned %+v, %v", plan, err)
+	}
+	r.Invalidate("t1")
+	r.InvalidateAll()
+}
+
+func TestAssignTenantNotFound(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mock.ExpectExec("UPDATE tenants SET plan_id").WithArgs("t1", "p1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := NewResolver(db).AssignTenant(context.Background(), "t1", "p1"); err != ErrTenantNotFound {
+		t.Fatalf("AssignTenant() err = %v, want ErrTenantNotFound", err)
+	}
+}
+
+func TestBillingPeriod(t *testing.T) {
+	t.Parallel()
+	start, renewal := BillingPeriod(time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC))
+	if !start.Equal(time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("start = %v", start)
+	}
+	if !renewal.Equal(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("renewal = %v", renewal)
+	}
+}
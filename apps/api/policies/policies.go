@@ -0,0 +1,260 @@
+// Package policies reads per-tenant feature flags from tenant_policies.
+package policies
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Feature names stored in the feature_policy enum.
+const (
+	FeatureSwarm              = "swarm"
+	FeatureTerminal           = "terminal"
+	FeatureDeploy             = "deploy"
+	FeatureTelegram           = "telegram"
+	FeatureWhatsApp           = "whatsapp"
+	FeatureViber              = "viber"
+	FeatureWebchat            = "webchat"
+	FeatureCatalog            = "catalog"
+	FeatureCustomSubtaskSpec  = "custom_subtask_spec"
+	FeatureExtendedTimeout    = "extended_timeout"
+	FeatureTaskClarification  = "task_clarification"
+	FeatureOutboundFilter     = "outbound_filter"
+	FeatureOutboundModeration = "outbound_moderation"
+	FeatureDBQuery            = "db_query"
+	FeatureWorkflows          = "workflows_enabled"
+	// FeatureTelegramGroupAlwaysRespond routes every Telegram group message to
+	// the agent instead of only those addressed to the bot.
+	FeatureTelegramGroupAlwaysRespond = "telegram_group_always_respond"
+	// FeatureStrictModelDeprecation fails requests for retired model ids
+	// instead of serving them with the replacement model.
+	FeatureStrictModelDeprecation = "strict_model_deprecation"
+	// FeatureWebhookTool gives agents the webhook_call tool, limited to the
+	// domains in the policy's settings.
+	FeatureWebhookTool = "webhook_tool"
+	// FeatureInternalFetch lets the tenant's tool requests reach the internal
+	// hosts and ranges in the policy's settings.
+	FeatureInternalFetch = "internal_fetch"
+	// FeatureModeration checks inbound channel messages against the tenant's
+	// tenant_moderation_rules.
+	FeatureModeration = "moderation_enabled"
+)
+
+const defaultCacheTTL = 15 * time.Second
+
+var (
+	// ErrUnknownFeature is returned when writing a feature outside the
+	// feature_policy enum.
+	ErrUnknownFeature = errors.New("unknown feature")
+	// ErrTenantNotFound is returned when writing a policy for an unknown tenant.
+	ErrTenantNotFound = errors.New("tenant not found")
+)
+
+// denials counts blocked requests per feature. It is published through expvar
+// so policy denials show up in /debug/vars.
+var denials = expvar.NewMap("policy_denials")
+
+// KnownFeature reports whether feature is a value of the feature_policy enum.
+func KnownFeature(feature string) bool {
+	switch feature {
+	case FeatureSwarm, FeatureTerminal, FeatureDeploy, FeatureTelegram, FeatureWhatsApp, FeatureViber,
+		FeatureWebchat, FeatureCatalog, FeatureCustomSubtaskSpec, FeatureExtendedTimeout, FeatureTaskClarification,
+		FeatureOutboundFilter, FeatureOutboundModeration, FeatureDBQuery, FeatureWorkflows,
+		FeatureTelegramGroupAlwaysRespond, FeatureStrictModelDeprecation, FeatureWebhookTool,
+		FeatureInternalFetch, FeatureModeration:
+		return true
+	default:
+		return false
+	}
+}
+
+// RecordDenial logs and counts a request blocked by a tenant policy.
+// component names the enforcement point, e.g. "coordinator" or "terminal".
+func RecordDenial(ctx context.Context, tenantID, feature, component string) {
+	denials.Add(feature, 1)
+	slog.WarnContext(ctx, "tenant policy denied request",
+		"tenant", tenantID,
+		"feature", feature,
+		"component", component,
+	)
+}
+
+type cachedPolicy struct {
+	enabled   bool
+	expiresAt time.Time
+}
+
+// Store looks up tenant feature policies. Lookups are cached for a short TTL;
+// writes through Set invalidate the tenant's entries immediately, and other
+// API instances pick changes up once their TTL lapses.
+type Store struct {
+	db  *sql.DB
+	ttl time.Duration
+	now func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedPolicy
+}
+
+// NewStore creates a policy store backed by db.
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db:    db,
+		ttl:   defaultCacheTTL,
+		now:   time.Now,
+		cache: make(map[string]cachedPolicy),
+	}
+}
+
+func cacheKey(tenantID, feature string) string {
+	return tenantID + "|" + feature
+}
+
+// Get reports whether the tenant has the feature enabled. A missing policy
+// row counts as disabled.
+func (s *Store) Get(ctx context.Context, tenantID, feature string) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, errors.New("database is not configured")
+	}
+	tenantID = strings.TrimSpace(tenantID)
+	feature = strings.TrimSpace(feature)
+	key := cacheKey(tenantID, feature)
+
+	s.mu.Lock()
+	if entry, ok := s.cache[key]; ok && s.now().Before(entry.expiresAt) {
+		s.mu.Unlock()
+		return entry.enabled, nil
+	}
+	s.mu.Unlock()
+
+	var enabled bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT enabled
+		FROM tenant_policies
+		WHERE tenant_id = $1 AND feature::text = $2
+	`, tenantID, feature).Scan(&enabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		enabled, err = false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("load tenant policy: %w", err)
+	}
+
+	s.mu.Lock()
+	s.cache[key] = cachedPolicy{enabled: enabled, expiresAt: s.now().Add(s.ttl)}
+	s.mu.Unlock()
+	return enabled, nil
+}
+
+// Checker reports whether a tenant feature policy is enabled. Store
+// implements it; packages that gate on policies take a Checker.
+type Checker interface {
+	FeatureEnabled(ctx context.Context, tenantID, feature string) (bool, error)
+}
+
+// FeatureEnabled is Get under the name Checker uses.
+func (s *Store) FeatureEnabled(ctx context.Context, tenantID, feature string) (bool, error) {
+	return s.Get(ctx, tenantID, feature)
+}
+
+// Set enables or disables a feature for a tenant and drops the cached value.
+func (s *Store) Set(ctx context.Context, tenantID, feature string, enabled bool) error {
+	if s == nil || s.db == nil {
+		return errors.New("database is not configured")
+	}
+	tenantID = strings.TrimSpace(tenantID)
+	feature = strings.TrimSpace(feature)
+	if !KnownFeature(feature) {
+		return fmt.Errorf("%w: %q", ErrUnknownFeature, feature)
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO tenant_policies (tenant_id, feature, enabled)
+		SELECT t.id, $2::feature_policy, $3
+		FROM tenants t
+		WHERE t.id = $1
+		ON CONFLICT (tenant_id, feature) DO UPDATE
+		SET enabled = EXCLUDED.enabled
+	`, tenantID, feature, enabled)
+	if err != nil {
+		return fmt.Errorf("save tenant policy: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrTenantNotFound
+	}
+	s.Invalidate(tenantID)
+	return nil
+}
+
+// Invalidate drops every cached policy for a tenant.
+func (s *Store) Invalidate(tenantID string) {
+	if s == nil {
+		return
+	}
+	prefix := cacheKey(strings.TrimSpace(tenantID), "")
+	s.mu.Lock()
+	for key := range s.cache {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.cache, key)
+		}
+	}
+	s.mu.Unlock()
+}
+
+// Settings returns the JSON settings stored with the tenant's feature policy,
+// or nil when the tenant has no policy row for it. Settings are not cached.
+func (s *Store) Settings(ctx context.Context, tenantID, feature string) (json.RawMessage, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("database is not configured")
+	}
+	var settings []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT settings
+		FROM tenant_policies
+		WHERE tenant_id = $1 AND feature::text = $2
+	`, strings.TrimSpace(tenantID), strings.TrimSpace(feature)).Scan(&settings)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load tenant policy settings: %w", err)
+	}
+	return settings, nil
+}
+
+// SetSettings replaces the settings of the tenant's feature policy. A tenant
+// without a policy row for the feature gets a disabled one.
+func (s *Store) SetSettings(ctx context.Context, tenantID, feature string, settings json.RawMessage) error {
+	if s == nil || s.db == nil {
+		return errors.New("database is not configured")
+	}
+	tenantID = strings.TrimSpace(tenantID)
+	feature = strings.TrimSpace(feature)
+	if !KnownFeature(feature) {
+		return fmt.Errorf("%w: %q", ErrUnknownFeature, feature)
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO tenant_policies (tenant_id, feature, enabled, settings)
+		SELECT t.id, $2::feature_policy, FALSE, $3::jsonb
+		FROM tenants t
+		WHERE t.id = $1
+		ON CONFLICT (tenant_id, feature) DO UPDATE
+		SET settings = EXCLUDED.settings
+	`, tenantID, feature, string(settings))
+	if err != nil {
+		return fmt.Errorf("save tenant policy settings: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrTenantNotFound
+	}
+	return nil
+}
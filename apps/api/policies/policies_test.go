@@ -0,0 +1,148 @@
+package policies
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestStoreFeatureEnabled(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		setup   func(sqlmock.Sqlmock)
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "enabled",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT enabled").WithArgs("t1", FeatureSwarm).WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(true))
+			},
+			want: true,
+		},
+		{
+			name: "missing row is disabled",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT enabled").WithArgs("t1", FeatureSwarm).WillReturnError(sql.ErrNoRows)
+			},
+		},
+		{
+			name: "db error",
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT enabled").WithArgs("t1", FeatureSwarm).WillReturnError(errors.New("boom"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New: %v", err)
+			}
+			defer db.Close()
+			tt.setup(mock)
+
+			got, err := NewStore(db).FeatureEnabled(context.Background(), "t1", FeatureSwarm)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FeatureEnabled() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("FeatureEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreGetCachesUntilSet(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewStore(db)
+	store.now = func() time.Time { return now }
+
+	mock.ExpectQuery("SELECT enabled").WithArgs("t1", FeatureSwarm).WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(true))
+	for i := 0; i < 2; i++ {
+		if ok, err := store.Get(context.Background(), "t1", FeatureSwarm); err != nil || !ok {
+			t.Fatalf("Get() = %v, %v", ok, err)
+		}
+	}
+
+	mock.ExpectExec("INSERT INTO tenant_policies").WithArgs("t1", FeatureSwarm, false).WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := store.Set(context.Background(), "t1", FeatureSwarm, false); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	mock.ExpectQuery("SELECT enabled").WithArgs("t1", FeatureSwarm).WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(false))
+	if ok, err := store.Get(context.Background(), "t1", FeatureSwarm); err != nil || ok {
+		t.Fatalf("Get() after Set = %v, %v", ok, err)
+	}
+
+	now = now.Add(defaultCacheTTL)
+	mock.ExpectQuery("SELECT enabled").WithArgs("t1", FeatureSwarm).WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(true))
+	if ok, err := store.Get(context.Background(), "t1", FeatureSwarm); err != nil || !ok {
+		t.Fatalf("Get() after TTL = %v, %v", ok, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestStoreSetErrors(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	store := NewStore(db)
+
+	if err := store.Set(context.Background(), "t1", "teleport", true); !errors.Is(err, ErrUnknownFeature) {
+		t.Fatalf("Set(unknown) err = %v", err)
+	}
+
+	mock.ExpectExec("INSERT INTO tenant_policies").WithArgs("missing", FeatureDeploy, true).WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := store.Set(context.Background(), "missing", FeatureDeploy, true); !errors.Is(err, ErrTenantNotFound) {
+		t.Fatalf("Set(missing tenant) err = %v", err)
+	}
+}
+
+func TestStoreSettings(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	store := NewStore(db)
+
+	mock.ExpectQuery("SELECT settings").WithArgs("t1", FeatureWebhookTool).WillReturnRows(sqlmock.NewRows([]string{"settings"}).AddRow([]byte(`{"webhook_allowed_domains":["api.example.com"]}`)))
+	if got, err := store.Settings(context.Background(), "t1", FeatureWebhookTool); err != nil || string(got) != `{"webhook_allowed_domains":["api.example.com"]}` {
+		t.Fatalf("Settings() = %s, %v", got, err)
+	}
+	mock.ExpectQuery("SELECT settings").WithArgs("t2", FeatureWebhookTool).WillReturnError(sql.ErrNoRows)
+	if got, err := store.Settings(context.Background(), "t2", FeatureWebhookTool); err != nil || got != nil {
+		t.Fatalf("Settings(no row) = %s, %v", got, err)
+	}
+
+	mock.ExpectExec("INSERT INTO tenant_policies").WithArgs("missing", FeatureWebhookTool, `{}`).WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := store.SetSettings(context.Background(), "missing", FeatureWebhookTool, []byte(`{}`)); !errors.Is(err, ErrTenantNotFound) {
+		t.Fatalf("SetSettings(missing tenant) err = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,340 @@
+// Package prompts stores admin-managed, versioned prompt templates such as
+// the swarm decomposition prompt and per-agent system prompts.
+package prompts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Template names the platform reads.
+const (
+	// Decomposition is the swarm task decomposition prompt. {{task}} is
+	// replaced with the task; see DecompositionVariables and Render.
+	Decomposition = "decomposition"
+	// AgentPrefix prefixes per-agent-type system prompts, e.g. "agent.coder".
+	AgentPrefix = "agent."
+)
+
+const defaultCacheTTL = 30 * time.Second
+
+var (
+	ErrNotFound       = errors.New("prompt template not found")
+	ErrTenantNotFound = errors.New("tenant not found")
+	ErrActiveVersion  = errors.New("the active version cannot be deleted")
+	ErrInvalidName    = errors.New("template name must be 1-64 lowercase letters, digits, '.', '_' or '-'")
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// ValidName reports whether name can be used as a template name.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// AgentName is the template name of an agent type's system prompt.
+func AgentName(agentID string) string {
+	return AgentPrefix + strings.ToLower(strings.TrimSpace(agentID))
+}
+
+// Template is one version of a prompt template. TenantID is empty for the
+// platform default.
+type Template struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	TenantID    string     `json:"tenant_id,omitempty"`
+	Version     int        `json:"version"`
+	Content     string     `json:"content"`
+	Active      bool       `json:"active"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ActivatedAt *time.Time `json:"activated_at,omitempty"`
+}
+
+// Ref records which template version produced a prompt.
+type Ref struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+	Scope   string `json:"scope"` // platform, tenant
+}
+
+// Ref identifies t for run records.
+func (t Template) Ref() Ref {
+	scope := "platform"
+	if t.TenantID != "" {
+		scope = "tenant"
+	}
+	return Ref{Name: t.Name, Version: t.Version, Scope: scope}
+}
+
+type cachedTemplate struct {
+	template  Template
+	found     bool
+	expiresAt time.Time
+}
+
+// Store reads and versions prompt templates. Resolved templates are cached
+// for a short TTL; writes drop the name's cached entries immediately, and
+// other API instances pick changes up once their TTL lapses.
+type Store struct {
+	db  *sql.DB
+	ttl time.Duration
+	now func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedTemplate
+}
+
+// NewStore creates a template store backed by db.
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db:    db,
+		ttl:   defaultCacheTTL,
+		now:   time.Now,
+		cache: make(map[string]cachedTemplate),
+	}
+}
+
+const templateColumns = `id, name, tenant_id, version, content, active, created_by, created_at, activated_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTemplate(row rowScanner) (Template, error) {
+	var (
+		t           Template
+		tenantID    sql.NullString
+		createdBy   sql.NullString
+		activatedAt sql.NullTime
+	)
+	if err := row.Scan(&t.ID, &t.Name, &tenantID, &t.Version, &t.Content, &t.Active, &createdBy, &t.CreatedAt, &activatedAt); err != nil {
+		return Template{}, err
+	}
+	t.TenantID = tenantID.String
+	t.CreatedBy = createdBy.String
+	if activatedAt.Valid {
+		t.ActivatedAt = &activatedAt.Time
+	}
+	return t, nil
+}
+
+// tenantArg maps an empty tenant id to NULL, the platform layer.
+func tenantArg(tenantID string) sql.NullString {
+	tenantID = strings.TrimSpace(tenantID)
+	return sql.NullString{String: tenantID, Valid: tenantID != ""}
+}
+
+// Resolve returns the active template for name, preferring the tenant's
+// override over the platform default. found is false when neither layer has
+// an active version.
+func (s *Store) Resolve(ctx context.Context, tenantID, name string) (Template, bool, error) {
+	if s == nil || s.db == nil {
+		return Template{}, false, errors.New("database is not configured")
+	}
+	tenantID = strings.TrimSpace(tenantID)
+	key := tenantID + "|" + name
+
+	s.mu.Lock()
+	if entry, ok := s.cache[key]; ok && s.now().Before(entry.expiresAt) {
+		s.mu.Unlock()
+		return entry.template, entry.found, nil
+	}
+	s.mu.Unlock()
+
+	t, err := scanTemplate(s.db.QueryRowContext(ctx, `
+		SELECT `+templateColumns+`
+		FROM prompt_templates
+		WHERE name = $1 AND active AND (tenant_id IS NULL OR tenant_id = $2::uuid)
+		ORDER BY tenant_id IS NULL
+		LIMIT 1
+	`, name, tenantArg(tenantID)))
+	found := err == nil
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	if err != nil {
+		return Template{}, false, fmt.Errorf("resolve prompt template: %w", err)
+	}
+
+	s.mu.Lock()
+	s.cache[key] = cachedTemplate{template: t, found: found, expiresAt: s.now().Add(s.ttl)}
+	s.mu.Unlock()
+	return t, found, nil
+}
+
+// List returns every version in a tenant layer (the platform layer when
+// tenantID is empty), newest first. An empty name lists all templates.
+func (s *Store) List(ctx context.Context, tenantID, name string) ([]Template, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("database is not configured")
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+templateColumns+`
+		FROM prompt_templates
+		WHERE tenant_id IS NOT DISTINCT FROM $1::uuid AND ($2 = '' OR name = $2)
+		ORDER BY name, version DESC
+	`, tenantArg(tenantID), name)
+	if err != nil {
+		return nil, fmt.Errorf("list prompt templates: %w", err)
+	}
+	defer rows.Close()
+
+	list := []Template{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan prompt template: %w", err)
+		}
+		list = append(list, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate prompt templates: %w", err)
+	}
+	return list, nil
+}
+
+// CreateVersion saves content as the next version of name in the tenant
+// layer. New versions are inactive until activated.
+func (s *Store) CreateVersion(ctx context.Context, tenantID, name, content, createdBy string) (Template, error) {
+	if s == nil || s.db == nil {
+		return Template{}, errors.New("database is not configured")
+	}
+	if !ValidName(name) {
+		return Template{}, ErrInvalidName
+	}
+	if name == Decomposition {
+		if err := ValidateDecomposition(content); err != nil {
+			return Template{}, err
+		}
+	}
+	t, err := scanTemplate(s.db.QueryRowContext(ctx, `
+		INSERT INTO prompt_templates (name, tenant_id, version, content, created_by)
+		SELECT $1, $2::uuid, (
+			SELECT COALESCE(MAX(version), 0) + 1
+			FROM prompt_templates
+			WHERE name = $1 AND tenant_id IS NOT DISTINCT FROM $2::uuid
+		), $3, NULLIF($4, '')
+		WHERE $2::uuid IS NULL OR EXISTS (SELECT 1 FROM tenants WHERE id = $2::uuid)
+		RETURNING `+templateColumns,
+		name, tenantArg(tenantID), content, createdBy))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Template{}, ErrTenantNotFound
+	}
+	if err != nil {
+		return Template{}, fmt.Errorf("create prompt template: %w", err)
+	}
+	return t, nil
+}
+
+// Activate makes version the tenant layer's active version of name, which
+// is also how a bad rollout is rolled back.
+func (s *Store) Activate(ctx context.Context, tenantID, name string, version int) (Template, error) {
+	if s == nil || s.db == nil {
+		return Template{}, errors.New("database is not configured")
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Template{}, fmt.Errorf("begin activate prompt template: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE prompt_templates
+		SET active = FALSE
+		WHERE name = $1 AND tenant_id IS NOT DISTINCT FROM $2::uuid AND active AND version <> $3
+	`, name, tenantArg(tenantID), version); err != nil {
+		return Template{}, fmt.Errorf("deactivate prompt template: %w", err)
+	}
+	t, err := scanTemplate(tx.QueryRowContext(ctx, `
+		UPDATE prompt_templates
+		SET active = TRUE, activated_at = NOW()
+		WHERE name = $1 AND tenant_id IS NOT DISTINCT FROM $2::uuid AND version = $3
+		RETURNING `+templateColumns,
+		name, tenantArg(tenantID), version))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Template{}, ErrNotFound
+	}
+	if err != nil {
+		return Template{}, fmt.Errorf("activate prompt template: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return Template{}, fmt.Errorf("commit activate prompt template: %w", err)
+	}
+	s.Invalidate(name)
+	return t, nil
+}
+
+// Deactivate clears the tenant layer's active version of name so the
+// platform default (or the built-in prompt) applies again.
+func (s *Store) Deactivate(ctx context.Context, tenantID, name string) error {
+	if s == nil || s.db == nil {
+		return errors.New("database is not configured")
+	}
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE prompt_templates
+		SET active = FALSE
+		WHERE name = $1 AND tenant_id IS NOT DISTINCT FROM $2::uuid AND active
+	`, name, tenantArg(tenantID))
+	if err != nil {
+		return fmt.Errorf("deactivate prompt template: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	s.Invalidate(name)
+	return nil
+}
+
+// DeleteVersion removes an inactive version.
+func (s *Store) DeleteVersion(ctx context.Context, tenantID, name string, version int) error {
+	if s == nil || s.db == nil {
+		return errors.New("database is not configured")
+	}
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM prompt_templates
+		WHERE name = $1 AND tenant_id IS NOT DISTINCT FROM $2::uuid AND version = $3 AND NOT active
+	`, name, tenantArg(tenantID), version)
+	if err != nil {
+		return fmt.Errorf("delete prompt template: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	// Nothing was deleted: either the version does not exist or it is active.
+	var active bool
+	err = s.db.QueryRowContext(ctx, `
+		SELECT active
+		FROM prompt_templates
+		WHERE name = $1 AND tenant_id IS NOT DISTINCT FROM $2::uuid AND version = $3
+	`, name, tenantArg(tenantID), version).Scan(&active)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("load prompt template: %w", err)
+	}
+	return ErrActiveVersion
+}
+
+// Invalidate drops every cached resolution of name.
+func (s *Store) Invalidate(name string) {
+	if s == nil {
+		return
+	}
+	suffix := "|" + name
+	s.mu.Lock()
+	for key := range s.cache {
+		if strings.HasSuffix(key, suffix) {
+			delete(s.cache, key)
+		}
+	}
+	s.mu.Unlock()
+}
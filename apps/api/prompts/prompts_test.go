@@ -0,0 +1,130 @@
+package prompts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+const tenantID = "6f1c2b8e-4d10-4c6e-9a55-3f1c2b8e4d10"
+
+var columns = []string{"id", "name", "tenant_id", "version", "content", "active", "created_by", "created_at", "activated_at"}
+
+func newStore(t *testing.T) (*Store, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewStore(db), mock
+}
+
+func TestResolvePrefersTenantAndCaches(t *testing.T) {
+	t.Parallel()
+	store, mock := newStore(t)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	mock.ExpectQuery("FROM prompt_templates").WithArgs(Decomposition, tenantID).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("p2", Decomposition, tenantID, 2, "tenant prompt", true, "admin", now, now))
+	mock.ExpectQuery("FROM prompt_templates").WithArgs(Decomposition, nil).WillReturnError(sql.ErrNoRows)
+
+	for range 2 {
+		tpl, found, err := store.Resolve(context.Background(), tenantID, Decomposition)
+		if err != nil || !found || tpl.Content != "tenant prompt" || tpl.Ref() != (Ref{Name: Decomposition, Version: 2, Scope: "tenant"}) {
+			t.Fatalf("Resolve = %+v %v %v", tpl, found, err)
+		}
+	}
+	if _, found, err := store.Resolve(context.Background(), "", Decomposition); err != nil || found {
+		t.Fatalf("platform Resolve found=%v err=%v", found, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestCreateVersion(t *testing.T) {
+	t.Parallel()
+	store, mock := newStore(t)
+	now := time.Now()
+	mock.ExpectQuery("INSERT INTO prompt_templates").WithArgs("agent.coder", nil, "You write Go.", "admin").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("p1", "agent.coder", nil, 4, "You write Go.", false, "admin", now, nil))
+	mock.ExpectQuery("INSERT INTO prompt_templates").WithArgs("agent.coder", tenantID, "x", "").WillReturnError(sql.ErrNoRows)
+
+	tpl, err := store.CreateVersion(context.Background(), "", "agent.coder", "You write Go.", "admin")
+	if err != nil || tpl.Version != 4 || tpl.Active || tpl.TenantID != "" {
+		t.Fatalf("CreateVersion = %+v %v", tpl, err)
+	}
+	if _, err := store.CreateVersion(context.Background(), tenantID, "agent.coder", "x", ""); !errors.Is(err, ErrTenantNotFound) {
+		t.Fatalf("unknown tenant err = %v", err)
+	}
+	if _, err := store.CreateVersion(context.Background(), "", "Bad Name", "x", ""); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("invalid name err = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestActivateInvalidatesCache(t *testing.T) {
+	t.Parallel()
+	store, mock := newStore(t)
+	now := time.Now()
+	store.cache[tenantID+"|"+Decomposition] = cachedTemplate{template: Template{Version: 1}, found: true, expiresAt: now.Add(time.Hour)}
+	store.cache[tenantID+"|agent.coder"] = cachedTemplate{found: false, expiresAt: now.Add(time.Hour)}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SET active = FALSE").WithArgs(Decomposition, nil, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SET active = TRUE").WithArgs(Decomposition, nil, 1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("p1", Decomposition, nil, 1, "v1", true, nil, now, now))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("SET active = FALSE").WithArgs(Decomposition, nil, 9).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SET active = TRUE").WithArgs(Decomposition, nil, 9).WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	tpl, err := store.Activate(context.Background(), "", Decomposition, 1)
+	if err != nil || !tpl.Active || tpl.ActivatedAt == nil {
+		t.Fatalf("Activate = %+v %v", tpl, err)
+	}
+	if _, ok := store.cache[tenantID+"|"+Decomposition]; ok {
+		t.Fatalf("tenant cache entry survived platform activation")
+	}
+	if _, ok := store.cache[tenantID+"|agent.coder"]; !ok {
+		t.Fatalf("unrelated cache entry dropped")
+	}
+	if _, err := store.Activate(context.Background(), "", Decomposition, 9); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing version err = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestDeleteVersion(t *testing.T) {
+	t.Parallel()
+	store, mock := newStore(t)
+	mock.ExpectExec("DELETE FROM prompt_templates").WithArgs(Decomposition, nil, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM prompt_templates").WithArgs(Decomposition, nil, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT active").WithArgs(Decomposition, nil, 2).WillReturnRows(sqlmock.NewRows([]string{"active"}).AddRow(true))
+	mock.ExpectExec("DELETE FROM prompt_templates").WithArgs(Decomposition, nil, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT active").WithArgs(Decomposition, nil, 3).WillReturnError(sql.ErrNoRows)
+
+	if err := store.DeleteVersion(context.Background(), "", Decomposition, 1); err != nil {
+		t.Fatalf("DeleteVersion: %v", err)
+	}
+	if err := store.DeleteVersion(context.Background(), "", Decomposition, 2); !errors.Is(err, ErrActiveVersion) {
+		t.Fatalf("active version err = %v", err)
+	}
+	if err := store.DeleteVersion(context.Background(), "", Decomposition, 3); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing version err = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package prompts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxVariableLength is the most runes of a variable value a rendered prompt
+// keeps. Longer values are cut and end with TruncationMarker.
+const MaxVariableLength = 8000
+
+// TruncationMarker ends a variable value that was cut to MaxVariableLength.
+const TruncationMarker = "…[truncated]"
+
+var (
+	ErrTemplateSyntax  = errors.New("malformed template placeholder")
+	ErrUnknownVariable = errors.New("unknown template variable")
+)
+
+// DecompositionVariables are the variables a decomposition prompt may use.
+var DecompositionVariables = []string{"task"}
+
+var variablePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z0-9_-]+)*$`)
+
+// placeholder is one {{name}} or {{name | json}} in a template. The json
+// filter escapes the value for use inside a JSON string literal; without it
+// the value is inserted as plain text.
+type placeholder struct {
+	start, end int // byte offsets of the whole {{...}}
+	name       string
+	json       bool
+}
+
+func parsePlaceholders(content string) ([]placeholder, error) {
+	var (
+		list []placeholder
+		pos  int
+	)
+	for {
+		open := strings.Index(content[pos:], "{{")
+		if open < 0 {
+			break
+		}
+		open += pos
+		closing := strings.Index(content[open+2:], "}}")
+		if closing < 0 {
+			return nil, fmt.Errorf("%w: unclosed {{ at offset %d", ErrTemplateSyntax, open)
+		}
+		end := open + 2 + closing + 2
+		name, filter, _ := strings.Cut(content[open+2:end-2], "|")
+		name, filter = strings.TrimSpace(name), strings.TrimSpace(filter)
+		if !variablePattern.MatchString(name) {
+			return nil, fmt.Errorf("%w: %q", ErrTemplateSyntax, content[open:end])
+		}
+		if filter != "" && filter != "json" && filter != "text" {
+			return nil, fmt.Errorf("%w: unknown filter %q in %q", ErrTemplateSyntax, filter, content[open:end])
+		}
+		list = append(list, placeholder{start: open, end: end, name: name, json: filter == "json"})
+		pos = end
+	}
+	return list, nil
+}
+
+// Variables returns the variable names content refers to, in order.
+func Variables(content string) ([]string, error) {
+	list, err := parsePlaceholders(content)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(list))
+	for _, p := range list {
+		names = append(names, p.name)
+	}
+	return names, nil
+}
+
+// ValidateTemplate checks that content is well formed and only refers to
+// variables for which known returns true. Templates are validated when they
+// are saved so a bad one is rejected before any run uses it.
+func ValidateTemplate(content string, known func(name string) bool) error {
+	names, err := Variables(content)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if !known(name) {
+			return fmt.Errorf("%w: %s", ErrUnknownVariable, name)
+		}
+	}
+	return nil
+}
+
+// ValidateDecomposition checks a decomposition prompt template.
+func ValidateDecomposition(content string) error {
+	return ValidateTemplate(content, func(name string) bool {
+		for _, v := range DecompositionVariables {
+			if v == name {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Render replaces each placeholder in content with its escaped value from
+// vars. Values are inserted in a single pass, so braces, quotes or JSON in a
+// value are never read as template syntax.
+func Render(content string, vars map[string]string) (string, error) {
+	list, err := parsePlaceholders(content)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	last := 0
+	for _, p := range list {
+		value, ok := vars[p.name]
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrUnknownVariable, p.name)
+		}
+		b.WriteString(content[last:p.start])
+		if p.json {
+			b.WriteString(EscapeJSON(value))
+		} else {
+			b.WriteString(EscapeText(value))
+		}
+		last = p.end
+	}
+	b.WriteString(content[last:])
+	return b.String(), nil
+}
+
+// EscapeText prepares value for a plain-text prompt: it is truncated, control
+// characters other than newlines and tabs are dropped, and template
+// delimiters are broken up so the text cannot be re-read as placeholders.
+func EscapeText(value string) string {
+	value = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' || !unicode.IsControl(r) {
+			return r
+		}
+		return -1
+	}, truncateVariable(value))
+	value = strings.ReplaceAll(value, "{{", "{ {")
+	return strings.ReplaceAll(value, "}}", "} }")
+}
+
+// EscapeJSON prepares value for the inside of a JSON string literal: it is
+// truncated and quoted as JSON, without the surrounding quotes.
+func EscapeJSON(value string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(truncateVariable(value)) // strings always encode
+	quoted := strings.TrimSuffix(buf.String(), "\n")
+	return quoted[1 : len(quoted)-1]
+}
+
+func truncateVariable(value string) string {
+	if utf8.RuneCountInString(value) <= MaxVariableLength {
+		return value
+	}
+	runes := []rune(value)
+	return string(runes[:MaxVariableLength]) + TruncationMarker
+}
@@ -0,0 +1,318 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// messageCountsTTL is how long a tenant's hourly message counts are
+	// reused before they are aggregated again.
+	messageCountsTTL = time.Minute
+	// messageCountsWindow is how far back hourly message counts go.
+	messageCountsWindow = 7 * 24 * time.Hour
+)
+
+// Activity entry types. message_counts is aggregated separately from the
+// event UNION so it can be cached.
+const (
+	activitySwarmRun          = "swarm_run"
+	activityMessageCounts     = "message_counts"
+	activityCreditTransaction = "credit_transaction"
+	activityDeployStep        = "deploy_step"
+	activityChannelEvent      = "channel_event"
+)
+
+var activityTypes = []string{activitySwarmRun, activityMessageCounts, activityCreditTransaction, activityDeployStep, activityChannelEvent}
+
+type activityEntry struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Timestamp time.Time       `json:"timestamp"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// before reports whether e sorts after the cursor position (ts, id) in the
+// newest-first feed.
+func (e activityEntry) before(ts time.Time, id string) bool {
+	return e.Timestamp.Before(ts) || (e.Timestamp.Equal(ts) && e.ID < id)
+}
+
+type cachedMessageCounts struct {
+	entries   []activityEntry
+	expiresAt time.Time
+}
+
+// ActivityHandler serves a tenant's merged activity feed.
+type ActivityHandler struct {
+	DB *sql.DB
+
+	mu     sync.Mutex
+	counts map[string]cachedMessageCounts // tenant id -> hourly counts
+	now    func() time.Time
+}
+
+func NewActivityHandler(db *sql.DB) *ActivityHandler {
+	return &ActivityHandler{DB: db, counts: make(map[string]cachedMessageCounts), now: time.Now}
+}
+
+func (h *ActivityHandler) Mount(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/tenants/{id}/activity", h.handleActivity)
+}
+
+// handleActivity returns what the tenant's agents have been doing, newest
+// first: swarm run results, hourly inbound/outbound message counts, credit
+// transactions, deployment steps and channel connects/disconnects. Pages are
+// chained with next_cursor; ?types=a,b limits the entry types.
+func (h *ActivityHandler) handleActivity(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	if scoped := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); scoped != "" && scoped != tenantID {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 50
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = min(n, 200)
+	}
+	types, err := parseActivityTypes(query.Get("types"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var (
+		cursorTS time.Time
+		cursorID string
+	)
+	if raw := strings.TrimSpace(query.Get("cursor")); raw != "" {
+		cursorTS, cursorID, err = decodeActivityCursor(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+	}
+
+	entries, err := h.queryActivityEvents(r.Context(), tenantID, types, cursorTS, cursorID, limit+1)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query activity")
+		return
+	}
+	if types[activityMessageCounts] {
+		counts, err := h.messageCounts(r.Context(), tenantID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to query message counts")
+			return
+		}
+		for _, e := range counts {
+			if cursorID == "" || e.before(cursorTS, cursorID) {
+				entries = append(entries, e)
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[j].before(entries[i].Timestamp, entries[i].ID)
+	})
+
+	var nextCursor any
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[len(entries)-1]
+		nextCursor = encodeActivityCursor(last.Timestamp, last.ID)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tenant_id":   tenantID,
+		"activity":    entries,
+		"next_cursor": nextCursor,
+	})
+}
+
+// parseActivityTypes returns the requested entry types, or all of them when
+// raw is empty.
+func parseActivityTypes(raw string) (map[string]bool, error) {
+	types := make(map[string]bool, len(activityTypes))
+	if strings.TrimSpace(raw) == "" {
+		for _, t := range activityTypes {
+			types[t] = true
+		}
+		return types, nil
+	}
+	for _, t := range strings.Split(raw, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		known := false
+		for _, k := range activityTypes {
+			known = known || k == t
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown activity type %q", t)
+		}
+		types[t] = true
+	}
+	return types, nil
+}
+
+// queryActivityEvents reads event entries from every source table through one
+// UNION with a common (id, type, ts, payload) projection. Ids compare
+// bytewise so the order matches the cursor comparison in Go.
+func (h *ActivityHandler) queryActivityEvents(ctx context.Context, tenantID string, types map[string]bool, cursorTS time.Time, cursorID string, limit int) ([]activityEntry, error) {
+	wanted := make([]string, 0, len(types))
+	for t := range types {
+		if t != activityMessageCounts {
+			wanted = append(wanted, t)
+		}
+	}
+	if len(wanted) == 0 {
+		return nil, nil
+	}
+	var cursor any
+	if cursorID != "" {
+		cursor = cursorTS
+	}
+
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT id, type, ts, payload FROM (
+			SELECT 'swarm_run:' || m.id::text AS id, 'swarm_run' AS type, m.created_at AS ts,
+			       jsonb_build_object('run_id', m.metadata->>'run_id', 'event', m.metadata->>'event',
+			                          'conversation_id', m.conversation_id) AS payload
+			FROM messages m
+			JOIN conversations c ON c.id = m.conversation_id
+			WHERE c.tenant_id = $1 AND m.metadata->>'source' = 'swarm'
+			UNION ALL
+			SELECT 'credit_transaction:' || ct.id::text, 'credit_transaction', ct.created_at,
+			       jsonb_build_object('amount_cents', ct.amount_cents, 'reason', ct.reason)
+			FROM credit_transactions ct
+			WHERE ct.tenant_id = $1
+			UNION ALL
+			SELECT 'deploy_step:' || d.id::text || ':' || l.ord, 'deploy_step', (l.entry->>'timestamp')::timestamptz,
+			       jsonb_build_object('deployment_id', d.id, 'provider', d.provider, 'target_name', d.target_name,
+			                          'message', l.entry->>'message')
+			FROM deployment_runs d
+			CROSS JOIN LATERAL jsonb_array_elements(d.logs) WITH ORDINALITY AS l(entry, ord)
+			WHERE d.tenant_id = $1
+			UNION ALL
+			SELECT 'channel_event:' || e.id::text, 'channel_event', e.created_at,
+			       jsonb_build_object('channel', e.channel, 'action', e.action)
+			FROM channel_events e
+			WHERE e.tenant_id = $1
+		) feed
+		WHERE type = ANY(string_to_array($2, ','))
+		  AND ($3::timestamptz IS NULL OR (ts, id COLLATE "C") < ($3, $4))
+		ORDER BY ts DESC, id COLLATE "C" DESC
+		LIMIT $5
+	`, tenantID, strings.Join(wanted, ","), cursor, cursorID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]activityEntry, 0, limit)
+	for rows.Next() {
+		var (
+			e       activityEntry
+			payload []byte
+		)
+		if err := rows.Scan(&e.ID, &e.Type, &e.Timestamp, &payload); err != nil {
+			return nil, err
+		}
+		e.Payload = payload
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// messageCounts returns hourly inbound (user) and outbound (assistant)
+// message counts for the last week, aggregated at most once a minute.
+func (h *ActivityHandler) messageCounts(ctx context.Context, tenantID string) ([]activityEntry, error) {
+	h.mu.Lock()
+	cached, ok := h.counts[tenantID]
+	h.mu.Unlock()
+	if ok && h.now().Before(cached.expiresAt) {
+		return cached.entries, nil
+	}
+
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT date_trunc('hour', m.created_at) AS hour,
+		       COUNT(*) FILTER (WHERE m.role = 'user') AS inbound,
+		       COUNT(*) FILTER (WHERE m.role = 'assistant') AS outbound
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE c.tenant_id = $1 AND m.created_at >= $2
+		GROUP BY 1
+	`, tenantID, h.now().Add(-messageCountsWindow))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]activityEntry, 0)
+	for rows.Next() {
+		var (
+			hour              time.Time
+			inbound, outbound int64
+		)
+		if err := rows.Scan(&hour, &inbound, &outbound); err != nil {
+			return nil, err
+		}
+		payload, _ := json.Marshal(map[string]int64{"inbound": inbound, "outbound": outbound})
+		entries = append(entries, activityEntry{
+			ID:        fmt.Sprintf("%s:%d", activityMessageCounts, hour.Unix()),
+			Type:      activityMessageCounts,
+			Timestamp: hour,
+			Payload:   payload,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	h.counts[tenantID] = cachedMessageCounts{entries: entries, expiresAt: h.now().Add(messageCountsTTL)}
+	h.mu.Unlock()
+	return entries, nil
+}
+
+func encodeActivityCursor(ts time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(ts.UTC().Format(time.RFC3339Nano) + "|" + id))
+}
+
+func decodeActivityCursor(raw string) (time.Time, string, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	tsPart, id, ok := strings.Cut(string(decoded), "|")
+	if !ok || id == "" {
+		return time.Time{}, "", errors.New("malformed cursor")
+	}
+	ts, err := time.Parse(time.RFC3339Nano, tsPart)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	return ts, id, nil
+}
@@ -0,0 +1,108 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestActivityFeed(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+	h := NewActivityHandler(db)
+	h.now = func() time.Time { return now }
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	mock.ExpectQuery("FROM channel_events").
+		WithArgs("t1", sqlmock.AnyArg(), nil, "", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "ts", "payload"}).
+			AddRow("channel_event:9", "channel_event", now.Add(-10*time.Minute), []byte(`{"channel":"telegram","action":"connected"}`)).
+			AddRow("credit_transaction:4", "credit_transaction", now.Add(-2*time.Hour), []byte(`{"amount_cents":-12,"reason":"usage"}`)).
+			AddRow("deploy_step:d1:1", "deploy_step", now.Add(-3*time.Hour), []byte(`{"message":"build started"}`)))
+	mock.ExpectQuery("date_trunc\\('hour'").
+		WithArgs("t1", now.Add(-messageCountsWindow)).
+		WillReturnRows(sqlmock.NewRows([]string{"hour", "inbound", "outbound"}).
+			AddRow(now.Truncate(time.Hour), 3, 2))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tenants/t1/activity?limit=2", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Activity   []activityEntry `json:"activity"`
+		NextCursor *string         `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Activity) != 2 || resp.Activity[0].Type != "channel_event" || resp.Activity[1].Type != "message_counts" {
+		t.Fatalf("unexpected activity: %s", w.Body.String())
+	}
+	if resp.NextCursor == nil {
+		t.Fatalf("expected next_cursor: %s", w.Body.String())
+	}
+	cursorTS, cursorID, err := decodeActivityCursor(*resp.NextCursor)
+	if err != nil || !cursorTS.Equal(now.Truncate(time.Hour)) || cursorID != resp.Activity[1].ID {
+		t.Fatalf("cursor = %v %q %v", cursorTS, cursorID, err)
+	}
+
+	// The next page reuses the cached message counts.
+	mock.ExpectQuery("FROM channel_events").
+		WithArgs("t1", sqlmock.AnyArg(), cursorTS, cursorID, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "ts", "payload"}).
+			AddRow("credit_transaction:4", "credit_transaction", now.Add(-2*time.Hour), []byte(`{}`)))
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tenants/t1/activity?limit=2&cursor="+*resp.NextCursor, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	resp.Activity, resp.NextCursor = nil, nil
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Activity) != 1 || resp.Activity[0].Type != "credit_transaction" || resp.NextCursor != nil {
+		t.Fatalf("unexpected second page: %s", w.Body.String())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestActivityFeedRejectsBadInput(t *testing.T) {
+	t.Parallel()
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	NewActivityHandler(db).Mount(mux)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tenants/t1/activity?types=swarm_run,bogus", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/tenants/t1/activity", nil)
+	req.Header.Set("X-Tenant-ID", "t2")
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", w.Code)
+	}
+}
@@ -13,33 +13,125 @@ import (
 	"strings"
 	"time"
 
+	"github.com/agentsquads/api/channels"
+	"github.com/agentsquads/api/coordinator"
+	"github.com/agentsquads/api/i18n"
+	"github.com/agentsquads/api/keyring"
+	"github.com/agentsquads/api/llmproxy"
+	"github.com/agentsquads/api/media"
 	"github.com/agentsquads/api/middleware"
 	"github.com/agentsquads/api/orchestrator"
-	"github.com/google/uuid"
+	"github.com/agentsquads/api/plans"
+	"github.com/agentsquads/api/policies"
+	"github.com/agentsquads/api/prompts"
+	"github.com/agentsquads/api/usage"
+	"github.com/redis/go-redis/v9"
 )
 
 // AdminHandler serves platform-admin-only APIs.
 type AdminHandler struct {
-	DB   *sql.DB
-	Orch orchestrator.TenantOrchestrator
+	DB         *sql.DB
+	Orch       orchestrator.TenantOrchestrator
+	Plans      *plans.Resolver
+	Policies   *policies.Store
+	Prompts    *prompts.Store
+	Rotation   *keyring.Rotator
+	Redis      *redis.Client
+	HTTPClient *http.Client
+	Benchmarks ModelBenchmarker
+	ModelTests ModelTester
+	InFlight   InFlightReporter
+	// SwarmConfigs stores per-tenant swarm overrides.
+	SwarmConfigs *coordinator.TenantConfigStore
+	// Models and SwarmSettings are reloaded by POST /api/admin/reload.
+	Models        *llmproxy.ModelRegistry
+	SwarmSettings *coordinator.SwarmSettingsStore
+	// Messages holds the system message overrides, also reloaded there.
+	Messages *i18n.Catalog
+	// Usage maintains the usage_daily rollup the usage queries read.
+	Usage *usage.Rollup
+	// Blobs holds the media, export and invoice files a tenant deletion
+	// removes.
+	Blobs media.BlobStore
+
+	// keys replaces keyring.FromEnv for reading raw channel credentials.
+	keys func() (*keyring.Keyring, error)
+	// publishOutbound replaces channels.PublishOutbound in tests.
+	publishOutbound func(ctx context.Context, out channels.OutboundMessage) error
 }
 
 func NewAdminHandler(db *sql.DB, orch orchestrator.TenantOrchestrator) *AdminHandler {
-	return &AdminHandler{DB: db, Orch: orch}
+	return &AdminHandler{DB: db, Orch: orch, HTTPClient: &http.Client{Timeout: 15 * time.Second}}
 }
 
 func (h *AdminHandler) Mount(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/admin/tenants", h.handleListTenants)
+	mux.HandleFunc("GET /api/admin/tenants/export", h.handleExportTenants)
+	mux.HandleFunc("POST /api/admin/usage/rollup/backfill", h.handleUsageRollupBackfill)
+	mux.HandleFunc("GET /api/admin/usage/rollup/verify", h.handleUsageRollupVerify)
+	mux.HandleFunc("GET /api/admin/lookup", h.handleTenantLookup)
+	mux.HandleFunc("GET /api/admin/tenants/active-containers", h.handleActiveContainers)
+	mux.HandleFunc("GET /api/admin/tenants/stale-containers", h.handleStaleContainers)
+	mux.HandleFunc("POST /api/admin/tenants/stale-containers/destroy", h.handleDestroyStaleContainers)
 	mux.HandleFunc("GET /api/admin/tenants/{id}", h.handleGetTenant)
 	mux.HandleFunc("POST /api/admin/tenants/{id}/credits", h.handleAdjustCredits)
+	mux.HandleFunc("POST /api/admin/tenants/{id}/reset-credits", h.handleResetCredits)
+	mux.HandleFunc("DELETE /api/admin/tenants/{id}", h.handleDeleteTenant)
+	mux.HandleFunc("GET /api/admin/tenants/{id}/conversations", h.handleListTenantConversations)
 	mux.HandleFunc("POST /api/admin/tenants/{id}/suspend", h.handleSuspendTenant)
 	mux.HandleFunc("POST /api/admin/tenants/{id}/resume", h.handleResumeTenant)
+	mux.HandleFunc("GET /api/admin/tenants/{id}/network", h.handleContainerNetwork)
+	mux.HandleFunc("GET /api/admin/tenants/{id}/container/events", h.handleContainerEvents)
+	mux.HandleFunc("PATCH /api/admin/tenants/{id}/container", h.handleUpdateContainerLabels)
+	mux.HandleFunc("POST /api/tenants/{id}/container/snapshot", h.handleContainerSnapshot)
+	mux.HandleFunc("POST /api/admin/tenants/{id}/container/copy-files", h.handleCopyFiles)
+	mux.HandleFunc("GET /api/admin/tenants/{id}/exec-history", h.handleExecHistory)
+	mux.HandleFunc("POST /api/admin/tenants/{id}/impersonate", h.handleImpersonateTenant)
+	mux.HandleFunc("POST /api/admin/tenants/{id}/send-message", h.handleSendAdminMessage)
+	mux.HandleFunc("GET /api/admin/tenants/{id}/whatsapp/status", h.handleWhatsAppStatus)
+	mux.HandleFunc("GET /api/admin/tenants/{id}/channels/{channel}/raw-config", h.handleRawChannelConfig)
+
+	mux.HandleFunc("GET /api/admin/credits/negative-balances", h.handleNegativeBalances)
+	mux.HandleFunc("POST /api/admin/credits/bulk-adjust", h.handleBulkCreditAdjust)
+
+	mux.HandleFunc("GET /api/admin/swarm/feedback", h.handleListSwarmFeedback)
 
 	mux.HandleFunc("GET /api/admin/stats", h.handlePlatformStats)
+	mux.HandleFunc("GET /api/admin/platform/errors", h.handleListPlatformErrors)
+	mux.HandleFunc("POST /api/admin/reload", h.handleReloadConfig)
+
+	mux.HandleFunc("POST /api/admin/crypto/rotate", h.handleStartKeyRotation)
+	mux.HandleFunc("GET /api/admin/crypto/rotations", h.handleListKeyRotations)
+	mux.HandleFunc("GET /api/admin/crypto/rotations/{id}", h.handleGetKeyRotation)
 
 	mux.HandleFunc("GET /api/admin/models", h.handleListModels)
 	mux.HandleFunc("PUT /api/admin/models/{id}", h.handleUpdateModel)
 	mux.HandleFunc("POST /api/admin/models", h.handleCreateModel)
+	mux.HandleFunc("POST /api/admin/models/{id}/test", h.handleTestModel)
+	mux.HandleFunc("POST /api/admin/models/benchmark", h.handleRunModelBenchmark)
+	mux.HandleFunc("GET /api/admin/models/benchmarks", h.handleListModelBenchmarks)
+	mux.HandleFunc("GET /api/admin/llm/inflight", h.handleLLMInFlight)
+	mux.HandleFunc("GET /api/admin/models/aliases", h.handleListModelAliases)
+	mux.HandleFunc("PUT /api/admin/models/aliases/{alias_id...}", h.handleSetModelAlias)
+	mux.HandleFunc("DELETE /api/admin/models/aliases/{alias_id...}", h.handleDeleteModelAlias)
+
+	mux.HandleFunc("GET /api/admin/plans", h.handleListPlans)
+	mux.HandleFunc("POST /api/admin/plans", h.handleCreatePlan)
+	mux.HandleFunc("PUT /api/admin/plans/{id}", h.handleUpdatePlan)
+	mux.HandleFunc("DELETE /api/admin/plans/{id}", h.handleDeletePlan)
+	mux.HandleFunc("PUT /api/admin/tenants/{id}/plan", h.handleSetTenantPlan)
+	mux.HandleFunc("PUT /api/admin/tenants/{id}/policies/{feature}", h.handleSetTenantPolicy)
+	mux.HandleFunc("PUT /api/admin/tenants/{id}/swarm/config", h.handleSetTenantSwarmConfig)
+
+	mux.HandleFunc("GET /api/admin/prompts", h.handleListPromptTemplates)
+	mux.HandleFunc("GET /api/admin/prompts/{name}", h.handleListPromptTemplates)
+	mux.HandleFunc("POST /api/admin/prompts/{name}/versions", h.handleCreatePromptVersion)
+	mux.HandleFunc("POST /api/admin/prompts/{name}/versions/{version}/activate", h.handleActivatePromptVersion)
+	mux.HandleFunc("DELETE /api/admin/prompts/{name}/versions/{version}", h.handleDeletePromptVersion)
+	mux.HandleFunc("POST /api/admin/prompts/{name}/deactivate", h.handleDeactivatePrompt)
+	mux.HandleFunc("GET /api/admin/tenants/{id}/model-access", h.handleListModelAccess)
+	mux.HandleFunc("PUT /api/admin/tenants/{id}/model-access/{model_id...}", h.handleSetModelAccess)
+	mux.HandleFunc("DELETE /api/admin/tenants/{id}/model-access/{model_id...}", h.handleDeleteModelAccess)
 }
 
 func (h *AdminHandler) handleListTenants(w http.ResponseWriter, r *http.Request) {
@@ -47,6 +139,11 @@ func (h *AdminHandler) handleListTenants(w http.ResponseWriter, r *http.Request)
 		writeError(w, http.StatusServiceUnavailable, "database is not configured")
 		return
 	}
+	filter, err := parseTenantListFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	rows, err := h.DB.QueryContext(r.Context(), `
 		SELECT
@@ -60,7 +157,7 @@ func (h *AdminHandler) handleListTenants(w http.ResponseWriter, r *http.Request)
 			COALESCE(uag.total_input_tokens, 0) AS total_input_tokens,
 			COALESCE(uag.total_output_tokens, 0) AS total_output_tokens,
 			COALESCE(uag.total_revenue_cents, 0) AS total_revenue_cents,
-			COALESCE(uag.tokens_24h, 0) AS tokens_24h
+			COALESCE(recent.tokens_24h, 0) AS tokens_24h
 		FROM tenants t
 		LEFT JOIN users u ON u.id = t.user_id
 		LEFT JOIN credits c ON c.tenant_id = t.id
@@ -69,13 +166,20 @@ func (h *AdminHandler) handleListTenants(w http.ResponseWriter, r *http.Request)
 				tenant_id,
 				SUM(input_tokens) AS total_input_tokens,
 				SUM(output_tokens) AS total_output_tokens,
-				SUM(cost_cents + margin_cents) AS total_revenue_cents,
-				SUM(CASE WHEN created_at >= NOW() - INTERVAL '1 day' THEN input_tokens + output_tokens ELSE 0 END) AS tokens_24h
-			FROM usage_logs
+				SUM(revenue_cents) AS total_revenue_cents
+			FROM `+usageDaysSQL("")+` days
 			GROUP BY tenant_id
 		) uag ON uag.tenant_id = t.id
+		LEFT JOIN (
+			SELECT tenant_id, SUM(input_tokens + output_tokens) AS tokens_24h
+			FROM usage_logs
+			WHERE created_at >= NOW() - INTERVAL '1 day'
+			GROUP BY tenant_id
+		) recent ON recent.tenant_id = t.id
+		WHERE ($1::text IS NULL OR t.status = $1)
+		  AND ($2::timestamptz IS NULL OR t.created_at >= $2)
 		ORDER BY t.created_at DESC
-	`)
+	`, filter.status, filter.since)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to query tenants")
 		return
@@ -86,7 +190,7 @@ func (h *AdminHandler) handleListTenants(w http.ResponseWriter, r *http.Request)
 	for rows.Next() {
 		var (
 			tenantID          string
-			userID            string
+			userID            sql.NullString
 			status            string
 			containerID       sql.NullString
 			createdAt         time.Time
@@ -117,7 +221,7 @@ func (h *AdminHandler) handleListTenants(w http.ResponseWriter, r *http.Request)
 
 		tenants = append(tenants, map[string]any{
 			"id":                    tenantID,
-			"user_id":               userID,
+			"user_id":               nullString(userID),
 			"email":                 nullString(email),
 			"status":                status,
 			"container_id":          nullString(containerID),
@@ -156,7 +260,7 @@ func (h *AdminHandler) handleGetTenant(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var (
-		userID       string
+		userID       sql.NullString
 		status       string
 		containerID  sql.NullString
 		createdAt    time.Time
@@ -201,16 +305,16 @@ func (h *AdminHandler) handleGetTenant(w http.ResponseWriter, r *http.Request) {
 		tokensWeek        int64
 		tokensMonth       int64
 	)
+	// Week and month are the last 7 and 30 UTC days, today included.
 	if err := h.DB.QueryRowContext(r.Context(), `
 		SELECT
 			COALESCE(SUM(input_tokens), 0) AS total_input_tokens,
 			COALESCE(SUM(output_tokens), 0) AS total_output_tokens,
-			COALESCE(SUM(cost_cents + margin_cents), 0) AS total_revenue_cents,
-			COALESCE(SUM(CASE WHEN created_at >= DATE_TRUNC('day', NOW()) THEN input_tokens + output_tokens ELSE 0 END), 0) AS tokens_today,
-			COALESCE(SUM(CASE WHEN created_at >= NOW() - INTERVAL '7 days' THEN input_tokens + output_tokens ELSE 0 END), 0) AS tokens_week,
-			COALESCE(SUM(CASE WHEN created_at >= NOW() - INTERVAL '30 days' THEN input_tokens + output_tokens ELSE 0 END), 0) AS tokens_month
-		FROM usage_logs
-		WHERE tenant_id = $1
+			COALESCE(SUM(revenue_cents), 0) AS total_revenue_cents,
+			COALESCE(SUM(CASE WHEN day >= (NOW() AT TIME ZONE 'UTC')::date THEN input_tokens + output_tokens ELSE 0 END), 0) AS tokens_today,
+			COALESCE(SUM(CASE WHEN day > (NOW() AT TIME ZONE 'UTC')::date - 7 THEN input_tokens + output_tokens ELSE 0 END), 0) AS tokens_week,
+			COALESCE(SUM(CASE WHEN day > (NOW() AT TIME ZONE 'UTC')::date - 30 THEN input_tokens + output_tokens ELSE 0 END), 0) AS tokens_month
+		FROM `+usageDaysSQL("tenant_id = $1")+` days
 	`, tenantID).Scan(
 		&totalInputTokens,
 		&totalOutputTokens,
@@ -228,10 +332,9 @@ func (h *AdminHandler) handleGetTenant(w http.ResponseWriter, r *http.Request) {
 			model,
 			COALESCE(SUM(input_tokens), 0) AS input_tokens,
 			COALESCE(SUM(output_tokens), 0) AS output_tokens,
-			COALESCE(SUM(cost_cents + margin_cents), 0) AS revenue_cents,
-			MAX(created_at) AS last_used_at
-		FROM usage_logs
-		WHERE tenant_id = $1
+			COALESCE(SUM(revenue_cents), 0) AS revenue_cents,
+			MAX(last_used_at) AS last_used_at
+		FROM `+usageDaysSQL("tenant_id = $1")+` days
 		GROUP BY model
 		ORDER BY COALESCE(SUM(input_tokens + output_tokens), 0) DESC
 	`, tenantID)
@@ -336,16 +439,18 @@ func (h *AdminHandler) handleGetTenant(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{
 		"tenant": map[string]any{
 			"id":                    tenantID,
-			"user_id":               userID,
+			"user_id":               nullString(userID),
 			"email":                 nullString(email),
 			"status":                status,
 			"container_id":          nullString(containerID),
 			"container":             h.tenantContainerSnapshot(r.Context(), tenantID, containerID),
 			"credits_balance_cents": balanceCents,
 			"created_at":            createdAt,
+			"plan":                  h.tenantPlanSnapshot(r, tenantID),
 			"config": map[string]any{
 				"policies": policies,
 				"channels": channels,
+				"swarm":    h.tenantSwarmConfigSnapshot(r, tenantID),
 			},
 			"usage": map[string]any{
 				"total_input_tokens":  totalInputTokens,
@@ -392,60 +497,13 @@ func (h *AdminHandler) handleAdjustCredits(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	tx, err := h.DB.BeginTx(r.Context(), nil)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to start transaction")
-		return
-	}
-	defer tx.Rollback()
-
-	var tenantExists bool
-	if err := tx.QueryRowContext(r.Context(), `SELECT EXISTS(SELECT 1 FROM tenants WHERE id = $1)`, tenantID).Scan(&tenantExists); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to verify tenant")
-		return
-	}
-	if !tenantExists {
+	balanceCents, err := h.adjustTenantCredits(r.Context(), tenantID, req.Amount, req.Reason)
+	if errors.Is(err, errCreditTenantNotFound) {
 		writeError(w, http.StatusNotFound, "tenant not found")
 		return
 	}
-
-	if _, err := tx.ExecContext(r.Context(), `
-		INSERT INTO credits (tenant_id, balance_cents, free_credit_used, updated_at)
-		VALUES ($1, 0, false, NOW())
-		ON CONFLICT (tenant_id) DO NOTHING
-	`, tenantID); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to ensure tenant credits")
-		return
-	}
-
-	var balanceCents int64
-	if err := tx.QueryRowContext(r.Context(), `
-		UPDATE credits
-		SET balance_cents = balance_cents + $2,
-		    updated_at = NOW()
-		WHERE tenant_id = $1
-		RETURNING balance_cents
-	`, tenantID, req.Amount).Scan(&balanceCents); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to update credits")
-		return
-	}
-
-	adminIdentity, _ := middleware.AdminFromContext(r.Context())
-	var adminUserID any
-	if parsedUUID, err := uuid.Parse(strings.TrimSpace(adminIdentity.ID)); err == nil {
-		adminUserID = parsedUUID.String()
-	}
-
-	if _, err := tx.ExecContext(r.Context(), `
-		INSERT INTO credit_transactions (tenant_id, amount_cents, reason, admin_user_id)
-		VALUES ($1, $2, $3, $4)
-	`, tenantID, req.Amount, req.Reason, adminUserID); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to record credit transaction")
-		return
-	}
-
-	if err := tx.Commit(); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to commit credit update")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
@@ -566,6 +624,104 @@ func (h *AdminHandler) handleResumeTenant(w http.ResponseWriter, r *http.Request
 	})
 }
 
+// handleContainerNetwork reports which networks the tenant container is
+// attached to. Live data is persisted on every successful inspect so the last
+// known topology can still be served while the container is stopped.
+func (h *AdminHandler) handleContainerNetwork(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	inspector, ok := orchestratorCapability[orchestrator.NetworkInspector](w, h.Orch, "network inspection")
+	if !ok {
+		return
+	}
+
+	var (
+		containerID   sql.NullString
+		storedNetwork []byte
+		storedAt      sql.NullTime
+	)
+	err := h.DB.QueryRowContext(r.Context(), `
+		SELECT container_id, network_config, network_config_updated_at
+		FROM tenants
+		WHERE id = $1
+	`, tenantID).Scan(&containerID, &storedNetwork, &storedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load tenant")
+		return
+	}
+
+	id := strings.TrimSpace(containerID.String)
+	if id == "" {
+		writeError(w, http.StatusNotFound, "tenant container is not provisioned")
+		return
+	}
+
+	topology, running, inspectErr := inspector.ContainerNetwork(r.Context(), tenantID)
+	if inspectErr == nil && running {
+		if payload, err := json.Marshal(topology); err == nil {
+			if _, err := h.DB.ExecContext(r.Context(), `
+				UPDATE tenants
+				SET network_config = $2::jsonb, network_config_updated_at = NOW()
+				WHERE id = $1
+			`, tenantID, string(payload)); err != nil {
+				slog.Warn("failed to persist tenant network config", "tenant_id", tenantID, "err", err)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"tenant_id":    tenantID,
+			"container_id": id,
+			"source":       "live",
+			"running":      true,
+			"networks":     topology.Networks,
+			"hostname":     topology.Hostname,
+			"ports":        topology.Ports,
+		})
+		return
+	}
+
+	if len(storedNetwork) == 0 {
+		if inspectErr != nil {
+			writeError(w, http.StatusBadGateway, "failed to inspect tenant container")
+			return
+		}
+		writeError(w, http.StatusNotFound, "container is not running and no network config is recorded")
+		return
+	}
+
+	var last orchestrator.ContainerNetwork
+	if err := json.Unmarshal(storedNetwork, &last); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to decode stored network config")
+		return
+	}
+
+	response := map[string]any{
+		"tenant_id":    tenantID,
+		"container_id": id,
+		"source":       "last_known",
+		"running":      false,
+		"networks":     last.Networks,
+		"hostname":     last.Hostname,
+		"ports":        last.Ports,
+		"recorded_at":  nullTime(storedAt),
+	}
+	if inspectErr != nil {
+		response["inspect_error"] = inspectErr.Error()
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
 func (h *AdminHandler) handlePlatformStats(w http.ResponseWriter, r *http.Request) {
 	if h.DB == nil {
 		writeError(w, http.StatusServiceUnavailable, "database is not configured")
@@ -627,15 +783,17 @@ func (h *AdminHandler) handlePlatformStats(w http.ResponseWriter, r *http.Reques
 		revenueWeekCents  int64
 		revenueMonthCents int64
 	)
+	// Week and month are the last 7 and 30 UTC days, today included.
 	if err := h.DB.QueryRowContext(r.Context(), `
 		SELECT
-			COALESCE(SUM(CASE WHEN created_at >= DATE_TRUNC('day', NOW()) THEN input_tokens + output_tokens ELSE 0 END), 0) AS tokens_today,
-			COALESCE(SUM(CASE WHEN created_at >= NOW() - INTERVAL '7 days' THEN input_tokens + output_tokens ELSE 0 END), 0) AS tokens_week,
-			COALESCE(SUM(CASE WHEN created_at >= NOW() - INTERVAL '30 days' THEN input_tokens + output_tokens ELSE 0 END), 0) AS tokens_month,
-			COALESCE(SUM(CASE WHEN created_at >= DATE_TRUNC('day', NOW()) THEN cost_cents + margin_cents ELSE 0 END), 0) AS revenue_today_cents,
-			COALESCE(SUM(CASE WHEN created_at >= NOW() - INTERVAL '7 days' THEN cost_cents + margin_cents ELSE 0 END), 0) AS revenue_week_cents,
-			COALESCE(SUM(CASE WHEN created_at >= NOW() - INTERVAL '30 days' THEN cost_cents + margin_cents ELSE 0 END), 0) AS revenue_month_cents
-		FROM usage_logs
+			COALESCE(SUM(CASE WHEN day >= today THEN input_tokens + output_tokens ELSE 0 END), 0) AS tokens_today,
+			COALESCE(SUM(CASE WHEN day > today - 7 THEN input_tokens + output_tokens ELSE 0 END), 0) AS tokens_week,
+			COALESCE(SUM(input_tokens + output_tokens), 0) AS tokens_month,
+			COALESCE(SUM(CASE WHEN day >= today THEN revenue_cents ELSE 0 END), 0) AS revenue_today_cents,
+			COALESCE(SUM(CASE WHEN day > today - 7 THEN revenue_cents ELSE 0 END), 0) AS revenue_week_cents,
+			COALESCE(SUM(revenue_cents), 0) AS revenue_month_cents
+		FROM `+usageDaysSQL("")+` days, (SELECT (NOW() AT TIME ZONE 'UTC')::date AS today) d
+		WHERE day > today - 30
 	`).Scan(&tokensToday, &tokensWeek, &tokensMonth, &revenueTodayCents, &revenueWeekCents, &revenueMonthCents); err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to query usage aggregates")
 		return
@@ -1001,15 +1159,34 @@ func (h *AdminHandler) tenantContainerSnapshot(ctx context.Context, tenantID str
 		state = "running"
 	}
 
-	return map[string]any{
-		"id":         containerID.String,
-		"state":      state,
-		"running":    status.Running,
-		"health":     status.Health,
-		"started_at": startedAt,
-		"memory_mb":  status.MemoryMB,
-		"cpu_pct":    status.CPUPct,
-	}
+	snapshot := map[string]any{
+		"id":                      containerID.String,
+		"state":                   state,
+		"running":                 status.Running,
+		"health":                  status.Health,
+		"started_at":              startedAt,
+		"restart_count":           status.RestartCount,
+		"oom_killed":              status.OOMKilled,
+		"memory_mb":               nil,
+		"memory_limit_mb":         nil,
+		"cpu_pct":                 nil,
+		"sampled_at":              nil,
+		"openfang_version":        nil,
+		"openfang_uptime_seconds": nil,
+	}
+	// Resource usage is only reported when stats were sampled, so a slow
+	// Docker daemon shows as missing data rather than an idle container.
+	if !status.SampledAt.IsZero() {
+		snapshot["memory_mb"] = status.MemoryMB
+		snapshot["memory_limit_mb"] = status.MemoryLimitMB
+		snapshot["cpu_pct"] = status.CPUPct
+		snapshot["sampled_at"] = status.SampledAt
+	}
+	if status.OpenFangVersion != "" {
+		snapshot["openfang_version"] = status.OpenFangVersion
+		snapshot["openfang_uptime_seconds"] = status.OpenFangUptimeSeconds
+	}
+	return snapshot
 }
 
 func (h *AdminHandler) getModelByID(ctx context.Context, cfg modelTableConfig, id string) (map[string]any, error) {
@@ -1176,20 +1353,25 @@ func (cfg modelTableConfig) enabledSelectExpr(alias string) string {
 	return "true"
 }
 
+// adminActorID identifies the calling admin for audit records, preferring the
+// subject over the email.
+func adminActorID(ctx context.Context) string {
+	adminIdentity, _ := middleware.AdminFromContext(ctx)
+	if id := strings.TrimSpace(adminIdentity.ID); id != "" {
+		return id
+	}
+	if email := strings.TrimSpace(adminIdentity.Email); email != "" {
+		return email
+	}
+	return "unknown"
+}
+
 func (h *AdminHandler) logAdminAction(ctx context.Context, action, targetID string, details map[string]any) {
 	if h.DB == nil {
 		return
 	}
 
-	adminIdentity, _ := middleware.AdminFromContext(ctx)
-	adminID := strings.TrimSpace(adminIdentity.ID)
-	if adminID == "" {
-		adminID = strings.TrimSpace(adminIdentity.Email)
-	}
-	if adminID == "" {
-		adminID = "unknown"
-	}
-
+	adminID := adminActorID(ctx)
 	if details == nil {
 		details = map[string]any{}
 	}
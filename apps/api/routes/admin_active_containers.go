@@ -0,0 +1,54 @@
+package routes
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/agentsquads/api/orchestrator"
+)
+
+type activeContainer struct {
+	TenantID         string  `json:"tenant_id"`
+	ContainerIDShort string  `json:"container_id_short"`
+	Status           string  `json:"status"`
+	Port             int     `json:"port"`
+	Healthy          bool    `json:"healthy"`
+	MemoryMB         int64   `json:"memory_mb"`
+	CPUPct           float64 `json:"cpu_pct"`
+}
+
+// handleActiveContainers lists every tenant container straight from the
+// orchestrator for the ops dashboard heat-map. Unlike handlePlatformStats it
+// makes one backend call instead of one per tenant.
+func (h *AdminHandler) handleActiveContainers(w http.ResponseWriter, r *http.Request) {
+	lister, ok := orchestrator.Capability[orchestrator.TenantLister](h.Orch)
+	if !ok || lister == nil {
+		writeError(w, http.StatusServiceUnavailable, "orchestrator cannot list containers")
+		return
+	}
+
+	containers, err := lister.ListTenants(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "failed to list containers")
+		return
+	}
+
+	out := make([]activeContainer, 0, len(containers))
+	for _, c := range containers {
+		short := c.ContainerID
+		if len(short) > 12 {
+			short = short[:12]
+		}
+		out = append(out, activeContainer{
+			TenantID:         c.TenantID,
+			ContainerIDShort: short,
+			Status:           c.Status,
+			Port:             c.Port,
+			Healthy:          c.Healthy,
+			MemoryMB:         c.MemoryMB,
+			CPUPct:           c.CPUPct,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].TenantID < out[j].TenantID })
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,53 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agentsquads/api/orchestrator"
+)
+
+type listingOrchestrator struct {
+	stubOrchestrator
+	containers []orchestrator.TenantContainer
+}
+
+func (l *listingOrchestrator) ListTenants(context.Context) ([]orchestrator.TenantContainer, error) {
+	return l.containers, nil
+}
+
+func TestAdminActiveContainers(t *testing.T) {
+	t.Parallel()
+	orch := &listingOrchestrator{containers: []orchestrator.TenantContainer{
+		{TenantID: "t2", ContainerID: "bbbbbbbbbbbbbbbbbbbb", Status: "exited", Port: 4200},
+		{TenantID: "t1", ContainerID: "aaaaaaaaaaaaaaaaaaaa", Status: "running", Port: 4200, Healthy: true},
+	}}
+	h := NewAdminHandler(nil, orch)
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/tenants/active-containers", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var got []activeContainer
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(got) != 2 || got[0].TenantID != "t1" || got[0].ContainerIDShort != "aaaaaaaaaaaa" || !got[0].Healthy || got[1].Status != "exited" {
+		t.Fatalf("unexpected containers: %+v", got)
+	}
+
+	h = NewAdminHandler(nil, &stubOrchestrator{})
+	mux = http.NewServeMux()
+	h.Mount(mux)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/tenants/active-containers", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status without lister = %d", w.Code)
+	}
+}
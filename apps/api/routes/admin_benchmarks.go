@@ -0,0 +1,256 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/llmproxy"
+	"github.com/google/uuid"
+)
+
+const defaultBenchmarkMaxTokens = 128
+
+// ModelBenchmarker runs a prompt against several models and measures them.
+// *llmproxy.Proxy implements it.
+type ModelBenchmarker interface {
+	Benchmark(ctx context.Context, modelIDs []string, prompt string, maxTokens int) ([]llmproxy.BenchmarkResult, error)
+}
+
+// ModelTester sends a sample prompt to one model. *llmproxy.Proxy implements
+// it.
+type ModelTester interface {
+	TestModel(ctx context.Context, modelID string) (llmproxy.ModelTestResult, error)
+}
+
+// handleTestModel checks that the proxy can reach a model's provider, e.g.
+// right after handleCreateModel. A failing provider call is reported in the
+// body with ok=false, not as a 5xx.
+func (h *AdminHandler) handleTestModel(w http.ResponseWriter, r *http.Request) {
+	if h.ModelTests == nil {
+		writeError(w, http.StatusServiceUnavailable, "llm proxy is not configured")
+		return
+	}
+	modelID := strings.TrimSpace(r.PathValue("id"))
+	if modelID == "" {
+		writeError(w, http.StatusBadRequest, "missing model id")
+		return
+	}
+
+	result, err := h.ModelTests.TestModel(r.Context(), modelID)
+	switch {
+	case errors.Is(err, llmproxy.ErrModelNotFound):
+		writeError(w, http.StatusNotFound, "model not found")
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "failed to load model")
+		return
+	}
+
+	h.logAdminAction(r.Context(), "admin.models.test", modelID, map[string]any{
+		"ok":         result.OK,
+		"latency_ms": result.LatencyMs,
+	})
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleRunModelBenchmark runs an admin's prompt against each requested
+// model, stores the measurements in model_benchmarks and returns them
+// fastest first, failed calls last.
+func (h *AdminHandler) handleRunModelBenchmark(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	if h.Benchmarks == nil {
+		writeError(w, http.StatusServiceUnavailable, "llm proxy is not configured")
+		return
+	}
+
+	var req struct {
+		Models    []string `json:"models"`
+		Prompt    string   `json:"prompt"`
+		MaxTokens int      `json:"max_tokens"`
+	}
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	seen := make(map[string]struct{}, len(req.Models))
+	models := make([]string, 0, len(req.Models))
+	for _, id := range req.Models {
+		id = strings.TrimSpace(id)
+		if _, dup := seen[id]; id == "" || dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		models = append(models, id)
+	}
+	if req.MaxTokens == 0 {
+		req.MaxTokens = defaultBenchmarkMaxTokens
+	}
+
+	results, err := h.Benchmarks.Benchmark(r.Context(), models, req.Prompt, req.MaxTokens)
+	switch {
+	case errors.Is(err, llmproxy.ErrInvalidBenchmark):
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, llmproxy.ErrBenchmarkRunning):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "benchmark failed")
+		return
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if (results[i].Error == "") != (results[j].Error == "") {
+			return results[i].Error == ""
+		}
+		return results[i].LatencyMs < results[j].LatencyMs
+	})
+
+	batchID := uuid.New().String()
+	promptChars := len([]rune(strings.TrimSpace(req.Prompt)))
+	stored := true
+	if err := h.storeModelBenchmarks(r.Context(), batchID, promptChars, req.MaxTokens, results); err != nil {
+		// The calls were already paid for, so still return what was measured.
+		slog.Error("failed to store model benchmarks", "batch", batchID, "err", err)
+		stored = false
+	}
+
+	h.logAdminAction(r.Context(), "admin.models.benchmark", batchID, map[string]any{
+		"models":       models,
+		"prompt_chars": promptChars,
+		"max_tokens":   req.MaxTokens,
+	})
+	writeJSON(w, http.StatusOK, map[string]any{
+		"batch_id":     batchID,
+		"prompt_chars": promptChars,
+		"max_tokens":   req.MaxTokens,
+		"results":      results,
+		"stored":       stored,
+	})
+}
+
+func (h *AdminHandler) storeModelBenchmarks(ctx context.Context, batchID string, promptChars, maxTokens int, results []llmproxy.BenchmarkResult) error {
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	createdBy := adminActorID(ctx)
+	for _, res := range results {
+		var ttfb sql.NullInt64
+		if res.TTFBMs != nil {
+			ttfb = sql.NullInt64{Int64: *res.TTFBMs, Valid: true}
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO model_benchmarks (
+				batch_id, model, provider, prompt_chars, max_tokens, ttfb_ms, latency_ms,
+				input_tokens, output_tokens, provider_cost_cents, price_cents, attempts, error, created_by
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		`, batchID, res.Model, res.Provider, promptChars, maxTokens, ttfb, res.LatencyMs,
+			res.InputTokens, res.OutputTokens, res.ProviderCostCents, res.PriceCents, max(res.Attempts, 1),
+			emptyToNil(res.Error), createdBy); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// handleListModelBenchmarks returns stored benchmark measurements, newest
+// first, optionally for one model and since a time, for trend charts.
+func (h *AdminHandler) handleListModelBenchmarks(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 200
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = min(n, 1000)
+	}
+	var since any
+	if raw := strings.TrimSpace(query.Get("since")); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "since must be an RFC3339 timestamp")
+			return
+		}
+		since = t
+	}
+
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT batch_id, model, provider, prompt_chars, max_tokens, ttfb_ms, latency_ms,
+		       input_tokens, output_tokens, provider_cost_cents, price_cents, attempts, error, created_at
+		FROM model_benchmarks
+		WHERE ($1::text IS NULL OR model = $1)
+		  AND ($2::timestamptz IS NULL OR created_at >= $2)
+		ORDER BY created_at DESC
+		LIMIT $3
+	`, emptyToNil(strings.TrimSpace(query.Get("model"))), since, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query model benchmarks")
+		return
+	}
+	defer rows.Close()
+
+	benchmarks := make([]map[string]any, 0)
+	for rows.Next() {
+		var (
+			batchID, model, provider  string
+			promptChars, maxTokens    int
+			ttfb                      sql.NullInt64
+			latency                   int64
+			inputTokens, outputTokens int
+			providerCost              float64
+			priceCents, attempts      int
+			benchErr                  sql.NullString
+			createdAt                 time.Time
+		)
+		if err := rows.Scan(&batchID, &model, &provider, &promptChars, &maxTokens, &ttfb, &latency,
+			&inputTokens, &outputTokens, &providerCost, &priceCents, &attempts, &benchErr, &createdAt); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to scan model benchmark")
+			return
+		}
+		entry := map[string]any{
+			"batch_id":            batchID,
+			"model":               model,
+			"provider":            provider,
+			"prompt_chars":        promptChars,
+			"max_tokens":          maxTokens,
+			"ttfb_ms":             nil,
+			"latency_ms":          latency,
+			"input_tokens":        inputTokens,
+			"output_tokens":       outputTokens,
+			"provider_cost_cents": providerCost,
+			"price_cents":         priceCents,
+			"attempts":            attempts,
+			"error":               nullString(benchErr),
+			"created_at":          createdAt,
+		}
+		if ttfb.Valid {
+			entry["ttfb_ms"] = ttfb.Int64
+		}
+		benchmarks = append(benchmarks, entry)
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed while reading model benchmarks")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"benchmarks": benchmarks})
+}
@@ -0,0 +1,197 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/llmproxy"
+)
+
+type stubBenchmarker struct {
+	models    []string
+	maxTokens int
+	results   []llmproxy.BenchmarkResult
+	err       error
+}
+
+func (s *stubBenchmarker) Benchmark(_ context.Context, modelIDs []string, _ string, maxTokens int) ([]llmproxy.BenchmarkResult, error) {
+	s.models = modelIDs
+	s.maxTokens = maxTokens
+	return s.results, s.err
+}
+
+func TestAdminRunModelBenchmark(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	ttfb := int64(120)
+	bench := &stubBenchmarker{results: []llmproxy.BenchmarkResult{
+		{Model: "gpt-4o", Provider: "openai", TTFBMs: &ttfb, LatencyMs: 900, InputTokens: 10, OutputTokens: 20, ProviderCostCents: 0.02, PriceCents: 1, Attempts: 1},
+		{Model: "claude-sonnet", Provider: "anthropic", LatencyMs: 50, Error: "upstream returned 500"},
+		{Model: "gpt-4o-mini", Provider: "openai", LatencyMs: 300, Attempts: 1},
+	}}
+	mock.ExpectBegin()
+	for _, model := range []string{"gpt-4o-mini", "gpt-4o", "claude-sonnet"} {
+		mock.ExpectExec("INSERT INTO model_benchmarks").
+			WithArgs(sqlmock.AnyArg(), model, sqlmock.AnyArg(), 5, 128, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 1, sqlmock.AnyArg(), "unknown").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO admin_audit_log").WithArgs("unknown", "admin.models.benchmark", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	h := NewAdminHandler(db, nil)
+	h.Benchmarks = bench
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/models/benchmark", strings.NewReader(`{"models":["gpt-4o"," gpt-4o ","claude-sonnet","gpt-4o-mini",""],"prompt":" hello "}`))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	if fmt.Sprint(bench.models) != "[gpt-4o claude-sonnet gpt-4o-mini]" || bench.maxTokens != 128 {
+		t.Fatalf("benchmarked models=%v max_tokens=%d", bench.models, bench.maxTokens)
+	}
+
+	var resp struct {
+		BatchID string                     `json:"batch_id"`
+		Results []llmproxy.BenchmarkResult `json:"results"`
+		Stored  bool                       `json:"stored"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.BatchID == "" || !resp.Stored || len(resp.Results) != 3 {
+		t.Fatalf("resp = %+v", resp)
+	}
+	if resp.Results[0].Model != "gpt-4o-mini" || resp.Results[1].Model != "gpt-4o" || resp.Results[2].Model != "claude-sonnet" {
+		t.Fatalf("results not ordered by latency with errors last: %+v", resp.Results)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestAdminRunModelBenchmarkErrors(t *testing.T) {
+	t.Parallel()
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name   string
+		bench  ModelBenchmarker
+		body   string
+		status int
+	}{
+		{"no proxy", nil, `{"models":["m"],"prompt":"hi"}`, http.StatusServiceUnavailable},
+		{"bad json", &stubBenchmarker{}, `{"models":["m"],"prompt":"hi","extra":1}`, http.StatusBadRequest},
+		{"invalid", &stubBenchmarker{err: fmt.Errorf("%w: prompt too long", llmproxy.ErrInvalidBenchmark)}, `{"models":["m"],"prompt":"hi"}`, http.StatusBadRequest},
+		{"running", &stubBenchmarker{err: llmproxy.ErrBenchmarkRunning}, `{"models":["m"],"prompt":"hi"}`, http.StatusConflict},
+	}
+	for _, tc := range tests {
+		h := NewAdminHandler(db, nil)
+		h.Benchmarks = tc.bench
+		rec := httptest.NewRecorder()
+		h.handleRunModelBenchmark(rec, httptest.NewRequest(http.MethodPost, "/api/admin/models/benchmark", strings.NewReader(tc.body)))
+		if rec.Code != tc.status {
+			t.Errorf("%s: status = %d, want %d (body=%s)", tc.name, rec.Code, tc.status, rec.Body.String())
+		}
+	}
+}
+
+func TestAdminListModelBenchmarks(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM model_benchmarks").WithArgs("gpt-4o", since, 50).WillReturnRows(sqlmock.NewRows([]string{
+		"batch_id", "model", "provider", "prompt_chars", "max_tokens", "ttfb_ms", "latency_ms",
+		"input_tokens", "output_tokens", "provider_cost_cents", "price_cents", "attempts", "error", "created_at",
+	}).
+		AddRow("b1", "gpt-4o", "openai", 5, 128, 120, 900, 10, 20, 0.02, 1, 1, nil, since.Add(time.Hour)).
+		AddRow("b0", "gpt-4o", "openai", 5, 128, nil, 30, 0, 0, 0.0, 0, 3, "upstream returned 500", since))
+
+	h := NewAdminHandler(db, nil)
+	rec := httptest.NewRecorder()
+	h.handleListModelBenchmarks(rec, httptest.NewRequest(http.MethodGet, "/api/admin/models/benchmarks?model=gpt-4o&since=2026-10-01T00:00:00Z&limit=50", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Benchmarks []map[string]any `json:"benchmarks"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Benchmarks) != 2 || resp.Benchmarks[0]["ttfb_ms"] != float64(120) || resp.Benchmarks[1]["ttfb_ms"] != nil || resp.Benchmarks[1]["error"] != "upstream returned 500" {
+		t.Fatalf("benchmarks = %+v", resp.Benchmarks)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+
+	for _, query := range []string{"limit=0", "since=yesterday"} {
+		rec := httptest.NewRecorder()
+		h.handleListModelBenchmarks(rec, httptest.NewRequest(http.MethodGet, "/api/admin/models/benchmarks?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+type stubModelTester struct {
+	result llmproxy.ModelTestResult
+	err    error
+}
+
+func (s *stubModelTester) TestModel(_ context.Context, modelID string) (llmproxy.ModelTestResult, error) {
+	s.result.Model = modelID
+	return s.result, s.err
+}
+
+func TestAdminTestModel(t *testing.T) {
+	t.Parallel()
+	h := NewAdminHandler(nil, nil)
+	h.ModelTests = &stubModelTester{result: llmproxy.ModelTestResult{Error: "invalid api key", LatencyMs: 40}}
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/admin/models/gpt-4o/test", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	var got llmproxy.ModelTestResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Model != "gpt-4o" || got.OK || got.Error != "invalid api key" {
+		t.Fatalf("result = %+v", got)
+	}
+
+	h.ModelTests = &stubModelTester{err: fmt.Errorf("%w: nope", llmproxy.ErrModelNotFound)}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/admin/models/nope/test", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("missing model status = %d", rec.Code)
+	}
+}
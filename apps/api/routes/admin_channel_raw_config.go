@@ -0,0 +1,160 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/keyring"
+)
+
+const (
+	rawConfigOverrideHeader = "X-Admin-Override-Confirm"
+	rawConfigAccessWindow   = 15 * time.Minute
+)
+
+func rawConfigAccessKey(adminID, tenantID, channel string) string {
+	return "admin_raw_config_access:" + adminID + ":" + tenantID + ":" + channel
+}
+
+// handleRawChannelConfig returns a tenant channel's credentials decrypted,
+// unlike the masked credentials in the channel list. The first access needs
+// an X-Admin-Override-Confirm: true header; it opens a rawConfigAccessWindow
+// for the same admin, tenant and channel in which the header can be omitted.
+// Every access is written to the audit log with the fields returned.
+func (h *AdminHandler) handleRawChannelConfig(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	channel := strings.ToLower(strings.TrimSpace(r.PathValue("channel")))
+	if tenantID == "" || channel == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id or channel")
+		return
+	}
+
+	accessKey := rawConfigAccessKey(adminActorID(r.Context()), tenantID, channel)
+	inWindow := h.rawConfigWindowOpen(r.Context(), accessKey)
+	confirmed := strings.EqualFold(strings.TrimSpace(r.Header.Get(rawConfigOverrideHeader)), "true")
+	if !inWindow && !confirmed {
+		writeError(w, http.StatusForbidden, rawConfigOverrideHeader+": true is required to read raw channel credentials")
+		return
+	}
+
+	var configJSON string
+	err := h.DB.QueryRowContext(r.Context(), `
+		SELECT COALESCE(NULLIF(config::text, ''), '{}')
+		FROM channel_credentials
+		WHERE tenant_id = $1 AND channel = $2
+	`, tenantID, channel).Scan(&configJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "channel credentials not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load channel credentials")
+		return
+	}
+	config := map[string]any{}
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		writeError(w, http.StatusInternalServerError, "stored channel credentials are not valid JSON")
+		return
+	}
+
+	decrypted, err := h.decryptChannelConfig(config)
+	if err != nil {
+		slog.Error("failed to decrypt channel credentials", "tenant_id", tenantID, "channel", channel, "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to decrypt channel credentials")
+		return
+	}
+
+	access := "access_window"
+	if !inWindow {
+		access = "override_header"
+		h.openRawConfigWindow(r.Context(), accessKey)
+	}
+	fields := make([]string, 0, len(config))
+	for key := range config {
+		fields = append(fields, key)
+	}
+	sort.Strings(fields)
+	h.logAdminAction(r.Context(), "admin.channels.raw_config", tenantID, map[string]any{
+		"channel":          channel,
+		"fields":           fields,
+		"decrypted_fields": decrypted,
+		"access":           access,
+	})
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tenant_id": tenantID,
+		"channel":   channel,
+		"config":    config,
+	})
+}
+
+// decryptChannelConfig replaces the encrypted string values in config with
+// their plaintext and returns the keys it decrypted, sorted. Values stored in
+// plaintext are left as they are.
+func (h *AdminHandler) decryptChannelConfig(config map[string]any) ([]string, error) {
+	var (
+		keys      *keyring.Keyring
+		decrypted = []string{}
+	)
+	for key, value := range config {
+		s, ok := value.(string)
+		if !ok || !keyring.IsEncrypted(s) {
+			continue
+		}
+		if keys == nil {
+			load := h.keys
+			if load == nil {
+				load = keyring.FromEnv
+			}
+			var err error
+			if keys, err = load(); err != nil {
+				return nil, err
+			}
+		}
+		plaintext, err := keys.Decrypt(s)
+		if err != nil {
+			return nil, err
+		}
+		config[key] = plaintext
+		decrypted = append(decrypted, key)
+	}
+	sort.Strings(decrypted)
+	return decrypted, nil
+}
+
+// rawConfigWindowOpen reports whether the admin confirmed access to the
+// channel's raw credentials within rawConfigAccessWindow. Without Redis
+// there is no window and every access needs the override header.
+func (h *AdminHandler) rawConfigWindowOpen(ctx context.Context, key string) bool {
+	if h.Redis == nil {
+		return false
+	}
+	n, err := h.Redis.Exists(ctx, key).Result()
+	if err != nil {
+		slog.Warn("failed to check raw config access window", "key", key, "err", err)
+		return false
+	}
+	return n > 0
+}
+
+// openRawConfigWindow starts the access window. A window that is already
+// open is not extended, so it always ends rawConfigAccessWindow after the
+// confirmed access that opened it.
+func (h *AdminHandler) openRawConfigWindow(ctx context.Context, key string) {
+	if h.Redis == nil {
+		return
+	}
+	if err := h.Redis.SetNX(ctx, key, "1", rawConfigAccessWindow).Err(); err != nil {
+		slog.Warn("failed to open raw config access window", "key", key, "err", err)
+	}
+}
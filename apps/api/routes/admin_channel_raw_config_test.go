@@ -0,0 +1,66 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/keyring"
+)
+
+func TestRawChannelConfig(t *testing.T) {
+	t.Parallel()
+	keys, err := keyring.New("0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatalf("keyring.New: %v", err)
+	}
+	token, err := keys.Encrypt("123:bot-token")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	h := NewAdminHandler(db, nil)
+	h.keys = func() (*keyring.Keyring, error) { return keys, nil }
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/tenants/t1/channels/telegram/raw-config", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("without confirmation status = %d, want 403", w.Code)
+	}
+
+	config, _ := json.Marshal(map[string]any{"bot_token": token, "bot_username": "acme_bot"})
+	mock.ExpectQuery("FROM channel_credentials").WithArgs("t1", "telegram").
+		WillReturnRows(sqlmock.NewRows([]string{"config"}).AddRow(string(config)))
+	mock.ExpectExec("INSERT INTO admin_audit_log").
+		WithArgs(sqlmock.AnyArg(), "admin.channels.raw_config", "t1",
+			`{"access":"override_header","channel":"telegram","decrypted_fields":["bot_token"],"fields":["bot_token","bot_username"]}`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/tenants/t1/channels/telegram/raw-config", nil)
+	req.Header.Set("X-Admin-Override-Confirm", "true")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Config map[string]string `json:"config"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Config["bot_token"] != "123:bot-token" || resp.Config["bot_username"] != "acme_bot" {
+		t.Fatalf("config = %v", resp.Config)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
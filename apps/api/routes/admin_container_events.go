@@ -0,0 +1,109 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/orchestrator"
+)
+
+const (
+	containerEventsMaxDuration = 5 * time.Minute
+	containerEventsHeartbeat   = 20 * time.Second
+)
+
+// handleContainerEvents streams lifecycle events for the tenant's container
+// as SSE. Streams are closed after five minutes so abandoned dashboards do
+// not hold connections open.
+func (h *AdminHandler) handleContainerEvents(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	streamer, ok := orchestratorCapability[orchestrator.EventStreamer](w, h.Orch, "container events")
+	if !ok {
+		return
+	}
+
+	var containerID sql.NullString
+	err := h.DB.QueryRowContext(r.Context(), `SELECT container_id FROM tenants WHERE id = $1`, tenantID).Scan(&containerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load tenant")
+		return
+	}
+	id := strings.TrimSpace(containerID.String)
+	if id == "" {
+		writeError(w, http.StatusNotFound, "tenant container is not provisioned")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), containerEventsMaxDuration)
+	defer cancel()
+	events, errs, err := streamer.ContainerEvents(ctx, tenantID)
+	if err != nil {
+		if errors.Is(err, orchestrator.ErrContainerNotFound) {
+			writeError(w, http.StatusNotFound, "tenant container not found")
+			return
+		}
+		slog.Warn("container events unavailable", "tenant_id", tenantID, "container_id", id, "err", err)
+		writeError(w, http.StatusBadGateway, "failed to inspect tenant container")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(containerEventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			_, _ = w.Write([]byte(": keep-alive\n\n"))
+			flusher.Flush()
+		case evt := <-events:
+			payload, err := json.Marshal(evt)
+			if err != nil {
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "event: docker\ndata: %s\n\n", payload)
+			flusher.Flush()
+		case err := <-errs:
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Warn("container events stream failed", "tenant_id", tenantID, "container_id", id, "err", err)
+			writeSSEError(w, flusher, "docker events stream failed")
+			return
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/orchestrator"
+)
+
+type eventsOrchestrator struct {
+	stubOrchestrator
+	err      error
+	tenantID string
+	events   chan orchestrator.ContainerEvent
+	errs     chan error
+}
+
+func (o *eventsOrchestrator) ContainerEvents(_ context.Context, tenantID string) (<-chan orchestrator.ContainerEvent, <-chan error, error) {
+	o.tenantID = tenantID
+	return o.events, o.errs, o.err
+}
+
+func newContainerEventsHandler(t *testing.T, orch orchestrator.TenantOrchestrator) *AdminHandler {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	mock.ExpectQuery("SELECT container_id FROM tenants").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"container_id"}).AddRow("c1"))
+	return NewAdminHandler(db, orch)
+}
+
+func TestContainerEventsStreamsEvents(t *testing.T) {
+	t.Parallel()
+	orch := &eventsOrchestrator{events: make(chan orchestrator.ContainerEvent), errs: make(chan error)}
+	go func() {
+		orch.events <- orchestrator.ContainerEvent{Action: "start", Time: 100, Status: "running"}
+		orch.events <- orchestrator.ContainerEvent{Action: "die", Time: 101, Status: "exited (137)"}
+		orch.errs <- errors.New("stream closed")
+	}()
+	h := newContainerEventsHandler(t, orch)
+
+	mux := http.NewServeMux()
+	h.Mount(mux)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/tenants/t1/container/events", nil))
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status = %d headers=%v", w.Code, w.Header())
+	}
+	if orch.tenantID != "t1" {
+		t.Fatalf("tenant = %q", orch.tenantID)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"event: docker\ndata: {\"action\":\"start\",\"time\":100,\"status\":\"running\"}\n\n",
+		"event: docker\ndata: {\"action\":\"die\",\"time\":101,\"status\":\"exited (137)\"}\n\n",
+		"event: error\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("body missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestContainerEventsMissingContainer(t *testing.T) {
+	t.Parallel()
+	h := newContainerEventsHandler(t, &eventsOrchestrator{err: fmt.Errorf("docker inspect: %w", orchestrator.ErrContainerNotFound)})
+
+	mux := http.NewServeMux()
+	h.Mount(mux)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/tenants/t1/container/events", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+}
+
+// TestContainerOperationsNeedBackendSupport checks that container operations
+// only some backends support answer 501 elsewhere, before touching the
+// database.
+func TestContainerOperationsNeedBackendSupport(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mux := http.NewServeMux()
+	NewAdminHandler(db, &stubOrchestrator{}).Mount(mux)
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/api/admin/tenants/t1/network"},
+		{http.MethodGet, "/api/admin/tenants/t1/container/events"},
+		{http.MethodPost, "/api/tenants/t1/container/snapshot"},
+		{http.MethodPost, "/api/admin/tenants/t1/container/copy-files"},
+		{http.MethodPatch, "/api/admin/tenants/t1/container"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(route.method, route.path, strings.NewReader(`{}`)))
+		if w.Code != http.StatusNotImplemented {
+			t.Errorf("%s %s: status = %d, want 501 body=%s", route.method, route.path, w.Code, w.Body.String())
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package routes
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/agentsquads/api/orchestrator"
+)
+
+const (
+	// reservedLabelPrefix marks labels the platform sets and reads itself.
+	reservedLabelPrefix = "agentsquads."
+	maxContainerLabels  = 64
+	maxLabelValueLength = 256
+)
+
+// labelKeyPattern follows Docker's recommended label key format.
+var labelKeyPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9._-]{0,126}[a-z0-9])?$`)
+
+// handleUpdateContainerLabels merges {"labels": {...}} into the labels of the
+// tenant container; a null value removes a label. Labels under agentsquads.
+// belong to the platform and cannot be changed. Backends that cannot relabel
+// in place replace the container, so the stored container id is updated.
+func (h *AdminHandler) handleUpdateContainerLabels(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	relabeler, ok := orchestratorCapability[orchestrator.Relabeler](w, h.Orch, "changing container labels")
+	if !ok {
+		return
+	}
+	var req struct {
+		Labels map[string]*string `json:"labels"`
+	}
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if len(req.Labels) == 0 {
+		writeError(w, http.StatusBadRequest, "labels is required")
+		return
+	}
+	for key, value := range req.Labels {
+		if err := validateContainerLabel(key, value); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	var (
+		containerID sql.NullString
+		rawLabels   []byte
+	)
+	err := h.DB.QueryRowContext(r.Context(), `SELECT container_id, container_labels FROM tenants WHERE id = $1`, tenantID).Scan(&containerID, &rawLabels)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load tenant")
+		return
+	}
+	oldID := strings.TrimSpace(containerID.String)
+	if oldID == "" {
+		writeError(w, http.StatusNotFound, "tenant container is not provisioned")
+		return
+	}
+	applied := map[string]string{}
+	if len(rawLabels) > 0 {
+		if err := json.Unmarshal(rawLabels, &applied); err != nil {
+			slog.Warn("ignoring unreadable container labels", "tenant_id", tenantID, "err", err)
+			applied = map[string]string{}
+		}
+	}
+	for key, value := range req.Labels {
+		if value == nil {
+			delete(applied, key)
+		} else {
+			applied[key] = *value
+		}
+	}
+	if len(applied) > maxContainerLabels {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d labels are allowed", maxContainerLabels))
+		return
+	}
+
+	newID, err := relabeler.Relabel(r.Context(), tenantID, req.Labels)
+	if err != nil {
+		if errors.Is(err, orchestrator.ErrContainerNotFound) {
+			writeError(w, http.StatusNotFound, "tenant container not found")
+			return
+		}
+		slog.Error("container relabel failed", "tenant_id", tenantID, "container_id", oldID, "err", err)
+		writeError(w, http.StatusBadGateway, "failed to update container labels")
+		return
+	}
+
+	encoded, _ := json.Marshal(applied)
+	if _, err := h.DB.ExecContext(r.Context(), `UPDATE tenants SET container_id = $1, container_labels = $2 WHERE id = $3`, newID, encoded, tenantID); err != nil {
+		// The new container is already running under the tenant's name and
+		// alias, so traffic reaches it; only the stored id is stale.
+		slog.Error("failed to record relabeled container", "tenant_id", tenantID, "container_id", newID, "err", err)
+		writeError(w, http.StatusInternalServerError, "container relabeled but failed to save it")
+		return
+	}
+
+	h.logAdminAction(r.Context(), "admin.tenants.container_labels", tenantID, map[string]any{
+		"old_container_id": oldID,
+		"container_id":     newID,
+		"labels":           req.Labels,
+	})
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tenant_id":    tenantID,
+		"container_id": newID,
+		"labels":       applied,
+	})
+}
+
+func validateContainerLabel(key string, value *string) error {
+	if !labelKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid label key %q", key)
+	}
+	if strings.HasPrefix(key, reservedLabelPrefix) {
+		return fmt.Errorf("label %q is reserved", key)
+	}
+	if value != nil && len(*value) > maxLabelValueLength {
+		return fmt.Errorf("label %q value must be at most %d bytes", key, maxLabelValueLength)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type relabelOrchestrator struct {
+	stubOrchestrator
+	tenantID string
+	patch    map[string]*string
+	err      error
+}
+
+func (o *relabelOrchestrator) Relabel(_ context.Context, tenantID string, patch map[string]*string) (string, error) {
+	o.tenantID = tenantID
+	o.patch = patch
+	if o.err != nil {
+		return "", o.err
+	}
+	return "new", nil
+}
+
+func TestUpdateContainerLabels(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	orch := &relabelOrchestrator{}
+	mux := http.NewServeMux()
+	NewAdminHandler(db, orch).Mount(mux)
+
+	mock.ExpectQuery("SELECT container_id, container_labels FROM tenants").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"container_id", "container_labels"}).AddRow("old", []byte(`{"team":"old"}`)))
+	mock.ExpectExec("UPDATE tenants SET container_id").
+		WithArgs("new", []byte(`{"environment":"production"}`), "t1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO admin_audit_log").
+		WithArgs(sqlmock.AnyArg(), "admin.tenants.container_labels", "t1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/admin/tenants/t1/container",
+		bytes.NewBufferString(`{"labels":{"environment":"production","team":null}}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	if orch.tenantID != "t1" || *orch.patch["environment"] != "production" || orch.patch["team"] != nil || len(orch.patch) != 2 {
+		t.Fatalf("relabel of %q with %v", orch.tenantID, orch.patch)
+	}
+	var resp struct {
+		ContainerID string            `json:"container_id"`
+		Labels      map[string]string `json:"labels"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.ContainerID != "new" || len(resp.Labels) != 1 || resp.Labels["environment"] != "production" {
+		t.Fatalf("response = %+v", resp)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestUpdateContainerLabelsFailures(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	orch := &relabelOrchestrator{err: errors.New("start container: port in use")}
+	mux := http.NewServeMux()
+	NewAdminHandler(db, orch).Mount(mux)
+
+	for _, body := range []string{`{"labels":{}}`, `{"labels":{"agentsquads.tenant":"x"}}`, `{"labels":{"Bad Key":"x"}}`} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/admin/tenants/t1/container", bytes.NewBufferString(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, w.Code)
+		}
+	}
+
+	mock.ExpectQuery("SELECT container_id, container_labels FROM tenants").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"container_id", "container_labels"}).AddRow("old", []byte(`{}`)))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/admin/tenants/t1/container",
+		bytes.NewBufferString(`{"labels":{"team":"platform"}}`)))
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
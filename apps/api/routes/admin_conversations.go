@@ -0,0 +1,181 @@
+package routes
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultAdminConversationMessages = 100
+	maxAdminConversationMessages     = 500
+	maxAdminConversationSearch       = 200
+)
+
+var (
+	// metadataKeyPattern bounds has_metadata_key to plain key names.
+	metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,100}$`)
+	likeEscaper        = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+)
+
+type adminConversationMessage struct {
+	ID        string          `json:"id"`
+	Role      string          `json:"role"`
+	Content   string          `json:"content"`
+	Channel   string          `json:"channel"`
+	Metadata  json.RawMessage `json:"metadata"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type adminConversation struct {
+	ID        string                     `json:"id"`
+	Title     *string                    `json:"title"`
+	CreatedAt time.Time                  `json:"created_at"`
+	Messages  []adminConversationMessage `json:"messages"`
+}
+
+// handleListTenantConversations returns a tenant's messages matching the
+// filters, newest first, grouped by conversation. search matches message
+// content case-insensitively as a literal substring; role, channel and
+// has_metadata_key narrow it further. limit caps the number of messages and
+// before pages back through older ones. Reads are audited because they
+// expose end-user content.
+func (h *AdminHandler) handleListTenantConversations(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+
+	query := r.URL.Query()
+	search := strings.TrimSpace(query.Get("search"))
+	if utf8.RuneCountInString(search) > maxAdminConversationSearch {
+		writeError(w, http.StatusBadRequest, "search must be at most "+strconv.Itoa(maxAdminConversationSearch)+" characters")
+		return
+	}
+	role := strings.TrimSpace(query.Get("role"))
+	if role != "" && role != "user" && role != "assistant" {
+		writeError(w, http.StatusBadRequest, "role must be user or assistant")
+		return
+	}
+	channel := strings.ToLower(strings.TrimSpace(query.Get("channel")))
+	switch channel {
+	case "", "web", "telegram", "whatsapp", "viber":
+	default:
+		writeError(w, http.StatusBadRequest, "channel must be web, telegram, whatsapp or viber")
+		return
+	}
+	metadataKey := strings.TrimSpace(query.Get("has_metadata_key"))
+	if metadataKey != "" && !metadataKeyPattern.MatchString(metadataKey) {
+		writeError(w, http.StatusBadRequest, "invalid has_metadata_key")
+		return
+	}
+	limit := defaultAdminConversationMessages
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = min(n, maxAdminConversationMessages)
+	}
+	var before any
+	if raw := strings.TrimSpace(query.Get("before")); raw != "" {
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "before must be an RFC3339 timestamp")
+			return
+		}
+		before = t
+	}
+
+	var exists bool
+	err := h.DB.QueryRowContext(r.Context(), `SELECT TRUE FROM tenants WHERE id = $1`, tenantID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load tenant")
+		return
+	}
+
+	var pattern any
+	if search != "" {
+		pattern = likeEscaper.Replace(search)
+	}
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT c.id, c.title, c.created_at, m.id, m.role, m.content, m.channel, COALESCE(m.metadata, '{}'::jsonb), m.created_at
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE c.tenant_id = $1
+		  AND ($2::text IS NULL OR m.content ILIKE '%' || $2 || '%')
+		  AND ($3::text IS NULL OR m.role = $3)
+		  AND ($4::text IS NULL OR m.channel = $4)
+		  AND ($5::text IS NULL OR m.metadata ? $5)
+		  AND ($6::timestamptz IS NULL OR m.created_at < $6)
+		ORDER BY m.created_at DESC, m.id DESC
+		LIMIT $7
+	`, tenantID, pattern, emptyToNil(role), emptyToNil(channel), emptyToNil(metadataKey), before, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query conversations")
+		return
+	}
+	defer rows.Close()
+
+	conversations := make([]*adminConversation, 0)
+	byID := make(map[string]*adminConversation)
+	returned := 0
+	for rows.Next() {
+		var (
+			conv     adminConversation
+			title    sql.NullString
+			msg      adminConversationMessage
+			metadata []byte
+		)
+		if err := rows.Scan(&conv.ID, &title, &conv.CreatedAt, &msg.ID, &msg.Role, &msg.Content, &msg.Channel, &metadata, &msg.CreatedAt); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to scan conversation message")
+			return
+		}
+		msg.Metadata = metadata
+		existing, ok := byID[conv.ID]
+		if !ok {
+			if title.Valid {
+				conv.Title = &title.String
+			}
+			existing = &conv
+			byID[conv.ID] = existing
+			conversations = append(conversations, existing)
+		}
+		existing.Messages = append(existing.Messages, msg)
+		returned++
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed while reading conversations")
+		return
+	}
+
+	h.logAdminAction(r.Context(), "admin.tenants.conversations", tenantID, map[string]any{
+		"search":           search,
+		"role":             role,
+		"channel":          channel,
+		"has_metadata_key": metadataKey,
+		"returned":         returned,
+	})
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tenant_id":     tenantID,
+		"conversations": conversations,
+		"returned":      returned,
+		"limit":         limit,
+	})
+}
@@ -0,0 +1,86 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestListTenantConversationsFilters(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mux := http.NewServeMux()
+	NewAdminHandler(db, nil).Mount(mux)
+
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT TRUE FROM tenants").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("FROM messages m").
+		WithArgs("t1", `50\% off`, "user", "whatsapp", "whatsapp_message_id", nil, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"cid", "title", "ccreated", "mid", "role", "content", "channel", "metadata", "mcreated"}).
+			AddRow("c1", "Promo", now, "m2", "user", "50% off today?", "whatsapp", `{"whatsapp_message_id":"w2"}`, now).
+			AddRow("c1", "Promo", now, "m1", "user", "is it 50% off", "whatsapp", `{"whatsapp_message_id":"w1"}`, now.Add(-time.Minute)))
+	mock.ExpectExec("INSERT INTO admin_audit_log").
+		WithArgs(sqlmock.AnyArg(), "admin.tenants.conversations", "t1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+		"/api/admin/tenants/t1/conversations?search=50%25+off&role=user&channel=whatsapp&has_metadata_key=whatsapp_message_id&limit=2", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Conversations []adminConversation `json:"conversations"`
+		Returned      int                 `json:"returned"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Returned != 2 || len(resp.Conversations) != 1 || len(resp.Conversations[0].Messages) != 2 || resp.Conversations[0].Messages[0].ID != "m2" {
+		t.Fatalf("unexpected response: %s", w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestListTenantConversationsRejectsBadFilters(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mux := http.NewServeMux()
+	NewAdminHandler(db, nil).Mount(mux)
+
+	long := make([]byte, maxAdminConversationSearch+1)
+	for i := range long {
+		long[i] = 'a'
+	}
+	for _, query := range []string{"role=system", "channel=sms", "has_metadata_key=a'%3B--", "limit=0", "before=yesterday", "search=" + string(long)} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/tenants/t1/conversations?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, w.Code)
+		}
+	}
+
+	mock.ExpectQuery("SELECT TRUE FROM tenants").WithArgs("missing").WillReturnRows(sqlmock.NewRows([]string{"exists"}))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/tenants/missing/conversations", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unknown tenant status = %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
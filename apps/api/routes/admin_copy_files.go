@@ -0,0 +1,215 @@
+package routes
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/orchestrator"
+)
+
+const (
+	// maxContainerCopyBytes caps a copy-files upload, all files together.
+	maxContainerCopyBytes int64 = 10 << 20
+	// defaultContainerCopyPrefix is where files may be copied unless
+	// CONTAINER_COPY_ALLOWED_PREFIX says otherwise.
+	defaultContainerCopyPrefix = "/app"
+)
+
+type copiedFile struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+// handleCopyFiles copies the files of a multipart upload into the tenant
+// container's destination_path directory, for injecting config files or
+// patches. The destination must be under CONTAINER_COPY_ALLOWED_PREFIX (/app
+// by default) and uploads are capped at 10 MB. Files are copied one at a
+// time, so a failure part way leaves the earlier files in place; those are
+// still audited.
+func (h *AdminHandler) handleCopyFiles(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	copier, ok := orchestratorCapability[orchestrator.FileCopier](w, h.Orch, "copying files into containers")
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxContainerCopyBytes)
+	if err := r.ParseMultipartForm(maxContainerCopyBytes); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, "upload exceeds the 10 MB limit")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "multipart form with destination_path and file fields is required")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	destination, err := containerCopyDestination(r.FormValue("destination_path"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	uploads, err := containerCopyUploads(r.MultipartForm.File)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var containerID sql.NullString
+	err = h.DB.QueryRowContext(r.Context(), `SELECT container_id FROM tenants WHERE id = $1`, tenantID).Scan(&containerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load tenant")
+		return
+	}
+	id := strings.TrimSpace(containerID.String)
+	if id == "" {
+		writeError(w, http.StatusNotFound, "tenant container is not provisioned")
+		return
+	}
+
+	copied := make([]copiedFile, 0, len(uploads))
+	var copyErr error
+	for _, upload := range uploads {
+		if copyErr = copyFileToContainer(r.Context(), copier, tenantID, destination, upload); copyErr != nil {
+			slog.Error("container file copy failed", "tenant_id", tenantID, "container_id", id, "file", upload.name, "err", copyErr)
+			break
+		}
+		copied = append(copied, copiedFile{Name: upload.name, Path: path.Join(destination, upload.name), SizeBytes: upload.file.Size})
+	}
+
+	if len(copied) > 0 {
+		names := make([]string, 0, len(copied))
+		for _, f := range copied {
+			names = append(names, f.Name)
+		}
+		h.logAdminAction(r.Context(), "admin.tenants.container_copy_files", tenantID, map[string]any{
+			"container_id":     id,
+			"destination_path": destination,
+			"files":            names,
+		})
+	}
+	if copyErr != nil {
+		if errors.Is(copyErr, orchestrator.ErrContainerNotFound) {
+			writeError(w, http.StatusNotFound, "tenant container or destination_path not found")
+			return
+		}
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("failed to copy files into the tenant container after %d of %d", len(copied), len(uploads)))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tenant_id":        tenantID,
+		"container_id":     id,
+		"destination_path": destination,
+		"files":            copied,
+	})
+}
+
+// containerCopyDestination cleans raw and checks it is under the allowed
+// prefix.
+func containerCopyDestination(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", errors.New("destination_path is required")
+	}
+	prefix := strings.TrimSpace(os.Getenv("CONTAINER_COPY_ALLOWED_PREFIX"))
+	if prefix == "" {
+		prefix = defaultContainerCopyPrefix
+	}
+	prefix = path.Clean(prefix)
+	destination := path.Clean(raw)
+	if !path.IsAbs(raw) || (destination != prefix && !strings.HasPrefix(destination, strings.TrimSuffix(prefix, "/")+"/")) {
+		return "", fmt.Errorf("destination_path must be under %s", prefix)
+	}
+	return destination, nil
+}
+
+type containerCopyUpload struct {
+	name string
+	file *multipart.FileHeader
+}
+
+// containerCopyUploads returns the uploaded files ordered by form field, with
+// their names reduced to a base name so they land in the destination
+// directory.
+func containerCopyUploads(fields map[string][]*multipart.FileHeader) ([]containerCopyUpload, error) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var uploads []containerCopyUpload
+	seen := map[string]bool{}
+	for _, key := range keys {
+		for _, fh := range fields[key] {
+			name := path.Base(strings.ReplaceAll(fh.Filename, "\\", "/"))
+			if name == "." || name == "/" || name == ".." || name == "" {
+				return nil, fmt.Errorf("invalid file name %q", fh.Filename)
+			}
+			if seen[name] {
+				return nil, fmt.Errorf("file %q is uploaded more than once", name)
+			}
+			seen[name] = true
+			uploads = append(uploads, containerCopyUpload{name: name, file: fh})
+		}
+	}
+	if len(uploads) == 0 {
+		return nil, errors.New("at least one file is required")
+	}
+	return uploads, nil
+}
+
+// copyFileToContainer copies upload into dir in the container as a
+// single-file tar archive.
+func copyFileToContainer(ctx context.Context, copier orchestrator.FileCopier, tenantID, dir string, upload containerCopyUpload) error {
+	f, err := upload.file.Open()
+	if err != nil {
+		return fmt.Errorf("open upload: %w", err)
+	}
+	defer f.Close()
+
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    upload.name,
+		Mode:    0o644,
+		Size:    upload.file.Size,
+		ModTime: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("write tar header: %w", err)
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("write tar: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("write tar: %w", err)
+	}
+	return copier.CopyToContainer(ctx, tenantID, dir, &archive)
+}
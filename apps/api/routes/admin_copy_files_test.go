@@ -0,0 +1,126 @@
+package routes
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type copyOrchestrator struct {
+	stubOrchestrator
+	copied  map[string]string
+	failOn  string
+	dstPath string
+}
+
+func (f *copyOrchestrator) CopyToContainer(_ context.Context, tenantID, dstPath string, content io.Reader) error {
+	if tenantID != "t1" {
+		return fmt.Errorf("copy into tenant %s", tenantID)
+	}
+	f.dstPath = dstPath
+	tr := tar.NewReader(content)
+	header, err := tr.Next()
+	if err != nil {
+		return err
+	}
+	if header.Name == f.failOn {
+		return errors.New("copy failed")
+	}
+	body, _ := io.ReadAll(tr)
+	if f.copied == nil {
+		f.copied = map[string]string{}
+	}
+	f.copied[header.Name] = string(body)
+	return nil
+}
+
+func copyFilesRequest(t *testing.T, destination string, files map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("destination_path", destination)
+	for name, content := range files {
+		part, err := mw.CreateFormFile("file_"+name, name)
+		if err != nil {
+			t.Fatalf("CreateFormFile: %v", err)
+		}
+		_, _ = part.Write([]byte(content))
+	}
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/tenants/t1/container/copy-files", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestCopyFiles(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		destination string
+		files       map[string]string
+		failOn      string
+		wantStatus  int
+		wantCopied  int
+	}{
+		{name: "copies", destination: "/app/config/", files: map[string]string{"a.yaml": "a: 1", "b.patch": "diff"}, wantStatus: http.StatusOK, wantCopied: 2},
+		{name: "outside prefix", destination: "/app/../etc", files: map[string]string{"a.yaml": "a: 1"}, wantStatus: http.StatusBadRequest},
+		{name: "prefix lookalike", destination: "/application", files: map[string]string{"a.yaml": "a: 1"}, wantStatus: http.StatusBadRequest},
+		{name: "no files", destination: "/app", wantStatus: http.StatusBadRequest},
+		{name: "too large", destination: "/app", files: map[string]string{"big.bin": strings.Repeat("x", int(maxContainerCopyBytes))}, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "copy fails part way", destination: "/app", files: map[string]string{"a.yaml": "a: 1", "b.patch": "diff"}, failOn: "b.patch", wantStatus: http.StatusBadGateway, wantCopied: 1},
+	}
+	for _, tt := range tests {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New: %v", err)
+		}
+		fake := &copyOrchestrator{failOn: tt.failOn}
+		if tt.wantStatus == http.StatusOK || tt.wantCopied > 0 {
+			mock.ExpectQuery("SELECT container_id FROM tenants").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"container_id"}).AddRow("c1"))
+			mock.ExpectExec("INSERT INTO admin_audit_log").WithArgs("unknown", "admin.tenants.container_copy_files", "t1", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+		}
+		h := NewAdminHandler(db, fake)
+
+		mux := http.NewServeMux()
+		h.Mount(mux)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, copyFilesRequest(t, tt.destination, tt.files))
+
+		if w.Code != tt.wantStatus {
+			t.Fatalf("%s: status = %d, want %d body=%s", tt.name, w.Code, tt.wantStatus, w.Body.String())
+		}
+		if len(fake.copied) != tt.wantCopied {
+			t.Fatalf("%s: copied = %v", tt.name, fake.copied)
+		}
+		if tt.wantStatus == http.StatusOK {
+			var resp struct {
+				DestinationPath string       `json:"destination_path"`
+				Files           []copiedFile `json:"files"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("%s: decode: %v", tt.name, err)
+			}
+			if resp.DestinationPath != "/app/config" || fake.dstPath != "/app/config" || fake.copied["a.yaml"] != "a: 1" {
+				t.Fatalf("%s: resp=%+v dst=%q copied=%v", tt.name, resp, fake.dstPath, fake.copied)
+			}
+			if len(resp.Files) != 2 || resp.Files[0].Path != "/app/config/a.yaml" || resp.Files[0].SizeBytes != 4 {
+				t.Fatalf("%s: files = %+v", tt.name, resp.Files)
+			}
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("%s: expectations: %v", tt.name, err)
+		}
+		db.Close()
+	}
+}
@@ -0,0 +1,327 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/agentsquads/api/middleware"
+	"github.com/google/uuid"
+)
+
+const autoSuspendNegativeBalanceReason = "auto_suspend_negative_balance"
+
+type negativeBalance struct {
+	TenantID        string `json:"tenant_id"`
+	Email           string `json:"email"`
+	BalanceCents    int64  `json:"balance_cents"`
+	TenantStatus    string `json:"tenant_status"`
+	ContainerStatus string `json:"container_status"`
+	AutoSuspended   bool   `json:"auto_suspended,omitempty"`
+	SuspendError    string `json:"suspend_error,omitempty"`
+
+	containerID sql.NullString
+}
+
+// handleNegativeBalances lists tenants whose credit balance has gone below
+// zero, most indebted first. With ?auto_suspend=true every listed tenant that
+// is not already suspended is stopped, marked suspended, and gets a zero-amount
+// auto_suspend_negative_balance credit transaction for the audit trail.
+func (h *AdminHandler) handleNegativeBalances(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	autoSuspend := false
+	if v := strings.TrimSpace(r.URL.Query().Get("auto_suspend")); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "auto_suspend must be a boolean")
+			return
+		}
+		autoSuspend = parsed
+	}
+	if autoSuspend && h.Orch == nil {
+		writeError(w, http.StatusServiceUnavailable, "orchestrator is not configured")
+		return
+	}
+
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT c.tenant_id, c.balance_cents, u.email, t.status, t.container_id
+		FROM credits c
+		JOIN tenants t ON t.id = c.tenant_id
+		JOIN users u ON u.id = t.user_id
+		WHERE c.balance_cents < 0
+		ORDER BY c.balance_cents ASC
+	`)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query negative balances")
+		return
+	}
+	defer rows.Close()
+
+	tenants := make([]*negativeBalance, 0)
+	for rows.Next() {
+		var nb negativeBalance
+		if err := rows.Scan(&nb.TenantID, &nb.BalanceCents, &nb.Email, &nb.TenantStatus, &nb.containerID); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to read negative balances")
+			return
+		}
+		tenants = append(tenants, &nb)
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed while reading negative balances")
+		return
+	}
+	rows.Close()
+
+	var totalDebt int64
+	suspended := 0
+	for _, nb := range tenants {
+		totalDebt += nb.BalanceCents
+		if autoSuspend && nb.TenantStatus != "suspended" {
+			if err := h.suspendForNegativeBalance(r.Context(), nb.TenantID); err != nil {
+				nb.SuspendError = err.Error()
+			} else {
+				nb.AutoSuspended = true
+				nb.TenantStatus = "suspended"
+				suspended++
+			}
+		}
+		state, _ := h.tenantContainerSnapshot(r.Context(), nb.TenantID, nb.containerID)["state"].(string)
+		nb.ContainerStatus = state
+	}
+
+	if autoSuspend {
+		h.logAdminAction(r.Context(), "admin.credits.negative_balances.auto_suspend", "", map[string]any{
+			"count":     len(tenants),
+			"suspended": suspended,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tenants":          tenants,
+		"count":            len(tenants),
+		"total_debt_cents": totalDebt,
+		"auto_suspend":     autoSuspend,
+		"suspended":        suspended,
+	})
+}
+
+// suspendForNegativeBalance stops the tenant container and marks the tenant
+// suspended, recording the reason as a zero-amount credit transaction.
+func (h *AdminHandler) suspendForNegativeBalance(ctx context.Context, tenantID string) error {
+	if err := h.Orch.Stop(ctx, tenantID); err != nil && !isNoContainerError(err) {
+		return errors.New("failed to stop tenant container")
+	}
+
+	adminIdentity, _ := middleware.AdminFromContext(ctx)
+	var adminUserID any
+	if parsedUUID, err := uuid.Parse(strings.TrimSpace(adminIdentity.ID)); err == nil {
+		adminUserID = parsedUUID.String()
+	}
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.New("failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE tenants SET status = 'suspended' WHERE id = $1`, tenantID); err != nil {
+		return errors.New("failed to suspend tenant")
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO credit_transactions (tenant_id, amount_cents, reason, admin_user_id)
+		VALUES ($1, 0, $2, $3)
+	`, tenantID, autoSuspendNegativeBalanceReason, adminUserID); err != nil {
+		return errors.New("failed to record credit transaction")
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.New("failed to commit suspension")
+	}
+	return nil
+}
+
+var errCreditTenantNotFound = errors.New("tenant not found")
+
+// handleResetCredits sets the tenant's balance to an exact value, as when
+// onboarding users migrated from another system. The difference from the
+// current balance is recorded as the credit transaction.
+func (h *AdminHandler) handleResetCredits(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+
+	var req struct {
+		BalanceCents *int64 `json:"balance_cents"`
+		Reason       string `json:"reason"`
+	}
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.BalanceCents == nil {
+		writeError(w, http.StatusBadRequest, "balance_cents is required")
+		return
+	}
+	if *req.BalanceCents < 0 {
+		writeError(w, http.StatusBadRequest, "balance_cents must not be negative")
+		return
+	}
+	if req.Reason == "" {
+		writeError(w, http.StatusBadRequest, "reason is required")
+		return
+	}
+
+	previous, err := h.resetTenantCredits(r.Context(), tenantID, *req.BalanceCents, req.Reason)
+	if errors.Is(err, errCreditTenantNotFound) {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.logAdminAction(r.Context(), "admin.tenants.credits.reset", tenantID, map[string]any{
+		"previous_balance_cents": previous,
+		"new_balance_cents":      *req.BalanceCents,
+		"reason":                 req.Reason,
+	})
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"previous_balance_cents": previous,
+		"new_balance_cents":      *req.BalanceCents,
+	})
+}
+
+// resetTenantCredits sets the tenant's balance to balance and records the
+// difference as a credit transaction, returning the previous balance. The
+// credits row is locked while the balance is read, so the final balance is
+// exactly balance even when adjustments run concurrently. Errors other than
+// errCreditTenantNotFound carry a client-safe message.
+func (h *AdminHandler) resetTenantCredits(ctx context.Context, tenantID string, balance int64, reason string) (int64, error) {
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, errors.New("failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	var tenantExists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM tenants WHERE id = $1)`, tenantID).Scan(&tenantExists); err != nil {
+		return 0, errors.New("failed to verify tenant")
+	}
+	if !tenantExists {
+		return 0, errCreditTenantNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO credits (tenant_id, balance_cents, free_credit_used, updated_at)
+		VALUES ($1, 0, false, NOW())
+		ON CONFLICT (tenant_id) DO NOTHING
+	`, tenantID); err != nil {
+		return 0, errors.New("failed to ensure tenant credits")
+	}
+
+	var previous int64
+	if err := tx.QueryRowContext(ctx, `
+		SELECT balance_cents FROM credits WHERE tenant_id = $1 FOR UPDATE
+	`, tenantID).Scan(&previous); err != nil {
+		return 0, errors.New("failed to read credits")
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE credits
+		SET balance_cents = $2,
+		    updated_at = NOW()
+		WHERE tenant_id = $1
+	`, tenantID, balance); err != nil {
+		return 0, errors.New("failed to update credits")
+	}
+
+	adminIdentity, _ := middleware.AdminFromContext(ctx)
+	var adminUserID any
+	if parsedUUID, err := uuid.Parse(strings.TrimSpace(adminIdentity.ID)); err == nil {
+		adminUserID = parsedUUID.String()
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO credit_transactions (tenant_id, amount_cents, reason, admin_user_id)
+		VALUES ($1, $2, $3, $4)
+	`, tenantID, balance-previous, reason, adminUserID); err != nil {
+		return 0, errors.New("failed to record credit transaction")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errors.New("failed to commit credit reset")
+	}
+	return previous, nil
+}
+
+// adjustTenantCredits adds amount (negative to deduct) to the tenant's
+// balance and records the credit transaction in one transaction. Errors other
+// than errCreditTenantNotFound carry a client-safe message.
+func (h *AdminHandler) adjustTenantCredits(ctx context.Context, tenantID string, amount int64, reason string) (int64, error) {
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, errors.New("failed to start transaction")
+	}
+	defer tx.Rollback()
+
+	var tenantExists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM tenants WHERE id = $1)`, tenantID).Scan(&tenantExists); err != nil {
+		return 0, errors.New("failed to verify tenant")
+	}
+	if !tenantExists {
+		return 0, errCreditTenantNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO credits (tenant_id, balance_cents, free_credit_used, updated_at)
+		VALUES ($1, 0, false, NOW())
+		ON CONFLICT (tenant_id) DO NOTHING
+	`, tenantID); err != nil {
+		return 0, errors.New("failed to ensure tenant credits")
+	}
+
+	var balanceCents int64
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE credits
+		SET balance_cents = balance_cents + $2,
+		    updated_at = NOW()
+		WHERE tenant_id = $1
+		RETURNING balance_cents
+	`, tenantID, amount).Scan(&balanceCents); err != nil {
+		return 0, errors.New("failed to update credits")
+	}
+
+	adminIdentity, _ := middleware.AdminFromContext(ctx)
+	var adminUserID any
+	if parsedUUID, err := uuid.Parse(strings.TrimSpace(adminIdentity.ID)); err == nil {
+		adminUserID = parsedUUID.String()
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO credit_transactions (tenant_id, amount_cents, reason, admin_user_id)
+		VALUES ($1, $2, $3, $4)
+	`, tenantID, amount, reason, adminUserID); err != nil {
+		return 0, errors.New("failed to record credit transaction")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errors.New("failed to commit credit update")
+	}
+	return balanceCents, nil
+}
@@ -0,0 +1,161 @@
+package routes
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxBulkCreditRows  = 10000
+	maxBulkCreditBytes = 8 << 20
+)
+
+type bulkCreditRow struct {
+	row      int
+	tenantID string
+	amount   int64
+	reason   string
+	invalid  string
+}
+
+type bulkCreditResult struct {
+	Row          int    `json:"row"`
+	TenantID     string `json:"tenant_id"`
+	Status       string `json:"status"`
+	Message      string `json:"message,omitempty"`
+	BalanceCents *int64 `json:"balance_cents,omitempty"`
+}
+
+// handleBulkCreditAdjust applies a CSV of tenant_id,amount_cents,reason rows
+// uploaded as the "file" form field. Each row is its own transaction, so one
+// bad row does not undo the others. Results are streamed as JSON lines in
+// row order, and the import is audited as a single entry.
+func (h *AdminHandler) handleBulkCreditAdjust(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBulkCreditBytes)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, "upload is too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "multipart form with a CSV file field is required")
+		return
+	}
+	defer file.Close()
+
+	rows, err := parseBulkCreditCSV(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+
+	var succeeded, failed int
+	var totalCents int64
+	for _, row := range rows {
+		result := bulkCreditResult{Row: row.row, TenantID: row.tenantID, Status: "ok"}
+		if row.invalid != "" {
+			result.Status, result.Message = "error", row.invalid
+		} else if balance, err := h.adjustTenantCredits(r.Context(), row.tenantID, row.amount, row.reason); err != nil {
+			result.Status, result.Message = "error", err.Error()
+		} else {
+			result.BalanceCents = &balance
+			totalCents += row.amount
+		}
+		if result.Status == "ok" {
+			succeeded++
+		} else {
+			failed++
+		}
+		_ = enc.Encode(result)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	h.logAdminAction(r.Context(), "admin.credits.bulk_adjust", "", map[string]any{
+		"filename":           header.Filename,
+		"rows":               len(rows),
+		"succeeded":          succeeded,
+		"failed":             failed,
+		"total_amount_cents": totalCents,
+	})
+}
+
+// parseBulkCreditCSV reads every row before any is applied, so an upload over
+// the row cap or with a malformed CSV changes nothing. An optional header row
+// starting with tenant_id is skipped. Rows that fail validation are returned
+// with invalid set and reported rather than applied.
+func parseBulkCreditCSV(src io.Reader) ([]bulkCreditRow, error) {
+	reader := csv.NewReader(src)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows []bulkCreditRow
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+		if line == 1 && len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), "tenant_id") {
+			continue
+		}
+		if len(rows) == maxBulkCreditRows {
+			return nil, fmt.Errorf("upload exceeds %d rows", maxBulkCreditRows)
+		}
+		rows = append(rows, parseBulkCreditRecord(len(rows)+1, record))
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("CSV has no rows")
+	}
+	return rows, nil
+}
+
+func parseBulkCreditRecord(n int, record []string) bulkCreditRow {
+	row := bulkCreditRow{row: n}
+	if len(record) != 3 {
+		row.invalid = "expected tenant_id,amount_cents,reason"
+		return row
+	}
+	row.tenantID = strings.TrimSpace(record[0])
+	row.reason = strings.TrimSpace(record[2])
+	if _, err := uuid.Parse(row.tenantID); err != nil {
+		row.invalid = "invalid tenant_id"
+		return row
+	}
+	amount, err := strconv.ParseInt(strings.TrimSpace(record[1]), 10, 64)
+	if err != nil {
+		row.invalid = "amount_cents must be an integer"
+		return row
+	}
+	if amount == 0 {
+		row.invalid = "amount must be non-zero"
+		return row
+	}
+	row.amount = amount
+	if row.reason == "" {
+		row.invalid = "reason is required"
+	}
+	return row
+}
@@ -0,0 +1,121 @@
+package routes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func bulkCreditRequest(t *testing.T, csvBody string) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, err := mw.CreateFormFile("file", "balances.csv")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	part.Write([]byte(csvBody))
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/credits/bulk-adjust", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestBulkCreditAdjustStreamsRowResults(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	const (
+		known   = "11111111-1111-1111-1111-111111111111"
+		unknown = "22222222-2222-2222-2222-222222222222"
+	)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(known).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("INSERT INTO credits").WithArgs(known).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("UPDATE credits").WithArgs(known, int64(500)).WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(500))
+	mock.ExpectExec("INSERT INTO credit_transactions").WithArgs(known, int64(500), "migration", nil).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(unknown).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
+	mock.ExpectExec("INSERT INTO admin_audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	h := NewAdminHandler(db, nil)
+	mux := http.NewServeMux()
+	h.Mount(mux)
+	w := httptest.NewRecorder()
+	csvBody := "tenant_id,amount_cents,reason\n" +
+		known + ",500,migration\n" +
+		known + ",lots,migration\n" +
+		unknown + ",100,migration\n"
+	mux.ServeHTTP(w, bulkCreditRequest(t, csvBody))
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var results []bulkCreditResult
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var res bulkCreditResult
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+			t.Fatalf("decode %q: %v", scanner.Text(), err)
+		}
+		results = append(results, res)
+	}
+	want := []struct {
+		status, message string
+	}{
+		{"ok", ""},
+		{"error", "amount_cents must be an integer"},
+		{"error", "tenant not found"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("results = %+v", results)
+	}
+	for i, res := range results {
+		if res.Row != i+1 || res.Status != want[i].status || res.Message != want[i].message {
+			t.Fatalf("row %d = %+v", i+1, res)
+		}
+	}
+	if results[0].BalanceCents == nil || *results[0].BalanceCents != 500 {
+		t.Fatalf("balance = %v", results[0].BalanceCents)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestBulkCreditAdjustRejectsOversizedUpload(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	h := NewAdminHandler(db, nil)
+	var csvBody strings.Builder
+	for i := 0; i <= maxBulkCreditRows; i++ {
+		fmt.Fprintf(&csvBody, "11111111-1111-1111-1111-111111111111,%d,seed\n", i+1)
+	}
+	w := httptest.NewRecorder()
+	h.handleBulkCreditAdjust(w, bulkCreditRequest(t, csvBody.String()))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "exceeds") {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,253 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// tenantErasure is one statement of a tenant erasure. Rows referencing a
+// conversation are removed before conversations, so their count and blob keys
+// are recorded rather than lost to the cascade. Queries marked blobs return
+// the blob_key of each deleted row.
+type tenantErasure struct {
+	table string
+	query string
+	blobs bool
+}
+
+var tenantErasures = []tenantErasure{
+	{"message_media", `DELETE FROM message_media WHERE tenant_id = $1 RETURNING blob_key`, true},
+	{"messages", `DELETE FROM messages WHERE conversation_id IN (SELECT id FROM conversations WHERE tenant_id = $1)`, false},
+	{"conversation_exports", `DELETE FROM conversation_exports WHERE tenant_id = $1 RETURNING blob_key`, true},
+	{"conversations", `DELETE FROM conversations WHERE tenant_id = $1`, false},
+	{"usage_logs", `DELETE FROM usage_logs WHERE tenant_id = $1`, false},
+	{"usage_daily", `DELETE FROM usage_daily WHERE tenant_id = $1`, false},
+	{"invoices", `DELETE FROM invoices WHERE tenant_id = $1 RETURNING blob_key`, true},
+	{"model_deprecation_notices", `DELETE FROM model_deprecation_notices WHERE tenant_id = $1`, false},
+	{"tenant_model_access", `DELETE FROM tenant_model_access WHERE tenant_id = $1`, false},
+	{"tenant_channels", `DELETE FROM tenant_channels WHERE tenant_id = $1`, false},
+	{"channel_credentials", `DELETE FROM channel_credentials WHERE tenant_id = $1`, false},
+	{"channel_events", `DELETE FROM channel_events WHERE tenant_id = $1`, false},
+	{"webhook_failures", `DELETE FROM webhook_failures WHERE tenant_id = $1`, false},
+	{"broadcasts", `DELETE FROM broadcasts WHERE tenant_id = $1`, false},
+	{"tenant_outbound_filters", `DELETE FROM tenant_outbound_filters WHERE tenant_id = $1`, false},
+	{"credits", `DELETE FROM credits WHERE tenant_id = $1`, false},
+	{"credit_transactions", `DELETE FROM credit_transactions WHERE tenant_id = $1`, false},
+	{"tenant_policies", `DELETE FROM tenant_policies WHERE tenant_id = $1`, false},
+	{"tenant_tools", `DELETE FROM tenant_tools WHERE tenant_id = $1`, false},
+	{"tenant_tool_calls", `DELETE FROM tenant_tool_calls WHERE tenant_id = $1`, false},
+	{"agent_db_queries", `DELETE FROM agent_db_queries WHERE tenant_id = $1`, false},
+	{"exec_history", `DELETE FROM exec_history WHERE tenant_id = $1`, false},
+	{"tenant_activity", `DELETE FROM tenant_activity WHERE tenant_id = $1`, false},
+	{"prompt_templates", `DELETE FROM prompt_templates WHERE tenant_id = $1`, false},
+	{"tenant_onboarding_dismissals", `DELETE FROM tenant_onboarding_dismissals WHERE tenant_id = $1`, false},
+	{"deployment_runs", `DELETE FROM deployment_runs WHERE tenant_id = $1`, false},
+	{"deploy_connections", `DELETE FROM deploy_connections WHERE tenant_id = $1`, false},
+	{"deploy_env_vars", `DELETE FROM deploy_env_vars WHERE tenant_id = $1`, false},
+	{"hand_invocations", `DELETE FROM hand_invocations WHERE tenant_id = $1`, false},
+	{"workflow_runs", `DELETE FROM workflow_runs WHERE tenant_id = $1`, false},
+	{"swarm_run_feedback", `DELETE FROM swarm_run_feedback WHERE tenant_id = $1`, false},
+	{"swarm_runs", `DELETE FROM swarm_runs WHERE tenant_id = $1`, false},
+	{"tenant_swarm_configs", `DELETE FROM tenant_swarm_configs WHERE tenant_id = $1`, false},
+	{"tenant_clarification_rubrics", `DELETE FROM tenant_clarification_rubrics WHERE tenant_id = $1`, false},
+	{"tenant_memories", `DELETE FROM tenant_memories WHERE tenant_id = $1`, false},
+	{"tenant_moderation_rules", `DELETE FROM tenant_moderation_rules WHERE tenant_id = $1`, false},
+	{"moderation_events", `DELETE FROM moderation_events WHERE tenant_id = $1`, false},
+}
+
+// tenantErasureKept lists the tenant-scoped tables an erasure leaves alone:
+// gdpr_erasure_log is the record of the erasure itself.
+var tenantErasureKept = map[string]bool{"gdpr_erasure_log": true}
+
+var errTenantAlreadyDeleted = errors.New("tenant is already deleted")
+
+// handleDeleteTenant erases a tenant for a GDPR deletion request. The
+// container is removed, the tenant's data is deleted and the tenants row is
+// kept as a tombstone with status "deleted" and no link to its user. The body
+// must be {"confirm": true}.
+func (h *AdminHandler) handleDeleteTenant(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	if h.Orch == nil {
+		writeError(w, http.StatusServiceUnavailable, "orchestrator is not configured")
+		return
+	}
+
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	var req struct {
+		Confirm bool `json:"confirm"`
+	}
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if !req.Confirm {
+		writeError(w, http.StatusBadRequest, `deleting a tenant requires {"confirm": true}`)
+		return
+	}
+
+	deleted, containerNote, blobKeys, err := h.eraseTenant(r.Context(), tenantID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	case errors.Is(err, errTenantAlreadyDeleted):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		slog.Error("tenant erasure failed", "tenant", tenantID, "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete tenant")
+		return
+	}
+
+	details := map[string]any{"rows_deleted": deleted}
+	if containerNote != "" {
+		details["container_delete_note"] = containerNote
+	}
+	if failed := h.deleteTenantBlobs(context.WithoutCancel(r.Context()), tenantID, blobKeys); failed > 0 {
+		details["blob_delete_failures"] = failed
+	}
+	h.logAdminAction(r.Context(), "admin.tenants.delete", tenantID, details)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tenant_id":    tenantID,
+		"status":       "deleted",
+		"rows_deleted": deleted,
+	})
+}
+
+// eraseTenant removes the tenant's container and data in one transaction and
+// records the erasure in gdpr_erasure_log. It returns the rows deleted per
+// table, the container error that was tolerated, if any, and the blob keys
+// of the deleted rows. A missing tenant is sql.ErrNoRows.
+func (h *AdminHandler) eraseTenant(ctx context.Context, tenantID string) (map[string]int64, string, []string, error) {
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	if err := tx.QueryRowContext(ctx, `SELECT status FROM tenants WHERE id = $1 FOR UPDATE`, tenantID).Scan(&status); err != nil {
+		return nil, "", nil, err
+	}
+	if status == "deleted" {
+		return nil, "", nil, errTenantAlreadyDeleted
+	}
+
+	// The container goes first: if it cannot be removed nothing is erased and
+	// the request can be retried.
+	deleteErr := h.Orch.Delete(ctx, tenantID)
+	if deleteErr != nil && !isNoContainerError(deleteErr) {
+		return nil, "", nil, fmt.Errorf("delete container: %w", deleteErr)
+	}
+
+	deleted := make(map[string]int64, len(tenantErasures))
+	var blobKeys []string
+	for _, e := range tenantErasures {
+		if e.blobs {
+			keys, err := deleteReturningBlobKeys(ctx, tx, e.query, tenantID)
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("delete %s: %w", e.table, err)
+			}
+			deleted[e.table] = int64(len(keys))
+			for _, key := range keys {
+				if key.Valid && key.String != "" {
+					blobKeys = append(blobKeys, key.String)
+				}
+			}
+			continue
+		}
+		res, err := tx.ExecContext(ctx, e.query, tenantID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("delete %s: %w", e.table, err)
+		}
+		n, _ := res.RowsAffected()
+		deleted[e.table] = n
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE tenants
+		SET status = 'deleted',
+		    user_id = NULL,
+		    container_id = NULL,
+		    container_alias = NULL,
+		    container_port = NULL,
+		    network_config = NULL,
+		    deleted_at = NOW(),
+		    updated_at = NOW()
+		WHERE id = $1
+	`, tenantID); err != nil {
+		return nil, "", nil, fmt.Errorf("tombstone tenant: %w", err)
+	}
+
+	counts, err := json.Marshal(deleted)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("marshal row counts: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO gdpr_erasure_log (tenant_id, admin_id, rows_deleted)
+		VALUES ($1, $2, $3::jsonb)
+	`, tenantID, adminActorID(ctx), string(counts)); err != nil {
+		return nil, "", nil, fmt.Errorf("record erasure: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, "", nil, fmt.Errorf("commit: %w", err)
+	}
+	if deleteErr != nil {
+		return deleted, deleteErr.Error(), blobKeys, nil
+	}
+	return deleted, "", blobKeys, nil
+}
+
+// deleteReturningBlobKeys runs a DELETE ... RETURNING blob_key and collects
+// the keys, one per deleted row.
+func deleteReturningBlobKeys(ctx context.Context, tx *sql.Tx, query, tenantID string) ([]sql.NullString, error) {
+	rows, err := tx.QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []sql.NullString
+	for rows.Next() {
+		var key sql.NullString
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// deleteTenantBlobs removes an erased tenant's files from the blob store once
+// its rows are gone, and returns how many could not be removed. Failures are
+// logged with their key so they can be cleaned up by hand.
+func (h *AdminHandler) deleteTenantBlobs(ctx context.Context, tenantID string, keys []string) int {
+	if len(keys) == 0 {
+		return 0
+	}
+	if h.Blobs == nil {
+		slog.Error("tenant blobs not deleted: blob store is not configured", "tenant", tenantID, "keys", keys)
+		return len(keys)
+	}
+	failed := 0
+	for _, key := range keys {
+		if err := h.Blobs.Delete(ctx, key); err != nil {
+			slog.Error("tenant blob delete failed", "tenant", tenantID, "key", key, "err", err)
+			failed++
+		}
+	}
+	return failed
+}
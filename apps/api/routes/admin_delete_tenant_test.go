@@ -0,0 +1,184 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/internal/testkit"
+	"github.com/agentsquads/api/media"
+)
+
+func TestDeleteTenantErasesData(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	orch := testkit.NewFakeOrchestrator()
+	orch.AddTenant("t1", "")
+	blobs := media.NewLocalBlobStore(t.TempDir())
+	for _, key := range []string{"t1/photo", "t1/export", "t2/photo"} {
+		if err := blobs.Put(context.Background(), key, "", []byte("x")); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	h := NewAdminHandler(db, orch)
+	h.Blobs = blobs
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	blobKeys := map[string][]any{"message_media": {"t1/photo"}, "conversation_exports": {nil, "t1/export"}}
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT status FROM tenants").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("active"))
+	for i, e := range tenantErasures {
+		if e.blobs {
+			rows := sqlmock.NewRows([]string{"blob_key"})
+			for _, key := range blobKeys[e.table] {
+				rows.AddRow(key)
+			}
+			mock.ExpectQuery("DELETE FROM " + e.table).WithArgs("t1").WillReturnRows(rows)
+			continue
+		}
+		mock.ExpectExec("DELETE FROM " + e.table).WithArgs("t1").WillReturnResult(sqlmock.NewResult(0, int64(i)))
+	}
+	mock.ExpectExec("SET status = 'deleted'").WithArgs("t1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO gdpr_erasure_log").
+		WithArgs("t1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO admin_audit_log").
+		WithArgs(sqlmock.AnyArg(), "admin.tenants.delete", "t1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/admin/tenants/t1", strings.NewReader(`{"confirm":true}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Status      string           `json:"status"`
+		RowsDeleted map[string]int64 `json:"rows_deleted"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Status != "deleted" || resp.RowsDeleted["conversations"] != 3 || resp.RowsDeleted["conversation_exports"] != 2 || len(resp.RowsDeleted) != len(tenantErasures) {
+		t.Fatalf("unexpected response: %s", w.Body.String())
+	}
+	for key, want := range map[string]bool{"t1/photo": false, "t1/export": false, "t2/photo": true} {
+		if _, err := blobs.Get(context.Background(), key); (err == nil) != want {
+			t.Fatalf("blob %s kept = %v, want %v", key, err == nil, want)
+		}
+	}
+	if _, ok := orch.Tenant("t1"); ok {
+		t.Fatalf("container was not deleted: %v", orch.Calls())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestDeleteTenantRejectsUnconfirmedAndUnknown(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	orch := testkit.NewFakeOrchestrator()
+	mux := http.NewServeMux()
+	NewAdminHandler(db, orch).Mount(mux)
+
+	del := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/admin/tenants/t1", strings.NewReader(body)))
+		return w
+	}
+	if w := del(`{"confirm":false}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unconfirmed status = %d, want 400", w.Code)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT status FROM tenants").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}))
+	mock.ExpectRollback()
+	if w := del(`{"confirm":true}`); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown status = %d, want 404", w.Code)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT status FROM tenants").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("deleted"))
+	mock.ExpectRollback()
+	if w := del(`{"confirm":true}`); w.Code != http.StatusConflict {
+		t.Fatalf("deleted status = %d, want 409", w.Code)
+	}
+	if calls := orch.Calls(); len(calls) != 0 {
+		t.Fatalf("orchestrator calls = %v", calls)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+// migrationTableStmt matches the statements that give a table a tenant_id
+// column or drop it, in the order the migrations run them.
+var migrationTableStmt = regexp.MustCompile(`(?is)CREATE TABLE(?: IF NOT EXISTS)?\s+(\w+)\s*\((.*?)\n\);|ALTER TABLE(?: IF EXISTS)?\s+(\w+)\s+ADD COLUMN(?: IF NOT EXISTS)?\s+tenant_id\b|DROP TABLE(?: IF EXISTS)?\s+(\w+)`)
+
+var tenantIDColumn = regexp.MustCompile(`(?im)^\s*tenant_id\b`)
+
+func TestTenantErasuresCoverTenantTables(t *testing.T) {
+	t.Parallel()
+	files, err := filepath.Glob(filepath.Join("..", "..", "..", "db", "migrations", "*.sql"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("migrations not found: %v", err)
+	}
+	sort.Strings(files)
+	tables := map[string]bool{}
+	for _, file := range files {
+		sql, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read %s: %v", file, err)
+		}
+		for _, m := range migrationTableStmt.FindAllStringSubmatch(string(sql), -1) {
+			switch {
+			case m[1] != "":
+				if tenantIDColumn.MatchString(m[2]) {
+					tables[strings.ToLower(m[1])] = true
+				}
+			case m[3] != "":
+				tables[strings.ToLower(m[3])] = true
+			default:
+				delete(tables, strings.ToLower(m[4]))
+			}
+		}
+	}
+
+	covered := map[string]bool{}
+	for _, e := range tenantErasures {
+		covered[e.table] = strings.HasPrefix(e.query, "DELETE FROM "+e.table+" WHERE tenant_id = $1")
+	}
+	for table := range tables {
+		if !covered[table] && !tenantErasureKept[table] {
+			t.Errorf("tenant_id table %s is not erased on tenant deletion", table)
+		}
+	}
+	for table, byTenantID := range covered {
+		if byTenantID && !tables[table] {
+			t.Errorf("erased table %s has no tenant_id column in the migrations", table)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package routes
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestExportTenantsStreamsCSV(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mux := http.NewServeMux()
+	NewAdminHandler(db, nil).Mount(mux)
+
+	for _, q := range []string{"status=gone", "since=yesterday"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/tenants/export?"+q, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s status = %d", q, w.Code)
+		}
+	}
+
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	created := time.Date(2026, 2, 3, 4, 5, 6, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "email", "status", "name", "balance_cents", "total_tokens", "total_revenue_cents", "created_at"}).
+		AddRow("t0", "a,b@example.com", "active", "pro", int64(-50), int64(1200), int64(34), created)
+	rows.AddRow("t1", "=HYPERLINK(\"http://evil.test\")", "active", "@SUM(A1)", int64(0), int64(0), int64(0), created)
+	for i := 2; i < 150; i++ {
+		rows.AddRow(fmt.Sprintf("t%d", i), nil, "active", nil, int64(0), int64(0), int64(0), created)
+	}
+	mock.ExpectQuery("FROM tenants t").WithArgs("active", since).WillReturnRows(rows)
+	mock.ExpectExec("INSERT INTO admin_audit_log").
+		WithArgs(sqlmock.AnyArg(), "admin.tenants.export", nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/tenants/export?status=active&since=2026-01-01T00:00:00Z", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv" {
+		t.Fatalf("status = %d content-type = %q body=%s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+	want := fmt.Sprintf(`attachment; filename="tenants-%s.csv"`, time.Now().UTC().Format("2006-01-02"))
+	if got := w.Header().Get("Content-Disposition"); got != want {
+		t.Fatalf("content-disposition = %q", got)
+	}
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil || len(records) != 151 {
+		t.Fatalf("records = %d err=%v", len(records), err)
+	}
+	if got := strings.Join(records[0], ","); got != "tenant_id,user_email,status,plan,balance_cents,total_tokens,total_revenue_cents,created_at" {
+		t.Fatalf("header = %q", got)
+	}
+	if got := records[1]; got[1] != "a,b@example.com" || got[3] != "pro" || got[4] != "-50" || got[7] != "2026-02-03T04:05:06Z" {
+		t.Fatalf("first row = %q", got)
+	}
+	if got := records[2]; got[1] != `'=HYPERLINK("http://evil.test")` || got[3] != "'@SUM(A1)" {
+		t.Fatalf("formula cells not escaped: %q", got)
+	}
+	for _, s := range []string{"+1", "-1", "\tx", "\rx"} {
+		if got := csvText(s); got != "'"+s {
+			t.Fatalf("csvText(%q) = %q", s, got)
+		}
+	}
+	if got := csvText("ada@example.com"); got != "ada@example.com" {
+		t.Fatalf("csvText changed a plain value: %q", got)
+	}
+	if !w.Flushed {
+		t.Fatalf("expected the export to be flushed while streaming")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
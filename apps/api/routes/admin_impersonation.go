@@ -0,0 +1,79 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/middleware"
+)
+
+// handleImpersonateTenant issues a short-lived token that calls tenant
+// endpoints as the tenant on behalf of the requesting admin. The token can
+// read tenant data and send messages, which record the admin; requests made
+// with it are audited by middleware.ApplyImpersonation.
+func (h *AdminHandler) handleImpersonateTenant(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	secret := strings.TrimSpace(os.Getenv("API_JWT_SECRET"))
+	if secret == "" {
+		writeError(w, http.StatusServiceUnavailable, "API JWT auth is not configured")
+		return
+	}
+
+	var exists int
+	err := h.DB.QueryRowContext(r.Context(), `SELECT 1 FROM tenants WHERE id = $1`, tenantID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load tenant")
+		return
+	}
+
+	adminID := adminActorID(r.Context())
+	token, expiresAt, err := middleware.IssueImpersonationToken(secret, adminID, tenantID, time.Now())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to issue impersonation token")
+		return
+	}
+
+	h.logAdminAction(r.Context(), "admin.tenants.impersonate", tenantID, map[string]any{"expires_at": expiresAt.UTC()})
+	writeJSON(w, http.StatusOK, map[string]any{
+		"token":        token,
+		"tenant_id":    tenantID,
+		"impersonator": adminID,
+		"expires_at":   expiresAt.UTC(),
+	})
+}
+
+// withImpersonator records the impersonating admin in metadata stored with
+// tenant data. A client-supplied impersonated_by is always dropped so it
+// cannot be forged.
+func withImpersonator(ctx context.Context, metadata map[string]string) map[string]string {
+	impersonation, ok := middleware.ImpersonationFromContext(ctx)
+	if _, forged := metadata["impersonated_by"]; !ok && !forged {
+		return metadata
+	}
+	out := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		out[k] = v
+	}
+	delete(out, "impersonated_by")
+	if ok {
+		out["impersonated_by"] = impersonation.AdminID
+	}
+	return out
+}
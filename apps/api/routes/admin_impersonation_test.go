@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/middleware"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAdminImpersonateTenant(t *testing.T) {
+	t.Setenv("API_JWT_SECRET", "s")
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT 1 FROM tenants").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO admin_audit_log").WithArgs("unknown", "admin.tenants.impersonate", "t1", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT 1 FROM tenants").WithArgs("missing").WillReturnRows(sqlmock.NewRows([]string{"?column?"}))
+
+	mux := http.NewServeMux()
+	NewAdminHandler(db, nil).Mount(mux)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/tenants/t1/impersonate", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(resp.Token, claims, func(*jwt.Token) (any, error) { return []byte("s"), nil }); err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+	if claims["tenant_id"] != "t1" || claims[middleware.ImpersonatorClaim] != "unknown" || time.Until(resp.ExpiresAt) > middleware.ImpersonationTTL {
+		t.Fatalf("claims = %v expires_at = %s", claims, resp.ExpiresAt)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/tenants/missing/impersonate", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("missing tenant status = %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestWithImpersonator(t *testing.T) {
+	t.Setenv("API_JWT_SECRET", "s")
+	token, _, err := middleware.IssueImpersonationToken("s", "admin-1", "t1", time.Now())
+	if err != nil {
+		t.Fatalf("IssueImpersonationToken: %v", err)
+	}
+	var ctx context.Context
+	h := middleware.ApplyImpersonation(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { ctx = r.Context() }))
+	req := httptest.NewRequest(http.MethodPost, "/api/channels/inbound", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	got := withImpersonator(ctx, map[string]string{"impersonated_by": "someone", "source": "web"})
+	if got["impersonated_by"] != "admin-1" || got["source"] != "web" {
+		t.Fatalf("impersonated metadata = %v", got)
+	}
+	got = withImpersonator(context.Background(), map[string]string{"impersonated_by": "admin-1"})
+	if _, ok := got["impersonated_by"]; ok {
+		t.Fatalf("forged impersonated_by kept: %v", got)
+	}
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/agentsquads/api/llmproxy"
+)
+
+// InFlightReporter reports the LLM proxy's running upstream calls.
+// *llmproxy.Proxy implements it.
+type InFlightReporter interface {
+	InFlight() llmproxy.InFlightSnapshot
+}
+
+// handleLLMInFlight returns the in-flight upstream calls per tenant and per
+// provider. It reads only in-memory counters of this API instance, so it is
+// cheap enough to poll.
+func (h *AdminHandler) handleLLMInFlight(w http.ResponseWriter, r *http.Request) {
+	if h.InFlight == nil {
+		writeError(w, http.StatusServiceUnavailable, "llm proxy is not configured")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.InFlight.InFlight())
+}
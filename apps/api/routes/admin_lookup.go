@@ -0,0 +1,252 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const maxTenantLookupResults = 50
+
+// tenantLookupFields are the query parameters handleTenantLookup searches
+// on, in the order they are reported.
+var tenantLookupFields = []string{
+	"telegram_username",
+	"telegram_chat_id",
+	"whatsapp_phone_number_id",
+	"email",
+	"container_id",
+	"deployment_domain",
+}
+
+// tenantLookupQueries return (tenant_id, matched value) for one search term.
+// Email and bot username match partially; container IDs match by prefix so
+// the short IDs from docker ps work.
+var tenantLookupQueries = map[string]string{
+	"telegram_username": `
+		SELECT tenant_id, config->>'bot_username'
+		FROM channel_credentials
+		WHERE channel = 'telegram' AND config->>'bot_username' ILIKE '%' || $1 || '%' ESCAPE '\'
+		LIMIT 50`,
+	"telegram_chat_id": `
+		SELECT tenant_id, channel_user_id
+		FROM tenant_channels
+		WHERE channel = 'telegram' AND channel_user_id = $1
+		LIMIT 50`,
+	"whatsapp_phone_number_id": `
+		SELECT tenant_id, config->>'phone_number_id'
+		FROM channel_credentials
+		WHERE channel = 'whatsapp' AND config->>'phone_number_id' = $1
+		LIMIT 50`,
+	"email": `
+		SELECT t.id, u.email
+		FROM tenants t
+		JOIN users u ON u.id = t.user_id
+		WHERE u.email ILIKE '%' || $1 || '%' ESCAPE '\'
+		LIMIT 50`,
+	"container_id": `
+		SELECT id, container_id
+		FROM tenants
+		WHERE container_id LIKE $1 || '%' ESCAPE '\'
+		LIMIT 50`,
+	"deployment_domain": `
+		SELECT DISTINCT tenant_id, target_name
+		FROM deployment_runs
+		WHERE LOWER(target_name) = LOWER($1)
+		   OR LOWER($1) LIKE LOWER(target_name) || '.%'
+		LIMIT 50`,
+}
+
+type tenantLookupMatch struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+// handleTenantLookup finds tenants by channel identity, owner email,
+// container ID or deployment domain. Each result lists the fields that
+// matched and the tenant's channels with credentials masked.
+func (h *AdminHandler) handleTenantLookup(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+
+	query := r.URL.Query()
+	terms := make(map[string]string)
+	for _, field := range tenantLookupFields {
+		value := strings.TrimSpace(query.Get(field))
+		if field == "telegram_username" {
+			value = strings.TrimPrefix(value, "@")
+		}
+		if value != "" {
+			terms[field] = value
+		}
+	}
+	if len(terms) == 0 {
+		writeError(w, http.StatusBadRequest, "at least one of "+strings.Join(tenantLookupFields, ", ")+" is required")
+		return
+	}
+
+	var order []string
+	matches := make(map[string][]tenantLookupMatch)
+	for _, field := range tenantLookupFields {
+		term, ok := terms[field]
+		if !ok {
+			continue
+		}
+		arg := term
+		switch field {
+		case "telegram_username", "email", "container_id":
+			arg = escapeLikePattern(term)
+		}
+		rows, err := h.DB.QueryContext(r.Context(), tenantLookupQueries[field], arg)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to search tenants")
+			return
+		}
+		for rows.Next() {
+			var tenantID string
+			var value sql.NullString
+			if err := rows.Scan(&tenantID, &value); err != nil {
+				rows.Close()
+				writeError(w, http.StatusInternalServerError, "failed to scan tenant match")
+				return
+			}
+			if _, seen := matches[tenantID]; !seen {
+				order = append(order, tenantID)
+			}
+			matches[tenantID] = append(matches[tenantID], tenantLookupMatch{Field: field, Value: value.String})
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed while reading tenant matches")
+			return
+		}
+	}
+
+	truncated := len(order) > maxTenantLookupResults
+	if truncated {
+		order = order[:maxTenantLookupResults]
+	}
+	results, err := h.tenantLookupResults(r.Context(), order, matches)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load matching tenants")
+		return
+	}
+
+	h.logAdminAction(r.Context(), "admin.tenants.lookup", "", map[string]any{"terms": terms, "results": len(results)})
+	writeJSON(w, http.StatusOK, map[string]any{"tenants": results, "truncated": truncated})
+}
+
+// tenantLookupResults loads the tenants in ids and their channels, keeping
+// the order they were first matched in.
+func (h *AdminHandler) tenantLookupResults(ctx context.Context, ids []string, matches map[string][]tenantLookupMatch) ([]map[string]any, error) {
+	results := make([]map[string]any, 0, len(ids))
+	if len(ids) == 0 {
+		return results, nil
+	}
+
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT t.id, t.status, t.container_id, t.created_at, u.email
+		FROM tenants t
+		LEFT JOIN users u ON u.id = t.user_id
+		WHERE t.id = ANY($1)
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	tenants := make(map[string]map[string]any, len(ids))
+	for rows.Next() {
+		var (
+			id          string
+			status      string
+			containerID sql.NullString
+			createdAt   time.Time
+			email       sql.NullString
+		)
+		if err := rows.Scan(&id, &status, &containerID, &createdAt, &email); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tenants[id] = map[string]any{
+			"id":           id,
+			"status":       status,
+			"container_id": nullString(containerID),
+			"email":        nullString(email),
+			"created_at":   createdAt,
+			"matched":      matches[id],
+			"channels":     make([]map[string]any, 0),
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = h.DB.QueryContext(ctx, `
+		SELECT tc.tenant_id, tc.channel, tc.channel_user_id, tc.muted, tc.linked_at,
+		       COALESCE(NULLIF(cc.config::text, ''), '{}') AS config_json
+		FROM tenant_channels tc
+		LEFT JOIN channel_credentials cc
+		  ON cc.tenant_id = tc.tenant_id
+		 AND cc.channel = tc.channel
+		WHERE tc.tenant_id = ANY($1)
+		ORDER BY tc.linked_at ASC
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			tenantID      string
+			channel       string
+			channelUserID sql.NullString
+			muted         bool
+			linkedAt      time.Time
+			configJSON    string
+		)
+		if err := rows.Scan(&tenantID, &channel, &channelUserID, &muted, &linkedAt, &configJSON); err != nil {
+			return nil, err
+		}
+		tenant, ok := tenants[tenantID]
+		if !ok {
+			continue
+		}
+		masked := map[string]any{}
+		if err := json.Unmarshal([]byte(configJSON), &masked); err != nil {
+			masked = map[string]any{}
+		}
+		maskSecrets(masked)
+		tenant["channels"] = append(tenant["channels"].([]map[string]any), map[string]any{
+			"channel":         channel,
+			"channel_user_id": nullString(channelUserID),
+			"enabled":         !muted,
+			"linked_at":       linkedAt,
+			"credentials":     masked,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		if tenant, ok := tenants[id]; ok {
+			results = append(results, tenant)
+		}
+	}
+	return results, nil
+}
+
+// escapeLikePattern escapes LIKE wildcards so a search term matches
+// literally.
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestAdminTenantLookup(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery("FROM channel_credentials").WithArgs(`foo\_bot`).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "bot_username"}).AddRow("t1", "foo_bot"))
+	mock.ExpectQuery("JOIN users u").WithArgs("example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow("t2", "b@example.com").AddRow("t1", "a@example.com"))
+	mock.ExpectQuery("WHERE t.id = ANY").WithArgs(pq.Array([]string{"t1", "t2"})).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "container_id", "created_at", "email"}).
+			AddRow("t2", "active", nil, now, "b@example.com").
+			AddRow("t1", "active", "abc123", now, "a@example.com"))
+	mock.ExpectQuery("FROM tenant_channels tc").WithArgs(pq.Array([]string{"t1", "t2"})).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "channel", "channel_user_id", "muted", "linked_at", "config_json"}).
+			AddRow("t1", "telegram", "42", false, now, `{"bot_username":"foo_bot","bot_token":"123456:ABCDEF"}`))
+	mock.ExpectExec("INSERT INTO admin_audit_log").
+		WithArgs("unknown", "admin.tenants.lookup", nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mux := http.NewServeMux()
+	NewAdminHandler(db, nil).Mount(mux)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/lookup?telegram_username=@foo_bot&email=example.com", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Tenants []struct {
+			ID       string              `json:"id"`
+			Matched  []tenantLookupMatch `json:"matched"`
+			Channels []struct {
+				Channel     string            `json:"channel"`
+				Credentials map[string]string `json:"credentials"`
+			} `json:"channels"`
+		} `json:"tenants"`
+		Truncated bool `json:"truncated"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Tenants) != 2 || resp.Tenants[0].ID != "t1" || resp.Tenants[1].ID != "t2" || resp.Truncated {
+		t.Fatalf("tenants = %+v", resp.Tenants)
+	}
+	t1 := resp.Tenants[0]
+	if len(t1.Matched) != 2 || t1.Matched[0] != (tenantLookupMatch{Field: "telegram_username", Value: "foo_bot"}) || t1.Matched[1].Field != "email" {
+		t.Fatalf("t1 matched = %+v", t1.Matched)
+	}
+	if len(t1.Channels) != 1 || t1.Channels[0].Credentials["bot_token"] != "123***DEF" || t1.Channels[0].Credentials["bot_username"] != "foo_bot" {
+		t.Fatalf("t1 channels = %+v", t1.Channels)
+	}
+	if len(resp.Tenants[1].Channels) != 0 {
+		t.Fatalf("t2 channels = %+v", resp.Tenants[1].Channels)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestAdminTenantLookupRequiresTerm(t *testing.T) {
+	t.Parallel()
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	NewAdminHandler(db, nil).Mount(mux)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/lookup?name=foo", nil))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "telegram_username") {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+}
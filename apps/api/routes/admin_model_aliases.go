@@ -0,0 +1,162 @@
+package routes
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/llmproxy"
+)
+
+// defaultModelAliasWarning is the deprecation warning window before an
+// alias's effective date when warn_from is not given.
+const defaultModelAliasWarning = 30 * 24 * time.Hour
+
+// handleListModelAliases lists every deprecated model id with its replacement
+// and dates, as stored rather than as loaded by this instance's registry.
+func (h *AdminHandler) handleListModelAliases(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT alias_id, replacement_id, warn_from, effective_at
+		FROM model_aliases
+		ORDER BY alias_id
+	`)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query model aliases")
+		return
+	}
+	defer rows.Close()
+
+	aliases := make([]llmproxy.ModelAlias, 0)
+	for rows.Next() {
+		var a llmproxy.ModelAlias
+		if err := rows.Scan(&a.AliasID, &a.ReplacementID, &a.WarnFrom, &a.EffectiveAt); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to scan model alias")
+			return
+		}
+		aliases = append(aliases, a)
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed while reading model aliases")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"aliases": aliases})
+}
+
+// handleSetModelAlias creates or replaces the alias of a deprecated model id.
+// The body is {"replacement_id", "effective_at", "warn_from"}; warn_from
+// defaults to 30 days before effective_at. Model IDs contain slashes, so the
+// alias id is the rest of the path.
+func (h *AdminHandler) handleSetModelAlias(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	aliasID := strings.TrimSpace(r.PathValue("alias_id"))
+	if aliasID == "" {
+		writeError(w, http.StatusBadRequest, "missing alias id")
+		return
+	}
+	var req struct {
+		ReplacementID string     `json:"replacement_id"`
+		EffectiveAt   *time.Time `json:"effective_at"`
+		WarnFrom      *time.Time `json:"warn_from"`
+	}
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	req.ReplacementID = strings.TrimSpace(req.ReplacementID)
+	if req.ReplacementID == "" || req.EffectiveAt == nil {
+		writeError(w, http.StatusBadRequest, "replacement_id and effective_at are required")
+		return
+	}
+	if req.ReplacementID == aliasID {
+		writeError(w, http.StatusBadRequest, "a model cannot replace itself")
+		return
+	}
+	alias := llmproxy.ModelAlias{
+		AliasID:       aliasID,
+		ReplacementID: req.ReplacementID,
+		EffectiveAt:   req.EffectiveAt.UTC(),
+		WarnFrom:      req.EffectiveAt.UTC().Add(-defaultModelAliasWarning),
+	}
+	if req.WarnFrom != nil {
+		alias.WarnFrom = req.WarnFrom.UTC()
+	}
+	if alias.WarnFrom.After(alias.EffectiveAt) {
+		writeError(w, http.StatusBadRequest, "warn_from must not be after effective_at")
+		return
+	}
+
+	res, err := h.DB.ExecContext(r.Context(), `
+		INSERT INTO model_aliases (alias_id, replacement_id, warn_from, effective_at)
+		SELECT $1, m.id, $3, $4
+		FROM models m
+		WHERE m.id = $2 AND m.enabled
+		ON CONFLICT (alias_id) DO UPDATE
+		SET replacement_id = EXCLUDED.replacement_id,
+			warn_from = EXCLUDED.warn_from,
+			effective_at = EXCLUDED.effective_at,
+			updated_at = NOW()
+	`, alias.AliasID, alias.ReplacementID, alias.WarnFrom, alias.EffectiveAt)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to save model alias")
+		return
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		writeError(w, http.StatusBadRequest, "replacement model not found or disabled")
+		return
+	}
+
+	h.reloadModelAliases(r)
+	h.logAdminAction(r.Context(), "admin.models.aliases.set", aliasID, map[string]any{
+		"replacement_id": alias.ReplacementID,
+		"warn_from":      alias.WarnFrom,
+		"effective_at":   alias.EffectiveAt,
+	})
+	writeJSON(w, http.StatusOK, map[string]any{"alias": alias})
+}
+
+// handleDeleteModelAlias removes an alias, so the old id resolves only while
+// the model itself is still enabled.
+func (h *AdminHandler) handleDeleteModelAlias(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	aliasID := strings.TrimSpace(r.PathValue("alias_id"))
+	if aliasID == "" {
+		writeError(w, http.StatusBadRequest, "missing alias id")
+		return
+	}
+
+	res, err := h.DB.ExecContext(r.Context(), `DELETE FROM model_aliases WHERE alias_id = $1`, aliasID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to delete model alias")
+		return
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		writeError(w, http.StatusNotFound, "model alias not found")
+		return
+	}
+
+	h.reloadModelAliases(r)
+	h.logAdminAction(r.Context(), "admin.models.aliases.delete", aliasID, nil)
+	writeJSON(w, http.StatusOK, map[string]any{"alias_id": aliasID, "deleted": true})
+}
+
+// reloadModelAliases applies an alias change to this instance's registry
+// straight away; other instances pick it up at their next reload.
+func (h *AdminHandler) reloadModelAliases(r *http.Request) {
+	if h.Models == nil {
+		return
+	}
+	if _, err := h.Models.Reload(r.Context()); err != nil {
+		slog.Warn("model registry reload after alias change failed", "err", err)
+	}
+}
@@ -0,0 +1,93 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/llmproxy"
+)
+
+func TestSetModelAliasDefaultsWarningWindow(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mux := http.NewServeMux()
+	NewAdminHandler(db, nil).Mount(mux)
+
+	effective := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectExec("INSERT INTO model_aliases").
+		WithArgs("openai/gpt-4-0613", "openai/gpt-4o", effective.Add(-defaultModelAliasWarning), effective).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO admin_audit_log").
+		WithArgs(sqlmock.AnyArg(), "admin.models.aliases.set", "openai/gpt-4-0613", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/admin/models/aliases/openai/gpt-4-0613",
+		bytes.NewBufferString(`{"replacement_id":"openai/gpt-4o","effective_at":"2026-06-01T00:00:00Z"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Alias llmproxy.ModelAlias `json:"alias"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Alias.ReplacementID != "openai/gpt-4o" || !resp.Alias.WarnFrom.Equal(effective.Add(-defaultModelAliasWarning)) {
+		t.Fatalf("alias = %+v", resp.Alias)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestSetModelAliasRejectsBadRequests(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mux := http.NewServeMux()
+	NewAdminHandler(db, nil).Mount(mux)
+
+	for _, body := range []string{
+		`{"effective_at":"2026-06-01T00:00:00Z"}`,
+		`{"replacement_id":"old"}`,
+		`{"replacement_id":"old","effective_at":"2026-06-01T00:00:00Z"}`,
+		`{"replacement_id":"new","effective_at":"2026-06-01T00:00:00Z","warn_from":"2026-07-01T00:00:00Z"}`,
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/admin/models/aliases/old", bytes.NewBufferString(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, w.Code)
+		}
+	}
+
+	mock.ExpectExec("INSERT INTO model_aliases").WillReturnResult(sqlmock.NewResult(0, 0))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/admin/models/aliases/old",
+		bytes.NewBufferString(`{"replacement_id":"missing","effective_at":"2026-06-01T00:00:00Z"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown replacement status = %d", w.Code)
+	}
+
+	mock.ExpectExec("DELETE FROM model_aliases").WithArgs("old").WillReturnResult(sqlmock.NewResult(0, 0))
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/admin/models/aliases/old", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("delete unknown alias status = %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/plans"
+)
+
+type planRequest struct {
+	Name              string  `json:"name"`
+	MaxSwarmAgents    int     `json:"max_swarm_agents"`
+	ContainerMemoryMB int     `json:"container_memory_mb"`
+	ContainerCPU      float64 `json:"container_cpu"`
+	ContainerDiskMB   int     `json:"container_disk_mb"`
+	RPMLimit          int     `json:"rpm_limit"`
+	MonthlyTokenCap   int64   `json:"monthly_token_cap"`
+	// MaxConcurrentRequests caps in-flight LLM proxy calls; 0 is the default.
+	MaxConcurrentRequests int             `json:"max_concurrent_requests"`
+	Features              map[string]bool `json:"features"`
+}
+
+func (req planRequest) validate() error {
+	if strings.TrimSpace(req.Name) == "" {
+		return errors.New("name is required")
+	}
+	if req.MaxSwarmAgents < 0 || req.ContainerMemoryMB < 0 || req.ContainerCPU < 0 || req.ContainerDiskMB < 0 || req.RPMLimit < 0 || req.MonthlyTokenCap < 0 || req.MaxConcurrentRequests < 0 {
+		return errors.New("plan limits must be zero or positive")
+	}
+	return nil
+}
+
+func (req planRequest) plan(id string) plans.Plan {
+	return plans.Plan{
+		ID:                    id,
+		Name:                  strings.TrimSpace(req.Name),
+		MaxSwarmAgents:        req.MaxSwarmAgents,
+		ContainerMemoryMB:     req.ContainerMemoryMB,
+		ContainerCPU:          req.ContainerCPU,
+		ContainerDiskMB:       req.ContainerDiskMB,
+		RPMLimit:              req.RPMLimit,
+		MonthlyTokenCap:       req.MonthlyTokenCap,
+		MaxConcurrentRequests: req.MaxConcurrentRequests,
+		Features:              req.Features,
+	}
+}
+
+func (h *AdminHandler) requirePlans(w http.ResponseWriter) bool {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return false
+	}
+	if h.Plans == nil {
+		writeError(w, http.StatusServiceUnavailable, "plans are not configured")
+		return false
+	}
+	return true
+}
+
+func (h *AdminHandler) handleListPlans(w http.ResponseWriter, r *http.Request) {
+	if !h.requirePlans(w) {
+		return
+	}
+	list, err := h.Plans.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query plans")
+		return
+	}
+	h.logAdminAction(r.Context(), "admin.plans.list", "", map[string]any{"count": len(list)})
+	writeJSON(w, http.StatusOK, map[string]any{"plans": list})
+}
+
+func (h *AdminHandler) handleCreatePlan(w http.ResponseWriter, r *http.Request) {
+	if !h.requirePlans(w) {
+		return
+	}
+	var req planRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	plan, err := h.Plans.Create(r.Context(), req.plan(""))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create plan")
+		return
+	}
+	h.logAdminAction(r.Context(), "admin.plans.create", plan.ID, map[string]any{"name": plan.Name})
+	writeJSON(w, http.StatusCreated, map[string]any{"plan": plan})
+}
+
+func (h *AdminHandler) handleUpdatePlan(w http.ResponseWriter, r *http.Request) {
+	if !h.requirePlans(w) {
+		return
+	}
+	planID := strings.TrimSpace(r.PathValue("id"))
+	if planID == "" {
+		writeError(w, http.StatusBadRequest, "missing plan id")
+		return
+	}
+	var req planRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	plan, err := h.Plans.Update(r.Context(), req.plan(planID))
+	if errors.Is(err, plans.ErrPlanNotFound) {
+		writeError(w, http.StatusNotFound, "plan not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to update plan")
+		return
+	}
+	h.logAdminAction(r.Context(), "admin.plans.update", plan.ID, map[string]any{"name": plan.Name})
+	writeJSON(w, http.StatusOK, map[string]any{"plan": plan})
+}
+
+func (h *AdminHandler) handleDeletePlan(w http.ResponseWriter, r *http.Request) {
+	if !h.requirePlans(w) {
+		return
+	}
+	planID := strings.TrimSpace(r.PathValue("id"))
+	if planID == "" {
+		writeError(w, http.StatusBadRequest, "missing plan id")
+		return
+	}
+
+	if err := h.Plans.Delete(r.Context(), planID); err != nil {
+		if errors.Is(err, plans.ErrPlanNotFound) {
+			writeError(w, http.StatusNotFound, "plan not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to delete plan")
+		return
+	}
+	h.logAdminAction(r.Context(), "admin.plans.delete", planID, nil)
+	writeJSON(w, http.StatusOK, map[string]any{"id": planID, "deleted": true})
+}
+
+func (h *AdminHandler) handleSetTenantPlan(w http.ResponseWriter, r *http.Request) {
+	if !h.requirePlans(w) {
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	var req struct {
+		PlanID string `json:"plan_id"`
+	}
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	planID := strings.TrimSpace(req.PlanID)
+	if planID != "" {
+		if _, err := h.Plans.Get(r.Context(), planID); err != nil {
+			if errors.Is(err, plans.ErrPlanNotFound) {
+				writeError(w, http.StatusNotFound, "plan not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "failed to load plan")
+			return
+		}
+	}
+
+	if err := h.Plans.AssignTenant(r.Context(), tenantID, planID); err != nil {
+		if errors.Is(err, plans.ErrTenantNotFound) {
+			writeError(w, http.StatusNotFound, "tenant not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to assign plan")
+		return
+	}
+	h.logAdminAction(r.Context(), "admin.tenants.plan", tenantID, map[string]any{"plan_id": emptyToNil(planID)})
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tenant_id": tenantID,
+		"plan_id":   emptyToNil(planID),
+	})
+}
+
+// tenantPlanSnapshot summarises the tenant's plan and usage against its
+// monthly token cap for the tenant overview.
+func (h *AdminHandler) tenantPlanSnapshot(r *http.Request, tenantID string) map[string]any {
+	if h.Plans == nil {
+		return nil
+	}
+	plan, err := h.Plans.ForTenant(r.Context(), tenantID)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	if plan == nil {
+		return nil
+	}
+
+	now := time.Now()
+	periodStart, renewal := plans.BillingPeriod(now)
+	snapshot := map[string]any{
+		"id":                plan.ID,
+		"name":              plan.Name,
+		"limits":            plan,
+		"period_start":      periodStart,
+		"renewal_date":      renewal,
+		"monthly_token_cap": plan.MonthlyTokenCap,
+	}
+	used, err := h.Plans.MonthlyTokenUsage(r.Context(), tenantID, now)
+	if err != nil {
+		snapshot["usage_error"] = err.Error()
+		return snapshot
+	}
+	snapshot["tokens_used"] = used
+	if plan.MonthlyTokenCap > 0 {
+		snapshot["tokens_remaining"] = max(plan.MonthlyTokenCap-used, 0)
+		snapshot["cap_used_pct"] = float64(used) * 100 / float64(plan.MonthlyTokenCap)
+	}
+	return snapshot
+}
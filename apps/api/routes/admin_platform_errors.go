@@ -0,0 +1,79 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// handleListPlatformErrors returns recent ERROR-level API log records
+// captured by the errorlog sink, newest first.
+func (h *AdminHandler) handleListPlatformErrors(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 100
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = min(n, 1000)
+	}
+	var since any
+	if raw := strings.TrimSpace(query.Get("since")); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "since must be an RFC3339 timestamp")
+			return
+		}
+		since = t
+	}
+
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT id, component, message, details_json, created_at
+		FROM platform_errors
+		WHERE ($1::text IS NULL OR component = $1)
+		  AND ($2::timestamptz IS NULL OR created_at >= $2)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3
+	`, emptyToNil(strings.TrimSpace(query.Get("component"))), since, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query platform errors")
+		return
+	}
+	defer rows.Close()
+
+	errs := make([]map[string]any, 0)
+	for rows.Next() {
+		var (
+			id                 int64
+			component, message string
+			details            []byte
+			createdAt          time.Time
+		)
+		if err := rows.Scan(&id, &component, &message, &details, &createdAt); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to scan platform error")
+			return
+		}
+		errs = append(errs, map[string]any{
+			"id":         id,
+			"component":  component,
+			"message":    message,
+			"details":    json.RawMessage(details),
+			"created_at": createdAt,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed while reading platform errors")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"errors": errs})
+}
@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestListPlatformErrors(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	h := NewAdminHandler(db, nil)
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	since := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM platform_errors").WithArgs("channels", since, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "component", "message", "details_json", "created_at"}).
+			AddRow(7, "channels", "route failed", []byte(`{"tenant":"t1"}`), since.Add(time.Hour)))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/platform/errors?component=channels&since=2026-10-16T00:00:00Z&limit=5", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"details":{"tenant":"t1"}`) || !strings.Contains(w.Body.String(), `"message":"route failed"`) {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+
+	for _, q := range []string{"limit=0", "since=yesterday"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/platform/errors?"+q, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status=%d", q, w.Code)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/agentsquads/api/policies"
+	"github.com/agentsquads/api/tools"
+)
+
+// policySettings validates the settings sent with a feature policy and
+// returns them normalized. Only webhook_tool and internal_fetch have
+// settings.
+func policySettings(feature string, raw json.RawMessage) (json.RawMessage, error) {
+	switch feature {
+	case policies.FeatureWebhookTool:
+		settings, err := tools.ParseWebhookSettings(raw)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(settings)
+	case policies.FeatureInternalFetch:
+		settings, err := tools.ParseInternalFetchSettings(raw)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(settings)
+	default:
+		return nil, errors.New("feature has no settings")
+	}
+}
+
+func (h *AdminHandler) handleSetTenantPolicy(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	if h.Policies == nil {
+		writeError(w, http.StatusServiceUnavailable, "policies are not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	feature := strings.TrimSpace(r.PathValue("feature"))
+	if tenantID == "" || feature == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id or feature")
+		return
+	}
+	if !policies.KnownFeature(feature) {
+		writeError(w, http.StatusBadRequest, "unknown feature")
+		return
+	}
+	var req struct {
+		Enabled  *bool           `json:"enabled"`
+		Settings json.RawMessage `json:"settings"`
+	}
+	if err := decodeJSONStrict(r, &req); err != nil || req.Enabled == nil {
+		writeError(w, http.StatusBadRequest, "enabled is required")
+		return
+	}
+	var settings json.RawMessage
+	if len(req.Settings) > 0 {
+		var err error
+		if settings, err = policySettings(feature, req.Settings); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if err := h.Policies.Set(r.Context(), tenantID, feature, *req.Enabled); err != nil {
+		if errors.Is(err, policies.ErrTenantNotFound) {
+			writeError(w, http.StatusNotFound, "tenant not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to update tenant policy")
+		return
+	}
+	if settings != nil {
+		if err := h.Policies.SetSettings(r.Context(), tenantID, feature, settings); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to update tenant policy settings")
+			return
+		}
+	}
+	details := map[string]any{"feature": feature, "enabled": *req.Enabled}
+	resp := map[string]any{
+		"tenant_id": tenantID,
+		"feature":   feature,
+		"enabled":   *req.Enabled,
+	}
+	if settings != nil {
+		details["settings"] = settings
+		resp["settings"] = settings
+	}
+	h.logAdminAction(r.Context(), "admin.tenants.policy", tenantID, details)
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,183 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/agentsquads/api/prompts"
+	"github.com/google/uuid"
+)
+
+// Prompt template endpoints act on the platform layer, or on a tenant's
+// override layer when ?tenant_id= is set.
+
+func (h *AdminHandler) requirePrompts(w http.ResponseWriter) bool {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return false
+	}
+	if h.Prompts == nil {
+		writeError(w, http.StatusServiceUnavailable, "prompt templates are not configured")
+		return false
+	}
+	return true
+}
+
+// promptTarget reads the template name, optional version and tenant layer
+// from the request.
+func promptTarget(w http.ResponseWriter, r *http.Request, withVersion bool) (name, tenantID string, version int, ok bool) {
+	name = strings.TrimSpace(r.PathValue("name"))
+	if name != "" && !prompts.ValidName(name) {
+		writeError(w, http.StatusBadRequest, prompts.ErrInvalidName.Error())
+		return "", "", 0, false
+	}
+	tenantID = strings.TrimSpace(r.URL.Query().Get("tenant_id"))
+	if tenantID != "" {
+		if _, err := uuid.Parse(tenantID); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid tenant_id")
+			return "", "", 0, false
+		}
+	}
+	if withVersion {
+		n, err := strconv.Atoi(r.PathValue("version"))
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid version")
+			return "", "", 0, false
+		}
+		version = n
+	}
+	return name, tenantID, version, true
+}
+
+func (h *AdminHandler) handleListPromptTemplates(w http.ResponseWriter, r *http.Request) {
+	if !h.requirePrompts(w) {
+		return
+	}
+	name, tenantID, _, ok := promptTarget(w, r, false)
+	if !ok {
+		return
+	}
+	if name == "" {
+		name = strings.TrimSpace(r.URL.Query().Get("name"))
+	}
+	list, err := h.Prompts.List(r.Context(), tenantID, name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query prompt templates")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"templates": list})
+}
+
+func (h *AdminHandler) handleCreatePromptVersion(w http.ResponseWriter, r *http.Request) {
+	if !h.requirePrompts(w) {
+		return
+	}
+	name, tenantID, _, ok := promptTarget(w, r, false)
+	if !ok {
+		return
+	}
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		writeError(w, http.StatusBadRequest, "content is required")
+		return
+	}
+
+	t, err := h.Prompts.CreateVersion(r.Context(), tenantID, name, req.Content, adminActorID(r.Context()))
+	if errors.Is(err, prompts.ErrTenantNotFound) {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	if errors.Is(err, prompts.ErrTemplateSyntax) || errors.Is(err, prompts.ErrUnknownVariable) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to save prompt template")
+		return
+	}
+	h.logAdminAction(r.Context(), "admin.prompts.create_version", name, map[string]any{
+		"tenant_id": tenantID,
+		"version":   t.Version,
+	})
+	writeJSON(w, http.StatusCreated, map[string]any{"template": t})
+}
+
+func (h *AdminHandler) handleActivatePromptVersion(w http.ResponseWriter, r *http.Request) {
+	if !h.requirePrompts(w) {
+		return
+	}
+	name, tenantID, version, ok := promptTarget(w, r, true)
+	if !ok {
+		return
+	}
+	t, err := h.Prompts.Activate(r.Context(), tenantID, name, version)
+	if errors.Is(err, prompts.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "prompt template version not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to activate prompt template")
+		return
+	}
+	h.logAdminAction(r.Context(), "admin.prompts.activate", name, map[string]any{
+		"tenant_id": tenantID,
+		"version":   version,
+	})
+	writeJSON(w, http.StatusOK, map[string]any{"template": t})
+}
+
+func (h *AdminHandler) handleDeactivatePrompt(w http.ResponseWriter, r *http.Request) {
+	if !h.requirePrompts(w) {
+		return
+	}
+	name, tenantID, _, ok := promptTarget(w, r, false)
+	if !ok {
+		return
+	}
+	err := h.Prompts.Deactivate(r.Context(), tenantID, name)
+	if errors.Is(err, prompts.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no active version")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to deactivate prompt template")
+		return
+	}
+	h.logAdminAction(r.Context(), "admin.prompts.deactivate", name, map[string]any{"tenant_id": tenantID})
+	writeJSON(w, http.StatusOK, map[string]any{"name": name, "tenant_id": tenantID, "active": false})
+}
+
+func (h *AdminHandler) handleDeletePromptVersion(w http.ResponseWriter, r *http.Request) {
+	if !h.requirePrompts(w) {
+		return
+	}
+	name, tenantID, version, ok := promptTarget(w, r, true)
+	if !ok {
+		return
+	}
+	err := h.Prompts.DeleteVersion(r.Context(), tenantID, name, version)
+	switch {
+	case errors.Is(err, prompts.ErrNotFound):
+		writeError(w, http.StatusNotFound, "prompt template version not found")
+		return
+	case errors.Is(err, prompts.ErrActiveVersion):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "failed to delete prompt template")
+		return
+	}
+	h.logAdminAction(r.Context(), "admin.prompts.delete_version", name, map[string]any{
+		"tenant_id": tenantID,
+		"version":   version,
+	})
+	writeJSON(w, http.StatusOK, map[string]any{"name": name, "tenant_id": tenantID, "version": version, "deleted": true})
+}
@@ -0,0 +1,61 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/prompts"
+)
+
+func TestAdminPromptTemplates(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	h := NewAdminHandler(db, nil)
+	h.Prompts = prompts.NewStore(db)
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	const tenantID = "6f1c2b8e-4d10-4c6e-9a55-3f1c2b8e4d10"
+	columns := []string{"id", "name", "tenant_id", "version", "content", "active", "created_by", "created_at", "activated_at"}
+	now := time.Now()
+	mock.ExpectQuery("INSERT INTO prompt_templates").WithArgs("decomposition", tenantID, "Split {{task}}", "unknown").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("p1", "decomposition", tenantID, 2, "Split {{task}}", false, "unknown", now, nil))
+	mock.ExpectExec("INSERT INTO admin_audit_log").WithArgs("unknown", "admin.prompts.create_version", "decomposition", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectBegin()
+	mock.ExpectExec("SET active = FALSE").WithArgs("decomposition", tenantID, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SET active = TRUE").WithArgs("decomposition", tenantID, 2).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("p1", "decomposition", tenantID, 2, "Split {{task}}", true, "unknown", now, now))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO admin_audit_log").WithArgs("unknown", "admin.prompts.activate", "decomposition", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	tests := []struct {
+		method, path, body string
+		wantStatus         int
+		wantBody           string
+	}{
+		{http.MethodPost, "/api/admin/prompts/decomposition/versions?tenant_id=" + tenantID, `{"content":"Split {{task}}"}`, http.StatusCreated, `"version":2`},
+		{http.MethodPost, "/api/admin/prompts/decomposition/versions/2/activate?tenant_id=" + tenantID, "", http.StatusOK, `"active":true`},
+		{http.MethodPost, "/api/admin/prompts/decomposition/versions", `{"content":" "}`, http.StatusBadRequest, "content is required"},
+		{http.MethodPost, "/api/admin/prompts/Bad%20Name/versions", `{"content":"x"}`, http.StatusBadRequest, "template name"},
+		{http.MethodPost, "/api/admin/prompts/decomposition/versions/0/activate", "", http.StatusBadRequest, "invalid version"},
+		{http.MethodGet, "/api/admin/prompts?tenant_id=t1", "", http.StatusBadRequest, "invalid tenant_id"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+			t.Fatalf("%s %s: status=%d body=%s", tt.method, tt.path, w.Code, w.Body.String())
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
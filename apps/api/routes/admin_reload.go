@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// handleReloadConfig reloads the model registry, the platform swarm defaults
+// and the system message overrides from the database now instead of at the
+// next timer tick, and reports what changed. A part that is not configured is
+// skipped.
+func (h *AdminHandler) handleReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if h.Models == nil && h.SwarmSettings == nil && h.Messages == nil {
+		writeError(w, http.StatusServiceUnavailable, "nothing to reload")
+		return
+	}
+
+	response := map[string]any{}
+	if h.Models != nil {
+		changes, err := h.Models.Reload(r.Context())
+		if err != nil {
+			slog.Error("model registry reload failed", "err", err)
+			writeError(w, http.StatusInternalServerError, "failed to reload models")
+			return
+		}
+		response["models"] = changes
+	}
+	if h.SwarmSettings != nil {
+		changed, err := h.SwarmSettings.Reload(r.Context())
+		if err != nil {
+			slog.Error("swarm settings reload failed", "err", err)
+			writeError(w, http.StatusInternalServerError, "failed to reload swarm config")
+			return
+		}
+		response["swarm_config"] = map[string]any{"changed": changed}
+	}
+	if h.Messages != nil {
+		overrides, err := h.Messages.Reload(r.Context())
+		if err != nil {
+			slog.Error("message overrides reload failed", "err", err)
+			writeError(w, http.StatusInternalServerError, "failed to reload message overrides")
+			return
+		}
+		response["messages"] = map[string]any{"overrides": overrides}
+	}
+
+	h.logAdminAction(r.Context(), "admin.config.reload", "", response)
+	writeJSON(w, http.StatusOK, response)
+}
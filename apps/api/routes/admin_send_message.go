@@ -0,0 +1,184 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/agentsquads/api/channels"
+)
+
+// maxAuditedMessageRunes is how much of an admin message the audit log keeps.
+const maxAuditedMessageRunes = 200
+
+type adminMessageDelivery struct {
+	Channel string `json:"channel"`
+	Status  string `json:"status"` // queued, not_connected, muted, failed
+}
+
+// handleSendAdminMessage sends an operator message to the tenant's chosen
+// channels as if their agent had written it. The message goes through the
+// outbound stream, so the fanout delivers it; channels that are not linked
+// or are muted are skipped and reported in the summary.
+func (h *AdminHandler) handleSendAdminMessage(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	publish := h.publishOutbound
+	if publish == nil {
+		if h.Redis == nil {
+			writeError(w, http.StatusServiceUnavailable, "redis is not configured")
+			return
+		}
+		publish = func(ctx context.Context, out channels.OutboundMessage) error {
+			return channels.PublishOutbound(ctx, h.Redis, out)
+		}
+	}
+
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	var req struct {
+		Content        string   `json:"content"`
+		Channels       []string `json:"channels"`
+		ConversationID string   `json:"conversation_id"`
+	}
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	req.Content = strings.TrimSpace(req.Content)
+	if req.Content == "" {
+		writeError(w, http.StatusBadRequest, "content is required")
+		return
+	}
+	targets, err := normalizeMessageChannels(req.Channels)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	linked, err := h.tenantChannelLinks(r.Context(), tenantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load tenant channels")
+		return
+	}
+
+	summary := make([]adminMessageDelivery, 0, len(targets))
+	var queued []string
+	for _, channel := range targets {
+		muted, ok := linked[channel]
+		switch {
+		case !ok:
+			summary = append(summary, adminMessageDelivery{Channel: channel, Status: "not_connected"})
+			continue
+		case muted:
+			summary = append(summary, adminMessageDelivery{Channel: channel, Status: "muted"})
+			continue
+		}
+		err := publish(r.Context(), channels.OutboundMessage{
+			TenantID:       tenantID,
+			Content:        req.Content,
+			Channel:        channel,
+			ConversationID: strings.TrimSpace(req.ConversationID),
+			Metadata:       map[string]string{"source": "admin"},
+		})
+		if err != nil {
+			slog.Error("publish admin message failed", "tenant", tenantID, "channel", channel, "err", err)
+			summary = append(summary, adminMessageDelivery{Channel: channel, Status: "failed"})
+			continue
+		}
+		queued = append(queued, channel)
+		summary = append(summary, adminMessageDelivery{Channel: channel, Status: "queued"})
+	}
+
+	h.logAdminAction(r.Context(), "send_message", tenantID, map[string]any{
+		"content":         truncateRunes(req.Content, maxAuditedMessageRunes),
+		"channels":        queued,
+		"conversation_id": strings.TrimSpace(req.ConversationID),
+	})
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tenant_id":  tenantID,
+		"queued":     len(queued),
+		"deliveries": summary,
+	})
+}
+
+// normalizeMessageChannels lowercases and de-duplicates the requested
+// channels, rejecting unknown ones.
+func normalizeMessageChannels(raw []string) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("channels is required")
+	}
+	seen := make(map[string]bool, len(raw))
+	out := make([]string, 0, len(raw))
+	for _, c := range raw {
+		c = strings.ToLower(strings.TrimSpace(c))
+		switch c {
+		case "web", "telegram", "whatsapp", "viber":
+		default:
+			return nil, errors.New("unsupported channel: " + c)
+		}
+		if !seen[c] {
+			seen[c] = true
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// tenantChannelLinks maps each linked channel to whether it is muted. It
+// returns sql.ErrNoRows when the tenant does not exist.
+func (h *AdminHandler) tenantChannelLinks(ctx context.Context, tenantID string) (map[string]bool, error) {
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT tc.channel, COALESCE(tc.muted, FALSE)
+		FROM tenants t
+		LEFT JOIN tenant_channels tc ON tc.tenant_id = t.id
+		WHERE t.id = $1
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := false
+	linked := map[string]bool{}
+	for rows.Next() {
+		found = true
+		var (
+			channel sql.NullString
+			muted   bool
+		)
+		if err := rows.Scan(&channel, &muted); err != nil {
+			return nil, err
+		}
+		if channel.Valid {
+			linked[channel.String] = muted
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, sql.ErrNoRows
+	}
+	return linked, nil
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
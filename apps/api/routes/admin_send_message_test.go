@@ -0,0 +1,104 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/channels"
+)
+
+func TestSendAdminMessage(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	var published []channels.OutboundMessage
+	h := NewAdminHandler(db, nil)
+	h.publishOutbound = func(_ context.Context, out channels.OutboundMessage) error {
+		published = append(published, out)
+		return nil
+	}
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	content := strings.Repeat("é", 250)
+	mock.ExpectQuery("LEFT JOIN tenant_channels").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"channel", "muted"}).
+			AddRow("telegram", false).
+			AddRow("whatsapp", true))
+	mock.ExpectExec("INSERT INTO admin_audit_log").
+		WithArgs(sqlmock.AnyArg(), "send_message", "t1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	body := `{"content":"` + content + `","channels":["Telegram","whatsapp","viber"],"conversation_id":"c1"}`
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/tenants/t1/send-message", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Queued     int                    `json:"queued"`
+		Deliveries []adminMessageDelivery `json:"deliveries"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := []adminMessageDelivery{{"telegram", "queued"}, {"whatsapp", "muted"}, {"viber", "not_connected"}}
+	if resp.Queued != 1 || len(resp.Deliveries) != 3 {
+		t.Fatalf("resp = %+v", resp)
+	}
+	for i := range want {
+		if resp.Deliveries[i] != want[i] {
+			t.Fatalf("deliveries = %+v", resp.Deliveries)
+		}
+	}
+	if len(published) != 1 || published[0].Channel != "telegram" || published[0].ConversationID != "c1" || published[0].Content != content {
+		t.Fatalf("published = %+v", published)
+	}
+	if got := truncateRunes(content, maxAuditedMessageRunes); len([]rune(got)) != 200 {
+		t.Fatalf("audited content has %d runes", len([]rune(got)))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestSendAdminMessageRejectsBadInput(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	h := NewAdminHandler(db, nil)
+	h.publishOutbound = func(context.Context, channels.OutboundMessage) error { return nil }
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	post := func(path, body string) int {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return w.Code
+	}
+	if code := post("/api/admin/tenants/t1/send-message", `{"content":" ","channels":["telegram"]}`); code != http.StatusBadRequest {
+		t.Fatalf("empty content status = %d", code)
+	}
+	if code := post("/api/admin/tenants/t1/send-message", `{"content":"hi","channels":["sms"]}`); code != http.StatusBadRequest {
+		t.Fatalf("unknown channel status = %d", code)
+	}
+
+	mock.ExpectQuery("LEFT JOIN tenant_channels").WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows([]string{"channel", "muted"}))
+	if code := post("/api/admin/tenants/missing/send-message", `{"content":"hi","channels":["telegram"]}`); code != http.StatusNotFound {
+		t.Fatalf("unknown tenant status = %d", code)
+	}
+}
@@ -0,0 +1,93 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/orchestrator"
+)
+
+// containerSnapshotMaxBytes caps a container filesystem export.
+const containerSnapshotMaxBytes int64 = 2 << 30
+
+// handleContainerSnapshot streams a tarball of the tenant container's
+// filesystem for backup. The container must be stopped so the export is
+// consistent, and exports over 2 GB are refused before anything is sent.
+func (h *AdminHandler) handleContainerSnapshot(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	exporter, ok := orchestratorCapability[orchestrator.FilesystemExporter](w, h.Orch, "container snapshots")
+	if !ok {
+		return
+	}
+
+	var containerID sql.NullString
+	err := h.DB.QueryRowContext(r.Context(), `SELECT container_id FROM tenants WHERE id = $1`, tenantID).Scan(&containerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load tenant")
+		return
+	}
+	id := strings.TrimSpace(containerID.String)
+	if id == "" {
+		writeError(w, http.StatusNotFound, "tenant container is not provisioned")
+		return
+	}
+
+	export, size, err := exporter.ExportFilesystem(r.Context(), tenantID, containerSnapshotMaxBytes)
+	switch {
+	case errors.Is(err, orchestrator.ErrContainerNotFound):
+		writeError(w, http.StatusNotFound, "tenant container not found")
+		return
+	case errors.Is(err, orchestrator.ErrContainerRunning):
+		writeError(w, http.StatusConflict, "stop the tenant container before taking a snapshot")
+		return
+	case errors.Is(err, orchestrator.ErrFilesystemTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "container filesystem exceeds the 2 GB snapshot limit")
+		return
+	case err != nil:
+		slog.Error("container snapshot failed", "tenant_id", tenantID, "container_id", id, "err", err)
+		writeError(w, http.StatusBadGateway, "failed to export tenant container")
+		return
+	}
+	defer export.Close()
+
+	h.logAdminAction(r.Context(), "admin.tenants.container_snapshot", tenantID, map[string]any{
+		"container_id": id,
+		"size_root_fs": size,
+	})
+
+	filename := fmt.Sprintf("tenant-%s-%s.tar", tenantID, time.Now().UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	// The size is an estimate; stop at the cap rather than trust it.
+	n, err := io.Copy(w, io.LimitReader(export, containerSnapshotMaxBytes+1))
+	if err != nil {
+		slog.Warn("container snapshot stream failed", "tenant_id", tenantID, "bytes", n, "error", err)
+		return
+	}
+	if n > containerSnapshotMaxBytes {
+		slog.Warn("container snapshot exceeded size limit mid-stream", "tenant_id", tenantID)
+		panic(http.ErrAbortHandler)
+	}
+}
@@ -0,0 +1,80 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/orchestrator"
+)
+
+type snapshotOrchestrator struct {
+	stubOrchestrator
+	err      error
+	exported bool
+}
+
+func (o *snapshotOrchestrator) ExportFilesystem(_ context.Context, tenantID string, maxBytes int64) (io.ReadCloser, int64, error) {
+	if o.err != nil {
+		return nil, 0, o.err
+	}
+	if tenantID != "t1" || maxBytes != containerSnapshotMaxBytes {
+		return nil, 0, fmt.Errorf("unexpected export of %s capped at %d", tenantID, maxBytes)
+	}
+	o.exported = true
+	return io.NopCloser(strings.NewReader("tar-bytes")), 9, nil
+}
+
+func TestContainerSnapshot(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		orch       *snapshotOrchestrator
+		wantStatus int
+	}{
+		{name: "stopped", orch: &snapshotOrchestrator{}, wantStatus: http.StatusOK},
+		{name: "running", orch: &snapshotOrchestrator{err: orchestrator.ErrContainerRunning}, wantStatus: http.StatusConflict},
+		{name: "too large", orch: &snapshotOrchestrator{err: orchestrator.ErrFilesystemTooLarge}, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "container gone", orch: &snapshotOrchestrator{err: fmt.Errorf("docker inspect: %w", orchestrator.ErrContainerNotFound)}, wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New: %v", err)
+		}
+		mock.ExpectQuery("SELECT container_id FROM tenants").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"container_id"}).AddRow("c1"))
+		if tt.wantStatus == http.StatusOK {
+			mock.ExpectExec("INSERT INTO admin_audit_log").WithArgs("unknown", "admin.tenants.container_snapshot", "t1", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+		}
+		h := NewAdminHandler(db, tt.orch)
+
+		mux := http.NewServeMux()
+		h.Mount(mux)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/tenants/t1/container/snapshot", nil))
+
+		if w.Code != tt.wantStatus {
+			t.Fatalf("%s: status = %d, want %d body=%s", tt.name, w.Code, tt.wantStatus, w.Body.String())
+		}
+		if tt.orch.exported != (tt.wantStatus == http.StatusOK) {
+			t.Fatalf("%s: exported = %v", tt.name, tt.orch.exported)
+		}
+		if tt.wantStatus == http.StatusOK {
+			if w.Header().Get("Content-Type") != "application/x-tar" || w.Body.String() != "tar-bytes" {
+				t.Fatalf("%s: headers=%v body=%q", tt.name, w.Header(), w.Body.String())
+			}
+			if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, `attachment; filename="tenant-t1-`) || !strings.HasSuffix(cd, `.tar"`) {
+				t.Fatalf("%s: Content-Disposition = %q", tt.name, cd)
+			}
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("%s: expectations: %v", tt.name, err)
+		}
+		db.Close()
+	}
+}
@@ -0,0 +1,125 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/agentsquads/api/orchestrator"
+)
+
+type staleContainer struct {
+	TenantID          string    `json:"tenant_id"`
+	SuspendedSince    time.Time `json:"suspended_since"`
+	ContainerStatus   string    `json:"container_status"`
+	EstimatedMemoryMB int64     `json:"estimated_memory_mb"`
+	DestroyError      string    `json:"destroy_error,omitempty"`
+
+	containerID sql.NullString
+}
+
+// handleStaleContainers lists containers of tenants suspended for over 30
+// days with their live status and the memory they are sized for.
+func (h *AdminHandler) handleStaleContainers(w http.ResponseWriter, r *http.Request) {
+	containers, ok := h.staleContainers(w, r)
+	if !ok {
+		return
+	}
+	var savingsMB int64
+	for _, sc := range containers {
+		savingsMB += sc.EstimatedMemoryMB
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"containers":           containers,
+		"count":                len(containers),
+		"potential_savings_mb": savingsMB,
+	})
+}
+
+// handleDestroyStaleContainers deletes the containers handleStaleContainers
+// lists, recording every deletion in admin_audit_log. Containers that fail to
+// delete are returned with their error and counted in potential_savings_mb.
+func (h *AdminHandler) handleDestroyStaleContainers(w http.ResponseWriter, r *http.Request) {
+	containers, ok := h.staleContainers(w, r)
+	if !ok {
+		return
+	}
+	var savingsMB, reclaimedMB int64
+	destroyed := 0
+	for _, sc := range containers {
+		if err := h.Orch.Delete(r.Context(), sc.TenantID); err != nil && !isNoContainerError(err) {
+			sc.DestroyError = err.Error()
+			savingsMB += sc.EstimatedMemoryMB
+			continue
+		}
+		h.logAdminAction(r.Context(), "admin.tenants.container.destroy", sc.TenantID, map[string]any{
+			"reason":           "stale_container",
+			"container_id":     sc.containerID.String,
+			"suspended_since":  sc.SuspendedSince,
+			"previous_status":  sc.ContainerStatus,
+			"reclaimed_mem_mb": sc.EstimatedMemoryMB,
+		})
+		sc.ContainerStatus = "destroyed"
+		reclaimedMB += sc.EstimatedMemoryMB
+		destroyed++
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"containers":           containers,
+		"count":                len(containers),
+		"potential_savings_mb": savingsMB,
+		"destroyed":            destroyed,
+		"reclaimed_mb":         reclaimedMB,
+	})
+}
+
+// staleContainers loads the containers of tenants suspended for over 30
+// days, by suspended_at, with their live status and estimated memory. It
+// writes the error response and returns false on failure.
+func (h *AdminHandler) staleContainers(w http.ResponseWriter, r *http.Request) ([]*staleContainer, bool) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return nil, false
+	}
+	if h.Orch == nil {
+		writeError(w, http.StatusServiceUnavailable, "orchestrator is not configured")
+		return nil, false
+	}
+
+	containers, err := loadStaleContainers(r.Context(), h.DB)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query stale containers")
+		return nil, false
+	}
+	for _, sc := range containers {
+		sc.EstimatedMemoryMB = orchestrator.TenantMemoryMB(r.Context(), h.Plans, sc.TenantID)
+		state, _ := h.tenantContainerSnapshot(r.Context(), sc.TenantID, sc.containerID)["state"].(string)
+		sc.ContainerStatus = state
+	}
+	return containers, true
+}
+
+func loadStaleContainers(ctx context.Context, db *sql.DB) ([]*staleContainer, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, suspended_at, container_id
+		FROM tenants
+		WHERE status = 'suspended'
+		  AND suspended_at < NOW() - INTERVAL '30 days'
+		  AND container_id IS NOT NULL
+		ORDER BY suspended_at ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	containers := make([]*staleContainer, 0)
+	for rows.Next() {
+		var sc staleContainer
+		if err := rows.Scan(&sc.TenantID, &sc.SuspendedSince, &sc.containerID); err != nil {
+			return nil, err
+		}
+		containers = append(containers, &sc)
+	}
+	return containers, rows.Err()
+}
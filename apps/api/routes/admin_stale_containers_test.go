@@ -0,0 +1,68 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAdminStaleContainers(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	h := NewAdminHandler(db, &stubOrchestrator{})
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	suspended := time.Now().Add(-45 * 24 * time.Hour)
+	staleRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "suspended_at", "container_id"}).
+			AddRow("t1", suspended, "c1").
+			AddRow("t2", suspended, "c2")
+	}
+
+	mock.ExpectQuery("SELECT id, suspended_at, container_id").WillReturnRows(staleRows())
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/tenants/stale-containers", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Containers         []staleContainer `json:"containers"`
+		PotentialSavingsMB int64            `json:"potential_savings_mb"`
+		Destroyed          int              `json:"destroyed"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Containers) != 2 || resp.Containers[0].ContainerStatus != "stopped" || resp.Containers[0].EstimatedMemoryMB != 512 || resp.PotentialSavingsMB != 1024 {
+		t.Fatalf("unexpected response: %s", w.Body.String())
+	}
+
+	mock.ExpectQuery("SELECT id, suspended_at, container_id").WillReturnRows(staleRows())
+	mock.ExpectExec("INSERT INTO admin_audit_log").WithArgs(sqlmock.AnyArg(), "admin.tenants.container.destroy", "t1", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO admin_audit_log").WithArgs(sqlmock.AnyArg(), "admin.tenants.container.destroy", "t2", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/admin/tenants/stale-containers/destroy", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	resp.Containers = nil
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Destroyed != 2 || resp.PotentialSavingsMB != 0 || resp.Containers[1].ContainerStatus != "destroyed" {
+		t.Fatalf("unexpected auto-destroy response: %s", w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
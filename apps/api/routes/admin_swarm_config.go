@@ -0,0 +1,91 @@
+package routes
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/agentsquads/api/coordinator"
+)
+
+type tenantSwarmConfigRequest struct {
+	MaxAgents             *int    `json:"max_agents"`
+	DefaultTimeoutSeconds *int    `json:"default_timeout_seconds"`
+	DecompositionPrompt   *string `json:"decomposition_prompt"`
+}
+
+// handleSetTenantSwarmConfig replaces a tenant's swarm overrides. Omitted or
+// null fields clear the override so the tenant falls back to its plan and
+// the platform defaults.
+func (h *AdminHandler) handleSetTenantSwarmConfig(w http.ResponseWriter, r *http.Request) {
+	if h.SwarmConfigs == nil {
+		writeError(w, http.StatusServiceUnavailable, "swarm config is not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+
+	var req tenantSwarmConfigRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	cfg := coordinator.TenantSwarmConfig{TenantID: tenantID}
+	if req.MaxAgents != nil {
+		if *req.MaxAgents == 0 {
+			writeError(w, http.StatusBadRequest, "max_agents must be between 1 and 20")
+			return
+		}
+		cfg.MaxAgents = *req.MaxAgents
+	}
+	if req.DefaultTimeoutSeconds != nil {
+		if *req.DefaultTimeoutSeconds == 0 {
+			writeError(w, http.StatusBadRequest, "default_timeout_seconds must be between 30 and 3600")
+			return
+		}
+		cfg.DefaultTimeoutSeconds = *req.DefaultTimeoutSeconds
+	}
+	if req.DecompositionPrompt != nil {
+		cfg.DecompositionPrompt = *req.DecompositionPrompt
+	}
+
+	saved, err := h.SwarmConfigs.Put(r.Context(), cfg)
+	switch {
+	case errors.Is(err, coordinator.ErrInvalidSwarmConfig):
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, coordinator.ErrTenantNotFound):
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	case err != nil:
+		slog.Error("save tenant swarm config failed", "tenant", tenantID, "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to update swarm config")
+		return
+	}
+	h.logAdminAction(r.Context(), "admin.tenants.swarm_config", tenantID, map[string]any{
+		"max_agents":              saved.MaxAgents,
+		"default_timeout_seconds": saved.DefaultTimeoutSeconds,
+		"decomposition_prompt":    saved.DecompositionPrompt != "",
+	})
+	writeJSON(w, http.StatusOK, saved)
+}
+
+// tenantSwarmConfigSnapshot returns the tenant's swarm overrides for the
+// tenant overview, or nil when it has none.
+func (h *AdminHandler) tenantSwarmConfigSnapshot(r *http.Request, tenantID string) any {
+	if h.SwarmConfigs == nil {
+		return nil
+	}
+	cfg, err := h.SwarmConfigs.TenantSwarmConfig(r.Context(), tenantID)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	if cfg == nil {
+		return nil
+	}
+	return cfg
+}
@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/coordinator"
+)
+
+func TestAdminSetTenantSwarmConfig(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	h := NewAdminHandler(db, nil)
+	h.SwarmConfigs = coordinator.NewTenantConfigStore(db, nil)
+	mux := http.NewServeMux()
+	h.Mount(mux)
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/admin/tenants/t1/swarm/config", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, body := range []string{`{"max_agents":0}`, `{"max_agents":21}`, `{"default_timeout_seconds":10}`, `{"default_timeout_seconds":4000}`, `{"unknown":1}`} {
+		if w := put(body); w.Code != http.StatusBadRequest {
+			t.Fatalf("body %s status = %d", body, w.Code)
+		}
+	}
+
+	mock.ExpectQuery("INSERT INTO tenant_swarm_configs").WithArgs("t1", 6, 120, "Split into research and writing").
+		WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+	mock.ExpectExec("INSERT INTO admin_audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+	w := put(`{"max_agents":6,"default_timeout_seconds":120,"decomposition_prompt":" Split into research and writing "}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"max_agents":6`) || !strings.Contains(w.Body.String(), `"default_timeout_seconds":120`) {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
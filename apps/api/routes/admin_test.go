@@ -1,9 +1,18 @@
 package routes
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/orchestrator"
+	"github.com/agentsquads/api/policies"
 )
 
 func TestAdminHandlerMountAndEndpointsWithNilDB(t *testing.T) {
@@ -14,9 +23,25 @@ func TestAdminHandlerMountAndEndpointsWithNilDB(t *testing.T) {
 
 	paths := []string{
 		"/api/admin/tenants",
+		"/api/admin/tenants/export",
+		"/api/admin/lookup?email=a",
+		"/api/admin/tenants/t1/whatsapp/status",
+		"/api/admin/tenants/t1/channels/telegram/raw-config",
+		"/api/admin/models/benchmarks",
 		"/api/admin/tenants/t1",
 		"/api/admin/stats",
+		"/api/admin/platform/errors",
 		"/api/admin/models",
+		"/api/admin/tenants/t1/network",
+		"/api/admin/tenants/t1/container/events",
+		"/api/admin/tenants/t1/model-access",
+		"/api/admin/plans",
+		"/api/admin/prompts",
+		"/api/admin/credits/negative-balances",
+		"/api/admin/swarm/feedback",
+		"/api/admin/tenants/t1/exec-history",
+		"/api/admin/crypto/rotations",
+		"/api/admin/crypto/rotations/r1",
 	}
 
 	for _, p := range paths {
@@ -28,3 +53,230 @@ func TestAdminHandlerMountAndEndpointsWithNilDB(t *testing.T) {
 		}
 	}
 }
+
+func TestAdminSetTenantPolicy(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	h := NewAdminHandler(db, nil)
+	h.Policies = policies.NewStore(db)
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	put := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := put("/api/admin/tenants/t1/policies/teleport", `{"enabled":true}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown feature status = %d", w.Code)
+	}
+	if w := put("/api/admin/tenants/t1/policies/swarm", `{}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing enabled status = %d", w.Code)
+	}
+
+	mock.ExpectExec("INSERT INTO tenant_policies").WithArgs("missing", "swarm", false).WillReturnResult(sqlmock.NewResult(0, 0))
+	if w := put("/api/admin/tenants/missing/policies/swarm", `{"enabled":false}`); w.Code != http.StatusNotFound {
+		t.Fatalf("missing tenant status = %d", w.Code)
+	}
+
+	mock.ExpectExec("INSERT INTO tenant_policies").WithArgs("t1", "swarm", false).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO admin_audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+	w := put("/api/admin/tenants/t1/policies/swarm", `{"enabled":false}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"enabled":false`) {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+
+	if w := put("/api/admin/tenants/t1/policies/swarm", `{"enabled":true,"settings":{}}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("settings for a feature without any status = %d", w.Code)
+	}
+	if w := put("/api/admin/tenants/t1/policies/webhook_tool", `{"enabled":true,"settings":{"webhook_allowed_domains":["http://x"]}}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid webhook domain status = %d", w.Code)
+	}
+	mock.ExpectExec("INSERT INTO tenant_policies").WithArgs("t1", "webhook_tool", true).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO tenant_policies").WithArgs("t1", "webhook_tool", `{"webhook_allowed_domains":["api.example.com"]}`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO admin_audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+	w = put("/api/admin/tenants/t1/policies/webhook_tool", `{"enabled":true,"settings":{"webhook_allowed_domains":["API.example.com"]}}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"webhook_allowed_domains":["api.example.com"]`) {
+		t.Fatalf("webhook policy status = %d body=%s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+type stubOrchestrator struct {
+	stopped []string
+	stopErr error
+	status  *orchestrator.ContainerStatus
+}
+
+func (s *stubOrchestrator) Create(context.Context, string) (*orchestrator.Container, error) {
+	return nil, nil
+}
+func (s *stubOrchestrator) Start(context.Context, string) error { return nil }
+func (s *stubOrchestrator) Stop(_ context.Context, tenantID string) error {
+	s.stopped = append(s.stopped, tenantID)
+	return s.stopErr
+}
+func (s *stubOrchestrator) Delete(context.Context, string) error { return nil }
+func (s *stubOrchestrator) Status(context.Context, string) (*orchestrator.ContainerStatus, error) {
+	if s.status != nil {
+		return s.status, nil
+	}
+	return &orchestrator.ContainerStatus{Running: false}, nil
+}
+func (s *stubOrchestrator) Exec(context.Context, string, []string) (string, error) { return "", nil }
+
+func TestTenantContainerSnapshotDetail(t *testing.T) {
+	t.Parallel()
+	sampledAt := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	orch := &stubOrchestrator{status: &orchestrator.ContainerStatus{
+		Running:               true,
+		Health:                "healthy",
+		MemoryMB:              498,
+		MemoryLimitMB:         512,
+		CPUPct:                12.5,
+		SampledAt:             sampledAt,
+		RestartCount:          4,
+		OOMKilled:             true,
+		OpenFangVersion:       "0.9.2",
+		OpenFangUptimeSeconds: 7200,
+	}}
+	h := NewAdminHandler(nil, orch)
+	containerID := sql.NullString{String: "cid", Valid: true}
+
+	snapshot := h.tenantContainerSnapshot(context.Background(), "t1", containerID)
+	if snapshot["memory_mb"] != int64(498) || snapshot["memory_limit_mb"] != int64(512) || snapshot["cpu_pct"] != 12.5 ||
+		snapshot["restart_count"] != 4 || snapshot["oom_killed"] != true || snapshot["openfang_version"] != "0.9.2" {
+		t.Fatalf("snapshot = %#v", snapshot)
+	}
+
+	// Without a stats sample, usage is reported as missing rather than zero.
+	orch.status = &orchestrator.ContainerStatus{Running: true, RestartCount: 1}
+	snapshot = h.tenantContainerSnapshot(context.Background(), "t1", containerID)
+	if snapshot["memory_mb"] != nil || snapshot["cpu_pct"] != nil || snapshot["sampled_at"] != nil || snapshot["restart_count"] != 1 {
+		t.Fatalf("snapshot without stats = %#v", snapshot)
+	}
+}
+
+func TestAdminNegativeBalances(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	orch := &stubOrchestrator{}
+	h := NewAdminHandler(db, orch)
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	rows := sqlmock.NewRows([]string{"tenant_id", "balance_cents", "email", "status", "container_id"}).
+		AddRow("t1", -500, "a@example.com", "active", "c1").
+		AddRow("t2", -20, "b@example.com", "suspended", nil)
+	mock.ExpectQuery("FROM credits c").WillReturnRows(rows)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE tenants SET status = 'suspended'").WithArgs("t1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO credit_transactions").WithArgs("t1", autoSuspendNegativeBalanceReason, nil).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO admin_audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/credits/negative-balances?auto_suspend=true", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Tenants        []negativeBalance `json:"tenants"`
+		TotalDebtCents int64             `json:"total_debt_cents"`
+		Suspended      int               `json:"suspended"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Tenants) != 2 || resp.TotalDebtCents != -520 || resp.Suspended != 1 {
+		t.Fatalf("unexpected response: %s", w.Body.String())
+	}
+	if !resp.Tenants[0].AutoSuspended || resp.Tenants[0].ContainerStatus != "stopped" || resp.Tenants[1].ContainerStatus != "not_provisioned" {
+		t.Fatalf("unexpected tenants: %+v", resp.Tenants)
+	}
+	if len(orch.stopped) != 1 || orch.stopped[0] != "t1" {
+		t.Fatalf("stopped = %v", orch.stopped)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/admin/credits/negative-balances?auto_suspend=maybe", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("bad auto_suspend status = %d", w.Code)
+	}
+}
+
+func TestResetCreditsSetsExactBalance(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	h := NewAdminHandler(db, nil)
+	mux := http.NewServeMux()
+	h.Mount(mux)
+	do := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/tenants/t1/reset-credits", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, body := range []string{`{"reason":"migration"}`, `{"balance_cents":-1,"reason":"migration"}`, `{"balance_cents":100}`, `{"balance_cents":100,"reason":"migration","amount":5}`} {
+		if w := do(body); w.Code != http.StatusBadRequest {
+			t.Fatalf("body %s status = %d", body, w.Code)
+		}
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("INSERT INTO credits").WithArgs("t1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT balance_cents FROM credits WHERE tenant_id = \\$1 FOR UPDATE").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"balance_cents"}).AddRow(1250))
+	mock.ExpectExec("UPDATE credits").WithArgs("t1", int64(5000)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO credit_transactions").WithArgs("t1", int64(3750), "migration", nil).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO admin_audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+	w := do(`{"balance_cents":5000,"reason":"migration"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Previous int64 `json:"previous_balance_cents"`
+		New      int64 `json:"new_balance_cents"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Previous != 1250 || resp.New != 5000 {
+		t.Fatalf("response = %s err=%v", w.Body.String(), err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
+	if w := do(`{"balance_cents":0,"reason":"migration"}`); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown tenant status = %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
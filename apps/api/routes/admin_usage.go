@@ -0,0 +1,116 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// usageRollupVerifyDays is the period GET /api/admin/usage/rollup/verify
+// samples when no range is given.
+const usageRollupVerifyDays = 7
+
+// usageDaysSQL is a subquery of per-day usage with the columns tenant_id,
+// model, day, input_tokens, output_tokens, revenue_cents and last_used_at.
+// Days before the current UTC day come from the usage_daily rollup and the
+// current day from usage_logs, so today's totals are exact whatever the
+// rollup job has reached. where, when set, filters both halves, e.g.
+// "tenant_id = $1".
+func usageDaysSQL(where string) string {
+	and := ""
+	if where != "" {
+		and = " AND " + where
+	}
+	return `(
+			SELECT tenant_id, model, day, input_tokens, output_tokens, cost_cents + margin_cents AS revenue_cents, last_used_at
+			FROM usage_daily
+			WHERE day < (NOW() AT TIME ZONE 'UTC')::date` + and + `
+			UNION ALL
+			SELECT tenant_id, model, (created_at AT TIME ZONE 'UTC')::date, input_tokens, output_tokens, cost_cents + margin_cents, created_at
+			FROM usage_logs
+			WHERE created_at >= DATE_TRUNC('day', NOW() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'` + and + `
+		)`
+}
+
+type usageBackfillRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// handleUsageRollupBackfill rebuilds the usage_daily rows for the UTC days in
+// [from, to). to defaults to tomorrow, so the current day is included.
+func (h *AdminHandler) handleUsageRollupBackfill(w http.ResponseWriter, r *http.Request) {
+	if h.Usage == nil {
+		writeError(w, http.StatusServiceUnavailable, "usage rollup is not configured")
+		return
+	}
+	var req usageBackfillRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	from, to, err := parseUsageRange(req.From, req.To, time.Time{}, time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if from.IsZero() {
+		writeError(w, http.StatusBadRequest, "from is required")
+		return
+	}
+
+	rows, err := h.Usage.Backfill(r.Context(), from, to)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to backfill usage rollup")
+		return
+	}
+	h.logAdminAction(r.Context(), "admin.usage.rollup.backfill", "", map[string]any{"from": from, "to": to, "rows": rows})
+	writeJSON(w, http.StatusOK, map[string]any{"from": from, "to": to, "rows": rows})
+}
+
+// handleUsageRollupVerify compares rollup and raw totals per UTC day. The
+// default range is the usageRollupVerifyDays complete days before today.
+func (h *AdminHandler) handleUsageRollupVerify(w http.ResponseWriter, r *http.Request) {
+	if h.Usage == nil {
+		writeError(w, http.StatusServiceUnavailable, "usage rollup is not configured")
+		return
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from, to, err := parseUsageRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"), today.AddDate(0, 0, -usageRollupVerifyDays), today)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.Usage.Verify(r.Context(), from, to)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to verify usage rollup")
+		return
+	}
+	h.logAdminAction(r.Context(), "admin.usage.rollup.verify", "", map[string]any{"from": from, "to": to, "mismatches": len(result.Mismatches)})
+	writeJSON(w, http.StatusOK, result)
+}
+
+// parseUsageRange reads YYYY-MM-DD bounds, using the defaults for empty ones.
+func parseUsageRange(rawFrom, rawTo string, defaultFrom, defaultTo time.Time) (time.Time, time.Time, error) {
+	from, to := defaultFrom, defaultTo
+	if raw := strings.TrimSpace(rawFrom); raw != "" {
+		t, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			return from, to, errors.New("from must be a YYYY-MM-DD date")
+		}
+		from = t
+	}
+	if raw := strings.TrimSpace(rawTo); raw != "" {
+		t, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			return from, to, errors.New("to must be a YYYY-MM-DD date")
+		}
+		to = t
+	}
+	if !from.IsZero() && !to.After(from) {
+		return from, to, errors.New("to must be after from")
+	}
+	return from, to, nil
+}
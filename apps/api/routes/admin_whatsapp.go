@@ -0,0 +1,189 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/channels"
+)
+
+const (
+	whatsAppStatusCacheTTL = 5 * time.Minute
+	whatsAppStatusFields   = "verified_name,code_verification_status,quality_rating,messaging_limit_tier"
+)
+
+// whatsAppStatus is a tenant's WhatsApp phone number health as reported by
+// the Graph API. Healthy is false when the number's quality is YELLOW or RED,
+// its verification lapsed, or Graph rejected the lookup (e.g. a revoked token
+// or deleted number); Issues says which.
+type whatsAppStatus struct {
+	TenantID               string    `json:"tenant_id"`
+	PhoneNumberID          string    `json:"phone_number_id"`
+	VerifiedName           string    `json:"verified_name,omitempty"`
+	CodeVerificationStatus string    `json:"code_verification_status,omitempty"`
+	QualityRating          string    `json:"quality_rating,omitempty"`
+	MessagingLimitTier     string    `json:"messaging_limit_tier,omitempty"`
+	Healthy                bool      `json:"healthy"`
+	Issues                 []string  `json:"issues,omitempty"`
+	CheckedAt              time.Time `json:"checked_at"`
+	Cached                 bool      `json:"cached"`
+}
+
+func whatsAppStatusCacheKey(tenantID string) string {
+	return "admin:whatsapp_status:" + tenantID
+}
+
+// handleWhatsAppStatus reports the health of the tenant's WhatsApp Business
+// phone number. Results are cached in Redis for whatsAppStatusCacheTTL so the
+// monitoring poll does not hit Graph rate limits.
+func (h *AdminHandler) handleWhatsAppStatus(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+
+	if status, ok := h.cachedWhatsAppStatus(r.Context(), tenantID); ok {
+		writeJSON(w, http.StatusOK, status)
+		return
+	}
+
+	cred, err := channels.NewCredentialsStore(h.DB).GetByTenantChannel(r.Context(), tenantID, "whatsapp")
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "whatsapp is not connected")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load whatsapp credentials")
+		return
+	}
+	accessToken := strings.TrimSpace(cred.Config["access_token"])
+	phoneNumberID := strings.TrimSpace(cred.Config["phone_number_id"])
+	if accessToken == "" || phoneNumberID == "" {
+		writeError(w, http.StatusNotFound, "whatsapp is not connected")
+		return
+	}
+	apiVersion := strings.TrimSpace(cred.Config["api_version"])
+	if apiVersion == "" {
+		apiVersion = "v20.0"
+	}
+
+	status, err := h.fetchWhatsAppStatus(r.Context(), accessToken, apiVersion, phoneNumberID)
+	if err != nil {
+		slog.Warn("whatsapp status check failed", "tenant", tenantID, "err", err)
+		writeError(w, http.StatusBadGateway, "failed to reach whatsapp graph api")
+		return
+	}
+	status.TenantID = tenantID
+	status.PhoneNumberID = phoneNumberID
+	h.cacheWhatsAppStatus(r.Context(), tenantID, status)
+	writeJSON(w, http.StatusOK, status)
+}
+
+// fetchWhatsAppStatus calls Graph for the phone number. A Graph error
+// response is a result (an unhealthy number), not an error; only transport
+// failures and unreadable responses are returned as errors.
+func (h *AdminHandler) fetchWhatsAppStatus(ctx context.Context, accessToken, apiVersion, phoneNumberID string) (whatsAppStatus, error) {
+	url := fmt.Sprintf("https://graph.facebook.com/%s/%s?fields=%s", apiVersion, phoneNumberID, whatsAppStatusFields)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return whatsAppStatus{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	client := h.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return whatsAppStatus{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return whatsAppStatus{}, err
+	}
+
+	status := whatsAppStatus{CheckedAt: time.Now().UTC()}
+	if resp.StatusCode >= http.StatusBadRequest {
+		var graphErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(body, &graphErr)
+		message := strings.TrimSpace(graphErr.Error.Message)
+		if message == "" {
+			message = fmt.Sprintf("graph api returned %d", resp.StatusCode)
+		}
+		status.Issues = []string{message}
+		return status, nil
+	}
+
+	var payload struct {
+		VerifiedName           string `json:"verified_name"`
+		CodeVerificationStatus string `json:"code_verification_status"`
+		QualityRating          string `json:"quality_rating"`
+		MessagingLimitTier     string `json:"messaging_limit_tier"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return whatsAppStatus{}, fmt.Errorf("decode graph response: %w", err)
+	}
+	status.VerifiedName = payload.VerifiedName
+	status.CodeVerificationStatus = payload.CodeVerificationStatus
+	status.QualityRating = payload.QualityRating
+	status.MessagingLimitTier = payload.MessagingLimitTier
+
+	switch quality := strings.ToUpper(payload.QualityRating); quality {
+	case "YELLOW", "RED":
+		status.Issues = append(status.Issues, "quality rating is "+quality)
+	}
+	switch verification := strings.ToUpper(payload.CodeVerificationStatus); verification {
+	case "EXPIRED", "NOT_VERIFIED":
+		status.Issues = append(status.Issues, "phone number verification is "+verification)
+	}
+	status.Healthy = len(status.Issues) == 0
+	return status, nil
+}
+
+func (h *AdminHandler) cachedWhatsAppStatus(ctx context.Context, tenantID string) (whatsAppStatus, bool) {
+	if h.Redis == nil {
+		return whatsAppStatus{}, false
+	}
+	raw, err := h.Redis.Get(ctx, whatsAppStatusCacheKey(tenantID)).Bytes()
+	if err != nil {
+		return whatsAppStatus{}, false
+	}
+	var status whatsAppStatus
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return whatsAppStatus{}, false
+	}
+	status.Cached = true
+	return status, true
+}
+
+func (h *AdminHandler) cacheWhatsAppStatus(ctx context.Context, tenantID string, status whatsAppStatus) {
+	if h.Redis == nil {
+		return
+	}
+	raw, err := json.Marshal(status)
+	if err != nil {
+		return
+	}
+	if err := h.Redis.Set(ctx, whatsAppStatusCacheKey(tenantID), raw, whatsAppStatusCacheTTL).Err(); err != nil {
+		slog.Warn("failed to cache whatsapp status", "tenant", tenantID, "err", err)
+	}
+}
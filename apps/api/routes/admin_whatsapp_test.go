@@ -0,0 +1,131 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type graphTransport func(*http.Request) (*http.Response, error)
+
+func (f graphTransport) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func graphReply(status int, body string) graphTransport {
+	return func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+	}
+}
+
+func whatsAppCredRows(config string) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"tenant_id", "channel", "config", "updated_at"}).AddRow("t1", "whatsapp", config, time.Now())
+}
+
+func TestAdminWhatsAppStatus(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		graph     graphTransport
+		status    int
+		healthy   bool
+		issueHint string
+	}{
+		{
+			name:    "healthy",
+			graph:   graphReply(http.StatusOK, `{"verified_name":"Acme","code_verification_status":"VERIFIED","quality_rating":"GREEN","messaging_limit_tier":"TIER_1K"}`),
+			status:  http.StatusOK,
+			healthy: true,
+		},
+		{
+			name:      "degraded quality",
+			graph:     graphReply(http.StatusOK, `{"verified_name":"Acme","code_verification_status":"EXPIRED","quality_rating":"RED","messaging_limit_tier":"TIER_50"}`),
+			status:    http.StatusOK,
+			issueHint: "quality rating is RED",
+		},
+		{
+			name:      "graph rejects lookup",
+			graph:     graphReply(http.StatusBadRequest, `{"error":{"message":"Object with ID '123' does not exist"}}`),
+			status:    http.StatusOK,
+			issueHint: "does not exist",
+		},
+		{
+			name:   "graph unreachable",
+			graph:  func(*http.Request) (*http.Response, error) { return nil, errors.New("dial tcp: timeout") },
+			status: http.StatusBadGateway,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New: %v", err)
+			}
+			defer db.Close()
+			mock.ExpectQuery("FROM channel_credentials").WithArgs("t1", "whatsapp").
+				WillReturnRows(whatsAppCredRows(`{"access_token":"tok","phone_number_id":"123","api_version":"v21.0"}`))
+
+			var gotURL, gotAuth string
+			h := NewAdminHandler(db, nil)
+			h.HTTPClient = &http.Client{Transport: graphTransport(func(r *http.Request) (*http.Response, error) {
+				gotURL, gotAuth = r.URL.String(), r.Header.Get("Authorization")
+				return tt.graph(r)
+			})}
+			mux := http.NewServeMux()
+			h.Mount(mux)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/tenants/t1/whatsapp/status", nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+			}
+			if gotURL != "https://graph.facebook.com/v21.0/123?fields="+whatsAppStatusFields || gotAuth != "Bearer tok" {
+				t.Fatalf("graph request url=%q auth=%q", gotURL, gotAuth)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var resp whatsAppStatus
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Healthy != tt.healthy || resp.TenantID != "t1" || resp.PhoneNumberID != "123" || resp.Cached {
+				t.Fatalf("resp = %+v", resp)
+			}
+			if tt.issueHint != "" && !strings.Contains(strings.Join(resp.Issues, "; "), tt.issueHint) {
+				t.Fatalf("issues = %v, want %q", resp.Issues, tt.issueHint)
+			}
+		})
+	}
+}
+
+func TestAdminWhatsAppStatusNotConnected(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mock.ExpectQuery("FROM channel_credentials").WithArgs("t1", "whatsapp").WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "channel", "config", "updated_at"}))
+	mock.ExpectQuery("FROM channel_credentials").WithArgs("t1", "whatsapp").WillReturnRows(whatsAppCredRows(`{"phone_number_id":"123"}`))
+
+	h := NewAdminHandler(db, nil)
+	h.HTTPClient = &http.Client{Transport: graphTransport(func(*http.Request) (*http.Response, error) {
+		t.Fatalf("graph called for a tenant without whatsapp")
+		return nil, nil
+	})}
+	mux := http.NewServeMux()
+	h.Mount(mux)
+	for range 2 {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/tenants/t1/whatsapp/status", nil))
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package routes
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/channels"
+)
+
+// BroadcastHandler lets tenants announce a message to every chat on their
+// Telegram, WhatsApp and Viber channels. Delivery happens in the background
+// (see channels.Broadcaster).
+type BroadcastHandler struct {
+	Store *channels.BroadcastStore
+}
+
+func NewBroadcastHandler(store *channels.BroadcastStore) *BroadcastHandler {
+	return &BroadcastHandler{Store: store}
+}
+
+func (h *BroadcastHandler) Mount(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/tenants/{id}/broadcast", h.handleCreateBroadcast)
+	mux.HandleFunc("GET /api/tenants/{id}/broadcasts/{broadcast_id}", h.handleGetBroadcast)
+	mux.HandleFunc("POST /api/tenants/{id}/broadcasts/{broadcast_id}/retry", h.handleRetryBroadcast)
+}
+
+type createBroadcastRequest struct {
+	Content     string     `json:"content"`
+	Channel     string     `json:"channel"`
+	ScheduledAt *time.Time `json:"scheduled_at"`
+}
+
+// handleCreateBroadcast queues content for every chat that has messaged the
+// tenant, optionally limited to one channel and delayed until scheduled_at.
+func (h *BroadcastHandler) handleCreateBroadcast(w http.ResponseWriter, r *http.Request) {
+	if h.Store == nil {
+		writeError(w, http.StatusServiceUnavailable, "broadcasts are not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+
+	var req createBroadcastRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		writeError(w, http.StatusBadRequest, "content is required")
+		return
+	}
+	scheduledAt := time.Now()
+	if req.ScheduledAt != nil && req.ScheduledAt.After(scheduledAt) {
+		scheduledAt = *req.ScheduledAt
+	}
+
+	broadcast, err := h.Store.Create(r.Context(), tenantID, req.Content, req.Channel, scheduledAt)
+	switch {
+	case errors.Is(err, channels.ErrInvalidChannel):
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, channels.ErrNoBroadcastRecipients):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	case err != nil:
+		slog.Error("create broadcast failed", "tenant", tenantID, "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to create broadcast")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, broadcast)
+}
+
+// handleGetBroadcast reports how many recipients were sent to, failed or are
+// still pending.
+func (h *BroadcastHandler) handleGetBroadcast(w http.ResponseWriter, r *http.Request) {
+	if h.Store == nil {
+		writeError(w, http.StatusServiceUnavailable, "broadcasts are not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	broadcastID := strings.TrimSpace(r.PathValue("broadcast_id"))
+	if tenantID == "" || broadcastID == "" {
+		writeError(w, http.StatusBadRequest, "tenant id and broadcast id are required")
+		return
+	}
+
+	broadcast, err := h.Store.Get(r.Context(), tenantID, broadcastID)
+	if errors.Is(err, channels.ErrBroadcastNotFound) {
+		writeError(w, http.StatusNotFound, "broadcast not found")
+		return
+	}
+	if err != nil {
+		slog.Error("get broadcast failed", "tenant", tenantID, "broadcast", broadcastID, "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to load broadcast")
+		return
+	}
+	writeJSON(w, http.StatusOK, broadcast)
+}
+
+// handleRetryBroadcast requeues the broadcast's failed recipients only.
+func (h *BroadcastHandler) handleRetryBroadcast(w http.ResponseWriter, r *http.Request) {
+	if h.Store == nil {
+		writeError(w, http.StatusServiceUnavailable, "broadcasts are not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	broadcastID := strings.TrimSpace(r.PathValue("broadcast_id"))
+	if tenantID == "" || broadcastID == "" {
+		writeError(w, http.StatusBadRequest, "tenant id and broadcast id are required")
+		return
+	}
+
+	retried, err := h.Store.RetryFailed(r.Context(), tenantID, broadcastID)
+	if errors.Is(err, channels.ErrBroadcastNotFound) {
+		writeError(w, http.StatusNotFound, "broadcast not found")
+		return
+	}
+	if err != nil {
+		slog.Error("retry broadcast failed", "tenant", tenantID, "broadcast", broadcastID, "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to retry broadcast")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"broadcast_id": broadcastID, "retried": retried})
+}
@@ -0,0 +1,79 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/channels"
+)
+
+func TestBroadcastRoutes(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	NewBroadcastHandler(channels.NewBroadcastStore(db)).Mount(mux)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, body := range []string{`{"content":" "}`, `{"content":"hi","extra":1}`, `{"content":"hi","channel":"web"}`, `not json`} {
+		if w := do(http.MethodPost, "/api/tenants/t1/broadcast", body); w.Code != http.StatusBadRequest {
+			t.Fatalf("body %s status = %d", body, w.Code)
+		}
+	}
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO broadcasts").WithArgs("t1", "Sale today", "telegram", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "scheduled_at", "created_at"}).AddRow("b1", now, now))
+	mock.ExpectExec("INSERT INTO broadcast_deliveries").WithArgs("b1", "t1", "telegram").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+	w := do(http.MethodPost, "/api/tenants/t1/broadcast", `{"content":"Sale today","channel":"telegram"}`)
+	if w.Code != http.StatusAccepted || !strings.Contains(w.Body.String(), `"pending":3`) {
+		t.Fatalf("create status = %d body=%s", w.Code, w.Body.String())
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO broadcasts").WillReturnRows(sqlmock.NewRows([]string{"id", "scheduled_at", "created_at"}).AddRow("b2", now, now))
+	mock.ExpectExec("INSERT INTO broadcast_deliveries").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	if w := do(http.MethodPost, "/api/tenants/t1/broadcast", `{"content":"hello"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("no recipients status = %d", w.Code)
+	}
+
+	statusRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "tenant_id", "content", "channel", "scheduled_at", "created_at", "sent", "failed", "pending"}).
+			AddRow("b1", "t1", "Sale today", "telegram", now, now, 1, 1, 1)
+	}
+	mock.ExpectQuery("SELECT b.id, b.tenant_id").WithArgs("b1", "t1").WillReturnRows(statusRows())
+	w = do(http.MethodGet, "/api/tenants/t1/broadcasts/b1", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"sent":1,"failed":1,"pending":1`) {
+		t.Fatalf("get status = %d body=%s", w.Code, w.Body.String())
+	}
+	mock.ExpectQuery("SELECT b.id, b.tenant_id").WithArgs("b1", "t2").WillReturnRows(sqlmock.NewRows(nil))
+	if w := do(http.MethodGet, "/api/tenants/t2/broadcasts/b1", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("other tenant status = %d", w.Code)
+	}
+
+	mock.ExpectQuery("SELECT b.id, b.tenant_id").WithArgs("b1", "t1").WillReturnRows(statusRows())
+	mock.ExpectExec("UPDATE broadcast_deliveries").WithArgs("b1").WillReturnResult(sqlmock.NewResult(0, 1))
+	w = do(http.MethodPost, "/api/tenants/t1/broadcasts/b1/retry", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"retried":1`) {
+		t.Fatalf("retry status = %d body=%s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
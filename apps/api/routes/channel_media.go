@@ -0,0 +1,78 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/agentsquads/api/media"
+)
+
+// whatsAppMedia is the image or document object of a WhatsApp message.
+type whatsAppMedia struct {
+	ID       string `json:"id"`
+	MimeType string `json:"mime_type"`
+	SHA256   string `json:"sha256"`
+	Caption  string `json:"caption"`
+	Filename string `json:"filename"`
+}
+
+func (m *whatsAppMedia) attachment(kind string) *media.Attachment {
+	return &media.Attachment{
+		Channel:        "whatsapp",
+		Kind:           kind,
+		ProviderFileID: m.ID,
+		FileName:       m.Filename,
+		ContentType:    m.MimeType,
+	}
+}
+
+// attachMedia ingests an inbound attachment and returns the message content
+// to route: the user's text followed by a line naming the file and its
+// media_id, which agents pass to the file_read tool. The ref also goes into
+// metadata so the router links the file to the stored message. A file that
+// cannot be ingested is still mentioned so the reply can say so.
+func (h *ChannelHandler) attachMedia(ctx context.Context, tenantID string, att media.Attachment, content string, metadata map[string]string) string {
+	ref, err := h.ingestAttachment(ctx, tenantID, att)
+	if err != nil {
+		slog.Warn("inbound attachment not ingested", "tenant", tenantID, "channel", att.Channel, "kind", att.Kind, "err", err)
+		name := att.FileName
+		if name == "" {
+			name = att.Kind
+		}
+		return joinMessageContent(content, fmt.Sprintf("[attached %s %q could not be downloaded]", att.Kind, name))
+	}
+	metadata[media.MetadataKey] = media.EncodeRefs([]media.Ref{ref})
+	return joinMessageContent(content, "[attached "+ref.Describe()+"]")
+}
+
+func (h *ChannelHandler) ingestAttachment(ctx context.Context, tenantID string, att media.Attachment) (media.Ref, error) {
+	if h.Media == nil {
+		return media.Ref{}, errors.New("media storage is not configured")
+	}
+	cred, err := h.Credentials.GetByTenantChannel(ctx, tenantID, att.Channel)
+	if err != nil {
+		return media.Ref{}, fmt.Errorf("load %s credentials: %w", att.Channel, err)
+	}
+
+	switch att.Channel {
+	case "telegram":
+		return h.Media.IngestTelegram(ctx, tenantID, strings.TrimSpace(cred.Config["bot_token"]), att)
+	case "whatsapp":
+		apiVersion := strings.TrimSpace(cred.Config["api_version"])
+		if apiVersion == "" {
+			apiVersion = "v20.0"
+		}
+		return h.Media.IngestWhatsApp(ctx, tenantID, strings.TrimSpace(cred.Config["access_token"]), apiVersion, att)
+	}
+	return media.Ref{}, fmt.Errorf("attachments are not supported on %s", att.Channel)
+}
+
+func joinMessageContent(text, note string) string {
+	if text == "" {
+		return note
+	}
+	return text + "\n\n" + note
+}
@@ -0,0 +1,57 @@
+package routes
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/channels"
+	"github.com/agentsquads/api/media"
+)
+
+func TestAttachMedia(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	svc := media.NewService(db, media.NewLocalBlobStore(t.TempDir()))
+	svc.HTTPClient = &http.Client{Transport: graphTransport(func(r *http.Request) (*http.Response, error) {
+		body := "col\n1\n"
+		if r.URL.Host == "graph.facebook.com" {
+			body = `{"url":"https://lookaside.fbsbx.com/whatsapp/M1","mime_type":"text/csv"}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+	})}
+	h := &ChannelHandler{Credentials: channels.NewCredentialsStore(db), Media: svc}
+
+	mock.ExpectQuery("FROM channel_credentials").WithArgs("t1", "whatsapp").
+		WillReturnRows(whatsAppCredRows(`{"access_token":"tok","phone_number_id":"123","api_version":"v21.0"}`))
+	mock.ExpectExec("INSERT INTO message_media").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	metadata := map[string]string{}
+	att := (&whatsAppMedia{ID: "M1", Filename: "data.csv"}).attachment("document")
+	content := h.attachMedia(context.Background(), "t1", *att, "what is in this file?", metadata)
+	refs := media.RefsFromMetadata(metadata)
+	if len(refs) != 1 || refs[0].FileName != "data.csv" || refs[0].ContentType != "text/csv" {
+		t.Fatalf("refs = %+v", refs)
+	}
+	if content != "what is in this file?\n\n[attached "+refs[0].Describe()+"]" {
+		t.Fatalf("content = %q", content)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+
+	// Without media storage the file is only named.
+	metadata = map[string]string{}
+	content = (&ChannelHandler{}).attachMedia(context.Background(), "t1", media.Attachment{Channel: "telegram", Kind: "photo"}, "", metadata)
+	if content != `[attached photo "photo" could not be downloaded]` || metadata[media.MetadataKey] != "" {
+		t.Fatalf("content=%q metadata=%v", content, metadata)
+	}
+}
@@ -0,0 +1,168 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/channels"
+)
+
+const (
+	testSendLimit  = 3
+	testSendWindow = time.Hour
+)
+
+type testSendRequest struct {
+	Channel string `json:"channel"`
+	Message string `json:"message"`
+}
+
+type testSendResponse struct {
+	Status    string `json:"status"`
+	Channel   string `json:"channel"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+func testSendRateKey(tenantID string) string {
+	return "channel_test_send:" + tenantID
+}
+
+// handleTestSend sends a message straight through one of the tenant's linked
+// channels, without the agent, so tenants can check that a channel they
+// connected delivers. The tenant_channels link holds the tenant's own bot or
+// phone number, so the message goes to whoever wrote to the tenant on that
+// channel most recently. Tenants get testSendLimit test sends per
+// testSendWindow.
+func (h *ChannelHandler) handleTestSend(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil || h.Links == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	send := h.testSend
+	if send == nil {
+		if h.Fanout == nil {
+			writeError(w, http.StatusServiceUnavailable, "channel delivery is not configured")
+			return
+		}
+		send = h.Fanout.Send
+	}
+
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	if scoped := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); scoped != "" && scoped != tenantID {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+
+	var req testSendRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	channel := strings.ToLower(strings.TrimSpace(req.Channel))
+	message := strings.TrimSpace(req.Message)
+	if channel == "" || message == "" {
+		writeError(w, http.StatusBadRequest, "channel and message are required")
+		return
+	}
+
+	links, err := h.Links.GetChannels(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load channels")
+		return
+	}
+	var link *channels.TenantChannel
+	for i := range links {
+		if links[i].Channel == channel {
+			link = &links[i]
+			break
+		}
+	}
+	if link == nil {
+		writeError(w, http.StatusNotFound, channel+" is not linked")
+		return
+	}
+
+	var recipient string
+	err = h.DB.QueryRowContext(r.Context(), `
+		SELECT m.metadata->>'channel_user_id'
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE c.tenant_id = $1
+		  AND m.channel = $2
+		  AND m.role = 'user'
+		  AND COALESCE(m.metadata->>'channel_user_id', '') <> ''
+		ORDER BY m.created_at DESC
+		LIMIT 1
+	`, tenantID, channel).Scan(&recipient)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusConflict, "no one has messaged the tenant on "+channel+" yet; send it a message first")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to find a recipient")
+		return
+	}
+
+	if retryAfter, ok := h.takeTestSend(r.Context(), tenantID); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int((retryAfter+time.Second-1)/time.Second)))
+		writeError(w, http.StatusTooManyRequests, "test send limit reached; try again later")
+		return
+	}
+
+	start := time.Now()
+	err = send(r.Context(), *link, channels.OutboundMessage{
+		TenantID: tenantID,
+		Content:  message,
+		Channel:  channel,
+		Metadata: map[string]string{"channel_user_id": recipient},
+	})
+	latency := time.Since(start).Milliseconds()
+	if err != nil {
+		slog.Warn("channel test send failed", "tenant_id", tenantID, "channel", channel, "latency_ms", latency, "err", err)
+		writeError(w, http.StatusBadGateway, "test send failed: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, testSendResponse{Status: "sent", Channel: channel, LatencyMS: latency})
+}
+
+// takeTestSend counts a test send against the tenant's limit. It reports
+// false, with the time until the window resets, once the limit is used up.
+// Without Redis there is no fanout to send through, and Redis failures let
+// the send through rather than blocking it.
+func (h *ChannelHandler) takeTestSend(ctx context.Context, tenantID string) (time.Duration, bool) {
+	if h.Redis == nil {
+		return 0, true
+	}
+	key := testSendRateKey(tenantID)
+	n, err := h.Redis.Incr(ctx, key).Result()
+	if err != nil {
+		slog.Warn("failed to count channel test send", "tenant_id", tenantID, "err", err)
+		return 0, true
+	}
+	ttl, err := h.Redis.TTL(ctx, key).Result()
+	if err == nil && ttl < 0 {
+		// The window starts with the first send; a key without an expiry
+		// would otherwise block test sends for good.
+		if err := h.Redis.Expire(ctx, key, testSendWindow).Err(); err != nil {
+			slog.Warn("failed to start channel test send window", "tenant_id", tenantID, "err", err)
+		}
+		ttl = testSendWindow
+	}
+	if n <= testSendLimit {
+		return 0, true
+	}
+	if err != nil || ttl <= 0 {
+		ttl = testSendWindow
+	}
+	return ttl, false
+}
@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/channels"
+)
+
+func TestChannelTestSend(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	h := NewChannelHandler(db, nil, channels.NewLinkStore(db), nil)
+	var (
+		sent    []channels.OutboundMessage
+		sendErr error
+	)
+	h.testSend = func(_ context.Context, link channels.TenantChannel, out channels.OutboundMessage) error {
+		if link.Channel != out.Channel {
+			t.Errorf("sent %s message through %s link", out.Channel, link.Channel)
+		}
+		sent = append(sent, out)
+		return sendErr
+	}
+	mux := http.NewServeMux()
+	h.Mount(mux)
+	post := func(body, scopedTenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/tenants/t1/channels/test-send", strings.NewReader(body))
+		if scopedTenant != "" {
+			req.Header.Set("X-Tenant-ID", scopedTenant)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	expectLinks := func() {
+		mock.ExpectQuery("SELECT id, tenant_id, channel").WithArgs("t1").WillReturnRows(
+			sqlmock.NewRows([]string{"id", "tenant_id", "channel", "channel_user_id", "linked_at", "muted", "notification_level"}).
+				AddRow("l1", "t1", "telegram", "bot-7", time.Now(), false, "milestones"))
+	}
+
+	expectLinks()
+	mock.ExpectQuery("FROM messages m").WithArgs("t1", "telegram").
+		WillReturnRows(sqlmock.NewRows([]string{"channel_user_id"}).AddRow("chat-42"))
+	w := post(`{"channel":"Telegram","message":" hello "}`, "t1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+	var resp testSendResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Status != "sent" || resp.Channel != "telegram" {
+		t.Fatalf("response = %+v, %v", resp, err)
+	}
+	if len(sent) != 1 || sent[0].Content != "hello" || sent[0].Metadata["channel_user_id"] != "chat-42" {
+		t.Fatalf("sent = %+v", sent)
+	}
+
+	// Delivery failures are reported with their cause.
+	sendErr = errors.New("telegram returned 401")
+	expectLinks()
+	mock.ExpectQuery("FROM messages m").WithArgs("t1", "telegram").
+		WillReturnRows(sqlmock.NewRows([]string{"channel_user_id"}).AddRow("chat-42"))
+	if w := post(`{"channel":"telegram","message":"hello"}`, ""); w.Code != http.StatusBadGateway || !strings.Contains(w.Body.String(), "telegram returned 401") {
+		t.Fatalf("failed send status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	// No one has written to the tenant yet, so there is no recipient.
+	expectLinks()
+	mock.ExpectQuery("FROM messages m").WithArgs("t1", "telegram").
+		WillReturnRows(sqlmock.NewRows([]string{"channel_user_id"}))
+	if w := post(`{"channel":"telegram","message":"hello"}`, ""); w.Code != http.StatusConflict {
+		t.Fatalf("no recipient status=%d", w.Code)
+	}
+
+	expectLinks()
+	if w := post(`{"channel":"whatsapp","message":"hello"}`, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("unlinked channel status=%d", w.Code)
+	}
+	if w := post(`{"channel":"telegram"}`, ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing message status=%d", w.Code)
+	}
+	if w := post(`{"channel":"telegram","message":"hello"}`, "t2"); w.Code != http.StatusNotFound {
+		t.Fatalf("other tenant status=%d", w.Code)
+	}
+	if len(sent) != 2 {
+		t.Fatalf("sends = %d, want 2", len(sent))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -16,6 +16,12 @@ import (
 	"time"
 
 	"github.com/agentsquads/api/channels"
+	"github.com/agentsquads/api/channels/adapters"
+	"github.com/agentsquads/api/media"
+	"github.com/agentsquads/api/middleware"
+	"github.com/agentsquads/api/openapi"
+	"github.com/agentsquads/api/policies"
+	"github.com/redis/go-redis/v9"
 )
 
 const telegramWebhookURL = "https://agentsquads.ai/api/channels/telegram/webhook"
@@ -26,6 +32,60 @@ type ChannelHandler struct {
 	Credentials *channels.CredentialsStore
 	DB          *sql.DB
 	HTTPClient  *http.Client
+	Policies    *policies.Store
+	Viber       *adapters.ViberAdapter
+	Media       *media.Service
+	// Blobs stores conversation export archives, whose download URLs are
+	// signed with ExportSecret.
+	Blobs        media.BlobStore
+	ExportSecret string
+	// Fanout sends test messages and Redis limits how often tenants can.
+	Fanout *channels.Fanout
+	Redis  *redis.Client
+	// Inbound queues webhook messages so providers are answered before the
+	// agent replies; see UseInboundQueue.
+	Inbound *channels.InboundQueue
+
+	// route replaces Router.Route in tests.
+	route func(context.Context, channels.InboundMessage) (channels.OutboundMessage, error)
+	// testSend replaces Fanout.Send in tests.
+	testSend func(context.Context, channels.TenantChannel, channels.OutboundMessage) error
+}
+
+// inboundRequest is a message for a tenant's agent. tenantId is accepted as
+// an alias of tenant_id.
+type inboundRequest struct {
+	TenantID    string            `json:"tenant_id"`
+	TenantIDAlt string            `json:"tenantId"`
+	Content     string            `json:"content"`
+	Channel     string            `json:"channel"`
+	Metadata    map[string]string `json:"metadata"`
+}
+
+type connectTelegramRequest struct {
+	TenantID string `json:"tenant_id"`
+	BotToken string `json:"bot_token"`
+}
+
+type connectWhatsAppRequest struct {
+	TenantID          string `json:"tenant_id"`
+	AccessToken       string `json:"access_token"`
+	PhoneNumberID     string `json:"phone_number_id"`
+	BusinessAccountID string `json:"business_account_id"`
+	APIVersion        string `json:"api_version"`
+}
+
+type updateChannelRequest struct {
+	NotificationLevel *string `json:"notification_level"`
+}
+
+// channelSummary is one channel's badge in the dashboard sidebar.
+type channelSummary struct {
+	Channel        string     `json:"channel"`
+	Connected      bool       `json:"connected"`
+	Enabled        bool       `json:"enabled"`
+	MessageCount7d int64      `json:"message_count_7d"`
+	LastMessageAt  *time.Time `json:"last_message_at"`
 }
 
 func NewChannelHandler(db *sql.DB, router *channels.Router, links *channels.LinkStore, creds *channels.CredentialsStore) *ChannelHandler {
@@ -35,60 +95,117 @@ func NewChannelHandler(db *sql.DB, router *channels.Router, links *channels.Link
 		Credentials: creds,
 		DB:          db,
 		HTTPClient:  &http.Client{Timeout: 15 * time.Second},
+		Viber:       adapters.NewViberAdapter(creds),
 	}
 }
 
 func (h *ChannelHandler) Mount(mux *http.ServeMux) {
-	mux.HandleFunc("POST /api/channels/inbound", h.handleInbound)
-	mux.HandleFunc("POST /api/channels/telegram", h.handleConnectTelegram)
-	mux.HandleFunc("POST /api/channels/whatsapp", h.handleConnectWhatsApp)
-	mux.HandleFunc("GET /api/channels", h.handleListChannels)
-	mux.HandleFunc("DELETE /api/channels/{id}", h.handleDeleteChannel)
+	tags := []string{"Channels"}
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/api/channels/inbound", Summary: "Send a message to the tenant's agent and return its reply",
+		Tags: tags, Request: inboundRequest{}, Response: channels.OutboundMessage{}}, h.handleInbound)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/api/channels/inbound/batch", Summary: "Send up to 100 messages to tenants' agents",
+		Tags: tags, Request: struct {
+			Messages []inboundRequest `json:"messages"`
+		}{}, Response: inboundBatchResponse{}}, h.handleInboundBatch)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/api/channels/telegram", Summary: "Connect a Telegram bot",
+		Tags: tags, Request: connectTelegramRequest{}, Status: http.StatusCreated}, h.handleConnectTelegram)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/api/channels/whatsapp", Summary: "Connect a WhatsApp Business number",
+		Tags: tags, Request: connectWhatsAppRequest{}, Status: http.StatusCreated}, h.handleConnectWhatsApp)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodGet, Path: "/api/channels", Summary: "List the tenant's linked channels",
+		Tags: tags, Query: []string{"tenant_id"}, Headers: []string{"X-Tenant-ID"}}, h.handleListChannels)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodGet, Path: "/api/tenants/{id}/channels/summary", Summary: "Summarize channel connections and recent activity",
+		Tags: tags, Response: struct {
+			Channels []channelSummary `json:"channels"`
+		}{}}, h.handleChannelSummary)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/api/tenants/{id}/channels/test-send", Summary: "Send a test message through a linked channel",
+		Tags: tags, Request: testSendRequest{}, Response: testSendResponse{}}, h.handleTestSend)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodDelete, Path: "/api/channels/{id}", Summary: "Disconnect a channel",
+		Tags: tags, Query: []string{"tenant_id"}, Headers: []string{"X-Tenant-ID"}, Status: http.StatusNoContent}, h.handleDeleteChannel)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPatch, Path: "/api/tenants/{id}/channels/{channel}", Summary: "Change a linked channel's notification level",
+		Tags: tags, Request: updateChannelRequest{}, Response: channels.TenantChannel{}}, h.handleUpdateChannel)
 	mux.HandleFunc("POST /api/channels/telegram/webhook", h.handleTelegramWebhook)
 	mux.HandleFunc("POST /api/channels/whatsapp/webhook", h.handleWhatsAppWebhook)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/api/channels/viber/connect", Summary: "Connect a Viber bot",
+		Tags: tags, Request: connectViberRequest{}, Status: http.StatusCreated}, h.handleConnectViber)
+	mux.HandleFunc("POST /api/channels/viber/webhook", h.handleViberWebhook)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/api/conversations/{id}/retitle", Summary: "Regenerate a conversation's title",
+		Tags: tags, Query: []string{"tenant_id"}, Headers: []string{"X-Tenant-ID"}}, h.handleRetitle)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodGet, Path: "/api/conversations/{id}/export", Summary: "Export a conversation as JSON or a markdown transcript",
+		Tags: tags, Query: []string{"tenant_id", "format"}, Headers: []string{"X-Tenant-ID"}}, h.handleExportConversation)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/api/tenants/{id}/conversations/export", Summary: "Export conversations in a date range as a zip archive",
+		Tags: tags, Request: conversationExportRequest{}, Response: conversationExportResponse{}, Status: http.StatusAccepted}, h.handleStartConversationExport)
+	mux.HandleFunc("GET /api/conversation-exports/{id}/download", h.handleDownloadConversationExport)
+
+	mux.HandleFunc("GET /api/admin/webhook-failures", h.handleListWebhookFailures)
+	mux.HandleFunc("POST /api/admin/webhook-failures/{id}/replay", h.handleReplayWebhookFailure)
 }
 
 func (h *ChannelHandler) handleInbound(w http.ResponseWriter, r *http.Request) {
-	if h.Router == nil {
+	if h.Router == nil && h.route == nil {
 		writeError(w, http.StatusServiceUnavailable, "channel router is not configured")
 		return
 	}
 
-	var req struct {
-		TenantID    string            `json:"tenant_id"`
-		TenantIDAlt string            `json:"tenantId"`
-		Content     string            `json:"content"`
-		Channel     string            `json:"channel"`
-		Metadata    map[string]string `json:"metadata"`
-	}
+	var req inboundRequest
 	if err := decodeJSONStrict(r, &req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid JSON body")
 		return
 	}
 
+	out, err := h.routeInbound(r.Context(), req)
+	if err != nil {
+		writeError(w, inboundErrorStatus(err), err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
+
+var errImpersonationScope = errors.New("impersonation token is scoped to another tenant")
+
+// routeInbound resolves the message's tenant, which an impersonation token
+// fixes, and routes it to the tenant's agent.
+func (h *ChannelHandler) routeInbound(ctx context.Context, req inboundRequest) (channels.OutboundMessage, error) {
 	tenantID := strings.TrimSpace(req.TenantID)
 	if tenantID == "" {
 		tenantID = strings.TrimSpace(req.TenantIDAlt)
 	}
+	if impersonation, ok := middleware.ImpersonationFromContext(ctx); ok {
+		if tenantID == "" {
+			tenantID = impersonation.TenantID
+		}
+		if tenantID != impersonation.TenantID {
+			return channels.OutboundMessage{}, errImpersonationScope
+		}
+	}
 
-	out, err := h.Router.Route(r.Context(), channels.InboundMessage{
+	route := h.route
+	if route == nil {
+		route = h.Router.Route
+	}
+	return route(ctx, channels.InboundMessage{
 		TenantID: tenantID,
 		Content:  req.Content,
 		Channel:  req.Channel,
-		Metadata: req.Metadata,
+		Metadata: withImpersonator(ctx, req.Metadata),
 	})
-	if err != nil {
-		status := http.StatusInternalServerError
-		if isInboundConflictError(err) {
-			status = http.StatusConflict
-		} else if isInboundValidationError(err) {
-			status = http.StatusBadRequest
-		}
-		writeError(w, status, err.Error())
-		return
-	}
+}
 
-	writeJSON(w, http.StatusOK, out)
+// inboundErrorStatus is the status an inbound routing error is answered
+// with.
+func inboundErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, channels.ErrChannelDisabled), errors.Is(err, errImpersonationScope):
+		return http.StatusForbidden
+	case isInboundConflictError(err):
+		return http.StatusConflict
+	case errors.Is(err, channels.ErrMessageModerated):
+		return http.StatusUnprocessableEntity
+	case isInboundValidationError(err):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
 }
 
 func (h *ChannelHandler) handleConnectTelegram(w http.ResponseWriter, r *http.Request) {
@@ -97,10 +214,7 @@ func (h *ChannelHandler) handleConnectTelegram(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	var req struct {
-		TenantID string `json:"tenant_id"`
-		BotToken string `json:"bot_token"`
-	}
+	var req connectTelegramRequest
 	if err := decodeJSONStrict(r, &req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid JSON body")
 		return
@@ -112,6 +226,9 @@ func (h *ChannelHandler) handleConnectTelegram(w http.ResponseWriter, r *http.Re
 		writeError(w, http.StatusBadRequest, "tenant_id and bot_token are required")
 		return
 	}
+	if !requireFeature(w, r, h.Policies, tenantID, policies.FeatureTelegram, "channels.connect") {
+		return
+	}
 
 	botInfo, err := h.verifyTelegramBot(r.Context(), botToken)
 	if err != nil {
@@ -157,13 +274,7 @@ func (h *ChannelHandler) handleConnectWhatsApp(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	var req struct {
-		TenantID          string `json:"tenant_id"`
-		AccessToken       string `json:"access_token"`
-		PhoneNumberID     string `json:"phone_number_id"`
-		BusinessAccountID string `json:"business_account_id"`
-		APIVersion        string `json:"api_version"`
-	}
+	var req connectWhatsAppRequest
 	if err := decodeJSONStrict(r, &req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid JSON body")
 		return
@@ -182,6 +293,9 @@ func (h *ChannelHandler) handleConnectWhatsApp(w http.ResponseWriter, r *http.Re
 		writeError(w, http.StatusBadRequest, "tenant_id, access_token and phone_number_id are required")
 		return
 	}
+	if !requireFeature(w, r, h.Policies, tenantID, policies.FeatureWhatsApp, "channels.connect") {
+		return
+	}
 
 	if err := h.verifyWhatsAppCredentials(r.Context(), accessToken, apiVersion, phoneNumberID); err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
@@ -224,19 +338,22 @@ func (h *ChannelHandler) handleListChannels(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	links := h.Links
+	if links == nil {
+		links = channels.NewLinkStore(h.DB)
+	}
+	stats, err := links.GetChannelStats(tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load channel stats")
+		return
+	}
+
 	rows, err := h.DB.QueryContext(r.Context(), `
 		SELECT
 			tc.id,
 			tc.channel,
 			tc.linked_at,
 			tc.muted,
-			COALESCE((
-				SELECT COUNT(*)
-				FROM messages m
-				JOIN conversations c ON c.id = m.conversation_id
-				WHERE c.tenant_id = tc.tenant_id
-				  AND m.channel = tc.channel
-			), 0) AS message_count,
 			COALESCE(cc.updated_at, tc.linked_at) AS updated_at,
 			COALESCE(NULLIF(cc.config::text, ''), '{}') AS config_json
 		FROM tenant_channels tc
@@ -255,16 +372,15 @@ func (h *ChannelHandler) handleListChannels(w http.ResponseWriter, r *http.Reque
 	result := make([]map[string]any, 0)
 	for rows.Next() {
 		var (
-			id           string
-			channel      string
-			linkedAt     time.Time
-			muted        bool
-			messageCount int64
-			updatedAt    time.Time
-			configJSON   string
+			id         string
+			channel    string
+			linkedAt   time.Time
+			muted      bool
+			updatedAt  time.Time
+			configJSON string
 		)
 
-		if err := rows.Scan(&id, &channel, &linkedAt, &muted, &messageCount, &updatedAt, &configJSON); err != nil {
+		if err := rows.Scan(&id, &channel, &linkedAt, &muted, &updatedAt, &configJSON); err != nil {
 			writeError(w, http.StatusInternalServerError, "failed to read channels")
 			return
 		}
@@ -275,15 +391,18 @@ func (h *ChannelHandler) handleListChannels(w http.ResponseWriter, r *http.Reque
 		}
 		maskSecrets(masked)
 
+		channelStats := stats[channel]
 		result = append(result, map[string]any{
-			"id":            id,
-			"channel":       channel,
-			"linked_at":     linkedAt,
-			"updated_at":    updatedAt,
-			"status":        map[bool]string{true: "disabled", false: "connected"}[muted],
-			"enabled":       !muted,
-			"message_count": messageCount,
-			"credentials":   masked,
+			"id":                id,
+			"channel":           channel,
+			"linked_at":         linkedAt,
+			"updated_at":        updatedAt,
+			"status":            map[bool]string{true: "disabled", false: "connected"}[muted],
+			"enabled":           !muted,
+			"message_count":     channelStats.MessageCount,
+			"last_message_at":   channelStats.LastMessageAt,
+			"failed_deliveries": channelStats.FailedDeliveries,
+			"credentials":       masked,
 		})
 	}
 
@@ -295,6 +414,121 @@ func (h *ChannelHandler) handleListChannels(w http.ResponseWriter, r *http.Reque
 	writeJSON(w, http.StatusOK, map[string]any{"channels": result})
 }
 
+// handleChannelSummary returns connection badges for the dashboard sidebar. It
+// avoids decrypting credentials and external calls; message counts come from
+// the router's Redis counters with a database fallback.
+func (h *ChannelHandler) handleChannelSummary(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT
+			tc.channel,
+			tc.muted,
+			(tc.channel = 'web' OR cc.id IS NOT NULL) AS connected
+		FROM tenant_channels tc
+		LEFT JOIN channel_credentials cc
+		  ON cc.tenant_id = tc.tenant_id
+		 AND cc.channel = tc.channel
+		WHERE tc.tenant_id = $1
+		ORDER BY tc.linked_at ASC
+	`, tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list channels")
+		return
+	}
+	defer rows.Close()
+
+	result := make([]*channelSummary, 0)
+	for rows.Next() {
+		var (
+			item  channelSummary
+			muted bool
+		)
+		if err := rows.Scan(&item.Channel, &muted, &item.Connected); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to read channels")
+			return
+		}
+		item.Enabled = !muted
+		result = append(result, &item)
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to read channels")
+		return
+	}
+
+	now := time.Now()
+	missing := make([]*channelSummary, 0)
+	for _, item := range result {
+		activity, ok, err := h.Router.ChannelActivity(r.Context(), tenantID, item.Channel, now)
+		if err != nil || !ok {
+			missing = append(missing, item)
+			continue
+		}
+		item.MessageCount7d = activity.MessageCount7d
+		if !activity.LastMessageAt.IsZero() {
+			last := activity.LastMessageAt
+			item.LastMessageAt = &last
+		}
+	}
+
+	if len(missing) > 0 {
+		activityRows, err := h.DB.QueryContext(r.Context(), `
+			SELECT
+				m.channel,
+				COUNT(*) FILTER (WHERE m.created_at >= NOW() - INTERVAL '7 days') AS message_count_7d,
+				MAX(m.created_at) AS last_message_at
+			FROM messages m
+			JOIN conversations c ON c.id = m.conversation_id
+			WHERE c.tenant_id = $1
+			GROUP BY m.channel
+		`, tenantID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to load channel activity")
+			return
+		}
+		defer activityRows.Close()
+
+		byChannel := make(map[string]*channelSummary, len(missing))
+		for _, item := range missing {
+			byChannel[item.Channel] = item
+		}
+		for activityRows.Next() {
+			var (
+				channel string
+				count   int64
+				last    sql.NullTime
+			)
+			if err := activityRows.Scan(&channel, &count, &last); err != nil {
+				writeError(w, http.StatusInternalServerError, "failed to read channel activity")
+				return
+			}
+			item, ok := byChannel[channel]
+			if !ok {
+				continue
+			}
+			item.MessageCount7d = count
+			if last.Valid {
+				item.LastMessageAt = &last.Time
+			}
+		}
+		if err := activityRows.Err(); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to read channel activity")
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"channels": result})
+}
+
 func (h *ChannelHandler) handleDeleteChannel(w http.ResponseWriter, r *http.Request) {
 	if h.DB == nil {
 		writeError(w, http.StatusServiceUnavailable, "database is not configured")
@@ -331,6 +565,50 @@ func (h *ChannelHandler) handleDeleteChannel(w http.ResponseWriter, r *http.Requ
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// handleUpdateChannel changes a linked channel's settings. Only the
+// notification level is editable; it decides which swarm run updates the
+// channel receives.
+func (h *ChannelHandler) handleUpdateChannel(w http.ResponseWriter, r *http.Request) {
+	if h.Links == nil {
+		writeError(w, http.StatusServiceUnavailable, "channel links are not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	channel := strings.TrimSpace(r.PathValue("channel"))
+	if tenantID == "" || channel == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id or channel")
+		return
+	}
+	if scoped := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); scoped != "" && scoped != tenantID {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+
+	var req updateChannelRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.NotificationLevel == nil {
+		writeError(w, http.StatusBadRequest, "notification_level is required")
+		return
+	}
+
+	link, err := h.Links.SetNotificationLevel(tenantID, channel, *req.NotificationLevel)
+	switch {
+	case errors.Is(err, channels.ErrInvalidNotificationLevel), errors.Is(err, channels.ErrInvalidChannel):
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, channels.ErrChannelNotLinked):
+		writeError(w, http.StatusNotFound, "channel link not found")
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "failed to update channel")
+		return
+	}
+	writeJSON(w, http.StatusOK, link)
+}
+
 func (h *ChannelHandler) handleTelegramWebhook(w http.ResponseWriter, r *http.Request) {
 	if h.Router == nil || h.Credentials == nil {
 		writeError(w, http.StatusServiceUnavailable, "channel webhook is not configured")
@@ -348,27 +626,93 @@ func (h *ChannelHandler) handleTelegramWebhook(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid webhook body")
+		return
+	}
+
+	status, result, err := h.processTelegramUpdate(r.Context(), tenantID, body, h.queueOrRoute(body, r.Header))
+	if err != nil {
+		h.recordWebhookFailure(r.Context(), "telegram", tenantID, body, r.Header, err)
+		writeError(w, status, err.Error())
+		return
+	}
+
+	writeJSON(w, status, result)
+}
+
+// processTelegramUpdate routes an authenticated Telegram update through
+// routeMsg. It is shared by the webhook and webhook-failure replay so both
+// exercise the same code.
+func (h *ChannelHandler) processTelegramUpdate(ctx context.Context, tenantID string, body []byte, routeMsg inboundRoute) (int, map[string]any, error) {
 	var payload struct {
 		UpdateID int64 `json:"update_id"`
 		Message  struct {
-			Text string `json:"text"`
-			Chat struct {
-				ID int64 `json:"id"`
+			Text            string           `json:"text"`
+			Caption         string           `json:"caption"`
+			Entities        []telegramEntity `json:"entities"`
+			CaptionEntities []telegramEntity `json:"caption_entities"`
+			ReplyTo         json.RawMessage  `json:"reply_to_message"`
+			Chat            struct {
+				ID   int64  `json:"id"`
+				Type string `json:"type"`
 			} `json:"chat"`
 			From struct {
-				ID int64 `json:"id"`
+				ID           int64  `json:"id"`
+				LanguageCode string `json:"language_code"`
 			} `json:"from"`
+			Photo []struct {
+				FileID string `json:"file_id"`
+			} `json:"photo"`
+			Document *struct {
+				FileID   string `json:"file_id"`
+				FileName string `json:"file_name"`
+				MimeType string `json:"mime_type"`
+			} `json:"document"`
 		} `json:"message"`
 	}
-	if err := decodeJSONStrict(r, &payload); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid telegram payload")
-		return
+	if err := decodeJSONStrictRaw(body, &payload); err != nil {
+		return http.StatusBadRequest, nil, fmt.Errorf("invalid telegram payload: %w", err)
 	}
 
-	content := strings.TrimSpace(payload.Message.Text)
-	if content == "" {
-		writeJSON(w, http.StatusOK, map[string]any{"status": "ignored"})
-		return
+	var attachment *media.Attachment
+	switch msg := payload.Message; {
+	case msg.Document != nil && msg.Document.FileID != "":
+		attachment = &media.Attachment{Kind: "document", ProviderFileID: msg.Document.FileID, FileName: msg.Document.FileName, ContentType: msg.Document.MimeType}
+	case len(msg.Photo) > 0:
+		// Sizes are listed smallest first.
+		attachment = &media.Attachment{Kind: "photo", ProviderFileID: msg.Photo[len(msg.Photo)-1].FileID, FileName: "photo.jpg", ContentType: "image/jpeg"}
+	}
+
+	content, entities := payload.Message.Text, payload.Message.Entities
+	if strings.TrimSpace(content) == "" {
+		content, entities = payload.Message.Caption, payload.Message.CaptionEntities
+	}
+	if strings.TrimSpace(content) == "" && attachment == nil {
+		return http.StatusOK, map[string]any{"status": "ignored"}, nil
+	}
+
+	// In groups the bot only acts on messages addressed to it; the rest of
+	// the chat is kept as context so a later task can refer to it.
+	addressed := true
+	if isTelegramGroup(payload.Message.Chat.Type) {
+		always, err := h.telegramGroupAlwaysResponds(ctx, tenantID)
+		if err != nil {
+			return http.StatusInternalServerError, nil, err
+		}
+		if !always {
+			bot, err := h.telegramBotIdentity(ctx, tenantID)
+			if err != nil {
+				return http.StatusInternalServerError, nil, err
+			}
+			content, addressed = telegramGroupTask(content, entities, telegramRepliesToBot(payload.Message.ReplyTo, bot), bot)
+		}
+	}
+	content = strings.TrimSpace(content)
+	if content == "" && attachment == nil {
+		// A bare mention or /agent with nothing to do.
+		return http.StatusOK, map[string]any{"status": "ignored"}, nil
 	}
 
 	metadata := map[string]string{
@@ -376,22 +720,47 @@ func (h *ChannelHandler) handleTelegramWebhook(w http.ResponseWriter, r *http.Re
 		"user_id":            strconv.FormatInt(payload.Message.From.ID, 10),
 		"telegram_update_id": strconv.FormatInt(payload.UpdateID, 10),
 	}
+	// The sender's language picks the locale of system messages for tenants
+	// that have not set one.
+	if lang := strings.TrimSpace(payload.Message.From.LanguageCode); lang != "" {
+		metadata["language_code"] = lang
+	}
+	if attachment != nil {
+		attachment.Channel = "telegram"
+		content = h.attachMedia(ctx, tenantID, *attachment, content, metadata)
+	}
 
-	if _, err := h.Router.Route(r.Context(), channels.InboundMessage{
+	msg := channels.InboundMessage{
 		TenantID: tenantID,
 		Content:  content,
 		Channel:  "telegram",
 		Metadata: metadata,
-	}); err != nil {
+	}
+	result := "ok"
+	var err error
+	if addressed {
+		err = routeMsg(ctx, msg)
+	} else {
+		result = "stored"
+		_, err = h.Router.Record(ctx, msg)
+	}
+	if err != nil {
+		// Acknowledge updates for a disabled channel so Telegram stops
+		// redelivering them; the router already recorded the denial.
+		if errors.Is(err, channels.ErrChannelDisabled) {
+			return http.StatusOK, map[string]any{"status": "disabled"}, nil
+		}
+		if errors.Is(err, channels.ErrMessageModerated) {
+			return http.StatusOK, map[string]any{"status": "blocked"}, nil
+		}
 		status := http.StatusInternalServerError
 		if isInboundValidationError(err) {
 			status = http.StatusBadRequest
 		}
-		writeError(w, status, err.Error())
-		return
+		return status, nil, err
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	return http.StatusOK, map[string]any{"status": result}, nil
 }
 
 func (h *ChannelHandler) handleWhatsAppWebhook(w http.ResponseWriter, r *http.Request) {
@@ -406,6 +775,24 @@ func (h *ChannelHandler) handleWhatsAppWebhook(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	processed, tenantID, err := h.processWhatsAppPayload(r.Context(), body, h.queueOrRoute(body, r.Header))
+	if err != nil {
+		h.recordWebhookFailure(r.Context(), "whatsapp", tenantID, body, r.Header, err)
+		if errors.Is(err, errInvalidWhatsAppPayload) {
+			writeError(w, http.StatusBadRequest, "invalid whatsapp payload")
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "processed": processed})
+}
+
+var errInvalidWhatsAppPayload = errors.New("invalid whatsapp payload")
+
+// processWhatsAppPayload routes every text message in a WhatsApp webhook body
+// through routeMsg. It returns the number of routed messages, the first
+// tenant it resolved, and the joined routing errors, if any.
+func (h *ChannelHandler) processWhatsAppPayload(ctx context.Context, body []byte, routeMsg inboundRoute) (int, string, error) {
 	var payload struct {
 		Entry []struct {
 			Changes []struct {
@@ -416,20 +803,24 @@ func (h *ChannelHandler) handleWhatsAppWebhook(w http.ResponseWriter, r *http.Re
 					Messages []struct {
 						From string `json:"from"`
 						ID   string `json:"id"`
+						Type string `json:"type"`
 						Text struct {
 							Body string `json:"body"`
 						} `json:"text"`
+						Image    *whatsAppMedia `json:"image"`
+						Document *whatsAppMedia `json:"document"`
 					} `json:"messages"`
 				} `json:"value"`
 			} `json:"changes"`
 		} `json:"entry"`
 	}
 	if err := decodeJSONStrictRaw(body, &payload); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid whatsapp payload")
-		return
+		return 0, "", fmt.Errorf("%w: %v", errInvalidWhatsAppPayload, err)
 	}
 
 	processed := 0
+	firstTenant := ""
+	var routeErrs []error
 	for _, entry := range payload.Entry {
 		for _, change := range entry.Changes {
 			phoneNumberID := strings.TrimSpace(change.Value.Metadata.PhoneNumberID)
@@ -437,14 +828,30 @@ func (h *ChannelHandler) handleWhatsAppWebhook(w http.ResponseWriter, r *http.Re
 				continue
 			}
 
-			tenantID, err := h.Credentials.FindTenantByWhatsAppPhoneNumberID(r.Context(), phoneNumberID)
+			tenantID, err := h.Credentials.FindTenantByWhatsAppPhoneNumberID(ctx, phoneNumberID)
 			if err != nil {
 				continue
 			}
+			if firstTenant == "" {
+				firstTenant = tenantID
+			}
 
 			for _, msg := range change.Value.Messages {
+				var (
+					attachment *media.Attachment
+					caption    string
+				)
+				switch {
+				case msg.Document != nil && msg.Document.ID != "":
+					attachment, caption = msg.Document.attachment("document"), msg.Document.Caption
+				case msg.Image != nil && msg.Image.ID != "":
+					attachment, caption = msg.Image.attachment("photo"), msg.Image.Caption
+				}
 				content := strings.TrimSpace(msg.Text.Body)
 				if content == "" {
+					content = strings.TrimSpace(caption)
+				}
+				if content == "" && attachment == nil {
 					continue
 				}
 
@@ -453,20 +860,28 @@ func (h *ChannelHandler) handleWhatsAppWebhook(w http.ResponseWriter, r *http.Re
 					"user_id":         msg.From,
 					"message_id":      msg.ID,
 				}
+				if attachment != nil {
+					content = h.attachMedia(ctx, tenantID, *attachment, content, metadata)
+				}
 
-				if _, err := h.Router.Route(r.Context(), channels.InboundMessage{
+				if err := routeMsg(ctx, channels.InboundMessage{
 					TenantID: tenantID,
 					Content:  content,
 					Channel:  "whatsapp",
 					Metadata: metadata,
-				}); err == nil {
-					processed++
+				}); err != nil {
+					if errors.Is(err, channels.ErrChannelDisabled) || errors.Is(err, channels.ErrMessageModerated) {
+						continue
+					}
+					routeErrs = append(routeErrs, fmt.Errorf("message %s: %w", msg.ID, err))
+					continue
 				}
+				processed++
 			}
 		}
 	}
 
-	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "processed": processed})
+	return processed, firstTenant, errors.Join(routeErrs...)
 }
 
 func (h *ChannelHandler) verifyTelegramBot(ctx context.Context, token string) (telegramBotInfo, error) {
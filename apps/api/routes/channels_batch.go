@@ -0,0 +1,91 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+const (
+	// maxInboundBatch caps the messages in one batch request.
+	maxInboundBatch = 100
+	// inboundBatchConcurrency is how many messages of a batch are routed at
+	// once.
+	inboundBatchConcurrency = 10
+)
+
+type inboundBatchResult struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"` // ok or error
+	Error  string `json:"error,omitempty"`
+}
+
+type inboundBatchResponse struct {
+	Processed int                  `json:"processed"`
+	Failed    int                  `json:"failed"`
+	Results   []inboundBatchResult `json:"results"`
+}
+
+// handleInboundBatch routes {"messages": [...]} one by one, as
+// POST /api/channels/inbound would, for integrations that import messages in
+// bursts. Each item is decoded and routed on its own, so a malformed or
+// rejected item is reported in its result without failing the others.
+func (h *ChannelHandler) handleInboundBatch(w http.ResponseWriter, r *http.Request) {
+	if h.Router == nil && h.route == nil {
+		writeError(w, http.StatusServiceUnavailable, "channel router is not configured")
+		return
+	}
+
+	var req struct {
+		Messages []json.RawMessage `json:"messages"`
+	}
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if len(req.Messages) == 0 {
+		writeError(w, http.StatusBadRequest, "messages is required")
+		return
+	}
+	if len(req.Messages) > maxInboundBatch {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d messages are allowed per batch", maxInboundBatch))
+		return
+	}
+
+	results := make([]inboundBatchResult, len(req.Messages))
+	slots := make(chan struct{}, inboundBatchConcurrency)
+	var wg sync.WaitGroup
+	for i, raw := range req.Messages {
+		results[i] = inboundBatchResult{Index: i, Status: "ok"}
+		var item inboundRequest
+		if err := decodeJSONStrictRaw(raw, &item); err != nil {
+			results[i].Status, results[i].Error = "error", "invalid message: "+err.Error()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			if _, err := h.routeInbound(r.Context(), item); err != nil {
+				if inboundErrorStatus(err) == http.StatusInternalServerError {
+					slog.Error("batch inbound message failed", "index", i, "tenant", item.TenantID, "err", err)
+				}
+				results[i].Status, results[i].Error = "error", err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	resp := inboundBatchResponse{Results: results}
+	for _, result := range results {
+		if result.Status == "ok" {
+			resp.Processed++
+		} else {
+			resp.Failed++
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
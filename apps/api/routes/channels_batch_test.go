@@ -0,0 +1,104 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agentsquads/api/channels"
+)
+
+func TestInboundBatch(t *testing.T) {
+	t.Parallel()
+	var (
+		mu       sync.Mutex
+		routed   []string
+		inFlight atomic.Int32
+		peak     atomic.Int32
+	)
+	h := NewChannelHandler(nil, nil, nil, nil)
+	h.route = func(_ context.Context, msg channels.InboundMessage) (channels.OutboundMessage, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if msg.TenantID == "" {
+			return channels.OutboundMessage{}, errors.New("tenant_id is required")
+		}
+		mu.Lock()
+		routed = append(routed, msg.Content)
+		mu.Unlock()
+		return channels.OutboundMessage{TenantID: msg.TenantID, Content: "reply"}, nil
+	}
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	items := []string{
+		`{"tenant_id":"t1","content":"a","channel":"web"}`,
+		`{"tenant_id":"t1","content":"b","bogus":true}`,
+		`{"content":"c","channel":"web"}`,
+		`"not an object"`,
+	}
+	for i := 0; i < 20; i++ {
+		items = append(items, `{"tenantId":"t2","content":"bulk","channel":"web"}`)
+	}
+	body := `{"messages":[` + strings.Join(items, ",") + `]}`
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/channels/inbound/batch", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var resp inboundBatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Processed != 21 || resp.Failed != 3 || len(resp.Results) != len(items) {
+		t.Fatalf("processed=%d failed=%d results=%d", resp.Processed, resp.Failed, len(resp.Results))
+	}
+	for i, want := range []string{"ok", "error", "error", "error"} {
+		if got := resp.Results[i]; got.Index != i || got.Status != want {
+			t.Fatalf("result %d = %+v, want %s", i, got, want)
+		}
+	}
+	if !strings.Contains(resp.Results[2].Error, "tenant_id is required") {
+		t.Fatalf("validation error = %q", resp.Results[2].Error)
+	}
+	if len(routed) != 21 {
+		t.Fatalf("routed %d messages", len(routed))
+	}
+	if p := peak.Load(); p > inboundBatchConcurrency {
+		t.Fatalf("peak concurrency = %d, want at most %d", p, inboundBatchConcurrency)
+	}
+}
+
+func TestInboundBatchRejectsBadBatches(t *testing.T) {
+	t.Parallel()
+	h := NewChannelHandler(nil, nil, nil, nil)
+	h.route = func(context.Context, channels.InboundMessage) (channels.OutboundMessage, error) {
+		t.Error("no message should be routed")
+		return channels.OutboundMessage{}, nil
+	}
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	tooMany := `{"messages":[` + strings.TrimSuffix(strings.Repeat(`{"content":"x"},`, maxInboundBatch+1), ",") + `]}`
+	for _, body := range []string{`{"messages":[]}`, `{"messages":{}}`, tooMany} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/channels/inbound/batch", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d for %.40s", w.Code, body)
+		}
+	}
+}
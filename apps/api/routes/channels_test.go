@@ -5,6 +5,10 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/channels"
 )
 
 func TestChannelHandlerMountAndBasicErrors(t *testing.T) {
@@ -23,6 +27,13 @@ func TestChannelHandlerMountAndBasicErrors(t *testing.T) {
 		{name: "inbound missing router", method: http.MethodPost, path: "/api/channels/inbound", body: `{}`, status: http.StatusServiceUnavailable},
 		{name: "connect telegram missing stores", method: http.MethodPost, path: "/api/channels/telegram", body: `{}`, status: http.StatusServiceUnavailable},
 		{name: "list channels missing db", method: http.MethodGet, path: "/api/channels", status: http.StatusServiceUnavailable},
+		{name: "channel summary missing db", method: http.MethodGet, path: "/api/tenants/t1/channels/summary", status: http.StatusServiceUnavailable},
+		{name: "webhook failures missing db", method: http.MethodGet, path: "/api/admin/webhook-failures", status: http.StatusServiceUnavailable},
+		{name: "webhook replay missing db", method: http.MethodPost, path: "/api/admin/webhook-failures/f1/replay", status: http.StatusServiceUnavailable},
+		{name: "connect viber missing stores", method: http.MethodPost, path: "/api/channels/viber/connect", body: `{}`, status: http.StatusServiceUnavailable},
+		{name: "viber webhook missing router", method: http.MethodPost, path: "/api/channels/viber/webhook", body: `{}`, status: http.StatusServiceUnavailable},
+		{name: "update channel missing links", method: http.MethodPatch, path: "/api/tenants/t1/channels/telegram", body: `{"notification_level":"all"}`, status: http.StatusServiceUnavailable},
+		{name: "retitle missing router", method: http.MethodPost, path: "/api/conversations/c1/retitle?tenant_id=t1", status: http.StatusServiceUnavailable},
 	}
 	for _, tt := range tests {
 		tt := tt
@@ -37,3 +48,61 @@ func TestChannelHandlerMountAndBasicErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateChannelNotificationLevel(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	NewChannelHandler(db, nil, channels.NewLinkStore(db), nil).Mount(mux)
+	patch := func(path, body, scopedTenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(body))
+		if scopedTenant != "" {
+			req.Header.Set("X-Tenant-ID", scopedTenant)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	mock.ExpectQuery("UPDATE tenant_channels").WithArgs("t1", "telegram", "final_only").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "channel", "channel_user_id", "linked_at", "muted", "notification_level"}).
+			AddRow("id1", "t1", "telegram", "42", time.Now(), false, "final_only"))
+	w := patch("/api/tenants/t1/channels/telegram", `{"notification_level":"final_only"}`, "t1")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"notification_level":"final_only"`) {
+		t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	if w := patch("/api/tenants/t1/channels/telegram", `{"notification_level":"loud"}`, ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid level status=%d", w.Code)
+	}
+	if w := patch("/api/tenants/t1/channels/telegram", `{}`, ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing level status=%d", w.Code)
+	}
+	if w := patch("/api/tenants/t1/channels/telegram", `{"notification_level":"all"}`, "t2"); w.Code != http.StatusNotFound {
+		t.Fatalf("other tenant status=%d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestSanitizeWebhookHeaders(t *testing.T) {
+	t.Parallel()
+	headers := http.Header{}
+	headers.Set("X-Telegram-Bot-Api-Secret-Token", "s3cret")
+	headers.Set("Content-Type", "application/json")
+
+	got := sanitizeWebhookHeaders(headers)
+	if got["Content-Type"] != "application/json" {
+		t.Fatalf("content type = %q", got["Content-Type"])
+	}
+	secret := got["X-Telegram-Bot-Api-Secret-Token"]
+	if !strings.HasPrefix(secret, "sha256:") || strings.Contains(secret, "s3cret") {
+		t.Fatalf("secret header not hashed: %q", secret)
+	}
+}
@@ -0,0 +1,95 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/orchestrator"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type portsOrchestrator struct {
+	stubOrchestrator
+	ports []orchestrator.ContainerPort
+	err   error
+}
+
+func (p *portsOrchestrator) ContainerPorts(context.Context, string) ([]orchestrator.ContainerPort, error) {
+	return p.ports, p.err
+}
+
+func TestContainerPortsEndpoint(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	hostPort := 5201
+	orch := &portsOrchestrator{ports: []orchestrator.ContainerPort{
+		{ContainerPort: 4200, HostPort: &hostPort, Protocol: "tcp", Binding: "127.0.0.1"},
+	}}
+	h := NewContainerHandler(db)
+	h.Orch = orch
+	h.JWTSecret = "secret"
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"tenant_id": "t1"}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	get := func(path, bearer string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("/api/tenants/t1/container/ports", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("no token status = %d", w.Code)
+	}
+	if w := get("/api/tenants/t2/container/ports", token); w.Code != http.StatusNotFound {
+		t.Fatalf("other tenant status = %d", w.Code)
+	}
+
+	w := get("/api/tenants/t1/container/ports", token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var ports []map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &ports); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(ports) != 1 || ports[0]["host_port"] != float64(5201) || ports[0]["binding"] != "127.0.0.1" {
+		t.Fatalf("ports = %s", w.Body.String())
+	}
+
+	// A stopped container falls back to the persisted port.
+	orch.err = orchestrator.ErrContainerNotRunning
+	mock.ExpectQuery("SELECT container_id, container_port FROM tenants").
+		WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"container_id", "container_port"}).AddRow("c1", 4200))
+	w = get("/api/tenants/t1/container/ports", token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("stopped status = %d body=%s", w.Code, w.Body.String())
+	}
+	ports = nil
+	if err := json.Unmarshal(w.Body.Bytes(), &ports); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(ports) != 1 || ports[0]["container_port"] != float64(4200) || ports[0]["host_port"] != nil {
+		t.Fatalf("stopped ports = %s", w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
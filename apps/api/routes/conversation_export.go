@@ -0,0 +1,516 @@
+package routes
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// conversationExportTTL is how long an export's download URL stays valid.
+	conversationExportTTL = 24 * time.Hour
+	// conversationExportTimeout bounds building and uploading one archive.
+	conversationExportTimeout = 30 * time.Minute
+	maxConversationExportDays = 366
+)
+
+// errExportNotStarted wraps failures that happen before any output was
+// written, which can still be answered with an error status.
+var errExportNotStarted = errors.New("export not started")
+
+// conversationExportMessage is one message in an export. Metadata keeps only
+// non-secret keys; see exportableMetadata.
+type conversationExportMessage struct {
+	ID        string         `json:"id"`
+	Role      string         `json:"role"`
+	Channel   string         `json:"channel"`
+	Content   string         `json:"content"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type conversationExportInfo struct {
+	ID         string    `json:"id"`
+	Title      *string   `json:"title"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// conversationExportWriter renders one conversation as it is read, so no
+// export holds all of a conversation's messages in memory.
+type conversationExportWriter interface {
+	begin(conv conversationExportInfo) error
+	message(msg conversationExportMessage) error
+	end() error
+}
+
+func newConversationExportWriter(format string, w io.Writer) conversationExportWriter {
+	if format == "markdown" {
+		return &markdownExportWriter{w: w}
+	}
+	return &jsonExportWriter{w: w}
+}
+
+// jsonExportWriter writes {"conversation": {...}, "messages": [...]}.
+type jsonExportWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonExportWriter) begin(conv conversationExportInfo) error {
+	header, err := json.Marshal(conv)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(e.w, `{"conversation":%s,"messages":[`, header)
+	return err
+}
+
+func (e *jsonExportWriter) message(msg conversationExportMessage) error {
+	encoded, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+	_, err = e.w.Write(encoded)
+	return err
+}
+
+func (e *jsonExportWriter) end() error {
+	_, err := io.WriteString(e.w, "]}\n")
+	return err
+}
+
+// markdownExportWriter writes a readable transcript with a heading per UTC
+// day.
+type markdownExportWriter struct {
+	w   io.Writer
+	day string
+}
+
+func (e *markdownExportWriter) begin(conv conversationExportInfo) error {
+	title := "Conversation " + conv.ID
+	if conv.Title != nil && strings.TrimSpace(*conv.Title) != "" {
+		title = strings.TrimSpace(*conv.Title)
+	}
+	_, err := fmt.Fprintf(e.w, "# %s\n\nConversation `%s`, started %s. Exported %s.\n",
+		title, conv.ID, conv.CreatedAt.UTC().Format("2006-01-02 15:04 UTC"), conv.ExportedAt.UTC().Format("2006-01-02 15:04 UTC"))
+	return err
+}
+
+func (e *markdownExportWriter) message(msg conversationExportMessage) error {
+	at := msg.CreatedAt.UTC()
+	if day := at.Format(time.DateOnly); day != e.day {
+		e.day = day
+		if _, err := fmt.Fprintf(e.w, "\n## %s\n", day); err != nil {
+			return err
+		}
+	}
+	role := msg.Role
+	if role != "" {
+		role = strings.ToUpper(role[:1]) + role[1:]
+	}
+	_, err := fmt.Fprintf(e.w, "\n**%s** · %s · %s\n\n%s\n", role, msg.Channel, at.Format("15:04:05 UTC"), strings.TrimSpace(msg.Content))
+	return err
+}
+
+func (e *markdownExportWriter) end() error { return nil }
+
+// parseExportFormat reads format=json|markdown, defaulting to json.
+func parseExportFormat(raw string) (string, error) {
+	switch format := strings.ToLower(strings.TrimSpace(raw)); format {
+	case "", "json":
+		return "json", nil
+	case "markdown", "md":
+		return "markdown", nil
+	default:
+		return "", errors.New("format must be json or markdown")
+	}
+}
+
+// handleExportConversation streams one conversation as JSON or a markdown
+// transcript. The conversation must belong to the requesting tenant; any
+// other id is a 404 so exports cannot probe other tenants' conversations.
+func (h *ChannelHandler) handleExportConversation(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID := tenantIDFromRequest(r)
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "tenant_id is required")
+		return
+	}
+	conversationID := strings.TrimSpace(r.PathValue("id"))
+	format, err := parseExportFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conv, err := h.loadExportConversation(r.Context(), tenantID, conversationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "conversation not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load conversation")
+		return
+	}
+
+	ext, contentType := ".json", "application/json"
+	if format == "markdown" {
+		ext, contentType = ".md", "text/markdown; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="conversation-%s%s"`, conv.ID, ext))
+	// Headers are sent with the first write, so a failure after that can
+	// only cut the body short.
+	err = h.writeConversationExport(r.Context(), newConversationExportWriter(format, w), conv, time.Time{}, time.Time{})
+	if errors.Is(err, errExportNotStarted) {
+		w.Header().Del("Content-Disposition")
+		writeError(w, http.StatusInternalServerError, "failed to export conversation")
+		return
+	}
+	if err != nil {
+		slog.Error("conversation export failed", "tenant", tenantID, "conversation", conv.ID, "err", err)
+	}
+}
+
+func (h *ChannelHandler) loadExportConversation(ctx context.Context, tenantID, conversationID string) (conversationExportInfo, error) {
+	conv := conversationExportInfo{ExportedAt: time.Now().UTC()}
+	if _, err := uuid.Parse(conversationID); err != nil {
+		return conv, sql.ErrNoRows
+	}
+	var title sql.NullString
+	err := h.DB.QueryRowContext(ctx, `
+		SELECT id, title, created_at
+		FROM conversations
+		WHERE id = $1 AND tenant_id = $2
+	`, conversationID, tenantID).Scan(&conv.ID, &title, &conv.CreatedAt)
+	if title.Valid {
+		conv.Title = &title.String
+	}
+	return conv, err
+}
+
+// writeConversationExport writes conv and its messages, oldest first, reading
+// them row by row. Non-zero from and to limit the messages to [from, to).
+func (h *ChannelHandler) writeConversationExport(ctx context.Context, out conversationExportWriter, conv conversationExportInfo, from, to time.Time) error {
+	var fromArg, toArg any
+	if !from.IsZero() {
+		fromArg, toArg = from, to
+	}
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT id, role, content, channel, COALESCE(metadata, '{}'::jsonb), created_at
+		FROM messages
+		WHERE conversation_id = $1
+		  AND ($2::timestamptz IS NULL OR created_at >= $2)
+		  AND ($3::timestamptz IS NULL OR created_at < $3)
+		ORDER BY created_at, id
+	`, conv.ID, fromArg, toArg)
+	if err != nil {
+		return fmt.Errorf("%w: query messages: %v", errExportNotStarted, err)
+	}
+	defer rows.Close()
+
+	if err := out.begin(conv); err != nil {
+		return err
+	}
+	for rows.Next() {
+		var (
+			msg      conversationExportMessage
+			metadata []byte
+		)
+		if err := rows.Scan(&msg.ID, &msg.Role, &msg.Content, &msg.Channel, &metadata, &msg.CreatedAt); err != nil {
+			return fmt.Errorf("scan message: %w", err)
+		}
+		msg.Metadata = exportableMetadata(metadata)
+		if err := out.message(msg); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read messages: %w", err)
+	}
+	return out.end()
+}
+
+// exportableMetadata decodes message metadata without the keys that may hold
+// credentials, such as bot tokens or webhook secrets.
+func exportableMetadata(raw []byte) map[string]any {
+	var metadata map[string]any
+	if err := json.Unmarshal(raw, &metadata); err != nil || len(metadata) == 0 {
+		return nil
+	}
+	for key := range metadata {
+		lower := strings.ToLower(key)
+		for _, secret := range []string{"token", "secret", "password", "api_key", "apikey", "authorization", "signature"} {
+			if strings.Contains(lower, secret) {
+				delete(metadata, key)
+				break
+			}
+		}
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+	return metadata
+}
+
+type conversationExportRequest struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Format string `json:"format"`
+}
+
+type conversationExportResponse struct {
+	ID          string    `json:"id"`
+	Status      string    `json:"status"`
+	DownloadURL string    `json:"download_url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// handleStartConversationExport queues a zip of every conversation with
+// messages in the UTC days [from, to) and returns its signed download URL.
+// The URL answers 409 until the archive is ready.
+func (h *ChannelHandler) handleStartConversationExport(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	if h.Blobs == nil || h.ExportSecret == "" {
+		writeError(w, http.StatusServiceUnavailable, "conversation exports are not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if _, err := uuid.Parse(tenantID); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid tenant id")
+		return
+	}
+	var req conversationExportRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	format, err := parseExportFormat(req.Format)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	from, to, err := parseUsageRange(req.From, req.To, time.Time{}, time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if from.IsZero() {
+		writeError(w, http.StatusBadRequest, "from is required")
+		return
+	}
+	if to.Sub(from) > maxConversationExportDays*24*time.Hour {
+		writeError(w, http.StatusBadRequest, "export range must be at most "+strconv.Itoa(maxConversationExportDays)+" days")
+		return
+	}
+
+	expiresAt := time.Now().UTC().Add(conversationExportTTL).Truncate(time.Second)
+	var exportID string
+	err = h.DB.QueryRowContext(r.Context(), `
+		INSERT INTO conversation_exports (tenant_id, format, range_from, range_to, expires_at)
+		SELECT id, $2, $3, $4, $5 FROM tenants WHERE id = $1
+		RETURNING id
+	`, tenantID, format, from, to, expiresAt).Scan(&exportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create export")
+		return
+	}
+
+	go h.runConversationExport(exportID, tenantID, format, from, to)
+	writeJSON(w, http.StatusAccepted, conversationExportResponse{
+		ID:          exportID,
+		Status:      "pending",
+		DownloadURL: conversationExportURL(h.ExportSecret, exportID, expiresAt),
+		ExpiresAt:   expiresAt,
+	})
+}
+
+// runConversationExport builds the archive in a temporary file, one zip entry
+// per conversation, and uploads it to the blob store.
+func (h *ChannelHandler) runConversationExport(exportID, tenantID, format string, from, to time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), conversationExportTimeout)
+	defer cancel()
+
+	key := fmt.Sprintf("exports/%s/%s.zip", tenantID, exportID)
+	count, err := h.buildConversationExport(ctx, key, tenantID, format, from, to)
+	if err != nil {
+		slog.Error("conversation export failed", "tenant", tenantID, "export", exportID, "err", err)
+		_, _ = h.DB.ExecContext(ctx, `
+			UPDATE conversation_exports SET status = 'failed', error_message = $2, completed_at = NOW() WHERE id = $1
+		`, exportID, err.Error())
+		return
+	}
+	_, _ = h.DB.ExecContext(ctx, `
+		UPDATE conversation_exports
+		SET status = 'ready', blob_key = $2, conversations = $3, completed_at = NOW()
+		WHERE id = $1
+	`, exportID, key, count)
+}
+
+func (h *ChannelHandler) buildConversationExport(ctx context.Context, key, tenantID, format string, from, to time.Time) (int, error) {
+	tmp, err := os.CreateTemp("", "conversation-export-*.zip")
+	if err != nil {
+		return 0, fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	// The conversation list is read up front so that each conversation's
+	// messages can be queried while the archive is written.
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT c.id, c.title, c.created_at
+		FROM conversations c
+		WHERE c.tenant_id = $1
+		  AND EXISTS (SELECT 1 FROM messages m WHERE m.conversation_id = c.id AND m.created_at >= $2 AND m.created_at < $3)
+		ORDER BY c.created_at, c.id
+	`, tenantID, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("query conversations: %w", err)
+	}
+	var conversations []conversationExportInfo
+	for rows.Next() {
+		conv := conversationExportInfo{ExportedAt: time.Now().UTC()}
+		var title sql.NullString
+		if err := rows.Scan(&conv.ID, &title, &conv.CreatedAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan conversation: %w", err)
+		}
+		if title.Valid {
+			conv.Title = &title.String
+		}
+		conversations = append(conversations, conv)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("read conversations: %w", err)
+	}
+
+	ext := ".json"
+	if format == "markdown" {
+		ext = ".md"
+	}
+	archive := zip.NewWriter(tmp)
+	for _, conv := range conversations {
+		entry, err := archive.Create(conv.CreatedAt.UTC().Format(time.DateOnly) + "-" + conv.ID + ext)
+		if err != nil {
+			return 0, fmt.Errorf("add zip entry: %w", err)
+		}
+		if err := h.writeConversationExport(ctx, newConversationExportWriter(format, entry), conv, from, to); err != nil {
+			return 0, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return 0, fmt.Errorf("finish zip: %w", err)
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	if err := h.Blobs.PutStream(ctx, key, "application/zip", tmp, size); err != nil {
+		return 0, fmt.Errorf("upload export: %w", err)
+	}
+	return len(conversations), nil
+}
+
+// handleDownloadConversationExport serves a finished export. The signed URL
+// is the credential, so it needs no tenant header and can be shared.
+func (h *ChannelHandler) handleDownloadConversationExport(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil || h.Blobs == nil || h.ExportSecret == "" {
+		writeError(w, http.StatusServiceUnavailable, "conversation exports are not configured")
+		return
+	}
+	exportID := strings.TrimSpace(r.PathValue("id"))
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || !hmac.Equal([]byte(r.URL.Query().Get("signature")), []byte(signConversationExport(h.ExportSecret, exportID, expires))) {
+		writeError(w, http.StatusForbidden, "invalid download signature")
+		return
+	}
+	if time.Now().Unix() > expires {
+		writeError(w, http.StatusGone, "download link has expired")
+		return
+	}
+
+	var status string
+	var blobKey, errMessage sql.NullString
+	err = h.DB.QueryRowContext(r.Context(), `
+		SELECT status, blob_key, error_message FROM conversation_exports WHERE id = $1
+	`, exportID).Scan(&status, &blobKey, &errMessage)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "export not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load export")
+		return
+	}
+	switch status {
+	case "pending":
+		w.Header().Set("Retry-After", "5")
+		writeError(w, http.StatusConflict, "export is not ready yet")
+		return
+	case "failed":
+		writeError(w, http.StatusConflict, "export failed: "+errMessage.String)
+		return
+	}
+
+	body, err := h.Blobs.Open(r.Context(), blobKey.String)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to read export")
+		return
+	}
+	defer body.Close()
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="conversations-%s.zip"`, exportID))
+	if _, err := io.Copy(w, body); err != nil {
+		slog.Warn("conversation export download interrupted", "export", exportID, "err", err)
+	}
+}
+
+// conversationExportURL is the signed path an export is downloaded from
+// until expiresAt.
+func conversationExportURL(secret, exportID string, expiresAt time.Time) string {
+	expires := expiresAt.Unix()
+	return fmt.Sprintf("/api/conversation-exports/%s/download?expires=%d&signature=%s", exportID, expires, signConversationExport(secret, exportID, expires))
+}
+
+func signConversationExport(secret, exportID string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s.%d", exportID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,138 @@
+package routes
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/media"
+)
+
+const exportConversationID = "0b6f4c1e-8a43-4c53-9a0c-2f1d5e6a7b8c"
+
+var exportMessageColumns = []string{"id", "role", "content", "channel", "metadata", "created_at"}
+
+func TestExportConversationFormats(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mux := http.NewServeMux()
+	NewChannelHandler(db, nil, nil, nil).Mount(mux)
+
+	day1 := time.Date(2026, 5, 1, 9, 30, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	for _, format := range []string{"json", "markdown"} {
+		mock.ExpectQuery("FROM conversations").WithArgs(exportConversationID, "t1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "created_at"}).AddRow(exportConversationID, "Refund", day1))
+		mock.ExpectQuery("FROM messages").WithArgs(exportConversationID, nil, nil).
+			WillReturnRows(sqlmock.NewRows(exportMessageColumns).
+				AddRow("m1", "user", "Where is my refund?", "telegram", `{"telegram_message_id":"7","bot_token":"123:abc"}`, day1).
+				AddRow("m2", "assistant", "It was sent today.", "telegram", `{}`, day2))
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/conversations/"+exportConversationID+"/export?format="+format, nil)
+		req.Header.Set("X-Tenant-ID", "t1")
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d body=%s", format, w.Code, w.Body.String())
+		}
+		body := w.Body.String()
+		if strings.Contains(body, "123:abc") {
+			t.Fatalf("%s: export leaked a secret: %s", format, body)
+		}
+		if format == "json" {
+			var got struct {
+				Conversation conversationExportInfo      `json:"conversation"`
+				Messages     []conversationExportMessage `json:"messages"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode: %v (%s)", err, body)
+			}
+			if len(got.Messages) != 2 || got.Messages[0].Metadata["telegram_message_id"] != "7" || got.Messages[1].Metadata != nil {
+				t.Fatalf("messages = %+v", got.Messages)
+			}
+			continue
+		}
+		if !strings.HasPrefix(body, "# Refund\n") || !strings.Contains(body, "## 2026-05-01") || !strings.Contains(body, "## 2026-05-02") ||
+			!strings.Contains(body, "**Assistant** · telegram · 09:30:00 UTC") {
+			t.Fatalf("markdown = %s", body)
+		}
+	}
+
+	// Another tenant's conversation is not found.
+	mock.ExpectQuery("FROM conversations").WithArgs(exportConversationID, "t2").WillReturnRows(sqlmock.NewRows([]string{"id", "title", "created_at"}))
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/conversations/"+exportConversationID+"/export?tenant_id=t2", nil)
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("other tenant status = %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestConversationExportArchiveAndDownload(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	h := NewChannelHandler(db, nil, nil, nil)
+	h.Blobs = media.NewLocalBlobStore(t.TempDir())
+	h.ExportSecret = "s3cret"
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	from := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	mock.ExpectQuery("FROM conversations c").WithArgs("t1", from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "created_at"}).AddRow(exportConversationID, nil, from))
+	mock.ExpectQuery("FROM messages").WithArgs(exportConversationID, from, to).
+		WillReturnRows(sqlmock.NewRows(exportMessageColumns).AddRow("m1", "user", "hello", "web", `{}`, from.Add(time.Hour)))
+	count, err := h.buildConversationExport(context.Background(), "exports/t1/e1.zip", "t1", "markdown", from, to)
+	if err != nil || count != 1 {
+		t.Fatalf("buildConversationExport = %d, %v", count, err)
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	link := conversationExportURL(h.ExportSecret, "e1", expiresAt)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, strings.Replace(link, "signature=", "signature=00", 1), nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("tampered link status = %d", w.Code)
+	}
+
+	mock.ExpectQuery("FROM conversation_exports").WithArgs("e1").
+		WillReturnRows(sqlmock.NewRows([]string{"status", "blob_key", "error_message"}).AddRow("ready", "exports/t1/e1.zip", nil))
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, link, nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("download status = %d body=%s", w.Code, w.Body.String())
+	}
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil || len(archive.File) != 1 || archive.File[0].Name != "2026-05-01-"+exportConversationID+".md" {
+		t.Fatalf("archive = %v, %v", archive, err)
+	}
+	entry, _ := archive.File[0].Open()
+	content, _ := io.ReadAll(entry)
+	if !strings.Contains(string(content), "# Conversation "+exportConversationID) || !strings.Contains(string(content), "hello") {
+		t.Fatalf("entry = %s", content)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
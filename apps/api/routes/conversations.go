@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/agentsquads/api/channels"
+)
+
+// handleRetitle regenerates a conversation title on demand. Titles are
+// otherwise refreshed in the background as the conversation grows.
+func (h *ChannelHandler) handleRetitle(w http.ResponseWriter, r *http.Request) {
+	if h.Router == nil {
+		writeError(w, http.StatusServiceUnavailable, "channel router is not configured")
+		return
+	}
+
+	tenantID := tenantIDFromRequest(r)
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "tenant_id is required")
+		return
+	}
+	conversationID := strings.TrimSpace(r.PathValue("id"))
+	if conversationID == "" {
+		writeError(w, http.StatusBadRequest, "missing conversation id")
+		return
+	}
+
+	title, err := h.Router.Retitle(r.Context(), tenantID, conversationID)
+	if errors.Is(err, channels.ErrConversationNotFound) {
+		writeError(w, http.StatusNotFound, "conversation not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "failed to generate title")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"conversation_id": conversationID, "title": title})
+}
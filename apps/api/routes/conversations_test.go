@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/channels"
+)
+
+func TestRetitleConversation(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	h := NewChannelHandler(db, channels.NewRouter(db, nil), nil, nil)
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/conversations/c1/retitle", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("missing tenant status=%d want=400", w.Code)
+	}
+
+	mock.ExpectQuery("FROM conversations c").
+		WithArgs("c1", "other-tenant").
+		WillReturnError(sql.ErrNoRows)
+	req = httptest.NewRequest(http.MethodPost, "/api/conversations/c1/retitle", nil)
+	req.Header.Set("X-Tenant-ID", "other-tenant")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("other tenant status=%d want=404 body=%s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,67 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/agentsquads/api/keyring"
+)
+
+// handleStartKeyRotation re-encrypts stored credentials with the primary
+// ENCRYPTION_KEY in the background. Calling it again while a run for the same
+// key is unfinished resumes that run.
+func (h *AdminHandler) handleStartKeyRotation(w http.ResponseWriter, r *http.Request) {
+	if h.Rotation == nil {
+		writeError(w, http.StatusServiceUnavailable, "key rotation is not configured")
+		return
+	}
+	rotation, err := h.Rotation.Start(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to start key rotation")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, rotation)
+}
+
+func (h *AdminHandler) handleListKeyRotations(w http.ResponseWriter, r *http.Request) {
+	if h.Rotation == nil {
+		writeError(w, http.StatusServiceUnavailable, "key rotation is not configured")
+		return
+	}
+	limit := 50
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = min(n, 500)
+	}
+	rotations, err := h.Rotation.List(r.Context(), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list key rotations")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"rotations": rotations})
+}
+
+// handleGetKeyRotation reports a run's per-table counts and the rows it
+// could not decrypt.
+func (h *AdminHandler) handleGetKeyRotation(w http.ResponseWriter, r *http.Request) {
+	if h.Rotation == nil {
+		writeError(w, http.StatusServiceUnavailable, "key rotation is not configured")
+		return
+	}
+	rotation, err := h.Rotation.Get(r.Context(), strings.TrimSpace(r.PathValue("id")))
+	if errors.Is(err, keyring.ErrRotationNotFound) {
+		writeError(w, http.StatusNotFound, "rotation not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load key rotation")
+		return
+	}
+	writeJSON(w, http.StatusOK, rotation)
+}
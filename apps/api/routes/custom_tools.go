@@ -0,0 +1,116 @@
+package routes
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/agentsquads/api/tools"
+)
+
+// CustomToolHandler lets tenants register their own agent tools: HTTPS
+// endpoints the tool loop POSTs the model's arguments to (see
+// tools.CustomToolStore).
+type CustomToolHandler struct {
+	Store *tools.CustomToolStore
+}
+
+func NewCustomToolHandler(store *tools.CustomToolStore) *CustomToolHandler {
+	return &CustomToolHandler{Store: store}
+}
+
+func (h *CustomToolHandler) Mount(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/tenants/{id}/tools", h.handleCreateTool)
+	mux.HandleFunc("GET /api/tenants/{id}/tools", h.handleListTools)
+	mux.HandleFunc("DELETE /api/tenants/{id}/tools/{name}", h.handleDeleteTool)
+}
+
+type createToolResponse struct {
+	tools.CustomToolInfo
+	SigningSecret string `json:"signing_secret"`
+}
+
+// customToolTenant returns the path's tenant id, writing the error response
+// when it is missing or outside the caller's tenant scope.
+func (h *CustomToolHandler) customToolTenant(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if h.Store == nil {
+		writeError(w, http.StatusServiceUnavailable, "custom tools are not configured")
+		return "", false
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return "", false
+	}
+	if scoped := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); scoped != "" && scoped != tenantID {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return "", false
+	}
+	return tenantID, true
+}
+
+// handleCreateTool registers a tool. The response carries the secret the
+// tool's requests are signed with; it is not shown again.
+func (h *CustomToolHandler) handleCreateTool(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := h.customToolTenant(w, r)
+	if !ok {
+		return
+	}
+	var spec tools.CustomToolSpec
+	if err := decodeJSONStrict(r, &spec); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	info, signingSecret, err := h.Store.Create(r.Context(), tenantID, spec)
+	switch {
+	case errors.Is(err, tools.ErrInvalidCustomTool):
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, tools.ErrCustomToolExists):
+		writeError(w, http.StatusConflict, "a tool named "+strings.TrimSpace(spec.Name)+" already exists")
+		return
+	case errors.Is(err, tools.ErrTooManyCustomTools):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	case err != nil:
+		slog.Error("create custom tool failed", "tenant", tenantID, "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to create tool")
+		return
+	}
+	writeJSON(w, http.StatusCreated, createToolResponse{CustomToolInfo: info, SigningSecret: signingSecret})
+}
+
+func (h *CustomToolHandler) handleListTools(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := h.customToolTenant(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.Store.List(r.Context(), tenantID)
+	if err != nil {
+		slog.Error("list custom tools failed", "tenant", tenantID, "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to load tools")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tools": list})
+}
+
+func (h *CustomToolHandler) handleDeleteTool(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := h.customToolTenant(w, r)
+	if !ok {
+		return
+	}
+	name := strings.TrimSpace(r.PathValue("name"))
+	deleted, err := h.Store.Delete(r.Context(), tenantID, name)
+	if err != nil {
+		slog.Error("delete custom tool failed", "tenant", tenantID, "tool", name, "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete tool")
+		return
+	}
+	if !deleted {
+		writeError(w, http.StatusNotFound, "tool not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
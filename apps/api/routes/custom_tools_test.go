@@ -0,0 +1,67 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/tools"
+)
+
+func TestCustomToolRoutes(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	NewCustomToolHandler(tools.NewCustomToolStore(db)).Mount(mux)
+	do := func(method, path, scope, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if scope != "" {
+			req.Header.Set("X-Tenant-ID", scope)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, body := range []string{
+		`not json`,
+		`{"name":"lookup","endpoint":"https://example.com","extra":1}`,
+		`{"name":"web_fetch","endpoint":"https://example.com"}`,
+		`{"name":"lookup","endpoint":"http://example.com"}`,
+	} {
+		if w := do(http.MethodPost, "/api/tenants/t1/tools", "", body); w.Code != http.StatusBadRequest {
+			t.Fatalf("body %s status = %d", body, w.Code)
+		}
+	}
+	if w := do(http.MethodGet, "/api/tenants/t1/tools", "t2", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("cross-tenant status = %d", w.Code)
+	}
+
+	mock.ExpectQuery("FROM tenant_tools").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{
+		"name", "description", "parameters", "endpoint", "auth_header", "timeout_seconds", "created_at",
+	}).AddRow("lookup_order", "Look up an order", []byte(`{"type":"object","properties":{}}`), "https://shop.example.com/hooks/order", "Authorization", 10, time.Now()))
+	w := do(http.MethodGet, "/api/tenants/t1/tools", "t1", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"lookup_order"`) || strings.Contains(w.Body.String(), "secret") {
+		t.Fatalf("list status = %d body=%s", w.Code, w.Body.String())
+	}
+
+	mock.ExpectExec("DELETE FROM tenant_tools").WithArgs("t1", "lookup_order").WillReturnResult(sqlmock.NewResult(0, 1))
+	if w := do(http.MethodDelete, "/api/tenants/t1/tools/lookup_order", "", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete status = %d", w.Code)
+	}
+	mock.ExpectExec("DELETE FROM tenant_tools").WithArgs("t1", "missing").WillReturnResult(sqlmock.NewResult(0, 0))
+	if w := do(http.MethodDelete, "/api/tenants/t1/tools/missing", "", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("missing delete status = %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -3,20 +3,21 @@ package routes
 import (
 	"bytes"
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
 	"database/sql"
-	"encoding/base64"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/agentsquads/api/keyring"
+	"github.com/agentsquads/api/openapi"
+	"github.com/agentsquads/api/policies"
+	"github.com/redis/go-redis/v9"
 )
 
 const (
@@ -26,11 +27,17 @@ const (
 type DeployHandler struct {
 	db         *sql.DB
 	httpClient *http.Client
+	policies   *policies.Store
+	redis      *redis.Client
+	notifiers  sync.Map
+	sleep      func(time.Duration)
+	keys       func() (*keyring.Keyring, error)
 }
 
 type deployRunResponse struct {
 	ID       string `json:"id"`
 	Provider string `json:"provider"`
+	Target   string `json:"target"`
 	Status   string `json:"status"`
 }
 
@@ -45,6 +52,10 @@ type vercelDeployRequest struct {
 	Branch        string            `json:"branch"`
 	Files         []vercelDeployFile `json:"files"`
 	Env           map[string]string `json:"env"`
+	NotifyURL     string            `json:"notify_url"`
+	NotifySecret  string            `json:"notify_secret"`
+	// Target is production (the default), preview or staging.
+	Target string `json:"target"`
 }
 
 type vercelDeployFile struct {
@@ -60,6 +71,10 @@ type supabaseDeployRequest struct {
 	DBPassword  string   `json:"db_password"`
 	Token       string   `json:"token"`
 	Migrations  []string `json:"migrations"`
+	NotifyURL   string   `json:"notify_url"`
+	NotifySecret string  `json:"notify_secret"`
+	// Target is production (the default), preview or staging.
+	Target string `json:"target"`
 }
 
 type deploymentStatusResponse struct {
@@ -67,10 +82,12 @@ type deploymentStatusResponse struct {
 	TenantID    string           `json:"tenant_id"`
 	Provider    string           `json:"provider"`
 	TargetName  string           `json:"target_name"`
+	Target      string           `json:"target"`
 	Status      string           `json:"status"`
 	ExternalID  string           `json:"external_id,omitempty"`
 	Error       string           `json:"error,omitempty"`
 	Logs        []deploymentLog  `json:"logs"`
+	Notifications []deploymentNotification `json:"notifications"`
 	CreatedAt   time.Time        `json:"created_at"`
 	UpdatedAt   time.Time        `json:"updated_at"`
 }
@@ -86,13 +103,35 @@ func NewDeployHandler(db *sql.DB) *DeployHandler {
 		httpClient: &http.Client{
 			Timeout: 45 * time.Second,
 		},
+		keys: keyring.FromEnv,
 	}
 }
 
+// SetPolicyStore makes deploy endpoints enforce the tenant deploy policy.
+func (h *DeployHandler) SetPolicyStore(store *policies.Store) {
+	h.policies = store
+}
+
+// SetRedis makes deploy status changes reach the tenant's linked chats
+// through the outbound channel fanout.
+func (h *DeployHandler) SetRedis(client *redis.Client) {
+	h.redis = client
+}
+
 func (h *DeployHandler) Mount(mux *http.ServeMux) {
-	mux.HandleFunc("POST /api/deploy/vercel", h.handleDeployVercel)
-	mux.HandleFunc("POST /api/deploy/supabase", h.handleDeploySupabase)
-	mux.HandleFunc("GET /api/deploy/status/{id}", h.handleDeployStatus)
+	tags := []string{"Deploy"}
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/api/deploy/vercel", Summary: "Deploy a project to Vercel",
+		Tags: tags, Request: vercelDeployRequest{}, Response: deployRunResponse{}, Status: http.StatusAccepted}, h.handleDeployVercel)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPost, Path: "/api/deploy/supabase", Summary: "Create a Supabase project and run migrations",
+		Tags: tags, Request: supabaseDeployRequest{}, Response: deployRunResponse{}, Status: http.StatusAccepted}, h.handleDeploySupabase)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodGet, Path: "/api/deploy/status/{id}", Summary: "Get a deployment's status and logs",
+		Tags: tags, Response: deploymentStatusResponse{}}, h.handleDeployStatus)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodGet, Path: "/api/deploy/env", Summary: "List the tenant's deploy environment variables and their sync state",
+		Tags: tags, Query: []string{"tenant_id"}, Headers: []string{"X-Tenant-ID"}, Response: deployEnvResponse{}}, h.handleGetDeployEnv)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodPut, Path: "/api/deploy/env", Summary: "Set deploy environment variables",
+		Tags: tags, Query: []string{"tenant_id"}, Headers: []string{"X-Tenant-ID"}, Request: putDeployEnvRequest{}, Response: deployEnvResponse{}}, h.handlePutDeployEnv)
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodDelete, Path: "/api/deploy/env", Summary: "Delete a deploy environment variable",
+		Tags: tags, Query: []string{"tenant_id", "key"}, Headers: []string{"X-Tenant-ID"}, Status: http.StatusNoContent}, h.handleDeleteDeployEnv)
 }
 
 func (h *DeployHandler) handleDeployVercel(w http.ResponseWriter, r *http.Request) {
@@ -124,6 +163,13 @@ func (h *DeployHandler) handleDeployVercel(w http.ResponseWriter, r *http.Reques
 		writeAPIError(w, http.StatusBadRequest, "tenant_id and project_name are required")
 		return
 	}
+	target, err := parseDeployTarget(req.Target)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.Target = target
+	req.ProjectName = targetProjectName(req.ProjectName, target)
 	if req.RepoURL == "" && len(req.Files) == 0 {
 		writeAPIError(w, http.StatusBadRequest, "repo_url or files is required")
 		return
@@ -134,17 +180,28 @@ func (h *DeployHandler) handleDeployVercel(w http.ResponseWriter, r *http.Reques
 			return
 		}
 	}
+	notify, err := validateDeployNotify(req.NotifyURL, req.NotifySecret)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	runID, err := h.createDeployRun(r.Context(), req.TenantID, "vercel", req.ProjectName)
+	if !requireFeature(w, r, h.policies, req.TenantID, policies.FeatureDeploy, "deploy") {
+		return
+	}
+
+	runID, err := h.createDeployRun(r.Context(), req.TenantID, "vercel", req.ProjectName, req.Target, notify)
 	if err != nil {
 		writeAPIError(w, http.StatusInternalServerError, "failed to create deployment run")
 		return
 	}
+	h.startDeployNotifier(runID, req.TenantID, "vercel", req.ProjectName, notify)
 
 	go h.runVercelDeployment(runID, req)
 	writeJSON(w, http.StatusAccepted, deployRunResponse{
 		ID:       runID,
 		Provider: "vercel",
+		Target:   req.Target,
 		Status:   "queued",
 	})
 }
@@ -173,17 +230,35 @@ func (h *DeployHandler) handleDeploySupabase(w http.ResponseWriter, r *http.Requ
 		writeAPIError(w, http.StatusBadRequest, "tenant_id, project_name, org_id, and db_password are required")
 		return
 	}
+	target, err := parseDeployTarget(req.Target)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.Target = target
+	req.ProjectName = targetProjectName(req.ProjectName, target)
+	notify, err := validateDeployNotify(req.NotifyURL, req.NotifySecret)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !requireFeature(w, r, h.policies, req.TenantID, policies.FeatureDeploy, "deploy") {
+		return
+	}
 
-	runID, err := h.createDeployRun(r.Context(), req.TenantID, "supabase", req.ProjectName)
+	runID, err := h.createDeployRun(r.Context(), req.TenantID, "supabase", req.ProjectName, req.Target, notify)
 	if err != nil {
 		writeAPIError(w, http.StatusInternalServerError, "failed to create deployment run")
 		return
 	}
+	h.startDeployNotifier(runID, req.TenantID, "supabase", req.ProjectName, notify)
 
 	go h.runSupabaseDeployment(runID, req)
 	writeJSON(w, http.StatusAccepted, deployRunResponse{
 		ID:       runID,
 		Provider: "supabase",
+		Target:   req.Target,
 		Status:   "queued",
 	})
 }
@@ -202,7 +277,7 @@ func (h *DeployHandler) handleDeployStatus(w http.ResponseWriter, r *http.Reques
 
 	tenantID := strings.TrimSpace(r.URL.Query().Get("tenant_id"))
 	query := `
-		SELECT id, tenant_id, provider, target_name, status, external_id, logs, error_message, created_at, updated_at
+		SELECT id, tenant_id, provider, target_name, target, status, external_id, logs, notifications, error_message, created_at, updated_at
 		FROM deployment_runs
 		WHERE id = $1
 	`
@@ -213,15 +288,17 @@ func (h *DeployHandler) handleDeployStatus(w http.ResponseWriter, r *http.Reques
 	}
 
 	var res deploymentStatusResponse
-	var logsRaw []byte
+	var logsRaw, notificationsRaw []byte
 	err := h.db.QueryRowContext(r.Context(), query, args...).Scan(
 		&res.ID,
 		&res.TenantID,
 		&res.Provider,
 		&res.TargetName,
+		&res.Target,
 		&res.Status,
 		&res.ExternalID,
 		&logsRaw,
+		&notificationsRaw,
 		&res.Error,
 		&res.CreatedAt,
 		&res.UpdatedAt,
@@ -241,6 +318,12 @@ func (h *DeployHandler) handleDeployStatus(w http.ResponseWriter, r *http.Reques
 	if res.Logs == nil {
 		res.Logs = []deploymentLog{}
 	}
+	if len(notificationsRaw) > 0 {
+		_ = json.Unmarshal(notificationsRaw, &res.Notifications)
+	}
+	if res.Notifications == nil {
+		res.Notifications = []deploymentNotification{}
+	}
 
 	writeJSON(w, http.StatusOK, res)
 }
@@ -301,39 +384,13 @@ func (h *DeployHandler) runVercelDeployment(runID string, req vercelDeployReques
 		h.appendDeployLog(runID, "Vercel project created")
 	}
 
-	deployBody := map[string]any{
-		"name":    req.ProjectName,
-		"project": req.ProjectName,
-	}
-	if len(req.Env) > 0 {
-		env := make([]map[string]string, 0, len(req.Env))
-		for k, v := range req.Env {
-			env = append(env, map[string]string{
-				"key":   k,
-				"value": v,
-				"target": "production",
-			})
-		}
-		deployBody["env"] = env
-	}
-	if repo, repoType := parseRepo(req.RepoURL); repo != "" {
-		deployBody["gitSource"] = map[string]any{
-			"type": repoType,
-			"repo": repo,
-			"ref":  req.Branch,
-		}
-	}
-	if len(req.Files) > 0 {
-		files := make([]map[string]string, 0, len(req.Files))
-		for _, f := range req.Files {
-			files = append(files, map[string]string{
-				"file": f.Path,
-				"data": f.Content,
-			})
-		}
-		deployBody["files"] = files
+	if err := h.syncVercelEnv(runID, token, req); err != nil {
+		h.failDeployRun(runID, fmt.Sprintf("sync Vercel env vars failed: %v", err))
+		return
 	}
 
+	deployBody := vercelDeployBody(req)
+
 	deployURL := "https://api.vercel.com/v13/deployments"
 	if req.TeamID != "" {
 		deployURL += "?teamId=" + url.QueryEscape(req.TeamID)
@@ -363,6 +420,46 @@ func (h *DeployHandler) runVercelDeployment(runID string, req vercelDeployReques
 	_ = h.updateDeployRun(runID, "succeeded", externalID, "")
 }
 
+// vercelDeployBody builds the Vercel create-deployment payload. The
+// deployment and its env vars go to the Vercel target for req.Target.
+func vercelDeployBody(req vercelDeployRequest) map[string]any {
+	target := vercelTarget(req.Target)
+	deployBody := map[string]any{
+		"name":    req.ProjectName,
+		"project": req.ProjectName,
+		"target":  target,
+	}
+	if len(req.Env) > 0 {
+		env := make([]map[string]string, 0, len(req.Env))
+		for k, v := range req.Env {
+			env = append(env, map[string]string{
+				"key":    k,
+				"value":  v,
+				"target": target,
+			})
+		}
+		deployBody["env"] = env
+	}
+	if repo, repoType := parseRepo(req.RepoURL); repo != "" {
+		deployBody["gitSource"] = map[string]any{
+			"type": repoType,
+			"repo": repo,
+			"ref":  req.Branch,
+		}
+	}
+	if len(req.Files) > 0 {
+		files := make([]map[string]string, 0, len(req.Files))
+		for _, f := range req.Files {
+			files = append(files, map[string]string{
+				"file": f.Path,
+				"data": f.Content,
+			})
+		}
+		deployBody["files"] = files
+	}
+	return deployBody
+}
+
 func (h *DeployHandler) runSupabaseDeployment(runID string, req supabaseDeployRequest) {
 	_ = h.updateDeployRun(runID, "running", "", "")
 	h.appendDeployLog(runID, "Starting Supabase provisioning")
@@ -512,21 +609,27 @@ func (h *DeployHandler) doJSONRequest(method, endpoint, bearerToken string, payl
 	return respBody, resp.StatusCode, nil
 }
 
-func (h *DeployHandler) createDeployRun(ctx context.Context, tenantID, provider, targetName string) (string, error) {
+func (h *DeployHandler) createDeployRun(ctx context.Context, tenantID, provider, targetName, target string, notify deployNotifyTarget) (string, error) {
 	var runID string
 	err := h.db.QueryRowContext(ctx, `
-		INSERT INTO deployment_runs (tenant_id, provider, target_name, status)
-		VALUES ($1, $2, $3, 'queued')
+		INSERT INTO deployment_runs (tenant_id, provider, target_name, target, status, notify_url, notify_secret)
+		VALUES ($1, $2, $3, $4, 'queued', $5, $6)
 		RETURNING id
-	`, tenantID, provider, targetName).Scan(&runID)
+	`, tenantID, provider, targetName, target, emptyToNil(notify.URL), emptyToNil(notify.Secret)).Scan(&runID)
 	return runID, err
 }
 
+// appendDeployLog records a step of the run and notifies subscribers of it.
 func (h *DeployHandler) appendDeployLog(runID, message string) {
 	msg := strings.TrimSpace(message)
 	if msg == "" {
 		return
 	}
+	h.writeDeployLog(runID, msg)
+	h.notifyDeployStep(runID, msg)
+}
+
+func (h *DeployHandler) writeDeployLog(runID, msg string) {
 	_, _ = h.db.Exec(`
 		UPDATE deployment_runs
 		SET logs = COALESCE(logs, '[]'::jsonb) || jsonb_build_array(
@@ -549,12 +652,17 @@ func (h *DeployHandler) updateDeployRun(runID, status, externalID, errorMessage
 		    updated_at = NOW()
 		WHERE id = $1
 	`, runID, status, externalID, errorMessage)
+	h.notifyDeployStatus(runID, status, errorMessage)
 	return err
 }
 
+// failDeployRun logs the error without reporting it as a step, so the failure
+// notification names the last step that was reached.
 func (h *DeployHandler) failDeployRun(runID, message string) {
 	trimmed := strings.TrimSpace(message)
-	h.appendDeployLog(runID, trimmed)
+	if trimmed != "" {
+		h.writeDeployLog(runID, trimmed)
+	}
 	_ = h.updateDeployRun(runID, "failed", "", trimmed)
 }
 
@@ -572,56 +680,15 @@ func (h *DeployHandler) getStoredToken(tenantID, provider string) (string, error
 		return "", err
 	}
 
-	keyHex := strings.TrimSpace(os.Getenv("ENCRYPTION_KEY"))
-	if keyHex == "" {
-		return "", errors.New("ENCRYPTION_KEY is not configured")
-	}
-	key, err := hex.DecodeString(keyHex)
-	if err != nil || len(key) != 32 {
-		return "", errors.New("ENCRYPTION_KEY must be a 32-byte hex string")
-	}
-
-	plaintext, err := decryptToken(encrypted, key)
-	if err != nil {
-		return "", err
-	}
-	return strings.TrimSpace(plaintext), nil
-}
-
-func decryptToken(payload string, key []byte) (string, error) {
-	parts := strings.Split(payload, ":")
-	if len(parts) != 3 {
-		return "", errors.New("invalid encrypted payload format")
-	}
-
-	iv, err := base64.StdEncoding.DecodeString(parts[0])
-	if err != nil {
-		return "", err
-	}
-	ciphertext, err := base64.StdEncoding.DecodeString(parts[1])
-	if err != nil {
-		return "", err
-	}
-	tag, err := base64.StdEncoding.DecodeString(parts[2])
-	if err != nil {
-		return "", err
-	}
-
-	block, err := aes.NewCipher(key)
+	keys, err := keyring.FromEnv()
 	if err != nil {
 		return "", err
 	}
-	aead, err := cipher.NewGCM(block)
+	plaintext, err := keys.Decrypt(encrypted)
 	if err != nil {
 		return "", err
 	}
-
-	raw := append(ciphertext, tag...)
-	plaintext, err := aead.Open(nil, iv, raw, nil)
-	if err != nil {
-		return "", err
-	}
-	return string(plaintext), nil
+	return strings.TrimSpace(plaintext), nil
 }
 
 func parseRepo(repoURL string) (string, string) {
@@ -0,0 +1,443 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/keyring"
+	"github.com/agentsquads/api/policies"
+)
+
+const (
+	maxDeployEnvVars      = 100
+	maxDeployEnvValueSize = 64 << 10
+)
+
+var deployEnvKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,255}$`)
+
+type deployEnvVarInput struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret"`
+}
+
+type putDeployEnvRequest struct {
+	Vars []deployEnvVarInput `json:"vars"`
+}
+
+// deployEnvVar is a stored variable as the API shows it. Secret values are
+// never returned. Synced means the current value reached SyncedProject.
+type deployEnvVar struct {
+	Key           string     `json:"key"`
+	Value         string     `json:"value,omitempty"`
+	Secret        bool       `json:"secret"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Synced        bool       `json:"synced"`
+	SyncedAt      *time.Time `json:"synced_at,omitempty"`
+	SyncedProject string     `json:"synced_project,omitempty"`
+}
+
+type deployEnvResponse struct {
+	TenantID string         `json:"tenant_id"`
+	Vars     []deployEnvVar `json:"vars"`
+}
+
+// deployEnvTenant returns the request's tenant, writing the error response
+// when it is missing or outside the caller's tenant scope.
+func deployEnvTenant(w http.ResponseWriter, r *http.Request) (string, bool) {
+	tenantID := tenantIDFromRequest(r)
+	if tenantID == "" {
+		writeAPIError(w, http.StatusBadRequest, "tenant_id is required")
+		return "", false
+	}
+	if scoped := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); scoped != "" && scoped != tenantID {
+		writeAPIError(w, http.StatusNotFound, "tenant not found")
+		return "", false
+	}
+	return tenantID, true
+}
+
+// handleGetDeployEnv lists the tenant's deploy env vars and whether each has
+// been synced to Vercel since it last changed.
+func (h *DeployHandler) handleGetDeployEnv(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID, ok := deployEnvTenant(w, r)
+	if !ok {
+		return
+	}
+	h.writeDeployEnv(w, r, tenantID)
+}
+
+// handlePutDeployEnv sets the given vars, leaving the tenant's other vars
+// as they are. They reach Vercel on the next deploy.
+func (h *DeployHandler) handlePutDeployEnv(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID, ok := deployEnvTenant(w, r)
+	if !ok {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxDeployRequestBodyBytes)
+
+	var req putDeployEnvRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if len(req.Vars) == 0 {
+		writeAPIError(w, http.StatusBadRequest, "vars is required")
+		return
+	}
+	seen := make(map[string]bool, len(req.Vars))
+	for i := range req.Vars {
+		v := &req.Vars[i]
+		v.Key = strings.TrimSpace(v.Key)
+		switch {
+		case !deployEnvKeyPattern.MatchString(v.Key):
+			writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid env var key %q", v.Key))
+			return
+		case strings.HasPrefix(strings.ToUpper(v.Key), "VERCEL_"):
+			writeAPIError(w, http.StatusBadRequest, v.Key+" is reserved by Vercel")
+			return
+		case seen[v.Key]:
+			writeAPIError(w, http.StatusBadRequest, v.Key+" is set more than once")
+			return
+		case len(v.Value) > maxDeployEnvValueSize:
+			writeAPIError(w, http.StatusBadRequest, v.Key+" is too large")
+			return
+		}
+		seen[v.Key] = true
+	}
+
+	if !requireFeature(w, r, h.policies, tenantID, policies.FeatureDeploy, "deploy") {
+		return
+	}
+
+	keys, err := h.keys()
+	if err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "encryption is not configured")
+		return
+	}
+
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to save env vars")
+		return
+	}
+	defer tx.Rollback()
+
+	for _, v := range req.Vars {
+		encrypted, err := keys.Encrypt(v.Value)
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "failed to encrypt env vars")
+			return
+		}
+		if _, err := tx.ExecContext(r.Context(), `
+			INSERT INTO deploy_env_vars (tenant_id, key, value_encrypted, secret, deleted, updated_at)
+			VALUES ($1, $2, $3, $4, false, NOW())
+			ON CONFLICT (tenant_id, key) DO UPDATE
+			SET value_encrypted = EXCLUDED.value_encrypted,
+			    secret = EXCLUDED.secret,
+			    deleted = false,
+			    updated_at = NOW()
+		`, tenantID, v.Key, encrypted, v.Secret); err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "failed to save env vars")
+			return
+		}
+	}
+	var count int
+	if err := tx.QueryRowContext(r.Context(), `
+		SELECT COUNT(*) FROM deploy_env_vars WHERE tenant_id = $1 AND NOT deleted
+	`, tenantID).Scan(&count); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to save env vars")
+		return
+	}
+	if count > maxDeployEnvVars {
+		writeAPIError(w, http.StatusUnprocessableEntity, fmt.Sprintf("a project can have at most %d env vars", maxDeployEnvVars))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to save env vars")
+		return
+	}
+	h.writeDeployEnv(w, r, tenantID)
+}
+
+// handleDeleteDeployEnv removes a var. One that was already synced is kept as
+// a tombstone until the next deploy removes it from Vercel.
+func (h *DeployHandler) handleDeleteDeployEnv(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID, ok := deployEnvTenant(w, r)
+	if !ok {
+		return
+	}
+	key := strings.TrimSpace(r.URL.Query().Get("key"))
+	if key == "" {
+		writeAPIError(w, http.StatusBadRequest, "key is required")
+		return
+	}
+	if !requireFeature(w, r, h.policies, tenantID, policies.FeatureDeploy, "deploy") {
+		return
+	}
+
+	var syncedAt sql.NullTime
+	err := h.db.QueryRowContext(r.Context(), `
+		UPDATE deploy_env_vars
+		SET deleted = true, value_encrypted = NULL, updated_at = NOW()
+		WHERE tenant_id = $1 AND key = $2 AND NOT deleted
+		RETURNING synced_at
+	`, tenantID, key).Scan(&syncedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "env var not found")
+		return
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to delete env var")
+		return
+	}
+	if !syncedAt.Valid {
+		if _, err := h.db.ExecContext(r.Context(), `
+			DELETE FROM deploy_env_vars WHERE tenant_id = $1 AND key = $2 AND deleted
+		`, tenantID, key); err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "failed to delete env var")
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *DeployHandler) writeDeployEnv(w http.ResponseWriter, r *http.Request, tenantID string) {
+	rows, err := h.db.QueryContext(r.Context(), `
+		SELECT key, COALESCE(value_encrypted, ''), secret, updated_at, synced_at, COALESCE(synced_project, '')
+		FROM deploy_env_vars
+		WHERE tenant_id = $1 AND NOT deleted
+		ORDER BY key
+	`, tenantID)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to load env vars")
+		return
+	}
+	defer rows.Close()
+
+	res := deployEnvResponse{TenantID: tenantID, Vars: []deployEnvVar{}}
+	var encrypted []string
+	for rows.Next() {
+		var v deployEnvVar
+		var value string
+		var syncedAt sql.NullTime
+		if err := rows.Scan(&v.Key, &value, &v.Secret, &v.UpdatedAt, &syncedAt, &v.SyncedProject); err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "failed to load env vars")
+			return
+		}
+		if syncedAt.Valid {
+			v.SyncedAt = &syncedAt.Time
+			v.Synced = !syncedAt.Time.Before(v.UpdatedAt)
+		}
+		res.Vars = append(res.Vars, v)
+		encrypted = append(encrypted, value)
+	}
+	if err := rows.Err(); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to load env vars")
+		return
+	}
+
+	var keys *keyring.Keyring
+	for i := range res.Vars {
+		if res.Vars[i].Secret || encrypted[i] == "" {
+			continue
+		}
+		if keys == nil {
+			if keys, err = h.keys(); err != nil {
+				writeAPIError(w, http.StatusServiceUnavailable, "encryption is not configured")
+				return
+			}
+		}
+		if res.Vars[i].Value, err = keys.Decrypt(encrypted[i]); err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "failed to decrypt env vars")
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// storedEnvVar is a deploy env var as the pipeline syncs it.
+type storedEnvVar struct {
+	key       string
+	value     string
+	secret    bool
+	deleted   bool
+	updatedAt time.Time
+}
+
+// vercelEnv is a variable on a Vercel project. Vercel returns target as a
+// string or a list of strings.
+type vercelEnv struct {
+	ID     string          `json:"id"`
+	Key    string          `json:"key"`
+	Target json.RawMessage `json:"target"`
+}
+
+func (e vercelEnv) hasTarget(target string) bool {
+	var targets []string
+	if err := json.Unmarshal(e.Target, &targets); err != nil {
+		var single string
+		if json.Unmarshal(e.Target, &single) != nil {
+			return false
+		}
+		targets = []string{single}
+	}
+	for _, t := range targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+// syncVercelEnv makes the project's env vars for the deploy's Vercel target
+// match the tenant's stored set. Vars the project already has are updated in
+// place, since Vercel rejects creating a duplicate; secrets are stored as
+// encrypted vars; tombstoned vars are removed. Vars set on the project by
+// other means are left alone.
+func (h *DeployHandler) syncVercelEnv(runID, token string, req vercelDeployRequest) error {
+	ctx := context.Background()
+	vars, err := h.loadDeployEnv(ctx, req.TenantID)
+	if err != nil || len(vars) == 0 {
+		return err
+	}
+
+	project := url.PathEscape(req.ProjectName)
+	query := ""
+	if req.TeamID != "" {
+		query = "?teamId=" + url.QueryEscape(req.TeamID)
+	}
+	body, status, err := h.doJSONRequest(http.MethodGet, "https://api.vercel.com/v9/projects/"+project+"/env"+query, token, nil)
+	if err != nil {
+		return fmt.Errorf("list project env vars: %w", err)
+	}
+	if status >= http.StatusBadRequest {
+		return fmt.Errorf("list project env vars (%d): %s", status, trimBody(body))
+	}
+	var listed struct {
+		Envs []vercelEnv `json:"envs"`
+	}
+	if err := json.Unmarshal(body, &listed); err != nil {
+		return fmt.Errorf("decode project env vars: %w", err)
+	}
+
+	target := vercelTarget(req.Target)
+	existing := make(map[string]string, len(listed.Envs)) // key -> env id
+	for _, env := range listed.Envs {
+		if env.hasTarget(target) {
+			existing[env.Key] = env.ID
+		}
+	}
+
+	synced := 0
+	for _, v := range vars {
+		id, exists := existing[v.key]
+		envType := "plain"
+		if v.secret {
+			envType = "encrypted"
+		}
+		var method, endpoint string
+		var payload any
+		switch {
+		case v.deleted && !exists:
+			continue
+		case v.deleted:
+			method, endpoint = http.MethodDelete, "https://api.vercel.com/v9/projects/"+project+"/env/"+url.PathEscape(id)+query
+		case exists:
+			method, endpoint = http.MethodPatch, "https://api.vercel.com/v9/projects/"+project+"/env/"+url.PathEscape(id)+query
+			payload = map[string]any{"value": v.value, "type": envType}
+		default:
+			method, endpoint = http.MethodPost, "https://api.vercel.com/v10/projects/"+project+"/env"+query
+			payload = map[string]any{"key": v.key, "value": v.value, "type": envType, "target": []string{target}}
+		}
+		body, status, err := h.doJSONRequest(method, endpoint, token, payload)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", strings.ToLower(method), v.key, err)
+		}
+		if status >= http.StatusBadRequest {
+			return fmt.Errorf("%s %s (%d): %s", strings.ToLower(method), v.key, status, trimBody(body))
+		}
+		synced++
+	}
+
+	// A var changed while the sync ran keeps its newer value unsynced.
+	for _, v := range vars {
+		if v.deleted {
+			_, err = h.db.ExecContext(ctx, `
+				DELETE FROM deploy_env_vars WHERE tenant_id = $1 AND key = $2 AND deleted AND updated_at = $3
+			`, req.TenantID, v.key, v.updatedAt)
+		} else {
+			_, err = h.db.ExecContext(ctx, `
+				UPDATE deploy_env_vars
+				SET synced_at = NOW(), synced_project = $4
+				WHERE tenant_id = $1 AND key = $2 AND updated_at = $3 AND NOT deleted
+			`, req.TenantID, v.key, v.updatedAt, req.ProjectName)
+		}
+		if err != nil {
+			return fmt.Errorf("record env var sync: %w", err)
+		}
+	}
+	h.appendDeployLog(runID, fmt.Sprintf("Synced %d environment variables to Vercel", synced))
+	return nil
+}
+
+func (h *DeployHandler) loadDeployEnv(ctx context.Context, tenantID string) ([]storedEnvVar, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT key, COALESCE(value_encrypted, ''), secret, deleted, updated_at
+		FROM deploy_env_vars
+		WHERE tenant_id = $1
+		ORDER BY key
+	`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("load env vars: %w", err)
+	}
+	defer rows.Close()
+
+	var vars []storedEnvVar
+	for rows.Next() {
+		var v storedEnvVar
+		if err := rows.Scan(&v.key, &v.value, &v.secret, &v.deleted, &v.updatedAt); err != nil {
+			return nil, fmt.Errorf("load env vars: %w", err)
+		}
+		vars = append(vars, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("load env vars: %w", err)
+	}
+
+	var keys *keyring.Keyring
+	for i := range vars {
+		if vars[i].deleted {
+			continue
+		}
+		if keys == nil {
+			if keys, err = h.keys(); err != nil {
+				return nil, err
+			}
+		}
+		if vars[i].value, err = keys.Decrypt(vars[i].value); err != nil {
+			return nil, fmt.Errorf("decrypt %s: %w", vars[i].key, err)
+		}
+	}
+	return vars, nil
+}
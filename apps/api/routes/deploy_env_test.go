@@ -0,0 +1,174 @@
+package routes
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/keyring"
+)
+
+func newDeployEnvHandler(t *testing.T) (*DeployHandler, sqlmock.Sqlmock, *keyring.Keyring) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	keys, err := keyring.New(strings.Repeat("cd", 32))
+	if err != nil {
+		t.Fatalf("keyring.New: %v", err)
+	}
+	h := NewDeployHandler(db)
+	h.keys = func() (*keyring.Keyring, error) { return keys, nil }
+	return h, mock, keys
+}
+
+func TestDeployEnvRoutes(t *testing.T) {
+	t.Parallel()
+	h, mock, keys := newDeployEnvHandler(t)
+	mux := http.NewServeMux()
+	h.Mount(mux)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, body := range []string{
+		`{"vars":[]}`,
+		`{"vars":[{"key":"1BAD","value":"x"}]}`,
+		`{"vars":[{"key":"VERCEL_URL","value":"x"}]}`,
+		`{"vars":[{"key":"A","value":"1"},{"key":"A","value":"2"}]}`,
+	} {
+		if w := do(http.MethodPut, "/api/deploy/env?tenant_id=t1", body); w.Code != http.StatusBadRequest {
+			t.Fatalf("body %s status = %d", body, w.Code)
+		}
+	}
+	if w := do(http.MethodGet, "/api/deploy/env", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing tenant status = %d", w.Code)
+	}
+
+	encrypted, _ := keys.Encrypt("https://db.example.com")
+	secret, _ := keys.Encrypt("sk_live")
+	updated := time.Now().Add(-time.Hour)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO deploy_env_vars").WithArgs("t1", "API_URL", sqlmock.AnyArg(), false).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO deploy_env_vars").WithArgs("t1", "STRIPE_KEY", sqlmock.AnyArg(), true).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT COUNT").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectCommit()
+	mock.ExpectQuery("FROM deploy_env_vars").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{
+		"key", "value_encrypted", "secret", "updated_at", "synced_at", "synced_project",
+	}).AddRow("API_URL", encrypted, false, updated, updated.Add(time.Minute), "shop").
+		AddRow("STRIPE_KEY", secret, true, updated, nil, ""))
+	w := do(http.MethodPut, "/api/deploy/env?tenant_id=t1", `{"vars":[{"key":"API_URL","value":"https://db.example.com"},{"key":"STRIPE_KEY","value":"sk_live","secret":true}]}`)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "sk_live") {
+		t.Fatalf("put status = %d body=%s", w.Code, w.Body.String())
+	}
+	var res deployEnvResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || len(res.Vars) != 2 {
+		t.Fatalf("decode: %v body=%s", err, w.Body.String())
+	}
+	if v := res.Vars[0]; v.Value != "https://db.example.com" || !v.Synced || v.SyncedProject != "shop" {
+		t.Fatalf("API_URL = %+v", v)
+	}
+	if v := res.Vars[1]; v.Value != "" || v.Synced || v.SyncedAt != nil {
+		t.Fatalf("STRIPE_KEY = %+v", v)
+	}
+
+	// A synced var stays as a tombstone for the next deploy to remove.
+	mock.ExpectQuery("UPDATE deploy_env_vars").WithArgs("t1", "API_URL").WillReturnRows(sqlmock.NewRows([]string{"synced_at"}).AddRow(updated))
+	if w := do(http.MethodDelete, "/api/deploy/env?tenant_id=t1&key=API_URL", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete synced status = %d", w.Code)
+	}
+	mock.ExpectQuery("UPDATE deploy_env_vars").WithArgs("t1", "STRIPE_KEY").WillReturnRows(sqlmock.NewRows([]string{"synced_at"}).AddRow(nil))
+	mock.ExpectExec("DELETE FROM deploy_env_vars").WithArgs("t1", "STRIPE_KEY").WillReturnResult(sqlmock.NewResult(0, 1))
+	if w := do(http.MethodDelete, "/api/deploy/env?tenant_id=t1&key=STRIPE_KEY", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete unsynced status = %d", w.Code)
+	}
+	mock.ExpectQuery("UPDATE deploy_env_vars").WithArgs("t1", "NOPE").WillReturnRows(sqlmock.NewRows([]string{"synced_at"}))
+	if w := do(http.MethodDelete, "/api/deploy/env?tenant_id=t1&key=NOPE", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("delete missing status = %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestSyncVercelEnv(t *testing.T) {
+	t.Parallel()
+	h, mock, keys := newDeployEnvHandler(t)
+	existing, _ := keys.Encrypt("https://db.example.com")
+	added, _ := keys.Encrypt("sk_live")
+	updated := time.Now()
+	envRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"key", "value_encrypted", "secret", "deleted", "updated_at"}).
+			AddRow("API_URL", existing, false, false, updated).
+			AddRow("OLD_FLAG", "", false, true, updated).
+			AddRow("STRIPE_KEY", added, true, false, updated)
+	}
+
+	type call struct {
+		method, path string
+		body         map[string]any
+	}
+	var calls []call
+	failPost := false
+	h.httpClient = &http.Client{Transport: graphTransport(func(r *http.Request) (*http.Response, error) {
+		c := call{method: r.Method, path: r.URL.Path}
+		if r.Body != nil {
+			_ = json.NewDecoder(r.Body).Decode(&c.body)
+		}
+		calls = append(calls, c)
+		status, body := http.StatusOK, `{}`
+		switch {
+		case r.Method == http.MethodGet:
+			body = `{"envs":[{"id":"env1","key":"API_URL","target":["production","preview"]},{"id":"env2","key":"OLD_FLAG","target":"production"},{"id":"env3","key":"STRIPE_KEY","target":["preview"]}]}`
+		case r.Method == http.MethodPost && failPost:
+			status, body = http.StatusBadRequest, `{"error":{"code":"ENV_CONFLICT"}}`
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+	})}
+
+	mock.ExpectQuery("FROM deploy_env_vars").WithArgs("t1").WillReturnRows(envRows())
+	mock.ExpectExec("UPDATE deploy_env_vars").WithArgs("t1", "API_URL", updated, "shop").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM deploy_env_vars").WithArgs("t1", "OLD_FLAG", updated).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE deploy_env_vars").WithArgs("t1", "STRIPE_KEY", updated, "shop").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE deployment_runs").WithArgs("run1", "Synced 3 environment variables to Vercel").WillReturnResult(sqlmock.NewResult(0, 1))
+	req := vercelDeployRequest{TenantID: "t1", ProjectName: "shop", Target: deployTargetProduction}
+	if err := h.syncVercelEnv("run1", "tok", req); err != nil {
+		t.Fatalf("syncVercelEnv: %v", err)
+	}
+
+	if len(calls) != 4 {
+		t.Fatalf("calls = %+v", calls)
+	}
+	if c := calls[0]; c.method != http.MethodGet || c.path != "/v9/projects/shop/env" {
+		t.Fatalf("list call = %+v", c)
+	}
+	if c := calls[1]; c.method != http.MethodPatch || c.path != "/v9/projects/shop/env/env1" || c.body["value"] != "https://db.example.com" || c.body["type"] != "plain" {
+		t.Fatalf("update call = %+v", c)
+	}
+	if c := calls[2]; c.method != http.MethodDelete || c.path != "/v9/projects/shop/env/env2" {
+		t.Fatalf("delete call = %+v", c)
+	}
+	// STRIPE_KEY only exists for preview, so production gets a new var.
+	if c := calls[3]; c.method != http.MethodPost || c.path != "/v10/projects/shop/env" || c.body["type"] != "encrypted" || c.body["key"] != "STRIPE_KEY" {
+		t.Fatalf("create call = %+v", c)
+	}
+
+	calls, failPost = nil, true
+	mock.ExpectQuery("FROM deploy_env_vars").WithArgs("t1").WillReturnRows(envRows())
+	if err := h.syncVercelEnv("run1", "tok", req); err == nil || !strings.Contains(err.Error(), "STRIPE_KEY") {
+		t.Fatalf("expected failed sync, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
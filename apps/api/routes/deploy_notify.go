@@ -0,0 +1,282 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/channels"
+)
+
+const (
+	deployNotifySignatureHeader = "X-AgentSquads-Signature"
+	deployNotifyMaxAttempts     = 4
+	deployNotifyBaseDelay       = time.Second
+	deployNotifyTimeout         = 10 * time.Second
+	deployNotifyQueueSize       = 32
+)
+
+// deploymentEvent is the JSON body POSTed to a run's notify_url. Step events
+// carry a progress message; status events carry the new run status and, for
+// failures, the error.
+type deploymentEvent struct {
+	Event        string    `json:"event"`
+	DeploymentID string    `json:"deployment_id"`
+	TenantID     string    `json:"tenant_id"`
+	Provider     string    `json:"provider"`
+	TargetName   string    `json:"target_name"`
+	Step         string    `json:"step,omitempty"`
+	Status       string    `json:"status"`
+	Message      string    `json:"message,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// deploymentNotification records the outcome of delivering one event.
+type deploymentNotification struct {
+	Event     string    `json:"event"`
+	Step      string    `json:"step,omitempty"`
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"`
+	Delivered bool      `json:"delivered"`
+	LastError string    `json:"last_error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type deployNotifyTarget struct {
+	URL    string
+	Secret string
+}
+
+// deployNotifier delivers one run's events in order on its own goroutine, so
+// a slow or failing receiver never holds up the deployment itself.
+type deployNotifier struct {
+	runID      string
+	tenantID   string
+	provider   string
+	targetName string
+	target     deployNotifyTarget
+	status     string
+	lastStep   string
+	events     chan deploymentEvent
+}
+
+// validateDeployNotify checks the optional notify_url and notify_secret of a
+// deploy request.
+func validateDeployNotify(rawURL, secret string) (deployNotifyTarget, error) {
+	target := deployNotifyTarget{URL: strings.TrimSpace(rawURL), Secret: strings.TrimSpace(secret)}
+	if target.URL == "" {
+		if target.Secret != "" {
+			return target, errors.New("notify_secret requires notify_url")
+		}
+		return target, nil
+	}
+	parsed, err := url.Parse(target.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return target, errors.New("notify_url must be an absolute http(s) URL")
+	}
+	return target, nil
+}
+
+// startDeployNotifier registers the run's notifier. Nothing is started when
+// there is neither a notify_url nor a fanout to publish to.
+func (h *DeployHandler) startDeployNotifier(runID, tenantID, provider, targetName string, target deployNotifyTarget) {
+	if target.URL == "" && h.redis == nil {
+		return
+	}
+	n := &deployNotifier{
+		runID:      runID,
+		tenantID:   tenantID,
+		provider:   provider,
+		targetName: targetName,
+		target:     target,
+		status:     "queued",
+		events:     make(chan deploymentEvent, deployNotifyQueueSize),
+	}
+	h.notifiers.Store(runID, n)
+	go h.runDeployNotifier(n)
+}
+
+// notifyDeployStep queues a step event. Steps are reported with the run's
+// current status.
+func (h *DeployHandler) notifyDeployStep(runID, step string) {
+	value, ok := h.notifiers.Load(runID)
+	if !ok {
+		return
+	}
+	n := value.(*deployNotifier)
+	n.lastStep = step
+	n.events <- n.event("step", step, step)
+}
+
+// notifyDeployStatus queues a status event. A terminal status closes the
+// notifier once its remaining events are delivered.
+func (h *DeployHandler) notifyDeployStatus(runID, status, message string) {
+	value, ok := h.notifiers.Load(runID)
+	if !ok {
+		return
+	}
+	n := value.(*deployNotifier)
+	n.status = status
+	n.events <- n.event("status", n.lastStep, message)
+	if status == "succeeded" || status == "failed" {
+		h.notifiers.Delete(runID)
+		close(n.events)
+	}
+}
+
+func (n *deployNotifier) event(kind, step, message string) deploymentEvent {
+	return deploymentEvent{
+		Event:        kind,
+		DeploymentID: n.runID,
+		TenantID:     n.tenantID,
+		Provider:     n.provider,
+		TargetName:   n.targetName,
+		Step:         step,
+		Status:       n.status,
+		Message:      message,
+		Timestamp:    time.Now().UTC(),
+	}
+}
+
+func (h *DeployHandler) runDeployNotifier(n *deployNotifier) {
+	ctx := context.Background()
+	for evt := range n.events {
+		if n.target.URL != "" {
+			h.recordDeployNotification(ctx, n.runID, h.deliverDeployEvent(ctx, n.target, evt))
+		}
+		h.publishDeployEvent(ctx, evt)
+	}
+}
+
+// deliverDeployEvent POSTs evt to the run's notify_url, retrying network
+// errors, 429s and 5xx responses with exponential backoff. When a secret is
+// set the body is signed as "sha256=<hex HMAC-SHA256>".
+func (h *DeployHandler) deliverDeployEvent(ctx context.Context, target deployNotifyTarget, evt deploymentEvent) deploymentNotification {
+	result := deploymentNotification{Event: evt.Event, Step: evt.Step, Status: evt.Status, Timestamp: evt.Timestamp}
+	body, err := json.Marshal(evt)
+	if err != nil {
+		result.LastError = err.Error()
+		return result
+	}
+	sleep := h.sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+
+	delay := deployNotifyBaseDelay
+	for result.Attempts < deployNotifyMaxAttempts {
+		if result.Attempts > 0 {
+			sleep(delay)
+			delay *= 2
+		}
+		result.Attempts++
+		retry, err := h.postDeployEvent(ctx, target, body)
+		if err == nil {
+			result.Delivered = true
+			result.LastError = ""
+			return result
+		}
+		result.LastError = err.Error()
+		if !retry {
+			break
+		}
+	}
+	slog.Warn("deploy notification not delivered", "deployment_id", evt.DeploymentID, "event", evt.Event, "attempts", result.Attempts, "err", result.LastError)
+	return result
+}
+
+func (h *DeployHandler) postDeployEvent(ctx context.Context, target deployNotifyTarget, body []byte) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, deployNotifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if target.Secret != "" {
+		req.Header.Set(deployNotifySignatureHeader, signDeployEvent(target.Secret, body))
+	}
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+	return retry, fmt.Errorf("notify_url returned status %d", resp.StatusCode)
+}
+
+func signDeployEvent(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (h *DeployHandler) recordDeployNotification(ctx context.Context, runID string, result deploymentNotification) {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	if _, err := h.db.ExecContext(ctx, `
+		UPDATE deployment_runs
+		SET notifications = COALESCE(notifications, '[]'::jsonb) || jsonb_build_array($2::jsonb)
+		WHERE id = $1
+	`, runID, string(encoded)); err != nil {
+		slog.Error("failed to record deploy notification", "deployment_id", runID, "err", err)
+	}
+}
+
+// publishDeployEvent sends evt to every linked chat of the tenant. Steps are
+// streamed so channels that support it edit one progress message in place.
+func (h *DeployHandler) publishDeployEvent(ctx context.Context, evt deploymentEvent) {
+	if h.redis == nil {
+		return
+	}
+	final := evt.Event == "status" && (evt.Status == "succeeded" || evt.Status == "failed")
+	out := channels.OutboundMessage{
+		TenantID: evt.TenantID,
+		Content:  deployEventText(evt),
+		Stream:   !final,
+		Metadata: map[string]string{
+			"deployment_id": evt.DeploymentID,
+			"event":         evt.Event,
+			"status":        evt.Status,
+		},
+	}
+	if err := channels.PublishOutbound(ctx, h.redis, out); err != nil {
+		slog.Error("failed to publish deploy update", "deployment_id", evt.DeploymentID, "err", err)
+	}
+}
+
+func deployEventText(evt deploymentEvent) string {
+	subject := fmt.Sprintf("Your %s deploy of %s", evt.Provider, evt.TargetName)
+	if evt.Event == "step" {
+		return subject + ": " + evt.Step
+	}
+	switch evt.Status {
+	case "running":
+		return subject + " has started"
+	case "succeeded":
+		return subject + " succeeded"
+	case "failed":
+		if evt.Step != "" {
+			return fmt.Sprintf("%s failed at %q: %s", subject, evt.Step, evt.Message)
+		}
+		return subject + " failed: " + evt.Message
+	default:
+		return subject + " is " + evt.Status
+	}
+}
@@ -0,0 +1,150 @@
+package routes
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestValidateDeployNotify(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		url, secret string
+		wantErr     bool
+	}{
+		{},
+		{url: "https://example.com/hook", secret: "s3cret"},
+		{url: "http://example.com/hook"},
+		{secret: "s3cret", wantErr: true},
+		{url: "ftp://example.com/hook", wantErr: true},
+		{url: "/relative", wantErr: true},
+	} {
+		if _, err := validateDeployNotify(tt.url, tt.secret); (err != nil) != tt.wantErr {
+			t.Fatalf("validateDeployNotify(%q, %q) err = %v", tt.url, tt.secret, err)
+		}
+	}
+}
+
+func TestDeliverDeployEventSignsAndRetries(t *testing.T) {
+	t.Parallel()
+	var calls int
+	var gotBody []byte
+	var gotSignature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSignature = r.Header.Get(deployNotifySignatureHeader)
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	h := NewDeployHandler(nil)
+	h.sleep = func(d time.Duration) { delays = append(delays, d) }
+
+	evt := deploymentEvent{Event: "status", DeploymentID: "run-1", TenantID: "t1", Provider: "supabase", TargetName: "shop", Step: "Running migration 2", Status: "failed", Message: "migration 2 failed"}
+	result := h.deliverDeployEvent(t.Context(), deployNotifyTarget{URL: server.URL, Secret: "s3cret"}, evt)
+
+	if !result.Delivered || result.Attempts != 3 || result.LastError != "" {
+		t.Fatalf("result = %+v", result)
+	}
+	if len(delays) != 2 || delays[0] != deployNotifyBaseDelay || delays[1] != 2*deployNotifyBaseDelay {
+		t.Fatalf("delays = %v", delays)
+	}
+	if gotSignature != signDeployEvent("s3cret", gotBody) || !strings.HasPrefix(gotSignature, "sha256=") {
+		t.Fatalf("signature = %q", gotSignature)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(gotBody, &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	for _, key := range []string{"event", "deployment_id", "tenant_id", "step", "status", "message", "timestamp"} {
+		if _, ok := payload[key]; !ok {
+			t.Fatalf("payload missing %q: %s", key, gotBody)
+		}
+	}
+}
+
+func TestDeliverDeployEventStopsOnClientError(t *testing.T) {
+	t.Parallel()
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get(deployNotifySignatureHeader) != "" {
+			t.Errorf("unexpected signature without secret")
+		}
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	h := NewDeployHandler(nil)
+	h.sleep = func(time.Duration) {}
+	result := h.deliverDeployEvent(t.Context(), deployNotifyTarget{URL: server.URL}, deploymentEvent{Event: "step"})
+	if result.Delivered || result.Attempts != 1 || calls != 1 || !strings.Contains(result.LastError, "410") {
+		t.Fatalf("result = %+v calls=%d", result, calls)
+	}
+}
+
+func TestDeployNotifierRecordsEventsInOrder(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	var events []deploymentEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var evt deploymentEvent
+		json.NewDecoder(r.Body).Decode(&evt)
+		events = append(events, evt)
+	}))
+	defer server.Close()
+
+	for range 3 {
+		mock.ExpectExec("UPDATE deployment_runs SET notifications").WithArgs("run-1", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	h := NewDeployHandler(db)
+	h.startDeployNotifier("run-1", "t1", "vercel", "shop", deployNotifyTarget{URL: server.URL})
+	value, _ := h.notifiers.Load("run-1")
+	n := value.(*deployNotifier)
+	h.notifyDeployStatus("run-1", "running", "")
+	h.notifyDeployStep("run-1", "Vercel token verified")
+	h.notifyDeployStatus("run-1", "failed", "create Vercel project failed (403)")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for mock.ExpectationsWereMet() != nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+	if _, ok := h.notifiers.Load("run-1"); ok {
+		t.Fatal("notifier still registered after terminal status")
+	}
+	if _, open := <-n.events; open {
+		t.Fatal("events channel still open")
+	}
+	if len(events) != 3 || events[0].Status != "running" || events[1].Step != "Vercel token verified" || events[2].Status != "failed" || events[2].Step != "Vercel token verified" {
+		t.Fatalf("events = %+v", events)
+	}
+}
+
+func TestDeployEventText(t *testing.T) {
+	t.Parallel()
+	got := deployEventText(deploymentEvent{Event: "status", Provider: "supabase", TargetName: "shop", Step: "Running migration 2", Status: "failed", Message: "migration 2 failed (400)"})
+	want := `Your supabase deploy of shop failed at "Running migration 2": migration 2 failed (400)`
+	if got != want {
+		t.Fatalf("text = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"errors"
+	"strings"
+)
+
+// Deploy targets. Production deploys use the project name as given; preview
+// and staging deploy to their own projects, so a tenant can keep separate
+// stacks side by side.
+const (
+	deployTargetProduction = "production"
+	deployTargetPreview    = "preview"
+	deployTargetStaging    = "staging"
+)
+
+// parseDeployTarget validates a request's target, defaulting to production.
+func parseDeployTarget(raw string) (string, error) {
+	switch target := strings.ToLower(strings.TrimSpace(raw)); target {
+	case "":
+		return deployTargetProduction, nil
+	case deployTargetProduction, deployTargetPreview, deployTargetStaging:
+		return target, nil
+	default:
+		return "", errors.New("target must be production, preview, or staging")
+	}
+}
+
+// targetProjectName is the provider project a target deploys to: name for
+// production, preview-name for preview and name-staging for staging.
+func targetProjectName(name, target string) string {
+	switch target {
+	case deployTargetPreview:
+		return "preview-" + name
+	case deployTargetStaging:
+		return name + "-staging"
+	default:
+		return name
+	}
+}
+
+// vercelTarget is the Vercel deployment target for a deploy target. Staging
+// has its own project, which it deploys to as production.
+func vercelTarget(target string) string {
+	if target == deployTargetPreview {
+		return deployTargetPreview
+	}
+	return deployTargetProduction
+}
@@ -43,3 +43,28 @@ func TestDeployValidationErrors(t *testing.T) {
 		t.Fatalf("status=%d", w.Code)
 	}
 }
+
+func TestDeployTargetProjectsAndVercelBody(t *testing.T) {
+	t.Parallel()
+	if _, err := parseDeployTarget("qa"); err == nil {
+		t.Fatal("unknown target accepted")
+	}
+	for raw, want := range map[string]string{"": "shop", "production": "shop", "Preview": "preview-shop", "staging": "shop-staging"} {
+		target, err := parseDeployTarget(raw)
+		if err != nil {
+			t.Fatalf("parseDeployTarget(%q): %v", raw, err)
+		}
+		if got := targetProjectName("shop", target); got != want {
+			t.Errorf("%q: project = %q, want %q", raw, got, want)
+		}
+	}
+
+	body := vercelDeployBody(vercelDeployRequest{ProjectName: "preview-shop", Target: deployTargetPreview, Env: map[string]string{"A": "1"}})
+	env, _ := body["env"].([]map[string]string)
+	if body["target"] != "preview" || body["project"] != "preview-shop" || len(env) != 1 || env[0]["target"] != "preview" {
+		t.Fatalf("preview body = %+v", body)
+	}
+	if body := vercelDeployBody(vercelDeployRequest{ProjectName: "shop-staging", Target: deployTargetStaging}); body["target"] != "production" {
+		t.Fatalf("staging body = %+v", body)
+	}
+}
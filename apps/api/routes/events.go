@@ -15,8 +15,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/docker/docker/client"
-	"github.com/docker/go-connections/nat"
+	"github.com/agentsquads/api/orchestrator"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -39,6 +38,7 @@ type Event struct {
 // EventsHandler proxies tenant-scoped OpenFang SSE events to authenticated API clients.
 type EventsHandler struct {
 	DB        *sql.DB
+	Orch      orchestrator.TenantOrchestrator
 	Client    *http.Client
 	JWTSecret string
 }
@@ -276,103 +276,40 @@ func extractDataPayload(block []string) string {
 	return strings.Join(parts, "\n")
 }
 
+// resolveUpstreamURL finds the tenant's OpenFang event stream through the
+// orchestrator, falling back to the default port on localhost when the
+// orchestrator cannot resolve it.
 func (h *EventsHandler) resolveUpstreamURL(ctx context.Context, tenantID string) (string, error) {
-	port, err := h.resolveTenantPort(ctx, tenantID)
-	if err != nil {
-		return "", err
+	if fixedPort, ok := readPortEnv("OPENFANG_EVENTS_PORT"); ok {
+		return fmt.Sprintf("http://localhost:%d%s", fixedPort, openFangSSEPath), nil
 	}
-	return fmt.Sprintf("http://localhost:%d%s", port, openFangSSEPath), nil
-}
 
-func (h *EventsHandler) resolveTenantPort(ctx context.Context, tenantID string) (int, error) {
-	if fixedPort, ok := readPortEnv("OPENFANG_EVENTS_PORT"); ok {
-		return fixedPort, nil
+	endpoint, err := orchestrator.TenantEndpoint(ctx, h.Orch, tenantID)
+	if err == nil {
+		return endpoint.JoinPath(openFangSSEPath).String(), nil
 	}
 
 	defaultPort := defaultOpenFangPort
 	if configuredPort, ok := readPortEnv("OPENFANG_CONTAINER_PORT"); ok {
 		defaultPort = configuredPort
 	}
-
-	if h.DB == nil {
-		return defaultPort, nil
-	}
-
-	containerID, err := lookupTenantContainerID(ctx, h.DB, tenantID)
-	if err != nil {
-		return 0, err
-	}
-	if containerID == "" {
-		return defaultPort, nil
-	}
-
-	hostPort, err := lookupPublishedContainerPort(ctx, containerID, defaultPort)
-	if err != nil {
-		slog.Warn("failed to resolve tenant host port from Docker; falling back to default",
+	if !errors.Is(err, orchestrator.ErrNoEndpoint) {
+		slog.Warn("failed to resolve tenant endpoint; falling back to default port",
 			"tenant", tenantID,
-			"container", containerID,
 			"default_port", defaultPort,
 			"err", err,
 		)
-		return defaultPort, nil
-	}
-	return hostPort, nil
-}
-
-func lookupTenantContainerID(ctx context.Context, db *sql.DB, tenantID string) (string, error) {
-	var containerID sql.NullString
-	err := db.QueryRowContext(ctx, `SELECT container_id FROM tenants WHERE id = $1`, tenantID).Scan(&containerID)
-	if err == sql.ErrNoRows {
-		return "", errors.New("tenant not found")
-	}
-	if err != nil {
-		return "", fmt.Errorf("resolve tenant container: %w", err)
-	}
-	if !containerID.Valid {
-		return "", nil
 	}
-	return strings.TrimSpace(containerID.String), nil
+	return fmt.Sprintf("http://localhost:%d%s", defaultPort, openFangSSEPath), nil
 }
 
-func lookupPublishedContainerPort(ctx context.Context, containerID string, containerPort int) (int, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-	if err != nil {
-		return 0, fmt.Errorf("docker client: %w", err)
-	}
-	defer cli.Close()
-
-	info, err := cli.ContainerInspect(ctx, containerID)
-	if err != nil {
-		return 0, fmt.Errorf("docker inspect: %w", err)
-	}
-	if info.NetworkSettings == nil {
-		return 0, errors.New("container network settings are missing")
-	}
-
-	portKey := nat.Port(fmt.Sprintf("%d/tcp", containerPort))
-	bindings := info.NetworkSettings.Ports[portKey]
-	if len(bindings) == 0 {
-		return 0, fmt.Errorf("no host binding for %s", portKey)
-	}
-
-	for _, binding := range bindings {
-		hostPortText := strings.TrimSpace(binding.HostPort)
-		if hostPortText == "" {
-			continue
-		}
-		hostPort, err := strconv.Atoi(hostPortText)
-		if err != nil {
-			continue
-		}
-		if hostPort > 0 {
-			return hostPort, nil
-		}
-	}
-
-	return 0, fmt.Errorf("invalid host port binding for %s", portKey)
+func (h *EventsHandler) extractTenantIDFromJWT(r *http.Request) (string, error) {
+	return tenantIDFromJWT(r, h.JWTSecret)
 }
 
-func (h *EventsHandler) extractTenantIDFromJWT(r *http.Request) (string, error) {
+// tenantIDFromJWT returns the tenant_id claim of the request's bearer token,
+// verified with secret or API_JWT_SECRET.
+func tenantIDFromJWT(r *http.Request, secret string) (string, error) {
 	token := strings.TrimSpace(r.Header.Get("Authorization"))
 	if token == "" || !strings.HasPrefix(strings.ToLower(token), "bearer ") {
 		return "", errors.New("missing bearer token")
@@ -382,7 +319,7 @@ func (h *EventsHandler) extractTenantIDFromJWT(r *http.Request) (string, error)
 		return "", errors.New("missing bearer token")
 	}
 
-	jwtSecret := strings.TrimSpace(h.JWTSecret)
+	jwtSecret := strings.TrimSpace(secret)
 	if jwtSecret == "" {
 		jwtSecret = strings.TrimSpace(os.Getenv("API_JWT_SECRET"))
 	}
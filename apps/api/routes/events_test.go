@@ -1,8 +1,15 @@
 package routes
 
 import (
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
+
+	"github.com/agentsquads/api/internal/testkit"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 func TestParseTypeFilter(t *testing.T) {
@@ -54,3 +61,59 @@ func TestExtractDataPayload(t *testing.T) {
 		t.Fatalf("payload=%q", payload)
 	}
 }
+
+func TestEventsStreamFiltersUpstreamEvents(t *testing.T) {
+	t.Parallel()
+	var connections atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != openFangSSEPath {
+			t.Errorf("upstream path = %s", r.URL.Path)
+		}
+		// The first stream ends after three blocks; the reconnect is refused
+		// so the handler gives up.
+		if connections.Add(1) > 1 {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "event: hand\ndata: {\"type\":\"hand\"}\n\n")
+		_, _ = io.WriteString(w, "event: log\ndata: {\"type\":\"log\"}\n\n")
+		_, _ = io.WriteString(w, ": keep-alive\n\n")
+	}))
+	defer upstream.Close()
+
+	orch := testkit.NewFakeOrchestrator()
+	orch.AddTenant("t1", upstream.URL)
+	h := NewEventsHandler(nil)
+	h.Orch = orch
+	h.Client = upstream.Client()
+	h.JWTSecret = "secret"
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"tenant_id": "t1"}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/events/stream?types=hand", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	if !strings.Contains(body, "event: hand\n") || strings.Contains(body, "event: log") {
+		t.Fatalf("type filter not applied: %q", body)
+	}
+	if !strings.Contains(body, ": keep-alive\n") {
+		t.Fatalf("keep-alive dropped: %q", body)
+	}
+	if !strings.Contains(body, "event: error\n") || connections.Load() != 2 {
+		t.Fatalf("expected one reconnect then an error event, connections=%d body=%q", connections.Load(), body)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/events/stream", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated status = %d", w.Code)
+	}
+}
@@ -0,0 +1,184 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/orchestrator"
+)
+
+// ContainerHandler serves tenant self-service views of their container.
+type ContainerHandler struct {
+	DB        *sql.DB
+	Orch      orchestrator.TenantOrchestrator
+	JWTSecret string
+}
+
+func NewContainerHandler(db *sql.DB) *ContainerHandler {
+	return &ContainerHandler{DB: db}
+}
+
+func (h *ContainerHandler) Mount(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/tenants/{id}/container/exec-history", h.handleExecHistory)
+	mux.HandleFunc("GET /api/tenants/{id}/container/ports", h.handleContainerPorts)
+}
+
+// handleContainerPorts lists the ports the tenant's container exposes. The
+// caller must hold a JWT for the tenant. When the container is not running,
+// only the persisted container port is known and host_port is null.
+func (h *ContainerHandler) handleContainerPorts(w http.ResponseWriter, r *http.Request) {
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	claimed, err := tenantIDFromJWT(r, h.JWTSecret)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	if claimed != tenantID {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+
+	if lister, ok := orchestrator.Capability[orchestrator.PortLister](h.Orch); ok {
+		ports, err := lister.ContainerPorts(r.Context(), tenantID)
+		switch {
+		case err == nil:
+			writeJSON(w, http.StatusOK, ports)
+			return
+		case isNoContainerError(err):
+			writeError(w, http.StatusNotFound, "container not found")
+			return
+		case !errors.Is(err, orchestrator.ErrContainerNotRunning):
+			slog.Error("list container ports failed", "tenant", tenantID, "err", err)
+			writeError(w, http.StatusBadGateway, "failed to list container ports")
+			return
+		}
+	}
+	h.writePersistedPorts(w, r, tenantID)
+}
+
+func (h *ContainerHandler) writePersistedPorts(w http.ResponseWriter, r *http.Request, tenantID string) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	var (
+		containerID sql.NullString
+		port        sql.NullInt64
+	)
+	err := h.DB.QueryRowContext(r.Context(),
+		`SELECT container_id, container_port FROM tenants WHERE id = $1`, tenantID,
+	).Scan(&containerID, &port)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load container ports")
+		return
+	}
+	if !containerID.Valid || containerID.String == "" {
+		writeError(w, http.StatusNotFound, "container not found")
+		return
+	}
+	ports := []orchestrator.ContainerPort{}
+	if port.Valid {
+		ports = append(ports, orchestrator.ContainerPort{ContainerPort: int(port.Int64), Protocol: "tcp"})
+	}
+	writeJSON(w, http.StatusOK, ports)
+}
+
+// handleExecHistory lists commands run in the tenant's own container. A
+// caller scoped to another tenant by X-Tenant-ID gets 404, as for swarm
+// tasks.
+func (h *ContainerHandler) handleExecHistory(w http.ResponseWriter, r *http.Request) {
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if scoped := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); scoped != "" && scoped != tenantID {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	writeExecHistory(w, r, h.DB, tenantID)
+}
+
+// handleExecHistory lists commands run in any tenant's container for
+// security audits.
+func (h *AdminHandler) handleExecHistory(w http.ResponseWriter, r *http.Request) {
+	writeExecHistory(w, r, h.DB, strings.TrimSpace(r.PathValue("id")))
+}
+
+func writeExecHistory(w http.ResponseWriter, r *http.Request, db *sql.DB, tenantID string) {
+	if db == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	limit := 50
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = min(n, 500)
+	}
+
+	history, err := queryExecHistory(r.Context(), db, tenantID, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query exec history")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tenant_id": tenantID, "history": history})
+}
+
+func queryExecHistory(ctx context.Context, db *sql.DB, tenantID string, limit int) ([]map[string]any, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, command_json, output_truncated, exit_code, executed_at
+		FROM exec_history
+		WHERE tenant_id = $1
+		ORDER BY executed_at DESC
+		LIMIT $2
+	`, tenantID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]map[string]any, 0)
+	for rows.Next() {
+		var (
+			id         string
+			command    []byte
+			output     string
+			exitCode   sql.NullInt64
+			executedAt time.Time
+		)
+		if err := rows.Scan(&id, &command, &output, &exitCode, &executedAt); err != nil {
+			return nil, err
+		}
+		entry := map[string]any{
+			"id":          id,
+			"command":     json.RawMessage(command),
+			"output":      output,
+			"exit_code":   nil,
+			"executed_at": executedAt,
+		}
+		if exitCode.Valid {
+			entry["exit_code"] = exitCode.Int64
+		}
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
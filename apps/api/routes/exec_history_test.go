@@ -0,0 +1,60 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestExecHistoryEndpoints(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	NewAdminHandler(db, nil).Mount(mux)
+	NewContainerHandler(db).Mount(mux)
+
+	get := func(path, scopedTenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if scopedTenant != "" {
+			req.Header.Set("X-Tenant-ID", scopedTenant)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "command_json", "output_truncated", "exit_code", "executed_at"}).
+			AddRow("e1", []byte(`["ls","-la"]`), "file.txt", 0, time.Now()).
+			AddRow("e2", []byte(`["cat","missing"]`), "", nil, time.Now())
+	}
+
+	mock.ExpectQuery("FROM exec_history").WithArgs("t1", 50).WillReturnRows(rows())
+	w := get("/api/admin/tenants/t1/exec-history", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"command":["ls","-la"]`) || !strings.Contains(w.Body.String(), `"exit_code":null`) {
+		t.Fatalf("admin status = %d body=%s", w.Code, w.Body.String())
+	}
+
+	mock.ExpectQuery("FROM exec_history").WithArgs("t1", 10).WillReturnRows(rows())
+	if w := get("/api/tenants/t1/container/exec-history?limit=10", "t1"); w.Code != http.StatusOK {
+		t.Fatalf("tenant status = %d body=%s", w.Code, w.Body.String())
+	}
+	if w := get("/api/tenants/t1/container/exec-history", "t2"); w.Code != http.StatusNotFound {
+		t.Fatalf("other tenant status = %d", w.Code)
+	}
+	if w := get("/api/tenants/t1/container/exec-history?limit=-1", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("bad limit status = %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -2,7 +2,11 @@ package routes
 
 import (
 	"encoding/json"
+	"log/slog"
 	"net/http"
+
+	"github.com/agentsquads/api/orchestrator"
+	"github.com/agentsquads/api/policies"
 )
 
 func writeJSON(w http.ResponseWriter, status int, payload any) {
@@ -20,3 +24,37 @@ func decodeJSONStrict(r *http.Request, v any) error {
 	dec.DisallowUnknownFields()
 	return dec.Decode(v)
 }
+
+// requireFeature writes a 403 and returns false when the tenant's policy
+// disables feature. A nil store allows everything.
+func requireFeature(w http.ResponseWriter, r *http.Request, store *policies.Store, tenantID, feature, component string) bool {
+	if store == nil {
+		return true
+	}
+	enabled, err := store.Get(r.Context(), tenantID, feature)
+	if err != nil {
+		slog.Error("tenant policy check failed", "tenant", tenantID, "feature", feature, "err", err)
+		writeAPIError(w, http.StatusInternalServerError, "failed to check tenant policy")
+		return false
+	}
+	if !enabled {
+		policies.RecordDenial(r.Context(), tenantID, feature, component)
+		writeAPIError(w, http.StatusForbidden, feature+" is disabled for this tenant")
+		return false
+	}
+	return true
+}
+
+// orchestratorCapability returns orch as T, for container operations only
+// some backends support. It writes a 503 when no orchestrator is configured
+// and a 501 when the backend does not support the operation.
+func orchestratorCapability[T any](w http.ResponseWriter, orch orchestrator.TenantOrchestrator, operation string) (T, bool) {
+	capability, ok := orchestrator.Capability[T](orch)
+	switch {
+	case orch == nil:
+		writeError(w, http.StatusServiceUnavailable, "orchestrator is not configured")
+	case !ok:
+		writeError(w, http.StatusNotImplemented, "the container backend does not support "+operation)
+	}
+	return capability, ok
+}
@@ -0,0 +1,352 @@
+package routes
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/media"
+	"github.com/agentsquads/api/openapi"
+	"github.com/jung-kurt/gofpdf"
+)
+
+const invoiceMonthLayout = "2006-01"
+
+// InvoiceHandler generates PDF invoices for a tenant's monthly usage.
+type InvoiceHandler struct {
+	DB *sql.DB
+	// Blobs keeps the invoices of ended months so they are downloaded again
+	// rather than regenerated. Without it every request regenerates.
+	Blobs media.BlobStore
+
+	now func() time.Time
+}
+
+func NewInvoiceHandler(db *sql.DB) *InvoiceHandler {
+	return &InvoiceHandler{DB: db, now: time.Now}
+}
+
+func (h *InvoiceHandler) Mount(mux *http.ServeMux) {
+	openapi.HandleFunc(mux, openapi.Route{Method: http.MethodGet, Path: "/api/tenants/{id}/billing/invoice", Summary: "Download a PDF invoice for a month's usage",
+		Tags: []string{"Billing"}, Query: []string{"month"}, Headers: []string{"X-Tenant-ID"}, ContentType: "application/pdf"}, h.handleGenerateInvoice)
+}
+
+// invoiceLine is one model's usage in the invoice period.
+type invoiceLine struct {
+	Model        string
+	InputTokens  int64
+	OutputTokens int64
+	CostCents    int64
+}
+
+type invoice struct {
+	Number    string
+	Date      time.Time
+	Period    time.Time
+	TenantID  string
+	Email     string
+	Name      string
+	Lines     []invoiceLine
+	FeeCents  int64
+	Subtotal  int64
+	Total     int64
+	Estimated bool
+}
+
+// handleGenerateInvoice streams the invoice for ?month=YYYY-MM (UTC). Usage
+// is itemised per model at its provider cost, and the platform's margin is
+// billed as a single platform fee line. The invoice of an ended month is
+// stored on first request and served from storage afterwards; the current
+// month is regenerated every time and marked as an estimate.
+func (h *InvoiceHandler) handleGenerateInvoice(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	if scoped := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); scoped != "" && scoped != tenantID {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	period, err := time.Parse(invoiceMonthLayout, strings.TrimSpace(r.URL.Query().Get("month")))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "month must be YYYY-MM")
+		return
+	}
+	now := h.now().UTC()
+	if period.After(now) {
+		writeError(w, http.StatusBadRequest, "month has not started")
+		return
+	}
+	periodEnd := period.AddDate(0, 1, 0)
+	ended := !periodEnd.After(now)
+	month := period.Format(invoiceMonthLayout)
+	filename := fmt.Sprintf("invoice-%s.pdf", month)
+
+	if ended && h.Blobs != nil {
+		if h.serveStoredInvoice(w, r, tenantID, period, filename) {
+			return
+		}
+	}
+
+	inv := invoice{
+		Number:    invoiceNumber(tenantID, period),
+		Date:      now,
+		Period:    period,
+		TenantID:  tenantID,
+		Estimated: !ended,
+	}
+	err = h.DB.QueryRowContext(r.Context(), `
+		SELECT u.email, COALESCE(u.name, '')
+		FROM tenants t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.id = $1
+	`, tenantID).Scan(&inv.Email, &inv.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load tenant")
+		return
+	}
+
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT model, COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0),
+		       COALESCE(SUM(cost_cents), 0), COALESCE(SUM(margin_cents), 0)
+		FROM usage_logs
+		WHERE tenant_id = $1 AND created_at >= $2 AND created_at < $3
+		GROUP BY model
+		ORDER BY model
+	`, tenantID, period, periodEnd)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load usage")
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			line   invoiceLine
+			margin int64
+		)
+		if err := rows.Scan(&line.Model, &line.InputTokens, &line.OutputTokens, &line.CostCents, &margin); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to load usage")
+			return
+		}
+		inv.Lines = append(inv.Lines, line)
+		inv.Subtotal += line.CostCents
+		inv.FeeCents += margin
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load usage")
+		return
+	}
+	inv.Total = inv.Subtotal + inv.FeeCents
+
+	pdf, err := renderInvoicePDF(inv)
+	if err != nil {
+		slog.Error("failed to render invoice", "tenant_id", tenantID, "month", month, "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to render invoice")
+		return
+	}
+	if ended && h.Blobs != nil {
+		h.storeInvoice(r, inv, pdf)
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	_, _ = w.Write(pdf)
+}
+
+// serveStoredInvoice streams the stored invoice for period and reports
+// whether there was one to stream.
+func (h *InvoiceHandler) serveStoredInvoice(w http.ResponseWriter, r *http.Request, tenantID string, period time.Time, filename string) bool {
+	var blobKey string
+	err := h.DB.QueryRowContext(r.Context(), `
+		SELECT blob_key FROM invoices WHERE tenant_id = $1 AND period = $2
+	`, tenantID, period).Scan(&blobKey)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			slog.Warn("failed to look up stored invoice", "tenant_id", tenantID, "err", err)
+		}
+		return false
+	}
+	body, err := h.Blobs.Open(r.Context(), blobKey)
+	if err != nil {
+		slog.Warn("stored invoice is unreadable, regenerating", "tenant_id", tenantID, "blob_key", blobKey, "err", err)
+		return false
+	}
+	defer body.Close()
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if _, err := io.Copy(w, body); err != nil {
+		slog.Warn("invoice download interrupted", "tenant_id", tenantID, "err", err)
+	}
+	return true
+}
+
+// storeInvoice uploads pdf and records it. Failures are logged; the invoice
+// is regenerated on the next request.
+func (h *InvoiceHandler) storeInvoice(r *http.Request, inv invoice, pdf []byte) {
+	key := fmt.Sprintf("invoices/%s/%s.pdf", inv.TenantID, inv.Period.Format(invoiceMonthLayout))
+	if err := h.Blobs.Put(r.Context(), key, "application/pdf", pdf); err != nil {
+		slog.Error("failed to store invoice", "tenant_id", inv.TenantID, "blob_key", key, "err", err)
+		return
+	}
+	if _, err := h.DB.ExecContext(r.Context(), `
+		INSERT INTO invoices (tenant_id, period, number, blob_key, subtotal_cents, fee_cents, total_cents)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (tenant_id, period) DO UPDATE
+		SET number = EXCLUDED.number, blob_key = EXCLUDED.blob_key, subtotal_cents = EXCLUDED.subtotal_cents,
+		    fee_cents = EXCLUDED.fee_cents, total_cents = EXCLUDED.total_cents, created_at = NOW()
+	`, inv.TenantID, inv.Period, inv.Number, key, inv.Subtotal, inv.FeeCents, inv.Total); err != nil {
+		slog.Error("failed to record invoice", "tenant_id", inv.TenantID, "blob_key", key, "err", err)
+	}
+}
+
+func renderInvoicePDF(inv invoice) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle("Invoice "+inv.Number, true)
+	pdf.SetCreator("AgentSquads", true)
+	pdf.SetCreationDate(inv.Date)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 20)
+	title := "Invoice"
+	if inv.Estimated {
+		title = "Invoice (estimate, month in progress)"
+	}
+	pdf.CellFormat(0, 10, title, "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+
+	pdf.SetFont("Helvetica", "", 10)
+	details := [][2]string{
+		{"Invoice number", inv.Number},
+		{"Invoice date", inv.Date.Format("2 January 2006")},
+		{"Billing period", inv.Period.Format("January 2006")},
+		{"Tenant", inv.TenantID},
+	}
+	if inv.Name != "" {
+		details = append(details, [2]string{"Name", tr(inv.Name)})
+	}
+	details = append(details, [2]string{"Email", tr(inv.Email)})
+	for _, d := range details {
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(40, 6, d[0], "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(0, 6, d[1], "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(6)
+
+	widths := []float64{60, 30, 30, 40, 30}
+	const tableWidth = 190
+	headers := []string{"Model", "Input tokens", "Output tokens", "Unit cost (per 1M)", "Total"}
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(235, 235, 235)
+	for i, header := range headers {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		pdf.CellFormat(widths[i], 7, header, "1", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 10)
+	if len(inv.Lines) == 0 {
+		pdf.CellFormat(tableWidth, 7, "No usage in this period", "1", 1, "C", false, 0, "")
+	}
+	for _, line := range inv.Lines {
+		cells := []string{
+			tr(line.Model),
+			formatTokens(line.InputTokens),
+			formatTokens(line.OutputTokens),
+			formatUnitCost(line.CostCents, line.InputTokens+line.OutputTokens),
+			formatCents(line.CostCents),
+		}
+		for i, cell := range cells {
+			align := "R"
+			if i == 0 {
+				align = "L"
+			}
+			pdf.CellFormat(widths[i], 7, cell, "1", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	pdf.Ln(4)
+
+	labelWidth := tableWidth - widths[len(widths)-1]
+	totals := [][2]string{
+		{"Subtotal", formatCents(inv.Subtotal)},
+		{"Platform fee", formatCents(inv.FeeCents)},
+		{"Total", formatCents(inv.Total)},
+	}
+	for i, t := range totals {
+		style := ""
+		if i == len(totals)-1 {
+			style = "B"
+		}
+		pdf.SetFont("Helvetica", style, 10)
+		pdf.CellFormat(labelWidth, 7, t[0], "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[len(widths)-1], 7, t[1], "", 1, "R", false, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// invoiceNumber identifies a tenant's invoice for period, e.g.
+// INV-202401-3F2A9C1B.
+func invoiceNumber(tenantID string, period time.Time) string {
+	prefix := strings.ReplaceAll(tenantID, "-", "")
+	if len(prefix) > 8 {
+		prefix = prefix[:8]
+	}
+	return fmt.Sprintf("INV-%s-%s", period.Format("200601"), strings.ToUpper(prefix))
+}
+
+func formatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s$%d.%02d", sign, cents/100, cents%100)
+}
+
+// formatUnitCost is the average cost per million tokens of a line.
+func formatUnitCost(cents, tokens int64) string {
+	if tokens <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("$%.2f", float64(cents)*1e6/float64(tokens)/100)
+}
+
+// formatTokens groups thousands with commas.
+func formatTokens(n int64) string {
+	s := fmt.Sprintf("%d", n)
+	if n < 0 {
+		return s
+	}
+	var b strings.Builder
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,99 @@
+package routes
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/media"
+)
+
+func TestGenerateInvoice(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	h := NewInvoiceHandler(db)
+	h.Blobs = media.NewLocalBlobStore(t.TempDir())
+	h.now = func() time.Time { return time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC) }
+	mux := http.NewServeMux()
+	h.Mount(mux)
+	get := func(query, scopedTenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/tenants/3f2a9c1b-0000-0000-0000-000000000001/billing/invoice"+query, nil)
+		if scopedTenant != "" {
+			req.Header.Set("X-Tenant-ID", scopedTenant)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	tenantID := "3f2a9c1b-0000-0000-0000-000000000001"
+	january := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT blob_key FROM invoices").WithArgs(tenantID, january).WillReturnRows(sqlmock.NewRows([]string{"blob_key"}))
+	mock.ExpectQuery("FROM tenants t").WithArgs(tenantID).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "name"}).AddRow("ada@example.com", "Ada"))
+	mock.ExpectQuery("FROM usage_logs").WithArgs(tenantID, january, january.AddDate(0, 1, 0)).
+		WillReturnRows(sqlmock.NewRows([]string{"model", "input", "output", "cost", "margin"}).
+			AddRow("claude-sonnet", 800000, 200000, 450, 90).
+			AddRow("gpt-4o", 100000, 50000, 120, 24))
+	mock.ExpectExec("INSERT INTO invoices").
+		WithArgs(tenantID, january, "INV-202401-3F2A9C1B", "invoices/"+tenantID+"/2024-01.pdf", int64(570), int64(114), int64(684)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	w := get("?month=2024-01", tenantID)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/pdf" || !bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")) {
+		t.Fatalf("status=%d type=%q body=%.20q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+	generated := w.Body.Bytes()
+
+	// An ended month's invoice is served from storage afterwards.
+	mock.ExpectQuery("SELECT blob_key FROM invoices").WithArgs(tenantID, january).
+		WillReturnRows(sqlmock.NewRows([]string{"blob_key"}).AddRow("invoices/" + tenantID + "/2024-01.pdf"))
+	if w := get("?month=2024-01", ""); w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), generated) {
+		t.Fatalf("stored invoice status=%d, same=%v", w.Code, bytes.Equal(w.Body.Bytes(), generated))
+	}
+
+	// The current month is an estimate and is not stored.
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM tenants t").WithArgs(tenantID).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "name"}).AddRow("ada@example.com", ""))
+	mock.ExpectQuery("FROM usage_logs").WithArgs(tenantID, march, march.AddDate(0, 1, 0)).
+		WillReturnRows(sqlmock.NewRows([]string{"model", "input", "output", "cost", "margin"}))
+	if w := get("?month=2024-03", ""); w.Code != http.StatusOK {
+		t.Fatalf("current month status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	for _, query := range []string{"", "?month=2024-13", "?month=2024-04"} {
+		if w := get(query, ""); w.Code != http.StatusBadRequest {
+			t.Fatalf("%q status=%d", query, w.Code)
+		}
+	}
+	if w := get("?month=2024-01", "other"); w.Code != http.StatusNotFound {
+		t.Fatalf("other tenant status=%d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestInvoiceFormatting(t *testing.T) {
+	t.Parallel()
+	if got := formatCents(123456); got != "$1234.56" {
+		t.Fatalf("formatCents = %q", got)
+	}
+	if got := formatTokens(1234567); got != "1,234,567" {
+		t.Fatalf("formatTokens = %q", got)
+	}
+	if got := formatUnitCost(450, 1000000); got != "$4.50" {
+		t.Fatalf("formatUnitCost = %q", got)
+	}
+	if got := formatUnitCost(0, 0); got != "-" {
+		t.Fatalf("formatUnitCost without tokens = %q", got)
+	}
+}
@@ -0,0 +1,95 @@
+package routes
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/agentsquads/api/tools"
+)
+
+// MemoryHandler lets tenants inspect and prune what their agents saved with
+// memory_persist (see tools.MemoryStore).
+type MemoryHandler struct {
+	Store *tools.MemoryStore
+}
+
+func NewMemoryHandler(store *tools.MemoryStore) *MemoryHandler {
+	return &MemoryHandler{Store: store}
+}
+
+func (h *MemoryHandler) Mount(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/tenants/{id}/memories", h.handleListMemories)
+	mux.HandleFunc("DELETE /api/tenants/{id}/memories", h.handleClearMemories)
+	mux.HandleFunc("DELETE /api/tenants/{id}/memories/{memory_id}", h.handleDeleteMemory)
+}
+
+// memoryTenant returns the path's tenant id, writing the error response when
+// it is missing or outside the caller's tenant scope.
+func (h *MemoryHandler) memoryTenant(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if h.Store == nil {
+		writeError(w, http.StatusServiceUnavailable, "memories are not configured")
+		return "", false
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return "", false
+	}
+	if scoped := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); scoped != "" && scoped != tenantID {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return "", false
+	}
+	return tenantID, true
+}
+
+// handleListMemories lists the tenant's memories, newest first, or only those
+// of the conversation_id query parameter.
+func (h *MemoryHandler) handleListMemories(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := h.memoryTenant(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.Store.List(r.Context(), tenantID, strings.TrimSpace(r.URL.Query().Get("conversation_id")))
+	if err != nil {
+		slog.Error("list memories failed", "tenant", tenantID, "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to load memories")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"memories": list})
+}
+
+// handleClearMemories deletes the tenant's memories, or only those of the
+// conversation_id query parameter.
+func (h *MemoryHandler) handleClearMemories(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := h.memoryTenant(w, r)
+	if !ok {
+		return
+	}
+	deleted, err := h.Store.DeleteAll(r.Context(), tenantID, strings.TrimSpace(r.URL.Query().Get("conversation_id")))
+	if err != nil {
+		slog.Error("clear memories failed", "tenant", tenantID, "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete memories")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"deleted": deleted})
+}
+
+func (h *MemoryHandler) handleDeleteMemory(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := h.memoryTenant(w, r)
+	if !ok {
+		return
+	}
+	id := strings.TrimSpace(r.PathValue("memory_id"))
+	deleted, err := h.Store.Delete(r.Context(), tenantID, id)
+	if err != nil {
+		slog.Error("delete memory failed", "tenant", tenantID, "memory", id, "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to delete memory")
+		return
+	}
+	if !deleted {
+		writeError(w, http.StatusNotFound, "memory not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
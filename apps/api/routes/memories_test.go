@@ -0,0 +1,69 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/tools"
+)
+
+func TestMemoryRoutes(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	NewMemoryHandler(tools.NewMemoryStore(db)).Mount(mux)
+	do := func(method, path, scope string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if scope != "" {
+			req.Header.Set("X-Tenant-ID", scope)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodGet, "/api/tenants/t1/memories", "t2"); w.Code != http.StatusNotFound {
+		t.Fatalf("cross-tenant status = %d", w.Code)
+	}
+
+	mock.ExpectQuery("FROM tenant_memories").WithArgs("t1", "").WillReturnRows(sqlmock.NewRows([]string{
+		"id", "scope", "conversation_id", "key", "content", "category", "updated_at",
+	}).AddRow("m1", "tenant", "", "launch_date", "March 3", "data", time.Now()))
+	w := do(http.MethodGet, "/api/tenants/t1/memories", "t1")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"key":"launch_date"`) || strings.Contains(w.Body.String(), "conversation_id") {
+		t.Fatalf("list status = %d body=%s", w.Code, w.Body.String())
+	}
+
+	mock.ExpectExec("DELETE FROM tenant_memories").WithArgs("t1", "m1").WillReturnResult(sqlmock.NewResult(0, 1))
+	if w := do(http.MethodDelete, "/api/tenants/t1/memories/m1", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete status = %d", w.Code)
+	}
+	mock.ExpectExec("DELETE FROM tenant_memories").WithArgs("t1", "missing").WillReturnResult(sqlmock.NewResult(0, 0))
+	if w := do(http.MethodDelete, "/api/tenants/t1/memories/missing", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("delete missing status = %d", w.Code)
+	}
+	mock.ExpectExec("DELETE FROM tenant_memories").WithArgs("t1", "c1").WillReturnResult(sqlmock.NewResult(0, 4))
+	if w := do(http.MethodDelete, "/api/tenants/t1/memories?conversation_id=c1", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"deleted":4`) {
+		t.Fatalf("clear status = %d body=%s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+
+	unconfigured := http.NewServeMux()
+	NewMemoryHandler(nil).Mount(unconfigured)
+	w = httptest.NewRecorder()
+	unconfigured.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tenants/t1/memories", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unconfigured status = %d", w.Code)
+	}
+}
@@ -0,0 +1,194 @@
+package routes
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/llmproxy"
+)
+
+// TenantModelsHandler serves the models a tenant's agents may use.
+type TenantModelsHandler struct {
+	DB *sql.DB
+}
+
+func NewTenantModelsHandler(db *sql.DB) *TenantModelsHandler {
+	return &TenantModelsHandler{DB: db}
+}
+
+func (h *TenantModelsHandler) Mount(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/tenants/{id}/models", h.handleListTenantModels)
+}
+
+// handleListTenantModels lists enabled models permitted by the tenant's
+// allowlist. A caller scoped to another tenant by X-Tenant-ID gets 404.
+func (h *TenantModelsHandler) handleListTenantModels(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	if scoped := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); scoped != "" && scoped != tenantID {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+
+	rows, err := h.DB.QueryContext(r.Context(), `SELECT id, name, provider FROM models WHERE enabled = true ORDER BY id`)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query models")
+		return
+	}
+	defer rows.Close()
+	var models []*llmproxy.Model
+	for rows.Next() {
+		m := &llmproxy.Model{Enabled: true}
+		if err := rows.Scan(&m.ID, &m.Name, &m.Provider); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to scan model")
+			return
+		}
+		models = append(models, m)
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed while reading models")
+		return
+	}
+
+	access, err := llmproxy.LoadModelAccess(r.Context(), h.DB, tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load model access")
+		return
+	}
+	permitted := access.Filter(models)
+	out := make([]map[string]any, 0, len(permitted))
+	for _, m := range permitted {
+		out = append(out, map[string]any{"id": m.ID, "name": m.Name, "provider": m.Provider})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tenant_id": tenantID, "models": out})
+}
+
+// handleListModelAccess lists the tenant's explicit model access rows. No
+// rows means every enabled model is allowed.
+func (h *AdminHandler) handleListModelAccess(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT model_id, allowed, updated_at
+		FROM tenant_model_access
+		WHERE tenant_id = $1
+		ORDER BY model_id
+	`, tenantID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query model access")
+		return
+	}
+	defer rows.Close()
+
+	access := make([]map[string]any, 0)
+	for rows.Next() {
+		var (
+			modelID   string
+			allowed   bool
+			updatedAt time.Time
+		)
+		if err := rows.Scan(&modelID, &allowed, &updatedAt); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to scan model access")
+			return
+		}
+		access = append(access, map[string]any{"model_id": modelID, "allowed": allowed, "updated_at": updatedAt})
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed while reading model access")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tenant_id": tenantID, "access": access})
+}
+
+// handleSetModelAccess allows or blocks one model for a tenant. Model IDs
+// contain slashes, so the ID is the rest of the path.
+func (h *AdminHandler) handleSetModelAccess(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	modelID := strings.TrimSpace(r.PathValue("model_id"))
+	if tenantID == "" || modelID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id or model id")
+		return
+	}
+	var req struct {
+		Allowed *bool `json:"allowed"`
+	}
+	if err := decodeJSONStrict(r, &req); err != nil || req.Allowed == nil {
+		writeError(w, http.StatusBadRequest, "allowed is required")
+		return
+	}
+
+	res, err := h.DB.ExecContext(r.Context(), `
+		INSERT INTO tenant_model_access (tenant_id, model_id, allowed)
+		SELECT t.id, m.id, $3
+		FROM tenants t, models m
+		WHERE t.id = $1 AND m.id = $2
+		ON CONFLICT (tenant_id, model_id) DO UPDATE
+		SET allowed = EXCLUDED.allowed, updated_at = NOW()
+	`, tenantID, modelID, *req.Allowed)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to update model access")
+		return
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		writeError(w, http.StatusNotFound, "tenant or model not found")
+		return
+	}
+
+	h.logAdminAction(r.Context(), "admin.tenants.model_access", tenantID, map[string]any{"model_id": modelID, "allowed": *req.Allowed})
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tenant_id": tenantID,
+		"model_id":  modelID,
+		"allowed":   *req.Allowed,
+	})
+}
+
+// handleDeleteModelAccess removes the tenant's row for one model, returning
+// it to the default for the tenant's remaining rows.
+func (h *AdminHandler) handleDeleteModelAccess(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	modelID := strings.TrimSpace(r.PathValue("model_id"))
+	if tenantID == "" || modelID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id or model id")
+		return
+	}
+
+	res, err := h.DB.ExecContext(r.Context(), `
+		DELETE FROM tenant_model_access WHERE tenant_id = $1 AND model_id = $2
+	`, tenantID, modelID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to delete model access")
+		return
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		writeError(w, http.StatusNotFound, "model access not found")
+		return
+	}
+
+	h.logAdminAction(r.Context(), "admin.tenants.model_access.delete", tenantID, map[string]any{"model_id": modelID})
+	writeJSON(w, http.StatusOK, map[string]any{"tenant_id": tenantID, "model_id": modelID, "deleted": true})
+}
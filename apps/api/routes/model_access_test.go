@@ -0,0 +1,88 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestListTenantModelsAppliesAllowlist(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	NewTenantModelsHandler(db).Mount(mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tenants/t1/models", nil)
+	req.Header.Set("X-Tenant-ID", "t2")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("cross-tenant status = %d", w.Code)
+	}
+
+	mock.ExpectQuery("SELECT id, name, provider FROM models").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "provider"}).
+		AddRow("anthropic/claude-opus", "Claude Opus", "anthropic").
+		AddRow("openai/gpt-4.1-mini", "GPT-4.1 mini", "openai"))
+	mock.ExpectQuery("SELECT model_id, allowed FROM tenant_model_access").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"model_id", "allowed"}).AddRow("openai/gpt-4.1-mini", true))
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tenants/t1/models", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "gpt-4.1-mini") || strings.Contains(w.Body.String(), "claude-opus") {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestAdminSetModelAccess(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	h := NewAdminHandler(db, nil)
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	if w := do(http.MethodPut, "/api/admin/tenants/t1/model-access/openai/gpt-4.1", `{}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing allowed status = %d", w.Code)
+	}
+
+	mock.ExpectExec("INSERT INTO tenant_model_access").WithArgs("t1", "openai/missing", true).WillReturnResult(sqlmock.NewResult(0, 0))
+	if w := do(http.MethodPut, "/api/admin/tenants/t1/model-access/openai/missing", `{"allowed":true}`); w.Code != http.StatusNotFound {
+		t.Fatalf("missing model status = %d", w.Code)
+	}
+
+	mock.ExpectExec("INSERT INTO tenant_model_access").WithArgs("t1", "openai/gpt-4.1", true).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO admin_audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+	w := do(http.MethodPut, "/api/admin/tenants/t1/model-access/openai/gpt-4.1", `{"allowed":true}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"model_id":"openai/gpt-4.1"`) {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+
+	mock.ExpectExec("DELETE FROM tenant_model_access").WithArgs("t1", "openai/gpt-4.1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO admin_audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+	if w := do(http.MethodDelete, "/api/admin/tenants/t1/model-access/openai/gpt-4.1", ``); w.Code != http.StatusOK {
+		t.Fatalf("delete status = %d body=%s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
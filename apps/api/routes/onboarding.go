@@ -0,0 +1,386 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/agentsquads/api/orchestrator"
+	"github.com/lib/pq"
+)
+
+// Onboarding checklist item ids, in the order the dashboard shows them.
+const (
+	onboardingContainer = "container"
+	onboardingChannel   = "channel"
+	onboardingCredits   = "credits"
+	onboardingModels    = "models"
+	onboardingSwarmRun  = "swarm_run"
+	onboardingDeploy    = "deploy"
+)
+
+var onboardingItems = []string{onboardingContainer, onboardingChannel, onboardingCredits, onboardingModels, onboardingSwarmRun, onboardingDeploy}
+
+// Onboarding item statuses. Unknown means the state could not be checked,
+// as when the orchestrator is not configured or a lookup failed.
+const (
+	onboardingDone    = "done"
+	onboardingTodo    = "todo"
+	onboardingUnknown = "unknown"
+)
+
+type onboardingItem struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+	Hint   string `json:"hint"`
+	Path   string `json:"path"`
+}
+
+type onboardingResponse struct {
+	TenantID  string           `json:"tenant_id"`
+	Completed int              `json:"completed"`
+	Total     int              `json:"total"`
+	Items     []onboardingItem `json:"items"`
+	Dismissed []string         `json:"dismissed"`
+}
+
+// SwarmRunHistory reports a tenant's in-memory swarm runs.
+// coordinator.Handler implements it.
+type SwarmRunHistory interface {
+	HasCompletedRun(tenantID string) bool
+}
+
+// OnboardingHandler serves the setup checklist shown on a new tenant's
+// dashboard.
+type OnboardingHandler struct {
+	DB   *sql.DB
+	Orch orchestrator.TenantOrchestrator
+	// Swarm is optional; without it only swarm runs that wrote their result
+	// to a conversation count.
+	Swarm SwarmRunHistory
+}
+
+func NewOnboardingHandler(db *sql.DB) *OnboardingHandler {
+	return &OnboardingHandler{DB: db}
+}
+
+func (h *OnboardingHandler) Mount(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/tenants/{id}/onboarding", h.handleOnboarding)
+	mux.HandleFunc("POST /api/tenants/{id}/onboarding/dismiss", h.handleDismissOnboarding)
+}
+
+// onboardingState is what the checklist is computed from. Each lookup fills
+// its own fields, so they run concurrently.
+type onboardingState struct {
+	containerID  string
+	balanceCents int64
+
+	containerStatus *orchestrator.ContainerStatus
+	containerErr    error
+
+	channelsLinked   int
+	channelsVerified int
+	channelsErr      error
+
+	modelsAllowed int
+	modelsErr     error
+
+	swarmCompleted bool
+	swarmErr       error
+
+	deployConnections int
+	deployErr         error
+
+	dismissed    []string
+	dismissedErr error
+}
+
+// handleOnboarding returns the tenant's setup checklist, computed from the
+// state of each subsystem. Items the tenant dismissed are left out and listed
+// under dismissed.
+func (h *OnboardingHandler) handleOnboarding(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	if scoped := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); scoped != "" && scoped != tenantID {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+
+	var (
+		state       onboardingState
+		containerID sql.NullString
+	)
+	err := h.DB.QueryRowContext(r.Context(), `
+		SELECT t.container_id, COALESCE(c.balance_cents, 0)
+		FROM tenants t
+		LEFT JOIN credits c ON c.tenant_id = t.id
+		WHERE t.id = $1
+	`, tenantID).Scan(&containerID, &state.balanceCents)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load tenant")
+		return
+	}
+	state.containerID = strings.TrimSpace(containerID.String)
+
+	h.loadOnboardingState(r.Context(), tenantID, &state)
+
+	resp := onboardingResponse{TenantID: tenantID, Items: []onboardingItem{}, Dismissed: []string{}}
+	dismissed := map[string]bool{}
+	for _, item := range state.dismissed {
+		dismissed[item] = true
+		resp.Dismissed = append(resp.Dismissed, item)
+	}
+	for _, item := range onboardingChecklist(state) {
+		if dismissed[item.ID] {
+			continue
+		}
+		resp.Total++
+		if item.Status == onboardingDone {
+			resp.Completed++
+		}
+		resp.Items = append(resp.Items, item)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// loadOnboardingState runs the lookups behind the checklist concurrently. A
+// failed lookup is logged and leaves its item unknown rather than failing the
+// checklist.
+func (h *OnboardingHandler) loadOnboardingState(ctx context.Context, tenantID string, state *onboardingState) {
+	lookups := []func(){
+		func() {
+			if h.Orch != nil && state.containerID != "" {
+				state.containerStatus, state.containerErr = h.Orch.Status(ctx, tenantID)
+			}
+		},
+		func() {
+			state.channelsErr = h.DB.QueryRowContext(ctx, `
+				SELECT
+					COUNT(*),
+					COUNT(*) FILTER (WHERE
+						(tc.channel = 'telegram' AND COALESCE(cc.config->>'bot_id', '') <> '')
+						OR (tc.channel = 'whatsapp' AND cc.config->>'webhook_verified' = 'true')
+						OR (tc.channel = 'viber' AND COALESCE(cc.config->>'webhook_id', '') <> ''))
+				FROM tenant_channels tc
+				LEFT JOIN channel_credentials cc
+				  ON cc.tenant_id = tc.tenant_id
+				 AND cc.channel = tc.channel
+				WHERE tc.tenant_id = $1 AND tc.channel <> 'web'
+			`, tenantID).Scan(&state.channelsLinked, &state.channelsVerified)
+		},
+		func() {
+			// Mirrors llmproxy.ModelAccess: deny rows always win, and allow
+			// rows, when there are any, limit the tenant to those models.
+			state.modelsErr = h.DB.QueryRowContext(ctx, `
+				SELECT COUNT(*)
+				FROM models m
+				WHERE m.enabled = true
+				  AND NOT EXISTS (
+					SELECT 1 FROM tenant_model_access a
+					WHERE a.tenant_id = $1 AND a.model_id = m.id AND a.allowed = false)
+				  AND (
+					NOT EXISTS (SELECT 1 FROM tenant_model_access a WHERE a.tenant_id = $1 AND a.allowed = true)
+					OR EXISTS (
+						SELECT 1 FROM tenant_model_access a
+						WHERE a.tenant_id = $1 AND a.model_id = m.id AND a.allowed = true))
+			`, tenantID).Scan(&state.modelsAllowed)
+		},
+		func() {
+			if h.Swarm != nil && h.Swarm.HasCompletedRun(tenantID) {
+				state.swarmCompleted = true
+				return
+			}
+			state.swarmErr = h.DB.QueryRowContext(ctx, `
+				SELECT EXISTS (
+					SELECT 1
+					FROM messages m
+					JOIN conversations c ON c.id = m.conversation_id
+					WHERE c.tenant_id = $1
+					  AND m.metadata->>'source' = 'swarm'
+					  AND m.metadata->>'event' = 'complete')
+			`, tenantID).Scan(&state.swarmCompleted)
+		},
+		func() {
+			state.deployErr = h.DB.QueryRowContext(ctx, `
+				SELECT COUNT(*) FROM deploy_connections WHERE tenant_id = $1
+			`, tenantID).Scan(&state.deployConnections)
+		},
+		func() {
+			var items pq.StringArray
+			state.dismissedErr = h.DB.QueryRowContext(ctx, `
+				SELECT COALESCE(array_agg(item ORDER BY dismissed_at), '{}')
+				FROM tenant_onboarding_dismissals
+				WHERE tenant_id = $1
+			`, tenantID).Scan(&items)
+			state.dismissed = items
+		},
+	}
+
+	var wg sync.WaitGroup
+	for _, lookup := range lookups {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lookup()
+		}()
+	}
+	wg.Wait()
+
+	for name, err := range map[string]error{
+		"container": state.containerErr,
+		"channels":  state.channelsErr,
+		"models":    state.modelsErr,
+		"swarm":     state.swarmErr,
+		"deploy":    state.deployErr,
+		"dismissed": state.dismissedErr,
+	} {
+		if err != nil {
+			slog.Warn("onboarding lookup failed", "tenant", tenantID, "lookup", name, "err", err)
+		}
+	}
+}
+
+// onboardingChecklist turns state into checklist items, in display order.
+func onboardingChecklist(state onboardingState) []onboardingItem {
+	container := onboardingItem{ID: onboardingContainer, Title: "Agent container", Path: "/dashboard"}
+	switch {
+	case state.containerID == "":
+		container.Status, container.Hint = onboardingTodo, "Your agent container has not been provisioned yet."
+	case state.containerStatus == nil:
+		container.Status, container.Hint = onboardingUnknown, "Your agent container is provisioned, but its health could not be checked."
+	case !state.containerStatus.Running:
+		container.Status, container.Hint = onboardingTodo, "Your agent container is stopped. Start it from the dashboard."
+	case state.containerStatus.Health != "" && state.containerStatus.Health != "healthy":
+		container.Status, container.Hint = onboardingTodo, fmt.Sprintf("Your agent container is %s. Restart it if this persists.", state.containerStatus.Health)
+	default:
+		container.Status, container.Hint = onboardingDone, "Your agent container is running and healthy."
+	}
+
+	channel := onboardingItem{ID: onboardingChannel, Title: "Connect a channel", Path: "/dashboard/channels"}
+	switch {
+	case state.channelsErr != nil:
+		channel.Status, channel.Hint = onboardingUnknown, "Channel connections could not be checked."
+	case state.channelsVerified > 0:
+		channel.Status, channel.Hint = onboardingDone, "A channel is connected and verified."
+	case state.channelsLinked > 0:
+		channel.Status, channel.Hint = onboardingTodo, "Finish verifying your channel: Telegram needs a bot identity and WhatsApp a verified webhook."
+	default:
+		channel.Status, channel.Hint = onboardingTodo, "Connect Telegram, WhatsApp or Viber so your agents can reach you."
+	}
+
+	credits := onboardingItem{ID: onboardingCredits, Title: "Add credits", Path: "/dashboard/billing"}
+	if state.balanceCents > 0 {
+		credits.Status, credits.Hint = onboardingDone, "You have credits for model usage."
+	} else {
+		credits.Status, credits.Hint = onboardingTodo, "Top up credits so your agents can call models."
+	}
+
+	models := onboardingItem{ID: onboardingModels, Title: "Allow a model", Path: "/dashboard/settings"}
+	switch {
+	case state.modelsErr != nil:
+		models.Status, models.Hint = onboardingUnknown, "Model access could not be checked."
+	case state.modelsAllowed > 0:
+		models.Status, models.Hint = onboardingDone, fmt.Sprintf("%d models are available to your agents.", state.modelsAllowed)
+	default:
+		models.Status, models.Hint = onboardingTodo, "No models are available to your agents. Ask an admin to allow one."
+	}
+
+	swarm := onboardingItem{ID: onboardingSwarmRun, Title: "Complete a swarm run", Path: "/dashboard/swarm"}
+	switch {
+	case state.swarmCompleted:
+		swarm.Status, swarm.Hint = onboardingDone, "Your first swarm run completed."
+	case state.swarmErr != nil:
+		swarm.Status, swarm.Hint = onboardingUnknown, "Swarm runs could not be checked."
+	default:
+		swarm.Status, swarm.Hint = onboardingTodo, "Give the agent swarm a task to see sub-agents work together."
+	}
+
+	deploy := onboardingItem{ID: onboardingDeploy, Title: "Connect a deploy provider", Path: "/dashboard/settings/deploy"}
+	switch {
+	case state.deployErr != nil:
+		deploy.Status, deploy.Hint = onboardingUnknown, "Deploy connections could not be checked."
+	case state.deployConnections > 0:
+		deploy.Status, deploy.Hint = onboardingDone, "A deploy provider is connected."
+	default:
+		deploy.Status, deploy.Hint = onboardingTodo, "Connect Vercel or Supabase so agents can ship what they build."
+	}
+
+	return []onboardingItem{container, channel, credits, models, swarm, deploy}
+}
+
+// handleDismissOnboarding hides {"items": [...]} from the tenant's checklist.
+// Dismissing an item twice is not an error.
+func (h *OnboardingHandler) handleDismissOnboarding(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return
+	}
+	if scoped := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); scoped != "" && scoped != tenantID {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	var req struct {
+		Items []string `json:"items"`
+	}
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if len(req.Items) == 0 {
+		writeError(w, http.StatusBadRequest, "items is required")
+		return
+	}
+	for _, item := range req.Items {
+		if !slices.Contains(onboardingItems, item) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown onboarding item %q", item))
+			return
+		}
+	}
+
+	res, err := h.DB.ExecContext(r.Context(), `
+		INSERT INTO tenant_onboarding_dismissals (tenant_id, item)
+		SELECT t.id, item
+		FROM tenants t, unnest($2::text[]) AS item
+		WHERE t.id = $1
+		ON CONFLICT (tenant_id, item) DO NOTHING
+	`, tenantID, pq.Array(req.Items))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to dismiss onboarding items")
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var exists bool
+		if err := h.DB.QueryRowContext(r.Context(), `SELECT EXISTS (SELECT 1 FROM tenants WHERE id = $1)`, tenantID).Scan(&exists); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to load tenant")
+			return
+		}
+		if !exists {
+			writeError(w, http.StatusNotFound, "tenant not found")
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tenant_id": tenantID, "dismissed": req.Items})
+}
@@ -0,0 +1,106 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type stubSwarmHistory bool
+
+func (s stubSwarmHistory) HasCompletedRun(string) bool { return bool(s) }
+
+func TestOnboardingChecklist(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mock.MatchExpectationsInOrder(false)
+	h := NewOnboardingHandler(db)
+	h.Orch = &stubOrchestrator{}
+	h.Swarm = stubSwarmHistory(true)
+	mux := http.NewServeMux()
+	h.Mount(mux)
+
+	mock.ExpectQuery("SELECT t.container_id").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"container_id", "balance_cents"}).AddRow("c1", 500))
+	mock.ExpectQuery("FROM tenant_channels").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"linked", "verified"}).AddRow(1, 0))
+	mock.ExpectQuery("FROM models").WithArgs("t1").WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectQuery("FROM deploy_connections").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("FROM tenant_onboarding_dismissals").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"items"}).AddRow("{deploy}"))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tenants/t1/onboarding", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var resp onboardingResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := map[string]string{
+		onboardingContainer: onboardingTodo,
+		onboardingChannel:   onboardingTodo,
+		onboardingCredits:   onboardingDone,
+		onboardingModels:    onboardingUnknown,
+		onboardingSwarmRun:  onboardingDone,
+	}
+	if len(resp.Items) != len(want) || resp.Total != 5 || resp.Completed != 2 || len(resp.Dismissed) != 1 {
+		t.Fatalf("response = %+v", resp)
+	}
+	for _, item := range resp.Items {
+		if item.Status != want[item.ID] || item.Hint == "" || item.Path == "" {
+			t.Errorf("item %s = %+v, want status %s", item.ID, item, want[item.ID])
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestDismissOnboarding(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mux := http.NewServeMux()
+	NewOnboardingHandler(db).Mount(mux)
+
+	post := func(tenantID, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/tenants/"+tenantID+"/onboarding/dismiss", bytes.NewBufferString(body)))
+		return w
+	}
+	for _, body := range []string{`{}`, `{"items":["billing"]}`} {
+		if w := post("t1", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, w.Code)
+		}
+	}
+
+	mock.ExpectExec("INSERT INTO tenant_onboarding_dismissals").WithArgs("t1", `{"deploy","swarm_run"}`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	if w := post("t1", `{"items":["deploy","swarm_run"]}`); w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+
+	mock.ExpectExec("INSERT INTO tenant_onboarding_dismissals").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	if w := post("missing", `{"items":["deploy"]}`); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown tenant status = %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/agentsquads/api/coordinator"
+)
+
+const maxSwarmFeedbackComment = 4000
+
+// SwarmRunLookup finds a tenant's swarm run. coordinator.RunStore implements
+// it from swarm_runs, so runs from before a restart are found too.
+type SwarmRunLookup interface {
+	Run(ctx context.Context, tenantID, runID string) (*coordinator.SwarmRun, bool, error)
+}
+
+// SwarmFeedbackHandler collects tenant ratings of finished swarm runs.
+type SwarmFeedbackHandler struct {
+	DB   *sql.DB
+	Runs SwarmRunLookup
+}
+
+func NewSwarmFeedbackHandler(db *sql.DB, runs SwarmRunLookup) *SwarmFeedbackHandler {
+	return &SwarmFeedbackHandler{DB: db, Runs: runs}
+}
+
+func (h *SwarmFeedbackHandler) Mount(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/tenants/{id}/swarm/runs/{run_id}/feedback", h.handleRunFeedback)
+}
+
+type swarmRunFeedbackRequest struct {
+	Rating  int    `json:"rating"`
+	Comment string `json:"comment"`
+}
+
+// handleRunFeedback stores a 1-5 rating for a finished run. Each run can be
+// rated once per tenant; a second rating gets 409.
+func (h *SwarmFeedbackHandler) handleRunFeedback(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	runID := strings.TrimSpace(r.PathValue("run_id"))
+	if tenantID == "" || runID == "" {
+		writeError(w, http.StatusBadRequest, "tenant id and run id are required")
+		return
+	}
+
+	var req swarmRunFeedbackRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.Rating < 1 || req.Rating > 5 {
+		writeError(w, http.StatusBadRequest, "rating must be between 1 and 5")
+		return
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+	if utf8.RuneCountInString(req.Comment) > maxSwarmFeedbackComment {
+		writeError(w, http.StatusBadRequest, "comment must be at most "+strconv.Itoa(maxSwarmFeedbackComment)+" characters")
+		return
+	}
+
+	if h.Runs != nil {
+		run, ok, err := h.Runs.Run(r.Context(), tenantID, runID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to load swarm run")
+			return
+		}
+		if !ok {
+			writeError(w, http.StatusNotFound, "swarm run not found")
+			return
+		}
+		if run.Status == "running" {
+			writeError(w, http.StatusConflict, "swarm run has not finished")
+			return
+		}
+	}
+
+	var createdAt time.Time
+	err := h.DB.QueryRowContext(r.Context(), `
+		INSERT INTO swarm_run_feedback (run_id, tenant_id, rating, comment)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant_id, run_id) DO NOTHING
+		RETURNING created_at
+	`, runID, tenantID, req.Rating, req.Comment).Scan(&createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusConflict, "feedback already submitted for this run")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to store feedback")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"run_id":     runID,
+		"tenant_id":  tenantID,
+		"rating":     req.Rating,
+		"comment":    req.Comment,
+		"created_at": createdAt,
+	})
+}
+
+// handleListSwarmFeedback lists run feedback newest first, optionally
+// filtered by tenant, run and a maximum rating (rating_lte=2 finds the runs
+// users were unhappy with).
+func (h *AdminHandler) handleListSwarmFeedback(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 50
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = min(n, 500)
+	}
+	var ratingLTE any
+	if raw := strings.TrimSpace(query.Get("rating_lte")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 5 {
+			writeError(w, http.StatusBadRequest, "rating_lte must be between 1 and 5")
+			return
+		}
+		ratingLTE = n
+	}
+
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT run_id, tenant_id, rating, comment, created_at
+		FROM swarm_run_feedback
+		WHERE ($1::uuid IS NULL OR tenant_id = $1)
+		  AND ($2::text IS NULL OR run_id = $2)
+		  AND ($3::int IS NULL OR rating <= $3)
+		ORDER BY created_at DESC
+		LIMIT $4
+	`, emptyToNil(query.Get("tenant_id")), emptyToNil(query.Get("run_id")), ratingLTE, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query swarm feedback")
+		return
+	}
+	defer rows.Close()
+
+	feedback := make([]map[string]any, 0)
+	for rows.Next() {
+		var (
+			runID     string
+			tenantID  string
+			rating    int
+			comment   string
+			createdAt time.Time
+		)
+		if err := rows.Scan(&runID, &tenantID, &rating, &comment, &createdAt); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to scan swarm feedback")
+			return
+		}
+		feedback = append(feedback, map[string]any{
+			"run_id":     runID,
+			"tenant_id":  tenantID,
+			"rating":     rating,
+			"comment":    comment,
+			"created_at": createdAt,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed while reading swarm feedback")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"feedback": feedback})
+}
@@ -0,0 +1,118 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/coordinator"
+)
+
+type stubRunLookup map[string]*coordinator.SwarmRun
+
+func (s stubRunLookup) Run(_ context.Context, tenantID, runID string) (*coordinator.SwarmRun, bool, error) {
+	if runID == "broken" {
+		return nil, false, errors.New("db down")
+	}
+	run, ok := s[runID]
+	if !ok || run.TenantID != tenantID {
+		return nil, false, nil
+	}
+	return run, true, nil
+}
+
+func TestSwarmRunFeedback(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	runs := stubRunLookup{
+		"done": {RunID: "done", TenantID: "t1", Status: "complete"},
+		"busy": {RunID: "busy", TenantID: "t1", Status: "running"},
+	}
+	mux := http.NewServeMux()
+	NewSwarmFeedbackHandler(db, runs).Mount(mux)
+
+	post := func(runID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/tenants/t1/swarm/runs/"+runID+"/feedback", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, body := range []string{`{"rating":0}`, `{"rating":6}`, `{"rating":3,"extra":1}`, `not json`} {
+		if w := post("done", body); w.Code != http.StatusBadRequest {
+			t.Fatalf("body %s status = %d", body, w.Code)
+		}
+	}
+	if w := post("missing", `{"rating":3}`); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown run status = %d", w.Code)
+	}
+	if w := post("busy", `{"rating":3}`); w.Code != http.StatusConflict {
+		t.Fatalf("running run status = %d", w.Code)
+	}
+	if w := post("broken", `{"rating":3}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("lookup failure status = %d", w.Code)
+	}
+
+	mock.ExpectQuery("INSERT INTO swarm_run_feedback").WithArgs("done", "t1", 4, "solid plan").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+	w := post("done", `{"rating":4,"comment":" solid plan "}`)
+	if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"rating":4`) {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+
+	mock.ExpectQuery("INSERT INTO swarm_run_feedback").WithArgs("done", "t1", 2, "").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}))
+	if w := post("done", `{"rating":2}`); w.Code != http.StatusConflict {
+		t.Fatalf("duplicate feedback status = %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestAdminListSwarmFeedback(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	NewAdminHandler(db, nil).Mount(mux)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("/api/admin/swarm/feedback?rating_lte=9"); w.Code != http.StatusBadRequest {
+		t.Fatalf("bad rating_lte status = %d", w.Code)
+	}
+	if w := get("/api/admin/swarm/feedback?limit=0"); w.Code != http.StatusBadRequest {
+		t.Fatalf("bad limit status = %d", w.Code)
+	}
+
+	rows := sqlmock.NewRows([]string{"run_id", "tenant_id", "rating", "comment", "created_at"}).
+		AddRow("r1", "t1", 1, "wrong answer", time.Now())
+	mock.ExpectQuery("FROM swarm_run_feedback").WithArgs("t1", nil, 2, 500).WillReturnRows(rows)
+	w := get("/api/admin/swarm/feedback?tenant_id=t1&rating_lte=2&limit=1000")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"comment":"wrong answer"`) {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
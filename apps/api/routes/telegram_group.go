@@ -0,0 +1,120 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/agentsquads/api/policies"
+)
+
+// telegramAgentCommand addresses the bot in a group without mentioning it.
+const telegramAgentCommand = "/agent"
+
+// telegramEntity marks a span of message text. Offsets and lengths count
+// UTF-16 code units.
+type telegramEntity struct {
+	Type   string `json:"type"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+}
+
+// telegramBot is the bot identity stored when the channel was connected.
+type telegramBot struct {
+	ID       int64
+	Username string
+}
+
+func isTelegramGroup(chatType string) bool {
+	return chatType == "group" || chatType == "supergroup"
+}
+
+// telegramGroupAlwaysResponds reports whether the tenant opted back into
+// routing every group message. Without a policy store groups are gated.
+func (h *ChannelHandler) telegramGroupAlwaysResponds(ctx context.Context, tenantID string) (bool, error) {
+	if h.Policies == nil {
+		return false, nil
+	}
+	enabled, err := h.Policies.FeatureEnabled(ctx, tenantID, policies.FeatureTelegramGroupAlwaysRespond)
+	if err != nil {
+		return false, fmt.Errorf("check telegram group policy: %w", err)
+	}
+	return enabled, nil
+}
+
+func (h *ChannelHandler) telegramBotIdentity(ctx context.Context, tenantID string) (telegramBot, error) {
+	cred, err := h.Credentials.GetByTenantChannel(ctx, tenantID, "telegram")
+	if err != nil {
+		return telegramBot{}, fmt.Errorf("load telegram bot identity: %w", err)
+	}
+	id, _ := strconv.ParseInt(cred.Config["bot_id"], 10, 64)
+	return telegramBot{ID: id, Username: strings.TrimPrefix(cred.Config["bot_username"], "@")}, nil
+}
+
+// telegramRepliesToBot reports whether replyTo, a quoted reply_to_message,
+// was sent by bot. The quoted message is a full Telegram message, so only
+// its sender is decoded.
+func telegramRepliesToBot(replyTo json.RawMessage, bot telegramBot) bool {
+	if len(replyTo) == 0 {
+		return false
+	}
+	var quoted struct {
+		From struct {
+			ID       int64  `json:"id"`
+			Username string `json:"username"`
+		} `json:"from"`
+	}
+	if err := json.Unmarshal(replyTo, &quoted); err != nil {
+		return false
+	}
+	if bot.ID != 0 && quoted.From.ID == bot.ID {
+		return true
+	}
+	return bot.Username != "" && strings.EqualFold(quoted.From.Username, bot.Username)
+}
+
+// telegramGroupTask reports whether a group message is a task for bot: it
+// mentions the bot, starts with /agent, or replies to one of the bot's
+// messages. A leading @mention or /agent command is stripped from the
+// returned text.
+func telegramGroupTask(text string, entities []telegramEntity, repliesToBot bool, bot telegramBot) (string, bool) {
+	units := utf16.Encode([]rune(text))
+	addressed := repliesToBot
+	strip := 0
+	for _, entity := range entities {
+		if entity.Offset < 0 || entity.Length <= 0 || entity.Offset+entity.Length > len(units) {
+			continue
+		}
+		span := string(utf16.Decode(units[entity.Offset : entity.Offset+entity.Length]))
+		switch entity.Type {
+		case "mention":
+			if bot.Username == "" || !strings.EqualFold(strings.TrimPrefix(span, "@"), bot.Username) {
+				continue
+			}
+		case "bot_command":
+			// Commands may name their bot: /agent@my_bot.
+			command, target, _ := strings.Cut(span, "@")
+			if !strings.EqualFold(command, telegramAgentCommand) || entity.Offset != 0 {
+				continue
+			}
+			if target != "" && !strings.EqualFold(target, bot.Username) {
+				continue
+			}
+		default:
+			continue
+		}
+		addressed = true
+		if entity.Offset == strip {
+			strip = entity.Offset + entity.Length
+		}
+	}
+	if strip == 0 {
+		return text, addressed
+	}
+	rest := string(utf16.Decode(units[strip:]))
+	return strings.TrimLeftFunc(rest, func(r rune) bool { return unicode.IsSpace(r) || r == ',' || r == ':' }), addressed
+}
@@ -0,0 +1,139 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/channels"
+	"github.com/agentsquads/api/policies"
+)
+
+func TestTelegramGroupTask(t *testing.T) {
+	t.Parallel()
+	bot := telegramBot{ID: 42, Username: "squad_bot"}
+	tests := []struct {
+		name      string
+		text      string
+		entities  []telegramEntity
+		reply     bool
+		want      string
+		addressed bool
+	}{
+		{name: "chatter", text: "lunch at noon?", want: "lunch at noon?"},
+		{name: "leading mention", text: "@Squad_Bot, build the landing page", entities: []telegramEntity{{Type: "mention", Offset: 0, Length: 10}},
+			want: "build the landing page", addressed: true},
+		{name: "mention later", text: "can @squad_bot help?", entities: []telegramEntity{{Type: "mention", Offset: 4, Length: 10}},
+			want: "can @squad_bot help?", addressed: true},
+		{name: "other bot", text: "@other_bot do it", entities: []telegramEntity{{Type: "mention", Offset: 0, Length: 10}}, want: "@other_bot do it"},
+		{name: "agent command", text: "/agent@squad_bot fix the tests", entities: []telegramEntity{{Type: "bot_command", Offset: 0, Length: 16}},
+			want: "fix the tests", addressed: true},
+		{name: "command for another bot", text: "/agent@other_bot go", entities: []telegramEntity{{Type: "bot_command", Offset: 0, Length: 16}},
+			want: "/agent@other_bot go"},
+		{name: "other command", text: "/start", entities: []telegramEntity{{Type: "bot_command", Offset: 0, Length: 6}}, want: "/start"},
+		{name: "reply", text: "yes, ship it", reply: true, want: "yes, ship it", addressed: true},
+		// Offsets count UTF-16 units, so the emoji takes two.
+		{name: "utf16 offsets", text: "🚀 @squad_bot go", entities: []telegramEntity{{Type: "mention", Offset: 3, Length: 10}},
+			want: "🚀 @squad_bot go", addressed: true},
+		{name: "entity out of range", text: "hi", entities: []telegramEntity{{Type: "mention", Offset: 1, Length: 10}}, want: "hi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, addressed := telegramGroupTask(tt.text, tt.entities, tt.reply, bot)
+			if got != tt.want || addressed != tt.addressed {
+				t.Fatalf("telegramGroupTask() = %q, %v; want %q, %v", got, addressed, tt.want, tt.addressed)
+			}
+		})
+	}
+}
+
+func TestTelegramRepliesToBot(t *testing.T) {
+	t.Parallel()
+	bot := telegramBot{ID: 42, Username: "squad_bot"}
+	for raw, want := range map[string]bool{
+		`{"message_id":7,"from":{"id":42,"is_bot":true},"text":"done"}`: true,
+		`{"from":{"id":9,"username":"Squad_Bot"}}`:                      true,
+		`{"from":{"id":9,"username":"alice"}}`:                          false,
+		``:                                                              false,
+	} {
+		if got := telegramRepliesToBot(json.RawMessage(raw), bot); got != want {
+			t.Errorf("telegramRepliesToBot(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestProcessTelegramUpdateGroups(t *testing.T) {
+	t.Parallel()
+	const (
+		chatter   = `{"update_id":1,"message":{"text":"lunch?","chat":{"id":-100,"type":"supergroup"},"from":{"id":5}}}`
+		mentioned = `{"update_id":2,"message":{"text":"@squad_bot build it","entities":[{"type":"mention","offset":0,"length":10}],"chat":{"id":-100,"type":"group"},"from":{"id":5}}}`
+		private   = `{"update_id":3,"message":{"text":"build it","chat":{"id":5,"type":"private"},"from":{"id":5,"language_code":"pt-br"}}}`
+	)
+	tests := []struct {
+		name    string
+		body    string
+		always  bool
+		status  string
+		routed  string
+		gatesDB bool
+	}{
+		{name: "chatter is stored", body: chatter, status: "stored", gatesDB: true},
+		{name: "mention is routed", body: mentioned, status: "ok", routed: "build it", gatesDB: true},
+		{name: "private chat is routed", body: private, status: "ok", routed: "build it"},
+		{name: "always respond policy", body: chatter, always: true, status: "ok", routed: "lunch?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New: %v", err)
+			}
+			defer db.Close()
+
+			var routed, language string
+			h := &ChannelHandler{
+				Router:      channels.NewRouter(db, nil),
+				Credentials: channels.NewCredentialsStore(db),
+				Policies:    policies.NewStore(db),
+				route: func(_ context.Context, msg channels.InboundMessage) (channels.OutboundMessage, error) {
+					routed, language = msg.Content, msg.Metadata["language_code"]
+					return channels.OutboundMessage{}, nil
+				},
+			}
+			if tt.body != private {
+				mock.ExpectQuery("SELECT enabled").WithArgs("t1", policies.FeatureTelegramGroupAlwaysRespond).
+					WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(tt.always))
+			}
+			if tt.gatesDB {
+				mock.ExpectQuery("FROM channel_credentials").WithArgs("t1", "telegram").
+					WillReturnRows(sqlmock.NewRows([]string{"tenant_id", "channel", "config", "updated_at"}).
+						AddRow("t1", "telegram", `{"bot_id":"42","bot_username":"squad_bot"}`, time.Now()))
+			}
+			if tt.status == "stored" {
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO conversations").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("c1"))
+				mock.ExpectQuery("INSERT INTO messages").WithArgs("c1", "lunch?", "telegram", sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("m1"))
+				mock.ExpectCommit()
+			}
+
+			code, result, err := h.processTelegramUpdate(context.Background(), "t1", []byte(tt.body), h.routeNow)
+			if err != nil || code != 200 || result["status"] != tt.status {
+				t.Fatalf("processTelegramUpdate() = %d, %v, %v", code, result, err)
+			}
+			if routed != tt.routed {
+				t.Fatalf("routed %q, want %q", routed, tt.routed)
+			}
+			if tt.body == private && language != "pt-br" {
+				t.Fatalf("language_code = %q", language)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatalf("expectations: %v", err)
+			}
+		})
+	}
+}
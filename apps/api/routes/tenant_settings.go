@@ -0,0 +1,111 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/agentsquads/api/i18n"
+)
+
+// TenantSettingsHandler serves settings tenants manage themselves. Today that
+// is the locale of the system messages sent to their channels.
+type TenantSettingsHandler struct {
+	Locales *i18n.TenantLocales
+}
+
+func NewTenantSettingsHandler(locales *i18n.TenantLocales) *TenantSettingsHandler {
+	return &TenantSettingsHandler{Locales: locales}
+}
+
+func (h *TenantSettingsHandler) Mount(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/tenants/{id}/settings", h.handleGetSettings)
+	mux.HandleFunc("PUT /api/tenants/{id}/settings", h.handleUpdateSettings)
+}
+
+// settingsTenant returns the path's tenant id, writing the error response
+// when it is missing or outside the caller's tenant scope.
+func (h *TenantSettingsHandler) settingsTenant(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if h.Locales == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return "", false
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	if tenantID == "" {
+		writeError(w, http.StatusBadRequest, "missing tenant id")
+		return "", false
+	}
+	if scoped := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); scoped != "" && scoped != tenantID {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return "", false
+	}
+	return tenantID, true
+}
+
+func (h *TenantSettingsHandler) handleGetSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := h.settingsTenant(w, r)
+	if !ok {
+		return
+	}
+	locale, err := h.Locales.TenantLocale(r.Context(), tenantID)
+	if errors.Is(err, i18n.ErrTenantNotFound) {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	if err != nil {
+		slog.Error("load tenant settings failed", "tenant", tenantID, "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to load settings")
+		return
+	}
+	writeTenantSettings(w, tenantID, locale)
+}
+
+// handleUpdateSettings sets the tenant's locale from {"locale": "es"}. An
+// empty locale clears it, so messages follow the language of each channel
+// user where the channel reports one.
+func (h *TenantSettingsHandler) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := h.settingsTenant(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Locale *string `json:"locale"`
+	}
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.Locale == nil {
+		writeError(w, http.StatusBadRequest, "locale is required")
+		return
+	}
+	locale := ""
+	if raw := strings.TrimSpace(*req.Locale); raw != "" {
+		if locale = i18n.Normalize(raw); locale == "" {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("unsupported locale %q; choose one of: %s", raw, strings.Join(i18n.Locales(), ", ")))
+			return
+		}
+	}
+
+	err := h.Locales.SetTenantLocale(r.Context(), tenantID, locale)
+	if errors.Is(err, i18n.ErrTenantNotFound) {
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	if err != nil {
+		slog.Error("update tenant settings failed", "tenant", tenantID, "err", err)
+		writeError(w, http.StatusInternalServerError, "failed to update settings")
+		return
+	}
+	writeTenantSettings(w, tenantID, locale)
+}
+
+func writeTenantSettings(w http.ResponseWriter, tenantID, locale string) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tenant_id":         tenantID,
+		"locale":            locale,
+		"available_locales": i18n.Locales(),
+	})
+}
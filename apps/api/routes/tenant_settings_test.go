@@ -0,0 +1,59 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/i18n"
+)
+
+func TestTenantSettingsRoutes(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	NewTenantSettingsHandler(i18n.NewTenantLocales(db)).Mount(mux)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Tenant-ID", "t1")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodGet, "/api/tenants/t2/settings", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("cross-tenant status = %d", w.Code)
+	}
+
+	mock.ExpectQuery("SELECT COALESCE\\(locale, ''\\) FROM tenants").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"locale"}).AddRow(""))
+	w := do(http.MethodGet, "/api/tenants/t1/settings", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"locale":""`) || !strings.Contains(w.Body.String(), `"available_locales":["en","es","pt"]`) {
+		t.Fatalf("get status = %d body=%s", w.Code, w.Body.String())
+	}
+
+	mock.ExpectExec("UPDATE tenants SET locale").WithArgs("t1", "pt").WillReturnResult(sqlmock.NewResult(0, 1))
+	if w := do(http.MethodPut, "/api/tenants/t1/settings", `{"locale":"pt-BR"}`); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"locale":"pt"`) {
+		t.Fatalf("put status = %d body=%s", w.Code, w.Body.String())
+	}
+	mock.ExpectExec("UPDATE tenants SET locale").WithArgs("t1", "").WillReturnResult(sqlmock.NewResult(0, 1))
+	if w := do(http.MethodPut, "/api/tenants/t1/settings", `{"locale":""}`); w.Code != http.StatusOK {
+		t.Fatalf("clear status = %d body=%s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPut, "/api/tenants/t1/settings", `{"locale":"fr"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unsupported status = %d", w.Code)
+	}
+	if w := do(http.MethodPut, "/api/tenants/t1/settings", `{}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing locale status = %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
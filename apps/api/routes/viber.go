@@ -0,0 +1,170 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/agentsquads/api/channels"
+	"github.com/agentsquads/api/channels/adapters"
+	"github.com/agentsquads/api/policies"
+)
+
+// viberWebhookURL is registered with a per-tenant hook query parameter, since
+// Viber callbacks do not say which account they are for.
+const viberWebhookURL = "https://agentsquads.ai/api/channels/viber/webhook"
+
+type connectViberRequest struct {
+	TenantID  string `json:"tenant_id"`
+	AuthToken string `json:"auth_token"`
+}
+
+func (h *ChannelHandler) handleConnectViber(w http.ResponseWriter, r *http.Request) {
+	if h.Links == nil || h.Credentials == nil || h.Viber == nil {
+		writeError(w, http.StatusServiceUnavailable, "channel stores are not configured")
+		return
+	}
+
+	var req connectViberRequest
+	if err := decodeJSONStrict(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	tenantID := strings.TrimSpace(req.TenantID)
+	authToken := strings.TrimSpace(req.AuthToken)
+	if tenantID == "" || authToken == "" {
+		writeError(w, http.StatusBadRequest, "tenant_id and auth_token are required")
+		return
+	}
+	if !requireFeature(w, r, h.Policies, tenantID, policies.FeatureViber, "channels.connect") {
+		return
+	}
+
+	account, err := h.Viber.AccountInfo(r.Context(), authToken)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	webhookID := randomToken(24)
+	webhookURL := viberWebhookURL + "?" + url.Values{"hook": {webhookID}}.Encode()
+	if err := h.Viber.SetWebhook(r.Context(), authToken, webhookURL); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.Credentials.Upsert(r.Context(), tenantID, "viber", map[string]string{
+		"auth_token":   authToken,
+		"account_id":   account.ID,
+		"account_name": account.Name,
+		"webhook_url":  webhookURL,
+		"webhook_id":   webhookID,
+	}); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to save viber credentials")
+		return
+	}
+
+	if err := h.Links.LinkChannel(tenantID, "viber", account.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to link viber channel")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"status": "connected",
+		"channel": map[string]string{
+			"channel":    "viber",
+			"account_id": account.ID,
+			"name":       account.Name,
+		},
+	})
+}
+
+func (h *ChannelHandler) handleViberWebhook(w http.ResponseWriter, r *http.Request) {
+	if h.Router == nil || h.Credentials == nil {
+		writeError(w, http.StatusServiceUnavailable, "channel webhook is not configured")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid webhook body")
+		return
+	}
+	event, err := adapters.ParseViberEvent(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid viber payload")
+		return
+	}
+	// set_webhook probes the URL before the account's credentials are saved.
+	// The probe carries no user data, so it is acknowledged unauthenticated.
+	if event.Event == "webhook" {
+		writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+		return
+	}
+
+	tenantID, err := h.Credentials.FindTenantByViberWebhookID(r.Context(), r.URL.Query().Get("hook"))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid viber webhook")
+		return
+	}
+	cred, err := h.Credentials.GetByTenantChannel(r.Context(), tenantID, "viber")
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid viber webhook")
+		return
+	}
+	if !adapters.VerifyViberSignature(cred.Config["auth_token"], body, r.Header.Get("X-Viber-Content-Signature")) {
+		writeError(w, http.StatusUnauthorized, "invalid viber signature")
+		return
+	}
+
+	status, result, err := h.processViberEvent(r.Context(), tenantID, body, h.queueOrRoute(body, r.Header))
+	if err != nil {
+		h.recordWebhookFailure(r.Context(), "viber", tenantID, body, r.Header, err)
+		writeError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, status, result)
+}
+
+// processViberEvent routes an authenticated Viber callback through routeMsg.
+// Like processTelegramUpdate it is shared with webhook-failure replay.
+func (h *ChannelHandler) processViberEvent(ctx context.Context, tenantID string, body []byte, routeMsg inboundRoute) (int, map[string]any, error) {
+	event, err := adapters.ParseViberEvent(body)
+	if err != nil {
+		return http.StatusBadRequest, nil, err
+	}
+	if event.Event != "message" || event.MessageType != "text" || event.Text == "" {
+		return http.StatusOK, map[string]any{"status": "ignored"}, nil
+	}
+	if event.SenderID == "" {
+		return http.StatusBadRequest, nil, errors.New("invalid viber payload: sender id is required")
+	}
+
+	if err := routeMsg(ctx, channels.InboundMessage{
+		TenantID: tenantID,
+		Content:  event.Text,
+		Channel:  "viber",
+		Metadata: map[string]string{
+			"channel_user_id":     event.SenderID,
+			"user_id":             event.SenderID,
+			"viber_message_token": event.MessageToken,
+		},
+	}); err != nil {
+		if errors.Is(err, channels.ErrChannelDisabled) {
+			return http.StatusOK, map[string]any{"status": "disabled"}, nil
+		}
+		if errors.Is(err, channels.ErrMessageModerated) {
+			return http.StatusOK, map[string]any{"status": "blocked"}, nil
+		}
+		status := http.StatusInternalServerError
+		if isInboundValidationError(err) {
+			status = http.StatusBadRequest
+		}
+		return status, nil, err
+	}
+	return http.StatusOK, map[string]any{"status": "ok"}, nil
+}
@@ -0,0 +1,295 @@
+package routes
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/channels"
+)
+
+const (
+	maxWebhookFailureBody          = 64 * 1024
+	defaultWebhookFailureRetention = 7 * 24 * time.Hour
+	webhookFailurePurgeInterval    = time.Hour
+)
+
+// sensitiveWebhookHeaders are stored as SHA-256 digests so replay tooling can
+// correlate requests without keeping the secret itself.
+var sensitiveWebhookHeaders = map[string]struct{}{
+	"Authorization":                   {},
+	"Cookie":                          {},
+	"X-Api-Key":                       {},
+	"X-Hub-Signature":                 {},
+	"X-Hub-Signature-256":             {},
+	"X-Telegram-Bot-Api-Secret-Token": {},
+	"X-Viber-Content-Signature":       {},
+}
+
+// recordWebhookFailure stores the raw payload of a webhook that failed after
+// authentication. Recording is best-effort and never affects the response.
+func (h *ChannelHandler) recordWebhookFailure(ctx context.Context, channel, tenantID string, body []byte, headers http.Header, failure error) {
+	h.storeWebhookFailure(ctx, channel, tenantID, body, sanitizeWebhookHeaders(headers), failure)
+}
+
+// recordQueuedFailure records a webhook message the inbound queue failed to
+// route. Every failed message of a WhatsApp batch is recorded with the whole
+// payload, which replay routes again in full.
+func (h *ChannelHandler) recordQueuedFailure(ctx context.Context, entry channels.InboundEntry, failure error) {
+	h.storeWebhookFailure(ctx, entry.Message.Channel, entry.Message.TenantID, entry.Webhook, entry.Headers, failure)
+}
+
+func (h *ChannelHandler) storeWebhookFailure(ctx context.Context, channel, tenantID string, body []byte, headers map[string]string, failure error) {
+	if h.DB == nil || failure == nil {
+		return
+	}
+
+	truncated := len(body) > maxWebhookFailureBody
+	if truncated {
+		body = body[:maxWebhookFailureBody]
+	}
+	headerJSON, err := json.Marshal(headers)
+	if err != nil {
+		headerJSON = []byte("{}")
+	}
+
+	_, err = h.DB.ExecContext(ctx, `
+		INSERT INTO webhook_failures (channel, tenant_id, body, body_truncated, headers, error)
+		VALUES ($1, $2, $3, $4, $5::jsonb, $6)
+	`, channel, emptyToNil(tenantID), body, truncated, string(headerJSON), failure.Error())
+	if err != nil {
+		slog.Error("failed to record webhook failure", "channel", channel, "tenant_id", tenantID, "err", err)
+	}
+}
+
+func sanitizeWebhookHeaders(headers http.Header) map[string]string {
+	out := make(map[string]string, len(headers))
+	for name, values := range headers {
+		value := strings.Join(values, ", ")
+		if _, ok := sensitiveWebhookHeaders[http.CanonicalHeaderKey(name)]; ok {
+			sum := sha256.Sum256([]byte(value))
+			value = "sha256:" + hex.EncodeToString(sum[:])
+		}
+		out[http.CanonicalHeaderKey(name)] = value
+	}
+	return out
+}
+
+func (h *ChannelHandler) handleListWebhookFailures(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 50
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = min(n, 500)
+	}
+	var since any
+	if raw := strings.TrimSpace(query.Get("since")); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "since must be an RFC3339 timestamp")
+			return
+		}
+		since = t
+	}
+
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT id, channel, tenant_id, body, body_truncated, headers, error, created_at,
+			replay_count, last_replayed_at, last_replay_error
+		FROM webhook_failures
+		WHERE ($1::text IS NULL OR channel = $1)
+		  AND ($2::uuid IS NULL OR tenant_id = $2)
+		  AND ($3::timestamptz IS NULL OR created_at >= $3)
+		ORDER BY created_at DESC
+		LIMIT $4
+	`, emptyToNil(query.Get("channel")), emptyToNil(query.Get("tenant_id")), since, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query webhook failures")
+		return
+	}
+	defer rows.Close()
+
+	failures := make([]map[string]any, 0)
+	for rows.Next() {
+		var (
+			id              string
+			channel         string
+			tenantID        sql.NullString
+			body            []byte
+			bodyTruncated   bool
+			headers         []byte
+			failure         string
+			createdAt       time.Time
+			replayCount     int
+			lastReplayedAt  sql.NullTime
+			lastReplayError sql.NullString
+		)
+		if err := rows.Scan(&id, &channel, &tenantID, &body, &bodyTruncated, &headers, &failure, &createdAt, &replayCount, &lastReplayedAt, &lastReplayError); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to scan webhook failure")
+			return
+		}
+		failures = append(failures, map[string]any{
+			"id":                id,
+			"channel":           channel,
+			"tenant_id":         nullString(tenantID),
+			"body":              string(body),
+			"body_truncated":    bodyTruncated,
+			"headers":           json.RawMessage(headers),
+			"error":             failure,
+			"created_at":        createdAt,
+			"replay_count":      replayCount,
+			"last_replayed_at":  nullTime(lastReplayedAt),
+			"last_replay_error": nullString(lastReplayError),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed while reading webhook failures")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"failures": failures})
+}
+
+// handleReplayWebhookFailure re-runs a stored payload through the current
+// parsing and routing code and records the outcome on the failure row.
+func (h *ChannelHandler) handleReplayWebhookFailure(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil {
+		writeError(w, http.StatusServiceUnavailable, "database is not configured")
+		return
+	}
+	if h.Router == nil || h.Credentials == nil {
+		writeError(w, http.StatusServiceUnavailable, "channel webhook is not configured")
+		return
+	}
+
+	id := strings.TrimSpace(r.PathValue("id"))
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "missing webhook failure id")
+		return
+	}
+
+	var (
+		channel   string
+		tenantID  sql.NullString
+		body      []byte
+		truncated bool
+	)
+	err := h.DB.QueryRowContext(r.Context(), `
+		SELECT channel, tenant_id, body, body_truncated
+		FROM webhook_failures
+		WHERE id = $1
+	`, id).Scan(&channel, &tenantID, &body, &truncated)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "webhook failure not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load webhook failure")
+		return
+	}
+	if truncated {
+		writeError(w, http.StatusUnprocessableEntity, "stored payload was truncated and cannot be replayed")
+		return
+	}
+
+	outcome := map[string]any{"id": id, "channel": channel}
+	var replayErr error
+	switch channel {
+	case "telegram":
+		if !tenantID.Valid {
+			writeError(w, http.StatusUnprocessableEntity, "telegram failure has no tenant to replay against")
+			return
+		}
+		status, result, err := h.processTelegramUpdate(r.Context(), tenantID.String, body, h.routeNow)
+		outcome["http_status"] = status
+		outcome["result"] = result
+		replayErr = err
+	case "whatsapp":
+		processed, _, err := h.processWhatsAppPayload(r.Context(), body, h.routeNow)
+		outcome["processed"] = processed
+		replayErr = err
+	case "viber":
+		if !tenantID.Valid {
+			writeError(w, http.StatusUnprocessableEntity, "viber failure has no tenant to replay against")
+			return
+		}
+		status, result, err := h.processViberEvent(r.Context(), tenantID.String, body, h.routeNow)
+		outcome["http_status"] = status
+		outcome["result"] = result
+		replayErr = err
+	default:
+		writeError(w, http.StatusUnprocessableEntity, "unsupported webhook channel: "+channel)
+		return
+	}
+
+	outcome["status"] = "ok"
+	var lastError any
+	if replayErr != nil {
+		outcome["status"] = "failed"
+		outcome["error"] = replayErr.Error()
+		lastError = replayErr.Error()
+	}
+
+	if _, err := h.DB.ExecContext(r.Context(), `
+		UPDATE webhook_failures
+		SET replay_count = replay_count + 1, last_replayed_at = NOW(), last_replay_error = $2
+		WHERE id = $1
+	`, id, lastError); err != nil {
+		slog.Error("failed to record webhook replay", "id", id, "err", err)
+	}
+
+	writeJSON(w, http.StatusOK, outcome)
+}
+
+// PurgeWebhookFailures deletes stored failures older than the retention
+// window (WEBHOOK_FAILURE_RETENTION, default 7 days) every hour until ctx is
+// cancelled.
+func (h *ChannelHandler) PurgeWebhookFailures(ctx context.Context) {
+	if h.DB == nil {
+		return
+	}
+	retention := webhookFailureRetention()
+	ticker := time.NewTicker(webhookFailurePurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		res, err := h.DB.ExecContext(ctx, `DELETE FROM webhook_failures WHERE created_at < $1`, time.Now().Add(-retention))
+		if err != nil {
+			slog.Error("failed to purge webhook failures", "err", err)
+		} else if n, _ := res.RowsAffected(); n > 0 {
+			slog.Info("purged webhook failures", "count", n, "retention", retention)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func webhookFailureRetention() time.Duration {
+	if raw := strings.TrimSpace(os.Getenv("WEBHOOK_FAILURE_RETENTION")); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultWebhookFailureRetention
+}
@@ -0,0 +1,50 @@
+package routes
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/agentsquads/api/channels"
+)
+
+// inboundRoute routes one message parsed from a webhook payload.
+type inboundRoute func(ctx context.Context, msg channels.InboundMessage) error
+
+// UseInboundQueue makes the webhooks queue their messages on q instead of
+// routing them before responding, and records the messages q fails to route
+// as webhook failures.
+func (h *ChannelHandler) UseInboundQueue(q *channels.InboundQueue) {
+	q.SetFailureHandler(h.recordQueuedFailure)
+	h.Inbound = q
+}
+
+// routeNow routes msg and waits for the agent. Webhook-failure replay uses it
+// to report the outcome.
+func (h *ChannelHandler) routeNow(ctx context.Context, msg channels.InboundMessage) error {
+	route := h.route
+	if route == nil {
+		route = h.Router.Route
+	}
+	_, err := route(ctx, msg)
+	return err
+}
+
+// queueOrRoute returns the route for the messages of one webhook request.
+// Providers retry webhooks that are slow to respond, and routing waits for a
+// cold container or a swarm run, so messages go on the inbound queue. Without
+// a queue, or when Redis cannot take the message, they are routed in place.
+func (h *ChannelHandler) queueOrRoute(body []byte, headers http.Header) inboundRoute {
+	if h.Inbound == nil {
+		return h.routeNow
+	}
+	sanitized := sanitizeWebhookHeaders(headers)
+	return func(ctx context.Context, msg channels.InboundMessage) error {
+		err := h.Inbound.Enqueue(ctx, channels.InboundEntry{Message: msg, Webhook: body, Headers: sanitized})
+		if err == nil {
+			return nil
+		}
+		slog.Warn("inbound queue unavailable, routing webhook message synchronously", "tenant_id", msg.TenantID, "channel", msg.Channel, "err", err)
+		return h.routeNow(ctx, msg)
+	}
+}
@@ -0,0 +1,53 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/channels"
+)
+
+func TestQueueOrRouteFallsBackWithoutRedis(t *testing.T) {
+	t.Parallel()
+	var routed []string
+	h := &ChannelHandler{route: func(_ context.Context, msg channels.InboundMessage) (channels.OutboundMessage, error) {
+		routed = append(routed, msg.Content)
+		return channels.OutboundMessage{}, nil
+	}}
+	msg := channels.InboundMessage{TenantID: "t1", Channel: "telegram", Content: "hi"}
+
+	if err := h.queueOrRoute(nil, http.Header{})(context.Background(), msg); err != nil || len(routed) != 1 {
+		t.Fatalf("without a queue: err=%v routed=%v", err, routed)
+	}
+	// The queue cannot reach Redis, so the message is routed in place.
+	h.UseInboundQueue(channels.NewInboundQueue(nil, nil))
+	if err := h.queueOrRoute(nil, http.Header{})(context.Background(), msg); err != nil || len(routed) != 2 {
+		t.Fatalf("with an unreachable queue: err=%v routed=%v", err, routed)
+	}
+}
+
+func TestRecordQueuedFailure(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	h := &ChannelHandler{DB: db}
+	headers := sanitizeWebhookHeaders(http.Header{"X-Telegram-Bot-Api-Secret-Token": {"secret"}})
+	mock.ExpectExec("INSERT INTO webhook_failures").
+		WithArgs("telegram", "t1", []byte(`{"update_id":1}`), false, `{"X-Telegram-Bot-Api-Secret-Token":"`+headers["X-Telegram-Bot-Api-Secret-Token"]+`"}`, "route failed").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	h.recordQueuedFailure(context.Background(), channels.InboundEntry{
+		Message: channels.InboundMessage{TenantID: "t1", Channel: "telegram"},
+		Webhook: []byte(`{"update_id":1}`),
+		Headers: headers,
+	}, errors.New("route failed"))
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
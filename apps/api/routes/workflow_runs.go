@@ -0,0 +1,196 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/agentsquads/api/policies"
+	"github.com/agentsquads/api/workflows"
+)
+
+// maxConcurrentWorkflowRuns caps the workflow runs one tenant can have in
+// flight on this instance.
+const maxConcurrentWorkflowRuns = 10
+
+// WorkflowRunHandler lets tenants run a workflow in one request instead of
+// stepping through it with the admin workflow routes.
+type WorkflowRunHandler struct {
+	DB       *sql.DB
+	Runner   *workflows.Runner
+	Policies *policies.Store
+
+	mu     sync.Mutex
+	active map[string]int // tenant id -> runs in flight
+}
+
+func NewWorkflowRunHandler(db *sql.DB, runner *workflows.Runner) *WorkflowRunHandler {
+	return &WorkflowRunHandler{DB: db, Runner: runner, active: make(map[string]int)}
+}
+
+func (h *WorkflowRunHandler) Mount(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/tenants/{id}/workflows/{name}/run", h.handleRunWorkflow)
+}
+
+// handleRunWorkflow fills the workflow's steps from {"input": {...}} and
+// returns the compiled brief. With Accept: text/event-stream each accepted
+// step is streamed before the result.
+func (h *WorkflowRunHandler) handleRunWorkflow(w http.ResponseWriter, r *http.Request) {
+	if h.DB == nil || h.Runner == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "workflows are not configured")
+		return
+	}
+	tenantID := strings.TrimSpace(r.PathValue("id"))
+	name := strings.TrimSpace(r.PathValue("name"))
+	if tenantID == "" || name == "" {
+		writeAPIError(w, http.StatusBadRequest, "missing tenant id or workflow name")
+		return
+	}
+	if scoped := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); scoped != "" && scoped != tenantID {
+		writeAPIError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+
+	var req struct {
+		Input map[string]any `json:"input"`
+	}
+	if err := decodeJSONStrict(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if _, ok := h.Runner.Workflow(name); !ok {
+		writeAPIError(w, http.StatusNotFound, "workflow not found")
+		return
+	}
+	if !requireFeature(w, r, h.Policies, tenantID, policies.FeatureWorkflows, "workflows.run") {
+		return
+	}
+	if !h.acquire(tenantID) {
+		writeAPIError(w, http.StatusTooManyRequests, fmt.Sprintf("at most %d workflow runs can be in progress", maxConcurrentWorkflowRuns))
+		return
+	}
+	defer h.release(tenantID)
+
+	runID, err := h.recordStart(r.Context(), tenantID, name, req.Input)
+	if err != nil {
+		slog.Error("record workflow run failed", "tenant", tenantID, "workflow", name, "err", err)
+		writeAPIError(w, http.StatusInternalServerError, "failed to start workflow run")
+		return
+	}
+
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		brief, err := h.Runner.Run(r.Context(), name, req.Input)
+		h.recordFinish(r.Context(), runID, brief, err)
+		if err != nil {
+			writeWorkflowRunError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"run_id":      runID,
+			"workflow_id": name,
+			"status":      "completed",
+			"brief":       brief,
+		})
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.recordFinish(r.Context(), runID, "", errors.New("streaming is not supported"))
+		writeAPIError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	writeWorkflowEvent(w, flusher, "started", map[string]any{"run_id": runID, "workflow_id": name})
+
+	brief, err := h.Runner.RunWithProgress(r.Context(), name, req.Input, func(step workflows.Step, value string) {
+		writeWorkflowEvent(w, flusher, "step", map[string]any{"step_id": step.ID, "prompt": step.Prompt, "value": value})
+	})
+	h.recordFinish(r.Context(), runID, brief, err)
+	if err != nil {
+		writeSSEError(w, flusher, err.Error())
+		return
+	}
+	writeWorkflowEvent(w, flusher, "complete", map[string]any{"run_id": runID, "status": "completed", "brief": brief})
+}
+
+func (h *WorkflowRunHandler) acquire(tenantID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.active[tenantID] >= maxConcurrentWorkflowRuns {
+		return false
+	}
+	h.active[tenantID]++
+	return true
+}
+
+func (h *WorkflowRunHandler) release(tenantID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.active[tenantID] <= 1 {
+		delete(h.active, tenantID)
+		return
+	}
+	h.active[tenantID]--
+}
+
+func (h *WorkflowRunHandler) recordStart(ctx context.Context, tenantID, workflowID string, input map[string]any) (string, error) {
+	if input == nil {
+		input = map[string]any{}
+	}
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return "", fmt.Errorf("marshal input: %w", err)
+	}
+	var id string
+	err = h.DB.QueryRowContext(ctx, `
+		INSERT INTO workflow_runs (tenant_id, workflow_id, inputs, status)
+		VALUES ($1, $2, $3::jsonb, 'running')
+		RETURNING id
+	`, tenantID, workflowID, raw).Scan(&id)
+	return id, err
+}
+
+// recordFinish stores the outcome of a run. It runs even when the client has
+// gone away, so the history does not keep runs stuck in "running".
+func (h *WorkflowRunHandler) recordFinish(ctx context.Context, runID, brief string, runErr error) {
+	status, errText := "completed", ""
+	if runErr != nil {
+		status, errText = "failed", runErr.Error()
+	}
+	_, err := h.DB.ExecContext(context.WithoutCancel(ctx), `
+		UPDATE workflow_runs
+		SET status = $2, brief = NULLIF($3, ''), error = NULLIF($4, ''), finished_at = NOW()
+		WHERE id = $1
+	`, runID, status, brief, errText)
+	if err != nil {
+		slog.Error("record workflow run result failed", "run", runID, "err", err)
+	}
+}
+
+func writeWorkflowRunError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, workflows.ErrInvalidInput):
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, workflows.ErrWorkflowNotFound):
+		writeAPIError(w, http.StatusNotFound, "workflow not found")
+	default:
+		writeAPIError(w, http.StatusInternalServerError, "workflow run failed")
+	}
+}
+
+func writeWorkflowEvent(w io.Writer, flusher http.Flusher, event string, payload any) {
+	data, _ := json.Marshal(payload)
+	_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	flusher.Flush()
+}
@@ -0,0 +1,159 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/policies"
+	"github.com/agentsquads/api/workflows"
+)
+
+func newWorkflowRunTestHandler(t *testing.T) (*WorkflowRunHandler, sqlmock.Sqlmock, *http.ServeMux) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	runner := workflows.NewRunner(map[string]workflows.Workflow{
+		"research": {
+			ID:   "research",
+			Name: "Research",
+			Steps: []workflows.Step{
+				{ID: "topic", Type: "text", Prompt: "Topic?"},
+				{ID: "depth", Type: "choice", Prompt: "Depth for {{step.topic}}?", Options: []string{"quick", "deep"}},
+			},
+		},
+	})
+	h := NewWorkflowRunHandler(db, runner)
+	h.Policies = policies.NewStore(db)
+	mux := http.NewServeMux()
+	h.Mount(mux)
+	return h, mock, mux
+}
+
+func expectWorkflowsEnabled(mock sqlmock.Sqlmock, enabled bool) {
+	mock.ExpectQuery("SELECT enabled").WithArgs("t1", policies.FeatureWorkflows).
+		WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(enabled))
+}
+
+func workflowRunRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/tenants/t1/workflows/research/run", bytes.NewBufferString(body))
+	req.Header.Set("X-Tenant-ID", "t1")
+	return req
+}
+
+func TestRunWorkflowJSON(t *testing.T) {
+	t.Parallel()
+	_, mock, mux := newWorkflowRunTestHandler(t)
+
+	expectWorkflowsEnabled(mock, true)
+	mock.ExpectQuery("INSERT INTO workflow_runs").WithArgs("t1", "research", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("run-1"))
+	mock.ExpectExec("UPDATE workflow_runs").WithArgs("run-1", "completed", sqlmock.AnyArg(), "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, workflowRunRequest(`{"input":{"topic":"solar","depth":"deep"}}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		RunID string `json:"run_id"`
+		Brief string `json:"brief"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.RunID != "run-1" || !strings.Contains(resp.Brief, "### Depth for solar?\ndeep") {
+		t.Fatalf("unexpected response: %s", w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRunWorkflowStreamsSteps(t *testing.T) {
+	t.Parallel()
+	_, mock, mux := newWorkflowRunTestHandler(t)
+
+	expectWorkflowsEnabled(mock, true)
+	mock.ExpectQuery("INSERT INTO workflow_runs").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("run-2"))
+	mock.ExpectExec("UPDATE workflow_runs").WithArgs("run-2", "completed", sqlmock.AnyArg(), "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req := workflowRunRequest(`{"input":{"topic":"solar","depth":"quick"}}`)
+	req.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	body := w.Body.String()
+	if w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("content type = %q", w.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{"event: started", `"step_id":"topic"`, `"prompt":"Depth for solar?"`, "event: complete"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("stream missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestRunWorkflowRejections(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unknown workflow", func(t *testing.T) {
+		t.Parallel()
+		_, _, mux := newWorkflowRunTestHandler(t)
+		req := httptest.NewRequest(http.MethodPost, "/api/tenants/t1/workflows/missing/run", bytes.NewBufferString(`{}`))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("status = %d, want 404", w.Code)
+		}
+	})
+
+	t.Run("policy disabled", func(t *testing.T) {
+		t.Parallel()
+		_, mock, mux := newWorkflowRunTestHandler(t)
+		expectWorkflowsEnabled(mock, false)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, workflowRunRequest(`{"input":{}}`))
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("status = %d, want 403", w.Code)
+		}
+	})
+
+	t.Run("invalid input is recorded as failed", func(t *testing.T) {
+		t.Parallel()
+		_, mock, mux := newWorkflowRunTestHandler(t)
+		expectWorkflowsEnabled(mock, true)
+		mock.ExpectQuery("INSERT INTO workflow_runs").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("run-3"))
+		mock.ExpectExec("UPDATE workflow_runs").WithArgs("run-3", "failed", "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, workflowRunRequest(`{"input":{"topic":"solar","depth":"forever"}}`))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want 400 body=%s", w.Code, w.Body.String())
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("expectations: %v", err)
+		}
+	})
+
+	t.Run("concurrency limit", func(t *testing.T) {
+		t.Parallel()
+		h, mock, mux := newWorkflowRunTestHandler(t)
+		h.active["t1"] = maxConcurrentWorkflowRuns
+		expectWorkflowsEnabled(mock, true)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, workflowRunRequest(`{"input":{}}`))
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("status = %d, want 429", w.Code)
+		}
+	})
+}
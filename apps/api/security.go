@@ -12,8 +12,30 @@ import (
 
 const maxRequestBodyBytes int64 = 1 << 20 // 1 MiB
 
+// ownBodyLimitPaths enforce their own, larger body limit: the LLM proxy
+// accepts long-context prompts well past 1 MiB, and bulk credit imports take
+// CSVs of up to 10,000 rows.
+var ownBodyLimitPaths = map[string]struct{}{
+	"/v1/chat/completions":           {},
+	"/v1/chat/completions/best-of-n": {},
+	"/api/admin/credits/bulk-adjust": {},
+}
+
+// ownsBodyLimit reports whether the handler for path enforces its own body
+// limit. Container file copies take uploads of up to 10 MB.
+func ownsBodyLimit(path string) bool {
+	if _, ok := ownBodyLimitPaths[path]; ok {
+		return true
+	}
+	return strings.HasPrefix(path, "/api/admin/tenants/") && strings.HasSuffix(path, "/container/copy-files")
+}
+
 func applyRequestBodyLimit(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ownsBodyLimit(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
 		switch r.Method {
 		case http.MethodPost, http.MethodPut, http.MethodPatch:
 			r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
@@ -63,12 +85,32 @@ func isProtectedPath(path string) bool {
 	if path == "/" || path == "/health" {
 		return false
 	}
-	if path == "/api/channels/telegram/webhook" || path == "/api/channels/whatsapp/webhook" {
+	switch path {
+	case "/api/channels/telegram/webhook", "/api/channels/whatsapp/webhook", "/api/channels/viber/webhook":
+		// Providers authenticate their callbacks with their own signatures.
+		return false
+	}
+	if path == apiSpecPath || path == apiDocsPath {
+		return false
+	}
+	if isConversationExportDownload(path) {
+		// The signed, expiring URL is the credential.
 		return false
 	}
 	return strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/v1/")
 }
 
+// isConversationExportDownload reports whether path is
+// /api/conversation-exports/{id}/download.
+func isConversationExportDownload(path string) bool {
+	id, ok := strings.CutPrefix(path, "/api/conversation-exports/")
+	if !ok {
+		return false
+	}
+	id, ok = strings.CutSuffix(id, "/download")
+	return ok && id != "" && !strings.Contains(id, "/")
+}
+
 func validateJWT(tokenString, secret string) error {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -1,12 +1,22 @@
 package main
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/media"
+	"github.com/agentsquads/api/routes"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -40,6 +50,7 @@ func TestApplyAuth(t *testing.T) {
 		wantNext   bool
 	}{
 		{name: "public path", path: "/health", wantStatus: 200, wantNext: true},
+		{name: "viber webhook", path: "/api/channels/viber/webhook", serviceKey: "k1", wantStatus: 200, wantNext: true},
 		{name: "missing config", path: "/api/x", wantStatus: 500},
 		{name: "service api key", path: "/api/x", serviceKey: "k1", headers: map[string]string{"X-Service-API-Key": "k1"}, wantStatus: 200, wantNext: true},
 		{name: "jwt auth", path: "/api/x", jwtSecret: "s1", headers: map[string]string{"Authorization": "Bearer " + signJWT(t, "s1")}, wantStatus: 200, wantNext: true},
@@ -87,10 +98,15 @@ func TestApplyAuth(t *testing.T) {
 
 func TestIsProtectedPathAndValidateJWT(t *testing.T) {
 	t.Parallel()
-	if isProtectedPath("/") || isProtectedPath("/health") || isProtectedPath("/api/channels/telegram/webhook") {
+	if isProtectedPath("/") || isProtectedPath("/health") || isProtectedPath("/api/channels/telegram/webhook") ||
+		isProtectedPath("/api/channels/whatsapp/webhook") || isProtectedPath("/api/channels/viber/webhook") {
 		t.Fatalf("public paths should be unprotected")
 	}
-	if !isProtectedPath("/api/tenants") {
+	if isProtectedPath("/api/conversation-exports/e1/download") {
+		t.Fatalf("signed export downloads should be unprotected")
+	}
+	if !isProtectedPath("/api/tenants") || !isProtectedPath("/api/conversation-exports") ||
+		!isProtectedPath("/api/conversation-exports/e1/x/download") {
 		t.Fatalf("expected protected api path")
 	}
 	if err := validateJWT(signJWT(t, "s"), "s"); err != nil {
@@ -110,3 +126,79 @@ func signJWT(t *testing.T, secret string) string {
 	}
 	return s
 }
+
+func TestApplyRequestBodyLimitSkipsOwnLimitPaths(t *testing.T) {
+	t.Parallel()
+	h := applyRequestBodyLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		if n != maxRequestBodyBytes+10 {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, path := range []string{"/v1/chat/completions", "/api/admin/tenants/t1/container/copy-files"} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(strings.Repeat("a", int(maxRequestBodyBytes)+10)))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status=%d", path, w.Code)
+		}
+	}
+}
+
+func TestConversationExportDownloadWithoutAPIAuth(t *testing.T) {
+	t.Setenv("SERVICE_API_KEY", "k1")
+	t.Setenv("API_JWT_SECRET", "s1")
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	blobs := media.NewLocalBlobStore(t.TempDir())
+	if err := blobs.Put(context.Background(), "exports/t1/e1.zip", "application/zip", []byte("zip")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	channels := routes.NewChannelHandler(db, nil, nil, nil)
+	channels.Blobs = blobs
+	channels.ExportSecret = "export-secret"
+	mux := http.NewServeMux()
+	channels.Mount(mux)
+	h := applyAuth(mux)
+
+	expires := time.Now().Add(time.Hour).Unix()
+	mac := hmac.New(sha256.New, []byte("export-secret"))
+	fmt.Fprintf(mac, "e1.%d", expires)
+	signature := hex.EncodeToString(mac.Sum(nil))
+	link := fmt.Sprintf("/api/conversation-exports/e1/download?expires=%d", expires)
+
+	for name, target := range map[string]string{
+		"missing signature":  link,
+		"tampered signature": link + "&signature=00" + signature[2:],
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("%s: status = %d", name, w.Code)
+		}
+	}
+
+	mock.ExpectQuery("FROM conversation_exports").WithArgs("e1").
+		WillReturnRows(sqlmock.NewRows([]string{"status", "blob_key", "error_message"}).AddRow("ready", "exports/t1/e1.zip", nil))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, link+"&signature="+signature, nil))
+	if w.Code != http.StatusOK || w.Body.String() != "zip" {
+		t.Fatalf("signed download status = %d body = %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+
+	// Other export routes still need API auth.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/conversation-exports", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated export start status = %d", w.Code)
+	}
+}
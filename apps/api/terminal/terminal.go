@@ -15,6 +15,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/agentsquads/api/policies"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
 	"github.com/gorilla/websocket"
@@ -74,8 +75,9 @@ type resizeMsg struct {
 
 // Handler returns an http.Handler that upgrades to WebSocket and bridges
 // to a Docker exec TTY session for the tenant identified in the URL path.
+// When checker is non-nil, tenants with the terminal policy disabled get a 403.
 // Expected route: GET /api/tenants/{id}/terminal
-func Handler(db *sql.DB) http.Handler {
+func Handler(db *sql.DB, checker policies.Checker) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		tenantID := r.PathValue("id")
 		if tenantID == "" {
@@ -85,6 +87,20 @@ func Handler(db *sql.DB) http.Handler {
 
 		log := slog.With("component", "terminal", "tenant", tenantID)
 
+		if checker != nil {
+			enabled, err := checker.FeatureEnabled(r.Context(), tenantID, policies.FeatureTerminal)
+			if err != nil {
+				log.Error("policy check failed", "err", err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			if !enabled {
+				policies.RecordDenial(r.Context(), tenantID, policies.FeatureTerminal, "terminal")
+				http.Error(w, "terminal is disabled for this tenant", http.StatusForbidden)
+				return
+			}
+		}
+
 		// Look up container ID from DB.
 		containerID, err := getContainerID(r.Context(), db, tenantID)
 		if err != nil {
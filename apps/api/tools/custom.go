@@ -0,0 +1,395 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxCustomToolCallsPerRun caps custom tool calls within one tool loop.
+	maxCustomToolCallsPerRun = 20
+	// maxCustomToolResponse caps how much of an endpoint's response is handed
+	// back to the model.
+	maxCustomToolResponse = 16 * 1024
+	// maxCustomToolParameters caps the size of a tool's JSON schema.
+	maxCustomToolParameters  = 8 * 1024
+	maxCustomToolDescription = 1024
+	defaultCustomToolTimeout = 10
+	maxCustomToolTimeout     = 30
+
+	// CustomToolSecretPlaceholder marks where the secret goes in an auth
+	// header template.
+	CustomToolSecretPlaceholder = "{{secret}}"
+
+	customToolTimestampHeader = "X-AgentSquads-Timestamp"
+	customToolSignatureHeader = "X-AgentSquads-Signature"
+)
+
+// ErrInvalidCustomTool wraps the reasons a custom tool registration is
+// rejected.
+var ErrInvalidCustomTool = errors.New("invalid custom tool")
+
+var (
+	customToolName   = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	headerNameToken  = regexp.MustCompile(`^[A-Za-z0-9!#$%&'*+.^_|~-]+$`)
+	defaultToolInput = json.RawMessage(`{"type":"object","properties":{}}`)
+)
+
+// builtinTools are the names of the tools the registry provides itself,
+// including the ones registered by SetDBQuery, SetWebhookTool,
+// SetMemoryBackend, SetMediaReader and registerGitHub. Custom tools cannot
+// use them.
+var builtinTools = map[string]bool{
+	"web_search":         true,
+	"web_fetch":          true,
+	"memory_store":       true,
+	"memory_recall":      true,
+	"memory_persist":     true,
+	"file_read":          true,
+	"db_query":           true,
+	"webhook_call":       true,
+	"github_code_search": true,
+	"github_file_fetch":  true,
+}
+
+// IsBuiltinTool reports whether name belongs to a built-in tool.
+func IsBuiltinTool(name string) bool {
+	return builtinTools[name]
+}
+
+// CustomToolAuth is the header a custom tool's endpoint authenticates calls
+// with. Template is the header value, with CustomToolSecretPlaceholder where
+// Secret goes.
+type CustomToolAuth struct {
+	Header   string `json:"header"`
+	Template string `json:"template"`
+	Secret   string `json:"secret"`
+}
+
+// CustomToolSpec is a custom tool as a tenant registers it.
+type CustomToolSpec struct {
+	Name           string          `json:"name"`
+	Description    string          `json:"description"`
+	Parameters     json.RawMessage `json:"parameters"`
+	Endpoint       string          `json:"endpoint"`
+	Auth           *CustomToolAuth `json:"auth,omitempty"`
+	TimeoutSeconds int             `json:"timeout_seconds"`
+}
+
+// Normalize validates the spec and fills in defaults. Errors wrap
+// ErrInvalidCustomTool.
+func (s *CustomToolSpec) Normalize() error {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s", ErrInvalidCustomTool, fmt.Sprintf(format, args...))
+	}
+
+	s.Name = strings.TrimSpace(s.Name)
+	if !customToolName.MatchString(s.Name) {
+		return invalid("name must be 1-64 letters, digits, underscores or hyphens")
+	}
+	if IsBuiltinTool(s.Name) {
+		return invalid("name %q is used by a built-in tool", s.Name)
+	}
+	s.Description = strings.TrimSpace(s.Description)
+	if len(s.Description) > maxCustomToolDescription {
+		return invalid("description must be at most %d bytes", maxCustomToolDescription)
+	}
+
+	if len(bytes.TrimSpace(s.Parameters)) == 0 || string(bytes.TrimSpace(s.Parameters)) == "null" {
+		s.Parameters = defaultToolInput
+	}
+	if len(s.Parameters) > maxCustomToolParameters {
+		return invalid("parameters must be at most %d bytes", maxCustomToolParameters)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(s.Parameters, &schema); err != nil {
+		return invalid("parameters must be a JSON schema object")
+	}
+	if schema["type"] != "object" {
+		return invalid(`parameters must be a schema of "type": "object"`)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, s.Parameters); err != nil {
+		return invalid("parameters must be a JSON schema object")
+	}
+	s.Parameters = compact.Bytes()
+
+	s.Endpoint = strings.TrimSpace(s.Endpoint)
+	if err := checkCustomToolEndpoint(s.Endpoint); err != nil {
+		return invalid("%v", err)
+	}
+
+	if s.Auth != nil {
+		s.Auth.Header = strings.TrimSpace(s.Auth.Header)
+		if !headerNameToken.MatchString(s.Auth.Header) {
+			return invalid("auth.header must be a valid header name")
+		}
+		switch canonical := http.CanonicalHeaderKey(s.Auth.Header); {
+		case canonical == "Host", canonical == "Content-Type", canonical == "Content-Length",
+			strings.HasPrefix(canonical, "X-Agentsquads-"):
+			return invalid("auth.header %q is set by the platform", s.Auth.Header)
+		}
+		if s.Auth.Template == "" {
+			s.Auth.Template = CustomToolSecretPlaceholder
+		}
+		if !strings.Contains(s.Auth.Template, CustomToolSecretPlaceholder) {
+			return invalid("auth.template must contain %s", CustomToolSecretPlaceholder)
+		}
+		if s.Auth.Secret == "" {
+			return invalid("auth.secret is required")
+		}
+		if strings.ContainsAny(s.Auth.Template+s.Auth.Secret, "\r\n") {
+			return invalid("auth.template and auth.secret must be a single line")
+		}
+	}
+
+	switch {
+	case s.TimeoutSeconds == 0:
+		s.TimeoutSeconds = defaultCustomToolTimeout
+	case s.TimeoutSeconds < 1 || s.TimeoutSeconds > maxCustomToolTimeout:
+		return invalid("timeout_seconds must be between 1 and %d", maxCustomToolTimeout)
+	}
+	return nil
+}
+
+// checkCustomToolEndpoint accepts HTTPS URLs whose host is not this machine
+// or an address on a private network.
+func checkCustomToolEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return errors.New("endpoint must be an absolute URL")
+	}
+	if u.Scheme != "https" {
+		return errors.New("endpoint must use https")
+	}
+	if u.User != nil {
+		return errors.New("endpoint must not contain credentials; use auth instead")
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.New("endpoint must not point at localhost")
+	}
+	if ip := net.ParseIP(host); ip != nil &&
+		(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()) {
+		return errors.New("endpoint must not point at a private address")
+	}
+	return nil
+}
+
+// CustomTool is a tenant's custom tool with its secrets decrypted, ready to
+// call.
+type CustomTool struct {
+	Name          string
+	Description   string
+	Parameters    json.RawMessage
+	Endpoint      string
+	AuthHeader    string
+	AuthValue     string
+	SigningSecret string
+	Timeout       time.Duration
+}
+
+// CustomToolCall is the audit record of one custom tool call.
+type CustomToolCall struct {
+	TenantID   string
+	RunID      string
+	Tool       string
+	Status     string // ok, rejected, error
+	HTTPStatus int
+	DurationMS int64
+	Error      string
+}
+
+// CustomToolBackend loads tenants' custom tools and records every call made
+// to them. CustomToolStore implements it.
+type CustomToolBackend interface {
+	CustomTools(ctx context.Context, tenantID string) ([]CustomTool, error)
+	LogCustomToolCall(ctx context.Context, entry CustomToolCall) error
+}
+
+// SetCustomTools makes ToolsForTenant offer each tenant's custom tools and
+// Execute call them through their endpoints.
+func (r *Registry) SetCustomTools(backend CustomToolBackend) {
+	r.custom = backend
+}
+
+// customToolDefs returns the tool definitions of the tenant's custom tools.
+// A tenant whose tools cannot be loaded gets none rather than failing the
+// request.
+func (r *Registry) customToolDefs(ctx context.Context, tenantID string) []Tool {
+	if r.custom == nil || tenantID == "" {
+		return nil
+	}
+	custom, err := r.custom.CustomTools(ctx, tenantID)
+	if err != nil {
+		slog.Warn("failed to load custom tools", "tenant", tenantID, "err", err)
+		return nil
+	}
+	defs := make([]Tool, 0, len(custom))
+	for _, t := range custom {
+		if IsBuiltinTool(t.Name) {
+			continue
+		}
+		defs = append(defs, Tool{
+			Type: "function",
+			Function: FunctionDef{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.Parameters,
+			},
+		})
+	}
+	return defs
+}
+
+func (r *Registry) executeCustomTool(ctx context.Context, name string, args json.RawMessage) (string, error) {
+	tenantID, _ := ctx.Value(tenantContextKey).(string)
+	if r.custom == nil || tenantID == "" || IsBuiltinTool(name) {
+		return "", fmt.Errorf("unknown tool: %s", name)
+	}
+	custom, err := r.custom.CustomTools(ctx, tenantID)
+	if err != nil {
+		return "", fmt.Errorf("load custom tools: %w", err)
+	}
+	for _, t := range custom {
+		if t.Name == name {
+			return r.callCustomTool(ctx, tenantID, t, args)
+		}
+	}
+	return "", fmt.Errorf("unknown tool: %s", name)
+}
+
+// callCustomTool POSTs the call's arguments as JSON to the tool's endpoint
+// and returns the response body, cut to maxCustomToolResponse. Requests are
+// signed so endpoints can verify they come from the platform:
+// X-AgentSquads-Signature is sha256= and the hex HMAC-SHA256, keyed with the
+// tool's signing secret, of the X-AgentSquads-Timestamp value, a dot and the
+// body. Redirects are not followed.
+func (r *Registry) callCustomTool(ctx context.Context, tenantID string, tool CustomTool, args json.RawMessage) (string, error) {
+	entry := CustomToolCall{TenantID: tenantID, RunID: RunIDFromContext(ctx), Tool: tool.Name}
+	start := time.Now()
+	defer func() {
+		entry.DurationMS = time.Since(start).Milliseconds()
+		if err := r.custom.LogCustomToolCall(context.WithoutCancel(ctx), entry); err != nil {
+			slog.Error("failed to log custom tool call", "tenant", tenantID, "run", entry.RunID, "tool", tool.Name, "err", err)
+		}
+	}()
+
+	body, err := customToolBody(args)
+	if err == nil {
+		err = checkCustomToolEndpoint(tool.Endpoint)
+	}
+	if err == nil && !r.countCustomCall(entry.RunID) {
+		err = fmt.Errorf("custom tool call limit of %d per task reached", maxCustomToolCallsPerRun)
+	}
+	if err != nil {
+		entry.Status, entry.Error = "rejected", err.Error()
+		return "", err
+	}
+
+	timeout := tool.Timeout
+	if timeout <= 0 {
+		timeout = defaultCustomToolTimeout * time.Second
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(callCtx, http.MethodPost, tool.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		entry.Status, entry.Error = "rejected", err.Error()
+		return "", err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "AgentSquads-Tools/1.0")
+	req.Header.Set(customToolTimestampHeader, timestamp)
+	req.Header.Set(customToolSignatureHeader, "sha256="+signCustomToolCall(tool.SigningSecret, timestamp, body))
+	if tool.AuthHeader != "" {
+		req.Header.Set(tool.AuthHeader, tool.AuthValue)
+	}
+
+	client := *r.client
+	client.Timeout = 0
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		entry.Status, entry.Error = "error", err.Error()
+		return "", fmt.Errorf("call %s: %w", tool.Name, err)
+	}
+	defer resp.Body.Close()
+	entry.HTTPStatus = resp.StatusCode
+
+	result, err := io.ReadAll(io.LimitReader(resp.Body, maxCustomToolResponse+1))
+	if err != nil {
+		entry.Status, entry.Error = "error", err.Error()
+		return "", fmt.Errorf("read %s response: %w", tool.Name, err)
+	}
+	truncated := len(result) > maxCustomToolResponse
+	if truncated {
+		result = result[:maxCustomToolResponse]
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := fmt.Errorf("%s returned %d: %s", tool.Name, resp.StatusCode, truncate(string(result), 200))
+		entry.Status, entry.Error = "error", err.Error()
+		return "", err
+	}
+
+	entry.Status = "ok"
+	if truncated {
+		return string(result) + "\n\n[...truncated]", nil
+	}
+	return string(result), nil
+}
+
+// customToolBody is the call's arguments as a JSON object.
+func customToolBody(args json.RawMessage) ([]byte, error) {
+	if len(bytes.TrimSpace(args)) == 0 {
+		return []byte("{}"), nil
+	}
+	var params map[string]any
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("parse args: %w", err)
+	}
+	return json.Marshal(params)
+}
+
+func signCustomToolCall(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// countCustomCall records a custom tool call for runID and reports whether it
+// is within maxCustomToolCallsPerRun.
+func (r *Registry) countCustomCall(runID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.runCustomCalls == nil {
+		r.runCustomCalls = make(map[string]int)
+	}
+	if r.runCustomCalls[runID] >= maxCustomToolCallsPerRun {
+		return false
+	}
+	r.runCustomCalls[runID]++
+	return true
+}
@@ -0,0 +1,207 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/keyring"
+)
+
+// maxCustomToolsPerTenant caps how many custom tools a tenant can register.
+const maxCustomToolsPerTenant = 20
+
+var (
+	ErrCustomToolExists   = errors.New("custom tool already exists")
+	ErrTooManyCustomTools = fmt.Errorf("a tenant can register at most %d custom tools", maxCustomToolsPerTenant)
+)
+
+// CustomToolInfo is a registered custom tool without its secrets.
+type CustomToolInfo struct {
+	Name           string          `json:"name"`
+	Description    string          `json:"description"`
+	Parameters     json.RawMessage `json:"parameters"`
+	Endpoint       string          `json:"endpoint"`
+	AuthHeader     string          `json:"auth_header,omitempty"`
+	TimeoutSeconds int             `json:"timeout_seconds"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// CustomToolStore is the CustomToolBackend used in production. Tools live in
+// tenant_tools with their auth and signing secrets encrypted by the keyring;
+// calls are recorded in tenant_tool_calls.
+type CustomToolStore struct {
+	db   *sql.DB
+	keys func() (*keyring.Keyring, error)
+}
+
+func NewCustomToolStore(db *sql.DB) *CustomToolStore {
+	return &CustomToolStore{db: db, keys: keyring.FromEnv}
+}
+
+// Create registers spec for the tenant and returns the secret the tool's
+// requests will be signed with. The secret is not readable afterwards.
+func (s *CustomToolStore) Create(ctx context.Context, tenantID string, spec CustomToolSpec) (CustomToolInfo, string, error) {
+	if err := spec.Normalize(); err != nil {
+		return CustomToolInfo{}, "", err
+	}
+	keys, err := s.keys()
+	if err != nil {
+		return CustomToolInfo{}, "", err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return CustomToolInfo{}, "", fmt.Errorf("generate signing secret: %w", err)
+	}
+	signingSecret := "whsec_" + hex.EncodeToString(raw)
+	signingEncrypted, err := keys.Encrypt(signingSecret)
+	if err != nil {
+		return CustomToolInfo{}, "", fmt.Errorf("encrypt signing secret: %w", err)
+	}
+	var authHeader, authTemplate, authEncrypted string
+	if spec.Auth != nil {
+		authHeader, authTemplate = spec.Auth.Header, spec.Auth.Template
+		if authEncrypted, err = keys.Encrypt(spec.Auth.Secret); err != nil {
+			return CustomToolInfo{}, "", fmt.Errorf("encrypt auth secret: %w", err)
+		}
+	}
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tenant_tools WHERE tenant_id = $1`, tenantID).Scan(&count); err != nil {
+		return CustomToolInfo{}, "", fmt.Errorf("count custom tools: %w", err)
+	}
+	if count >= maxCustomToolsPerTenant {
+		return CustomToolInfo{}, "", ErrTooManyCustomTools
+	}
+
+	info := CustomToolInfo{
+		Name:           spec.Name,
+		Description:    spec.Description,
+		Parameters:     spec.Parameters,
+		Endpoint:       spec.Endpoint,
+		AuthHeader:     authHeader,
+		TimeoutSeconds: spec.TimeoutSeconds,
+	}
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO tenant_tools (
+			tenant_id, name, description, parameters, endpoint,
+			auth_header, auth_template, auth_secret_encrypted, signing_secret_encrypted, timeout_seconds
+		)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), $9, $10)
+		ON CONFLICT (tenant_id, name) DO NOTHING
+		RETURNING created_at
+	`, tenantID, spec.Name, spec.Description, string(spec.Parameters), spec.Endpoint,
+		authHeader, authTemplate, authEncrypted, signingEncrypted, spec.TimeoutSeconds).Scan(&info.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return CustomToolInfo{}, "", ErrCustomToolExists
+	}
+	if err != nil {
+		return CustomToolInfo{}, "", fmt.Errorf("insert custom tool: %w", err)
+	}
+	return info, signingSecret, nil
+}
+
+// List returns the tenant's custom tools by name.
+func (s *CustomToolStore) List(ctx context.Context, tenantID string) ([]CustomToolInfo, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name, description, parameters, endpoint, COALESCE(auth_header, ''), timeout_seconds, created_at
+		FROM tenant_tools
+		WHERE tenant_id = $1
+		ORDER BY name
+	`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("query custom tools: %w", err)
+	}
+	defer rows.Close()
+
+	list := []CustomToolInfo{}
+	for rows.Next() {
+		var info CustomToolInfo
+		var params []byte
+		if err := rows.Scan(&info.Name, &info.Description, &params, &info.Endpoint, &info.AuthHeader, &info.TimeoutSeconds, &info.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan custom tool: %w", err)
+		}
+		info.Parameters = json.RawMessage(params)
+		list = append(list, info)
+	}
+	return list, rows.Err()
+}
+
+// Delete removes the tenant's tool called name and reports whether there was
+// one.
+func (s *CustomToolStore) Delete(ctx context.Context, tenantID, name string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM tenant_tools WHERE tenant_id = $1 AND name = $2`, tenantID, name)
+	if err != nil {
+		return false, fmt.Errorf("delete custom tool: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete custom tool: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (s *CustomToolStore) CustomTools(ctx context.Context, tenantID string) ([]CustomTool, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name, description, parameters, endpoint,
+		       COALESCE(auth_header, ''), COALESCE(auth_template, ''), COALESCE(auth_secret_encrypted, ''),
+		       signing_secret_encrypted, timeout_seconds
+		FROM tenant_tools
+		WHERE tenant_id = $1
+		ORDER BY name
+	`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("query custom tools: %w", err)
+	}
+	defer rows.Close()
+
+	var keys *keyring.Keyring
+	var list []CustomTool
+	for rows.Next() {
+		var t CustomTool
+		var params []byte
+		var template, authEncrypted, signingEncrypted string
+		var timeout int
+		if err := rows.Scan(&t.Name, &t.Description, &params, &t.Endpoint,
+			&t.AuthHeader, &template, &authEncrypted, &signingEncrypted, &timeout); err != nil {
+			return nil, fmt.Errorf("scan custom tool: %w", err)
+		}
+		if keys == nil {
+			if keys, err = s.keys(); err != nil {
+				return nil, err
+			}
+		}
+		if t.SigningSecret, err = keys.Decrypt(signingEncrypted); err != nil {
+			return nil, fmt.Errorf("decrypt %s signing secret: %w", t.Name, err)
+		}
+		if t.AuthHeader != "" {
+			secret, err := keys.Decrypt(authEncrypted)
+			if err != nil {
+				return nil, fmt.Errorf("decrypt %s auth secret: %w", t.Name, err)
+			}
+			t.AuthValue = strings.ReplaceAll(template, CustomToolSecretPlaceholder, secret)
+		}
+		t.Parameters = json.RawMessage(params)
+		t.Timeout = time.Duration(timeout) * time.Second
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+func (s *CustomToolStore) LogCustomToolCall(ctx context.Context, entry CustomToolCall) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO tenant_tool_calls (tenant_id, run_id, tool, status, http_status, duration_ms, error)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0), $6, NULLIF($7, ''))
+	`, entry.TenantID, entry.RunID, entry.Tool, entry.Status, entry.HTTPStatus, entry.DurationMS, entry.Error)
+	if err != nil {
+		return fmt.Errorf("insert custom tool call: %w", err)
+	}
+	return nil
+}
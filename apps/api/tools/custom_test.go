@@ -0,0 +1,211 @@
+package tools
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/keyring"
+)
+
+type stubCustomTools struct {
+	tools map[string][]CustomTool
+	calls []CustomToolCall
+}
+
+func (s *stubCustomTools) CustomTools(_ context.Context, tenantID string) ([]CustomTool, error) {
+	return s.tools[tenantID], nil
+}
+
+func (s *stubCustomTools) LogCustomToolCall(_ context.Context, entry CustomToolCall) error {
+	s.calls = append(s.calls, entry)
+	return nil
+}
+
+func TestCustomToolSpecNormalize(t *testing.T) {
+	t.Parallel()
+	spec := CustomToolSpec{Name: " lookup_order ", Endpoint: "https://shop.example.com/hooks/order", Auth: &CustomToolAuth{Header: "Authorization", Template: "Bearer {{secret}}", Secret: "s3cret"}}
+	if err := spec.Normalize(); err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	if spec.Name != "lookup_order" || spec.TimeoutSeconds != defaultCustomToolTimeout || string(spec.Parameters) != string(defaultToolInput) {
+		t.Fatalf("spec = %+v", spec)
+	}
+
+	for name, bad := range map[string]CustomToolSpec{
+		"builtin name":   {Name: "web_search", Endpoint: "https://example.com"},
+		"bad name":       {Name: "look up", Endpoint: "https://example.com"},
+		"http endpoint":  {Name: "x", Endpoint: "http://example.com"},
+		"localhost":      {Name: "x", Endpoint: "https://localhost:8443/hook"},
+		"private ip":     {Name: "x", Endpoint: "https://10.0.0.5/hook"},
+		"not an object":  {Name: "x", Endpoint: "https://example.com", Parameters: json.RawMessage(`{"type":"string"}`)},
+		"timeout":        {Name: "x", Endpoint: "https://example.com", TimeoutSeconds: 31},
+		"no placeholder": {Name: "x", Endpoint: "https://example.com", Auth: &CustomToolAuth{Header: "X-Key", Template: "key", Secret: "s"}},
+		"reserved auth":  {Name: "x", Endpoint: "https://example.com", Auth: &CustomToolAuth{Header: "X-AgentSquads-Signature", Secret: "s"}},
+	} {
+		if err := bad.Normalize(); !errors.Is(err, ErrInvalidCustomTool) {
+			t.Fatalf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func TestCustomToolExecute(t *testing.T) {
+	t.Parallel()
+	backend := &stubCustomTools{tools: map[string][]CustomTool{"t1": {{
+		Name:          "lookup_order",
+		Description:   "Look up an order",
+		Parameters:    json.RawMessage(`{"type":"object","properties":{"id":{"type":"string"}}}`),
+		Endpoint:      "https://shop.example.com/hooks/order",
+		AuthHeader:    "Authorization",
+		AuthValue:     "Bearer s3cret",
+		SigningSecret: "whsec_test",
+		Timeout:       time.Second,
+	}}}}
+	r := NewRegistry()
+	r.SetCustomTools(backend)
+
+	var got *http.Request
+	var gotBody []byte
+	response := `{"status":"shipped"}`
+	r.client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = req
+		gotBody, _ = io.ReadAll(req.Body)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(response)), Header: make(http.Header)}, nil
+	})}
+
+	offered := func(tenantID string) bool {
+		for _, tool := range r.ToolsForTenant(context.Background(), tenantID, "chat") {
+			if tool.Function.Name == "lookup_order" {
+				return true
+			}
+		}
+		return false
+	}
+	if !offered("t1") || offered("t2") {
+		t.Fatalf("lookup_order offered to t1=%v t2=%v", offered("t1"), offered("t2"))
+	}
+	if _, err := r.Execute(WithMemoryContext(context.Background(), "t2", "c1"), "lookup_order", json.RawMessage(`{}`)); err == nil || !strings.Contains(err.Error(), "unknown tool") {
+		t.Fatalf("t2 err = %v", err)
+	}
+
+	ctx := WithRunID(WithMemoryContext(context.Background(), "t1", "c1"), "run-1")
+	out, err := r.Execute(ctx, "lookup_order", json.RawMessage(`{"id":"A-1"}`))
+	if err != nil || out != response {
+		t.Fatalf("out = %q err=%v", out, err)
+	}
+	timestamp := got.Header.Get(customToolTimestampHeader)
+	if got.Method != http.MethodPost || got.URL.String() != "https://shop.example.com/hooks/order" || got.Header.Get("Authorization") != "Bearer s3cret" {
+		t.Fatalf("request = %s %s %v", got.Method, got.URL, got.Header)
+	}
+	if string(gotBody) != `{"id":"A-1"}` || got.Header.Get(customToolSignatureHeader) != "sha256="+signCustomToolCall("whsec_test", timestamp, gotBody) {
+		t.Fatalf("body = %s signature = %q", gotBody, got.Header.Get(customToolSignatureHeader))
+	}
+
+	response = strings.Repeat("x", maxCustomToolResponse+10)
+	if out, err := r.Execute(ctx, "lookup_order", nil); err != nil || !strings.HasSuffix(out, "[...truncated]") || len(out) > maxCustomToolResponse+20 {
+		t.Fatalf("truncated len = %d err=%v", len(out), err)
+	}
+
+	if _, err := r.Execute(ctx, "lookup_order", json.RawMessage(`[1]`)); err == nil {
+		t.Fatalf("expected non-object args to be rejected")
+	}
+	if len(backend.calls) != 3 || backend.calls[0].Status != "ok" || backend.calls[0].HTTPStatus != http.StatusOK || backend.calls[0].RunID != "run-1" || backend.calls[2].Status != "rejected" {
+		t.Fatalf("calls = %+v", backend.calls)
+	}
+
+	for i := 2; i < maxCustomToolCallsPerRun; i++ {
+		if _, err := r.Execute(ctx, "lookup_order", nil); err != nil {
+			t.Fatalf("call %d: %v", i+1, err)
+		}
+	}
+	if _, err := r.Execute(ctx, "lookup_order", nil); err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Fatalf("expected per-run limit, got %v", err)
+	}
+	r.endRun("run-1")
+
+	response = "order service down"
+	r.client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusBadGateway, Body: io.NopCloser(strings.NewReader(response)), Header: make(http.Header)}, nil
+	})}
+	if _, err := r.Execute(ctx, "lookup_order", nil); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Fatalf("expected endpoint error, got %v", err)
+	}
+	if last := backend.calls[len(backend.calls)-1]; last.Status != "error" || last.HTTPStatus != http.StatusBadGateway {
+		t.Fatalf("last call = %+v", last)
+	}
+}
+
+func TestCustomToolStore(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	keys, err := keyring.New(strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatalf("keyring.New: %v", err)
+	}
+	s := NewCustomToolStore(db)
+	s.keys = func() (*keyring.Keyring, error) { return keys, nil }
+	spec := CustomToolSpec{Name: "lookup_order", Endpoint: "https://shop.example.com/hooks/order", Auth: &CustomToolAuth{Header: "Authorization", Template: "Bearer {{secret}}", Secret: "s3cret"}}
+
+	now := time.Now()
+	var signingEncrypted, authEncrypted string
+	mock.ExpectQuery("SELECT COUNT").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("INSERT INTO tenant_tools").
+		WithArgs("t1", "lookup_order", "", string(defaultToolInput), spec.Endpoint, "Authorization", "Bearer {{secret}}",
+			captureArg{&authEncrypted}, captureArg{&signingEncrypted}, defaultCustomToolTimeout).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(now))
+	info, signingSecret, err := s.Create(context.Background(), "t1", spec)
+	if err != nil || info.Name != "lookup_order" || !strings.HasPrefix(signingSecret, "whsec_") {
+		t.Fatalf("info = %+v secret=%q err=%v", info, signingSecret, err)
+	}
+	if !keyring.IsEncrypted(authEncrypted) || !keyring.IsEncrypted(signingEncrypted) {
+		t.Fatalf("secrets stored in the clear: %q %q", authEncrypted, signingEncrypted)
+	}
+
+	mock.ExpectQuery("SELECT COUNT").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("INSERT INTO tenant_tools").WillReturnRows(sqlmock.NewRows([]string{"created_at"}))
+	if _, _, err := s.Create(context.Background(), "t1", spec); !errors.Is(err, ErrCustomToolExists) {
+		t.Fatalf("duplicate err = %v", err)
+	}
+	mock.ExpectQuery("SELECT COUNT").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(maxCustomToolsPerTenant))
+	if _, _, err := s.Create(context.Background(), "t1", CustomToolSpec{Name: "other", Endpoint: spec.Endpoint}); !errors.Is(err, ErrTooManyCustomTools) {
+		t.Fatalf("limit err = %v", err)
+	}
+
+	mock.ExpectQuery("FROM tenant_tools").WithArgs("t1").WillReturnRows(sqlmock.NewRows([]string{
+		"name", "description", "parameters", "endpoint", "auth_header", "auth_template", "auth_secret_encrypted", "signing_secret_encrypted", "timeout_seconds",
+	}).AddRow("lookup_order", "", []byte(defaultToolInput), spec.Endpoint, "Authorization", "Bearer {{secret}}", authEncrypted, signingEncrypted, 10))
+	custom, err := s.CustomTools(context.Background(), "t1")
+	if err != nil || len(custom) != 1 || custom[0].AuthValue != "Bearer s3cret" || custom[0].SigningSecret != signingSecret || custom[0].Timeout != 10*time.Second {
+		t.Fatalf("custom = %+v err=%v", custom, err)
+	}
+
+	mock.ExpectExec("INSERT INTO tenant_tool_calls").WithArgs("t1", "run-1", "lookup_order", "ok", 200, int64(12), "").WillReturnResult(sqlmock.NewResult(1, 1))
+	if err := s.LogCustomToolCall(context.Background(), CustomToolCall{TenantID: "t1", RunID: "run-1", Tool: "lookup_order", Status: "ok", HTTPStatus: 200, DurationMS: 12}); err != nil {
+		t.Fatalf("LogCustomToolCall: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+// captureArg matches any string argument and stores it in dst.
+type captureArg struct{ dst *string }
+
+func (c captureArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	if ok {
+		*c.dst = s
+	}
+	return ok
+}
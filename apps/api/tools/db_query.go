@@ -0,0 +1,248 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/agentsquads/api/policies"
+)
+
+const (
+	supabaseAPIBase = "https://api.supabase.com"
+
+	// maxDBQueryRows caps the rows a query returns; the query is wrapped in
+	// a LIMIT one above it so truncation can be reported.
+	maxDBQueryRows = 50
+	// maxDBQueryBytes caps the compact JSON handed back to the model.
+	maxDBQueryBytes = 8000
+	// maxDBQueriesPerRun caps db_query calls within one tool loop.
+	maxDBQueriesPerRun = 10
+	// maxDBQueryResponse caps how much of the Supabase response is read.
+	maxDBQueryResponse = 1 << 20
+)
+
+// ErrDBQueryUnavailable is returned when the tenant cannot use db_query:
+// the policy is off or there is no successful Supabase deployment.
+var ErrDBQueryUnavailable = errors.New("db_query is not available for this tenant")
+
+var (
+	selectStatement = regexp.MustCompile(`(?is)^select\b`)
+	sqlComment      = regexp.MustCompile(`(?s)--[^\n]*|/\*.*?\*/`)
+	// sideEffectCall matches calls to functions that change state or reach
+	// outside the database even from a SELECT: sequences, settings, backend
+	// signals, server files, large objects, dblink and SQL run from strings.
+	sideEffectCall = regexp.MustCompile(`(?i)\b"?(pg_terminate_backend|pg_cancel_backend|pg_reload_conf|pg_rotate_logfile|pg_promote|nextval|setval|set_config|pg_read_file|pg_read_binary_file|pg_ls_\w+|pg_stat_file|lo_\w+|dblink\w*|pg_advisory\w*|pg_sleep\w*|pg_notify|pg_logical_emit_message|query_to_xml\w*|query_to_json\w*|cursor_to_xml\w*)"?\s*\(`)
+)
+
+// SupabaseProject is the project a tenant's Supabase deployment created and
+// the management API token used to reach it.
+type SupabaseProject struct {
+	Ref   string
+	Token string
+}
+
+// DBQueryLog is the audit record of one db_query call.
+type DBQueryLog struct {
+	TenantID   string
+	RunID      string
+	ProjectRef string
+	Query      string
+	Status     string // ok, rejected, error
+	Rows       int
+	Error      string
+}
+
+// DBQueryBackend finds tenants' Supabase projects and records every query
+// run against them.
+type DBQueryBackend interface {
+	// SupabaseProject returns the project of the tenant's latest successful
+	// Supabase deployment, or ok=false when there is none.
+	SupabaseProject(ctx context.Context, tenantID string) (project SupabaseProject, ok bool, err error)
+	LogDBQuery(ctx context.Context, entry DBQueryLog) error
+}
+
+// SetDBQuery registers the db_query tool, backed by backend. Tenants only get
+// the tool when checker enables the db_query policy and they have a
+// successful Supabase deployment (see ToolsForTenant).
+func (r *Registry) SetDBQuery(backend DBQueryBackend, checker policies.Checker) {
+	r.dbQuery = backend
+	r.policies = checker
+
+	// ─── db_query ───────────────────────────────────────────────────────
+	r.tools["db_query"] = Tool{
+		Type: "function",
+		Function: FunctionDef{
+			Name:        "db_query",
+			Description: fmt.Sprintf("Run a read-only SQL SELECT against the tenant's own Supabase (Postgres) database to answer questions about their data. One SELECT statement only, no semicolons. Returns at most %d rows as JSON; aggregate or filter in SQL rather than fetching everything. Limited to %d queries per task.", maxDBQueryRows, maxDBQueriesPerRun),
+			Parameters:  json.RawMessage(`{"type":"object","properties":{"query":{"type":"string","description":"A single SELECT statement, e.g. SELECT status, count(*) FROM orders GROUP BY status"}},"required":["query"]}`),
+		},
+	}
+	r.handlers["db_query"] = r.handleDBQuery
+}
+
+// dbQueryProject returns the tenant's Supabase project when db_query is
+// enabled for it, and ErrDBQueryUnavailable otherwise.
+func (r *Registry) dbQueryProject(ctx context.Context, tenantID string) (SupabaseProject, error) {
+	if r.dbQuery == nil || r.policies == nil || tenantID == "" {
+		return SupabaseProject{}, ErrDBQueryUnavailable
+	}
+	enabled, err := r.policies.FeatureEnabled(ctx, tenantID, policies.FeatureDBQuery)
+	if err != nil {
+		return SupabaseProject{}, fmt.Errorf("check db_query policy: %w", err)
+	}
+	if !enabled {
+		policies.RecordDenial(ctx, tenantID, policies.FeatureDBQuery, "tools")
+		return SupabaseProject{}, ErrDBQueryUnavailable
+	}
+	project, ok, err := r.dbQuery.SupabaseProject(ctx, tenantID)
+	if err != nil {
+		return SupabaseProject{}, fmt.Errorf("load supabase project: %w", err)
+	}
+	if !ok {
+		return SupabaseProject{}, ErrDBQueryUnavailable
+	}
+	return project, nil
+}
+
+func (r *Registry) handleDBQuery(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse args: %w", err)
+	}
+	tenantID, _ := ctx.Value(tenantContextKey).(string)
+	project, err := r.dbQueryProject(ctx, tenantID)
+	if err != nil {
+		return "", err
+	}
+
+	entry := DBQueryLog{TenantID: tenantID, RunID: RunIDFromContext(ctx), ProjectRef: project.Ref, Query: params.Query}
+	defer func() {
+		if err := r.dbQuery.LogDBQuery(context.WithoutCancel(ctx), entry); err != nil {
+			slog.Error("failed to log db_query", "tenant", tenantID, "run", entry.RunID, "err", err)
+		}
+	}()
+
+	query, err := readOnlyQuery(params.Query)
+	if err == nil && !r.countRunQuery(entry.RunID) {
+		err = fmt.Errorf("query limit of %d per task reached", maxDBQueriesPerRun)
+	}
+	if err != nil {
+		entry.Status, entry.Error = "rejected", err.Error()
+		return "", err
+	}
+
+	rows, err := r.runSupabaseQuery(ctx, project, query)
+	if err != nil {
+		entry.Status, entry.Error = "error", err.Error()
+		return "", err
+	}
+	entry.Status, entry.Rows = "ok", len(rows)
+
+	truncated := len(rows) > maxDBQueryRows
+	if truncated {
+		rows = rows[:maxDBQueryRows]
+	}
+	for {
+		out, _ := json.Marshal(map[string]any{"rows": rows, "row_count": len(rows), "truncated": truncated})
+		if len(out) <= maxDBQueryBytes || len(rows) == 0 {
+			return string(out), nil
+		}
+		rows = rows[:len(rows)/2]
+		truncated = true
+	}
+}
+
+// readOnlyQuery accepts a single SELECT statement and wraps it as a subquery
+// capped at one row over maxDBQueryRows. Postgres rejects data-modifying
+// statements inside FROM, so a query that closes the parenthesis early still
+// cannot smuggle one in. Calls to functions with side effects are rejected
+// up front; runSupabaseQuery also runs the query as a read-only role, which
+// is what actually enforces this.
+func readOnlyQuery(raw string) (string, error) {
+	query := strings.TrimSpace(raw)
+	query = strings.TrimSpace(strings.TrimSuffix(query, ";"))
+	if query == "" {
+		return "", errors.New("query is required")
+	}
+	if strings.Contains(query, ";") {
+		return "", errors.New("only a single statement is allowed")
+	}
+	if !selectStatement.MatchString(query) {
+		return "", errors.New("only SELECT statements are allowed")
+	}
+	if fn := sideEffectCall.FindStringSubmatch(sqlComment.ReplaceAllString(query, " ")); fn != nil {
+		return "", fmt.Errorf("%s() is not allowed in db_query", strings.ToLower(fn[1]))
+	}
+	return fmt.Sprintf("SELECT * FROM (\n%s\n) AS db_query LIMIT %d", query, maxDBQueryRows+1), nil
+}
+
+// countRunQuery records a query for runID and reports whether it is within
+// the per-run limit.
+func (r *Registry) countRunQuery(runID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.runQueries == nil {
+		r.runQueries = make(map[string]int)
+	}
+	if r.runQueries[runID] >= maxDBQueriesPerRun {
+		return false
+	}
+	r.runQueries[runID]++
+	return true
+}
+
+// endRun drops the per-run state kept for runID.
+func (r *Registry) endRun(runID string) {
+	r.mu.Lock()
+	delete(r.runQueries, runID)
+	delete(r.runCustomCalls, runID)
+	r.mu.Unlock()
+}
+
+// runSupabaseQuery runs query through the management API with read_only set,
+// so Supabase runs it as supabase_read_only_user: a role with only read
+// grants, in a read-only transaction.
+func (r *Registry) runSupabaseQuery(ctx context.Context, project SupabaseProject, query string) ([]json.RawMessage, error) {
+	body, _ := json.Marshal(map[string]any{"query": query, "read_only": true})
+	reqURL := fmt.Sprintf("%s/v1/projects/%s/database/query", supabaseAPIBase, url.PathEscape(project.Ref))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+project.Token)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("supabase query request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxDBQueryResponse+1))
+	if err != nil {
+		return nil, fmt.Errorf("read supabase response: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("query failed (%d): %s", resp.StatusCode, truncate(string(respBody), 500))
+	}
+	if len(respBody) > maxDBQueryResponse {
+		return nil, errors.New("query result is too large; select fewer columns")
+	}
+
+	var rows []json.RawMessage
+	if err := json.Unmarshal(respBody, &rows); err != nil {
+		return nil, fmt.Errorf("parse supabase response: %w", err)
+	}
+	return rows, nil
+}
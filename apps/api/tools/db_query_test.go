@@ -0,0 +1,197 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/agentsquads/api/keyring"
+	"github.com/agentsquads/api/policies"
+)
+
+type stubDBQueryBackend struct {
+	projects map[string]SupabaseProject
+	logs     []DBQueryLog
+}
+
+func (s *stubDBQueryBackend) SupabaseProject(_ context.Context, tenantID string) (SupabaseProject, bool, error) {
+	p, ok := s.projects[tenantID]
+	return p, ok, nil
+}
+
+func (s *stubDBQueryBackend) LogDBQuery(_ context.Context, entry DBQueryLog) error {
+	s.logs = append(s.logs, entry)
+	return nil
+}
+
+type stubPolicies map[string]bool
+
+func (s stubPolicies) FeatureEnabled(_ context.Context, tenantID, feature string) (bool, error) {
+	return s[tenantID+"|"+feature], nil
+}
+
+func TestReadOnlyQuery(t *testing.T) {
+	t.Parallel()
+	got, err := readOnlyQuery("  select id from orders;  ")
+	if err != nil || got != "SELECT * FROM (\nselect id from orders\n) AS db_query LIMIT 51" {
+		t.Fatalf("wrapped = %q err=%v", got, err)
+	}
+	for _, q := range []string{"", "DELETE FROM orders", "WITH x AS (DELETE FROM orders RETURNING *) SELECT * FROM x", "SELECT 1; DROP TABLE orders", "selection"} {
+		if _, err := readOnlyQuery(q); err == nil {
+			t.Fatalf("expected %q to be rejected", q)
+		}
+	}
+}
+
+func TestReadOnlyQueryRejectsSideEffects(t *testing.T) {
+	t.Parallel()
+	for _, q := range []string{
+		"SELECT pg_terminate_backend(pid) FROM pg_stat_activity",
+		"SELECT nextval('orders_id_seq')",
+		"SELECT set_config('role', 'postgres', false)",
+		"SELECT pg_read_file('/etc/passwd')",
+		"SELECT lo_import('/etc/passwd')",
+		"SELECT * FROM dblink('host=10.0.0.1', 'DELETE FROM orders') AS t(x int)",
+		"SELECT pg_catalog.NEXTVAL ('orders_id_seq')",
+		`SELECT "set_config"('role', 'postgres', false)`,
+		"SELECT nextval/* hidden */('orders_id_seq')",
+		"SELECT query_to_xml('DELETE FROM orders', true, true, '')",
+	} {
+		if _, err := readOnlyQuery(q); err == nil || !strings.Contains(err.Error(), "not allowed") {
+			t.Fatalf("expected %q to be rejected, got %v", q, err)
+		}
+	}
+	for _, q := range []string{"SELECT next_value FROM settings", "SELECT count(*) FROM lo_orders"} {
+		if _, err := readOnlyQuery(q); err != nil {
+			t.Fatalf("%q: %v", q, err)
+		}
+	}
+}
+
+func TestDBQueryTool(t *testing.T) {
+	t.Parallel()
+	backend := &stubDBQueryBackend{projects: map[string]SupabaseProject{
+		"t1": {Ref: "abcd", Token: "sbp_1"},
+		"t2": {Ref: "efgh", Token: "sbp_2"},
+	}}
+	r := NewRegistry()
+	r.SetDBQuery(backend, stubPolicies{"t1|" + policies.FeatureDBQuery: true, "t3|" + policies.FeatureDBQuery: true})
+
+	var got *http.Request
+	var gotQuery string
+	var gotReadOnly bool
+	r.client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = req
+		var body struct {
+			Query    string `json:"query"`
+			ReadOnly bool   `json:"read_only"`
+		}
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		gotQuery, gotReadOnly = body.Query, body.ReadOnly
+		rows := make([]string, 60)
+		for i := range rows {
+			rows[i] = fmt.Sprintf(`{"id":%d}`, i)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("[" + strings.Join(rows, ",") + "]")), Header: make(http.Header)}, nil
+	})}
+
+	hasDBQuery := func(tenantID string) bool {
+		for _, tool := range r.ToolsForTenant(context.Background(), tenantID, "chat") {
+			if tool.Function.Name == "db_query" {
+				return true
+			}
+		}
+		return false
+	}
+	// t2 has a deployment but no policy, t3 the policy but no deployment.
+	if !hasDBQuery("t1") || hasDBQuery("t2") || hasDBQuery("t3") {
+		t.Fatalf("db_query offered to t1=%v t2=%v t3=%v", hasDBQuery("t1"), hasDBQuery("t2"), hasDBQuery("t3"))
+	}
+	if _, err := r.Execute(WithMemoryContext(context.Background(), "t2", "c1"), "db_query", json.RawMessage(`{"query":"SELECT 1"}`)); !errors.Is(err, ErrDBQueryUnavailable) {
+		t.Fatalf("t2 err = %v", err)
+	}
+
+	ctx := WithRunID(WithMemoryContext(context.Background(), "t1", "c1"), "run-1")
+	out, err := r.Execute(ctx, "db_query", json.RawMessage(`{"query":"SELECT id FROM orders"}`))
+	if err != nil {
+		t.Fatalf("db_query: %v", err)
+	}
+	if got.URL.String() != "https://api.supabase.com/v1/projects/abcd/database/query" || got.Header.Get("Authorization") != "Bearer sbp_1" {
+		t.Fatalf("request = %s %v", got.URL, got.Header)
+	}
+	if !strings.HasSuffix(gotQuery, "LIMIT 51") || !gotReadOnly {
+		t.Fatalf("query = %q read_only = %v", gotQuery, gotReadOnly)
+	}
+	var result struct {
+		Rows      []json.RawMessage `json:"rows"`
+		RowCount  int               `json:"row_count"`
+		Truncated bool              `json:"truncated"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil || result.RowCount != maxDBQueryRows || len(result.Rows) != maxDBQueryRows || !result.Truncated {
+		t.Fatalf("result = %s err=%v", out, err)
+	}
+
+	if _, err := r.Execute(ctx, "db_query", json.RawMessage(`{"query":"UPDATE orders SET paid = true"}`)); err == nil {
+		t.Fatalf("expected update to be rejected")
+	}
+	if len(backend.logs) != 2 || backend.logs[0].Status != "ok" || backend.logs[0].RunID != "run-1" || backend.logs[1].Status != "rejected" {
+		t.Fatalf("logs = %+v", backend.logs)
+	}
+
+	for i := 1; i < maxDBQueriesPerRun; i++ {
+		if _, err := r.Execute(ctx, "db_query", json.RawMessage(`{"query":"SELECT 1"}`)); err != nil {
+			t.Fatalf("query %d: %v", i+1, err)
+		}
+	}
+	if _, err := r.Execute(ctx, "db_query", json.RawMessage(`{"query":"SELECT 1"}`)); err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Fatalf("expected per-run limit, got %v", err)
+	}
+	r.endRun("run-1")
+	if _, err := r.Execute(ctx, "db_query", json.RawMessage(`{"query":"SELECT 1"}`)); err != nil {
+		t.Fatalf("after run end: %v", err)
+	}
+}
+
+func TestSupabaseDeployments(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	keys, err := keyring.New(strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatalf("keyring.New: %v", err)
+	}
+	encrypted, err := keys.Encrypt("sbp_token")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	s := NewSupabaseDeployments(db)
+	s.keys = func() (*keyring.Keyring, error) { return keys, nil }
+
+	mock.ExpectQuery("FROM deployment_runs r").WithArgs("t1").
+		WillReturnRows(sqlmock.NewRows([]string{"external_id", "access_token_encrypted"}).AddRow("abcd", encrypted))
+	project, ok, err := s.SupabaseProject(context.Background(), "t1")
+	if err != nil || !ok || project != (SupabaseProject{Ref: "abcd", Token: "sbp_token"}) {
+		t.Fatalf("project = %+v ok=%v err=%v", project, ok, err)
+	}
+	mock.ExpectQuery("FROM deployment_runs r").WithArgs("t2").WillReturnRows(sqlmock.NewRows(nil))
+	if _, ok, err := s.SupabaseProject(context.Background(), "t2"); ok || err != nil {
+		t.Fatalf("undeployed tenant ok=%v err=%v", ok, err)
+	}
+
+	mock.ExpectExec("INSERT INTO agent_db_queries").WithArgs("t1", "run-1", "abcd", "SELECT 1", "ok", 1, "").WillReturnResult(sqlmock.NewResult(1, 1))
+	if err := s.LogDBQuery(context.Background(), DBQueryLog{TenantID: "t1", RunID: "run-1", ProjectRef: "abcd", Query: "SELECT 1", Status: "ok", Rows: 1}); err != nil {
+		t.Fatalf("LogDBQuery: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,293 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/policies"
+)
+
+const (
+	// maxFetchRedirects caps the redirects a tool request follows.
+	maxFetchRedirects = 5
+	// maxFetchResponse caps how much of any response the tools read.
+	maxFetchResponse = 5 << 20
+	// maxInternalFetchEntries caps a tenant's internal fetch allow-list.
+	maxInternalFetchEntries = 50
+)
+
+// defaultBlockedNets are blocked on top of loopback, private, link-local,
+// multicast and unspecified addresses: shared address space, which carrier
+// NAT and some container networks use.
+var defaultBlockedNets = []string{"100.64.0.0/10"}
+
+// BlockedURLError is returned for tool requests to a URL the SSRF guard does
+// not allow.
+type BlockedURLError struct {
+	URL    string
+	Reason string
+}
+
+func (e *BlockedURLError) Error() string {
+	return fmt.Sprintf("URL not allowed: %s (%s)", e.URL, e.Reason)
+}
+
+// InternalFetchSettings are the settings of the internal_fetch policy: the
+// hosts, IPs and CIDR ranges on internal networks a tenant's tools may reach.
+type InternalFetchSettings struct {
+	Allowed []string `json:"internal_fetch_allowed"`
+}
+
+// ParseInternalFetchSettings decodes and normalizes internal_fetch policy
+// settings.
+func ParseInternalFetchSettings(raw json.RawMessage) (InternalFetchSettings, error) {
+	var settings InternalFetchSettings
+	if len(raw) == 0 {
+		return settings, nil
+	}
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return InternalFetchSettings{}, errors.New("settings must be an object with an internal_fetch_allowed list")
+	}
+	if len(settings.Allowed) > maxInternalFetchEntries {
+		return InternalFetchSettings{}, fmt.Errorf("at most %d internal fetch entries are allowed", maxInternalFetchEntries)
+	}
+	allowed := make([]string, 0, len(settings.Allowed))
+	for _, entry := range settings.Allowed {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		_, _, cidrErr := net.ParseCIDR(entry)
+		isHost := entry != "" && !strings.ContainsAny(entry, "/:*@ ")
+		if cidrErr != nil && net.ParseIP(entry) == nil && !isHost {
+			return InternalFetchSettings{}, fmt.Errorf("invalid internal fetch entry %q", entry)
+		}
+		allowed = append(allowed, entry)
+	}
+	settings.Allowed = allowed
+	return settings, nil
+}
+
+// permits reports whether the entries allow reaching ip as host.
+func (s InternalFetchSettings) permits(host string, ip net.IP) bool {
+	for _, entry := range s.Allowed {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if allowedIP := net.ParseIP(entry); allowedIP != nil {
+			if allowedIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		if entry == host {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchGuard keeps tool HTTP requests off the API host's internal networks:
+// cloud metadata endpoints, other tenants' containers and local admin ports.
+// Every request, redirects included, is checked against the addresses its
+// host resolves to, and connections are made to the checked address so DNS
+// cannot change the answer in between. Tenants reach internal addresses only
+// through their internal_fetch policy.
+type fetchGuard struct {
+	blocked  []*net.IPNet
+	resolver ipResolver
+	dialer   *net.Dialer
+	policies PolicySettingsSource
+}
+
+// ipResolver looks up host addresses; *net.Resolver implements it.
+type ipResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// newFetchGuard blocks defaultBlockedNets plus the CIDR ranges in
+// TOOLS_FETCH_BLOCKED_CIDRS, such as the tenant bridge network when it uses
+// public addresses.
+func newFetchGuard() *fetchGuard {
+	g := &fetchGuard{
+		resolver: net.DefaultResolver,
+		dialer:   &net.Dialer{Timeout: 10 * time.Second},
+	}
+	ranges := append([]string(nil), defaultBlockedNets...)
+	ranges = append(ranges, strings.Split(os.Getenv("TOOLS_FETCH_BLOCKED_CIDRS"), ",")...)
+	for _, cidr := range ranges {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			slog.Warn("ignoring invalid TOOLS_FETCH_BLOCKED_CIDRS entry", "cidr", cidr)
+			continue
+		}
+		g.blocked = append(g.blocked, network)
+	}
+	return g
+}
+
+func (g *fetchGuard) blockedIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() {
+		return true
+	}
+	for _, network := range g.blocked {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedIPs resolves host and returns its addresses, or a BlockedURLError
+// when any of them is blocked and not on the tenant's internal allow-list.
+func (g *fetchGuard) allowedIPs(ctx context.Context, rawURL, host string) ([]net.IP, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := g.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+
+	var internal *InternalFetchSettings
+	for _, ip := range ips {
+		if !g.blockedIP(ip) {
+			continue
+		}
+		if internal == nil {
+			settings := g.internalSettings(ctx)
+			internal = &settings
+		}
+		if !internal.permits(host, ip) {
+			return nil, g.block(ctx, rawURL, fmt.Sprintf("%s resolves to internal address %s", host, ip))
+		}
+	}
+	return ips, nil
+}
+
+// internalSettings returns the internal_fetch allow-list of the tenant in
+// ctx, or an empty one when the policy is off or cannot be read.
+func (g *fetchGuard) internalSettings(ctx context.Context) InternalFetchSettings {
+	tenantID, _ := ctx.Value(tenantContextKey).(string)
+	if g.policies == nil || tenantID == "" {
+		return InternalFetchSettings{}
+	}
+	enabled, err := g.policies.FeatureEnabled(ctx, tenantID, policies.FeatureInternalFetch)
+	if err != nil || !enabled {
+		return InternalFetchSettings{}
+	}
+	raw, err := g.policies.Settings(ctx, tenantID, policies.FeatureInternalFetch)
+	if err != nil {
+		slog.Warn("load internal fetch allow-list failed", "tenant", tenantID, "err", err)
+		return InternalFetchSettings{}
+	}
+	settings, err := ParseInternalFetchSettings(raw)
+	if err != nil {
+		slog.Warn("invalid internal fetch allow-list", "tenant", tenantID, "err", err)
+		return InternalFetchSettings{}
+	}
+	return settings
+}
+
+func (g *fetchGuard) block(ctx context.Context, rawURL, reason string) error {
+	tenantID, _ := ctx.Value(tenantContextKey).(string)
+	slog.Warn("blocked tool request", "tenant", tenantID, "run", RunIDFromContext(ctx), "url", rawURL, "reason", reason)
+	return &BlockedURLError{URL: rawURL, Reason: reason}
+}
+
+// dialContext connects to the first allowed address of addr's host.
+func (g *fetchGuard) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := g.allowedIPs(ctx, addr, host)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := g.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no addresses for %s", host)
+	}
+	return nil, lastErr
+}
+
+// guardedTransport checks each request's URL before handing it to base and
+// caps how much of the response can be read.
+type guardedTransport struct {
+	guard *fetchGuard
+	base  http.RoundTripper
+}
+
+func (t *guardedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, t.guard.block(req.Context(), req.URL.String(), "only http and https URLs are allowed")
+	}
+	// Checked here as well as when dialing, as a pooled connection skips the
+	// dial and may have been opened for a tenant allowed to reach the host.
+	if _, err := t.guard.allowedIPs(req.Context(), req.URL.String(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = limitedBody{Reader: io.LimitReader(resp.Body, maxFetchResponse), Closer: resp.Body}
+	return resp, nil
+}
+
+type limitedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// newGuardedClient returns the HTTP client the tools share, with guard
+// checking every request and redirects capped at maxFetchRedirects.
+func newGuardedClient(guard *fetchGuard) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = guard.dialContext
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &guardedTransport{guard: guard, base: transport},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxFetchRedirects {
+				return guard.block(req.Context(), req.URL.String(), fmt.Sprintf("stopped after %d redirects", maxFetchRedirects))
+			}
+			return nil
+		},
+	}
+}
+
+// SetFetchAllowlist lets tenants whose internal_fetch policy is enabled reach
+// the internal hosts and ranges listed in its settings from tool requests.
+func (r *Registry) SetFetchAllowlist(source PolicySettingsSource) {
+	r.fetchGuard.policies = source
+}
@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agentsquads/api/policies"
+)
+
+func TestParseInternalFetchSettings(t *testing.T) {
+	t.Parallel()
+	got, err := ParseInternalFetchSettings(json.RawMessage(`{"internal_fetch_allowed":[" 10.0.4.0/24 ","Wiki.Corp","192.168.1.5"]}`))
+	if err != nil || len(got.Allowed) != 3 || got.Allowed[1] != "wiki.corp" {
+		t.Fatalf("settings = %+v err=%v", got, err)
+	}
+	if !got.permits("x", net.ParseIP("10.0.4.9")) || !got.permits("wiki.corp", net.ParseIP("10.9.9.9")) ||
+		!got.permits("y", net.ParseIP("192.168.1.5")) || got.permits("y", net.ParseIP("192.168.1.6")) {
+		t.Fatalf("permits mismatch for %+v", got)
+	}
+	for _, raw := range []string{`[]`, `{"internal_fetch_allowed":["http://x"]}`, `{"internal_fetch_allowed":[""]}`} {
+		if _, err := ParseInternalFetchSettings(json.RawMessage(raw)); err == nil {
+			t.Fatalf("expected error for %s", raw)
+		}
+	}
+}
+
+func TestFetchGuardBlocksInternalAddresses(t *testing.T) {
+	t.Parallel()
+	guard := newFetchGuard()
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.17.0.2", "192.168.0.1", "169.254.169.254", "100.64.0.1", "::1", "0.0.0.0"} {
+		if !guard.blockedIP(net.ParseIP(ip)) {
+			t.Fatalf("%s should be blocked", ip)
+		}
+	}
+	if guard.blockedIP(net.ParseIP("93.184.216.34")) {
+		t.Fatalf("public address should not be blocked")
+	}
+}
+
+func TestGuardedClient(t *testing.T) {
+	t.Parallel()
+	var hops int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/metadata":
+			http.Redirect(w, req, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+		case "/loop":
+			hops++
+			http.Redirect(w, req, "/loop", http.StatusFound)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer ts.Close()
+
+	r := NewRegistry()
+	r.SetFetchAllowlist(stubPolicySettings{
+		stubPolicies: stubPolicies{"t1|" + policies.FeatureInternalFetch: true},
+		settings:     map[string]string{"t1": `{"internal_fetch_allowed":["127.0.0.1"]}`},
+	})
+	ctx := WithMemoryContext(context.Background(), "t1", "c1")
+	get := func(rawURL string) error {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		resp, err := r.client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(ts.URL); err != nil {
+		t.Fatalf("allowed request: %v", err)
+	}
+	var blocked *BlockedURLError
+	if err := get(ts.URL + "/metadata"); !errors.As(err, &blocked) || !strings.Contains(blocked.URL, "169.254.169.254") {
+		t.Fatalf("redirect to metadata err = %v", err)
+	}
+	if err := get(ts.URL + "/loop"); !errors.As(err, &blocked) || hops != maxFetchRedirects {
+		t.Fatalf("redirect loop err = %v after %d hops", err, hops)
+	}
+	if err := get("ftp://example.com/file"); !errors.As(err, &blocked) {
+		t.Fatalf("ftp err = %v", err)
+	}
+
+	// Another tenant cannot reuse t1's allow-list, even over a pooled
+	// connection.
+	other := WithMemoryContext(context.Background(), "t2", "c1")
+	req, _ := http.NewRequestWithContext(other, http.MethodGet, ts.URL, nil)
+	if _, err := r.client.Do(req); !errors.As(err, &blocked) {
+		t.Fatalf("other tenant err = %v", err)
+	}
+}
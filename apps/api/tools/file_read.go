@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MediaFile is a file a user sent over a channel, as file_read sees it. Text
+// is empty for files without extractable text, such as photos.
+type MediaFile struct {
+	Name        string
+	ContentType string
+	Size        int64
+	Text        string
+}
+
+// MediaReader loads a tenant's ingested file by media id.
+type MediaReader interface {
+	ReadMedia(ctx context.Context, tenantID, mediaID string) (MediaFile, error)
+}
+
+// SetMediaReader registers the file_read tool, backed by m. Agents only get
+// the tool once a reader is set.
+func (r *Registry) SetMediaReader(m MediaReader) {
+	r.media = m
+
+	// ─── file_read ──────────────────────────────────────────────────────
+	r.tools["file_read"] = Tool{
+		Type: "function",
+		Function: FunctionDef{
+			Name:        "file_read",
+			Description: "Read a file the user attached to their message (PDF, text, CSV, JSON, ...). Messages with attachments list each file with its media_id. Returns the file's text content.",
+			Parameters:  json.RawMessage(`{"type":"object","properties":{"media_id":{"type":"string","description":"media_id of the attached file"},"max_chars":{"type":"integer","description":"Maximum characters to return (default 8000)","default":8000}},"required":["media_id"]}`),
+		},
+	}
+	r.handlers["file_read"] = r.handleFileRead
+}
+
+func (r *Registry) handleFileRead(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		MediaID  string `json:"media_id"`
+		MaxChars int    `json:"max_chars"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse args: %w", err)
+	}
+	if strings.TrimSpace(params.MediaID) == "" {
+		return "", fmt.Errorf("media_id is required")
+	}
+	if params.MaxChars <= 0 {
+		params.MaxChars = 8000
+	}
+	if params.MaxChars > 30000 {
+		params.MaxChars = 30000
+	}
+	tenantID, _ := ctx.Value(tenantContextKey).(string)
+	if r.media == nil || tenantID == "" {
+		return "", fmt.Errorf("file storage is not available")
+	}
+
+	file, err := r.media.ReadMedia(ctx, tenantID, strings.TrimSpace(params.MediaID))
+	if err != nil {
+		return "", fmt.Errorf("read file: %w", err)
+	}
+	name := file.Name
+	if name == "" {
+		name = params.MediaID
+	}
+	if file.Text == "" {
+		return fmt.Sprintf("%s (%s, %d bytes) has no readable text content.", name, file.ContentType, file.Size), nil
+	}
+
+	text := file.Text
+	if len(text) > params.MaxChars {
+		text = text[:params.MaxChars] + "\n\n[...truncated]"
+	}
+	return fmt.Sprintf("Content of %s (%s, %d bytes):\n\n%s", name, file.ContentType, file.Size, text), nil
+}
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type stubMediaReader map[string]MediaFile
+
+func (s stubMediaReader) ReadMedia(_ context.Context, tenantID, mediaID string) (MediaFile, error) {
+	file, ok := s[tenantID+"/"+mediaID]
+	if !ok {
+		return MediaFile{}, errors.New("media not found")
+	}
+	return file, nil
+}
+
+func TestFileRead(t *testing.T) {
+	t.Parallel()
+	r := NewRegistry()
+	if len(r.GetTools("chat")) != 2 {
+		t.Fatalf("file_read offered without a media reader")
+	}
+	r.SetMediaReader(stubMediaReader{
+		"t1/m1": {Name: "report.pdf", ContentType: "application/pdf", Size: 2048, Text: strings.Repeat("revenue ", 10)},
+		"t1/m2": {Name: "photo.jpg", ContentType: "image/jpeg", Size: 512},
+	})
+	found := false
+	for _, tool := range r.GetTools("chat") {
+		found = found || tool.Function.Name == "file_read"
+	}
+	if !found {
+		t.Fatalf("chat tools missing file_read")
+	}
+
+	ctx := WithMemoryContext(context.Background(), "t1", "c1")
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		args    string
+		wantErr bool
+		want    string
+	}{
+		{name: "text", ctx: ctx, args: `{"media_id":"m1","max_chars":20}`, want: "Content of report.pdf (application/pdf, 2048 bytes):\n\nrevenue revenue reve\n\n[...truncated]"},
+		{name: "no text", ctx: ctx, args: `{"media_id":"m2"}`, want: "photo.jpg (image/jpeg, 512 bytes) has no readable text content."},
+		{name: "other tenant", ctx: WithMemoryContext(context.Background(), "t2", "c1"), args: `{"media_id":"m1"}`, wantErr: true},
+		{name: "no tenant", ctx: context.Background(), args: `{"media_id":"m1"}`, wantErr: true},
+		{name: "missing id", ctx: ctx, args: `{}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		out, err := r.Execute(tt.ctx, "file_read", json.RawMessage(tt.args))
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: err=%v wantErr=%v", tt.name, err, tt.wantErr)
+		}
+		if tt.want != "" && out != tt.want {
+			t.Fatalf("%s: output %q, want %q", tt.name, out, tt.want)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	githubAPIBase = "https://api.github.com"
+
+	// githubRateLimitFloor is how many calls left in a rate limit window
+	// count as "near zero"; at or below it calls wait for the window reset.
+	githubRateLimitFloor = 1
+	// maxGitHubRateLimitWait caps how long a tool call waits for a reset. A
+	// longer wait fails the call instead of stalling the agent loop.
+	maxGitHubRateLimitWait = time.Minute
+)
+
+// githubRateLimiter remembers the last X-RateLimit-* headers per GitHub
+// rate limit bucket ("search" or "core") so the next call can wait out an
+// exhausted window instead of being rejected.
+type githubRateLimiter struct {
+	mu     sync.Mutex
+	resets map[string]time.Time
+}
+
+// wait blocks until resource's window resets when the last response said it
+// was nearly exhausted.
+func (l *githubRateLimiter) wait(ctx context.Context, resource string) error {
+	l.mu.Lock()
+	reset := l.resets[resource]
+	l.mu.Unlock()
+
+	delay := time.Until(reset)
+	if delay <= 0 {
+		return nil
+	}
+	if delay > maxGitHubRateLimitWait {
+		return fmt.Errorf("github %s rate limit exhausted until %s", resource, reset.UTC().Format(time.RFC3339))
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// observe records resp's rate limit headers for resource.
+func (l *githubRateLimiter) observe(resource string, h http.Header) {
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.resets == nil {
+		l.resets = make(map[string]time.Time)
+	}
+	if remaining > githubRateLimitFloor {
+		delete(l.resets, resource)
+		return
+	}
+	if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		l.resets[resource] = time.Unix(reset, 0)
+	}
+}
+
+func (r *Registry) registerGitHub() {
+	// ─── github_code_search ─────────────────────────────────────────────
+	r.tools["github_code_search"] = Tool{
+		Type: "function",
+		Function: FunctionDef{
+			Name:        "github_code_search",
+			Description: "Search public code on GitHub. Returns matching repository/file paths with a snippet of the matching code. Use this to find real-world usage of an API, library or pattern.",
+			Parameters:  json.RawMessage(`{"type":"object","properties":{"query":{"type":"string","description":"Code search query, e.g. 'http.NewRequestWithContext retry'"},"language":{"type":"string","description":"Restrict results to a language, e.g. 'go'"},"max_results":{"type":"integer","description":"Number of results (1-20, default 5)","default":5}},"required":["query"]}`),
+		},
+	}
+	r.handlers["github_code_search"] = r.handleGitHubCodeSearch
+
+	// ─── github_file_fetch ──────────────────────────────────────────────
+	r.tools["github_file_fetch"] = Tool{
+		Type: "function",
+		Function: FunctionDef{
+			Name:        "github_file_fetch",
+			Description: "Fetch a file from a GitHub repository. Returns the decoded file content, or the entries when the path is a directory. Use this to read a file found with github_code_search.",
+			Parameters:  json.RawMessage(`{"type":"object","properties":{"owner":{"type":"string","description":"Repository owner"},"repo":{"type":"string","description":"Repository name"},"path":{"type":"string","description":"File path within the repository"},"ref":{"type":"string","description":"Branch, tag or commit (default: the repository's default branch)"}},"required":["owner","repo","path"]}`),
+		},
+	}
+	r.handlers["github_file_fetch"] = r.handleGitHubFileFetch
+}
+
+func (r *Registry) handleGitHubCodeSearch(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Query      string `json:"query"`
+		Language   string `json:"language"`
+		MaxResults int    `json:"max_results"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse args: %w", err)
+	}
+	params.Query = strings.TrimSpace(params.Query)
+	if params.Query == "" {
+		return "", fmt.Errorf("query is required")
+	}
+	if params.MaxResults <= 0 || params.MaxResults > 20 {
+		params.MaxResults = 5
+	}
+	// Code search only works authenticated.
+	if strings.TrimSpace(os.Getenv("GITHUB_TOKEN")) == "" {
+		return "", fmt.Errorf("GITHUB_TOKEN is not configured")
+	}
+
+	q := params.Query
+	if lang := strings.TrimSpace(params.Language); lang != "" {
+		q += " language:" + lang
+	}
+	reqURL := fmt.Sprintf("%s/search/code?q=%s&per_page=%d", githubAPIBase, url.QueryEscape(q), params.MaxResults)
+	body, status, err := r.githubGet(ctx, "search", reqURL, "application/vnd.github.text-match+json")
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return githubErrorMessage(status, body), nil
+	}
+
+	var result struct {
+		TotalCount int `json:"total_count"`
+		Items      []struct {
+			Path       string `json:"path"`
+			HTMLURL    string `json:"html_url"`
+			Repository struct {
+				FullName string `json:"full_name"`
+			} `json:"repository"`
+			TextMatches []struct {
+				Fragment string `json:"fragment"`
+			} `json:"text_matches"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("parse github search response: %w", err)
+	}
+	if len(result.Items) == 0 {
+		return "No code found for: " + q, nil
+	}
+
+	var sb strings.Builder
+	for i, item := range result.Items {
+		if i >= params.MaxResults {
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%d. **%s/%s**\n   URL: %s\n", i+1, item.Repository.FullName, item.Path, item.HTMLURL))
+		if len(item.TextMatches) > 0 {
+			sb.WriteString("   ```\n   " + strings.ReplaceAll(strings.TrimSpace(item.TextMatches[0].Fragment), "\n", "\n   ") + "\n   ```\n")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+func (r *Registry) handleGitHubFileFetch(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Owner string `json:"owner"`
+		Repo  string `json:"repo"`
+		Path  string `json:"path"`
+		Ref   string `json:"ref"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse args: %w", err)
+	}
+	params.Path = strings.Trim(strings.TrimSpace(params.Path), "/")
+	if strings.TrimSpace(params.Owner) == "" || strings.TrimSpace(params.Repo) == "" || params.Path == "" {
+		return "", fmt.Errorf("owner, repo and path are required")
+	}
+
+	segments := strings.Split(params.Path, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	reqURL := fmt.Sprintf("%s/repos/%s/%s/contents/%s", githubAPIBase,
+		url.PathEscape(strings.TrimSpace(params.Owner)), url.PathEscape(strings.TrimSpace(params.Repo)), strings.Join(segments, "/"))
+	if ref := strings.TrimSpace(params.Ref); ref != "" {
+		reqURL += "?ref=" + url.QueryEscape(ref)
+	}
+	body, status, err := r.githubGet(ctx, "core", reqURL, "application/vnd.github+json")
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return githubErrorMessage(status, body), nil
+	}
+
+	// A directory comes back as a list of entries.
+	if trimmed := strings.TrimSpace(string(body)); strings.HasPrefix(trimmed, "[") {
+		var entries []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(body, &entries); err != nil {
+			return "", fmt.Errorf("parse github contents response: %w", err)
+		}
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("%s/%s/%s is a directory:\n", params.Owner, params.Repo, params.Path))
+		for _, e := range entries {
+			sb.WriteString(fmt.Sprintf("- %s (%s)\n", e.Name, e.Type))
+		}
+		return sb.String(), nil
+	}
+
+	var file struct {
+		Type     string `json:"type"`
+		Encoding string `json:"encoding"`
+		Content  string `json:"content"`
+		Size     int    `json:"size"`
+	}
+	if err := json.Unmarshal(body, &file); err != nil {
+		return "", fmt.Errorf("parse github contents response: %w", err)
+	}
+	if file.Type != "file" {
+		return fmt.Sprintf("%s/%s/%s is a %s, not a file.", params.Owner, params.Repo, params.Path, file.Type), nil
+	}
+	if file.Encoding != "base64" {
+		// Files over 1 MB come back without inline content.
+		return fmt.Sprintf("%s/%s/%s is too large to fetch (%d bytes).", params.Owner, params.Repo, params.Path, file.Size), nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+	if err != nil {
+		return "", fmt.Errorf("decode github file content: %w", err)
+	}
+
+	content := string(decoded)
+	if len(content) > 30000 {
+		content = content[:30000] + "\n\n[...truncated]"
+	}
+	return fmt.Sprintf("Content of %s/%s/%s:\n\n%s", params.Owner, params.Repo, params.Path, content), nil
+}
+
+// githubGet makes an authenticated GET against the GitHub API, waiting first
+// if resource's rate limit is nearly exhausted.
+func (r *Registry) githubGet(ctx context.Context, resource, reqURL, accept string) ([]byte, int, error) {
+	if err := r.github.wait(ctx, resource); err != nil {
+		return nil, 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("User-Agent", "AgentSquads/1.0")
+	if token := strings.TrimSpace(os.Getenv("GITHUB_TOKEN")); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("github request: %w", err)
+	}
+	defer resp.Body.Close()
+	r.github.observe(resource, resp.Header)
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+	if err != nil {
+		return nil, 0, fmt.Errorf("read github response: %w", err)
+	}
+	return body, resp.StatusCode, nil
+}
+
+func githubErrorMessage(status int, body []byte) string {
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(body, &apiErr)
+	if apiErr.Message != "" {
+		return fmt.Sprintf("GitHub API error (HTTP %d): %s", status, apiErr.Message)
+	}
+	return fmt.Sprintf("GitHub API error (HTTP %d)", status)
+}
@@ -0,0 +1,141 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func githubReply(status int, body string, header http.Header) *http.Response {
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Header: header}
+}
+
+func TestGitHubCodeSearch(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "ghp_test")
+	r := NewRegistry()
+	var got *http.Request
+	r.client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = req
+		body := `{"total_count":1,"items":[{"path":"pkg/retry.go","html_url":"https://github.com/acme/lib/blob/main/pkg/retry.go","repository":{"full_name":"acme/lib"},"text_matches":[{"fragment":"func Retry(ctx context.Context) error {\n\treturn nil"}]}]}`
+		return githubReply(http.StatusOK, body, nil), nil
+	})}
+
+	out, err := r.Execute(context.Background(), "github_code_search", json.RawMessage(`{"query":"func Retry","language":"go","max_results":3}`))
+	if err != nil {
+		t.Fatalf("github_code_search: %v", err)
+	}
+	if !strings.Contains(out, "acme/lib/pkg/retry.go") || !strings.Contains(out, "func Retry(ctx context.Context) error {") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+	if q := got.URL.Query(); got.URL.Path != "/search/code" || q.Get("q") != "func Retry language:go" || q.Get("per_page") != "3" {
+		t.Fatalf("request url = %s", got.URL)
+	}
+	if got.Header.Get("Authorization") != "Bearer ghp_test" || !strings.Contains(got.Header.Get("Accept"), "text-match") {
+		t.Fatalf("request headers = %v", got.Header)
+	}
+
+	if _, err := r.handleGitHubCodeSearch(context.Background(), json.RawMessage(`{"language":"go"}`)); err == nil {
+		t.Fatalf("expected query required error")
+	}
+	t.Setenv("GITHUB_TOKEN", "")
+	if _, err := r.handleGitHubCodeSearch(context.Background(), json.RawMessage(`{"query":"x"}`)); err == nil {
+		t.Fatalf("expected missing token error")
+	}
+}
+
+func TestGitHubFileFetch(t *testing.T) {
+	r := NewRegistry()
+	r.client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Path {
+		case "/repos/acme/lib/contents/pkg/retry.go":
+			if req.URL.Query().Get("ref") != "v1.2.0" {
+				t.Errorf("ref = %q", req.URL.Query().Get("ref"))
+			}
+			content := base64.StdEncoding.EncodeToString([]byte("package pkg\n\nfunc Retry() {}\n"))
+			return githubReply(http.StatusOK, `{"type":"file","encoding":"base64","content":"`+content[:10]+`\n`+content[10:]+`","size":30}`, nil), nil
+		case "/repos/acme/lib/contents/pkg":
+			return githubReply(http.StatusOK, `[{"name":"retry.go","type":"file"},{"name":"internal","type":"dir"}]`, nil), nil
+		default:
+			return githubReply(http.StatusNotFound, `{"message":"Not Found"}`, nil), nil
+		}
+	})}
+
+	tests := []struct {
+		name    string
+		args    string
+		wantErr bool
+		want    string
+	}{
+		{name: "file", args: `{"owner":"acme","repo":"lib","path":"/pkg/retry.go","ref":"v1.2.0"}`, want: "func Retry() {}"},
+		{name: "directory", args: `{"owner":"acme","repo":"lib","path":"pkg"}`, want: "- internal (dir)"},
+		{name: "not found", args: `{"owner":"acme","repo":"lib","path":"missing.go"}`, want: "HTTP 404): Not Found"},
+		{name: "missing path", args: `{"owner":"acme","repo":"lib"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		out, err := r.handleGitHubFileFetch(context.Background(), json.RawMessage(tt.args))
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: err=%v wantErr=%v", tt.name, err, tt.wantErr)
+		}
+		if tt.want != "" && !strings.Contains(out, tt.want) {
+			t.Fatalf("%s: output %q missing %q", tt.name, out, tt.want)
+		}
+	}
+}
+
+func TestGitHubRateLimit(t *testing.T) {
+	t.Parallel()
+	var l githubRateLimiter
+	exhausted := func(reset time.Time) http.Header {
+		h := make(http.Header)
+		h.Set("X-RateLimit-Remaining", "0")
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		return h
+	}
+
+	l.observe("search", exhausted(time.Now().Add(time.Hour)))
+	if err := l.wait(context.Background(), "search"); err == nil || !strings.Contains(err.Error(), "rate limit exhausted") {
+		t.Fatalf("expected exhausted error, got %v", err)
+	}
+	if err := l.wait(context.Background(), "core"); err != nil {
+		t.Fatalf("core should not wait: %v", err)
+	}
+
+	l.observe("search", exhausted(time.Now().Add(30*time.Second)))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx, "search"); err != context.DeadlineExceeded {
+		t.Fatalf("expected to wait for reset, got %v", err)
+	}
+
+	ok := make(http.Header)
+	ok.Set("X-RateLimit-Remaining", "9")
+	l.observe("search", ok)
+	if err := l.wait(context.Background(), "search"); err != nil {
+		t.Fatalf("wait after recovery: %v", err)
+	}
+}
+
+func TestCoderHasGitHubTools(t *testing.T) {
+	t.Parallel()
+	names := map[string]bool{}
+	for _, tool := range NewRegistry().GetTools("coder") {
+		names[tool.Function.Name] = true
+	}
+	if !names["github_code_search"] || !names["github_file_fetch"] {
+		t.Fatalf("coder tools = %v", names)
+	}
+	for _, tool := range NewRegistry().GetTools("research") {
+		if strings.HasPrefix(tool.Function.Name, "github_") {
+			t.Fatalf("research should not get %s", tool.Function.Name)
+		}
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Message is an OpenAI-format message for the tool loop.
@@ -46,6 +48,13 @@ func RunToolLoop(ctx context.Context, reg *Registry, cfg LoopConfig, messages []
 		return "", fmt.Errorf("OPENAI_API_KEY not set — required for tool-calling agents")
 	}
 
+	runID := RunIDFromContext(ctx)
+	if runID == "" {
+		runID = uuid.NewString()
+		ctx = WithRunID(ctx, runID)
+	}
+	defer reg.endRun(runID)
+
 	// Map our model to OpenAI model name
 	model := mapModel(cfg.Model)
 
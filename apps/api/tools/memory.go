@@ -0,0 +1,175 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// maxMemoryContent caps one persistent memory.
+	maxMemoryContent = 4 << 10
+	// maxTenantMemoryBytes caps the content a tenant's persistent memories
+	// hold in total.
+	maxTenantMemoryBytes = 256 << 10
+	// maxMemoryKeywords caps the words matched when picking memories for a
+	// request's context.
+	maxMemoryKeywords = 10
+)
+
+// Persistent memory scopes: a conversation memory is recalled only in the
+// conversation that saved it, a tenant memory in all of the tenant's.
+const (
+	MemoryScopeConversation = "conversation"
+	MemoryScopeTenant       = "tenant"
+)
+
+var (
+	ErrInvalidMemory = errors.New("invalid memory")
+	ErrMemoryFull    = fmt.Errorf("persistent memory is full (%d KB per tenant); delete memories that are no longer needed", maxTenantMemoryBytes>>10)
+)
+
+// Memory is a fact an agent saved with memory_persist. ConversationID is
+// empty for tenant-scoped memories.
+type Memory struct {
+	ID             string    `json:"id"`
+	Scope          string    `json:"scope"`
+	ConversationID string    `json:"conversation_id,omitempty"`
+	Key            string    `json:"key"`
+	Content        string    `json:"content"`
+	Category       string    `json:"category"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Normalize trims m and checks it can be stored.
+func (m *Memory) Normalize() error {
+	m.Key = strings.TrimSpace(m.Key)
+	m.Content = strings.TrimSpace(m.Content)
+	m.Category = strings.TrimSpace(m.Category)
+	if m.Category == "" {
+		m.Category = "note"
+	}
+	if m.Scope == "" {
+		m.Scope = MemoryScopeConversation
+	}
+	switch {
+	case m.Key == "" || len(m.Key) > 200:
+		return fmt.Errorf("%w: key must be 1-200 characters", ErrInvalidMemory)
+	case m.Content == "":
+		return fmt.Errorf("%w: content is required", ErrInvalidMemory)
+	case len(m.Content) > maxMemoryContent:
+		return fmt.Errorf("%w: content must be at most %d KB", ErrInvalidMemory, maxMemoryContent>>10)
+	case m.Scope == MemoryScopeTenant:
+		m.ConversationID = ""
+	case m.Scope == MemoryScopeConversation:
+		if m.ConversationID == "" {
+			return fmt.Errorf("%w: conversation memories need a conversation", ErrInvalidMemory)
+		}
+	default:
+		return fmt.Errorf("%w: scope must be conversation or tenant", ErrInvalidMemory)
+	}
+	return nil
+}
+
+// MemoryBackend keeps the memories agents persist beyond working memory.
+// MemoryStore implements it.
+type MemoryBackend interface {
+	PersistMemory(ctx context.Context, tenantID string, m Memory) (Memory, error)
+	// RecallMemory returns the memory saved under key that conversationID can
+	// see, preferring the conversation's own over a tenant memory.
+	RecallMemory(ctx context.Context, tenantID, conversationID, key string) (Memory, bool, error)
+	// RelevantMemories returns up to limit memories conversationID can see
+	// that mention the most keywords.
+	RelevantMemories(ctx context.Context, tenantID, conversationID string, keywords []string, limit int) ([]Memory, error)
+}
+
+// SetMemoryBackend registers the memory_persist tool and makes memory_recall
+// fall back to persistent memories for keys not in working memory.
+func (r *Registry) SetMemoryBackend(backend MemoryBackend) {
+	r.memories = backend
+
+	// ─── memory_persist ─────────────────────────────────────────────────
+	r.tools["memory_persist"] = Tool{
+		Type: "function",
+		Function: FunctionDef{
+			Name:        "memory_persist",
+			Description: "Save a fact so it is remembered in later runs, unlike memory_store which only lasts for this request. Use it for things the user asks you to remember, such as dates, preferences or decisions. Use scope 'tenant' for facts that hold across all of the user's conversations.",
+			Parameters:  json.RawMessage(`{"type":"object","properties":{"key":{"type":"string","description":"Short unique key, e.g. 'launch_date'. Saving the same key again replaces the memory."},"content":{"type":"string","description":"The fact to remember"},"category":{"type":"string","description":"Category: finding, source, data, note","enum":["finding","source","data","note"]},"scope":{"type":"string","enum":["conversation","tenant"],"description":"conversation (default) or tenant"}},"required":["key","content"]}`),
+		},
+	}
+	r.handlers["memory_persist"] = r.handleMemoryPersist
+}
+
+func (r *Registry) handleMemoryPersist(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Key      string `json:"key"`
+		Content  string `json:"content"`
+		Category string `json:"category"`
+		Scope    string `json:"scope"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse args: %w", err)
+	}
+	tenantID, _ := ctx.Value(tenantContextKey).(string)
+	if r.memories == nil || tenantID == "" {
+		return "", errors.New("persistent memory is not available")
+	}
+	conversationID, _ := ctx.Value(conversationContextKey).(string)
+	saved, err := r.memories.PersistMemory(ctx, tenantID, Memory{
+		Scope:          strings.TrimSpace(params.Scope),
+		ConversationID: conversationID,
+		Key:            params.Key,
+		Content:        params.Content,
+		Category:       params.Category,
+	})
+	if errors.Is(err, ErrInvalidMemory) || errors.Is(err, ErrMemoryFull) {
+		return err.Error(), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Remembered '%s' for this %s.", saved.Key, saved.Scope), nil
+}
+
+// recallPersistent looks key up in persistent memory for memory_recall.
+func (r *Registry) recallPersistent(ctx context.Context, key string) (string, bool) {
+	tenantID, _ := ctx.Value(tenantContextKey).(string)
+	if r.memories == nil || tenantID == "" {
+		return "", false
+	}
+	conversationID, _ := ctx.Value(conversationContextKey).(string)
+	m, ok, err := r.memories.RecallMemory(ctx, tenantID, conversationID, key)
+	if err != nil {
+		slog.Warn("recall persistent memory failed", "tenant", tenantID, "key", key, "err", err)
+		return "", false
+	}
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("[%s] %s", m.Category, m.Content), true
+}
+
+// MemoryKeywords returns the distinct words of text that are long enough to
+// match memories on, lowercased.
+func MemoryKeywords(text string) []string {
+	seen := map[string]bool{}
+	var keywords []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	}) {
+		if len(word) < 4 || seen[word] {
+			continue
+		}
+		seen[word] = true
+		keywords = append(keywords, word)
+		if len(keywords) == maxMemoryKeywords {
+			break
+		}
+	}
+	return keywords
+}
@@ -0,0 +1,157 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// MemoryStore is the MemoryBackend used in production. Memories live in
+// tenant_memories, one per tenant, conversation and key; tenant memories
+// have an empty conversation_id.
+type MemoryStore struct {
+	db *sql.DB
+}
+
+func NewMemoryStore(db *sql.DB) *MemoryStore {
+	return &MemoryStore{db: db}
+}
+
+const memoryColumns = `id, scope, conversation_id, key, content, category, updated_at`
+
+func scanMemory(row interface{ Scan(...any) error }) (Memory, error) {
+	var m Memory
+	err := row.Scan(&m.ID, &m.Scope, &m.ConversationID, &m.Key, &m.Content, &m.Category, &m.UpdatedAt)
+	return m, err
+}
+
+// PersistMemory saves m, replacing the memory with the same key in its scope.
+// It returns ErrMemoryFull when the tenant's memories would exceed
+// maxTenantMemoryBytes.
+func (s *MemoryStore) PersistMemory(ctx context.Context, tenantID string, m Memory) (Memory, error) {
+	if err := m.Normalize(); err != nil {
+		return Memory{}, err
+	}
+	// The memory being replaced does not count towards the cap.
+	saved, err := scanMemory(s.db.QueryRowContext(ctx, `
+		INSERT INTO tenant_memories (tenant_id, scope, conversation_id, key, content, category)
+		SELECT $1, $2, $3, $4, $5, $6
+		WHERE (
+			SELECT COALESCE(SUM(octet_length(content)), 0)
+			FROM tenant_memories
+			WHERE tenant_id = $1 AND NOT (conversation_id = $3 AND key = $4)
+		) + octet_length($5) <= $7
+		ON CONFLICT (tenant_id, conversation_id, key) DO UPDATE
+		SET content = EXCLUDED.content, category = EXCLUDED.category, updated_at = NOW()
+		RETURNING `+memoryColumns,
+		tenantID, m.Scope, m.ConversationID, m.Key, m.Content, m.Category, maxTenantMemoryBytes))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Memory{}, ErrMemoryFull
+	}
+	if err != nil {
+		return Memory{}, fmt.Errorf("save memory: %w", err)
+	}
+	return saved, nil
+}
+
+func (s *MemoryStore) RecallMemory(ctx context.Context, tenantID, conversationID, key string) (Memory, bool, error) {
+	m, err := scanMemory(s.db.QueryRowContext(ctx, `
+		SELECT `+memoryColumns+`
+		FROM tenant_memories
+		WHERE tenant_id = $1 AND key = $3 AND (scope = 'tenant' OR conversation_id = $2)
+		ORDER BY scope = 'conversation' DESC
+		LIMIT 1
+	`, tenantID, conversationID, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Memory{}, false, nil
+	}
+	if err != nil {
+		return Memory{}, false, fmt.Errorf("recall memory: %w", err)
+	}
+	return m, true, nil
+}
+
+// RelevantMemories ranks memories by how many keywords their key and content
+// contain, newest first among equals. Memories matching none are left out.
+func (s *MemoryStore) RelevantMemories(ctx context.Context, tenantID, conversationID string, keywords []string, limit int) ([]Memory, error) {
+	if len(keywords) == 0 || limit <= 0 {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+memoryColumns+`
+		FROM (
+			SELECT m.*, (
+				SELECT COUNT(*) FROM unnest($3::text[]) AS kw
+				WHERE strpos(lower(m.key || ' ' || m.content), kw) > 0
+			) AS score
+			FROM tenant_memories m
+			WHERE m.tenant_id = $1 AND (m.scope = 'tenant' OR m.conversation_id = $2)
+		) scored
+		WHERE score > 0
+		ORDER BY score DESC, updated_at DESC
+		LIMIT $4
+	`, tenantID, conversationID, pq.Array(keywords), limit)
+	if err != nil {
+		return nil, fmt.Errorf("query relevant memories: %w", err)
+	}
+	defer rows.Close()
+	var list []Memory
+	for rows.Next() {
+		m, err := scanMemory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan memory: %w", err)
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// List returns the tenant's memories, newest first. A non-empty
+// conversationID limits them to that conversation's.
+func (s *MemoryStore) List(ctx context.Context, tenantID, conversationID string) ([]Memory, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+memoryColumns+`
+		FROM tenant_memories
+		WHERE tenant_id = $1 AND ($2 = '' OR conversation_id = $2)
+		ORDER BY updated_at DESC
+	`, tenantID, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("query memories: %w", err)
+	}
+	defer rows.Close()
+	list := []Memory{}
+	for rows.Next() {
+		m, err := scanMemory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan memory: %w", err)
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// Delete removes the tenant's memory id and reports whether there was one.
+func (s *MemoryStore) Delete(ctx context.Context, tenantID, id string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM tenant_memories WHERE tenant_id = $1 AND id::text = $2`, tenantID, id)
+	if err != nil {
+		return false, fmt.Errorf("delete memory: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete memory: %w", err)
+	}
+	return n > 0, nil
+}
+
+// DeleteAll removes the tenant's memories, or only conversationID's when it
+// is not empty, and returns how many there were.
+func (s *MemoryStore) DeleteAll(ctx context.Context, tenantID, conversationID string) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM tenant_memories WHERE tenant_id = $1 AND ($2 = '' OR conversation_id = $2)`, tenantID, conversationID)
+	if err != nil {
+		return 0, fmt.Errorf("delete memories: %w", err)
+	}
+	return res.RowsAffected()
+}
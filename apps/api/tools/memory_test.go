@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// fakeMemories is an in-memory MemoryBackend.
+type fakeMemories struct {
+	saved []Memory
+}
+
+func (f *fakeMemories) PersistMemory(_ context.Context, _ string, m Memory) (Memory, error) {
+	if err := m.Normalize(); err != nil {
+		return Memory{}, err
+	}
+	f.saved = append(f.saved, m)
+	return m, nil
+}
+
+func (f *fakeMemories) RecallMemory(_ context.Context, _, conversationID, key string) (Memory, bool, error) {
+	for _, m := range f.saved {
+		if m.Key == key && (m.Scope == MemoryScopeTenant || m.ConversationID == conversationID) {
+			return m, true, nil
+		}
+	}
+	return Memory{}, false, nil
+}
+
+func (f *fakeMemories) RelevantMemories(context.Context, string, string, []string, int) ([]Memory, error) {
+	return f.saved, nil
+}
+
+func TestMemoryPersistAndRecall(t *testing.T) {
+	t.Parallel()
+	r := NewRegistry()
+	offered := func(agentID string) bool {
+		for _, tl := range r.GetTools(agentID) {
+			if tl.Function.Name == "memory_persist" {
+				return true
+			}
+		}
+		return false
+	}
+	if offered("research") {
+		t.Fatalf("memory_persist should only be offered with a backend")
+	}
+	backend := &fakeMemories{}
+	r.SetMemoryBackend(backend)
+	if !offered("research") || offered("social") {
+		t.Fatalf("memory_persist offered to research=%v social=%v", offered("research"), offered("social"))
+	}
+
+	ctx := WithMemoryContext(context.Background(), "t1", "c-persist-1")
+	out, err := r.Execute(ctx, "memory_persist", json.RawMessage(`{"key":"launch_date","content":"March 3","scope":"tenant"}`))
+	if err != nil || out != "Remembered 'launch_date' for this tenant." {
+		t.Fatalf("persist = %q err=%v", out, err)
+	}
+	if _, err := r.Execute(ctx, "memory_persist", json.RawMessage(`{"key":"brand","content":"Acme"}`)); err != nil {
+		t.Fatalf("persist conversation memory: %v", err)
+	}
+	if got := backend.saved[1]; got.Scope != MemoryScopeConversation || got.ConversationID != "c-persist-1" || got.Category != "note" {
+		t.Fatalf("conversation memory = %+v", got)
+	}
+	if out, _ := r.Execute(ctx, "memory_persist", json.RawMessage(`{"key":"x","content":"y","scope":"global"}`)); !strings.Contains(out, "scope must be") {
+		t.Fatalf("invalid scope result = %q", out)
+	}
+
+	// Another conversation has no working memory, so recall falls back to
+	// persistent memory and sees only the tenant memory.
+	other := WithMemoryContext(context.Background(), "t1", "c-persist-2")
+	if out, _ := r.Execute(other, "memory_recall", json.RawMessage(`{"key":"launch_date"}`)); out != "[note] March 3" {
+		t.Fatalf("recall = %q", out)
+	}
+	if out, _ := r.Execute(other, "memory_recall", json.RawMessage(`{"key":"brand"}`)); out != "No memory stored yet." {
+		t.Fatalf("recall of another conversation's memory = %q", out)
+	}
+	if _, err := r.Execute(other, "memory_store", json.RawMessage(`{"key":"draft","content":"v1"}`)); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	if out, _ := r.Execute(other, "memory_recall", json.RawMessage(`{"key":"launch_date"}`)); out != "[note] March 3" {
+		t.Fatalf("recall on working memory miss = %q", out)
+	}
+}
+
+func TestMemoryKeywords(t *testing.T) {
+	t.Parallel()
+	got := MemoryKeywords("When is our LAUNCH date? The launch, I mean.")
+	if strings.Join(got, ",") != "when,launch,date,mean" {
+		t.Fatalf("keywords = %v", got)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	store := NewMemoryStore(db)
+	ctx := context.Background()
+	columns := []string{"id", "scope", "conversation_id", "key", "content", "category", "updated_at"}
+	now := time.Now()
+
+	mock.ExpectQuery("INSERT INTO tenant_memories").
+		WithArgs("t1", "tenant", "", "launch_date", "March 3", "data", maxTenantMemoryBytes).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("m1", "tenant", "", "launch_date", "March 3", "data", now))
+	saved, err := store.PersistMemory(ctx, "t1", Memory{Scope: "tenant", ConversationID: "c1", Key: " launch_date ", Content: "March 3", Category: "data"})
+	if err != nil || saved.ID != "m1" {
+		t.Fatalf("persist = %+v err=%v", saved, err)
+	}
+
+	// No row comes back when the memory would go over the tenant's cap.
+	mock.ExpectQuery("INSERT INTO tenant_memories").WillReturnRows(sqlmock.NewRows(columns))
+	if _, err := store.PersistMemory(ctx, "t1", Memory{ConversationID: "c1", Key: "k", Content: "v"}); !errors.Is(err, ErrMemoryFull) {
+		t.Fatalf("persist over cap err = %v", err)
+	}
+	if _, err := store.PersistMemory(ctx, "t1", Memory{Key: "k", Content: strings.Repeat("x", maxMemoryContent+1), Scope: "tenant"}); !errors.Is(err, ErrInvalidMemory) {
+		t.Fatalf("oversized memory err = %v", err)
+	}
+
+	mock.ExpectQuery("strpos").
+		WithArgs("t1", "c1", sqlmock.AnyArg(), 3).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("m1", "tenant", "", "launch_date", "March 3", "data", now))
+	relevant, err := store.RelevantMemories(ctx, "t1", "c1", []string{"launch"}, 3)
+	if err != nil || len(relevant) != 1 {
+		t.Fatalf("relevant = %+v err=%v", relevant, err)
+	}
+
+	mock.ExpectQuery("FROM tenant_memories").
+		WithArgs("t1", "c2", "launch_date").
+		WillReturnRows(sqlmock.NewRows(columns))
+	if _, ok, err := store.RecallMemory(ctx, "t1", "c2", "launch_date"); ok || err != nil {
+		t.Fatalf("recall missing: ok=%v err=%v", ok, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
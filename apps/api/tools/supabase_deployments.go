@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/agentsquads/api/keyring"
+)
+
+// SupabaseDeployments is the DBQueryBackend used in production. Projects come
+// from the tenant's latest succeeded Supabase deployment run and the token
+// the tenant connected in deploy_connections; queries are recorded in
+// agent_db_queries.
+type SupabaseDeployments struct {
+	db   *sql.DB
+	keys func() (*keyring.Keyring, error)
+}
+
+func NewSupabaseDeployments(db *sql.DB) *SupabaseDeployments {
+	return &SupabaseDeployments{db: db, keys: keyring.FromEnv}
+}
+
+func (s *SupabaseDeployments) SupabaseProject(ctx context.Context, tenantID string) (SupabaseProject, bool, error) {
+	var ref, encrypted string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT r.external_id, c.access_token_encrypted
+		FROM deployment_runs r
+		JOIN deploy_connections c ON c.tenant_id = r.tenant_id AND c.provider = 'supabase'
+		WHERE r.tenant_id = $1
+		  AND r.provider = 'supabase'
+		  AND r.status = 'succeeded'
+		  AND COALESCE(r.external_id, '') <> ''
+		ORDER BY r.updated_at DESC
+		LIMIT 1
+	`, tenantID).Scan(&ref, &encrypted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return SupabaseProject{}, false, nil
+	}
+	if err != nil {
+		return SupabaseProject{}, false, fmt.Errorf("query supabase deployment: %w", err)
+	}
+
+	keys, err := s.keys()
+	if err != nil {
+		return SupabaseProject{}, false, err
+	}
+	token, err := keys.Decrypt(encrypted)
+	if err != nil {
+		return SupabaseProject{}, false, fmt.Errorf("decrypt supabase token: %w", err)
+	}
+	return SupabaseProject{Ref: ref, Token: strings.TrimSpace(token)}, true, nil
+}
+
+func (s *SupabaseDeployments) LogDBQuery(ctx context.Context, entry DBQueryLog) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO agent_db_queries (tenant_id, run_id, project_ref, query, status, row_count, error)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+	`, entry.TenantID, entry.RunID, entry.ProjectRef, entry.Query, entry.Status, entry.Rows, entry.Error)
+	if err != nil {
+		return fmt.Errorf("insert db query log: %w", err)
+	}
+	return nil
+}
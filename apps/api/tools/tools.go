@@ -3,15 +3,17 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/agentsquads/api/policies"
 )
 
 // Tool represents an OpenAI-format tool definition.
@@ -48,13 +50,29 @@ type Registry struct {
 	tools    map[string]Tool
 	handlers map[string]func(ctx context.Context, args json.RawMessage) (string, error)
 	client   *http.Client
+	github   githubRateLimiter
+	media    MediaReader
+	dbQuery  DBQueryBackend
+	policies policies.Checker
+	custom   CustomToolBackend
+	webhook  *WebhookTool
+	memories MemoryBackend
+	// fetchGuard checks every request made through client; see
+	// SetFetchAllowlist.
+	fetchGuard *fetchGuard
+
+	mu             sync.Mutex
+	runQueries     map[string]int // run id -> db_query calls so far
+	runCustomCalls map[string]int // run id -> custom tool calls so far
 }
 
 func NewRegistry() *Registry {
+	guard := newFetchGuard()
 	r := &Registry{
-		tools:    make(map[string]Tool),
-		handlers: make(map[string]func(ctx context.Context, args json.RawMessage) (string, error)),
-		client:   &http.Client{Timeout: 30 * time.Second},
+		tools:      make(map[string]Tool),
+		handlers:   make(map[string]func(ctx context.Context, args json.RawMessage) (string, error)),
+		client:     newGuardedClient(guard),
+		fetchGuard: guard,
 	}
 	r.registerAll()
 	return r
@@ -72,11 +90,35 @@ func (r *Registry) GetTools(agentID string) []Tool {
 	return result
 }
 
-// Execute runs a tool by name with the given arguments.
+// ToolsForTenant is GetTools without the tools tenantID cannot use, such as
+// db_query when the policy is off or there is no Supabase deployment and
+// webhook_call when the tenant has not enabled and configured it, plus the
+// tenant's custom tools.
+func (r *Registry) ToolsForTenant(ctx context.Context, tenantID, agentID string) []Tool {
+	all := r.GetTools(agentID)
+	result := all[:0:0]
+	for _, t := range all {
+		switch t.Function.Name {
+		case "db_query":
+			if _, err := r.dbQueryProject(ctx, tenantID); err != nil {
+				continue
+			}
+		case "webhook_call":
+			if _, err := r.webhook.settings(ctx, tenantID); err != nil {
+				continue
+			}
+		}
+		result = append(result, t)
+	}
+	return append(result, r.customToolDefs(ctx, tenantID)...)
+}
+
+// Execute runs a tool by name with the given arguments. Names that are not
+// built-in tools are looked up among the custom tools of the tenant in ctx.
 func (r *Registry) Execute(ctx context.Context, name string, args json.RawMessage) (string, error) {
 	handler, ok := r.handlers[name]
 	if !ok {
-		return "", fmt.Errorf("unknown tool: %s", name)
+		return r.executeCustomTool(ctx, name, args)
 	}
 	return handler(ctx, args)
 }
@@ -84,19 +126,19 @@ func (r *Registry) Execute(ctx context.Context, name string, args json.RawMessag
 func agentToolMap(agentID string) []string {
 	switch agentID {
 	case "research":
-		return []string{"web_search", "web_fetch", "memory_store", "memory_recall"}
+		return []string{"web_search", "web_fetch", "memory_store", "memory_recall", "memory_persist", "file_read", "db_query", "webhook_call"}
 	case "coder":
-		return []string{"web_search", "web_fetch"}
+		return []string{"web_search", "web_fetch", "github_code_search", "github_file_fetch", "file_read", "db_query", "webhook_call"}
 	case "intel":
-		return []string{"web_search", "web_fetch", "memory_store", "memory_recall"}
+		return []string{"web_search", "web_fetch", "memory_store", "memory_recall", "memory_persist", "file_read", "db_query", "webhook_call"}
 	case "social":
-		return []string{"web_search", "web_fetch"}
+		return []string{"web_search", "web_fetch", "file_read"}
 	case "clip":
-		return []string{"web_search", "web_fetch"}
+		return []string{"web_search", "web_fetch", "file_read"}
 	case "chat":
-		return []string{"web_search", "web_fetch"}
+		return []string{"web_search", "web_fetch", "file_read", "db_query", "webhook_call"}
 	default:
-		return []string{"web_search", "web_fetch"}
+		return []string{"web_search", "web_fetch", "file_read"}
 	}
 }
 
@@ -144,6 +186,8 @@ func (r *Registry) registerAll() {
 		},
 	}
 	r.handlers["memory_recall"] = r.handleMemoryRecall
+
+	r.registerGitHub()
 }
 
 // ─── Tool handlers ──────────────────────────────────────────────────────────
@@ -283,6 +327,11 @@ func (r *Registry) handleWebFetch(ctx context.Context, args json.RawMessage) (st
 
 	resp, err := r.client.Do(req)
 	if err != nil {
+		// A blocked URL is the model's mistake, not a reason to fail the task.
+		var blocked *BlockedURLError
+		if errors.As(err, &blocked) {
+			return blocked.Error(), nil
+		}
 		return "", fmt.Errorf("fetch url: %w", err)
 	}
 	defer resp.Body.Close()
@@ -373,6 +422,11 @@ func (r *Registry) handleMemoryRecall(ctx context.Context, args json.RawMessage)
 	}
 	mem := workingMemory[memID]
 	if mem == nil {
+		if params.Key != "*" {
+			if val, ok := r.recallPersistent(ctx, params.Key); ok {
+				return val, nil
+			}
+		}
 		return "No memory stored yet.", nil
 	}
 
@@ -392,6 +446,9 @@ func (r *Registry) handleMemoryRecall(ctx context.Context, args json.RawMessage)
 
 	val, ok := mem[params.Key]
 	if !ok {
+		if val, ok := r.recallPersistent(ctx, params.Key); ok {
+			return val, nil
+		}
 		return fmt.Sprintf("Key '%s' not found in memory.", params.Key), nil
 	}
 	return val, nil
@@ -399,9 +456,30 @@ func (r *Registry) handleMemoryRecall(ctx context.Context, args json.RawMessage)
 
 type contextKey string
 
-const memoryContextKey contextKey = "memory_id"
+const (
+	memoryContextKey       contextKey = "memory_id"
+	tenantContextKey       contextKey = "tenant_id"
+	conversationContextKey contextKey = "conversation_id"
+	runContextKey          contextKey = "run_id"
+)
+
+// WithRunID tags ctx with the tool loop run that tool calls belong to.
+// RunToolLoop assigns one when the caller has not.
+func WithRunID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, runContextKey, runID)
+}
+
+// RunIDFromContext returns the tool loop run id, or "" outside a run.
+func RunIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(runContextKey).(string)
+	return id
+}
 
-// WithMemoryContext returns a context with the memory scope ID set.
+// WithMemoryContext returns a context with the memory scope ID and the
+// tenant and conversation that tools such as file_read and memory_persist
+// act for.
 func WithMemoryContext(ctx context.Context, tenantID, conversationID string) context.Context {
+	ctx = context.WithValue(ctx, tenantContextKey, tenantID)
+	ctx = context.WithValue(ctx, conversationContextKey, conversationID)
 	return context.WithValue(ctx, memoryContextKey, memKey(tenantID, conversationID))
 }
@@ -9,6 +9,8 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/agentsquads/api/policies"
 )
 
 type roundTripFunc func(*http.Request) (*http.Response, error)
@@ -37,6 +39,11 @@ func TestExecuteUnknownTool(t *testing.T) {
 func TestWebFetch(t *testing.T) {
 	t.Parallel()
 	r := NewRegistry()
+	// The test server is on loopback, which t1 is allowed to reach.
+	r.SetFetchAllowlist(stubPolicySettings{
+		stubPolicies: stubPolicies{"t1|" + policies.FeatureInternalFetch: true},
+		settings:     map[string]string{"t1": `{"internal_fetch_allowed":["127.0.0.1"]}`},
+	})
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		if req.URL.Path == "/404" {
 			w.WriteHeader(http.StatusNotFound)
@@ -49,18 +56,21 @@ func TestWebFetch(t *testing.T) {
 
 	tests := []struct {
 		name    string
+		tenant  string
 		args    string
 		wantErr bool
 		want    string
 	}{
-		{name: "happy path", args: `{"url":"` + ts.URL + `"}`, want: "Hello world"},
-		{name: "http error status", args: `{"url":"` + ts.URL + `/404"}`, want: "HTTP 404"},
-		{name: "missing url", args: `{}`, wantErr: true},
+		{name: "happy path", tenant: "t1", args: `{"url":"` + ts.URL + `"}`, want: "Hello world"},
+		{name: "http error status", tenant: "t1", args: `{"url":"` + ts.URL + `/404"}`, want: "HTTP 404"},
+		{name: "missing url", tenant: "t1", args: `{}`, wantErr: true},
+		{name: "loopback without allow-list", tenant: "t2", args: `{"url":"` + ts.URL + `"}`, want: "URL not allowed"},
 	}
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
-			out, err := r.handleWebFetch(context.Background(), json.RawMessage(tt.args))
+			ctx := WithMemoryContext(context.Background(), tt.tenant, "c1")
+			out, err := r.handleWebFetch(ctx, json.RawMessage(tt.args))
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("handleWebFetch err=%v wantErr=%v", err, tt.wantErr)
 			}
@@ -0,0 +1,265 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/agentsquads/api/policies"
+)
+
+const (
+	// maxWebhookResponse caps the response body handed back to the model.
+	maxWebhookResponse = 32 << 10
+	// maxWebhookRequestBody caps the body an agent can send.
+	maxWebhookRequestBody = 64 << 10
+	defaultWebhookTimeout = 10 * time.Second
+	maxWebhookTimeout     = 30 * time.Second
+	// maxWebhookDomains caps a tenant's allow-list.
+	maxWebhookDomains = 50
+)
+
+// ErrWebhookUnavailable is returned when the tenant cannot use webhook_call:
+// the policy is off or no domains are allowed.
+var ErrWebhookUnavailable = errors.New("webhook_call is not available for this tenant")
+
+var webhookMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodHead:   true,
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// WebhookSettings are the settings of the webhook_tool policy. An entry of
+// AllowedDomains matches its host exactly; one starting with "*." matches
+// subdomains of the rest.
+type WebhookSettings struct {
+	AllowedDomains []string `json:"webhook_allowed_domains"`
+}
+
+// ParseWebhookSettings decodes and normalizes webhook_tool policy settings,
+// rejecting entries that are not host names.
+func ParseWebhookSettings(raw json.RawMessage) (WebhookSettings, error) {
+	var settings WebhookSettings
+	if len(raw) == 0 {
+		return settings, nil
+	}
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return WebhookSettings{}, errors.New("settings must be an object with a webhook_allowed_domains list")
+	}
+	if len(settings.AllowedDomains) > maxWebhookDomains {
+		return WebhookSettings{}, fmt.Errorf("at most %d webhook domains are allowed", maxWebhookDomains)
+	}
+	domains := make([]string, 0, len(settings.AllowedDomains))
+	for _, d := range settings.AllowedDomains {
+		d = strings.ToLower(strings.TrimSpace(d))
+		host := strings.TrimPrefix(d, "*.")
+		if host == "" || strings.ContainsAny(host, "/:*@ ") || !strings.Contains(host, ".") || net.ParseIP(host) != nil {
+			return WebhookSettings{}, fmt.Errorf("invalid webhook domain %q", d)
+		}
+		domains = append(domains, d)
+	}
+	settings.AllowedDomains = domains
+	return settings, nil
+}
+
+// allows reports whether host is on the allow-list.
+func (s WebhookSettings) allows(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, d := range s.AllowedDomains {
+		if parent, ok := strings.CutPrefix(d, "*."); ok {
+			if strings.HasSuffix(host, "."+parent) {
+				return true
+			}
+			continue
+		}
+		if host == d {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicySettingsSource reports whether a tenant has a feature enabled and
+// returns the settings stored with its policy. policies.Store implements it.
+type PolicySettingsSource interface {
+	policies.Checker
+	Settings(ctx context.Context, tenantID, feature string) (json.RawMessage, error)
+}
+
+// WebhookTool is the webhook_call tool: it lets agents call external HTTP
+// APIs on the hosts their tenant allows. Its requests go through the
+// registry's fetch guard like every other tool request, so a host that
+// resolves to an internal address is refused, and redirects are returned
+// rather than followed.
+type WebhookTool struct {
+	policies PolicySettingsSource
+	client   *http.Client
+}
+
+// NewWebhookTool creates the webhook_call tool for tenants whose
+// webhook_tool policy source enables it. It can call out once registered
+// with SetWebhookTool.
+func NewWebhookTool(source PolicySettingsSource) *WebhookTool {
+	return &WebhookTool{policies: source}
+}
+
+// SetWebhookTool registers the webhook_call tool, sending its requests
+// through the registry's fetch guard. Tenants only get it when the
+// webhook_tool policy is enabled for them (see ToolsForTenant).
+func (r *Registry) SetWebhookTool(t *WebhookTool) {
+	t.client = newGuardedClient(r.fetchGuard)
+	t.client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	r.webhook = t
+
+	// ─── webhook_call ───────────────────────────────────────────────────
+	r.tools["webhook_call"] = Tool{
+		Type: "function",
+		Function: FunctionDef{
+			Name:        "webhook_call",
+			Description: fmt.Sprintf("Call an external HTTP API the tenant has allowed, such as their CRM or ERP. Only hosts on the tenant's allow-list can be called. Returns the status code, response headers and up to %d KB of the response body.", maxWebhookResponse>>10),
+			Parameters:  json.RawMessage(`{"type":"object","properties":{"url":{"type":"string","description":"Full URL to call, e.g. https://api.example.com/v1/orders"},"method":{"type":"string","enum":["GET","HEAD","POST","PUT","PATCH","DELETE"],"description":"HTTP method (default GET)"},"headers":{"type":"object","additionalProperties":{"type":"string"},"description":"Request headers"},"body":{"type":"string","description":"Request body"},"timeout_seconds":{"type":"integer","description":"Timeout in seconds (default 10, max 30)"}},"required":["url"]}`),
+		},
+	}
+	r.handlers["webhook_call"] = r.handleWebhookCall
+}
+
+// settings returns the tenant's allow-list when webhook_call is enabled for
+// it, and ErrWebhookUnavailable otherwise.
+func (t *WebhookTool) settings(ctx context.Context, tenantID string) (WebhookSettings, error) {
+	if t == nil || t.policies == nil || tenantID == "" {
+		return WebhookSettings{}, ErrWebhookUnavailable
+	}
+	enabled, err := t.policies.FeatureEnabled(ctx, tenantID, policies.FeatureWebhookTool)
+	if err != nil {
+		return WebhookSettings{}, fmt.Errorf("check webhook_tool policy: %w", err)
+	}
+	if !enabled {
+		policies.RecordDenial(ctx, tenantID, policies.FeatureWebhookTool, "tools")
+		return WebhookSettings{}, ErrWebhookUnavailable
+	}
+	raw, err := t.policies.Settings(ctx, tenantID, policies.FeatureWebhookTool)
+	if err != nil {
+		return WebhookSettings{}, err
+	}
+	settings, err := ParseWebhookSettings(raw)
+	if err != nil {
+		return WebhookSettings{}, fmt.Errorf("webhook_tool settings: %w", err)
+	}
+	if len(settings.AllowedDomains) == 0 {
+		return WebhookSettings{}, ErrWebhookUnavailable
+	}
+	return settings, nil
+}
+
+type webhookCallArgs struct {
+	URL            string            `json:"url"`
+	Method         string            `json:"method"`
+	Headers        map[string]string `json:"headers"`
+	Body           string            `json:"body"`
+	TimeoutSeconds int               `json:"timeout_seconds"`
+}
+
+func (r *Registry) handleWebhookCall(ctx context.Context, args json.RawMessage) (string, error) {
+	var params webhookCallArgs
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse args: %w", err)
+	}
+	tenantID, _ := ctx.Value(tenantContextKey).(string)
+	settings, err := r.webhook.settings(ctx, tenantID)
+	if err != nil {
+		return "", err
+	}
+	req, timeout, err := params.request(settings)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	resp, err := r.webhook.client.Do(req.WithContext(ctx))
+	if err != nil {
+		var blocked *BlockedURLError
+		if errors.As(err, &blocked) {
+			return blocked.Error(), nil
+		}
+		return "", fmt.Errorf("webhook call failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponse+1))
+	if err != nil {
+		return "", fmt.Errorf("read webhook response: %w", err)
+	}
+	result := map[string]any{"status_code": resp.StatusCode}
+	if len(body) > maxWebhookResponse {
+		body = body[:maxWebhookResponse]
+		result["truncated"] = true
+	}
+	headers := make(map[string]string, len(resp.Header))
+	for name, values := range resp.Header {
+		headers[name] = strings.Join(values, ", ")
+	}
+	result["body"] = string(body)
+	result["headers"] = headers
+	out, _ := json.Marshal(result)
+	return string(out), nil
+}
+
+// request builds the HTTP request for the call, checking its URL against the
+// allow-list.
+func (p webhookCallArgs) request(settings WebhookSettings) (*http.Request, time.Duration, error) {
+	u, err := url.Parse(strings.TrimSpace(p.URL))
+	if err != nil || u.Host == "" {
+		return nil, 0, errors.New("url must be an absolute URL")
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return nil, 0, errors.New("url must use http or https")
+	}
+	if u.User != nil {
+		return nil, 0, errors.New("url must not contain credentials; use headers instead")
+	}
+	if !settings.allows(u.Hostname()) {
+		return nil, 0, fmt.Errorf("host %s is not on the tenant's webhook allow-list", u.Hostname())
+	}
+
+	method := strings.ToUpper(strings.TrimSpace(p.Method))
+	if method == "" {
+		method = http.MethodGet
+	}
+	if !webhookMethods[method] {
+		return nil, 0, fmt.Errorf("unsupported method %q", p.Method)
+	}
+	if len(p.Body) > maxWebhookRequestBody {
+		return nil, 0, fmt.Errorf("body must be at most %d KB", maxWebhookRequestBody>>10)
+	}
+	timeout := defaultWebhookTimeout
+	if p.TimeoutSeconds > 0 {
+		timeout = min(time.Duration(p.TimeoutSeconds)*time.Second, maxWebhookTimeout)
+	}
+
+	var body io.Reader
+	if p.Body != "" {
+		body = strings.NewReader(p.Body)
+	}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("build request: %w", err)
+	}
+	for name, value := range p.Headers {
+		if strings.EqualFold(name, "Host") {
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+	return req, timeout, nil
+}
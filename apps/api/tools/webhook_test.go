@@ -0,0 +1,159 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/agentsquads/api/policies"
+)
+
+type stubPolicySettings struct {
+	stubPolicies
+	settings map[string]string
+}
+
+func (s stubPolicySettings) Settings(_ context.Context, tenantID, _ string) (json.RawMessage, error) {
+	if raw, ok := s.settings[tenantID]; ok {
+		return json.RawMessage(raw), nil
+	}
+	return nil, nil
+}
+
+func TestParseWebhookSettings(t *testing.T) {
+	t.Parallel()
+	got, err := ParseWebhookSettings(json.RawMessage(`{"webhook_allowed_domains":[" API.Example.com ","*.crm.io"]}`))
+	if err != nil || len(got.AllowedDomains) != 2 || got.AllowedDomains[0] != "api.example.com" {
+		t.Fatalf("settings = %+v err=%v", got, err)
+	}
+	for host, want := range map[string]bool{
+		"api.example.com": true, "API.example.com.": true, "example.com": false, "evil-api.example.com": false,
+		"eu.crm.io": true, "crm.io": false, "eucrm.io": false,
+	} {
+		if got.allows(host) != want {
+			t.Fatalf("allows(%q) = %v", host, !want)
+		}
+	}
+	for _, raw := range []string{`[]`, `{"webhook_allowed_domains":["https://api.example.com"]}`, `{"webhook_allowed_domains":["10.0.0.1"]}`, `{"webhook_allowed_domains":["localhost"]}`} {
+		if _, err := ParseWebhookSettings(json.RawMessage(raw)); err == nil {
+			t.Fatalf("expected %s to be rejected", raw)
+		}
+	}
+}
+
+func TestWebhookCallTool(t *testing.T) {
+	t.Parallel()
+	r := NewRegistry()
+	feature := "|" + policies.FeatureWebhookTool
+	tool := NewWebhookTool(stubPolicySettings{
+		stubPolicies: stubPolicies{"t1" + feature: true, "t3" + feature: true},
+		settings: map[string]string{
+			"t1": `{"webhook_allowed_domains":["api.example.com"]}`,
+			"t2": `{"webhook_allowed_domains":["api.example.com"]}`,
+		},
+	})
+	r.SetWebhookTool(tool)
+
+	has := func(tenantID string) bool {
+		for _, tl := range r.ToolsForTenant(context.Background(), tenantID, "chat") {
+			if tl.Function.Name == "webhook_call" {
+				return true
+			}
+		}
+		return false
+	}
+	// t2 has domains but the policy is off; t3 has the policy but no domains.
+	if !has("t1") || has("t2") || has("t3") {
+		t.Fatalf("webhook_call offered to t1=%v t2=%v t3=%v", has("t1"), has("t2"), has("t3"))
+	}
+
+	var got *http.Request
+	var gotBody string
+	tool.client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = req
+		if req.Body != nil {
+			b, _ := io.ReadAll(req.Body)
+			gotBody = string(b)
+		}
+		header := http.Header{"Content-Type": {"application/json"}, "X-Request-Id": {"abc"}}
+		return &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader(strings.Repeat("x", maxWebhookResponse+10))), Header: header}, nil
+	})
+
+	ctx := WithMemoryContext(context.Background(), "t1", "c1")
+	out, err := r.Execute(ctx, "webhook_call", json.RawMessage(`{"url":"https://api.example.com/v1/leads","method":"post","headers":{"Authorization":"Bearer k","Host":"evil.test"},"body":"{\"name\":\"Ada\"}","timeout_seconds":5}`))
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if got.Method != http.MethodPost || got.URL.String() != "https://api.example.com/v1/leads" || got.Header.Get("Authorization") != "Bearer k" || got.Header.Get("Host") != "" || gotBody != `{"name":"Ada"}` {
+		t.Fatalf("request = %s %s headers=%v body=%q", got.Method, got.URL, got.Header, gotBody)
+	}
+	var res struct {
+		StatusCode int               `json:"status_code"`
+		Body       string            `json:"body"`
+		Headers    map[string]string `json:"headers"`
+		Truncated  bool              `json:"truncated"`
+	}
+	if err := json.Unmarshal([]byte(out), &res); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if res.StatusCode != http.StatusCreated || len(res.Body) != maxWebhookResponse || !res.Truncated || res.Headers["X-Request-Id"] != "abc" {
+		t.Fatalf("result status=%d body=%d truncated=%v headers=%v", res.StatusCode, len(res.Body), res.Truncated, res.Headers)
+	}
+
+	got = nil
+	for _, args := range []string{
+		`{"url":"https://other.example.com/"}`,
+		`{"url":"http://10.0.0.5/"}`,
+		`{"url":"ftp://api.example.com/"}`,
+		`{"url":"https://user:pw@api.example.com/"}`,
+		`{"url":"https://api.example.com/","method":"TRACE"}`,
+	} {
+		if _, err := r.Execute(ctx, "webhook_call", json.RawMessage(args)); err == nil {
+			t.Fatalf("expected %s to be rejected", args)
+		}
+	}
+	if _, err := r.Execute(WithMemoryContext(context.Background(), "t2", "c1"), "webhook_call", json.RawMessage(`{"url":"https://api.example.com/"}`)); err != ErrWebhookUnavailable {
+		t.Fatalf("disabled tenant err = %v", err)
+	}
+	if got != nil {
+		t.Fatalf("rejected calls reached the network: %s", got.URL)
+	}
+}
+
+// resolverFunc stands in for DNS in guard tests.
+type resolverFunc func(ctx context.Context, host string) ([]net.IPAddr, error)
+
+func (f resolverFunc) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return f(ctx, host)
+}
+
+func TestWebhookCallBlocksInternalAddresses(t *testing.T) {
+	// The tenant bridge network, configured as in production.
+	t.Setenv("TOOLS_FETCH_BLOCKED_CIDRS", "203.0.113.0/24")
+	r := NewRegistry()
+	feature := "|" + policies.FeatureWebhookTool
+	r.SetWebhookTool(NewWebhookTool(stubPolicySettings{
+		stubPolicies: stubPolicies{"t1" + feature: true},
+		settings:     map[string]string{"t1": `{"webhook_allowed_domains":["*.example.com"]}`},
+	}))
+	addrs := map[string]string{
+		"bridge.example.com":   "203.0.113.7",
+		"cgnat.example.com":    "100.64.3.4",
+		"metadata.example.com": "169.254.169.254",
+	}
+	r.fetchGuard.resolver = resolverFunc(func(_ context.Context, host string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP(addrs[host])}}, nil
+	})
+
+	ctx := WithRunID(WithMemoryContext(context.Background(), "t1", "c1"), "run-1")
+	for host := range addrs {
+		out, err := r.Execute(ctx, "webhook_call", json.RawMessage(`{"url":"https://`+host+`/hook"}`))
+		if err != nil || !strings.HasPrefix(out, "URL not allowed: ") {
+			t.Fatalf("%s: out = %q err = %v", host, out, err)
+		}
+	}
+}
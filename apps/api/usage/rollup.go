@@ -0,0 +1,287 @@
+// Package usage maintains the usage_daily rollup of usage_logs that the
+// admin dashboards read instead of scanning every log.
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// rollupLockKey is the advisory lock held while rollup rows are
+	// recomputed, so API instances do not run the job concurrently.
+	rollupLockKey int64 = 0x75736167_65646179
+	rollupName          = "usage_daily"
+	// settleDelay keeps the watermark behind logs whose transactions may not
+	// have committed yet.
+	settleDelay = 5 * time.Minute
+	day         = 24 * time.Hour
+)
+
+// rollupStats counts rollup runs, recomputed rows and failures. It is
+// published through expvar at /debug/vars.
+var rollupStats = expvar.NewMap("usage_rollup")
+
+// recomputeSQL rebuilds the usage_daily rows of every tenant and UTC day with
+// a log created in [$1, $2). Whole days are recomputed from usage_logs, so a
+// run overwrites whatever the billing fast path added with exact totals.
+const recomputeSQL = `
+	WITH touched AS (
+		SELECT DISTINCT tenant_id, (created_at AT TIME ZONE 'UTC')::date AS day
+		FROM usage_logs
+		WHERE created_at >= $1 AND created_at < $2
+	)
+	INSERT INTO usage_daily (tenant_id, model, day, requests, input_tokens, output_tokens, cost_cents, margin_cents, last_used_at)
+	SELECT l.tenant_id, l.model, t.day, COUNT(*), SUM(l.input_tokens), SUM(l.output_tokens), SUM(l.cost_cents), SUM(l.margin_cents), MAX(l.created_at)
+	FROM touched t
+	JOIN usage_logs l ON l.tenant_id = t.tenant_id
+		AND l.created_at >= t.day::timestamp AT TIME ZONE 'UTC'
+		AND l.created_at < (t.day + 1)::timestamp AT TIME ZONE 'UTC'
+	GROUP BY l.tenant_id, l.model, t.day
+	ON CONFLICT (tenant_id, model, day) DO UPDATE
+	SET requests = EXCLUDED.requests,
+		input_tokens = EXCLUDED.input_tokens,
+		output_tokens = EXCLUDED.output_tokens,
+		cost_cents = EXCLUDED.cost_cents,
+		margin_cents = EXCLUDED.margin_cents,
+		last_used_at = EXCLUDED.last_used_at
+`
+
+// Rollup keeps usage_daily in step with usage_logs. The billing path adds
+// each log to its day's row as it is written; Run reconciles the days that
+// received logs since the last watermark, and Backfill rebuilds a range.
+type Rollup struct {
+	db  *sql.DB
+	log *slog.Logger
+	now func() time.Time
+}
+
+func NewRollup(db *sql.DB) *Rollup {
+	return &Rollup{db: db, log: slog.Default().With("component", "usage-rollup"), now: time.Now}
+}
+
+// Start runs Run now and then every interval until ctx is done, so a fresh
+// deployment backfills without waiting a full interval.
+func (r *Rollup) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Run(ctx); err != nil {
+			rollupStats.Add("errors", 1)
+			r.log.Error("usage rollup failed", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run recomputes the days that received logs between the stored watermark
+// and now less settleDelay, then advances the watermark. It returns without
+// doing anything when another instance holds the rollup lock. The first run
+// has no watermark and backfills every logged day.
+func (r *Rollup) Run(ctx context.Context) error {
+	upTo := r.now().UTC().Add(-settleDelay)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin rollup: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, rollupLockKey).Scan(&locked); err != nil {
+		return fmt.Errorf("take rollup lock: %w", err)
+	}
+	if !locked {
+		return nil
+	}
+
+	var watermark time.Time
+	err = tx.QueryRowContext(ctx, `SELECT watermark FROM usage_rollup_state WHERE name = $1`, rollupName).Scan(&watermark)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		var earliest sql.NullTime
+		if err := tx.QueryRowContext(ctx, `SELECT MIN(created_at) FROM usage_logs`).Scan(&earliest); err != nil {
+			return fmt.Errorf("find first usage log: %w", err)
+		}
+		watermark = upTo
+		if earliest.Valid {
+			watermark = earliest.Time
+		}
+	case err != nil:
+		return fmt.Errorf("load rollup watermark: %w", err)
+	}
+	if !upTo.After(watermark) {
+		return nil
+	}
+
+	rows, err := recompute(ctx, tx, watermark, upTo)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO usage_rollup_state (name, watermark, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (name) DO UPDATE
+		SET watermark = EXCLUDED.watermark, updated_at = NOW()
+	`, rollupName, upTo); err != nil {
+		return fmt.Errorf("save rollup watermark: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit rollup: %w", err)
+	}
+	rollupStats.Add("runs", 1)
+	rollupStats.Add("rows", rows)
+	r.log.Info("usage rollup complete", "from", watermark, "to", upTo, "rows", rows)
+	return nil
+}
+
+// Backfill recomputes the rollup rows of every UTC day in [from, to), one day
+// per transaction so a long range does not hold the lock throughout. It
+// returns the number of rows written.
+func (r *Rollup) Backfill(ctx context.Context, from, to time.Time) (int64, error) {
+	var total int64
+	for start := truncateDay(from); start.Before(to); start = start.Add(day) {
+		n, err := r.backfillDay(ctx, start)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	r.log.Info("usage rollup backfilled", "from", truncateDay(from), "to", to, "rows", total)
+	return total, nil
+}
+
+func (r *Rollup) backfillDay(ctx context.Context, start time.Time) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin backfill: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, rollupLockKey); err != nil {
+		return 0, fmt.Errorf("take rollup lock: %w", err)
+	}
+	n, err := recompute(ctx, tx, start, start.Add(day))
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit backfill: %w", err)
+	}
+	return n, nil
+}
+
+func recompute(ctx context.Context, tx *sql.Tx, from, to time.Time) (int64, error) {
+	res, err := tx.ExecContext(ctx, recomputeSQL, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("recompute usage rollup: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
+
+// Totals are summed usage over a period.
+type Totals struct {
+	Requests     int64 `json:"requests"`
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	CostCents    int64 `json:"cost_cents"`
+	MarginCents  int64 `json:"margin_cents"`
+}
+
+// DayMismatch is a day whose rollup totals differ from its raw logs.
+type DayMismatch struct {
+	Day    string `json:"day"`
+	Rollup Totals `json:"rollup"`
+	Raw    Totals `json:"raw"`
+}
+
+// Verification compares rollup and raw totals over the UTC days in
+// [From, To).
+type Verification struct {
+	From       time.Time     `json:"from"`
+	To         time.Time     `json:"to"`
+	Days       int           `json:"days"`
+	Rollup     Totals        `json:"rollup"`
+	Raw        Totals        `json:"raw"`
+	Mismatches []DayMismatch `json:"mismatches"`
+	OK         bool          `json:"ok"`
+}
+
+// Verify sums usage_daily and usage_logs per UTC day over [from, to) and
+// reports the days where they disagree.
+func (r *Rollup) Verify(ctx context.Context, from, to time.Time) (Verification, error) {
+	from, to = truncateDay(from), truncateDay(to)
+	v := Verification{From: from, To: to, Days: int(to.Sub(from) / day), Mismatches: []DayMismatch{}}
+
+	rollup, err := r.dailyTotals(ctx, `
+		SELECT day::text, SUM(requests), SUM(input_tokens), SUM(output_tokens), SUM(cost_cents), SUM(margin_cents)
+		FROM usage_daily
+		WHERE day >= $1::date AND day < $2::date
+		GROUP BY day
+	`, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return v, fmt.Errorf("sum usage rollup: %w", err)
+	}
+	raw, err := r.dailyTotals(ctx, `
+		SELECT (created_at AT TIME ZONE 'UTC')::date::text, COUNT(*), SUM(input_tokens), SUM(output_tokens), SUM(cost_cents), SUM(margin_cents)
+		FROM usage_logs
+		WHERE created_at >= $1 AND created_at < $2
+		GROUP BY 1
+	`, from, to)
+	if err != nil {
+		return v, fmt.Errorf("sum usage logs: %w", err)
+	}
+
+	for start := from; start.Before(to); start = start.Add(day) {
+		key := start.Format(time.DateOnly)
+		got, want := rollup[key], raw[key]
+		v.Rollup = v.Rollup.add(got)
+		v.Raw = v.Raw.add(want)
+		if got != want {
+			v.Mismatches = append(v.Mismatches, DayMismatch{Day: key, Rollup: got, Raw: want})
+		}
+	}
+	v.OK = len(v.Mismatches) == 0
+	return v, nil
+}
+
+func (r *Rollup) dailyTotals(ctx context.Context, query string, args ...any) (map[string]Totals, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string]Totals)
+	for rows.Next() {
+		var key string
+		var t Totals
+		if err := rows.Scan(&key, &t.Requests, &t.InputTokens, &t.OutputTokens, &t.CostCents, &t.MarginCents); err != nil {
+			return nil, err
+		}
+		out[key] = t
+	}
+	return out, rows.Err()
+}
+
+func (t Totals) add(o Totals) Totals {
+	return Totals{
+		Requests:     t.Requests + o.Requests,
+		InputTokens:  t.InputTokens + o.InputTokens,
+		OutputTokens: t.OutputTokens + o.OutputTokens,
+		CostCents:    t.CostCents + o.CostCents,
+		MarginCents:  t.MarginCents + o.MarginCents,
+	}
+}
+
+func truncateDay(t time.Time) time.Time {
+	return t.UTC().Truncate(day)
+}
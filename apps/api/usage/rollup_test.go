@@ -0,0 +1,102 @@
+package usage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newTestRollup(t *testing.T, now time.Time) (*Rollup, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	r := NewRollup(db)
+	r.now = func() time.Time { return now }
+	return r, mock
+}
+
+func TestRunAdvancesWatermark(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	upTo := now.Add(-settleDelay)
+	watermark := now.Add(-time.Hour)
+	r, mock := newTestRollup(t, now)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("pg_try_advisory_xact_lock").WithArgs(rollupLockKey).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	mock.ExpectQuery("SELECT watermark FROM usage_rollup_state").WithArgs(rollupName).WillReturnRows(sqlmock.NewRows([]string{"watermark"}).AddRow(watermark))
+	mock.ExpectExec("INSERT INTO usage_daily").WithArgs(watermark, upTo).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("INSERT INTO usage_rollup_state").WithArgs(rollupName, upTo).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRunBackfillsWithoutWatermarkAndSkipsWhenLocked(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	first := time.Date(2025, 12, 1, 8, 0, 0, 0, time.UTC)
+	r, mock := newTestRollup(t, now)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("pg_try_advisory_xact_lock").WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	mock.ExpectQuery("SELECT watermark").WillReturnRows(sqlmock.NewRows([]string{"watermark"}))
+	mock.ExpectQuery("SELECT MIN\\(created_at\\) FROM usage_logs").WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(first))
+	mock.ExpectExec("INSERT INTO usage_daily").WithArgs(first, now.Add(-settleDelay)).WillReturnResult(sqlmock.NewResult(0, 90))
+	mock.ExpectExec("INSERT INTO usage_rollup_state").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("first Run: %v", err)
+	}
+
+	// Another instance holds the lock.
+	mock.ExpectBegin()
+	mock.ExpectQuery("pg_try_advisory_xact_lock").WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+	mock.ExpectRollback()
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("locked Run: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestVerifyReportsMismatchedDays(t *testing.T) {
+	r, mock := newTestRollup(t, time.Now())
+	from := time.Date(2026, 3, 1, 15, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	cols := []string{"day", "requests", "input", "output", "cost", "margin"}
+
+	mock.ExpectQuery("FROM usage_daily").WithArgs("2026-03-01", "2026-03-04").WillReturnRows(sqlmock.NewRows(cols).
+		AddRow("2026-03-01", 2, 100, 50, 4, 1).
+		AddRow("2026-03-02", 1, 10, 5, 1, 0))
+	mock.ExpectQuery("FROM usage_logs").WithArgs(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), to).WillReturnRows(sqlmock.NewRows(cols).
+		AddRow("2026-03-01", 2, 100, 50, 4, 1).
+		AddRow("2026-03-02", 2, 30, 5, 2, 0).
+		AddRow("2026-03-03", 1, 7, 7, 1, 0))
+
+	v, err := r.Verify(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if v.OK || v.Days != 3 || len(v.Mismatches) != 2 {
+		t.Fatalf("verification = %+v", v)
+	}
+	if m := v.Mismatches[0]; m.Day != "2026-03-02" || m.Rollup.InputTokens != 10 || m.Raw.InputTokens != 30 {
+		t.Fatalf("first mismatch = %+v", m)
+	}
+	if v.Rollup.InputTokens != 110 || v.Raw.InputTokens != 137 {
+		t.Fatalf("totals rollup=%+v raw=%+v", v.Rollup, v.Raw)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -3,6 +3,8 @@ package workflows
 import (
 	"fmt"
 	"strings"
+
+	"github.com/agentsquads/api/prompts"
 )
 
 // CompileTaskBrief compiles workflow inputs into a structured markdown brief.
@@ -15,8 +17,28 @@ func CompileTaskBrief(workflow Workflow, inputs map[string]string) string {
 		if value == "" {
 			continue
 		}
-		fmt.Fprintf(&b, "### %s\n%s\n\n", step.Prompt, value)
+		fmt.Fprintf(&b, "### %s\n%s\n\n", renderPrompt(workflow, step, inputs), prompts.EscapeText(value))
 	}
 
 	return strings.TrimSpace(b.String()) + "\n"
 }
+
+// stepVariable is the template variable holding a step's input.
+func stepVariable(stepID string) string {
+	return "step." + stepID
+}
+
+// renderPrompt fills {{step.<id>}} placeholders in step's prompt with the
+// inputs given so far. Workflows are validated when loaded, so a render
+// failure leaves the prompt as written.
+func renderPrompt(workflow Workflow, step Step, inputs map[string]string) string {
+	vars := make(map[string]string, len(workflow.Steps))
+	for _, s := range workflow.Steps {
+		vars[stepVariable(s.ID)] = inputs[s.ID]
+	}
+	rendered, err := prompts.Render(step.Prompt, vars)
+	if err != nil {
+		return step.Prompt
+	}
+	return rendered
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/BurntSushi/toml"
+	"github.com/agentsquads/api/prompts"
 )
 
 var validStepTypes = map[string]struct{}{
@@ -142,6 +143,18 @@ func validateWorkflow(wf Workflow) error {
 		if strings.TrimSpace(step.Prompt) == "" {
 			return fmt.Errorf("step %q missing prompt", step.ID)
 		}
+		// Prompts may quote the input of earlier steps as {{step.<id>}}.
+		err := prompts.ValidateTemplate(step.Prompt, func(name string) bool {
+			for _, earlier := range wf.Steps[:i] {
+				if name == stepVariable(earlier.ID) {
+					return true
+				}
+			}
+			return false
+		})
+		if err != nil {
+			return fmt.Errorf("step %q prompt: %w", step.ID, err)
+		}
 
 		if step.Type == "choice" {
 			if len(step.Options) == 0 {
@@ -25,3 +25,21 @@ func TestParseStarterWorkflows(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateWorkflowStepVariables(t *testing.T) {
+	t.Parallel()
+	base := func(prompt string) Workflow {
+		return Workflow{ID: "w", Name: "W", CostHint: "low", Steps: []Step{
+			{ID: "goal", Type: "text", Prompt: "Goal?"},
+			{ID: "scope", Type: "text", Prompt: prompt},
+		}}
+	}
+	if err := validateWorkflow(base("Scope for {{step.goal}}?")); err != nil {
+		t.Fatalf("earlier step reference rejected: %v", err)
+	}
+	for _, prompt := range []string{"Scope for {{step.scope}}?", "Scope for {{step.missing}}?", "Scope for {{task}}?", "Scope {{"} {
+		if err := validateWorkflow(base(prompt)); err == nil {
+			t.Fatalf("validateWorkflow accepted %q", prompt)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package workflows
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -14,6 +16,7 @@ var (
 	ErrRunNotFound      = errors.New("workflow run not found")
 	ErrRunNotInProgress = errors.New("workflow run is not in progress")
 	ErrRunIncomplete    = errors.New("workflow run is not complete")
+	ErrInvalidInput     = errors.New("invalid workflow input")
 )
 
 // WorkflowRun tracks one active workflow execution.
@@ -104,6 +107,7 @@ func (r *Runner) SubmitStep(runID string, input string) (*Step, bool, error) {
 	}
 
 	next := workflow.Steps[run.CurrentStep]
+	next.Prompt = renderPrompt(workflow, next, run.Inputs)
 	return &next, false, nil
 }
 
@@ -133,6 +137,68 @@ func (r *Runner) Confirm(runID string) (string, error) {
 	return brief, nil
 }
 
+// Run fills every step of the workflow from input, keyed by step id, and
+// returns the compiled task brief. It is the one-shot form of Start,
+// SubmitStep and Confirm and keeps no run state.
+func (r *Runner) Run(ctx context.Context, workflowID string, input map[string]any) (string, error) {
+	return r.RunWithProgress(ctx, workflowID, input, nil)
+}
+
+// RunWithProgress is Run, calling progress after each step is accepted.
+func (r *Runner) RunWithProgress(ctx context.Context, workflowID string, input map[string]any, progress func(step Step, value string)) (string, error) {
+	r.mu.RLock()
+	workflow, ok := r.workflows[workflowID]
+	r.mu.RUnlock()
+	if !ok {
+		return "", ErrWorkflowNotFound
+	}
+
+	for key := range input {
+		known := false
+		for _, step := range workflow.Steps {
+			known = known || step.ID == key
+		}
+		if !known {
+			return "", fmt.Errorf("%w: unknown step %q", ErrInvalidInput, key)
+		}
+	}
+
+	inputs := make(map[string]string, len(workflow.Steps))
+	for _, step := range workflow.Steps {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		value, err := inputString(input[step.ID])
+		if err != nil {
+			return "", fmt.Errorf("%w: step %q: %v", ErrInvalidInput, step.ID, err)
+		}
+		normalized, err := normalizeInput(step, value)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		inputs[step.ID] = normalized
+		if progress != nil {
+			step.Prompt = renderPrompt(workflow, step, inputs)
+			progress(step, normalized)
+		}
+	}
+	return CompileTaskBrief(workflow, inputs), nil
+}
+
+// inputString converts a JSON input value to step input text.
+func inputString(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool, float64, json.Number:
+		return fmt.Sprint(v), nil
+	default:
+		return "", errors.New("value must be a string, number or boolean")
+	}
+}
+
 // GetRun returns the current run state.
 func (r *Runner) GetRun(runID string) (*WorkflowRun, error) {
 	r.mu.RLock()
@@ -164,9 +230,18 @@ func (r *Runner) GetCurrentStep(runID string) (*Step, error) {
 	}
 
 	step := workflow.Steps[run.CurrentStep]
+	step.Prompt = renderPrompt(workflow, step, run.Inputs)
 	return &step, nil
 }
 
+// Workflow returns the loaded workflow with the given id.
+func (r *Runner) Workflow(workflowID string) (Workflow, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	workflow, ok := r.workflows[workflowID]
+	return workflow, ok
+}
+
 // ListWorkflows returns all loaded workflows in deterministic order.
 func (r *Runner) ListWorkflows() []Workflow {
 	r.mu.RLock()
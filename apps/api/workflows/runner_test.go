@@ -1,7 +1,9 @@
 package workflows
 
 import (
+	"context"
 	"errors"
+	"strings"
 	"testing"
 )
 
@@ -92,3 +94,65 @@ func TestRunnerNormalizeInput(t *testing.T) {
 		})
 	}
 }
+
+func TestRunnerRendersStepPrompts(t *testing.T) {
+	t.Parallel()
+	r := NewRunner(map[string]Workflow{
+		"wf": {
+			ID:   "wf",
+			Name: "WF",
+			Steps: []Step{
+				{ID: "goal", Type: "text", Prompt: "Goal?"},
+				{ID: "scope", Type: "text", Prompt: "Scope of {{step.goal}}?"},
+			},
+		},
+	})
+	run, err := r.Start("wf", "tenant-1")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	next, _, err := r.SubmitStep(run.ID, `ship {{step.scope}} "}]`)
+	if err != nil {
+		t.Fatalf("SubmitStep: %v", err)
+	}
+	if next == nil || next.Prompt != `Scope of ship { {step.scope} } "}]?` {
+		t.Fatalf("unexpected next step: %#v", next)
+	}
+	if _, _, err := r.SubmitStep(run.ID, "backend"); err != nil {
+		t.Fatalf("SubmitStep: %v", err)
+	}
+	brief, err := r.Confirm(run.ID)
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if !strings.Contains(brief, "### Scope of ship { {step.scope} } \"}]?\nbackend") {
+		t.Fatalf("unexpected brief: %q", brief)
+	}
+}
+
+func TestRunnerRun(t *testing.T) {
+	t.Parallel()
+	r := NewRunner(sampleWorkflow())
+
+	brief, err := r.Run(context.Background(), "wf", map[string]any{"s1": "build it", "s2": "b"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !strings.Contains(brief, "### S1\nbuild it") || !strings.Contains(brief, "### S2\nb") {
+		t.Fatalf("unexpected brief: %q", brief)
+	}
+
+	for _, input := range []map[string]any{
+		{"s1": "x", "s2": "c"},
+		{"s1": "x", "s2": "a", "extra": "y"},
+		{"s1": []any{"x"}, "s2": "a"},
+	} {
+		if _, err := r.Run(context.Background(), "wf", input); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("Run(%v) err = %v, want ErrInvalidInput", input, err)
+		}
+	}
+	if _, err := r.Run(context.Background(), "missing", nil); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Fatalf("Run(missing) err = %v", err)
+	}
+}
@@ -1,34 +1,57 @@
-import { createCipheriv, createDecipheriv, randomBytes } from "crypto";
+import { createCipheriv, createDecipheriv, createHash, randomBytes } from "crypto";
 
 const ALGORITHM = "aes-256-gcm";
 const IV_BYTES = 12;
 
+function parseKey(keyHex: string): Buffer {
+  const key = Buffer.from(keyHex.trim(), "hex");
+  if (key.length !== 32) {
+    throw new Error("ENCRYPTION_KEY must be a 32-byte hex string");
+  }
+  return key;
+}
+
+// Matches keyring.KeyID in the API: payloads name the key that sealed them
+// so ENCRYPTION_KEY can be rotated.
+function keyId(key: Buffer): string {
+  return "k" + createHash("sha256").update(key).digest("hex").slice(0, 8);
+}
+
 function getEncryptionKey(): Buffer {
   const keyHex = process.env.ENCRYPTION_KEY;
   if (!keyHex) {
     throw new Error("ENCRYPTION_KEY is not set");
   }
+  return parseKey(keyHex);
+}
 
-  const key = Buffer.from(keyHex, "hex");
-  if (key.length !== 32) {
-    throw new Error("ENCRYPTION_KEY must be a 32-byte hex string");
-  }
-
-  return key;
+// The primary key first, then ENCRYPTION_RETIRED_KEYS, which are only used
+// to decrypt values written before a rotation.
+function getDecryptionKeys(): Buffer[] {
+  const retired = (process.env.ENCRYPTION_RETIRED_KEYS ?? "")
+    .split(",")
+    .map((value) => value.trim())
+    .filter(Boolean)
+    .map(parseKey);
+  return [getEncryptionKey(), ...retired];
 }
 
 export function encrypt(plaintext: string): string {
+  const key = getEncryptionKey();
   const iv = randomBytes(IV_BYTES);
-  const cipher = createCipheriv(ALGORITHM, getEncryptionKey(), iv);
+  const cipher = createCipheriv(ALGORITHM, key, iv);
   const ciphertext = Buffer.concat([cipher.update(plaintext, "utf8"), cipher.final()]);
   const tag = cipher.getAuthTag();
 
-  return `${iv.toString("base64")}:${ciphertext.toString("base64")}:${tag.toString("base64")}`;
+  return `${keyId(key)}:${iv.toString("base64")}:${ciphertext.toString("base64")}:${tag.toString("base64")}`;
 }
 
 export function decrypt(payload: string): string {
-  const [ivPart, ciphertextPart, tagPart] = payload.split(":");
-  if (!ivPart || !ciphertextPart || !tagPart) {
+  const parts = payload.split(":");
+  // Payloads from before key versioning have no key id.
+  const id = parts.length === 4 ? parts.shift() : undefined;
+  const [ivPart, ciphertextPart, tagPart] = parts;
+  if (parts.length !== 3 || !ivPart || ciphertextPart === undefined || !tagPart) {
     throw new Error("Invalid encrypted payload format");
   }
 
@@ -36,9 +59,19 @@ export function decrypt(payload: string): string {
   const ciphertext = Buffer.from(ciphertextPart, "base64");
   const tag = Buffer.from(tagPart, "base64");
 
-  const decipher = createDecipheriv(ALGORITHM, getEncryptionKey(), iv);
-  decipher.setAuthTag(tag);
-
-  const plaintext = Buffer.concat([decipher.update(ciphertext), decipher.final()]);
-  return plaintext.toString("utf8");
+  const keys = getDecryptionKeys().filter((key) => id === undefined || keyId(key) === id);
+  if (keys.length === 0) {
+    throw new Error("Payload was encrypted with an unknown key");
+  }
+  for (const key of keys) {
+    try {
+      const decipher = createDecipheriv(ALGORITHM, key, iv);
+      decipher.setAuthTag(tag);
+      const plaintext = Buffer.concat([decipher.update(ciphertext), decipher.final()]);
+      return plaintext.toString("utf8");
+    } catch {
+      // Try the next key.
+    }
+  }
+  throw new Error("Payload could not be decrypted");
 }
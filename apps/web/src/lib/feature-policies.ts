@@ -18,6 +18,7 @@ function defaultFeatureMap(): Record<Feature, boolean> {
     deploy: true,
     telegram: true,
     whatsapp: true,
+    viber: true,
     webchat: true,
     catalog: true,
   };
@@ -4,6 +4,7 @@ export const FEATURES = [
   "deploy",
   "telegram",
   "whatsapp",
+  "viber",
   "webchat",
   "catalog",
 ] as const;
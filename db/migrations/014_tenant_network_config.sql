@@ -0,0 +1,3 @@
+ALTER TABLE tenants
+  ADD COLUMN IF NOT EXISTS network_config JSONB,
+  ADD COLUMN IF NOT EXISTS network_config_updated_at TIMESTAMPTZ;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS plans (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name TEXT UNIQUE NOT NULL,
+  max_swarm_agents INTEGER NOT NULL DEFAULT 0,
+  container_memory_mb INTEGER NOT NULL DEFAULT 0,
+  container_cpu NUMERIC(6,2) NOT NULL DEFAULT 0,
+  rpm_limit INTEGER NOT NULL DEFAULT 0,
+  monthly_token_cap BIGINT NOT NULL DEFAULT 0,
+  features JSONB NOT NULL DEFAULT '{}'::jsonb,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE tenants
+  ADD COLUMN IF NOT EXISTS plan_id UUID REFERENCES plans(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tenants_plan_id ON tenants(plan_id);
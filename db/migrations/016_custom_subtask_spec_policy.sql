@@ -0,0 +1,3 @@
+-- Opt-in flag for user-defined swarm subtask graphs. Existing tenants are not
+-- seeded with it, so it stays disabled until an admin enables it.
+ALTER TYPE feature_policy ADD VALUE IF NOT EXISTS 'custom_subtask_spec';
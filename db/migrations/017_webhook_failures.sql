@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS webhook_failures (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  channel TEXT NOT NULL,
+  tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+  body BYTEA NOT NULL,
+  body_truncated BOOLEAN NOT NULL DEFAULT FALSE,
+  headers JSONB NOT NULL DEFAULT '{}'::jsonb,
+  error TEXT NOT NULL,
+  replay_count INTEGER NOT NULL DEFAULT 0,
+  last_replayed_at TIMESTAMPTZ,
+  last_replay_error TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_failures_created_at
+  ON webhook_failures(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_failures_channel_tenant
+  ON webhook_failures(channel, tenant_id, created_at DESC);
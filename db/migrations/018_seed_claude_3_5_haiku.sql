@@ -0,0 +1,5 @@
+-- claude-haiku-3.5 does not resolve to a valid Anthropic model id; seed the
+-- upstream claude-3-5-haiku alias alongside it.
+INSERT INTO models (id, name, provider, provider_cost_input_per_m, provider_cost_output_per_m, markup_pct, enabled) VALUES
+  ('anthropic/claude-3-5-haiku-latest', 'anthropic/claude-3-5-haiku-latest', 'anthropic', 80, 400, 35, true)
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,3 @@
+-- Opt-in flag that raises the OpenFang request timeout for a tenant. Existing
+-- tenants are not seeded with it, so it stays disabled until an admin enables it.
+ALTER TYPE feature_policy ADD VALUE IF NOT EXISTS 'extended_timeout';
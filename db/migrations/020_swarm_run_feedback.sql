@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS swarm_run_feedback (
+  run_id TEXT NOT NULL,
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+  comment TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (tenant_id, run_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_swarm_run_feedback_created_at
+  ON swarm_run_feedback(created_at DESC);
@@ -0,0 +1,13 @@
+-- Audit trail of commands run in tenant containers through the orchestrator.
+-- Output is kept only up to 4 KB; the full output is returned to the caller.
+CREATE TABLE IF NOT EXISTS exec_history (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  command_json JSONB NOT NULL,
+  output_truncated TEXT NOT NULL DEFAULT '',
+  exit_code INTEGER,
+  executed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_exec_history_tenant_executed_at
+  ON exec_history(tenant_id, executed_at DESC);
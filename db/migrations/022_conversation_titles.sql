@@ -0,0 +1,11 @@
+-- Generated titles and rolling summaries for conversations. titled_message_count
+-- records how many messages the title was generated from so it can be
+-- regenerated as the conversation drifts; summarized_through is the created_at
+-- of the newest message folded into the summary.
+ALTER TABLE conversations
+  ADD COLUMN IF NOT EXISTS title TEXT,
+  ADD COLUMN IF NOT EXISTS titled_message_count INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS title_updated_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS summary TEXT,
+  ADD COLUMN IF NOT EXISTS summarized_through TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS summary_updated_at TIMESTAMPTZ;
@@ -0,0 +1,38 @@
+-- Re-encryption runs that move stored credentials onto the primary
+-- ENCRYPTION_KEY. Progress is kept per table so an interrupted run resumes
+-- after the last processed row; rows that cannot be decrypted are recorded in
+-- crypto_rotation_failures instead of aborting the run.
+CREATE TABLE IF NOT EXISTS crypto_rotations (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  target_key_id TEXT NOT NULL,
+  status TEXT NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'completed', 'failed')),
+  error TEXT,
+  started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  finished_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_crypto_rotations_running
+  ON crypto_rotations ((status)) WHERE status = 'running';
+
+CREATE TABLE IF NOT EXISTS crypto_rotation_progress (
+  rotation_id UUID NOT NULL REFERENCES crypto_rotations(id) ON DELETE CASCADE,
+  table_name TEXT NOT NULL,
+  last_id UUID,
+  scanned INTEGER NOT NULL DEFAULT 0,
+  rotated INTEGER NOT NULL DEFAULT 0,
+  failed INTEGER NOT NULL DEFAULT 0,
+  done BOOLEAN NOT NULL DEFAULT FALSE,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (rotation_id, table_name)
+);
+
+CREATE TABLE IF NOT EXISTS crypto_rotation_failures (
+  rotation_id UUID NOT NULL REFERENCES crypto_rotations(id) ON DELETE CASCADE,
+  table_name TEXT NOT NULL,
+  row_id UUID NOT NULL,
+  column_name TEXT NOT NULL,
+  error TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (rotation_id, table_name, row_id, column_name)
+);
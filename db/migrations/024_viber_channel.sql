@@ -0,0 +1,21 @@
+-- Viber channel: allow it wherever channels are constrained and give it a
+-- feature policy. The new enum value cannot be used in the transaction that
+-- adds it, so tenant rows are seeded by the web app's ensureTenantPolicyRows
+-- like the other channel features.
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_channel_check;
+ALTER TABLE messages
+  ADD CONSTRAINT messages_channel_check CHECK (channel IN ('web', 'telegram', 'whatsapp', 'viber'));
+
+ALTER TABLE tenant_channels DROP CONSTRAINT IF EXISTS tenant_channels_channel_check;
+ALTER TABLE tenant_channels
+  ADD CONSTRAINT tenant_channels_channel_check CHECK (channel IN ('web', 'telegram', 'whatsapp', 'viber'));
+
+ALTER TABLE channel_credentials DROP CONSTRAINT IF EXISTS channel_credentials_channel_check;
+ALTER TABLE channel_credentials
+  ADD CONSTRAINT channel_credentials_channel_check CHECK (channel IN ('telegram', 'whatsapp', 'viber'));
+
+CREATE INDEX IF NOT EXISTS idx_channel_credentials_viber_webhook
+  ON channel_credentials ((config->>'webhook_id'))
+  WHERE channel = 'viber';
+
+ALTER TYPE feature_policy ADD VALUE IF NOT EXISTS 'viber';
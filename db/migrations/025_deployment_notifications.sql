@@ -0,0 +1,6 @@
+-- Deployment status webhooks: an optional callback URL and signing secret per
+-- run, and a log of every delivery attempt for the status endpoint.
+ALTER TABLE deployment_runs
+  ADD COLUMN IF NOT EXISTS notify_url TEXT,
+  ADD COLUMN IF NOT EXISTS notify_secret TEXT,
+  ADD COLUMN IF NOT EXISTS notifications JSONB NOT NULL DEFAULT '[]'::jsonb;
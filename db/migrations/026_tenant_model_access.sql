@@ -0,0 +1,11 @@
+-- Per-tenant model access. A tenant with no rows may use every enabled model.
+-- Once any row has allowed = true, only those models are permitted; rows with
+-- allowed = false always block the model.
+CREATE TABLE tenant_model_access (
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  model_id TEXT NOT NULL REFERENCES models(id) ON DELETE CASCADE,
+  allowed BOOLEAN NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (tenant_id, model_id)
+);
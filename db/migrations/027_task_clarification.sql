@@ -0,0 +1,11 @@
+-- Opt-in follow-up questions for vague channel tasks. Existing tenants are not
+-- seeded with the policy, so it stays disabled until an admin enables it.
+ALTER TYPE feature_policy ADD VALUE IF NOT EXISTS 'task_clarification';
+
+-- Optional per-tenant rubric the task is checked against; tenants without a
+-- row use SWARM_CLARIFICATION_RUBRIC.
+CREATE TABLE tenant_clarification_rubrics (
+  tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+  rubric TEXT NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,2 @@
+ALTER TABLE usage_logs
+  ADD COLUMN IF NOT EXISTS finish_reason TEXT;
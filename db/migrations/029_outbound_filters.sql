@@ -0,0 +1,14 @@
+-- Opt-in filtering of channel replies before delivery. outbound_filter applies
+-- the tenant's redaction patterns and length cap; outbound_moderation adds an
+-- LLM moderation pass. Existing tenants are not seeded with either policy.
+ALTER TYPE feature_policy ADD VALUE IF NOT EXISTS 'outbound_filter';
+ALTER TYPE feature_policy ADD VALUE IF NOT EXISTS 'outbound_moderation';
+
+-- Per-tenant filter settings. redact_patterns are Go regular expressions;
+-- max_length of 0 leaves replies untruncated.
+CREATE TABLE tenant_outbound_filters (
+  tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+  redact_patterns TEXT[] NOT NULL DEFAULT '{}',
+  max_length INTEGER NOT NULL DEFAULT 0 CHECK (max_length >= 0),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,22 @@
+-- Admin benchmark runs: one row per model per run, grouped by batch_id, so
+-- latency and cost can be charted over time.
+CREATE TABLE model_benchmarks (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  batch_id UUID NOT NULL,
+  model TEXT NOT NULL,
+  provider TEXT NOT NULL,
+  prompt_chars INTEGER NOT NULL,
+  max_tokens INTEGER NOT NULL,
+  ttfb_ms INTEGER,
+  latency_ms INTEGER NOT NULL,
+  input_tokens INTEGER NOT NULL DEFAULT 0,
+  output_tokens INTEGER NOT NULL DEFAULT 0,
+  provider_cost_cents NUMERIC(12, 4) NOT NULL DEFAULT 0,
+  price_cents INTEGER NOT NULL DEFAULT 0,
+  attempts INTEGER NOT NULL DEFAULT 1,
+  error TEXT,
+  created_by TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_model_benchmarks_model_created ON model_benchmarks(model, created_at);
+CREATE INDEX idx_model_benchmarks_created ON model_benchmarks(created_at);
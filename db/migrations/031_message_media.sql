@@ -0,0 +1,19 @@
+-- Files users send over channels (Telegram/WhatsApp photos and documents).
+-- The bytes live in the media blob store under blob_key; rows are created
+-- when the file is downloaded and linked to their message once it is stored.
+CREATE TABLE message_media (
+  id UUID PRIMARY KEY,
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  conversation_id UUID REFERENCES conversations(id) ON DELETE CASCADE,
+  message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+  channel TEXT NOT NULL,
+  kind TEXT NOT NULL,
+  provider_file_id TEXT NOT NULL,
+  file_name TEXT,
+  content_type TEXT NOT NULL,
+  size_bytes BIGINT NOT NULL CHECK (size_bytes >= 0),
+  blob_key TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_message_media_message ON message_media(message_id);
+CREATE INDEX idx_message_media_tenant ON message_media(tenant_id, created_at);
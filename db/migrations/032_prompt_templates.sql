@@ -0,0 +1,19 @@
+-- Admin-managed prompt templates. Every edit is a new version; at most one
+-- version per (name, tenant layer) is active. Rows with a NULL tenant_id are
+-- the platform default and a tenant's active row overrides it.
+CREATE TABLE prompt_templates (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name TEXT NOT NULL,
+  tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+  version INTEGER NOT NULL CHECK (version > 0),
+  content TEXT NOT NULL,
+  active BOOLEAN NOT NULL DEFAULT FALSE,
+  created_by TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  activated_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX idx_prompt_templates_version
+  ON prompt_templates(name, COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000'::uuid), version);
+CREATE UNIQUE INDEX idx_prompt_templates_active
+  ON prompt_templates(name, COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000'::uuid))
+  WHERE active;
@@ -0,0 +1,14 @@
+-- ERROR-level API log records, for the admin platform errors view. Repeats of
+-- the same component and message within an hour are stored once; the API
+-- keeps only the newest 10,000 rows.
+CREATE TABLE platform_errors (
+  id BIGSERIAL PRIMARY KEY,
+  component TEXT NOT NULL,
+  message TEXT NOT NULL,
+  details_json JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX idx_platform_errors_dedup
+  ON platform_errors(component, message, DATE_TRUNC('hour', created_at AT TIME ZONE 'UTC'));
+CREATE INDEX idx_platform_errors_created ON platform_errors(created_at);
+CREATE INDEX idx_platform_errors_component_created ON platform_errors(component, created_at);
@@ -0,0 +1,4 @@
+-- DNS alias of the tenant container on the tenant network. The API reaches
+-- containers by alias; rows without one belong to containers created before
+-- aliases and are resolved by inspecting the container until recreated.
+ALTER TABLE tenants ADD COLUMN container_alias TEXT;
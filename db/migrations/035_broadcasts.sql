@@ -0,0 +1,28 @@
+-- Tenant announcements pushed to every chat that has messaged the tenant's
+-- channels. Recipients are resolved when the broadcast is created; each gets a
+-- delivery row so failures can be retried without re-sending to everyone.
+CREATE TABLE broadcasts (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  content TEXT NOT NULL,
+  channel TEXT,
+  scheduled_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_broadcasts_tenant_created ON broadcasts(tenant_id, created_at DESC);
+
+-- claimed_at leases a pending delivery to one API replica; a lease older than
+-- five minutes is considered abandoned and the delivery is claimed again.
+CREATE TABLE broadcast_deliveries (
+  id BIGSERIAL PRIMARY KEY,
+  broadcast_id UUID NOT NULL REFERENCES broadcasts(id) ON DELETE CASCADE,
+  channel TEXT NOT NULL,
+  recipient TEXT NOT NULL,
+  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT,
+  claimed_at TIMESTAMPTZ,
+  sent_at TIMESTAMPTZ,
+  UNIQUE (broadcast_id, channel, recipient)
+);
+CREATE INDEX idx_broadcast_deliveries_pending ON broadcast_deliveries(broadcast_id) WHERE status = 'pending';
@@ -0,0 +1,9 @@
+-- Admin-set swarm overrides per tenant. NULL columns fall back to the plan
+-- limit or the MAX_SWARM_AGENTS and SWARM_AGENT_TIMEOUT defaults.
+CREATE TABLE tenant_swarm_configs (
+  tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+  max_agents INTEGER CHECK (max_agents BETWEEN 1 AND 20),
+  default_timeout_seconds INTEGER CHECK (default_timeout_seconds BETWEEN 30 AND 3600),
+  decomposition_prompt TEXT,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,18 @@
+-- Opt-in db_query agent tool: read-only SELECTs against the tenant's deployed
+-- Supabase project. Every call is recorded with the tool loop run it came
+-- from, including rejected and failed ones.
+ALTER TYPE feature_policy ADD VALUE IF NOT EXISTS 'db_query';
+
+CREATE TABLE agent_db_queries (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  run_id TEXT NOT NULL,
+  project_ref TEXT NOT NULL,
+  query TEXT NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('ok', 'rejected', 'error')),
+  row_count INTEGER NOT NULL DEFAULT 0,
+  error TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_agent_db_queries_tenant_created ON agent_db_queries(tenant_id, created_at DESC);
+CREATE INDEX idx_agent_db_queries_run ON agent_db_queries(run_id);
@@ -0,0 +1,6 @@
+-- Swarm run results are stored as assistant messages on the conversation the
+-- run came from. The key is derived from the run, event and content so a
+-- republished result is not stored twice.
+CREATE UNIQUE INDEX idx_messages_swarm_message_key
+  ON messages (conversation_id, (metadata->>'swarm_message_key'))
+  WHERE metadata ? 'swarm_message_key';
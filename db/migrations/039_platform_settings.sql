@@ -0,0 +1,8 @@
+-- Platform-wide settings the API reloads without a restart. The 'swarm' row
+-- overrides the MAX_SWARM_AGENTS / SWARM_* environment defaults, e.g.
+--   {"max_agents": 5, "default_timeout_seconds": 900}
+CREATE TABLE platform_settings (
+  key TEXT PRIMARY KEY,
+  value JSONB NOT NULL DEFAULT '{}',
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,20 @@
+-- Track when a tenant row last changed, e.g. when it was suspended, so
+-- abandoned containers of long-suspended tenants can be found. Existing rows
+-- start from now rather than guessing.
+ALTER TABLE tenants ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE OR REPLACE FUNCTION set_tenant_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+  NEW.updated_at = NOW();
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_tenant_updated_at ON tenants;
+CREATE TRIGGER trg_tenant_updated_at
+BEFORE UPDATE ON tenants
+FOR EACH ROW
+EXECUTE FUNCTION set_tenant_updated_at();
+
+CREATE INDEX idx_tenants_suspended_updated ON tenants(updated_at) WHERE status = 'suspended';
@@ -0,0 +1,34 @@
+-- Channel connect/disconnect history for the tenant activity feed. Every
+-- path that links or unlinks a channel goes through tenant_channels, so a
+-- trigger records the events rather than each caller.
+CREATE TABLE channel_events (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  channel TEXT NOT NULL,
+  action TEXT NOT NULL CHECK (action IN ('connected', 'disconnected')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_channel_events_tenant_created ON channel_events(tenant_id, created_at DESC);
+
+CREATE OR REPLACE FUNCTION record_channel_event()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    INSERT INTO channel_events (tenant_id, channel, action) VALUES (OLD.tenant_id, OLD.channel, 'disconnected');
+    RETURN OLD;
+  END IF;
+  IF TG_OP = 'INSERT' OR NEW.linked_at IS DISTINCT FROM OLD.linked_at THEN
+    INSERT INTO channel_events (tenant_id, channel, action) VALUES (NEW.tenant_id, NEW.channel, 'connected');
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_tenant_channels_events ON tenant_channels;
+CREATE TRIGGER trg_tenant_channels_events
+AFTER INSERT OR UPDATE OR DELETE ON tenant_channels
+FOR EACH ROW
+EXECUTE FUNCTION record_channel_event();
+
+CREATE INDEX IF NOT EXISTS idx_messages_swarm_source ON messages(conversation_id, created_at)
+  WHERE metadata->>'source' = 'swarm';
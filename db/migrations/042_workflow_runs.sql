@@ -0,0 +1,15 @@
+-- Self-service workflow runs. Runs triggered through the tenant API are
+-- recorded in workflow_runs alongside the stepwise ones, with their outcome
+-- and compiled brief, for history and billing.
+ALTER TYPE feature_policy ADD VALUE IF NOT EXISTS 'workflows_enabled';
+
+ALTER TABLE workflow_runs
+  ADD COLUMN brief TEXT,
+  ADD COLUMN error TEXT,
+  ADD COLUMN finished_at TIMESTAMPTZ;
+
+ALTER TABLE workflow_runs DROP CONSTRAINT IF EXISTS workflow_runs_status_check;
+ALTER TABLE workflow_runs ADD CONSTRAINT workflow_runs_status_check
+  CHECK (status IN ('in_progress', 'confirmed', 'cancelled', 'running', 'completed', 'failed'));
+
+CREATE INDEX IF NOT EXISTS idx_workflow_runs_tenant_created ON workflow_runs(tenant_id, created_at DESC);
@@ -0,0 +1,2 @@
+-- Per-plan container disk allowance in MB. 0 means the platform default.
+ALTER TABLE plans ADD COLUMN IF NOT EXISTS container_disk_mb INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+-- Port the tenant's OpenFang listens on inside its container, so the port
+-- list can be answered while the container is stopped.
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS container_port INTEGER;
+UPDATE tenants SET container_port = 4200 WHERE container_id IS NOT NULL AND container_port IS NULL;
@@ -0,0 +1,5 @@
+-- Which swarm run updates a linked channel receives. SSE subscribers and the
+-- run timeline always get every update.
+ALTER TABLE tenant_channels
+  ADD COLUMN IF NOT EXISTS notification_level TEXT NOT NULL DEFAULT 'milestones'
+  CHECK (notification_level IN ('all', 'milestones', 'final_only', 'errors_only'));
@@ -0,0 +1,22 @@
+-- Prompt cache pricing in cents per million tokens. Zero bills cached tokens
+-- at the model's input rate.
+ALTER TABLE models
+  ADD COLUMN IF NOT EXISTS provider_cost_cache_read_per_m INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS provider_cost_cache_write_per_m INTEGER NOT NULL DEFAULT 0;
+
+-- Anthropic bills cache reads at 10% and cache writes at 125% of the input
+-- rate; OpenAI bills cached reads at 50% and charges nothing extra to write.
+UPDATE models
+SET provider_cost_cache_read_per_m = provider_cost_input_per_m / 10,
+    provider_cost_cache_write_per_m = provider_cost_input_per_m * 5 / 4
+WHERE provider = 'anthropic' AND provider_cost_cache_read_per_m = 0;
+
+UPDATE models
+SET provider_cost_cache_read_per_m = provider_cost_input_per_m / 2
+WHERE provider = 'openai' AND provider_cost_cache_read_per_m = 0;
+
+-- input_tokens keeps counting every prompt token; these record how many of
+-- them were written to or read from the prompt cache.
+ALTER TABLE usage_logs
+  ADD COLUMN IF NOT EXISTS cache_creation_tokens INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS cache_read_tokens INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,18 @@
+-- GDPR erasure: a deleted tenant keeps its row as a tombstone with the user
+-- link and container details cleared, and each erasure is recorded with the
+-- rows removed from every table.
+ALTER TABLE tenants ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE tenants DROP CONSTRAINT IF EXISTS tenants_status_check;
+ALTER TABLE tenants ADD CONSTRAINT tenants_status_check
+  CHECK (status IN ('active', 'paused', 'suspended', 'deleted'));
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS gdpr_erasure_log (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id UUID NOT NULL,
+  admin_id TEXT NOT NULL,
+  rows_deleted JSONB NOT NULL DEFAULT '{}'::jsonb,
+  erased_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_gdpr_erasure_log_tenant ON gdpr_erasure_log(tenant_id);
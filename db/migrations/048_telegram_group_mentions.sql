@@ -0,0 +1,5 @@
+-- Telegram group chats only route messages addressed to the bot (a mention,
+-- a reply to the bot or /agent); other messages are stored as context. The
+-- policy restores the old behaviour of answering every group message and is
+-- off unless set.
+ALTER TYPE feature_policy ADD VALUE IF NOT EXISTS 'telegram_group_always_respond';
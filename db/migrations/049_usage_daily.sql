@@ -0,0 +1,23 @@
+-- Daily usage rollups for the admin dashboards. Each usage log is added to
+-- its tenant, model and UTC day row as it is written; the rollup job
+-- recomputes days that received logs since its watermark so the rows match
+-- usage_logs exactly, and the first run backfills history.
+CREATE TABLE IF NOT EXISTS usage_daily (
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  model TEXT NOT NULL,
+  day DATE NOT NULL,
+  requests BIGINT NOT NULL DEFAULT 0,
+  input_tokens BIGINT NOT NULL DEFAULT 0,
+  output_tokens BIGINT NOT NULL DEFAULT 0,
+  cost_cents BIGINT NOT NULL DEFAULT 0,
+  margin_cents BIGINT NOT NULL DEFAULT 0,
+  last_used_at TIMESTAMPTZ,
+  PRIMARY KEY (tenant_id, model, day)
+);
+CREATE INDEX IF NOT EXISTS idx_usage_daily_day ON usage_daily(day);
+
+CREATE TABLE IF NOT EXISTS usage_rollup_state (
+  name TEXT PRIMARY KEY,
+  watermark TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
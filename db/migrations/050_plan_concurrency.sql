@@ -0,0 +1,3 @@
+-- Per-plan cap on a tenant's in-flight LLM proxy requests. 0 means the
+-- proxy default (LLM_PROXY_TENANT_CONCURRENCY).
+ALTER TABLE plans ADD COLUMN IF NOT EXISTS max_concurrent_requests INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- Environment a deployment run went to. Preview and staging runs deploy to
+-- their own provider projects.
+ALTER TABLE deployment_runs
+  ADD COLUMN IF NOT EXISTS target TEXT NOT NULL DEFAULT 'production'
+  CHECK (target IN ('production', 'preview', 'staging'));
@@ -0,0 +1,19 @@
+-- Zip archives of a tenant's conversations, built in the background and
+-- downloaded through a signed URL until expires_at.
+CREATE TABLE IF NOT EXISTS conversation_exports (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  format TEXT NOT NULL CHECK (format IN ('json', 'markdown')),
+  range_from TIMESTAMPTZ NOT NULL,
+  range_to TIMESTAMPTZ NOT NULL,
+  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+  blob_key TEXT,
+  conversations INTEGER NOT NULL DEFAULT 0,
+  error_message TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  completed_at TIMESTAMPTZ,
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_exports_tenant_created
+  ON conversation_exports (tenant_id, created_at DESC);
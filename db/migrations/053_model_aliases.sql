@@ -0,0 +1,33 @@
+-- Retired model ids and the model that replaces them. From warn_from the old
+-- id still works but responses carry a deprecation header; from effective_at
+-- requests are served by the replacement.
+CREATE TABLE IF NOT EXISTS model_aliases (
+  alias_id TEXT PRIMARY KEY,
+  replacement_id TEXT NOT NULL REFERENCES models(id),
+  warn_from TIMESTAMPTZ NOT NULL,
+  effective_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CHECK (alias_id <> replacement_id),
+  CHECK (warn_from <= effective_at)
+);
+
+-- The deprecated id a call asked for, when it went through an alias.
+ALTER TABLE usage_logs ADD COLUMN IF NOT EXISTS deprecated_model TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_usage_logs_deprecated_model
+  ON usage_logs (created_at)
+  WHERE deprecated_model IS NOT NULL;
+
+-- When a tenant was last told it still uses a deprecated id, so the weekly
+-- notice goes out once per tenant and alias even with several API instances.
+CREATE TABLE IF NOT EXISTS model_deprecation_notices (
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  alias_id TEXT NOT NULL,
+  notified_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (tenant_id, alias_id)
+);
+
+-- Tenants with this policy get an error for a retired model id instead of
+-- being served by its replacement.
+ALTER TYPE feature_policy ADD VALUE IF NOT EXISTS 'strict_model_deprecation';
@@ -0,0 +1,3 @@
+-- Labels an operator added to the tenant container through
+-- PATCH /api/admin/tenants/{id}/container, beyond the ones the platform sets.
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS container_labels JSONB NOT NULL DEFAULT '{}';
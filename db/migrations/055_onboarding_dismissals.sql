@@ -0,0 +1,8 @@
+-- Onboarding checklist items a tenant chose to skip; see
+-- POST /api/tenants/{id}/onboarding/dismiss.
+CREATE TABLE IF NOT EXISTS tenant_onboarding_dismissals (
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  item TEXT NOT NULL,
+  dismissed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (tenant_id, item)
+);
@@ -0,0 +1,15 @@
+-- PDF invoices for a tenant's usage in a calendar month, generated on the
+-- first request for a month that has ended and downloaded from the blob
+-- store afterwards; see GET /api/tenants/{id}/billing/invoice.
+CREATE TABLE IF NOT EXISTS invoices (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  period DATE NOT NULL,
+  number TEXT NOT NULL,
+  blob_key TEXT NOT NULL,
+  subtotal_cents BIGINT NOT NULL DEFAULT 0,
+  fee_cents BIGINT NOT NULL DEFAULT 0,
+  total_cents BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (tenant_id, period)
+);
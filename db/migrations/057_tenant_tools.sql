@@ -0,0 +1,33 @@
+-- Tenant-defined agent tools: HTTPS webhooks the tool loop calls with the
+-- model's arguments. The auth secret and the signing secret are encrypted
+-- with the keyring. Every call is recorded with the tool loop run it came
+-- from, including rejected and failed ones.
+CREATE TABLE IF NOT EXISTS tenant_tools (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  parameters JSONB NOT NULL DEFAULT '{"type":"object","properties":{}}',
+  endpoint TEXT NOT NULL,
+  auth_header TEXT,
+  auth_template TEXT,
+  auth_secret_encrypted TEXT,
+  signing_secret_encrypted TEXT NOT NULL,
+  timeout_seconds INTEGER NOT NULL DEFAULT 10,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (tenant_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS tenant_tool_calls (
+  id BIGSERIAL PRIMARY KEY,
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  run_id TEXT NOT NULL,
+  tool TEXT NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('ok', 'rejected', 'error')),
+  http_status INTEGER,
+  duration_ms INTEGER NOT NULL DEFAULT 0,
+  error TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_tenant_tool_calls_tenant_created ON tenant_tool_calls(tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_tenant_tool_calls_run ON tenant_tool_calls(run_id);
@@ -0,0 +1,16 @@
+-- Environment variables tenants set for their deployed Vercel projects. This
+-- table is the source of truth: each Vercel deploy syncs the full set to the
+-- project. Values are encrypted with the keyring. Deleting a variable that was
+-- already synced leaves a tombstone (deleted = true, no value) until the next
+-- deploy removes it from Vercel.
+CREATE TABLE IF NOT EXISTS deploy_env_vars (
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  key TEXT NOT NULL,
+  value_encrypted TEXT,
+  secret BOOLEAN NOT NULL DEFAULT false,
+  deleted BOOLEAN NOT NULL DEFAULT false,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  synced_at TIMESTAMPTZ,
+  synced_project TEXT,
+  PRIMARY KEY (tenant_id, key)
+);
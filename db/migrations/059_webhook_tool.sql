@@ -0,0 +1,7 @@
+-- Opt-in webhook_call agent tool: agents call external HTTP APIs on hosts the
+-- tenant has allowed. Policies may now carry settings; the webhook_tool
+-- policy keeps its allow-list there as {"webhook_allowed_domains": [...]}.
+ALTER TYPE feature_policy ADD VALUE IF NOT EXISTS 'webhook_tool';
+
+ALTER TABLE tenant_policies
+  ADD COLUMN IF NOT EXISTS settings JSONB NOT NULL DEFAULT '{}'::jsonb;
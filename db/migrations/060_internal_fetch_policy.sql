@@ -0,0 +1,4 @@
+-- Tool HTTP requests no longer reach loopback, private or link-local
+-- addresses. The internal_fetch policy lets a tenant reach the hosts and
+-- ranges in its settings: {"internal_fetch_allowed": ["10.0.4.0/24", ...]}.
+ALTER TYPE feature_policy ADD VALUE IF NOT EXISTS 'internal_fetch';
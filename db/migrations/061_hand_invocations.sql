@@ -0,0 +1,13 @@
+-- One row per subtask a hand ran to completion, failure or timeout, written
+-- by the coordinator. The hand performance endpoint computes latency
+-- percentiles and success rates over it.
+CREATE TABLE IF NOT EXISTS hand_invocations (
+  id BIGSERIAL PRIMARY KEY,
+  hand_id TEXT NOT NULL,
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  latency_ms INTEGER NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('complete', 'failed', 'timeout')),
+  invoked_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_hand_invocations_tenant_hand_invoked
+  ON hand_invocations(tenant_id, hand_id, invoked_at DESC);
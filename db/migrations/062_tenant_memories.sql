@@ -0,0 +1,17 @@
+-- Persistent agent memories saved with the memory_persist tool. A
+-- conversation memory is recalled only in its conversation; a tenant memory
+-- (empty conversation_id) in all of the tenant's conversations.
+CREATE TABLE IF NOT EXISTS tenant_memories (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  scope TEXT NOT NULL CHECK (scope IN ('conversation', 'tenant')),
+  conversation_id TEXT NOT NULL DEFAULT '',
+  key TEXT NOT NULL,
+  content TEXT NOT NULL,
+  category TEXT NOT NULL DEFAULT 'note',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (tenant_id, conversation_id, key),
+  CHECK ((scope = 'tenant') = (conversation_id = ''))
+);
+CREATE INDEX IF NOT EXISTS idx_tenant_memories_tenant_updated ON tenant_memories(tenant_id, updated_at DESC);
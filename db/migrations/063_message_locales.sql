@@ -0,0 +1,13 @@
+-- Locale of the system messages sent to a tenant's channels. NULL follows
+-- the channel user's language where the channel reports it, else English.
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS locale TEXT;
+
+-- Operator overrides of the embedded system message catalogs, per message id
+-- and locale.
+CREATE TABLE IF NOT EXISTS message_overrides (
+  message_id TEXT NOT NULL,
+  locale TEXT NOT NULL,
+  text TEXT NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (message_id, locale)
+);
@@ -0,0 +1,27 @@
+-- Opt-in moderation of inbound channel messages. Tenants with the
+-- moderation_enabled policy have each message checked against their rules:
+-- "block" drops the message, "flag" routes it with flagged=true in its
+-- metadata. Existing tenants are not seeded with the policy.
+ALTER TYPE feature_policy ADD VALUE IF NOT EXISTS 'moderation_enabled';
+
+-- pattern is a Go regular expression matched case-insensitively.
+CREATE TABLE IF NOT EXISTS tenant_moderation_rules (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  pattern TEXT NOT NULL CHECK (pattern <> ''),
+  action TEXT NOT NULL CHECK (action IN ('block', 'flag')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_tenant_moderation_rules_tenant ON tenant_moderation_rules(tenant_id, created_at);
+
+-- One row per blocked or flagged message. content_hash is the hex SHA-256 of
+-- the message content; the text itself is not stored.
+CREATE TABLE IF NOT EXISTS moderation_events (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  content_hash TEXT NOT NULL,
+  pattern_matched TEXT NOT NULL,
+  action TEXT NOT NULL CHECK (action IN ('block', 'flag')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_moderation_events_tenant_created ON moderation_events(tenant_id, created_at DESC);
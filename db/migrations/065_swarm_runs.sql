@@ -0,0 +1,15 @@
+-- Swarm runs outlive the coordinator's in-memory history so finished runs
+-- can still be looked up (e.g. to rate them) after a restart.
+CREATE TABLE IF NOT EXISTS swarm_runs (
+  run_id TEXT NOT NULL,
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  task TEXT NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('running', 'complete', 'failed', 'cancelled')),
+  trigger_type TEXT NOT NULL DEFAULT 'manual',
+  started_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (tenant_id, run_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_swarm_runs_tenant_started
+  ON swarm_runs(tenant_id, started_at DESC);
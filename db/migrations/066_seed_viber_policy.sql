@@ -0,0 +1,34 @@
+-- 024 added the viber feature but left it out of seed_tenant_policies, so
+-- tenants created since then have no viber row and the API treats the channel
+-- as disabled until the web app's ensureTenantPolicyRows runs. Seed it like
+-- the other channels and backfill tenants that are missing it. The opt-in
+-- features added since stay unseeded: a missing row means disabled.
+CREATE OR REPLACE FUNCTION seed_tenant_policies()
+RETURNS TRIGGER AS $$
+BEGIN
+  INSERT INTO tenant_policies (tenant_id, feature, enabled)
+  SELECT
+    NEW.id,
+    f.feature::feature_policy,
+    TRUE
+  FROM (
+    VALUES
+      ('swarm'),
+      ('terminal'),
+      ('deploy'),
+      ('telegram'),
+      ('whatsapp'),
+      ('viber'),
+      ('webchat'),
+      ('catalog')
+  ) AS f(feature)
+  ON CONFLICT (tenant_id, feature) DO NOTHING;
+
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+INSERT INTO tenant_policies (tenant_id, feature, enabled)
+SELECT t.id, 'viber'::feature_policy, TRUE
+FROM tenants t
+ON CONFLICT (tenant_id, feature) DO NOTHING;
@@ -0,0 +1,32 @@
+-- updated_at changes on every tenant update, so it says nothing about how
+-- long a tenant has been suspended. Record suspended_at when a tenant enters
+-- the suspended status, whichever code path suspends it, and clear it when
+-- the tenant leaves it. Suspended tenants start from their updated_at, the
+-- best estimate available; the updated_at trigger is paused so the backfill
+-- does not move it.
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ;
+
+ALTER TABLE tenants DISABLE TRIGGER trg_tenant_updated_at;
+UPDATE tenants SET suspended_at = updated_at WHERE status = 'suspended' AND suspended_at IS NULL;
+ALTER TABLE tenants ENABLE TRIGGER trg_tenant_updated_at;
+
+CREATE OR REPLACE FUNCTION set_tenant_suspended_at()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF NEW.status <> 'suspended' THEN
+    NEW.suspended_at = NULL;
+  ELSIF TG_OP = 'INSERT' OR OLD.status IS DISTINCT FROM 'suspended' THEN
+    NEW.suspended_at = NOW();
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_tenant_suspended_at ON tenants;
+CREATE TRIGGER trg_tenant_suspended_at
+BEFORE INSERT OR UPDATE ON tenants
+FOR EACH ROW
+EXECUTE FUNCTION set_tenant_suspended_at();
+
+DROP INDEX IF EXISTS idx_tenants_suspended_updated;
+CREATE INDEX IF NOT EXISTS idx_tenants_suspended_at ON tenants(suspended_at) WHERE status = 'suspended';
@@ -0,0 +1,7 @@
+-- Key rotation pages through encrypted tables by a uuid id, so give
+-- deploy_env_vars one. (tenant_id, key) stays unique for the upserts.
+ALTER TABLE deploy_env_vars ADD COLUMN IF NOT EXISTS id UUID NOT NULL DEFAULT gen_random_uuid();
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deploy_env_vars_tenant_key ON deploy_env_vars(tenant_id, key);
+ALTER TABLE deploy_env_vars DROP CONSTRAINT IF EXISTS deploy_env_vars_pkey;
+ALTER TABLE deploy_env_vars ADD CONSTRAINT deploy_env_vars_pkey PRIMARY KEY (id);
@@ -0,0 +1,12 @@
+-- When each tenant last had traffic, for the optional idle window
+-- (TENANT_IDLE_WINDOW) that stops containers of tenants idle for longer.
+-- Existing tenants start from now so enabling the window does not stop
+-- them all at once.
+CREATE TABLE IF NOT EXISTS tenant_activity (
+  tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+  last_active_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO tenant_activity (tenant_id)
+SELECT id FROM tenants
+ON CONFLICT (tenant_id) DO NOTHING;